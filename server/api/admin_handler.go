@@ -0,0 +1,226 @@
+package api
+
+import (
+	"game/config"
+	"game/protocol"
+	"game/repository"
+	"game/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServerStatsProvider 由服务器实现，提供实例运行统计
+type ServerStatsProvider interface {
+	Stats() protocol.ServerStats
+}
+
+// AdminHandler 定义管理 API 处理函数结构
+type AdminHandler struct {
+	cfg           *config.Config
+	userService   service.UserService
+	roomService   service.RoomService
+	resultService service.ResultService
+	backupService service.BackupService
+	analytics     service.AnalyticsService
+	stats         ServerStatsProvider
+	words         *service.WordFilter
+}
+
+// NewAdminHandler 创建 AdminHandler 实例
+func NewAdminHandler(cfg *config.Config, userService service.UserService, roomService service.RoomService, resultService service.ResultService, backupService service.BackupService, analytics service.AnalyticsService, stats ServerStatsProvider, words *service.WordFilter) *AdminHandler {
+	return &AdminHandler{
+		cfg:           cfg,
+		userService:   userService,
+		roomService:   roomService,
+		resultService: resultService,
+		backupService: backupService,
+		analytics:     analytics,
+		stats:         stats,
+		words:         words,
+	}
+}
+
+// PruneResults 处理手动清理游戏结果请求，before 参数缺省时按保留时长计算
+func (h *AdminHandler) PruneResults(c *gin.Context) {
+	var cutoff time.Time
+	if before := c.Query("before"); before != "" {
+		t, err := time.Parse(time.RFC3339, before)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+				Code:      http.StatusBadRequest,
+				Message:   "before 参数格式错误，应为 RFC3339 时间",
+				RequestID: requestID(c),
+			})
+			return
+		}
+		cutoff = t
+	} else {
+		if h.cfg.ResultRetention <= 0 {
+			c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+				Code:      http.StatusBadRequest,
+				Message:   "未配置结果保留时长，请指定 before 参数",
+				RequestID: requestID(c),
+			})
+			return
+		}
+		cutoff = time.Now().Add(-h.cfg.ResultRetention)
+	}
+
+	// 调用 Service 层清理
+	removed, err := h.resultService.PruneResults(cutoff, h.cfg.ResultArchive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, protocol.ErrorResponse{
+			Code:      http.StatusInternalServerError,
+			Message:   "清理游戏结果失败: " + err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	// 返回响应
+	c.JSON(http.StatusOK, gin.H{
+		"removed":  removed,
+		"archived": h.cfg.ResultArchive,
+		"before":   cutoff,
+	})
+}
+
+// Repair 处理手动触发用户与房间引用一致性修复请求
+func (h *AdminHandler) Repair(c *gin.Context) {
+	repairs := h.roomService.RepairRooms(h.cfg.RepairOfflineAfter)
+	c.JSON(http.StatusOK, gin.H{
+		"repairs": repairs,
+	})
+}
+
+// DeleteUser 处理管理员删除用户请求
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	username := c.Param("username")
+	if !h.userService.DeleteUser(username) {
+		c.JSON(http.StatusNotFound, protocol.ErrorResponse{
+			Code:      http.StatusNotFound,
+			Message:   "用户不存在",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "用户已删除"})
+}
+
+// ListWords 返回当前的屏蔽词列表
+func (h *AdminHandler) ListWords(c *gin.Context) {
+	c.JSON(http.StatusOK, protocol.WordListResponse{Words: h.words.Words(), ChatMode: h.words.ChatMode()})
+}
+
+// AddWords 添加屏蔽词，立即对新的用户名、房间名和聊天消息生效
+func (h *AdminHandler) AddWords(c *gin.Context) {
+	var req protocol.WordListRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if len(req.Words) == 0 {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "屏蔽词不能为空",
+			Field:     "words",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	added := h.words.Add(req.Words)
+	c.JSON(http.StatusOK, gin.H{"added": added, "words": h.words.Words()})
+}
+
+// RemoveWord 删除屏蔽词
+func (h *AdminHandler) RemoveWord(c *gin.Context) {
+	if !h.words.Remove(c.Param("word")) {
+		c.JSON(http.StatusNotFound, protocol.ErrorResponse{
+			Code:      http.StatusNotFound,
+			Message:   "屏蔽词不存在",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "屏蔽词已删除"})
+}
+
+// ListBackups 处理备份列表请求
+func (h *AdminHandler) ListBackups(c *gin.Context) {
+	backups, err := h.backupService.ListBackups()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, protocol.ErrorResponse{
+			Code:      http.StatusInternalServerError,
+			Message:   "读取备份列表失败: " + err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+// CreateBackup 处理立即备份请求，恢复备份需停止服务器后通过 restore 命令执行
+func (h *AdminHandler) CreateBackup(c *gin.Context) {
+	backup, err := h.backupService.CreateBackup()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, protocol.ErrorResponse{
+			Code:      http.StatusInternalServerError,
+			Message:   "创建备份失败: " + err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, backup)
+}
+
+// Stats 处理服务器实例统计查询请求
+func (h *AdminHandler) Stats(c *gin.Context) {
+	if h.stats == nil {
+		c.JSON(http.StatusServiceUnavailable, protocol.ErrorResponse{
+			Code:      http.StatusServiceUnavailable,
+			Message:   "服务器统计不可用",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, h.stats.Stats())
+}
+
+// Analytics 处理运营统计查询请求，days 参数为统计天数，缺省为 30 天
+func (h *AdminHandler) Analytics(c *gin.Context) {
+	days := 0
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+				Code:      http.StatusBadRequest,
+				Message:   "days 参数应为正整数",
+				RequestID: requestID(c),
+			})
+			return
+		}
+		days = n
+	}
+
+	list := h.analytics.Daily(time.Now(), days)
+	resp := protocol.AnalyticsResponse{Days: make([]protocol.AnalyticsDayInfo, 0, len(list))}
+	for _, d := range list {
+		resp.Days = append(resp.Days, protocol.AnalyticsDayInfo{
+			Date:           d.Date,
+			ActiveUsers:    d.ActiveUsers,
+			Logins:         d.Logins,
+			PeakConcurrent: d.PeakConcurrent,
+			AvgConcurrent:  d.AvgConcurrent,
+			Matches:        d.Matches,
+			AvgMatchLength: d.AvgMatchLength,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CacheStats 处理缓存命中统计查询请求
+func (h *AdminHandler) CacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"caches": repository.CacheStatistics()})
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"game/models"
+	"game/protocol"
+	"game/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler 定义第三方登录 API 处理函数结构
+type AuthHandler struct {
+	authService service.AuthService
+	userService service.UserService
+}
+
+// NewAuthHandler 创建 AuthHandler 实例
+func NewAuthHandler(authService service.AuthService, userService service.UserService) *AuthHandler {
+	return &AuthHandler{authService: authService, userService: userService}
+}
+
+// Providers 返回已启用的第三方登录方式
+func (h *AuthHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.authService.Providers()})
+}
+
+// Login 跳转到第三方登录页
+func (h *AuthHandler) Login(c *gin.Context) {
+	authURL, err := h.authService.BeginLogin(c.Param("provider"))
+	if err != nil {
+		h.beginError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Link 校验密码后返回关联第三方账号的登录页地址，客户端打开该地址完成关联
+func (h *AuthHandler) Link(c *gin.Context) {
+	var req protocol.LoginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	authURL, err := h.authService.BeginLink(c.Param("provider"), req.Username, req.Password)
+	if err != nil {
+		h.beginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": authURL})
+}
+
+// Callback 处理第三方登录回调，响应与密码登录相同
+func (h *AuthHandler) Callback(c *gin.Context) {
+	success, message, token, loggedIn := h.authService.CompleteLogin(c.Request.Context(), c.Param("provider"), c.Request.URL.Query())
+	status := http.StatusOK
+	if !success {
+		status = http.StatusUnauthorized
+	}
+	sessionID := ""
+	var rejoin *protocol.RoomInfo
+	if loggedIn {
+		rejoin = rejoinRoom(h.userService, token)
+		h.userService.RecordLogin(models.LoginRecord{
+			Username:  token,
+			IP:        c.ClientIP(),
+			UserAgent: c.GetHeader("User-Agent"),
+			Method:    c.Param("provider"),
+			Success:   true,
+		})
+		sessionID = h.userService.StartSession(token, c.GetHeader("User-Agent"), c.ClientIP(), "").ID
+	}
+	c.JSON(status, protocol.LoginResponse{
+		Success:    success,
+		Message:    message,
+		Token:      token,
+		SessionID:  sessionID,
+		RejoinRoom: rejoin,
+	})
+}
+
+func (h *AuthHandler) beginError(c *gin.Context, err error) {
+	status := http.StatusUnauthorized
+	switch {
+	case errors.Is(err, service.ErrUnknownProvider):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrAuthBusy):
+		status = http.StatusTooManyRequests
+	}
+	c.JSON(status, protocol.ErrorResponse{
+		Code:      status,
+		Message:   err.Error(),
+		RequestID: requestID(c),
+	})
+}
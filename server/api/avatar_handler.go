@@ -0,0 +1,77 @@
+package api
+
+import (
+	"errors"
+	"game/protocol"
+	"game/service"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AvatarHandler 定义头像 API 处理函数结构
+type AvatarHandler struct {
+	avatarService service.AvatarService
+}
+
+// NewAvatarHandler 创建 AvatarHandler 实例
+func NewAvatarHandler(avatarService service.AvatarService) *AvatarHandler {
+	return &AvatarHandler{avatarService: avatarService}
+}
+
+// Upload 处理头像上传请求，请求体为 multipart 表单：username、session_id 和图片文件 avatar
+func (h *AvatarHandler) Upload(c *gin.Context) {
+	file, _, err := c.Request.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, protocol.ErrorResponse{
+				Code:      http.StatusRequestEntityTooLarge,
+				Message:   "图片过大",
+				RequestID: requestID(c),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "请选择要上传的图片",
+			Field:     "avatar",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "读取图片失败",
+			Field:     "avatar",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	success, message := h.avatarService.Upload(c.Request.FormValue("username"), c.Request.FormValue("session_id"), content)
+	c.JSON(http.StatusOK, protocol.RegisterResponse{
+		Success: success,
+		Message: message,
+	})
+}
+
+// Get 返回用户的头像，:user 可以是当前或曾用的用户名
+func (h *AvatarHandler) Get(c *gin.Context) {
+	content, updatedAt, ok := h.avatarService.Avatar(c.Param("user"))
+	if !ok {
+		c.JSON(http.StatusNotFound, protocol.ErrorResponse{
+			Code:      http.StatusNotFound,
+			Message:   "头像不存在",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Header("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, "image/png", content)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"game/protocol"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BalanceManager 由连接层实现，查询和发布英雄、武器平衡版本
+type BalanceManager interface {
+	Balance() protocol.BalanceInfo
+	PushBalance(content []byte) (protocol.BalanceInfo, error)
+}
+
+// BalanceHandler 定义平衡版本 API 处理函数结构
+type BalanceHandler struct {
+	balance BalanceManager
+}
+
+// NewBalanceHandler 创建 BalanceHandler 实例
+func NewBalanceHandler(balance BalanceManager) *BalanceHandler {
+	return &BalanceHandler{balance: balance}
+}
+
+// Get 返回当前生效的平衡版本和已发布的版本
+func (h *BalanceHandler) Get(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.balance.Balance())
+}
+
+// Push 发布新的平衡版本，请求体为 heroes.json 格式且必须填写未使用过的版本号；
+// 新版本立即生效，但只用于之后开局的对局
+func (h *BalanceHandler) Push(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	content, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "读取请求体失败",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	info, err := h.balance.PushBalance(content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// available 未设置平衡版本管理时返回 503
+func (h *BalanceHandler) available(c *gin.Context) bool {
+	if h.balance != nil {
+		return true
+	}
+	c.JSON(http.StatusServiceUnavailable, protocol.ErrorResponse{
+		Code:      http.StatusServiceUnavailable,
+		Message:   "平衡版本管理不可用",
+		RequestID: requestID(c),
+	})
+	return false
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"game/protocol"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigReloader 由连接层实现，重新加载可热更新的数据文件
+type ConfigReloader interface {
+	ReloadConfig() (protocol.ConfigReloadResponse, error)
+}
+
+// ConfigHandler 定义配置热更新 API 处理函数结构
+type ConfigHandler struct {
+	reloader ConfigReloader
+}
+
+// NewConfigHandler 创建 ConfigHandler 实例
+func NewConfigHandler(reloader ConfigReloader) *ConfigHandler {
+	return &ConfigHandler{reloader: reloader}
+}
+
+// Reload 处理管理端热更新请求，数据文件无效时返回 422 并保留原配置
+func (h *ConfigHandler) Reload(c *gin.Context) {
+	if h.reloader == nil {
+		c.JSON(http.StatusServiceUnavailable, protocol.ErrorResponse{
+			Code:      http.StatusServiceUnavailable,
+			Message:   "配置热更新不可用",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	resp, err := h.reloader.ReloadConfig()
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, protocol.ErrorResponse{
+			Code:      http.StatusUnprocessableEntity,
+			Message:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
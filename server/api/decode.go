@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"game/protocol"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodyLimitMiddleware 限制请求体大小，超过 limit 字节的请求体在解码时报错；overrides 按路由路径指定单独的限制，
+// 用于上传头像等需要更大请求体的接口。限制不大于 0 时不限制
+func bodyLimitMiddleware(limit int, overrides map[string]int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		n := limit
+		if override, ok := overrides[c.FullPath()]; ok {
+			n = override
+		}
+		if n > 0 && c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(n))
+		}
+		c.Next()
+	}
+}
+
+// bindJSON 严格解码请求体到 v，失败时返回结构化的 400 或 413 错误响应并返回 false
+func bindJSON(c *gin.Context, v any) bool {
+	err := protocol.Decode(c.Request.Body, v)
+	if err == nil {
+		return true
+	}
+	var decodeErr *protocol.DecodeError
+	if !errors.As(err, &decodeErr) {
+		decodeErr = &protocol.DecodeError{Reason: err.Error()}
+	}
+	status := http.StatusBadRequest
+	if decodeErr.TooLarge {
+		status = http.StatusRequestEntityTooLarge
+	}
+	c.JSON(status, protocol.ErrorResponse{
+		Code:      status,
+		Message:   "请求格式错误: " + decodeErr.Reason,
+		Field:     decodeErr.Field,
+		RequestID: requestID(c),
+	})
+	return false
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"game/models"
+	"game/protocol"
+	"game/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DisputeHandler 定义结果申诉 API 处理函数结构
+type DisputeHandler struct {
+	disputes service.DisputeService
+}
+
+// NewDisputeHandler 创建 DisputeHandler 实例
+func NewDisputeHandler(disputes service.DisputeService) *DisputeHandler {
+	return &DisputeHandler{disputes: disputes}
+}
+
+// Create 处理玩家对对局结果的申诉，返回的申诉不含回放和违规记录
+func (h *DisputeHandler) Create(c *gin.Context) {
+	var req protocol.DisputeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	d, err := h.disputes.Create(c.Param("id"), req, time.Now())
+	if err != nil {
+		disputeError(c, err)
+		return
+	}
+	info := disputeInfo(d)
+	info.Replay, info.Flags = nil, nil
+	c.JSON(http.StatusOK, info)
+}
+
+// Queue 返回申诉审核队列，status 参数为 pending（默认）、upheld 或 rejected
+func (h *DisputeHandler) Queue(c *gin.Context) {
+	disputes := h.disputes.Queue(c.Query("status"))
+	resp := protocol.DisputeListResponse{Disputes: make([]protocol.DisputeInfo, 0, len(disputes))}
+	for _, d := range disputes {
+		resp.Disputes = append(resp.Disputes, disputeInfo(d))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Review 审核申诉
+func (h *DisputeHandler) Review(c *gin.Context) {
+	var req protocol.ReviewDisputeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	d, err := h.disputes.Review(c.Param("id"), req.Verdict, req.Note)
+	if err != nil {
+		disputeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, disputeInfo(d))
+}
+
+// disputeError 按申诉业务错误返回对应的状态码
+func disputeError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, service.ErrDisputeNotFound), errors.Is(err, service.ErrDisputeResult), errors.Is(err, service.ErrDisputeUserNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrDisputeSession):
+		status = http.StatusUnauthorized
+	case errors.Is(err, service.ErrDisputePlayer):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrDisputeDuplicate), errors.Is(err, service.ErrDisputeReviewed):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrDisputeDisabled):
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, protocol.ErrorResponse{
+		Code:      status,
+		Message:   err.Error(),
+		RequestID: requestID(c),
+	})
+}
+
+// disputeInfo 将申诉转换为协议中的格式
+func disputeInfo(d models.Dispute) protocol.DisputeInfo {
+	info := protocol.DisputeInfo{
+		ID:        d.ID,
+		ResultID:  d.ResultID,
+		Username:  d.Username,
+		Reason:    d.Reason,
+		Status:    d.Status,
+		CreatedAt: d.CreatedAt,
+		Note:      d.Note,
+		Result:    resultInfo(d.Result),
+	}
+	if !d.ReviewedAt.IsZero() {
+		reviewed := d.ReviewedAt
+		info.ReviewedAt = &reviewed
+	}
+	for _, rec := range d.Replay {
+		info.Replay = append(info.Replay, protocol.ReplayRecordInfo{
+			Time:      rec.Time,
+			Username:  rec.Username,
+			Event:     rec.Event,
+			MessageID: rec.MessageID,
+			Message:   rec.Message,
+		})
+	}
+	for _, flag := range d.Flags {
+		info.Flags = append(info.Flags, cheatFlagInfo(flag))
+	}
+	for _, a := range d.Adjustments {
+		info.Adjustments = append(info.Adjustments, protocol.RatingAdjustmentInfo{Username: a.Username, Delta: a.Delta})
+	}
+	return info
+}
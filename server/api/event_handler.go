@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"game/protocol"
+	"game/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventHandler 定义限时活动 API 处理函数结构
+type EventHandler struct {
+	events service.EventService
+}
+
+// NewEventHandler 创建 EventHandler 实例
+func NewEventHandler(events service.EventService) *EventHandler {
+	return &EventHandler{events: events}
+}
+
+// List 返回进行中和即将开始的活动，all=true 时包含已结束的活动
+func (h *EventHandler) List(c *gin.Context) {
+	now := time.Now()
+	events := h.events.List(now, c.Query("all") == "true")
+	resp := protocol.EventListResponse{Events: make([]protocol.EventInfo, 0, len(events))}
+	for _, e := range events {
+		resp.Events = append(resp.Events, service.EventInfo(e, now))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Create 处理管理端安排活动请求
+func (h *EventHandler) Create(c *gin.Context) {
+	var req protocol.CreateEventRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	e, err := h.events.Create(req)
+	if err != nil {
+		field := "name"
+		switch {
+		case errors.Is(err, service.ErrEventTime):
+			field = "ends_at"
+		case errors.Is(err, service.ErrEventMultiplier):
+			field = "win_multiplier"
+		case errors.Is(err, service.ErrEventRotate):
+			field = "rotate_minutes"
+		case errors.Is(err, service.ErrEventMode):
+			field = "modes"
+		}
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   err.Error(),
+			Field:     field,
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, service.EventInfo(e, time.Now()))
+}
+
+// Remove 处理管理端删除活动请求
+func (h *EventHandler) Remove(c *gin.Context) {
+	if err := h.events.Remove(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, protocol.ErrorResponse{
+			Code:      http.StatusNotFound,
+			Message:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "活动已删除"})
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"game/protocol"
+	"game/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LadderHandler 定义天梯 API 处理函数结构
+type LadderHandler struct {
+	ladder service.LadderService
+}
+
+// NewLadderHandler 创建 LadderHandler 实例
+func NewLadderHandler(ladder service.LadderService) *LadderHandler {
+	return &LadderHandler{ladder: ladder}
+}
+
+// List 返回按排名排列的天梯
+func (h *LadderHandler) List(c *gin.Context) {
+	players := h.ladder.Ladder()
+	resp := protocol.LadderResponse{Players: make([]protocol.LadderEntryInfo, 0, len(players))}
+	for i, p := range players {
+		resp.Players = append(resp.Players, protocol.LadderEntryInfo{Position: i + 1, Username: p.Username})
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"game/protocol"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MatchStatsProvider 由连接层实现，提供匹配队列的实时统计和玩家的弃赛统计
+type MatchStatsProvider interface {
+	MatchStats(detail bool) protocol.MatchStats
+	PlayerAbandons(username string) protocol.PlayerAbandonInfo
+}
+
+// MatchHandler 定义匹配 API 处理函数结构
+type MatchHandler struct {
+	stats MatchStatsProvider
+}
+
+// NewMatchHandler 创建 MatchHandler 实例
+func NewMatchHandler(stats MatchStatsProvider) *MatchHandler {
+	return &MatchHandler{stats: stats}
+}
+
+// Stats 处理匹配统计查询请求，返回队列长度、等待时间、评分分布和配对质量
+func (h *MatchHandler) Stats(c *gin.Context) {
+	h.respond(c, false)
+}
+
+// AdminStats 处理管理端匹配统计查询请求，额外返回队列中的玩家列表
+func (h *MatchHandler) AdminStats(c *gin.Context) {
+	h.respond(c, true)
+}
+
+// respond 返回匹配统计，匹配服务未启用时返回 503
+func (h *MatchHandler) respond(c *gin.Context, detail bool) {
+	if h.stats == nil {
+		c.JSON(http.StatusServiceUnavailable, protocol.ErrorResponse{
+			Code:      http.StatusServiceUnavailable,
+			Message:   "匹配服务未启用",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, h.stats.MatchStats(detail))
+}
+
+// Abandons 处理玩家弃赛统计查询请求，返回最近对局和全部对局的掉线、弃赛和重连比例以及剩余的匹配冷却时间
+func (h *MatchHandler) Abandons(c *gin.Context) {
+	username := c.Query("username")
+	if username == "" {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "用户名不能为空",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if h.stats == nil {
+		c.JSON(http.StatusServiceUnavailable, protocol.ErrorResponse{
+			Code:      http.StatusServiceUnavailable,
+			Message:   "匹配服务未启用",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, h.stats.PlayerAbandons(username))
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"game/models"
+	"game/protocol"
+	"game/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModerationHandler 定义作弊标记审核 API 处理函数结构
+type ModerationHandler struct {
+	moderation service.ModerationService
+}
+
+// NewModerationHandler 创建 ModerationHandler 实例
+func NewModerationHandler(moderation service.ModerationService) *ModerationHandler {
+	return &ModerationHandler{moderation: moderation}
+}
+
+// Queue 返回作弊标记审核队列，status 参数为 pending（默认）、cleared 或 confirmed。
+// 待审核队列同时列出待审核的对局结果申诉，申诉通过 /admin/disputes/:id/review 审核
+func (h *ModerationHandler) Queue(c *gin.Context) {
+	status := c.Query("status")
+	flags := h.moderation.Queue(status)
+	list := make([]protocol.CheatFlagInfo, 0, len(flags))
+	for _, flag := range flags {
+		list = append(list, cheatFlagInfo(flag))
+	}
+	resp := protocol.CheatFlagListResponse{Flags: list}
+	if status == "" || status == models.CheatFlagPending {
+		for _, d := range h.moderation.PendingDisputes() {
+			resp.Disputes = append(resp.Disputes, disputeInfo(d))
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Review 审核用户的作弊标记
+func (h *ModerationHandler) Review(c *gin.Context) {
+	var req protocol.ReviewFlagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	flag, err := h.moderation.Review(c.Param("user_id"), req.Verdict, req.Note)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrFlagNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, protocol.ErrorResponse{
+			Code:      status,
+			Message:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, cheatFlagInfo(flag))
+}
+
+// cheatFlagInfo 将作弊标记转换为协议中的格式
+func cheatFlagInfo(flag models.CheatFlag) protocol.CheatFlagInfo {
+	info := protocol.CheatFlagInfo{
+		UserID:     flag.UserID,
+		Username:   flag.Username,
+		Score:      flag.Score,
+		Violations: flag.Violations,
+		Status:     flag.Status,
+		FlaggedAt:  flag.FlaggedAt,
+		Note:       flag.Note,
+	}
+	if !flag.ReviewedAt.IsZero() {
+		reviewed := flag.ReviewedAt
+		info.ReviewedAt = &reviewed
+	}
+	return info
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"game/protocol"
+	"game/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlayerHandler 定义玩家搜索 API 处理函数结构
+type PlayerHandler struct {
+	search *service.PlayerSearch
+}
+
+// NewPlayerHandler 创建 PlayerHandler 实例
+func NewPlayerHandler(search *service.PlayerSearch) *PlayerHandler {
+	return &PlayerHandler{search: search}
+}
+
+// Search 处理玩家搜索请求，按用户名前缀返回匹配的玩家，供邀请好友和私聊时自动补全；按客户端 IP 限制频率
+func (h *PlayerHandler) Search(c *gin.Context) {
+	if h.search == nil {
+		c.JSON(http.StatusServiceUnavailable, protocol.ErrorResponse{
+			Code:      http.StatusServiceUnavailable,
+			Message:   "玩家搜索未启用",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	users, err := h.search.Search(c.ClientIP(), c.Query("q"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrSearchTooFrequent) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, protocol.ErrorResponse{
+			Code:      status,
+			Message:   err.Error(),
+			Field:     "q",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	players := make([]protocol.PlayerSearchResult, 0, len(users))
+	for _, user := range users {
+		players = append(players, protocol.PlayerSearchResult{
+			ID:        user.UserID,
+			Username:  user.Username,
+			AvatarURL: service.AvatarURL(user),
+			Online:    user.Online,
+		})
+	}
+	c.JSON(http.StatusOK, protocol.PlayerSearchResponse{Players: players})
+}
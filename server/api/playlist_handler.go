@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"game/protocol"
+	"game/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlaylistHandler 定义快速匹配精选玩法 API 处理函数结构
+type PlaylistHandler struct {
+	playlist service.PlaylistService
+}
+
+// NewPlaylistHandler 创建 PlaylistHandler 实例
+func NewPlaylistHandler(playlist service.PlaylistService) *PlaylistHandler {
+	return &PlaylistHandler{playlist: playlist}
+}
+
+// Get 返回精选玩法列表及当前轮换到的玩法
+func (h *PlaylistHandler) Get(c *gin.Context) {
+	c.JSON(http.StatusOK, service.PlaylistInfo(h.playlist.Get(), time.Now()))
+}
+
+// Set 处理管理端替换精选玩法列表请求
+func (h *PlaylistHandler) Set(c *gin.Context) {
+	var req protocol.SetPlaylistRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	now := time.Now()
+	p, err := h.playlist.Set(req, now)
+	if err != nil {
+		field := "entries"
+		if errors.Is(err, service.ErrPlaylistRotate) {
+			field = "rotate_minutes"
+		}
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   err.Error(),
+			Field:     field,
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, service.PlaylistInfo(p, now))
+}
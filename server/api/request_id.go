@@ -0,0 +1,76 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// requestIDHeader 请求ID的请求头和响应头，客户端传入合法的ID时沿用，否则由服务器生成
+	requestIDHeader = "X-Request-ID"
+	// requestIDKey 请求ID在 gin.Context 中的键
+	requestIDKey = "request_id"
+	// maxRequestIDLength 沿用客户端请求ID的最大长度
+	maxRequestIDLength = 64
+)
+
+// NewRequestID 生成随机的请求ID
+func NewRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// validRequestID 客户端传入的请求ID只能包含字母、数字和 - _ .，避免污染日志
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDMiddleware 为每个请求分配请求ID，写入响应头供用户反馈问题时提供
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// requestID 返回当前请求的ID，附加到错误响应中
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// logFormatter 访问日志格式，在 gin 默认格式后附加请求ID
+func logFormatter(p gin.LogFormatterParams) string {
+	id, _ := p.Keys[requestIDKey].(string)
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | %s\n%s",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		p.StatusCode,
+		p.Latency,
+		p.ClientIP,
+		p.Method,
+		p.Path,
+		id,
+		p.ErrorMessage,
+	)
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"game/models"
+	"game/protocol"
+	"game/repository"
+	"game/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResultHandler 定义游戏结果 API 处理函数结构
+type ResultHandler struct {
+	resultService service.ResultService
+}
+
+// NewResultHandler 创建 ResultHandler 实例
+func NewResultHandler(resultService service.ResultService) *ResultHandler {
+	return &ResultHandler{resultService: resultService}
+}
+
+// GetResults 处理游戏结果查询请求，支持 room_id、player、since、offset、limit 参数
+func (h *ResultHandler) GetResults(c *gin.Context) {
+	q := repository.ResultQuery{
+		RoomID: c.Query("room_id"),
+		Player: c.Query("player"),
+	}
+
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+				Code:      http.StatusBadRequest,
+				Message:   "since 参数格式错误，应为 RFC3339 时间",
+				RequestID: requestID(c),
+			})
+			return
+		}
+		q.Since = t
+	}
+
+	var err error
+	if q.Offset, err = queryInt(c, "offset"); err != nil {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "offset 参数格式错误",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if q.Limit, err = queryInt(c, "limit"); err != nil {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "limit 参数格式错误",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	// 调用 Service 层查询
+	results, total, q := h.resultService.QueryResults(q)
+
+	resultInfos := make([]protocol.ResultInfo, 0, len(results))
+	for _, r := range results {
+		resultInfos = append(resultInfos, resultInfo(r))
+	}
+
+	// 返回响应
+	c.JSON(http.StatusOK, protocol.ResultListResponse{
+		Results: resultInfos,
+		Total:   total,
+		Offset:  q.Offset,
+		Limit:   q.Limit,
+	})
+}
+
+// resultInfo 将游戏结果转换为协议中的结果信息
+func resultInfo(r models.GameResult) protocol.ResultInfo {
+	return protocol.ResultInfo{
+		ID:         r.ID,
+		RoomID:     r.RoomID,
+		Winner:     r.Winner,
+		Loser:      r.Loser,
+		PlayTime:   r.PlayTime,
+		Duration:   r.Duration,
+		Reason:     r.Reason,
+		Map:        r.Map,
+		Players:    service.PlayerSummaries(r.Players),
+		MVP:        r.MVP,
+		Overtime:   r.Overtime,
+		Placements: r.Placements,
+		BotMatch:   r.BotMatch,
+	}
+}
+
+// queryInt 读取整数查询参数，参数不存在时返回 0
+func queryInt(c *gin.Context, key string) (int, error) {
+	v := c.Query(key)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
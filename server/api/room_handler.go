@@ -1,9 +1,12 @@
 package api
 
 import (
+	"errors"
+	"game/models"
 	"game/protocol"
 	"game/service"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,29 +24,78 @@ func NewRoomHandler(roomService service.RoomService) *RoomHandler {
 // CreateRoom 处理创建房间请求
 func (h *RoomHandler) CreateRoom(c *gin.Context) {
 	var req protocol.CreateRoomRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
-			Code:    http.StatusBadRequest,
-			Message: "请求格式错误",
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	username := c.Query("username")
 	if username == "" {
 		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
-			Code:    http.StatusBadRequest,
-			Message: "用户名不能为空",
+			Code:      http.StatusBadRequest,
+			Message:   "用户名不能为空",
+			RequestID: requestID(c),
 		})
 		return
 	}
 
+	// 幂等键也可以通过 Idempotency-Key 请求头传入
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = c.GetHeader("Idempotency-Key")
+	}
+
 	// 调用 Service 层处理创建房间逻辑
-	room, err := h.roomService.CreateRoom(req, username)
+	result, err := h.roomService.CreateRoom(req, username)
+	if reason := service.RoomErrorCode(err); reason != "" {
+		// 房间名或人数不符合规则，重名时返回 409
+		status := http.StatusBadRequest
+		if reason == service.ErrCodeRoomNameTaken {
+			status = http.StatusConflict
+		}
+		c.JSON(status, protocol.ErrorResponse{
+			Code:      status,
+			Message:   err.Error(),
+			Reason:    reason,
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidRules) || errors.Is(err, service.ErrUnknownRegion) {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrAlreadyInRoom) || errors.Is(err, service.ErrInMatch) {
+		c.JSON(http.StatusConflict, protocol.ErrorResponse{
+			Code:      http.StatusConflict,
+			Message:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrRoomCreateTooFrequent) {
+		c.JSON(http.StatusTooManyRequests, protocol.ErrorResponse{
+			Code:      http.StatusTooManyRequests,
+			Message:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrRoomLimitReached) {
+		c.JSON(http.StatusServiceUnavailable, protocol.ErrorResponse{
+			Code:      http.StatusServiceUnavailable,
+			Message:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, protocol.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "创建房间失败: " + err.Error(),
+			Code:      http.StatusInternalServerError,
+			Message:   "创建房间失败: " + err.Error(),
+			RequestID: requestID(c),
 		})
 		return
 	}
@@ -51,19 +103,15 @@ func (h *RoomHandler) CreateRoom(c *gin.Context) {
 	// 返回响应
 	c.JSON(http.StatusOK, protocol.CreateRoomResponse{
 		Success: true,
-		Message: "房间创建成功",
-		RoomID:  room.ID,
+		Message: result.Message,
+		RoomID:  result.Room.ID,
 	})
 }
 
 // JoinRoom 处理加入房间请求
 func (h *RoomHandler) JoinRoom(c *gin.Context) {
 	var req protocol.JoinRoomRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
-			Code:    http.StatusBadRequest,
-			Message: "请求格式错误",
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -72,65 +120,78 @@ func (h *RoomHandler) JoinRoom(c *gin.Context) {
 	username := c.Query("username")
 	if username == "" {
 		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
-			Code:    http.StatusBadRequest,
-			Message: "用户名不能为空",
+			Code:      http.StatusBadRequest,
+			Message:   "用户名不能为空",
+			RequestID: requestID(c),
 		})
 		return
 	}
 
 	// 调用 Service 层处理加入房间逻辑
-	room, message, err := h.roomService.JoinRoom(req, username)
+	result, err := h.roomService.JoinRoom(req, username)
+	if errors.Is(err, service.ErrAlreadyInRoom) || errors.Is(err, service.ErrInMatch) {
+		c.JSON(http.StatusConflict, protocol.JoinRoomResponse{
+			Message: err.Error(),
+			Code:    service.ErrCodeAlreadyInRoom,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, protocol.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "加入房间失败: " + err.Error(),
+			Code:      http.StatusInternalServerError,
+			Message:   "加入房间失败: " + err.Error(),
+			RequestID: requestID(c),
 		})
 		return
 	}
 
-	if room == nil {
+	if result.Room == nil {
+		// 集群模式下房间可能由其他实例托管，返回转移信息让客户端重新连接
+		if handoff := h.roomService.Handoff(req.RoomID, username); handoff != nil {
+			c.JSON(http.StatusOK, protocol.JoinRoomResponse{Message: "房间位于其他服务器，请重新连接", Handoff: handoff})
+			return
+		}
 		c.JSON(http.StatusOK, protocol.JoinRoomResponse{
 			Success: false,
-			Message: message,
+			Message: result.Message,
 		})
 		return
 	}
 
-	// 构建房间信息响应
-	roomInfo := protocol.RoomInfo{
-		ID:         room.ID,
-		Name:       room.Name,
-		Host:       room.HostID,
-		Players:    room.Players,
-		MaxPlayers: room.MaxPlayers,
-		Status:     room.Status,
-	}
-
 	// 返回响应
 	c.JSON(http.StatusOK, protocol.JoinRoomResponse{
 		Success: true,
-		Message: message,
-		Room:    roomInfo,
+		Message: result.Message,
+		Room:    roomInfo(*result.Room),
 	})
 }
 
-// GetRoomList 处理获取房间列表请求
+// GetRoomList 处理获取房间列表请求，region 参数不为空时只返回该区域的房间
 func (h *RoomHandler) GetRoomList(c *gin.Context) {
 	// 调用 Service 层获取所有房间
 	rooms := h.roomService.GetAllRooms()
+	region := strings.ToLower(strings.TrimSpace(c.Query("region")))
+
+	// filter 为用户保存的筛选条件名称，需同时提供 username
+	filter := models.RoomFilter{}
+	if name := c.Query("filter"); name != "" {
+		f := h.roomService.RoomFilter(c.Query("username"), name)
+		if f == nil {
+			c.JSON(http.StatusNotFound, protocol.ErrorResponse{
+				Code:      http.StatusNotFound,
+				Message:   "筛选条件不存在",
+				RequestID: requestID(c),
+			})
+			return
+		}
+		filter = *f
+	}
 
 	// 构建房间列表响应
 	roomInfos := make([]protocol.RoomInfo, 0)
 	for _, room := range rooms {
-		if room.Status != "playing" {
-			roomInfos = append(roomInfos, protocol.RoomInfo{
-				ID:         room.ID,
-				Name:       room.Name,
-				Host:       room.HostID,
-				Players:    room.Players,
-				MaxPlayers: room.MaxPlayers,
-				Status:     room.Status,
-			})
+		if room.Status != "playing" && (region == "" || room.Region == region) && filter.Match(room) {
+			roomInfos = append(roomInfos, roomInfo(room))
 		}
 	}
 
@@ -139,3 +200,20 @@ func (h *RoomHandler) GetRoomList(c *gin.Context) {
 		Rooms: roomInfos,
 	})
 }
+
+// roomInfo 将房间转换为协议中的房间信息
+func roomInfo(room models.Room) protocol.RoomInfo {
+	return protocol.RoomInfo{
+		ID:         room.ID,
+		Name:       room.Name,
+		Host:       room.HostID,
+		Players:    room.Players,
+		MaxPlayers: room.MaxPlayers,
+		Status:     room.Status,
+		Map:        room.Map,
+		Rules:      service.RulesInfo(room.Rules),
+		Heroes:     room.Heroes,
+		Region:     room.Region,
+		Instance:   room.Instance,
+	}
+}
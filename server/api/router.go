@@ -1,27 +1,126 @@
 package api
 
 import (
+	"game/config"
+	"game/protocol"
+	"game/report"
 	"game/service"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Router 定义路由器结构
 type Router struct {
-	Engine      *gin.Engine
-	userService service.UserService
-	roomService service.RoomService
+	Engine        *gin.Engine
+	cfg           *config.Config
+	userService   service.UserService
+	roomService   service.RoomService
+	resultService service.ResultService
+	backupService service.BackupService
+	authService   service.AuthService
+	analytics     service.AnalyticsService
+	avatars       service.AvatarService
+	words         *service.WordFilter
+	matchStats    MatchStatsProvider
+	serverStats   ServerStatsProvider
+	moderation    service.ModerationService
+	signatures    *signatureVerifier
+	playerSearch  *service.PlayerSearch
+	titles        service.TitleService
+	reloader      ConfigReloader
+	balance       BalanceManager
+	tournaments   service.TournamentService
+	ladder        service.LadderService
+	events        service.EventService
+	playlist      service.PlaylistService
+	disputes      service.DisputeService
 }
 
 // NewRouter 创建路由器实例
-func NewRouter(userService service.UserService, roomService service.RoomService) *Router {
+func NewRouter(cfg *config.Config, userService service.UserService, roomService service.RoomService, resultService service.ResultService, backupService service.BackupService, authService service.AuthService, analyticsService service.AnalyticsService, avatarService service.AvatarService, words *service.WordFilter) *Router {
+	engine := gin.New()
+	bodyLimits := map[string]int{"/user/avatar": cfg.AvatarMaxBytes}
+	engine.Use(requestIDMiddleware(), gin.LoggerWithFormatter(logFormatter), slowMiddleware(), recoveryMiddleware(), bodyLimitMiddleware(cfg.MaxBodyBytes, bodyLimits))
+
 	return &Router{
-		Engine:      gin.Default(),
-		userService: userService,
-		roomService: roomService,
+		Engine:        engine,
+		cfg:           cfg,
+		userService:   userService,
+		roomService:   roomService,
+		resultService: resultService,
+		backupService: backupService,
+		authService:   authService,
+		analytics:     analyticsService,
+		avatars:       avatarService,
+		words:         words,
+		signatures:    newSignatureVerifier(cfg.SigningKeys, cfg.SignatureWindow, cfg.MaxBodyBytes),
 	}
 }
 
+// SetMatchStats 设置匹配统计来源，需在 SetupRoutes 之前调用
+func (r *Router) SetMatchStats(stats MatchStatsProvider) {
+	r.matchStats = stats
+}
+
+// SetServerStats 设置服务器实例统计来源，需在 SetupRoutes 之前调用
+func (r *Router) SetServerStats(stats ServerStatsProvider) {
+	r.serverStats = stats
+}
+
+// SetModeration 设置作弊标记服务，需在 SetupRoutes 之前调用
+func (r *Router) SetModeration(moderation service.ModerationService) {
+	r.moderation = moderation
+}
+
+// SetPlayerSearch 设置玩家搜索索引，需在 SetupRoutes 之前调用
+func (r *Router) SetPlayerSearch(search *service.PlayerSearch) {
+	r.playerSearch = search
+}
+
+// SetTitles 设置称号服务，需在 SetupRoutes 之前调用
+func (r *Router) SetTitles(titles service.TitleService) {
+	r.titles = titles
+}
+
+// SetConfigReloader 设置数据文件热更新的实现，需在 SetupRoutes 之前调用
+func (r *Router) SetConfigReloader(reloader ConfigReloader) {
+	r.reloader = reloader
+}
+
+// SetBalance 设置平衡版本管理，需在 SetupRoutes 之前调用
+func (r *Router) SetBalance(balance BalanceManager) {
+	r.balance = balance
+}
+
+// SetTournaments 设置赛事服务，需在 SetupRoutes 之前调用
+func (r *Router) SetTournaments(tournaments service.TournamentService) {
+	r.tournaments = tournaments
+}
+
+// SetLadder 设置天梯服务，需在 SetupRoutes 之前调用
+func (r *Router) SetLadder(ladder service.LadderService) {
+	r.ladder = ladder
+}
+
+// SetEvents 设置限时活动服务，需在 SetupRoutes 之前调用
+func (r *Router) SetEvents(events service.EventService) {
+	r.events = events
+}
+
+// SetPlaylist 设置快速匹配精选玩法服务，需在 SetupRoutes 之前调用
+func (r *Router) SetPlaylist(playlist service.PlaylistService) {
+	r.playlist = playlist
+}
+
+// SetDisputes 设置结果申诉服务，需在 SetupRoutes 之前调用
+func (r *Router) SetDisputes(disputes service.DisputeService) {
+	r.disputes = disputes
+}
+
 // SetupRoutes 设置路由
 func (r *Router) SetupRoutes() {
 	// 添加 CORS 中间件
@@ -30,11 +129,70 @@ func (r *Router) SetupRoutes() {
 	// 用户相关路由
 	userGroup := r.Engine.Group("/user")
 	{
-		userHandler := NewUserHandler(r.userService)
+		userHandler := NewUserHandler(r.userService, service.NewRegions(r.cfg.Regions))
 		userGroup.POST("/register", userHandler.Register)
 		userGroup.POST("/login", userHandler.Login)
+		userGroup.POST("/refresh", userHandler.Refresh)
 		userGroup.POST("/logout", userHandler.Logout)
 		userGroup.GET("/test", userHandler.Test)
+		userGroup.DELETE("/account", userHandler.DeleteAccount)
+		userGroup.POST("/change-password", userHandler.ChangePassword)
+		userGroup.POST("/change-username", userHandler.ChangeUsername)
+		userGroup.GET("/lookup", userHandler.Lookup)
+		userGroup.GET("/recent-opponents", userHandler.RecentOpponents)
+		userGroup.GET("/room-filters", userHandler.RoomFilters)
+		userGroup.POST("/room-filters", userHandler.SaveRoomFilter)
+		userGroup.DELETE("/room-filters/:name", userHandler.DeleteRoomFilter)
+		userGroup.POST("/change-email", userHandler.ChangeEmail)
+		userGroup.POST("/confirm-email", userHandler.ConfirmEmail)
+		userGroup.GET("/export", userHandler.Export)
+		userGroup.GET("/sessions", userHandler.Sessions)
+		userGroup.GET("/logins", userHandler.Logins)
+		userGroup.DELETE("/sessions/:id", userHandler.RevokeSession)
+		userGroup.POST("/privacy", userHandler.SetSearchPrivacy)
+	}
+
+	// 称号路由
+	titleHandler := NewTitleHandler(r.titles)
+	r.Engine.GET("/user/titles", titleHandler.List)
+	r.Engine.POST("/user/titles/active", titleHandler.Select)
+
+	// 赛事路由
+	tournamentHandler := NewTournamentHandler(r.tournaments)
+	r.Engine.GET("/tournaments", tournamentHandler.List)
+	r.Engine.GET("/tournaments/live", tournamentHandler.Live)
+	r.Engine.GET("/tournaments/:id", tournamentHandler.Get)
+	r.Engine.POST("/tournaments/:id/register", tournamentHandler.Register)
+
+	// 天梯路由
+	ladderHandler := NewLadderHandler(r.ladder)
+	r.Engine.GET("/ladder", ladderHandler.List)
+
+	// 活动路由
+	eventHandler := NewEventHandler(r.events)
+	r.Engine.GET("/events", eventHandler.List)
+
+	// 精选玩法路由
+	playlistHandler := NewPlaylistHandler(r.playlist)
+	r.Engine.GET("/playlist", playlistHandler.Get)
+
+	// 玩家搜索路由
+	playerHandler := NewPlayerHandler(r.playerSearch)
+	r.Engine.GET("/players/search", playerHandler.Search)
+
+	// 头像路由
+	avatarHandler := NewAvatarHandler(r.avatars)
+	r.Engine.POST("/user/avatar", avatarHandler.Upload)
+	r.Engine.GET("/avatars/:user", avatarHandler.Get)
+
+	// 第三方登录路由
+	authGroup := r.Engine.Group("/auth")
+	{
+		authHandler := NewAuthHandler(r.authService, r.userService)
+		authGroup.GET("/providers", authHandler.Providers)
+		authGroup.GET("/:provider/login", authHandler.Login)
+		authGroup.POST("/:provider/link", authHandler.Link)
+		authGroup.GET("/:provider/callback", authHandler.Callback)
 	}
 
 	// 房间相关路由
@@ -45,6 +203,58 @@ func (r *Router) SetupRoutes() {
 		roomGroup.POST("/join", roomHandler.JoinRoom)
 		roomGroup.GET("/list", roomHandler.GetRoomList)
 	}
+
+	// 游戏结果查询路由
+	resultHandler := NewResultHandler(r.resultService)
+	r.Engine.GET("/results", resultHandler.GetResults)
+
+	// 结果申诉路由
+	disputeHandler := NewDisputeHandler(r.disputes)
+	r.Engine.POST("/results/:id/dispute", disputeHandler.Create)
+
+	// 匹配统计路由
+	matchHandler := NewMatchHandler(r.matchStats)
+	r.Engine.GET("/match/stats", matchHandler.Stats)
+	r.Engine.GET("/match/abandons", matchHandler.Abandons)
+
+	// 管理相关路由
+	adminGroup := r.Engine.Group("/admin", adminMiddleware(r.cfg.AdminToken, r.signatures))
+	{
+		adminHandler := NewAdminHandler(r.cfg, r.userService, r.roomService, r.resultService, r.backupService, r.analytics, r.serverStats, r.words)
+		adminGroup.POST("/results/prune", adminHandler.PruneResults)
+		adminGroup.DELETE("/users/:username", adminHandler.DeleteUser)
+		adminGroup.GET("/backups", adminHandler.ListBackups)
+		adminGroup.POST("/backups", adminHandler.CreateBackup)
+		adminGroup.POST("/repair", adminHandler.Repair)
+		adminGroup.GET("/cache", adminHandler.CacheStats)
+		adminGroup.GET("/stats", adminHandler.Stats)
+		adminGroup.GET("/analytics", adminHandler.Analytics)
+		adminGroup.GET("/match/stats", matchHandler.AdminStats)
+		adminGroup.GET("/words", adminHandler.ListWords)
+		adminGroup.POST("/words", adminHandler.AddWords)
+		adminGroup.DELETE("/words/:word", adminHandler.RemoveWord)
+
+		moderationHandler := NewModerationHandler(r.moderation)
+		adminGroup.GET("/flags", moderationHandler.Queue)
+		adminGroup.POST("/flags/:user_id/review", moderationHandler.Review)
+		adminGroup.POST("/users/:username/titles", titleHandler.Grant)
+		adminGroup.POST("/config/reload", NewConfigHandler(r.reloader).Reload)
+
+		balanceHandler := NewBalanceHandler(r.balance)
+		adminGroup.GET("/balance", balanceHandler.Get)
+		adminGroup.POST("/balance", balanceHandler.Push)
+
+		adminGroup.POST("/tournaments", tournamentHandler.Create)
+		adminGroup.POST("/tournaments/:id/seed", tournamentHandler.Seed)
+		adminGroup.POST("/tournaments/:id/matches/:match/start", tournamentHandler.StartMatch)
+
+		adminGroup.POST("/events", eventHandler.Create)
+		adminGroup.DELETE("/events/:id", eventHandler.Remove)
+		adminGroup.POST("/playlist", playlistHandler.Set)
+
+		adminGroup.GET("/disputes", disputeHandler.Queue)
+		adminGroup.POST("/disputes/:id/review", disputeHandler.Review)
+	}
 }
 
 // Run 启动服务器
@@ -57,7 +267,8 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Admin-Token, X-Request-ID, X-Signature-Key, X-Signature-Timestamp, X-Signature-Nonce, X-Signature")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
@@ -68,3 +279,64 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// adminMiddleware 校验 X-Admin-Token 请求头，未配置令牌时拒绝所有管理请求；
+// 携带服务间签名的请求改为校验签名，不再需要管理令牌
+func adminMiddleware(token string, signatures *signatureVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if signedRequest(c) {
+			if verifySignature(c, signatures) {
+				c.Next()
+			}
+			return
+		}
+		if token == "" || c.GetHeader("X-Admin-Token") != token {
+			c.AbortWithStatusJSON(http.StatusForbidden, protocol.ErrorResponse{
+				Code:      http.StatusForbidden,
+				Message:   "无管理权限",
+				RequestID: requestID(c),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// slowMiddleware 统计请求处理耗时（包括处理函数调用的服务），超过阈值时记录慢请求
+func slowMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		// 未匹配路由的请求按同一项统计，避免任意路径撑大统计表
+		route := c.FullPath()
+		if route == "" {
+			route = "<unmatched>"
+		}
+		report.Track(report.SlowHTTP, c.Request.Method+" "+route, start, map[string]string{
+			"path":       c.Request.URL.Path,
+			"status":     strconv.Itoa(c.Writer.Status()),
+			"request_id": requestID(c),
+		})
+	}
+}
+
+// recoveryMiddleware 捕获处理函数中的 panic，上报错误并返回结构化的 500 响应
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				report.Panic(r, debug.Stack(), map[string]string{
+					"method":     c.Request.Method,
+					"path":       c.Request.URL.Path,
+					"request_id": requestID(c),
+				})
+				c.AbortWithStatusJSON(http.StatusInternalServerError, protocol.ErrorResponse{
+					Code:      http.StatusInternalServerError,
+					Message:   "服务器内部错误",
+					RequestID: requestID(c),
+				})
+			}
+		}()
+		c.Next()
+	}
+}
\ No newline at end of file
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"game/crypto"
+	"game/protocol"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 服务间签名请求使用的请求头，签名方法见 crypto.SignRequest
+const (
+	signatureKeyHeader       = "X-Signature-Key"       // 密钥ID
+	signatureTimestampHeader = "X-Signature-Timestamp" // Unix 秒
+	signatureNonceHeader     = "X-Signature-Nonce"     // 调用方生成的随机串，窗口内不能重复
+	signatureHeader          = "X-Signature"           // 十六进制 HMAC-SHA256
+	// signerKey 通过签名校验的调用方密钥ID在 gin.Context 中的键
+	signerKey = "signer"
+	// maxNonceLength nonce 的最大长度
+	maxNonceLength = 64
+	// defaultSignedBodyLimit 未配置请求体上限时，签名校验读取请求体的最大字节数
+	defaultSignedBodyLimit = 1 << 20
+)
+
+var (
+	errSigningDisabled   = errors.New("未配置签名密钥")
+	errSignatureHeaders  = errors.New("签名请求头不完整")
+	errUnknownSigningKey = errors.New("未知的签名密钥")
+	errSignatureExpired  = errors.New("签名时间戳超出允许范围")
+	errSignatureReplay   = errors.New("签名请求已被使用")
+	errSignatureMismatch = errors.New("签名无效")
+	errSignatureTooLarge = errors.New("请求体过大")
+)
+
+// signatureVerifier 校验服务间调用方的 HMAC 签名，并记录时间窗口内用过的 nonce 防止重放
+type signatureVerifier struct {
+	keys    map[string][]byte
+	window  time.Duration
+	maxBody int64
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // 密钥ID+nonce 到可以遗忘的时间
+	lastPrune time.Time
+}
+
+// newSignatureVerifier 创建签名校验器，空密钥会被忽略，没有可用密钥时返回 nil，此时所有签名请求都会被拒绝。
+// maxBody 为校验时读取请求体的最大字节数，不大于 0 时使用 defaultSignedBodyLimit
+func newSignatureVerifier(keys map[string]string, window time.Duration, maxBody int) *signatureVerifier {
+	v := &signatureVerifier{
+		keys:    make(map[string][]byte, len(keys)),
+		window:  window,
+		maxBody: int64(maxBody),
+		seen:    make(map[string]time.Time),
+	}
+	for id, secret := range keys {
+		if secret == "" {
+			log.Printf("签名密钥 %s 为空，已忽略", id)
+			continue
+		}
+		v.keys[id] = []byte(secret)
+	}
+	if len(v.keys) == 0 {
+		return nil
+	}
+	if v.maxBody <= 0 {
+		v.maxBody = defaultSignedBodyLimit
+	}
+	return v
+}
+
+// verify 校验请求的签名，成功时返回密钥ID。会读出请求体计算摘要，再放回供处理函数解码
+func (v *signatureVerifier) verify(c *gin.Context) (string, error) {
+	if v == nil {
+		return "", errSigningDisabled
+	}
+	keyID := c.GetHeader(signatureKeyHeader)
+	timestamp := c.GetHeader(signatureTimestampHeader)
+	nonce := c.GetHeader(signatureNonceHeader)
+	signature := c.GetHeader(signatureHeader)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" || len(nonce) > maxNonceLength {
+		return "", errSignatureHeaders
+	}
+	secret, ok := v.keys[keyID]
+	if !ok {
+		return "", errUnknownSigningKey
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", errSignatureHeaders
+	}
+	now := time.Now()
+	signedAt := time.Unix(sec, 0)
+	if signedAt.Before(now.Add(-v.window)) || signedAt.After(now.Add(v.window)) {
+		return "", errSignatureExpired
+	}
+
+	var body []byte
+	if c.Request.Body != nil {
+		// 签名校验之前的请求还未认证，先限制大小再读取
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, v.maxBody)
+		if body, err = io.ReadAll(c.Request.Body); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return "", errSignatureTooLarge
+			}
+			return "", errSignatureHeaders
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	expected := crypto.SignRequest(secret, c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", errSignatureMismatch
+	}
+	// 签名通过后才登记 nonce，避免伪造的请求占用调用方的 nonce
+	if !v.remember(keyID+":"+nonce, signedAt.Add(v.window), now) {
+		return "", errSignatureReplay
+	}
+	return keyID, nil
+}
+
+// remember 登记 nonce，已登记过时返回 false。过期的 nonce 按窗口周期清理，
+// 超出时间窗口的请求已被时间戳校验拒绝，不需要继续记住
+func (v *signatureVerifier) remember(id string, until, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.Sub(v.lastPrune) >= v.window {
+		for k, t := range v.seen {
+			if now.After(t) {
+				delete(v.seen, k)
+			}
+		}
+		v.lastPrune = now
+	}
+	if _, ok := v.seen[id]; ok {
+		return false
+	}
+	v.seen[id] = until
+	return true
+}
+
+// signedRequest 请求是否携带了服务间签名
+func signedRequest(c *gin.Context) bool {
+	return c.GetHeader(signatureKeyHeader) != ""
+}
+
+// verifySignature 校验服务间签名，失败时返回 401 并中止请求
+func verifySignature(c *gin.Context, v *signatureVerifier) bool {
+	keyID, err := v.verify(c)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if errors.Is(err, errSignatureTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.AbortWithStatusJSON(status, protocol.ErrorResponse{
+			Code:      status,
+			Message:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return false
+	}
+	c.Set(signerKey, keyID)
+	return true
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"game/models"
+	"game/protocol"
+	"game/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TitleHandler 定义称号 API 处理函数结构
+type TitleHandler struct {
+	titles service.TitleService
+}
+
+// NewTitleHandler 创建 TitleHandler 实例
+func NewTitleHandler(titles service.TitleService) *TitleHandler {
+	return &TitleHandler{titles: titles}
+}
+
+// List 返回用户拥有的称号、当前装备的称号以及全部称号列表
+func (h *TitleHandler) List(c *gin.Context) {
+	h.respond(c, c.Query("username"))
+}
+
+// Select 装备已拥有的称号，key 为空时卸下称号
+func (h *TitleHandler) Select(c *gin.Context) {
+	var req protocol.SelectTitleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := h.titles.Select(req.Username, req.SessionID, req.Key); err != nil {
+		titleError(c, err)
+		return
+	}
+	h.respond(c, req.Username)
+}
+
+// Grant 处理管理端授予称号请求，赛季结算时为排名靠前的玩家授予赛季称号
+func (h *TitleHandler) Grant(c *gin.Context) {
+	var req protocol.GrantTitleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	title, err := h.titles.Grant(c.Param("username"), req.TitleID, req.Season)
+	if err != nil {
+		titleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, titleInfo(title))
+}
+
+// respond 返回用户的称号列表
+func (h *TitleHandler) respond(c *gin.Context, username string) {
+	owned, active, err := h.titles.Titles(username)
+	if err != nil {
+		titleError(c, err)
+		return
+	}
+	resp := protocol.TitleListResponse{
+		Active:  active,
+		Owned:   make([]protocol.TitleInfo, 0, len(owned)),
+		Catalog: make([]protocol.TitleInfo, 0, len(h.titles.Catalog())),
+	}
+	for _, t := range owned {
+		resp.Owned = append(resp.Owned, titleInfo(t))
+	}
+	for _, def := range h.titles.Catalog() {
+		resp.Catalog = append(resp.Catalog, protocol.TitleInfo{
+			Key:         def.ID,
+			ID:          def.ID,
+			Name:        def.Name,
+			Kind:        def.Kind,
+			Source:      def.Source,
+			Description: def.Description,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// titleError 按错误类型返回称号接口的错误响应
+func titleError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, service.ErrTitleUserNotFound), errors.Is(err, service.ErrUnknownTitle):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrTitleSession):
+		status = http.StatusUnauthorized
+	}
+	c.JSON(status, protocol.ErrorResponse{
+		Code:      status,
+		Message:   err.Error(),
+		RequestID: requestID(c),
+	})
+}
+
+// titleInfo 将用户拥有的称号转换为协议中的格式
+func titleInfo(t models.Title) protocol.TitleInfo {
+	info := protocol.TitleInfo{
+		Key:    t.Key(),
+		ID:     t.ID,
+		Name:   service.TitleDisplay(t),
+		Season: t.Season,
+	}
+	if def, ok := service.FindTitle(t.ID); ok {
+		info.Kind, info.Source, info.Description = def.Kind, def.Source, def.Description
+	}
+	granted := t.GrantedAt
+	info.GrantedAt = &granted
+	return info
+}
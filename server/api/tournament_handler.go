@@ -0,0 +1,141 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"game/protocol"
+	"game/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TournamentHandler 定义赛事 API 处理函数结构
+type TournamentHandler struct {
+	tournaments service.TournamentService
+}
+
+// NewTournamentHandler 创建 TournamentHandler 实例
+func NewTournamentHandler(tournaments service.TournamentService) *TournamentHandler {
+	return &TournamentHandler{tournaments: tournaments}
+}
+
+// List 返回所有赛事
+func (h *TournamentHandler) List(c *gin.Context) {
+	tournaments := h.tournaments.List()
+	resp := protocol.TournamentListResponse{Tournaments: make([]protocol.TournamentInfo, 0, len(tournaments))}
+	for _, t := range tournaments {
+		resp.Tournaments = append(resp.Tournaments, service.TournamentInfo(t))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Get 返回赛事信息，生成对阵表后附带种子和对阵
+func (h *TournamentHandler) Get(c *gin.Context) {
+	t, err := h.tournaments.Get(c.Param("id"))
+	if err != nil {
+		tournamentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, service.TournamentInfo(t))
+}
+
+// Live 返回所有正在进行的赛事比赛，观战者据此选择比赛房间观战
+func (h *TournamentHandler) Live(c *gin.Context) {
+	live := h.tournaments.LiveMatches()
+	resp := protocol.LiveMatchListResponse{Matches: make([]protocol.LiveMatchInfo, 0, len(live))}
+	for _, l := range live {
+		resp.Matches = append(resp.Matches, protocol.LiveMatchInfo{
+			TournamentID:   l.TournamentID,
+			TournamentName: l.TournamentName,
+			Match:          service.BracketMatchInfo(l.Match),
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Register 处理玩家报名请求
+func (h *TournamentHandler) Register(c *gin.Context) {
+	var req protocol.TournamentRegisterRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	t, err := h.tournaments.Register(c.Param("id"), req.Username, req.SessionID)
+	if err != nil {
+		tournamentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, service.TournamentInfo(t))
+}
+
+// Create 处理管理端创建赛事请求
+func (h *TournamentHandler) Create(c *gin.Context) {
+	var req protocol.CreateTournamentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	t, err := h.tournaments.Create(req)
+	if err != nil {
+		field := "name"
+		switch {
+		case errors.Is(err, service.ErrTournamentFormat):
+			field = "format"
+		case errors.Is(err, service.ErrTournamentGroupSize):
+			field = "group_size"
+		case errors.Is(err, service.ErrTournamentSchedule) && req.BreakMinutes < 0:
+			field = "break_minutes"
+		case errors.Is(err, service.ErrTournamentSchedule):
+			field = "grace_minutes"
+		}
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   err.Error(),
+			Field:     field,
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, service.TournamentInfo(t))
+}
+
+// Seed 处理管理端截止报名并生成对阵表的请求
+func (h *TournamentHandler) Seed(c *gin.Context) {
+	t, err := h.tournaments.Seed(c.Param("id"))
+	if err != nil {
+		tournamentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, service.TournamentInfo(t))
+}
+
+// StartMatch 处理管理端开始比赛的请求，为双方创建房间，双方需要在线
+func (h *TournamentHandler) StartMatch(c *gin.Context) {
+	t, err := h.tournaments.StartMatch(c.Param("id"), c.Param("match"))
+	if err != nil {
+		tournamentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, service.TournamentInfo(t))
+}
+
+// tournamentError 按赛事业务错误返回对应的状态码
+func tournamentError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, service.ErrTournamentNotFound), errors.Is(err, service.ErrTournamentUserNotFound),
+		errors.Is(err, service.ErrBracketMatchNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrTournamentSession):
+		status = http.StatusUnauthorized
+	case errors.Is(err, service.ErrTournamentClosed), errors.Is(err, service.ErrTournamentFull), errors.Is(err, service.ErrTournamentRegistered),
+		errors.Is(err, service.ErrTournamentNotRunning), errors.Is(err, service.ErrBracketMatchNotReady):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrTournamentHost):
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, protocol.ErrorResponse{
+		Code:      status,
+		Message:   err.Error(),
+		RequestID: requestID(c),
+	})
+}
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"game/models"
 	"game/protocol"
 	"game/service"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,68 +13,257 @@ import (
 // UserHandler 定义用户 API 处理函数结构
 type UserHandler struct {
 	userService service.UserService
+	regions     *service.Regions
 }
 
 // NewUserHandler 创建 UserHandler 实例
-func NewUserHandler(userService service.UserService) *UserHandler {
+func NewUserHandler(userService service.UserService, regions *service.Regions) *UserHandler {
 	return &UserHandler{
 		userService: userService,
+		regions:     regions,
 	}
 }
 
 // Register 处理用户注册请求
 func (h *UserHandler) Register(c *gin.Context) {
 	var req protocol.RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
-			Code:    http.StatusBadRequest,
-			Message: "请求格式错误",
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// 调用 Service 层处理注册逻辑
-	success, message := h.userService.Register(req)
+	success, message, code := h.userService.Register(req)
 
 	// 返回响应
 	c.JSON(http.StatusOK, protocol.RegisterResponse{
 		Success: success,
 		Message: message,
+		Code:    code,
 	})
 }
 
 // Login 处理用户登录请求
 func (h *UserHandler) Login(c *gin.Context) {
 	var req protocol.LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
-			Code:    http.StatusBadRequest,
-			Message: "请求格式错误",
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// 调用 Service 层处理登录逻辑
 	success, message, token := h.userService.Login(req)
+	h.userService.RecordLogin(models.LoginRecord{
+		Username:  req.Username,
+		IP:        c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Method:    "password",
+		Success:   success,
+		Reason:    failureReason(success, message),
+	})
+
+	// 登录成功后记录会话、设备信息和区域，要求记住登录时签发刷新令牌
+	sessionID, region, refreshToken := "", "", ""
+	var rejoin *protocol.RoomInfo
+	if success {
+		rejoin = rejoinRoom(h.userService, token)
+		region = h.regions.Resolve(req.Region, req.Latencies)
+		sessionID = h.userService.StartSession(token, c.GetHeader("User-Agent"), c.ClientIP(), region).ID
+		if req.RememberMe {
+			refreshToken = h.userService.IssueRefreshToken(token, c.GetHeader("User-Agent"))
+		}
+	}
 
 	// 返回响应
 	c.JSON(http.StatusOK, protocol.LoginResponse{
-		Success: success,
-		Message: message,
-		Token:   token,
+		Success:      success,
+		Message:      message,
+		Token:        token,
+		SessionID:    sessionID,
+		Region:       region,
+		RefreshToken: refreshToken,
+		RejoinRoom:   rejoin,
+	})
+}
+
+// Refresh 使用刷新令牌重新登录，成功时开始新的会话，响应与密码登录相同并附带轮换后的刷新令牌
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req protocol.RefreshRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	success, message, username, refreshToken := h.userService.Refresh(req.RefreshToken, c.GetHeader("User-Agent"))
+	if username != "" {
+		h.userService.RecordLogin(models.LoginRecord{
+			Username:  username,
+			IP:        c.ClientIP(),
+			UserAgent: c.GetHeader("User-Agent"),
+			Method:    "refresh",
+			Success:   success,
+			Reason:    failureReason(success, message),
+		})
+	}
+
+	sessionID, region := "", ""
+	var rejoin *protocol.RoomInfo
+	if success {
+		rejoin = rejoinRoom(h.userService, username)
+		region = h.regions.Resolve(req.Region, req.Latencies)
+		sessionID = h.userService.StartSession(username, c.GetHeader("User-Agent"), c.ClientIP(), region).ID
+	}
+	status := http.StatusOK
+	if !success && refreshToken == "" {
+		status = http.StatusUnauthorized
+	}
+	c.JSON(status, protocol.LoginResponse{
+		Success:      success,
+		Message:      message,
+		Token:        username,
+		SessionID:    sessionID,
+		Region:       region,
+		RefreshToken: refreshToken,
+		RejoinRoom:   rejoin,
 	})
 }
 
+// Sessions 返回用户当前的登录会话列表，session 参数为发起请求的会话，必须属于该用户，同时用于标记当前设备
+func (h *UserHandler) Sessions(c *gin.Context) {
+	username := c.Query("username")
+	if username == "" {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "用户名不能为空",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	current := c.Query("session")
+	if !h.authorize(c, username, current) {
+		return
+	}
+
+	sessions := h.userService.ListSessions(username)
+	list := make([]protocol.SessionInfo, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, sessionInfo(s, current))
+	}
+	c.JSON(http.StatusOK, protocol.SessionListResponse{Sessions: list})
+}
+
+// sessionInfo 将登录会话转换为协议中的格式，current 为发起请求的会话
+func sessionInfo(s models.Session, current string) protocol.SessionInfo {
+	return protocol.SessionInfo{
+		ID:        service.SessionHandle(s.ID),
+		Device:    s.Device,
+		UserAgent: s.UserAgent,
+		IP:        s.IP,
+		CreatedAt: s.CreatedAt,
+		LastSeen:  s.LastSeen,
+		Region:    s.Region,
+		Current:   s.ID == current,
+	}
+}
+
+// Logins 返回用户最近的登录记录，包括失败的尝试；session 参数必须是该用户的登录会话
+func (h *UserHandler) Logins(c *gin.Context) {
+	username := c.Query("username")
+	if username == "" {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "用户名不能为空",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if !h.authorize(c, username, c.Query("session")) {
+		return
+	}
+
+	records := h.userService.LoginHistory(username)
+	list := make([]protocol.LoginRecordInfo, 0, len(records))
+	for _, r := range records {
+		list = append(list, loginRecordInfo(r))
+	}
+	c.JSON(http.StatusOK, protocol.LoginHistoryResponse{Logins: list})
+}
+
+// loginRecordInfo 将登录记录转换为协议中的格式
+func loginRecordInfo(r models.LoginRecord) protocol.LoginRecordInfo {
+	return protocol.LoginRecordInfo{
+		Time:      r.Time,
+		IP:        r.IP,
+		Device:    r.Device,
+		UserAgent: r.UserAgent,
+		Method:    r.Method,
+		Success:   r.Success,
+		Reason:    r.Reason,
+		NewIP:     r.NewIP,
+	}
+}
+
+// authorize 校验 sessionID 是用户本人的登录会话，失败时返回 401
+func (h *UserHandler) authorize(c *gin.Context, username, sessionID string) bool {
+	if h.userService.Authorize(username, sessionID) {
+		return true
+	}
+	c.JSON(http.StatusUnauthorized, protocol.ErrorResponse{
+		Code:      http.StatusUnauthorized,
+		Message:   "会话已失效，请重新登录",
+		RequestID: requestID(c),
+	})
+	return false
+}
+
+// rejoinRoom 返回登录成功的用户可以重新加入的房间，没有时返回 nil
+func rejoinRoom(userService service.UserService, username string) *protocol.RoomInfo {
+	room := userService.RejoinRoom(username)
+	if room == nil {
+		return nil
+	}
+	info := roomInfo(*room)
+	return &info
+}
+
+// failureReason 登录失败时返回提示信息作为记录的失败原因，成功时为空
+func failureReason(success bool, message string) string {
+	if success {
+		return ""
+	}
+	return message
+}
+
+// RevokeSession 远程注销指定会话，使用该会话的 WebSocket 连接会被断开；路径中的 id 为会话列表返回的句柄，
+// session 参数为发起请求的会话，必须属于该用户
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	username := c.Query("username")
+	if username == "" {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "用户名不能为空",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if !h.authorize(c, username, c.Query("session")) {
+		return
+	}
+
+	if !h.userService.RevokeSession(username, c.Param("id")) {
+		c.JSON(http.StatusNotFound, protocol.ErrorResponse{
+			Code:      http.StatusNotFound,
+			Message:   "会话不存在",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "会话已注销"})
+}
+
 // Logout 处理用户登出请求
 func (h *UserHandler) Logout(c *gin.Context) {
 	var req struct {
 		Username string `json:"username"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
-			Code:    http.StatusBadRequest,
-			Message: "请求格式错误",
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -87,3 +278,311 @@ func (h *UserHandler) Logout(c *gin.Context) {
 func (h *UserHandler) Test(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "服务器运行正常"})
 }
+
+// ChangePassword 处理修改密码请求，成功后其他设备上的会话和连接被注销
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req protocol.ChangePasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	success, message, code := h.userService.ChangePassword(req)
+	c.JSON(http.StatusOK, protocol.RegisterResponse{
+		Success: success,
+		Message: message,
+		Code:    code,
+	})
+}
+
+// ChangeUsername 处理修改用户名请求，成功后用户的连接被断开，需要用新用户名重新登录
+func (h *UserHandler) ChangeUsername(c *gin.Context) {
+	var req protocol.ChangeUsernameRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	success, message := h.userService.ChangeUsername(req)
+	c.JSON(http.StatusOK, protocol.RegisterResponse{
+		Success: success,
+		Message: message,
+	})
+}
+
+// Lookup 按当前或曾用的用户名查找用户
+func (h *UserHandler) Lookup(c *gin.Context) {
+	username := c.Query("username")
+	if username == "" {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "用户名不能为空",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	user := h.userService.LookupUser(username)
+	if user == nil {
+		c.JSON(http.StatusNotFound, protocol.ErrorResponse{
+			Code:      http.StatusNotFound,
+			Message:   "用户不存在",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	resp := protocol.UserLookupResponse{
+		ID:            user.UserID,
+		Username:      user.Username,
+		PreviousNames: make([]string, 0, len(user.PreviousNames)),
+		AvatarURL:     service.AvatarURL(*user),
+	}
+	for _, prev := range user.PreviousNames {
+		resp.PreviousNames = append(resp.PreviousNames, prev.Username)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RecentOpponents 返回用户最近交手过的对手及其在线状态，用于快速邀请
+func (h *UserHandler) RecentOpponents(c *gin.Context) {
+	username := c.Query("username")
+	if username == "" {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "用户名不能为空",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	opponents := h.userService.RecentOpponents(username)
+	list := make([]protocol.RecentOpponentInfo, 0, len(opponents))
+	for _, o := range opponents {
+		list = append(list, protocol.RecentOpponentInfo{
+			Username:   o.User.Username,
+			AvatarURL:  service.AvatarURL(o.User),
+			Online:     o.User.Online,
+			LastPlayed: o.LastPlayed,
+			Matches:    o.Matches,
+			Wins:       o.Wins,
+			Losses:     o.Losses,
+		})
+	}
+	c.JSON(http.StatusOK, protocol.RecentOpponentsResponse{Opponents: list})
+}
+
+// RoomFilters 返回用户保存的房间列表筛选条件
+func (h *UserHandler) RoomFilters(c *gin.Context) {
+	username := c.Query("username")
+	if username == "" {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "用户名不能为空",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	filters := h.userService.RoomFilters(username)
+	list := make([]protocol.RoomFilterInfo, 0, len(filters))
+	for _, f := range filters {
+		list = append(list, roomFilterInfo(f))
+	}
+	c.JSON(http.StatusOK, protocol.RoomFilterListResponse{Filters: list})
+}
+
+// roomFilterInfo 将保存的房间筛选条件转换为协议中的格式
+func roomFilterInfo(f models.RoomFilter) protocol.RoomFilterInfo {
+	return protocol.RoomFilterInfo{
+		Name:    f.Name,
+		Map:     f.Map,
+		Region:  f.Region,
+		Mode:    f.Mode,
+		NotFull: f.NotFull,
+	}
+}
+
+// SaveRoomFilter 保存房间列表筛选条件，之后可通过名称在房间列表中使用
+func (h *UserHandler) SaveRoomFilter(c *gin.Context) {
+	var req protocol.SaveRoomFilterRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	success, message := h.userService.SaveRoomFilter(req.Username, req.SessionID, models.RoomFilter{
+		Name:    req.Filter.Name,
+		Map:     req.Filter.Map,
+		Region:  req.Filter.Region,
+		Mode:    req.Filter.Mode,
+		NotFull: req.Filter.NotFull,
+	})
+	c.JSON(http.StatusOK, protocol.RegisterResponse{
+		Success: success,
+		Message: message,
+	})
+}
+
+// SetSearchPrivacy 设置是否在玩家搜索中隐藏自己
+func (h *UserHandler) SetSearchPrivacy(c *gin.Context) {
+	var req protocol.SearchPrivacyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	success, message := h.userService.SetSearchPrivacy(req.Username, req.SessionID, req.HideFromSearch)
+	c.JSON(http.StatusOK, protocol.RegisterResponse{
+		Success: success,
+		Message: message,
+	})
+}
+
+// DeleteRoomFilter 删除房间列表筛选条件
+func (h *UserHandler) DeleteRoomFilter(c *gin.Context) {
+	success, message := h.userService.DeleteRoomFilter(c.Query("username"), c.Query("session_id"), c.Param("name"))
+	c.JSON(http.StatusOK, protocol.RegisterResponse{
+		Success: success,
+		Message: message,
+	})
+}
+
+// ChangeEmail 处理修改邮箱请求，向新邮箱发送确认令牌
+func (h *UserHandler) ChangeEmail(c *gin.Context) {
+	var req protocol.ChangeEmailRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	success, message := h.userService.ChangeEmail(req)
+	c.JSON(http.StatusOK, protocol.RegisterResponse{
+		Success: success,
+		Message: message,
+	})
+}
+
+// ConfirmEmail 处理确认修改邮箱请求，令牌正确时替换邮箱
+func (h *UserHandler) ConfirmEmail(c *gin.Context) {
+	var req protocol.ConfirmEmailRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	success, message := h.userService.ConfirmEmail(req)
+	c.JSON(http.StatusOK, protocol.RegisterResponse{
+		Success: success,
+		Message: message,
+	})
+}
+
+// DeleteAccount 处理用户注销账号请求
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	var req protocol.DeleteAccountRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 调用 Service 层处理注销逻辑
+	success, message := h.userService.DeleteAccount(req)
+
+	// 返回响应
+	c.JSON(http.StatusOK, protocol.RegisterResponse{
+		Success: success,
+		Message: message,
+	})
+}
+
+// Export 处理用户数据导出请求，session 参数必须是该用户的登录会话
+func (h *UserHandler) Export(c *gin.Context) {
+	username := c.Query("username")
+	if username == "" {
+		c.JSON(http.StatusBadRequest, protocol.ErrorResponse{
+			Code:      http.StatusBadRequest,
+			Message:   "用户名不能为空",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if !h.authorize(c, username, c.Query("session")) {
+		return
+	}
+
+	// 调用 Service 层汇总数据
+	data := h.userService.ExportData(username)
+	if data == nil {
+		c.JSON(http.StatusNotFound, protocol.ErrorResponse{
+			Code:      http.StatusNotFound,
+			Message:   "用户不存在",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	resp := protocol.UserExportResponse{
+		Results:     make([]protocol.ResultInfo, 0, len(data.Results)),
+		Sessions:    make([]protocol.SessionInfo, 0, len(data.Sessions)),
+		Logins:      make([]protocol.LoginRecordInfo, 0, len(data.Logins)),
+		Titles:      make([]protocol.TitleInfo, 0, len(data.User.Titles)),
+		ActiveTitle: data.User.ActiveTitle,
+		RoomFilters: make([]protocol.RoomFilterInfo, 0, len(data.User.RoomFilters)),
+		Invites:     make([]protocol.InviteExportInfo, 0, len(data.Invites)),
+		Tournaments: make([]protocol.TournamentInfo, 0, len(data.Tournaments)),
+		Disputes:    make([]protocol.DisputeInfo, 0, len(data.Disputes)),
+		ChatHistory: data.Chat,
+	}
+	for _, r := range data.Results {
+		resp.Results = append(resp.Results, resultInfo(r))
+	}
+	for _, s := range data.Sessions {
+		resp.Sessions = append(resp.Sessions, sessionInfo(s, c.Query("session")))
+	}
+	for _, r := range data.Logins {
+		resp.Logins = append(resp.Logins, loginRecordInfo(r))
+	}
+	for _, t := range data.User.Titles {
+		resp.Titles = append(resp.Titles, titleInfo(t))
+	}
+	for _, f := range data.User.RoomFilters {
+		resp.RoomFilters = append(resp.RoomFilters, roomFilterInfo(f))
+	}
+	for _, invite := range data.Invites {
+		resp.Invites = append(resp.Invites, protocol.InviteExportInfo{
+			ID:        invite.ID,
+			Sent:      invite.FromID == data.User.UserID,
+			From:      invite.From,
+			RoomID:    invite.RoomID,
+			RoomName:  invite.RoomName,
+			CreatedAt: invite.CreatedAt,
+			ExpiresAt: invite.ExpiresAt,
+		})
+	}
+	if data.LadderPosition > 0 {
+		resp.Ladder = &protocol.LadderEntryInfo{Position: data.LadderPosition, Username: data.User.Username}
+	}
+	for _, t := range data.Tournaments {
+		resp.Tournaments = append(resp.Tournaments, service.TournamentInfo(t))
+	}
+	for _, d := range data.Disputes {
+		resp.Disputes = append(resp.Disputes, disputeInfo(d))
+	}
+
+	// 返回响应
+	c.Header("Content-Disposition", "attachment; filename=\""+username+"-export.json\"")
+	resp.ExportedAt = time.Now()
+	resp.Profile = protocol.ProfileInfo{
+		UserID:    data.User.UserID,
+		Username:  data.User.Username,
+		AvatarURL: service.AvatarURL(data.User),
+		Email:     data.User.Email,
+		Online:    data.User.Online,
+		LoginTime: data.User.LoginTime,
+		RoomID:    data.User.RoomID,
+	}
+	resp.Stats = protocol.StatsInfo{
+		Matches:    data.Stats.Matches,
+		Wins:       data.Stats.Wins,
+		Losses:     data.Stats.Losses,
+		Kills:      data.Stats.Kills,
+		Deaths:     data.Stats.Deaths,
+		ShotsFired: data.Stats.ShotsFired,
+		ShotsHit:   data.Stats.ShotsHit,
+		Accuracy:   data.Stats.Accuracy,
+	}
+	c.JSON(http.StatusOK, resp)
+}
\ No newline at end of file
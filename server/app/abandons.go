@@ -0,0 +1,122 @@
+package app
+
+import (
+	"log"
+	"time"
+
+	"game/models"
+	"game/protocol"
+)
+
+// leaverWindow 计算弃赛惩罚时考虑的最近对局数
+const leaverWindow = 10
+
+// abandonCounter 累计掉线和弃赛次数
+type abandonCounter struct {
+	stats protocol.AbandonStats
+}
+
+// addPlayer 计入一名玩家在一局中的表现
+func (a *abandonCounter) addPlayer(p models.PlayerResult) {
+	a.stats.Disconnects += p.Disconnects
+	a.stats.Reconnects += p.Reconnects
+}
+
+// result 计算比例并返回统计
+func (a *abandonCounter) result() protocol.AbandonStats {
+	st := a.stats
+	if st.Matches > 0 {
+		st.AbandonRate = float64(st.Abandons) / float64(st.Matches)
+	}
+	if st.Disconnects > 0 {
+		st.ReconnectRate = float64(st.Reconnects) / float64(st.Disconnects)
+	}
+	return st
+}
+
+// playerAbandons 统计玩家在 results 中的掉线和弃赛，userID 对应的玩家改过名时按其在每局中使用的用户名统计
+func playerAbandons(userID, username string, results []models.GameResult) protocol.AbandonStats {
+	var counter abandonCounter
+	for _, r := range results {
+		name := r.NameOf(userID)
+		if name == "" {
+			name = username
+		}
+		for _, p := range r.Players {
+			if p.Username != name {
+				continue
+			}
+			counter.stats.Matches++
+			if p.Forfeited {
+				counter.stats.Abandons++
+			}
+			counter.addPlayer(p)
+		}
+	}
+	return counter.result()
+}
+
+// serverAbandons 统计 results 中有玩家弃赛的对局数和所有玩家的掉线重连次数
+func serverAbandons(results []models.GameResult) protocol.AbandonStats {
+	var counter abandonCounter
+	for _, r := range results {
+		counter.stats.Matches++
+		abandoned := false
+		for _, p := range r.Players {
+			abandoned = abandoned || p.Forfeited
+			counter.addPlayer(p)
+		}
+		if abandoned {
+			counter.stats.Abandons++
+		}
+	}
+	return counter.result()
+}
+
+// recentResults 返回用户最近的对局结果，最新的在前
+func (h *Hub) recentResults(username string) (string, []models.GameResult) {
+	var userID string
+	if user := h.userStore.FindByUsername(username); user != nil {
+		userID = user.UserID
+	}
+	return userID, h.resultStore.FindByUser(userID, username)
+}
+
+// PlayerAbandons 返回玩家的弃赛统计和剩余的弃赛惩罚冷却时间，供匹配惩罚参考
+func (h *Hub) PlayerAbandons(username string) protocol.PlayerAbandonInfo {
+	userID, results := h.recentResults(username)
+	info := protocol.PlayerAbandonInfo{
+		Username: username,
+		Recent:   playerAbandons(userID, username, results[:min(len(results), leaverWindow)]),
+		Total:    playerAbandons(userID, username, results),
+	}
+	now := h.clock.Now()
+	h.matcher.mu.Lock()
+	if until, ok := h.matcher.leavers[username]; ok && now.Before(until) {
+		info.Cooldown = int(until.Sub(now).Seconds() + 0.5)
+	}
+	h.matcher.mu.Unlock()
+	return info
+}
+
+// penalizeLeavers 对局结束后为弃赛的玩家设置匹配冷却，时长为 LeaverCooldown 乘以最近 leaverWindow 局中的弃赛次数
+func (h *Hub) penalizeLeavers(result models.GameResult) {
+	if h.cfg.LeaverCooldown <= 0 {
+		return
+	}
+	now := h.clock.Now()
+	for _, p := range result.Players {
+		if !p.Forfeited || models.IsBot(p.Username) {
+			continue
+		}
+		userID, results := h.recentResults(p.Username)
+		recent := playerAbandons(userID, p.Username, results[:min(len(results), leaverWindow)])
+		until := now.Add(h.cfg.LeaverCooldown * time.Duration(max(recent.Abandons, 1)))
+		h.matcher.mu.Lock()
+		if until.After(h.matcher.leavers[p.Username]) {
+			h.matcher.leavers[p.Username] = until
+		}
+		h.matcher.mu.Unlock()
+		log.Printf("用户 %s 中途弃赛（最近 %d 局中 %d 次），%s 前不能匹配", p.Username, recent.Matches, recent.Abandons, until.Format(time.DateTime))
+	}
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"game/models"
+	"game/protocol"
+
+	"github.com/gin-gonic/gin"
+)
+
+// announceTimeout 向主服务器宣告的请求超时
+const announceTimeout = 10 * time.Second
+
+// serverModes 服务器支持的玩法：两人对战、三人以上的混战，以及两种地图目标
+var serverModes = []string{"duel", "ffa", models.ObjectiveKingOfTheHill, models.ObjectiveShrinkingZone}
+
+// serverInfo 汇总本服务器的公开信息
+func (s *Server) serverInfo() protocol.ServerInfo {
+	maps := make([]string, 0, len(mapObstacles))
+	for name := range mapObstacles {
+		maps = append(maps, name)
+	}
+	slices.Sort(maps)
+	return protocol.ServerInfo{
+		Name:       s.cfg.ServerName,
+		Addr:       s.cfg.PublicAddr,
+		Regions:    s.cfg.Regions,
+		Players:    s.hub.ConnectionCount(),
+		MaxPlayers: s.cfg.MaxConnections,
+		Rooms:      len(s.roomStore.GetAll()),
+		Modes:      serverModes,
+		Maps:       maps,
+	}
+}
+
+// handleServerInfo 处理服务器信息查询请求
+func (s *Server) handleServerInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, s.serverInfo())
+}
+
+// announcer 定期向主服务器宣告本服务器，主服务器据此维护公开服务器列表
+func (s *Server) announcer() {
+	client := &http.Client{Timeout: announceTimeout}
+	ticker := s.hub.clock.NewTicker(s.cfg.AnnounceInterval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		if err := s.announce(client); err != nil {
+			if !failing {
+				log.Printf("向主服务器宣告失败: %v", err)
+			}
+			failing = true
+		} else if failing {
+			log.Println("已恢复向主服务器宣告")
+			failing = false
+		}
+		<-ticker.C()
+	}
+}
+
+// announce 向主服务器提交一次服务器信息
+func (s *Server) announce(client *http.Client) error {
+	body, err := json.Marshal(s.serverInfo())
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(s.cfg.MasterServerURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("主服务器返回 %s", resp.Status)
+	}
+	return nil
+}
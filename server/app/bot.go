@@ -0,0 +1,325 @@
+package app
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"game/models"
+	"game/protocol"
+	"game/sim"
+)
+
+// 战场参数，与客户端 ShootingGame.vue 保持一致
+const (
+	fieldWidth   = 800.0
+	fieldHeight  = 400.0
+	playerWidth  = 20.0
+	playerHeight = 60.0
+	bulletSpeed  = 7.0 // 每帧移动像素
+)
+
+// botTickInterval 客户端的帧间隔（60 帧），机器人参数和子弹速度按该间隔计算，逻辑帧率不同时按比例换算
+const botTickInterval = time.Second / 60
+
+// 难度配置到机器人参数的换算：准确度决定瞄准偏差和开火时允许的纵向偏差，攻击性决定开火间隔
+const (
+	botMaxAimJitter     = 80.0                    // 准确度为 0 时的瞄准偏差上限
+	botMinAimTolerance  = 10.0                    // 准确度为 1 时开火允许的纵向偏差
+	botAimToleranceSpan = 40.0                    // 准确度从 1 降到 0 时开火允许的纵向偏差增加量
+	botMaxFireCooldown  = 1400 * time.Millisecond // 攻击性为 0 时的开火间隔
+	botFireCooldownSpan = 1000 * time.Millisecond // 攻击性从 0 升到 1 时开火间隔的缩短量
+)
+
+// botProfile 定义机器人难度参数
+type botProfile struct {
+	moveSpeed    float64       // 每帧移动像素
+	fireCooldown time.Duration // 开火间隔
+	aimJitter    float64       // 瞄准位置的随机偏差上限
+	aimTolerance float64       // 与对手纵向距离小于该值时开火
+	reaction     time.Duration // 看到对手移动到开始跟随的延迟
+}
+
+// newBotProfile 将数据文件中的难度配置换算为机器人参数
+func newBotProfile(p models.BotProfile) botProfile {
+	return botProfile{
+		moveSpeed:    p.MoveSpeed,
+		fireCooldown: botMaxFireCooldown - time.Duration(p.Aggression*float64(botFireCooldownSpan)),
+		aimJitter:    botMaxAimJitter * (1 - p.Accuracy),
+		aimTolerance: botMinAimTolerance + botAimToleranceSpan*(1-p.Accuracy),
+		reaction:     time.Duration(p.ReactionMs) * time.Millisecond,
+	}
+}
+
+// botProfileFor 按机器人名字中的难度查找参数；难度已在热更新中移除时使用服务器默认难度，再退回第一个难度
+func (h *Hub) botProfileFor(name string) botProfile {
+	if p, ok := h.bots.Get(strings.TrimPrefix(name, models.BotPrefix)); ok {
+		return newBotProfile(p)
+	}
+	if p, ok := h.bots.Get(h.cfg.BotDifficulty); ok {
+		return newBotProfile(p)
+	}
+	return newBotProfile(h.bots.All()[0])
+}
+
+// botName 返回指定难度机器人的用户名，难度编码在名字中，会话据此恢复参数
+func botName(difficulty string) string {
+	return models.BotPrefix + difficulty
+}
+
+// botSighting 机器人看到的一次对手移动，经过反应时间后才会跟随
+type botSighting struct {
+	y  float64
+	at time.Time
+}
+
+// botBullet 机器人发射的子弹，id 为服务器分配的实体ID
+type botBullet struct {
+	id       string
+	x, y, vx float64
+}
+
+// botPlayer 服务器端机器人玩家，由游戏会话的逻辑帧驱动。
+// 客户端只结算自己子弹的伤害，因此机器人子弹的命中由这里模拟并上报
+type botPlayer struct {
+	name     string
+	opponent string
+	profile  botProfile
+	rng      sim.RNG
+
+	x, y      float64
+	dir       float64 // 开火方向：左侧为 1，右侧为 -1
+	aimOffset float64
+	lastFire  time.Time
+	bullets   []botBullet
+
+	opponentX, opponentY float64       // 对手的实际位置，用于结算子弹
+	trackY               float64       // 机器人反应过来的对手位置，用于瞄准和开火
+	sightings            []botSighting // 尚未反应过来的对手移动，按时间排列
+}
+
+// newBotPlayer 创建机器人，slot 为其在房间玩家列表中的位置，0 在左侧，其余在右侧
+func newBotPlayer(name, opponent string, slot int, profile botProfile, rng sim.RNG) *botPlayer {
+	b := &botPlayer{
+		name:      name,
+		opponent:  opponent,
+		profile:   profile,
+		rng:       rng,
+		y:         fieldHeight/2 - playerHeight/2,
+		opponentY: fieldHeight/2 - playerHeight/2,
+		trackY:    fieldHeight/2 - playerHeight/2,
+	}
+	left, right := 50.0, fieldWidth-50-playerWidth
+	if slot == 0 {
+		b.x, b.dir, b.opponentX = left, 1, right
+	} else {
+		b.x, b.dir, b.opponentX = right, -1, left
+	}
+	b.retarget()
+	return b
+}
+
+// retarget 重新抽取瞄准偏差
+func (b *botPlayer) retarget() {
+	b.aimOffset = (b.rng.Float64()*2 - 1) * b.profile.aimJitter
+}
+
+// observe 处理对手上报的动作，更新机器人掌握的对手状态
+func (b *botPlayer) observe(username string, msg protocol.Message, now time.Time) {
+	switch msg.Type {
+	case protocol.MsgTypePlayerAction:
+		var action protocol.PlayerAction
+		if protocol.DecodeBytes(msg.Payload, &action) == nil && username == b.opponent && action.Action == "move_y" {
+			b.opponentY = action.Value
+			b.sightings = append(b.sightings, botSighting{y: action.Value, at: now})
+		}
+	}
+}
+
+// react 跟随已经过反应时间的对手移动
+func (b *botPlayer) react(now time.Time) {
+	n := 0
+	for n < len(b.sightings) && now.Sub(b.sightings[n].at) >= b.profile.reaction {
+		b.trackY = b.sightings[n].y
+		n++
+	}
+	b.sightings = append(b.sightings[:0], b.sightings[n:]...)
+}
+
+// tickBot 推进机器人一帧：移动、开火、结算子弹，对局结束时返回 true
+func (s *roomSession) tickBot(now time.Time) bool {
+	b := s.bot
+	frames := float64(s.tickInterval()) / float64(botTickInterval)
+	b.react(now)
+
+	// 向对手位置靠拢，保留一定偏差模拟瞄准误差
+	goal := clamp(b.trackY+b.aimOffset, 0, fieldHeight-playerHeight)
+	if step := goal - b.y; step != 0 {
+		speed := b.profile.moveSpeed * frames
+		b.y += math.Max(-speed, math.Min(speed, step))
+		s.broadcast(protocol.MsgTypePlayerAction, protocol.PlayerAction{
+			PlayerID: b.name,
+			Action:   "move_y",
+			Value:    b.y,
+		})
+	}
+
+	// 与对手纵向对齐且冷却结束时开火
+	if now.Sub(b.lastFire) >= b.profile.fireCooldown && math.Abs(b.y-b.trackY) <= b.profile.aimTolerance {
+		b.lastFire = now
+		bullet := botBullet{x: b.x, y: b.y + playerHeight/2, vx: b.dir * bulletSpeed}
+		if b.dir > 0 {
+			bullet.x += playerWidth
+		}
+		b.retarget()
+		if st := s.stats[b.name]; st != nil {
+			st.ShotsFired++
+		}
+		// 机器人子弹在这里结算，飞出场地或命中时移除，不设存活时间
+		fire := s.spawnBullet(b.name, protocol.FireAction{
+			PlayerID:  b.name,
+			Direction: int(b.dir),
+			X:         bullet.x,
+			Y:         bullet.y,
+		}, 0)
+		bullet.id = fire.BulletID
+		b.bullets = append(b.bullets, bullet)
+		s.broadcast(protocol.MsgTypeFire, fire)
+	}
+
+	// 结算子弹，判定方式与客户端一致
+	kept := b.bullets[:0]
+	for _, bullet := range b.bullets {
+		bullet.x += bullet.vx * frames
+		hit := bullet.x > b.opponentX && bullet.x < b.opponentX+playerWidth &&
+			bullet.y > b.opponentY && bullet.y < b.opponentY+playerHeight
+		if !hit {
+			if bullet.x >= 0 && bullet.x <= fieldWidth {
+				kept = append(kept, bullet)
+			} else {
+				s.despawn(bullet.id, protocol.DespawnExpired)
+			}
+			continue
+		}
+
+		weapon, _ := s.roster.Weapon(s.heroes[b.name], "")
+		distance := s.distance(b.name, b.opponent)
+		s.despawn(bullet.id, protocol.DespawnHit)
+		result := s.applyHit(protocol.HitAction{
+			TargetID: b.opponent,
+			BulletID: bullet.id,
+			Damage:   weapon.DamageAt(distance, false),
+			Weapon:   weapon.Name,
+			Distance: distance,
+		})
+		if st := s.stats[b.name]; st != nil {
+			st.ShotsHit++
+			st.DamageDealt += result.Damage
+		}
+		s.broadcast(protocol.MsgTypeHit, result)
+		if result.Remaining <= 0 {
+			// 阵亡后要么对局结束，要么开始下一局并清空子弹
+			s.broadcast(protocol.MsgTypeDeath, map[string]string{"player_id": b.opponent})
+			return s.recordDeath(protocol.DeathAction{PlayerID: b.opponent, KillerID: b.name})
+		}
+	}
+	b.bullets = kept
+	return false
+}
+
+// broadcast 以机器人的身份向房间内所有客户端发送消息
+func (s *roomSession) broadcast(msgType protocol.MessageType, payload interface{}) {
+	data, err := json.Marshal(protocol.Message{Type: msgType, Payload: mustMarshal(payload)})
+	if err != nil {
+		return
+	}
+	s.hub.sendGame(s.roomID, s.hub.roomPeers(s.roomID, ""), data)
+}
+
+// addBot 房主请求为房间加入机器人对手
+func (h *Hub) addBot(client *Client, req protocol.AddBotRequest) {
+	difficulty := req.Difficulty
+	if difficulty == "" {
+		difficulty = h.cfg.BotDifficulty
+	}
+	if _, ok := h.bots.Get(difficulty); !ok {
+		h.sendError(client, http.StatusBadRequest, "未知的机器人难度: "+difficulty)
+		return
+	}
+
+	var room models.Room
+	reason := "房间不存在"
+	added := h.roomStore.Modify(client.roomID, func(r *models.Room) bool {
+		switch {
+		case r.HostID != client.username:
+			reason = "只有房主可以添加机器人"
+		case r.Status == "playing":
+			reason = "游戏进行中，无法添加机器人"
+		case len(r.Players) >= r.MaxPlayers:
+			reason = "房间已满"
+		case hasBot(r.Players):
+			reason = "房间中已有机器人"
+		default:
+			r.Players = append(r.Players, botName(difficulty))
+			if len(r.Players) >= 2 {
+				r.Status = "ready"
+			}
+			room = r.Clone()
+			return true
+		}
+		return false
+	})
+	if !added {
+		h.sendError(client, http.StatusBadRequest, reason)
+		return
+	}
+
+	msg := protocol.Message{
+		Type: protocol.MsgTypeJoinRoomResult,
+		Payload: mustMarshal(protocol.JoinRoomResponse{
+			Success: true,
+			Message: "机器人（" + difficulty + "）加入了房间",
+			Room:    h.roomInfo(room),
+		}),
+	}
+	data, _ := json.Marshal(msg)
+	h.broadcaster.submit(h.roomPeers(room.ID, ""), data)
+}
+
+// hasBot 判断玩家列表中是否已有机器人
+func hasBot(players []string) bool {
+	for _, player := range players {
+		if models.IsBot(player) {
+			return true
+		}
+	}
+	return false
+}
+
+// clamp 将 v 限制在 [lo, hi] 区间
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+// listBots 返回可选的机器人难度
+func (h *Hub) listBots(client *Client) {
+	profiles := h.bots.All()
+	resp := protocol.BotListResponse{Profiles: make([]protocol.BotProfileInfo, 0, len(profiles))}
+	for _, p := range profiles {
+		resp.Profiles = append(resp.Profiles, protocol.BotProfileInfo{
+			ID:         p.ID,
+			Name:       p.Name,
+			ReactionMs: p.ReactionMs,
+			Accuracy:   p.Accuracy,
+			Aggression: p.Aggression,
+			Default:    p.ID == h.cfg.BotDifficulty,
+		})
+	}
+	data, _ := json.Marshal(protocol.Message{
+		Type:    protocol.MsgTypeBotList,
+		Payload: mustMarshal(resp),
+	})
+	client.send <- data
+}
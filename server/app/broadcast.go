@@ -0,0 +1,87 @@
+package app
+
+import (
+	"hash/fnv"
+	"log"
+)
+
+const (
+	broadcastWorkers   = 8    // 广播工作协程数量
+	broadcastQueueSize = 1024 // 广播任务队列总长度，平均分给各工作协程，队列满时提交方阻塞
+)
+
+// broadcastJob 定义一次投递任务：把同一份已序列化的帧发给一个客户端
+type broadcastJob struct {
+	client *Client
+	data   []byte
+}
+
+// broadcastPool 定义有界的广播工作池。每个工作协程有自己的队列，
+// 同一客户端的任务总是进入同一个队列，保证发给它的帧按提交顺序到达
+type broadcastPool struct {
+	hub    *Hub
+	shards []chan broadcastJob
+}
+
+// newBroadcastPool 创建广播工作池并启动工作协程
+func newBroadcastPool(hub *Hub, workers, queueSize int) *broadcastPool {
+	p := &broadcastPool{
+		hub:    hub,
+		shards: make([]chan broadcastJob, workers),
+	}
+	for i := range p.shards {
+		p.shards[i] = make(chan broadcastJob, max(queueSize/workers, 1))
+		go p.worker(p.shards[i])
+	}
+	return p
+}
+
+// worker 从自己的队列中取出任务并投递
+func (p *broadcastPool) worker(jobs <-chan broadcastJob) {
+	for job := range jobs {
+		p.hub.deliver(job.client, job.data)
+	}
+}
+
+// submit 将同一份帧扇出给多个客户端
+func (p *broadcastPool) submit(clients []*Client, data []byte) {
+	for _, c := range clients {
+		p.shard(c) <- broadcastJob{client: c, data: data}
+	}
+}
+
+// shard 按连接ID选择客户端所属的队列
+func (p *broadcastPool) shard(c *Client) chan<- broadcastJob {
+	h := fnv.New32a()
+	h.Write([]byte(c.connID))
+	return p.shards[h.Sum32()%uint32(len(p.shards))]
+}
+
+// deliver 向单个客户端投递消息，客户端已注销时直接丢弃
+func (h *Hub) deliver(client *Client, data []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	// 注销时 send 通道会被关闭，必须在锁内确认客户端仍然存在
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	select {
+	case client.send <- data:
+	default:
+		log.Printf("用户 %s 发送队列已满，丢弃广播消息", client.username)
+	}
+}
+
+// roomPeers 返回同房间内除 exclude 外的所有客户端
+func (h *Hub) roomPeers(roomID string, exclude string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	peers := make([]*Client, 0)
+	for c := range h.clients {
+		if c.roomID == roomID && c.username != exclude {
+			peers = append(peers, c)
+		}
+	}
+	return peers
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"game/protocol"
+)
+
+// newTestClients 创建已注册到 hub 的客户端，并为每个客户端启动读取协程，把收到的帧交给 recv
+func newTestClients(h *Hub, n int, recv func(i int, data []byte)) ([]*Client, func()) {
+	clients := make([]*Client, n)
+	var wg sync.WaitGroup
+	for i := range clients {
+		c := &Client{send: make(chan []byte, 256), username: fmt.Sprintf("user%d", i), connID: fmt.Sprintf("conn%d", i)}
+		clients[i] = c
+		h.clients[c] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for data := range c.send {
+				recv(i, data)
+			}
+		}()
+	}
+	stop := func() {
+		for _, c := range clients {
+			close(c.send)
+		}
+		wg.Wait()
+	}
+	return clients, stop
+}
+
+func TestBroadcastPoolKeepsOrderPerClient(t *testing.T) {
+	h := &Hub{clients: make(map[*Client]bool)}
+	pool := newBroadcastPool(h, broadcastWorkers, broadcastQueueSize)
+
+	// 帧数小于客户端发送队列长度，不会因队列满被丢弃
+	const frames = 200
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	got := make(map[int][]int)
+	clients, stop := newTestClients(h, 16, func(i int, data []byte) {
+		n, _ := strconv.Atoi(string(data))
+		mu.Lock()
+		got[i] = append(got[i], n)
+		mu.Unlock()
+		wg.Done()
+	})
+	wg.Add(len(clients) * frames)
+	for n := 0; n < frames; n++ {
+		pool.submit(clients, []byte(strconv.Itoa(n)))
+	}
+	wg.Wait()
+	stop()
+
+	for i := range clients {
+		for n, v := range got[i] {
+			if v != n {
+				t.Fatalf("客户端 %d 第 %d 帧收到 %d，顺序被打乱", i, n, v)
+			}
+		}
+	}
+}
+
+// benchAction 广播基准使用的游戏动作
+var benchAction = protocol.Message{
+	Type:    protocol.MsgTypeHit,
+	Payload: mustMarshal(protocol.HitAction{TargetID: "user1", Weapon: "rifle", Damage: 30, Distance: 12.5}),
+}
+
+// BenchmarkBroadcastPool 与 broadcastGameAction 相同，每帧只序列化一次，再交给工作池扇出
+func BenchmarkBroadcastPool(b *testing.B) {
+	for _, n := range []int{10, 100} {
+		b.Run(fmt.Sprintf("clients=%d", n), func(b *testing.B) {
+			h := &Hub{clients: make(map[*Client]bool)}
+			pool := newBroadcastPool(h, broadcastWorkers, broadcastQueueSize)
+			var wg sync.WaitGroup
+			clients, stop := newTestClients(h, n, func(int, []byte) { wg.Done() })
+
+			// 每次等一帧扇出到所有客户端，衡量一帧广播的完整耗时
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				wg.Add(len(clients))
+				data, _ := json.Marshal(benchAction)
+				pool.submit(clients, data)
+				wg.Wait()
+			}
+			b.StopTimer()
+			stop()
+		})
+	}
+}
+
+// BenchmarkBroadcastPerRecipient 引入工作池之前的广播方式，作为对照：持有 Hub 读锁遍历客户端，
+// 为每个接收方单独序列化一次消息，再直接写入其发送队列
+func BenchmarkBroadcastPerRecipient(b *testing.B) {
+	for _, n := range []int{10, 100} {
+		b.Run(fmt.Sprintf("clients=%d", n), func(b *testing.B) {
+			h := &Hub{clients: make(map[*Client]bool)}
+			var wg sync.WaitGroup
+			clients, stop := newTestClients(h, n, func(int, []byte) { wg.Done() })
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				wg.Add(len(clients))
+				h.mu.RLock()
+				for client := range h.clients {
+					data, _ := json.Marshal(benchAction)
+					client.send <- data
+				}
+				h.mu.RUnlock()
+				wg.Wait()
+			}
+			b.StopTimer()
+			stop()
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"log"
+	"net/http"
+
+	"game/models"
+	"game/protocol"
+)
+
+// challengeInfo 转换为推送给被挑战方的挑战，附带双方当前的天梯排名
+func (h *Hub) challengeInfo(c models.Challenge) protocol.ChallengeInfo {
+	return protocol.ChallengeInfo{
+		ID:           c.ID,
+		From:         c.From,
+		FromPosition: h.ladder.Position(c.FromID),
+		ToPosition:   h.ladder.Position(c.ToID),
+		ExpiresAt:    c.ExpiresAt,
+	}
+}
+
+// challenge 玩家向天梯上排名更高的玩家发起挑战。对方在线时立即推送，不在线时保存挑战，
+// 对方在有效期内上线后推送；被挑战方需在有效期内接受
+func (h *Hub) challenge(client *Client, req protocol.ChallengeRequest) {
+	reply := func(success bool, message string) {
+		h.sendNotices([]matchNotice{{client: client, msgType: protocol.MsgTypeChallengeAck, payload: protocol.ChallengeResult{Success: success, Message: message}}})
+	}
+
+	from := h.userStore.FindByUsername(client.username)
+	to := h.userStore.FindByUsername(req.Username)
+	if from == nil || to == nil {
+		reply(false, "用户不存在")
+		return
+	}
+	if err := h.ladder.Challengeable(*from, *to, h.cfg.ChallengeRange); err != nil {
+		reply(false, err.Error())
+		return
+	}
+
+	now := h.clock.Now()
+	challenge := models.Challenge{
+		ID:        h.newID("challenge"),
+		FromID:    from.UserID,
+		From:      from.Username,
+		ToID:      to.UserID,
+		To:        to.Username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.cfg.ChallengeTTL),
+	}
+	h.challenges.Add(challenge)
+
+	targets := h.userClients(to.Username)
+	if len(targets) == 0 {
+		reply(true, "对方不在线，上线后会收到挑战")
+		return
+	}
+	info := h.challengeInfo(challenge)
+	notices := make([]matchNotice, 0, len(targets))
+	for _, c := range targets {
+		notices = append(notices, matchNotice{client: c, msgType: protocol.MsgTypeChallenged, payload: info})
+	}
+	h.sendNotices(notices)
+	reply(true, "已向 "+to.Username+" 发起挑战")
+}
+
+// pushPendingChallenges 用户上线时推送其不在线期间收到、仍在有效期内的挑战
+func (h *Hub) pushPendingChallenges(client *Client) {
+	user := h.userStore.FindByUsername(client.username)
+	if user == nil {
+		return
+	}
+	challenges := h.challenges.Pending(user.UserID, h.clock.Now())
+	notices := make([]matchNotice, 0, len(challenges))
+	for _, c := range challenges {
+		notices = append(notices, matchNotice{client: client, msgType: protocol.MsgTypeChallenged, payload: h.challengeInfo(c)})
+	}
+	h.sendNotices(notices)
+}
+
+// acceptChallenge 处理被挑战方的回应：接受时为双方创建 1v1 房间，双方都需要在本实例在线且不在其他房间中；
+// 接受或拒绝的结果会通知在线的挑战方
+func (h *Hub) acceptChallenge(client *Client, req protocol.AcceptChallengeRequest) {
+	user := h.userStore.FindByUsername(client.username)
+	if user == nil {
+		return
+	}
+	challenge := h.challenges.Take(req.ChallengeID, user.UserID, h.clock.Now())
+	if challenge == nil {
+		h.sendError(client, http.StatusNotFound, "挑战不存在或已过期")
+		return
+	}
+
+	challenger := h.userStore.FindByID(challenge.FromID)
+	notify := func(success bool, message string) {
+		if challenger == nil {
+			return
+		}
+		notices := make([]matchNotice, 0, 1)
+		for _, c := range h.userClients(challenger.Username) {
+			notices = append(notices, matchNotice{client: c, msgType: protocol.MsgTypeChallengeAck, payload: protocol.ChallengeResult{Success: success, Message: message}})
+		}
+		h.sendNotices(notices)
+	}
+	if !req.Accept {
+		notify(false, client.username+" 拒绝了挑战")
+		return
+	}
+	if challenger == nil {
+		h.sendError(client, http.StatusNotFound, "挑战方不存在")
+		return
+	}
+
+	clients := make([]*Client, 0, 2)
+	for _, username := range []string{challenger.Username, client.username} {
+		conns := h.userClients(username)
+		reason := ""
+		switch {
+		case len(conns) == 0:
+			reason = "玩家 " + username + " 不在线"
+		case conns[0].roomID != "" && !conns[0].spectator:
+			reason = "玩家 " + username + " 正在其他房间中"
+		}
+		if reason != "" {
+			// 挑战仍在有效期内，双方都空闲后可以再次接受
+			h.challenges.Add(*challenge)
+			h.sendError(client, http.StatusConflict, reason)
+			return
+		}
+		clients = append(clients, conns[0])
+	}
+	for _, c := range clients {
+		h.leaveQueue(c.username, "有玩家进入了天梯挑战")
+		h.stopPractice(c, false)
+		h.stopSpectate(c)
+	}
+
+	room := models.Room{
+		ID:         h.newID("room"),
+		Name:       "天梯挑战",
+		HostID:     challenger.Username,
+		Players:    []string{challenger.Username, client.username},
+		MaxPlayers: 2,
+		Status:     "ready",
+		CreatedAt:  h.clock.Now(),
+		Map:        models.DefaultMap,
+		Rules:      models.DefaultRules(),
+		Challenger: challenger.Username,
+	}
+	if err := h.openRoom(room, clients, "天梯挑战已开始"); err == nil {
+		notify(true, client.username+" 接受了挑战")
+	}
+}
+
+// settleChallenge 天梯挑战房间的对局决出胜负后结算排名并通知在线的双方；没有胜者时双方可以在原房间再次开局
+func (h *Hub) settleChallenge(result models.GameResult) {
+	room := h.roomStore.GetByID(result.RoomID)
+	if room == nil || room.Challenger == "" || result.Winner == "" {
+		return
+	}
+	var challenged string
+	for _, p := range room.Players {
+		if p != room.Challenger {
+			challenged = p
+		}
+	}
+	challenger := h.userStore.FindByUsername(room.Challenger)
+	opponent := h.userStore.FindByUsername(challenged)
+	winner := h.userStore.FindByUsername(result.Winner)
+	if challenger == nil || opponent == nil || winner == nil {
+		log.Printf("结算天梯挑战失败: 房间 %s 的玩家不存在", room.ID)
+		return
+	}
+	swapped := h.ladder.Settle(challenger.UserID, opponent.UserID, winner.UserID)
+	// 每次挑战只结算一次，之后在房间里继续的对局不再影响排名
+	h.roomStore.Modify(room.ID, func(r *models.Room) bool {
+		r.Challenger = ""
+		return true
+	})
+
+	info := protocol.LadderResult{
+		Challenger:         challenger.Username,
+		Challenged:         opponent.Username,
+		Winner:             winner.Username,
+		Swapped:            swapped,
+		ChallengerPosition: h.ladder.Position(challenger.UserID),
+		ChallengedPosition: h.ladder.Position(opponent.UserID),
+	}
+	var notices []matchNotice
+	for _, username := range []string{challenger.Username, opponent.Username} {
+		for _, c := range h.userClients(username) {
+			notices = append(notices, matchNotice{client: c, msgType: protocol.MsgTypeLadderResult, payload: info})
+		}
+	}
+	h.sendNotices(notices)
+}
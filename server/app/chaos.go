@@ -0,0 +1,109 @@
+package app
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"game/config"
+	"game/sim"
+)
+
+// chaosInjector 按配置对客户端的 WebSocket 消息注入延迟、丢弃和乱序，
+// 用于在弱网条件下验证重连、延迟补偿和超时逻辑。未启用时为 nil
+type chaosInjector struct {
+	delay       time.Duration
+	jitter      time.Duration
+	dropRate    float64
+	reorderRate float64
+	users       map[string]bool // 为空时对所有用户生效
+
+	mu  sync.Mutex
+	rng sim.RNG
+}
+
+// newChaosInjector 根据配置创建注入器，未启用任何注入时返回 nil
+func newChaosInjector(cfg *config.Config, rng sim.RNG) *chaosInjector {
+	if !cfg.ChaosEnabled() {
+		return nil
+	}
+	x := &chaosInjector{
+		delay:       cfg.ChaosDelay,
+		jitter:      cfg.ChaosJitter,
+		dropRate:    cfg.ChaosDropRate,
+		reorderRate: cfg.ChaosReorderRate,
+		rng:         rng,
+	}
+	if len(cfg.ChaosUsers) > 0 {
+		x.users = make(map[string]bool, len(cfg.ChaosUsers))
+		for _, u := range cfg.ChaosUsers {
+			x.users[u] = true
+		}
+	}
+	log.Printf("警告: 已启用混沌注入（延迟 %s，抖动 %s，丢弃率 %g，乱序率 %g，用户 %v），不要在生产环境使用",
+		x.delay, x.jitter, x.dropRate, x.reorderRate, cfg.ChaosUsers)
+	return x
+}
+
+// enabledFor 判断是否对该用户的连接注入
+func (x *chaosInjector) enabledFor(username string) bool {
+	return x != nil && (x.users == nil || x.users[username])
+}
+
+// roll 抽取一条消息的处理方式
+func (x *chaosInjector) roll() (drop, reorder bool, delay time.Duration) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.dropRate > 0 && x.rng.Float64() < x.dropRate {
+		return true, false, 0
+	}
+	reorder = x.reorderRate > 0 && x.rng.Float64() < x.reorderRate
+	delay = x.delay
+	if x.jitter > 0 {
+		delay += time.Duration(x.rng.Int63() % int64(x.jitter))
+	}
+	return false, reorder, delay
+}
+
+// pipe 将 in 中的消息经过注入后转发到返回的通道。
+// in 关闭且所有延迟中的消息都已投递后关闭返回的通道；stop 关闭后未投递的消息直接丢弃
+func (x *chaosInjector) pipe(in <-chan []byte, stop <-chan struct{}) <-chan []byte {
+	out := make(chan []byte, cap(in))
+	go func() {
+		var wg sync.WaitGroup
+		send := func(msg []byte, delay time.Duration) {
+			wg.Add(1)
+			time.AfterFunc(delay, func() {
+				defer wg.Done()
+				select {
+				case out <- msg:
+				case <-stop:
+				}
+			})
+		}
+
+		// held 为等待与下一条消息交换顺序的消息
+		var held []byte
+		for msg := range in {
+			drop, reorder, delay := x.roll()
+			if drop {
+				continue
+			}
+			if reorder && held == nil {
+				held = msg
+				continue
+			}
+			send(msg, delay)
+			if held != nil {
+				send(held, delay+time.Millisecond)
+				held = nil
+			}
+		}
+		if held != nil {
+			send(held, x.delay)
+		}
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"log"
+
+	"game/models"
+)
+
+// violation 记录玩家的一次违规，对局结束后由 recordViolations 计入作弊标记
+func (s *roomSession) violation(username, kind string) {
+	if s.cheats[username] == nil {
+		s.cheats[username] = make(map[string]int)
+	}
+	s.cheats[username][kind]++
+}
+
+// recordViolations 把对局中的违规计入玩家的作弊标记，机器人和没有用户ID的玩家不记录
+func (h *Hub) recordViolations(players []models.PlayerResult, violations map[string]map[string]int) {
+	for _, p := range players {
+		if p.UserID == "" || len(violations[p.Username]) == 0 {
+			continue
+		}
+		flag, flagged := h.moderation.RecordViolations(p.UserID, p.Username, violations[p.Username])
+		if flagged {
+			log.Printf("用户 %s 的违规分达到 %d，已标记为疑似作弊等待审核", p.Username, flag.Score)
+		}
+	}
+}
+
+// flaggedUser 用户是否处于作弊标记状态
+func (h *Hub) flaggedUser(username string) bool {
+	user := h.userStore.FindByUsername(username)
+	return user != nil && h.moderation.Flagged(user.UserID)
+}
+
+// flaggedResult 对局中是否有玩家当前处于作弊标记状态，这样的对局在审核前不计入评分
+func (h *Hub) flaggedResult(r models.GameResult) bool {
+	for _, p := range r.Players {
+		if h.moderation.Flagged(p.UserID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"game/cluster"
+	"game/data"
+)
+
+// clusterLoop 集群模式下定期刷新本实例的存活标记和本实例上连接的在线状态
+func (h *Hub) clusterLoop() {
+	h.cluster.Heartbeat()
+	ticker := h.clock.NewTicker(cluster.RefreshInterval)
+	defer ticker.Stop()
+	for range ticker.C() {
+		h.mu.RLock()
+		usernames := make([]string, 0, len(h.clients))
+		for c := range h.clients {
+			usernames = append(usernames, c.username)
+		}
+		h.mu.RUnlock()
+
+		h.cluster.Heartbeat()
+		h.cluster.RefreshPresence(usernames)
+	}
+}
+
+// publishRoom 把本实例的房间变更同步到集群注册表，其他实例的大厅据此列出房间
+func (h *Hub) publishRoom(ev data.RoomChange) {
+	if ev.New == nil {
+		h.cluster.DeleteRoom(ev.Old.ID)
+		return
+	}
+	h.cluster.PutRoom(*ev.New)
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"game/config"
+	"game/crypto"
+	"game/data"
+)
+
+// configureStorage 根据配置初始化数据层并锁定数据目录，需要在创建任何存储之前调用
+func configureStorage(cfg *config.Config) error {
+	if cfg.InMemory() {
+		return nil
+	}
+	if err := data.LockDataDir(); err != nil {
+		return err
+	}
+
+	secret, err := cfg.UsersSecret()
+	if err != nil {
+		return fmt.Errorf("读取用户数据密钥失败: %v", err)
+	}
+	if secret != "" {
+		data.SetUsersKey(crypto.DeriveKey(secret))
+	}
+	return nil
+}
+
+// RunCommand 执行运维命令
+func RunCommand(name string, args []string) error {
+	cfg := config.Load()
+	if name == "stats" {
+		// 查询运行中的服务器，不访问数据目录
+		return printStats(cfg, args)
+	}
+	if cfg.InMemory() {
+		return fmt.Errorf("内存存储模式下没有可操作的数据文件")
+	}
+	if err := configureStorage(cfg); err != nil {
+		return err
+	}
+
+	switch name {
+	case "encrypt-users":
+		// 将已有的明文 users.json 按当前密钥加密重写
+		if err := data.EncryptUsersFile(); err != nil {
+			return err
+		}
+		log.Println("用户数据已加密")
+		return nil
+
+	case "backup":
+		backup, err := data.NewFileBackupManager(cfg.BackupKeep).Create()
+		if err != nil {
+			return err
+		}
+		log.Printf("已创建备份 %s", backup.Name)
+		return nil
+
+	case "backups":
+		backups, err := data.ListBackups()
+		if err != nil {
+			return err
+		}
+		for _, b := range backups {
+			fmt.Printf("%s\t%d\t%s\n", b.Name, b.Size, b.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		return nil
+
+	case "restore":
+		// 数据目录锁保证服务器未在运行，恢复后重新启动服务器即可加载
+		if len(args) != 1 {
+			return fmt.Errorf("用法: restore <备份名称>")
+		}
+		previous, err := data.RestoreBackup(args[0], cfg.BackupKeep)
+		if err != nil {
+			return err
+		}
+		log.Printf("已从 %s 恢复数据，恢复前的数据已备份为 %s", args[0], previous.Name)
+		return nil
+	}
+	return fmt.Errorf("未知命令: %s", name)
+}
+
+// printStats 通过管理接口查询运行中服务器的统计并输出 JSON，可用参数指定服务器地址，缺省为本机的监听端口
+func printStats(cfg *config.Config, args []string) error {
+	base := "http://127.0.0.1" + cfg.Addr
+	if !strings.HasPrefix(cfg.Addr, ":") {
+		base = "http://" + cfg.Addr
+	}
+	if len(args) > 0 {
+		base = strings.TrimSuffix(args[0], "/")
+	}
+	req, err := http.NewRequest(http.MethodGet, base+"/admin/stats", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Admin-Token", cfg.AdminToken)
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("查询服务器统计失败: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("查询服务器统计失败: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		return err
+	}
+	fmt.Println(out.String())
+	return nil
+}
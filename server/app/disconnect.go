@@ -0,0 +1,104 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"game/data"
+	"game/models"
+	"game/protocol"
+	"game/service"
+)
+
+// pendingDrop 断线的房间成员，超过 deadline 仍未重新加入房间时移出
+type pendingDrop struct {
+	roomID   string
+	deadline time.Time
+}
+
+// playerDisconnected 房间成员断开连接后通知房间内的其他成员，并开始重连宽限期：
+// 宽限期内重新加入房间时保留位置，超过后由 dropDisconnected 移出房间。对局中的掉线仍由游戏会话暂停等待，
+// 对局结束后玩家仍未回来时同样移出
+func (h *Hub) playerDisconnected(client *Client) {
+	inMatch := h.session(client.roomID) != nil
+	grace := h.cfg.ReconnectGrace
+	update, _ := json.Marshal(protocol.Message{
+		Type: protocol.MsgTypeDisconnected,
+		Payload: mustMarshal(protocol.PlayerDisconnected{
+			RoomID:  client.roomID,
+			Player:  client.username,
+			Grace:   int(grace / time.Second),
+			InMatch: inMatch,
+		}),
+	})
+	h.broadcaster.submit(h.roomPeers(client.roomID, client.username), update)
+
+	h.dropsMu.Lock()
+	h.drops[client.username] = pendingDrop{roomID: client.roomID, deadline: h.clock.Now().Add(grace)}
+	h.dropsMu.Unlock()
+	if grace <= 0 && !inMatch {
+		h.dropDisconnected(h.clock.Now())
+	}
+}
+
+// dropDisconnected 把超过宽限期仍未重新加入房间的断线成员移出房间，并通知房间内的其他成员。
+// 房间正在对局时等对局结束后再处理
+func (h *Hub) dropDisconnected(now time.Time) {
+	h.dropsMu.Lock()
+	due := make(map[string]pendingDrop)
+	for username, drop := range h.drops {
+		if h.inRoom(username, drop.roomID) {
+			delete(h.drops, username)
+			continue
+		}
+		if now.Before(drop.deadline) {
+			continue
+		}
+		if room := h.roomStore.GetByID(drop.roomID); room != nil && room.Status == "playing" {
+			continue
+		}
+		delete(h.drops, username)
+		due[username] = drop
+	}
+	h.dropsMu.Unlock()
+
+	for username, drop := range due {
+		var room *models.Room
+		removed := false
+		err := data.RunTransaction(h.userStore, h.roomStore, func(tx *data.Txn) error {
+			room, removed = service.RemoveMember(tx, drop.roomID, username)
+			return nil
+		})
+		if err != nil {
+			log.Printf("移出断线玩家 %s 失败: %v", username, err)
+			continue
+		}
+		if !removed {
+			continue
+		}
+		log.Printf("玩家 %s 断线超过宽限期，已移出房间 %s", username, drop.roomID)
+		if room == nil {
+			continue
+		}
+		update, _ := json.Marshal(protocol.Message{
+			Type: protocol.MsgTypeJoinRoomResult,
+			Payload: mustMarshal(protocol.JoinRoomResponse{
+				Success: true,
+				Message: username + " 断线超时，已离开房间",
+				Room:    h.roomInfo(*room),
+			}),
+		})
+		h.broadcaster.submit(h.roomPeers(room.ID, ""), update)
+	}
+}
+
+// inRoom 用户在本实例上是否有以玩家身份位于该房间的连接
+func (h *Hub) inRoom(username, roomID string) bool {
+	for _, c := range h.userClients(username) {
+		if c.roomID == roomID && !c.spectator {
+			return true
+		}
+	}
+	return false
+}
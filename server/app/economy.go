@@ -0,0 +1,165 @@
+package app
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"game/models"
+	"game/protocol"
+)
+
+// 多局制对局的经济参数
+const (
+	startingMoney    = 800
+	maxMoney         = 16000
+	killReward       = 300
+	roundWinReward   = 3250
+	roundLossReward  = 1400
+	buyPhaseDuration = 15 * time.Second // 每局开始后允许购买的时间
+)
+
+// loadout 玩家在多局制对局中的金钱和购买的装备，阵亡后购买的武器和护甲清空，金钱保留
+type loadout struct {
+	money   int
+	weapons []string
+	armor   int
+}
+
+// economy 判断对局是否启用经济系统：只在双人多局制对局中启用
+func (s *roomSession) economy() bool {
+	return !s.ffa && s.rules.Rounds > 1
+}
+
+// initEconomy 为真人玩家发放起始金钱，机器人不参与购买
+func (s *roomSession) initEconomy() {
+	if !s.economy() {
+		return
+	}
+	for _, player := range s.players {
+		if !models.IsBot(player) {
+			s.loadouts[player] = &loadout{money: startingMoney}
+		}
+	}
+}
+
+// earn 发放奖励，金钱不超过上限
+func (s *roomSession) earn(player string, amount int) {
+	if l := s.loadouts[player]; l != nil {
+		l.money = min(maxMoney, l.money+amount)
+	}
+}
+
+// loseLoadout 玩家阵亡后失去本局购买的武器和护甲
+func (s *roomSession) loseLoadout(player string) {
+	if l := s.loadouts[player]; l != nil {
+		l.weapons, l.armor = nil, 0
+	}
+}
+
+// weapon 返回玩家可用的武器：英雄自带的装备或购买的武器，name 为空时返回英雄的默认武器
+func (s *roomSession) weapon(player, name string) (models.Weapon, bool) {
+	if l := s.loadouts[player]; l != nil && name != "" && slices.Contains(l.weapons, name) {
+		return s.roster.WeaponNamed(name)
+	}
+	return s.roster.Weapon(s.heroes[player], name)
+}
+
+// absorb 用护甲抵扣伤害，返回扣除护甲后对生命值造成的伤害
+func (s *roomSession) absorb(player string, damage int) int {
+	l := s.loadouts[player]
+	if l == nil || l.armor == 0 {
+		return damage
+	}
+	absorbed := min(l.armor, damage)
+	l.armor -= absorbed
+	return damage - absorbed
+}
+
+// armorOf 返回玩家剩余的护甲值
+func (s *roomSession) armorOf(player string) int {
+	if l := s.loadouts[player]; l != nil {
+		return l.armor
+	}
+	return 0
+}
+
+// loadoutInfo 将玩家的装备转换为协议中的装备信息
+func loadoutInfo(l *loadout) protocol.LoadoutInfo {
+	return protocol.LoadoutInfo{
+		Money:   l.money,
+		Weapons: append([]string{}, l.weapons...),
+		Armor:   l.armor,
+	}
+}
+
+// loadoutInfos 返回所有玩家的装备快照，未启用经济系统时为 nil
+func (s *roomSession) loadoutInfos() map[string]protocol.LoadoutInfo {
+	if len(s.loadouts) == 0 {
+		return nil
+	}
+	infos := make(map[string]protocol.LoadoutInfo, len(s.loadouts))
+	for player, l := range s.loadouts {
+		infos[player] = loadoutInfo(l)
+	}
+	return infos
+}
+
+// buy 处理购买阶段的购买请求，按价格表校验后扣除金钱
+func (s *roomSession) buy(client *Client, req protocol.BuyRequest) {
+	reply := func(result protocol.BuyResult) {
+		data, err := json.Marshal(protocol.Message{Type: protocol.MsgTypeBuyResult, Payload: mustMarshal(result)})
+		if err != nil {
+			return
+		}
+		s.hub.broadcaster.submit([]*Client{client}, data)
+	}
+
+	l := s.loadouts[client.username]
+	switch {
+	case l == nil:
+		reply(protocol.BuyResult{Item: req.Item, Message: "当前对局不能购买装备"})
+		return
+	case !s.hub.clock.Now().Before(s.buyEnds):
+		reply(protocol.BuyResult{Item: req.Item, Message: "购买阶段已结束", Loadout: loadoutInfo(l)})
+		return
+	}
+
+	price := 0
+	weapon, isWeapon := s.roster.ShopWeapon(req.Item)
+	armor, isArmor := s.roster.ShopArmor(req.Item)
+	switch {
+	case isWeapon:
+		if _, owned := s.weapon(client.username, req.Item); owned {
+			reply(protocol.BuyResult{Item: req.Item, Message: "已拥有该武器", Loadout: loadoutInfo(l)})
+			return
+		}
+		price = weapon.Price
+	case isArmor:
+		if l.armor >= armor.Points {
+			reply(protocol.BuyResult{Item: req.Item, Message: "护甲已满", Loadout: loadoutInfo(l)})
+			return
+		}
+		price = armor.Price
+	default:
+		reply(protocol.BuyResult{Item: req.Item, Message: "商店中没有该物品", Loadout: loadoutInfo(l)})
+		return
+	}
+	if l.money < price {
+		reply(protocol.BuyResult{Item: req.Item, Message: "金钱不足", Loadout: loadoutInfo(l)})
+		return
+	}
+
+	l.money -= price
+	if isWeapon {
+		l.weapons = append(l.weapons, req.Item)
+	} else {
+		l.armor = armor.Points
+	}
+	reply(protocol.BuyResult{Success: true, Item: req.Item, Message: "购买成功", Loadout: loadoutInfo(l)})
+}
+
+// startBuyPhase 开始本局的购买阶段
+func (s *roomSession) startBuyPhase() {
+	s.buyEnds = s.hub.clock.Now().Add(buyPhaseDuration)
+}
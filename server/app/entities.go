@@ -0,0 +1,63 @@
+package app
+
+import (
+	"time"
+
+	"game/game"
+	"game/protocol"
+)
+
+// bulletLifetime 玩家子弹的存活时间，略长于子弹横穿整个场地所需的时间（约 1.9 秒），到期后由服务器移除
+const bulletLifetime = 2 * time.Second
+
+// spawnBullet 为射击分配子弹ID并向房间广播 entity_spawn，返回改写为服务器ID的射击消息。
+// ttl 不大于 0 时子弹不会自动过期，由调用方负责移除
+func (s *roomSession) spawnBullet(owner string, fire protocol.FireAction, ttl time.Duration) protocol.FireAction {
+	ent := s.entities.Spawn(game.EntityBullet, owner, s.hub.clock.Now(), ttl)
+	s.broadcast(protocol.MsgTypeEntitySpawn, protocol.EntitySpawn{
+		ID:        ent.ID,
+		Kind:      ent.Kind,
+		OwnerID:   owner,
+		X:         fire.X,
+		Y:         fire.Y,
+		VX:        float64(fire.Direction) * bulletSpeed,
+		ExpiresIn: ttl.Seconds(),
+		ClientRef: fire.BulletID,
+	})
+	fire.BulletID = ent.ID
+	return fire
+}
+
+// despawn 移除实体并向房间广播 entity_despawn，实体不存在或已被移除时返回 false
+func (s *roomSession) despawn(id, reason string) bool {
+	ent, ok := s.entities.Despawn(id)
+	if ok {
+		delete(s.crits, id)
+		s.broadcastDespawn(ent, reason)
+	}
+	return ok
+}
+
+// expireEntities 移除到期的实体，由逻辑帧驱动
+func (s *roomSession) expireEntities(now time.Time) {
+	for _, ent := range s.entities.Expire(now) {
+		delete(s.crits, ent.ID)
+		s.broadcastDespawn(ent, protocol.DespawnExpired)
+	}
+}
+
+// clearEntities 换局时移除所有实体
+func (s *roomSession) clearEntities() {
+	for _, ent := range s.entities.Clear() {
+		s.broadcastDespawn(ent, protocol.DespawnRoundReset)
+	}
+}
+
+// broadcastDespawn 向房间广播实体被移除
+func (s *roomSession) broadcastDespawn(ent game.Entity, reason string) {
+	s.broadcast(protocol.MsgTypeEntityDespawn, protocol.EntityDespawn{
+		ID:     ent.ID,
+		Kind:   ent.Kind,
+		Reason: reason,
+	})
+}
@@ -0,0 +1,20 @@
+package app
+
+import (
+	"game/protocol"
+	"game/service"
+)
+
+// pushActiveEvents 用户上线时推送正在进行的限时活动，没有活动时不推送
+func (h *Hub) pushActiveEvents(client *Client) {
+	now := h.clock.Now()
+	active := h.events.Active(now)
+	if len(active) == 0 {
+		return
+	}
+	info := protocol.EventListResponse{Events: make([]protocol.EventInfo, 0, len(active))}
+	for _, e := range active {
+		info.Events = append(info.Events, service.EventInfo(e, now))
+	}
+	h.sendNotices([]matchNotice{{client: client, msgType: protocol.MsgTypeEvents, payload: info}})
+}
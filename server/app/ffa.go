@@ -0,0 +1,92 @@
+package app
+
+import (
+	"sort"
+
+	"game/protocol"
+)
+
+// recordFFADeath 处理混战中的阵亡：设置了击杀目标时阵亡玩家复活，先达到目标击杀数的玩家获胜；
+// 否则阵亡即出局，最后存活的玩家获胜
+func (s *roomSession) recordFFADeath(victim, killer string) bool {
+	if s.rules.KillTarget > 0 {
+		s.broadcastScoreboard()
+		if st := s.stats[killer]; st != nil && st.Kills >= s.rules.KillTarget {
+			s.finish(protocol.GameOverInfo{Winner: killer})
+			return true
+		}
+		s.hp[victim] = s.startingHP(victim)
+		delete(s.lastHit, victim)
+		s.broadcast(protocol.MsgTypeRespawn, protocol.Respawn{Player: victim, HP: s.hp[victim]})
+		return false
+	}
+	return s.eliminate(victim, "")
+}
+
+// eliminate 混战中玩家出局，只剩一名存活玩家时结束对局并返回 true
+func (s *roomSession) eliminate(username, reason string) bool {
+	if s.out[username] {
+		return false
+	}
+	s.out[username] = true
+	s.outOrder = append(s.outOrder, username)
+	s.broadcastScoreboard()
+
+	alive := make([]string, 0, len(s.players))
+	for _, player := range s.players {
+		if !s.out[player] {
+			alive = append(alive, player)
+		}
+	}
+	if len(alive) > 1 {
+		return false
+	}
+	gameOver := protocol.GameOverInfo{Reason: reason}
+	if len(alive) == 1 {
+		gameOver.Winner = alive[0]
+	}
+	s.finish(gameOver)
+	return true
+}
+
+// ranking 返回最终名次，第一名在前：胜者第一，其余存活玩家按目标分数、赢下的局数、击杀、阵亡、伤害排列，
+// 出局的玩家排在最后，越晚出局名次越高。最后一名同时作为结果中的 Loser
+func (s *roomSession) ranking(winner string) []string {
+	outAt := make(map[string]int, len(s.outOrder))
+	for i, player := range s.outOrder {
+		outAt[player] = i
+	}
+	group := func(player string) int {
+		switch {
+		case player != "" && player == winner:
+			return 0
+		case s.out[player]:
+			return 2
+		}
+		return 1
+	}
+
+	ranked := append([]string(nil), s.players...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if ga, gb := group(a), group(b); ga != gb {
+			return ga < gb
+		}
+		if s.out[a] {
+			return outAt[a] > outAt[b]
+		}
+		sa, sb := s.stats[a], s.stats[b]
+		switch {
+		case sa.Objective != sb.Objective:
+			return sa.Objective > sb.Objective
+		case s.roundWins[a] != s.roundWins[b]:
+			return s.roundWins[a] > s.roundWins[b]
+		case sa.Kills != sb.Kills:
+			return sa.Kills > sb.Kills
+		case sa.Deaths != sb.Deaths:
+			return sa.Deaths < sb.Deaths
+		}
+		return sa.DamageDealt > sb.DamageDealt
+	})
+	return ranked
+}
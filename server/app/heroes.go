@@ -0,0 +1,122 @@
+package app
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"game/data"
+	"game/models"
+	"game/protocol"
+)
+
+// roster 返回当前生效的平衡版本，大厅中的英雄列表和选择使用它，对局使用开局时取得的版本
+func (h *Hub) roster() *data.HeroRoster {
+	return h.balance.Current()
+}
+
+// heroInfo 将英雄及其武器配置转换为协议中的英雄信息
+func (h *Hub) heroInfo(hero models.Hero) protocol.HeroInfo {
+	loadout := make([]protocol.WeaponInfo, 0, len(hero.Loadout))
+	for _, name := range hero.Loadout {
+		w, _ := h.roster().Weapon(hero, name)
+		loadout = append(loadout, weaponInfo(w))
+	}
+	return protocol.HeroInfo{ID: hero.ID, Name: hero.Name, Speed: hero.Speed, HP: hero.HP, Loadout: loadout}
+}
+
+// weaponInfo 将武器配置转换为协议中的武器信息
+func weaponInfo(w models.Weapon) protocol.WeaponInfo {
+	return protocol.WeaponInfo{
+		Name:               w.Name,
+		Damage:             w.Damage,
+		HeadshotMultiplier: w.HeadshotMultiplier,
+		FalloffStart:       w.FalloffStart,
+		FalloffEnd:         w.FalloffEnd,
+		FalloffMin:         w.FalloffMin,
+		Spread:             w.Spread,
+		CritChance:         w.CritChance,
+		CritMultiplier:     w.CritMultiplier,
+	}
+}
+
+// listHeroes 向客户端发送可选英雄列表
+func (h *Hub) listHeroes(client *Client) {
+	heroes := h.roster().All()
+	resp := protocol.HeroListResponse{Heroes: make([]protocol.HeroInfo, 0, len(heroes))}
+	for _, hero := range heroes {
+		resp.Heroes = append(resp.Heroes, h.heroInfo(hero))
+	}
+	data, _ := json.Marshal(protocol.Message{
+		Type:    protocol.MsgTypeHeroList,
+		Payload: mustMarshal(resp),
+	})
+	client.send <- data
+}
+
+// selectHero 在对局开始前锁定英雄，对局进行中不能更换
+func (h *Hub) selectHero(client *Client, req protocol.SelectHeroRequest) {
+	if _, ok := h.roster().Get(req.HeroID); !ok {
+		h.sendError(client, http.StatusBadRequest, "未知的英雄: "+req.HeroID)
+		return
+	}
+
+	reason := "房间不存在"
+	selected := h.roomStore.Modify(client.roomID, func(r *models.Room) bool {
+		switch {
+		case !slices.Contains(r.Players, client.username):
+			reason = "不在房间中，无法选择英雄"
+		case r.Status == "playing":
+			reason = "游戏进行中，无法更换英雄"
+		default:
+			if r.Heroes == nil {
+				r.Heroes = make(map[string]string)
+			}
+			r.Heroes[client.username] = req.HeroID
+			return true
+		}
+		return false
+	})
+	if !selected {
+		h.sendError(client, http.StatusBadRequest, reason)
+		return
+	}
+
+	data, _ := json.Marshal(protocol.Message{
+		Type:    protocol.MsgTypeHeroSelected,
+		Payload: mustMarshal(protocol.HeroSelected{Player: client.username, HeroID: req.HeroID}),
+	})
+	h.broadcaster.submit(h.roomPeers(client.roomID, ""), data)
+}
+
+// lockHeroes 开始对局前检查玩家都已锁定英雄，机器人使用默认英雄；
+// 同时清理已离开房间的玩家留下的选择，返回尚未锁定英雄的玩家
+func (h *Hub) lockHeroes(r *models.Room) []string {
+	heroes := make(map[string]string, len(r.Players))
+	var missing []string
+	for _, player := range r.Players {
+		id := r.Heroes[player]
+		if models.IsBot(player) {
+			id = h.roster().Default().ID
+		}
+		if _, ok := h.roster().Get(id); !ok {
+			missing = append(missing, player)
+			continue
+		}
+		heroes[player] = id
+	}
+	r.Heroes = heroes
+	return missing
+}
+
+// heroesMissingMessage 返回玩家尚未锁定英雄时给房主的提示
+func heroesMissingMessage(missing []string) string {
+	return "以下玩家尚未选择英雄: " + strings.Join(missing, "、")
+}
+
+// startingHP 返回玩家按英雄生命值倍率折算后的起始生命值
+func (s *roomSession) startingHP(player string) int {
+	return int(math.Max(1, math.Round(float64(s.rules.StartingHP)*s.heroes[player].HP)))
+}
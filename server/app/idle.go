@@ -0,0 +1,65 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"game/protocol"
+)
+
+// touch 记录客户端最近一次活跃时间，并清除空闲警告标记
+func (h *Hub) touch(client *Client) {
+	h.mu.Lock()
+	client.lastActive = h.clock.Now()
+	client.idleWarned = false
+	h.mu.Unlock()
+}
+
+// idleReaper 断开长时间停留在大厅且无任何操作的客户端
+func (h *Hub) idleReaper() {
+	timeout := h.cfg.LobbyIdleTimeout
+	warnBefore := h.cfg.LobbyIdleWarning
+	if warnBefore >= timeout {
+		warnBefore = 0
+	}
+
+	ticker := h.clock.NewTicker(time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C() {
+		var warn, reap []*Client
+		h.mu.Lock()
+		for client := range h.clients {
+			// 房间内的玩家不受大厅空闲策略影响
+			if client.roomID != "" {
+				continue
+			}
+			idle := now.Sub(client.lastActive)
+			if idle >= timeout {
+				reap = append(reap, client)
+			} else if warnBefore > 0 && !client.idleWarned && idle >= timeout-warnBefore {
+				client.idleWarned = true
+				warn = append(warn, client)
+			}
+		}
+		h.mu.Unlock()
+
+		if len(warn) > 0 {
+			msg := protocol.Message{
+				Type: protocol.MsgTypeIdleWarning,
+				Payload: mustMarshal(protocol.IdleWarning{
+					Seconds: int(warnBefore.Seconds()),
+					Message: "您长时间未操作，即将断开连接",
+				}),
+			}
+			data, _ := json.Marshal(msg)
+			h.broadcaster.submit(warn, data)
+		}
+
+		for _, client := range reap {
+			log.Printf("用户 %s 在大厅空闲超过 %s，断开连接", client.username, timeout)
+			// 关闭连接后 readPump 退出并注销客户端
+			client.conn.Close()
+		}
+	}
+}
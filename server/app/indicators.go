@@ -0,0 +1,91 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"game/protocol"
+)
+
+// emotes 支持的表情动作，客户端按名称播放对应的动画
+var emotes = map[string]bool{
+	"wave":     true,
+	"thumbsup": true,
+	"laugh":    true,
+	"cry":      true,
+	"angry":    true,
+	"gg":       true,
+	"salute":   true,
+	"dance":    true,
+}
+
+// indicatorRecipients 返回输入状态和表情的接收者：与聊天频道一致，观战者的只发给其他观战者
+func (h *Hub) indicatorRecipients(client *Client) []*Client {
+	recipients := h.roomPeers(client.roomID, client.username)
+	if client.spectator {
+		recipients = spectatorsOnly(recipients)
+	}
+	return recipients
+}
+
+// typing 把输入状态转发给房间内的其他成员，只用于实时展示，不保存也不经过对局会话
+func (h *Hub) typing(client *Client, req protocol.TypingRequest) {
+	if client.roomID == "" {
+		return
+	}
+	data, _ := json.Marshal(protocol.Message{
+		Type:    protocol.MsgTypeTyping,
+		Payload: mustMarshal(protocol.TypingInfo{From: client.username, Typing: req.Typing}),
+	})
+	h.broadcaster.submit(h.indicatorRecipients(client), data)
+}
+
+// emote 转发表情动作。对局进行中玩家的表情交给游戏会话处理，与对局操作按同一顺序广播，
+// 观战延迟和流量回放时也能出现在对应的时间点；大厅中和观战者的表情直接转发
+func (h *Hub) emote(client *Client, msg protocol.Message) {
+	if client.roomID == "" {
+		h.sendError(client, http.StatusBadRequest, "不在房间中，无法发送表情")
+		return
+	}
+	if !client.spectator && h.session(client.roomID) != nil {
+		h.dispatchToSession(client, msg)
+		return
+	}
+	var req protocol.EmoteRequest
+	if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+		h.sendDecodeError(client, err)
+		return
+	}
+	if !emotes[req.Emote] {
+		h.sendError(client, http.StatusBadRequest, "未知的表情")
+		return
+	}
+	data, _ := json.Marshal(protocol.Message{
+		Type:    protocol.MsgTypeEmote,
+		Payload: mustMarshal(protocol.EmoteInfo{From: client.username, Emote: req.Emote, SentAt: h.clock.Now()}),
+	})
+	h.broadcaster.submit(h.indicatorRecipients(client), data)
+}
+
+// emote 在会话协程中广播对局内的表情，附带距开局的时间
+func (s *roomSession) emote(ev sessionEvent) {
+	var req protocol.EmoteRequest
+	if !s.decode(ev, &req) {
+		return
+	}
+	if !emotes[req.Emote] {
+		s.hub.sendError(ev.client, http.StatusBadRequest, "未知的表情")
+		return
+	}
+	now := s.hub.clock.Now()
+	data, _ := json.Marshal(protocol.Message{
+		Type: protocol.MsgTypeEmote,
+		Payload: mustMarshal(protocol.EmoteInfo{
+			From:      ev.client.username,
+			Emote:     req.Emote,
+			SentAt:    now,
+			MatchTime: now.Sub(s.startedAt).Seconds(),
+		}),
+	})
+	s.hub.sendGame(s.roomID, s.hub.roomPeers(s.roomID, ev.client.username), data)
+}
@@ -0,0 +1,158 @@
+package app
+
+import (
+	"net/http"
+
+	"game/data"
+	"game/models"
+	"game/protocol"
+)
+
+// userClients 返回用户在本实例上的所有连接
+func (h *Hub) userClients(username string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	clients := make([]*Client, 0, 1)
+	for c := range h.clients {
+		if c.username == username {
+			clients = append(clients, c)
+		}
+	}
+	return clients
+}
+
+// invitationInfo 转换为推送给被邀请方的邀请
+func invitationInfo(invite models.Invite) protocol.InvitationInfo {
+	return protocol.InvitationInfo{
+		ID:        invite.ID,
+		From:      invite.From,
+		RoomID:    invite.RoomID,
+		RoomName:  invite.RoomName,
+		ExpiresAt: invite.ExpiresAt,
+	}
+}
+
+// invite 房主邀请其他用户加入自己的房间。对方在线时立即推送，不在线时保存邀请，
+// 对方在有效期内上线后推送；被邀请方需在有效期内接受
+func (h *Hub) invite(client *Client, req protocol.InviteRequest) {
+	reply := func(success bool, message string) {
+		h.sendNotices([]matchNotice{{client: client, msgType: protocol.MsgTypeInviteResult, payload: protocol.InviteResult{Success: success, Message: message}}})
+	}
+
+	if client.roomID == "" || client.spectator {
+		reply(false, "不在房间中，无法邀请")
+		return
+	}
+	room := h.roomStore.GetByID(client.roomID)
+	switch {
+	case room == nil:
+		reply(false, "房间不存在")
+		return
+	case room.HostID != client.username:
+		reply(false, "只有房主可以邀请玩家")
+		return
+	case room.Status == "playing":
+		reply(false, "游戏进行中，无法邀请")
+		return
+	case len(room.Players) >= room.MaxPlayers:
+		reply(false, "房间已满")
+		return
+	case req.Username == client.username:
+		reply(false, "不能邀请自己")
+		return
+	case containsPlayer(room.Players, req.Username):
+		reply(false, "对方已在房间中")
+		return
+	case room.IsBanned(req.Username):
+		reply(false, "对方已被禁止加入该房间")
+		return
+	}
+	from := h.userStore.FindByUsername(client.username)
+	to := h.userStore.FindByUsername(req.Username)
+	if from == nil || to == nil {
+		reply(false, "用户不存在")
+		return
+	}
+
+	now := h.clock.Now()
+	invite := models.Invite{
+		ID:        h.newID("invite"),
+		RoomID:    room.ID,
+		RoomName:  room.Name,
+		FromID:    from.UserID,
+		From:      from.Username,
+		ToID:      to.UserID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.cfg.InviteTTL),
+	}
+	h.invites.Add(invite)
+
+	targets := h.userClients(to.Username)
+	if len(targets) == 0 {
+		reply(true, "对方不在线，上线后会收到邀请")
+		return
+	}
+	notices := make([]matchNotice, 0, len(targets))
+	for _, c := range targets {
+		notices = append(notices, matchNotice{client: c, msgType: protocol.MsgTypeInvitation, payload: invitationInfo(invite)})
+	}
+	h.sendNotices(notices)
+	reply(true, "已向 "+to.Username+" 发送邀请")
+}
+
+// pushPendingInvites 用户上线时推送其不在线期间收到、仍在有效期内的邀请
+func (h *Hub) pushPendingInvites(client *Client) {
+	user := h.userStore.FindByUsername(client.username)
+	if user == nil {
+		return
+	}
+	invites := h.invites.Pending(user.UserID, h.clock.Now())
+	notices := make([]matchNotice, 0, len(invites))
+	for _, invite := range invites {
+		notices = append(notices, matchNotice{client: client, msgType: protocol.MsgTypeInvitation, payload: invitationInfo(invite)})
+	}
+	h.sendNotices(notices)
+}
+
+// acceptInvite 处理被邀请方的回应：接受时加入邀请方的房间，房间人数上限和封禁名单仍然有效；
+// 接受或拒绝的结果会通知在线的邀请方
+func (h *Hub) acceptInvite(client *Client, req protocol.AcceptInviteRequest) {
+	user := h.userStore.FindByUsername(client.username)
+	if user == nil {
+		return
+	}
+	invite := h.invites.Take(req.InviteID, user.UserID, h.clock.Now())
+	if invite == nil {
+		h.sendError(client, http.StatusNotFound, "邀请不存在或已过期")
+		return
+	}
+
+	notify := func(success bool, message string) {
+		inviter := h.userStore.FindByID(invite.FromID)
+		if inviter == nil {
+			return
+		}
+		notices := make([]matchNotice, 0, 1)
+		for _, c := range h.userClients(inviter.Username) {
+			notices = append(notices, matchNotice{client: c, msgType: protocol.MsgTypeInviteResult, payload: protocol.InviteResult{Success: success, Message: message}})
+		}
+		h.sendNotices(notices)
+	}
+	if !req.Accept {
+		notify(false, client.username+" 拒绝了邀请")
+		return
+	}
+	// 已在其他房间中时由 joinRoom 按配置拒绝或先离开原房间
+	h.stopSpectate(client)
+	h.joinRoom(client, invite.RoomID)
+	if client.roomID == invite.RoomID {
+		notify(true, client.username+" 接受了邀请")
+	}
+}
+
+// dropRoomInvites 房间解散后删除发往该房间的邀请
+func (h *Hub) dropRoomInvites(ev data.RoomChange) {
+	if ev.New == nil {
+		h.invites.RemoveRoom(ev.Old.ID)
+	}
+}
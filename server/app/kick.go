@@ -0,0 +1,93 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"game/data"
+	"game/models"
+	"game/protocol"
+)
+
+// kickPlayer 房主把玩家踢出房间，可同时封禁该用户名。封禁名单保存在房间中，房间存在期间
+// HTTP 和 WebSocket 加入、接受邀请以及观战都会检查；对局进行中不能踢出玩家，但可以封禁
+func (h *Hub) kickPlayer(client *Client, req protocol.KickPlayerRequest) {
+	var room models.Room
+	reason := "房间不存在"
+	kicked := false
+	err := data.RunTransaction(h.userStore, h.roomStore, func(tx *data.Txn) error {
+		r := tx.Room(client.roomID)
+		if r == nil {
+			return nil
+		}
+		present := containsPlayer(r.Players, req.Username)
+		switch {
+		case r.HostID != client.username:
+			reason = "只有房主可以踢出玩家"
+			return nil
+		case req.Username == client.username:
+			reason = "不能踢出自己"
+			return nil
+		case present && r.Status == "playing":
+			reason = "游戏进行中，无法踢出玩家"
+			return nil
+		case !present && !req.Ban:
+			reason = "对方不在房间中"
+			return nil
+		}
+		if present {
+			r.Players = slices.DeleteFunc(r.Players, func(p string) bool { return p == req.Username })
+			delete(r.Heroes, req.Username)
+			if len(r.Players) < 2 {
+				r.Status = "waiting"
+			}
+			if user := tx.User(req.Username); user != nil && user.RoomID == r.ID {
+				user.RoomID = ""
+				tx.UpdateUser(*user)
+			}
+		}
+		if req.Ban && !r.IsBanned(req.Username) {
+			r.Banned = append(r.Banned, req.Username)
+		}
+		tx.PutRoom(*r)
+		room = r.Clone()
+		kicked = true
+		return nil
+	})
+	if err != nil {
+		client.logf("踢出玩家失败: %v", err)
+		reason = "踢出玩家失败"
+		kicked = false
+	}
+	if !kicked {
+		h.sendError(client, http.StatusBadRequest, reason)
+		return
+	}
+
+	// 被踢出的玩家回到大厅；被封禁的用户正在观战时同样移出
+	notices := make([]matchNotice, 0, 1)
+	for _, c := range h.userClients(req.Username) {
+		if c.roomID != room.ID || (c.spectator && !req.Ban) {
+			continue
+		}
+		c.roomID = ""
+		c.spectator = false
+		notices = append(notices, matchNotice{client: c, msgType: protocol.MsgTypeKicked, payload: protocol.KickedNotice{RoomID: room.ID, Banned: req.Ban}})
+	}
+	h.sendNotices(notices)
+
+	message := req.Username + " 被踢出了房间"
+	if req.Ban {
+		message = req.Username + " 已被禁止加入房间"
+	}
+	update, _ := json.Marshal(protocol.Message{
+		Type: protocol.MsgTypeJoinRoomResult,
+		Payload: mustMarshal(protocol.JoinRoomResponse{
+			Success: true,
+			Message: message,
+			Room:    h.roomInfo(room),
+		}),
+	})
+	h.broadcaster.submit(h.roomPeers(room.ID, ""), update)
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"game/data"
+	"game/protocol"
+	"game/service"
+)
+
+// lobbyPresence 大厅在线名单：本实例上在线且不在对局中的玩家，以及订阅了名单变化的客户端
+type lobbyPresence struct {
+	mu          sync.Mutex
+	available   map[string]bool // 当前在名单中的用户名
+	subscribers map[*Client]bool
+}
+
+// newLobbyPresence 创建空的大厅在线名单
+func newLobbyPresence() *lobbyPresence {
+	return &lobbyPresence{
+		available:   make(map[string]bool),
+		subscribers: make(map[*Client]bool),
+	}
+}
+
+// forget 连接断开时取消订阅
+func (p *lobbyPresence) forget(client *Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.subscribers, client)
+}
+
+// lobbyPlayer 返回名单中玩家的展示信息
+func (h *Hub) lobbyPlayer(username string) protocol.LobbyPlayer {
+	player := protocol.LobbyPlayer{Username: username, Rating: h.playerRating(username)}
+	if user := h.userStore.FindByUsername(username); user != nil {
+		player.AvatarURL = service.AvatarURL(*user)
+	}
+	return player
+}
+
+// lobbyAvailable 判断用户是否应出现在大厅在线名单中：在本实例上有连接，且所在房间不在对局中
+func (h *Hub) lobbyAvailable(username string) bool {
+	if len(h.userClients(username)) == 0 {
+		return false
+	}
+	user := h.userStore.FindByUsername(username)
+	if user == nil {
+		return false
+	}
+	if user.RoomID == "" {
+		return true
+	}
+	room := h.roomStore.GetByID(user.RoomID)
+	return room == nil || room.Status != "playing" || !containsPlayer(room.Players, username)
+}
+
+// subscribeLobby 订阅或取消订阅大厅在线名单，订阅时先发送一次完整名单，之后只推送变化
+func (h *Hub) subscribeLobby(client *Client, req protocol.LobbyPresenceRequest) {
+	p := h.presence
+	p.mu.Lock()
+	if !req.Subscribe {
+		delete(p.subscribers, client)
+		p.mu.Unlock()
+		return
+	}
+	p.subscribers[client] = true
+	usernames := make([]string, 0, len(p.available))
+	for username := range p.available {
+		usernames = append(usernames, username)
+	}
+	p.mu.Unlock()
+
+	sort.Strings(usernames)
+	list := protocol.LobbyPresenceList{Players: make([]protocol.LobbyPlayer, 0, len(usernames))}
+	// 大厅握手时附带快速匹配当前轮换的精选玩法
+	if playlist := service.PlaylistInfo(h.playlist.Get(), h.clock.Now()); playlist.Current != nil {
+		list.Playlist = &playlist
+	}
+	for _, username := range usernames {
+		list.Players = append(list.Players, h.lobbyPlayer(username))
+	}
+	h.sendNotices([]matchNotice{{client: client, msgType: protocol.MsgTypeLobbyPresence, payload: list}})
+}
+
+// updateLobbyPresence 重新判断用户是否在大厅在线名单中，有变化时推送给订阅者
+func (h *Hub) updateLobbyPresence(username string) {
+	available := h.lobbyAvailable(username)
+	p := h.presence
+	p.mu.Lock()
+	if p.available[username] == available {
+		p.mu.Unlock()
+		return
+	}
+	if available {
+		p.available[username] = true
+	} else {
+		delete(p.available, username)
+	}
+	subscribers := make([]*Client, 0, len(p.subscribers))
+	for c := range p.subscribers {
+		subscribers = append(subscribers, c)
+	}
+	p.mu.Unlock()
+	if len(subscribers) == 0 {
+		return
+	}
+
+	delta := protocol.LobbyPresenceDelta{Op: protocol.LobbyPresenceLeave, Player: protocol.LobbyPlayer{Username: username}}
+	if available {
+		delta = protocol.LobbyPresenceDelta{Op: protocol.LobbyPresenceJoin, Player: h.lobbyPlayer(username)}
+	}
+	msg, _ := json.Marshal(protocol.Message{Type: protocol.MsgTypeLobbyDelta, Payload: mustMarshal(delta)})
+	h.broadcaster.submit(subscribers, msg)
+}
+
+// lobbyRoomChange 房间开局或对局结束时更新房间内玩家在大厅在线名单中的状态
+func (h *Hub) lobbyRoomChange(ev data.RoomChange) {
+	wasPlaying := ev.Old != nil && ev.Old.Status == "playing"
+	isPlaying := ev.New != nil && ev.New.Status == "playing"
+	if wasPlaying == isPlaying {
+		return
+	}
+	players := make(map[string]bool)
+	if ev.Old != nil {
+		for _, player := range ev.Old.Players {
+			players[player] = true
+		}
+	}
+	if ev.New != nil {
+		for _, player := range ev.New.Players {
+			players[player] = true
+		}
+	}
+	for player := range players {
+		h.updateLobbyPresence(player)
+	}
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"log"
+	"time"
+)
+
+// resultPruner 定期清理超过保留时长的游戏结果
+func (s *Server) resultPruner() {
+	ticker := s.hub.clock.NewTicker(s.cfg.ResultPruneInterval)
+	defer ticker.Stop()
+	for {
+		cutoff := s.hub.clock.Now().Add(-s.cfg.ResultRetention)
+		removed, err := s.resultService.PruneResults(cutoff, s.cfg.ResultArchive)
+		if err != nil {
+			log.Printf("清理游戏结果失败: %v", err)
+		} else if removed > 0 {
+			log.Printf("已清理 %d 条 %s 之前的游戏结果", removed, cutoff.Format(time.RFC3339))
+		}
+		<-ticker.C()
+	}
+}
+
+// resultCompactor 定期压缩游戏结果日志
+func (s *Server) resultCompactor() {
+	ticker := s.hub.clock.NewTicker(s.cfg.ResultCompactInterval)
+	defer ticker.Stop()
+	for range ticker.C() {
+		compacted, err := s.resultStore.Compact()
+		if err != nil {
+			log.Printf("压缩游戏结果日志失败: %v", err)
+		} else if compacted {
+			log.Println("已压缩游戏结果日志")
+		}
+	}
+}
+
+// backupScheduler 定期备份数据目录
+func (s *Server) backupScheduler() {
+	ticker := s.hub.clock.NewTicker(s.cfg.BackupInterval)
+	defer ticker.Stop()
+	for range ticker.C() {
+		backup, err := s.backupService.CreateBackup()
+		if err != nil {
+			log.Printf("备份数据失败: %v", err)
+			continue
+		}
+		log.Printf("已创建备份 %s（%d 字节）", backup.Name, backup.Size)
+	}
+}
+
+// roomRepairer 定期检查并修复用户与房间之间不一致的引用
+func (s *Server) roomRepairer() {
+	ticker := s.hub.clock.NewTicker(s.cfg.RepairInterval)
+	defer ticker.Stop()
+	for range ticker.C() {
+		s.hub.rooms.RepairRooms(s.cfg.RepairOfflineAfter)
+	}
+}
+
+// analyticsSampler 定期采样在线连接数，用于统计每日峰值和平均在线数
+func (h *Hub) analyticsSampler() {
+	ticker := h.clock.NewTicker(h.cfg.AnalyticsSampleInterval)
+	defer ticker.Stop()
+	for now := range ticker.C() {
+		h.analytics.RecordConcurrency(h.ConnectionCount(), now)
+	}
+}
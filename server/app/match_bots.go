@@ -0,0 +1,86 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"game/protocol"
+)
+
+// 按评分选择机器人难度的分界
+const (
+	botEasyBelow = baseRating - 2*ratingPerWin // 低于该评分使用 easy
+	botHardFrom  = baseRating + 4*ratingPerWin // 达到该评分使用 hard
+)
+
+// botDifficultyFor 按玩家评分选择机器人难度
+func botDifficultyFor(rating int) string {
+	switch {
+	case rating < botEasyBelow:
+		return "easy"
+	case rating >= botHardFrom:
+		return "hard"
+	}
+	return "normal"
+}
+
+// backfillBots 排队超过 MatchBotBackfill 的玩家：开启 MatchBotAuto 时直接创建机器人对局，否则提供一次机器人对局
+func (h *Hub) backfillBots(now time.Time) {
+	threshold := h.cfg.MatchBotBackfill
+	if threshold <= 0 {
+		return
+	}
+	m := h.matcher
+	var notices []matchNotice
+	var auto []*queueEntry
+	m.mu.Lock()
+	waiting := m.queue[:0]
+	for _, e := range m.queue {
+		if e.botOffered || now.Sub(e.since) < threshold || e.client.roomID != "" {
+			waiting = append(waiting, e)
+			continue
+		}
+		e.botOffered = true
+		if h.cfg.MatchBotAuto {
+			auto = append(auto, e)
+			continue
+		}
+		waiting = append(waiting, e)
+		notices = append(notices, matchNotice{e.client, protocol.MsgTypeBotMatchOffer, protocol.BotMatchOffer{
+			Difficulty: botDifficultyFor(e.rating),
+			Waited:     int(now.Sub(e.since).Seconds()),
+		}})
+	}
+	m.queue = waiting
+	m.mu.Unlock()
+
+	h.sendNotices(notices)
+	for _, e := range auto {
+		h.openBotMatch(e)
+	}
+}
+
+// acceptBotMatch 玩家接受机器人对局，退出匹配队列
+func (h *Hub) acceptBotMatch(client *Client) {
+	m := h.matcher
+	var entry *queueEntry
+	m.mu.Lock()
+	for i, e := range m.queue {
+		if e.client == client && e.botOffered {
+			entry = e
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			break
+		}
+	}
+	m.mu.Unlock()
+	if entry == nil {
+		h.sendError(client, http.StatusBadRequest, "没有可接受的机器人对局")
+		return
+	}
+	h.openBotMatch(entry)
+}
+
+// openBotMatch 为排队玩家创建与机器人的对局，对局结果标记为机器人对局，不计入匹配评分
+func (h *Hub) openBotMatch(e *queueEntry) {
+	h.openMatchRoom([]*Client{e.client}, []string{botName(botDifficultyFor(e.rating))}, "已为您匹配机器人对手")
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"math"
+	"time"
+
+	"game/models"
+	"game/protocol"
+)
+
+const (
+	// baseRating 没有战绩的玩家的评分。服务器没有独立的天梯分，评分按历史胜负场估算
+	baseRating = 1000
+	// ratingPerWin 每多赢一场（或少输一场）增加的评分
+	ratingPerWin = 25
+	// ratingBucketWidth 评分分布统计的区间宽度
+	ratingBucketWidth = 100
+)
+
+// matchTotals 服务器启动以来的配对统计，由 matchmaker.mu 保护
+type matchTotals struct {
+	proposed   int
+	accepted   int
+	cancelled  int
+	sameRegion int
+	ratingGap  int           // 所有配对双方评分差之和
+	waited     time.Duration // 所有配对玩家配对时等待时间之和
+}
+
+// record 记录一次配对
+func (t *matchTotals) record(pm *pendingMatch, now time.Time) {
+	t.proposed++
+	a, b := pm.entries[0], pm.entries[1]
+	t.ratingGap += abs(a.rating - b.rating)
+	if a.client.region != "" && a.client.region == b.client.region {
+		t.sameRegion++
+	}
+	for _, e := range pm.entries {
+		t.waited += now.Sub(e.since)
+	}
+}
+
+// quality 汇总配对质量
+func (t *matchTotals) quality() protocol.MatchQuality {
+	q := protocol.MatchQuality{
+		Proposed:  t.proposed,
+		Accepted:  t.accepted,
+		Cancelled: t.cancelled,
+	}
+	if decided := t.accepted + t.cancelled; decided > 0 {
+		q.AcceptRate = float64(t.accepted) / float64(decided)
+	}
+	if t.proposed > 0 {
+		q.AvgPairWaitSeconds = t.waited.Seconds() / float64(2*t.proposed)
+		q.AvgRatingGap = float64(t.ratingGap) / float64(t.proposed)
+		q.SameRegionRate = float64(t.sameRegion) / float64(t.proposed)
+	}
+	return q
+}
+
+// abs 整数绝对值
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// playerRating 按历史胜负场估算玩家评分，再计入申诉成立后的评分补偿；改名前的对局按稳定用户ID计入
+func (h *Hub) playerRating(username string) int {
+	rating := baseRating
+	var userID string
+	if user := h.userStore.FindByUsername(username); user != nil {
+		userID = user.UserID
+	}
+	for _, r := range h.resultStore.FindByUser(userID, username) {
+		name := r.NameOf(userID)
+		if name == "" {
+			name = username
+		}
+		rating += h.resultRating(r, name)
+	}
+	rating += h.disputes.Adjustment(userID)
+	return max(rating, 0)
+}
+
+// resultRating 一局结果为玩家 name 带来的评分变化，机器人对局和有玩家处于作弊标记状态的对局不计入
+func (h *Hub) resultRating(r models.GameResult, name string) int {
+	if r.BotMatch || h.flaggedResult(r) {
+		return 0
+	}
+	switch name {
+	case r.Winner:
+		// 活动期间赢下的对局按活动倍率计分
+		mult := 1.0
+		if r.WinMultiplier > 0 {
+			mult = r.WinMultiplier
+		}
+		return int(math.Round(ratingPerWin * mult))
+	case r.Loser:
+		return -ratingPerWin
+	}
+	return 0
+}
+
+// RatingDeltas 返回一局结果为各玩家带来的评分变化，按用户ID索引，实现 service.DisputeEvidence
+func (h *Hub) RatingDeltas(r models.GameResult) map[string]int {
+	deltas := make(map[string]int)
+	for _, p := range r.Players {
+		if p.UserID != "" {
+			deltas[p.UserID] = h.resultRating(r, p.Username)
+		}
+	}
+	return deltas
+}
+
+// Replay 返回对局期间房间的入站流量录制，未开启录制时为空，实现 service.DisputeEvidence
+func (h *Hub) Replay(r models.GameResult) []models.TrafficRecord {
+	from := r.PlayTime.Add(-time.Duration(r.Duration) * time.Second)
+	return h.recorder.roomRecords(r.RoomID, from, r.PlayTime)
+}
+
+// MatchStats 返回匹配队列统计，detail 为 true 时附带队列中的玩家列表
+func (h *Hub) MatchStats(detail bool) protocol.MatchStats {
+	now := h.clock.Now()
+	m := h.matcher
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := protocol.MatchStats{
+		QueueLength:        len(m.queue),
+		Pending:            len(m.pending),
+		RatingDistribution: make([]protocol.RatingBucket, 0),
+		Quality:            m.totals.quality(),
+	}
+	var waited time.Duration
+	buckets := make(map[int]int)
+	for _, e := range m.queue {
+		wait := now.Sub(e.since)
+		waited += wait
+		buckets[e.rating/ratingBucketWidth]++
+		if detail {
+			stats.Queue = append(stats.Queue, protocol.QueuedPlayerInfo{
+				Username:    e.client.username,
+				Region:      e.client.region,
+				Rating:      e.rating,
+				WaitSeconds: wait.Seconds(),
+			})
+		}
+	}
+	if len(m.queue) > 0 {
+		stats.AvgWaitSeconds = waited.Seconds() / float64(len(m.queue))
+		lo, hi := m.queue[0].rating/ratingBucketWidth, m.queue[0].rating/ratingBucketWidth
+		for b := range buckets {
+			lo, hi = min(lo, b), max(hi, b)
+		}
+		for b := lo; b <= hi; b++ {
+			stats.RatingDistribution = append(stats.RatingDistribution, protocol.RatingBucket{
+				Min:     b * ratingBucketWidth,
+				Max:     (b + 1) * ratingBucketWidth,
+				Players: buckets[b],
+			})
+		}
+	}
+	return stats
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"game/models"
+	"game/protocol"
+)
+
+// matchVote 进行中的对局内投票
+type matchVote struct {
+	kind    string
+	mapName string
+	voters  map[string]bool
+	expires time.Time
+}
+
+// matchVote 处理对局内投票，让卡住或出问题的对局不必所有人强退就能了结。投票者是仍在场的真人玩家，
+// 平局结束需要全员同意，重开当前局和下一场换图需要过半数同意；返回 true 表示对局已结束
+func (s *roomSession) matchVote(ev sessionEvent) bool {
+	var req protocol.MatchVoteRequest
+	if !s.decode(ev, &req) {
+		return false
+	}
+	voter := ev.client.username
+	now := s.hub.clock.Now()
+	if s.vote != nil && !now.Before(s.vote.expires) {
+		s.vote = nil
+	}
+
+	reason := ""
+	switch {
+	case s.hub.cfg.MatchVoteWindow <= 0:
+		reason = "服务器未开启对局内投票"
+	case s.stats[voter] == nil || s.out[voter]:
+		reason = "只有在场的玩家可以投票"
+	case req.Kind != protocol.MatchVoteDraw && req.Kind != protocol.MatchVoteRestartRound && req.Kind != protocol.MatchVoteChangeMap:
+		reason = "未知的投票类型 " + req.Kind
+	case req.Kind == protocol.MatchVoteChangeMap && !knownMap(req.Map):
+		reason = "未知的地图 " + req.Map
+	case s.vote != nil && (s.vote.kind != req.Kind || s.vote.mapName != req.Map):
+		reason = "已有进行中的投票，请等待投票结束"
+	}
+	if reason != "" {
+		s.hub.sendError(ev.client, http.StatusBadRequest, reason)
+		return false
+	}
+	if s.vote == nil {
+		s.vote = &matchVote{kind: req.Kind, mapName: req.Map, voters: make(map[string]bool), expires: now.Add(s.hub.cfg.MatchVoteWindow)}
+	}
+	s.vote.voters[voter] = true
+
+	eligible, votes := 0, 0
+	for _, p := range s.players {
+		if s.out[p] || models.IsBot(p) {
+			continue
+		}
+		eligible++
+		if s.vote.voters[p] {
+			votes++
+		}
+	}
+	status := protocol.MatchVoteStatus{
+		Kind:      s.vote.kind,
+		Map:       s.vote.mapName,
+		Votes:     votes,
+		Needed:    eligible/2 + 1,
+		ExpiresAt: s.vote.expires,
+	}
+	if status.Kind == protocol.MatchVoteDraw {
+		status.Needed = eligible
+	}
+	if votes < status.Needed {
+		s.broadcast(protocol.MsgTypeMatchVoteState, status)
+		return false
+	}
+
+	s.vote = nil
+	status.Passed = true
+	s.broadcast(protocol.MsgTypeMatchVoteState, status)
+	log.Printf("房间 %s 投票通过: %s %s (%d/%d)", s.roomID, status.Kind, status.Map, votes, eligible)
+	switch status.Kind {
+	case protocol.MatchVoteDraw:
+		s.finish(protocol.GameOverInfo{Reason: models.ResultReasonVoteDraw})
+		return true
+	case protocol.MatchVoteRestartRound:
+		s.restartRound()
+	case protocol.MatchVoteChangeMap:
+		// 进行中的对局沿用开局时的地图，房间回到等待状态后的下一场使用新地图
+		s.hub.roomStore.Modify(s.roomID, func(room *models.Room) bool {
+			room.Map = status.Map
+			return true
+		})
+	}
+	return false
+}
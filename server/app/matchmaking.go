@@ -0,0 +1,444 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"game/data"
+	"game/models"
+	"game/protocol"
+)
+
+const (
+	// matchmakerTick 检查待确认对局是否超时、重新尝试放宽区域配对的间隔
+	matchmakerTick = time.Second
+	// queueStatusInterval 向排队玩家推送队列状态的间隔
+	queueStatusInterval = 5 * time.Second
+)
+
+// queueEntry 匹配队列中的一名玩家
+type queueEntry struct {
+	client  *Client
+	since   time.Time // 入队时间，被对手拒绝后重新入队时保留
+	rating  int       // 入队时按历史战绩估算的评分
+	flagged bool      // 入队时处于作弊标记状态，只与同样被标记的玩家配对
+
+	notified   time.Time // 最近一次推送队列状态的时间
+	botOffered bool      // 是否已提供与机器人的对局
+}
+
+// pendingMatch 已配对、等待双方确认的对局
+type pendingMatch struct {
+	id       string
+	entries  []*queueEntry
+	accepted map[string]bool
+	deadline time.Time
+}
+
+// matchmaker 匹配队列：按入队顺序优先与同区域玩家两两配对，双方都确认后才创建房间
+type matchmaker struct {
+	mu        sync.Mutex
+	queue     []*queueEntry
+	pending   map[string]*pendingMatch // 按对局ID索引
+	cooldowns map[string]time.Time     // 拒绝或未确认对局的玩家在此时间前不能重新排队
+	leavers   map[string]time.Time     // 中途弃赛的玩家在此时间前不能重新排队
+	totals    matchTotals              // 配对质量统计
+}
+
+// newMatchmaker 创建匹配队列
+func newMatchmaker() *matchmaker {
+	return &matchmaker{
+		pending:   make(map[string]*pendingMatch),
+		cooldowns: make(map[string]time.Time),
+		leavers:   make(map[string]time.Time),
+	}
+}
+
+// matchNotice 在锁外发送给玩家的匹配通知
+type matchNotice struct {
+	client  *Client
+	msgType protocol.MessageType
+	payload interface{}
+}
+
+// sendNotices 发送匹配通知
+func (h *Hub) sendNotices(notices []matchNotice) {
+	for _, n := range notices {
+		data, err := json.Marshal(protocol.Message{Type: n.msgType, Payload: mustMarshal(n.payload)})
+		if err != nil {
+			continue
+		}
+		h.broadcaster.submit([]*Client{n.client}, data)
+	}
+}
+
+// queued 玩家是否在队列中或处于待确认对局中，调用方需持有 mu
+func (m *matchmaker) queued(username string) bool {
+	for _, e := range m.queue {
+		if e.client.username == username {
+			return true
+		}
+	}
+	for _, pm := range m.pending {
+		for _, e := range pm.entries {
+			if e.client.username == username {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// joinQueue 玩家进入匹配队列，房间中的玩家和处于冷却期（包括弃赛惩罚）的玩家不能排队
+func (h *Hub) joinQueue(client *Client) {
+	reply := func(result protocol.QueueResult) {
+		h.sendNotices([]matchNotice{{client, protocol.MsgTypeQueueResult, result}})
+	}
+	if client.roomID != "" {
+		reply(protocol.QueueResult{Message: "已在房间中，无法匹配"})
+		return
+	}
+
+	now := h.clock.Now()
+	rating := h.playerRating(client.username)
+	flagged := h.flaggedUser(client.username)
+	m := h.matcher
+	m.mu.Lock()
+	if until, ok := m.cooldowns[client.username]; ok && now.Before(until) {
+		m.mu.Unlock()
+		reply(protocol.QueueResult{
+			Message:  "拒绝或未确认对局后需要等待一段时间才能重新匹配",
+			Cooldown: int(until.Sub(now).Seconds() + 0.5),
+		})
+		return
+	}
+	delete(m.cooldowns, client.username)
+	if until, ok := m.leavers[client.username]; ok && now.Before(until) {
+		m.mu.Unlock()
+		reply(protocol.QueueResult{
+			Message:  "中途弃赛后需要等待一段时间才能重新匹配",
+			Cooldown: int(until.Sub(now).Seconds() + 0.5),
+		})
+		return
+	}
+	delete(m.leavers, client.username)
+	if m.queued(client.username) {
+		m.mu.Unlock()
+		reply(protocol.QueueResult{Message: "已在匹配中"})
+		return
+	}
+	m.queue = append(m.queue, &queueEntry{client: client, since: now, rating: rating, flagged: flagged})
+	m.mu.Unlock()
+
+	reply(protocol.QueueResult{Success: true, Message: "开始匹配"})
+	h.matchQueue()
+}
+
+// compatible 判断两名排队玩家能否配对：被标记作弊的玩家只与同样被标记的玩家配对；区域相同或有一方区域未知时可以直接配对，
+// 任一方等待超过 MatchRegionWiden 后放宽到所有区域
+func (h *Hub) compatible(a, b *queueEntry, now time.Time) bool {
+	if a.flagged != b.flagged {
+		return false
+	}
+	if a.client.region == "" || b.client.region == "" || a.client.region == b.client.region {
+		return true
+	}
+	widen := h.cfg.MatchRegionWiden
+	return now.Sub(a.since) >= widen || now.Sub(b.since) >= widen
+}
+
+// nextPair 按入队顺序找出第一对可以配对的玩家，没有时返回 -1
+func (h *Hub) nextPair(queue []*queueEntry, now time.Time) (int, int) {
+	for i := range queue {
+		for j := i + 1; j < len(queue); j++ {
+			if h.compatible(queue[i], queue[j], now) {
+				return i, j
+			}
+		}
+	}
+	return -1, -1
+}
+
+// matchQueue 按入队顺序两两配对，优先同区域，向双方发送确认提示
+func (h *Hub) matchQueue() {
+	m := h.matcher
+	now := h.clock.Now()
+	var notices []matchNotice
+	m.mu.Lock()
+	// 排队期间自行进入房间的玩家退出匹配
+	waiting := m.queue[:0]
+	for _, e := range m.queue {
+		if e.client.roomID == "" {
+			waiting = append(waiting, e)
+		}
+	}
+	m.queue = waiting
+	for {
+		i, j := h.nextPair(m.queue, now)
+		if i < 0 {
+			break
+		}
+		pm := &pendingMatch{
+			id:       fmt.Sprintf("match_%d", now.UnixNano()+int64(len(m.pending))),
+			entries:  []*queueEntry{m.queue[i], m.queue[j]},
+			accepted: make(map[string]bool),
+			deadline: now.Add(h.cfg.MatchAcceptTimeout),
+		}
+		m.queue = append(m.queue[:j], m.queue[j+1:]...)
+		m.queue = append(m.queue[:i], m.queue[i+1:]...)
+		m.pending[pm.id] = pm
+		m.totals.record(pm, now)
+
+		players := []string{pm.entries[0].client.username, pm.entries[1].client.username}
+		for _, e := range pm.entries {
+			notices = append(notices, matchNotice{e.client, protocol.MsgTypeMatchReady, protocol.MatchReady{
+				MatchID: pm.id,
+				Players: players,
+				Seconds: int(h.cfg.MatchAcceptTimeout.Seconds()),
+			}})
+		}
+	}
+	m.mu.Unlock()
+	h.sendNotices(notices)
+}
+
+// acceptMatch 处理玩家对配对结果的确认或拒绝，双方都确认后创建房间
+func (h *Hub) acceptMatch(client *Client, req protocol.AcceptMatchRequest) {
+	m := h.matcher
+	m.mu.Lock()
+	pm := m.pending[req.MatchID]
+	if pm == nil || !pm.has(client.username) {
+		m.mu.Unlock()
+		h.sendError(client, http.StatusBadRequest, "对局不存在或已取消")
+		return
+	}
+	if !req.Accept {
+		notices := h.cancelMatch(pm, "有玩家拒绝了对局")
+		m.mu.Unlock()
+		h.sendNotices(notices)
+		h.matchQueue()
+		return
+	}
+	pm.accepted[client.username] = true
+	if len(pm.accepted) < len(pm.entries) {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.pending, pm.id)
+	m.totals.accepted++
+	m.mu.Unlock()
+	h.createMatchRoom(pm)
+}
+
+// has 判断玩家是否属于该对局
+func (pm *pendingMatch) has(username string) bool {
+	for _, e := range pm.entries {
+		if e.client.username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelMatch 取消待确认对局：已确认的玩家回到队首继续匹配，拒绝或未确认的玩家进入冷却期。
+// 调用方需持有 mu，返回需要在锁外发送的通知
+func (h *Hub) cancelMatch(pm *pendingMatch, reason string) []matchNotice {
+	m := h.matcher
+	delete(m.pending, pm.id)
+	m.totals.cancelled++
+	now := h.clock.Now()
+	var requeue []*queueEntry
+	var notices []matchNotice
+	for _, e := range pm.entries {
+		accepted := pm.accepted[e.client.username]
+		if accepted {
+			requeue = append(requeue, e)
+		} else {
+			m.cooldowns[e.client.username] = now.Add(h.cfg.MatchDeclineCooldown)
+		}
+		notices = append(notices, matchNotice{e.client, protocol.MsgTypeMatchCancelled, protocol.MatchCancelled{
+			MatchID:  pm.id,
+			Reason:   reason,
+			Requeued: accepted,
+		}})
+	}
+	m.queue = append(requeue, m.queue...)
+	return notices
+}
+
+// leaveQueue 玩家退出匹配：在队列中直接移除，在待确认对局中视为拒绝，reason 为通知对手的取消原因。
+// 返回玩家是否在匹配中
+func (h *Hub) leaveQueue(username, reason string) bool {
+	m := h.matcher
+	m.mu.Lock()
+	found := false
+	for i, e := range m.queue {
+		if e.client.username == username {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			found = true
+			break
+		}
+	}
+	var notices []matchNotice
+	for _, pm := range m.pending {
+		if pm.has(username) {
+			delete(pm.accepted, username)
+			notices = h.cancelMatch(pm, reason)
+			found = true
+			break
+		}
+	}
+	m.mu.Unlock()
+	h.sendNotices(notices)
+	if len(notices) > 0 {
+		h.matchQueue()
+	}
+	return found
+}
+
+// cancelQueue 玩家主动取消匹配
+func (h *Hub) cancelQueue(client *Client) {
+	result := protocol.QueueResult{Message: "不在匹配中"}
+	if h.leaveQueue(client.username, "有玩家取消了匹配") {
+		result = protocol.QueueResult{Success: true, Message: "已取消匹配"}
+	}
+	h.sendNotices([]matchNotice{{client, protocol.MsgTypeQueueLeft, result}})
+}
+
+// pushQueueStatus 向距上次推送已超过 queueStatusInterval 的排队玩家推送队列状态
+func (h *Hub) pushQueueStatus(now time.Time) {
+	m := h.matcher
+	var notices []matchNotice
+	m.mu.Lock()
+	estimate := -1
+	if q := m.totals.quality(); q.Proposed > 0 {
+		estimate = int(q.AvgPairWaitSeconds + 0.5)
+	}
+	for i, e := range m.queue {
+		if now.Sub(e.notified) < queueStatusInterval {
+			continue
+		}
+		e.notified = now
+		waited := now.Sub(e.since)
+		status := protocol.QueueStatus{
+			Position:      i + 1,
+			QueueLength:   len(m.queue),
+			Waited:        int(waited.Seconds()),
+			EstimatedWait: estimate,
+		}
+		if estimate >= 0 {
+			status.EstimatedWait = max(estimate-status.Waited, 0)
+		}
+		if widen := h.cfg.MatchRegionWiden; e.client.region != "" && waited < widen {
+			status.SearchRegion = e.client.region
+			status.WidenIn = int((widen - waited).Seconds() + 0.5)
+		}
+		notices = append(notices, matchNotice{e.client, protocol.MsgTypeQueueStatus, status})
+	}
+	m.mu.Unlock()
+	h.sendNotices(notices)
+}
+
+// matchmakerLoop 定期取消超时未确认的对局，让等待较久的玩家放宽区域重新配对，并推送队列状态
+func (h *Hub) matchmakerLoop() {
+	ticker := h.clock.NewTicker(matchmakerTick)
+	defer ticker.Stop()
+	for now := range ticker.C() {
+		m := h.matcher
+		var notices []matchNotice
+		m.mu.Lock()
+		for _, pm := range m.pending {
+			if now.After(pm.deadline) {
+				notices = append(notices, h.cancelMatch(pm, "有玩家未在规定时间内确认")...)
+			}
+		}
+		m.mu.Unlock()
+		h.sendNotices(notices)
+		h.matchQueue()
+		h.backfillBots(now)
+		h.pushQueueStatus(now)
+	}
+}
+
+// createMatchRoom 为双方都确认的对局创建房间
+func (h *Hub) createMatchRoom(pm *pendingMatch) {
+	clients := make([]*Client, 0, len(pm.entries))
+	for _, e := range pm.entries {
+		clients = append(clients, e.client)
+	}
+	h.openMatchRoom(clients, nil, "匹配成功")
+}
+
+// openMatchRoom 为匹配到的玩家和机器人创建房间，第一名玩家为房主；玩家区域相同时房间归属该区域
+func (h *Hub) openMatchRoom(clients []*Client, bots []string, message string) {
+	players := make([]string, 0, len(clients)+len(bots))
+	region := clients[0].region
+	for _, c := range clients {
+		players = append(players, c.username)
+		if c.region != region {
+			region = ""
+		}
+	}
+	players = append(players, bots...)
+	now := h.clock.Now()
+	rules := models.DefaultRules()
+	mapName := models.DefaultMap
+	// 匹配对局使用精选玩法当前轮换到的地图和目标，活动轮换地图目标时以活动为准
+	if entry, ok := h.playlist.Current(now); ok {
+		mapName = entry.Map
+		rules.Objective = entry.Objective
+	}
+	if mode := h.events.Mode(now); mode != "" {
+		rules.Objective = mode
+	}
+	h.openRoom(models.Room{
+		ID:         h.newID("room"),
+		Name:       "匹配对局",
+		HostID:     players[0],
+		Players:    players,
+		MaxPlayers: len(players),
+		Status:     "ready",
+		CreatedAt:  now,
+		Map:        mapName,
+		Rules:      rules,
+		Region:     region,
+	}, clients, message)
+}
+
+// openRoom 创建已就绪的房间，把在线的玩家直接拉入房间并通知，房间的玩家列表中还可以有机器人等不在线的成员
+func (h *Hub) openRoom(room models.Room, clients []*Client, message string) error {
+	err := data.RunTransaction(h.userStore, h.roomStore, func(tx *data.Txn) error {
+		tx.PutRoom(room)
+		for _, player := range room.Players {
+			if user := tx.User(player); user != nil {
+				user.RoomID = room.ID
+				tx.UpdateUser(*user)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("创建房间 %s 失败: %v", room.Name, err)
+		for _, c := range clients {
+			h.sendError(c, http.StatusInternalServerError, "创建房间失败")
+		}
+		return err
+	}
+
+	var notices []matchNotice
+	for _, c := range clients {
+		c.roomID = room.ID
+		c.spectator = false
+		notices = append(notices, matchNotice{c, protocol.MsgTypeJoinRoomResult, protocol.JoinRoomResponse{
+			Success: true,
+			Message: message,
+			Room:    h.roomInfo(room),
+		}})
+	}
+	h.sendNotices(notices)
+	return nil
+}
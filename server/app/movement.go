@@ -0,0 +1,90 @@
+package app
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"game/models"
+	"game/protocol"
+)
+
+// 玩家移动参数，与客户端 ShootingGame.vue 保持一致
+const (
+	playerMoveSpeed = 5.0  // 英雄和规则倍率均为 1 时每帧移动的像素
+	moveFrameRate   = 60.0 // 客户端每秒帧数
+	moveTolerance   = 30.0 // 在速度上限之外额外允许的位移，吸收网络抖动造成的消息合并
+)
+
+// obstacle 地图中的障碍物，玩家不能与之重叠
+type obstacle struct {
+	x, y, w, h float64
+}
+
+// mapObstacles 各地图的碰撞几何，未列出的地图只限制在战场边界内
+var mapObstacles = map[string][]obstacle{
+	models.DefaultMap: nil,
+	"pillars": {
+		{x: 40, y: 110, w: 40, h: 40},
+		{x: fieldWidth - 80, y: 250, w: 40, h: 40},
+	},
+}
+
+// knownMap 地图是否在服务器支持的地图中
+func knownMap(name string) bool {
+	_, ok := mapObstacles[name]
+	return ok
+}
+
+// columnOf 返回玩家所在的横坐标，与机器人的站位规则一致：第一名玩家在左侧，其余在右侧
+func (s *roomSession) columnOf(username string) float64 {
+	if len(s.players) > 0 && s.players[0] == username {
+		return 50
+	}
+	return fieldWidth - 50 - playerWidth
+}
+
+// validateMove 校验玩家上报的纵向位置：位移超过速度上限时截断到上限，
+// 穿过障碍物时停在障碍物边缘，并限制在战场范围内，返回修正后的位置并记录本次移动时间
+func (s *roomSession) validateMove(username string, y float64, now time.Time) float64 {
+	from := s.positionOf(username)
+	since, ok := s.lastMove[username]
+	if !ok {
+		since = s.startedAt
+	}
+	s.lastMove[username] = now
+	frames := now.Sub(since).Seconds() * moveFrameRate
+	maxStep := playerMoveSpeed*s.rules.MoveSpeed*s.heroes[username].Speed*frames + moveTolerance
+	to := clamp(clamp(y, from-maxStep, from+maxStep), 0, fieldHeight-playerHeight)
+
+	x := s.columnOf(username)
+	for _, o := range mapObstacles[s.mapName] {
+		if x+playerWidth <= o.x || x >= o.x+o.w {
+			continue
+		}
+		switch {
+		case to > from && from+playerHeight <= o.y && to+playerHeight > o.y:
+			to = o.y - playerHeight
+		case to < from && from >= o.y+o.h && to < o.y+o.h:
+			to = o.y + o.h
+		}
+	}
+	return to
+}
+
+// correctMove 通知上报方以服务器认定的位置为准
+func (s *roomSession) correctMove(client *Client, y, reported float64) {
+	data, err := json.Marshal(protocol.Message{
+		Type:    protocol.MsgTypeMoveCorrection,
+		Payload: mustMarshal(protocol.MoveCorrection{Y: y, Reported: reported}),
+	})
+	if err != nil {
+		return
+	}
+	s.hub.broadcaster.submit([]*Client{client}, data)
+}
+
+// distance 返回两名玩家之间的距离，按各自站位和最近上报的位置计算
+func (s *roomSession) distance(a, b string) float64 {
+	return math.Hypot(s.columnOf(a)-s.columnOf(b), s.positionOf(a)-s.positionOf(b))
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"math"
+	"time"
+
+	"game/models"
+	"game/protocol"
+)
+
+// 地图目标参数，纵向坐标与客户端战场一致
+const (
+	objectiveTickInterval = time.Second      // 目标结算与状态广播的间隔
+	hillHalfHeight        = 60.0             // 控制点以战场中线为中心，上下各延伸的像素
+	hillScoreTarget       = 30               // 占领控制点获胜所需的分数，每秒独占得 1 分
+	zoneShrinkDuration    = 2 * time.Minute  // 安全区从整个战场收缩到最小所需的时间
+	zoneMinHeight         = playerHeight * 2 // 安全区收缩后的最小高度
+	zoneDamage            = 1                // 区外每秒受到的伤害，不受伤害倍率影响
+	zoneWeapon            = "zone"           // 区外伤害在命中和击杀播报中使用的武器名
+)
+
+// hasObjective 判断房间规则是否启用了地图目标
+func (s *roomSession) hasObjective() bool {
+	return s.rules.Objective != models.ObjectiveNone
+}
+
+// trackPosition 记录玩家上报的纵向位置，用于判定是否处于控制点或安全区内
+func (s *roomSession) trackPosition(username string, action protocol.PlayerAction) {
+	if action.Action == "move_y" {
+		s.positions[username] = action.Value
+	}
+}
+
+// positionOf 返回玩家当前的纵向位置（上沿），机器人的位置由服务器直接维护
+func (s *roomSession) positionOf(username string) float64 {
+	if s.bot != nil && username == s.bot.name {
+		return s.bot.y
+	}
+	if y, ok := s.positions[username]; ok {
+		return y
+	}
+	return fieldHeight/2 - playerHeight/2
+}
+
+// zoneBounds 返回当前控制点或安全区的纵向范围
+func (s *roomSession) zoneBounds() (top, bottom float64) {
+	if s.rules.Objective == models.ObjectiveKingOfTheHill {
+		return fieldHeight/2 - hillHalfHeight, fieldHeight/2 + hillHalfHeight
+	}
+	progress := math.Min(1, float64(s.zoneTicks)*float64(objectiveTickInterval)/float64(zoneShrinkDuration))
+	half := (fieldHeight - (fieldHeight-zoneMinHeight)*progress) / 2
+	return fieldHeight/2 - half, fieldHeight/2 + half
+}
+
+// inZone 判断玩家的中心是否处于指定范围内
+func (s *roomSession) inZone(username string, top, bottom float64) bool {
+	center := s.positionOf(username) + playerHeight/2
+	return center >= top && center <= bottom
+}
+
+// tickObjective 推进一次地图目标结算并广播状态，对局结束时返回 true
+func (s *roomSession) tickObjective() bool {
+	s.zoneTicks++
+	top, bottom := s.zoneBounds()
+
+	if s.rules.Objective == models.ObjectiveKingOfTheHill {
+		// 只有独自站在控制点内的玩家得分，多人争夺时都不得分
+		holder := ""
+		for _, player := range s.players {
+			if s.out[player] || !s.inZone(player, top, bottom) {
+				continue
+			}
+			if holder != "" {
+				holder = ""
+				break
+			}
+			holder = player
+		}
+		if holder != "" {
+			s.stats[holder].Objective++
+		}
+		s.broadcastZone(top, bottom, holder)
+		if holder != "" && s.stats[holder].Objective >= hillScoreTarget {
+			gameOver := protocol.GameOverInfo{Winner: holder}
+			if !s.ffa {
+				gameOver.Loser = s.opponentOf(holder)
+			}
+			s.finish(gameOver)
+			return true
+		}
+		return false
+	}
+
+	s.broadcastZone(top, bottom, "")
+	for _, player := range s.players {
+		if s.out[player] || s.inZone(player, top, bottom) {
+			continue
+		}
+		hp := int(math.Max(0, float64(s.hp[player]-zoneDamage)))
+		s.hp[player] = hp
+		s.broadcast(protocol.MsgTypeHit, protocol.HitAction{
+			TargetID:  player,
+			Damage:    zoneDamage,
+			Remaining: hp,
+			Weapon:    zoneWeapon,
+		})
+		if hp <= 0 {
+			// 每次结算最多判定一名玩家阵亡，双人对局中阵亡后会开始新一局
+			s.broadcast(protocol.MsgTypeDeath, map[string]string{"player_id": player})
+			return s.recordDeath(protocol.DeathAction{PlayerID: player, Weapon: zoneWeapon})
+		}
+	}
+	return false
+}
+
+// broadcastZone 向房间广播地图目标的当前状态
+func (s *roomSession) broadcastZone(top, bottom float64, holder string) {
+	state := protocol.ZoneState{
+		Mode:   s.rules.Objective,
+		Top:    top,
+		Bottom: bottom,
+		Holder: holder,
+	}
+	if s.rules.Objective == models.ObjectiveKingOfTheHill {
+		state.Target = hillScoreTarget
+		state.Scores = make(map[string]int, len(s.players))
+		for _, player := range s.players {
+			state.Scores[player] = s.stats[player].Objective
+		}
+	}
+	s.broadcast(protocol.MsgTypeZoneState, state)
+}
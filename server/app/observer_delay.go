@@ -0,0 +1,103 @@
+package app
+
+import (
+	"sync"
+	"time"
+)
+
+// spectatorDelayTick 检查延迟消息是否到期的间隔
+const spectatorDelayTick = 100 * time.Millisecond
+
+// delayedFrame 等待发给观战者的一帧消息
+type delayedFrame struct {
+	at   time.Time
+	data []byte
+}
+
+// spectatorDelay 按房间缓存发给观战者的对局消息，延迟配置的时间后再发出，防止观战者给玩家实时报点
+type spectatorDelay struct {
+	hub   *Hub
+	delay time.Duration
+
+	mu     sync.Mutex
+	frames map[string][]delayedFrame // 按房间ID索引，按入队时间排列
+}
+
+// newSpectatorDelay 创建观战延迟缓冲，delay 不大于 0 时返回 nil，观战者与玩家同步接收
+func newSpectatorDelay(hub *Hub, delay time.Duration) *spectatorDelay {
+	if delay <= 0 {
+		return nil
+	}
+	d := &spectatorDelay{hub: hub, delay: delay, frames: make(map[string][]delayedFrame)}
+	go d.run()
+	return d
+}
+
+// push 缓存一帧发给房间观战者的消息
+func (d *spectatorDelay) push(roomID string, data []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.frames[roomID] = append(d.frames[roomID], delayedFrame{at: d.hub.clock.Now().Add(d.delay), data: data})
+}
+
+// run 定期把到期的消息发给房间内当前的观战者
+func (d *spectatorDelay) run() {
+	ticker := d.hub.clock.NewTicker(spectatorDelayTick)
+	defer ticker.Stop()
+	for now := range ticker.C() {
+		for roomID, frames := range d.due(now) {
+			spectators := spectatorsOnly(d.hub.roomPeers(roomID, ""))
+			for _, data := range frames {
+				d.hub.broadcaster.submit(spectators, data)
+			}
+		}
+	}
+}
+
+// due 取出所有到期的消息
+func (d *spectatorDelay) due(now time.Time) map[string][][]byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make(map[string][][]byte)
+	for roomID, frames := range d.frames {
+		n := 0
+		for n < len(frames) && !frames[n].at.After(now) {
+			out[roomID] = append(out[roomID], frames[n].data)
+			n++
+		}
+		if n == len(frames) {
+			delete(d.frames, roomID)
+		} else if n > 0 {
+			d.frames[roomID] = frames[n:]
+		}
+	}
+	return out
+}
+
+// sendGame 向房间内的客户端发送对局消息：玩家实时接收，开启观战延迟时观战者的消息进入延迟缓冲。
+// 开启房间密钥时玩家和观战者的帧分别用各自的密钥加密一次
+func (h *Hub) sendGame(roomID string, recipients []*Client, data []byte) {
+	if h.spectatorDelay == nil && h.roomKeys == nil {
+		h.broadcaster.submit(recipients, data)
+		return
+	}
+	players := make([]*Client, 0, len(recipients))
+	watching := false
+	for _, c := range recipients {
+		if c.spectator {
+			watching = true
+			continue
+		}
+		players = append(players, c)
+	}
+	h.broadcaster.submit(players, h.seal(roomID, false, data))
+	if !watching {
+		return
+	}
+	spectated := h.seal(roomID, true, data)
+	if h.spectatorDelay == nil {
+		h.broadcaster.submit(spectatorsOnly(recipients), spectated)
+		return
+	}
+	h.spectatorDelay.push(roomID, spectated)
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"encoding/json"
+
+	"game/data"
+	"game/models"
+	"game/protocol"
+	"game/service"
+)
+
+// watchStores 订阅房间和用户存储的变更，由存储通知驱动大厅推送，
+// 处理函数修改数据后不需要再手动广播
+func (h *Hub) watchStores() {
+	h.roomStore.OnChange(h.onRoomChange)
+	h.userStore.OnChange(h.onUserChange)
+	h.roomStore.OnChange(h.dropRoomInvites)
+	h.roomStore.OnChange(h.abandonRemovedRoom)
+	h.roomStore.OnChange(h.lobbyRoomChange)
+	if h.cluster != nil {
+		h.roomStore.OnChange(h.publishRoom)
+	}
+}
+
+// onRoomChange 将房间变更推送给大厅中的客户端
+func (h *Hub) onRoomChange(ev data.RoomChange) {
+	room := ev.New
+	if room == nil {
+		room = ev.Old
+	}
+	msg := protocol.Message{
+		Type: protocol.MsgTypeRoomUpdate,
+		Payload: mustMarshal(protocol.RoomUpdate{
+			Op:   string(ev.Op),
+			Room: h.roomInfo(*room),
+		}),
+	}
+	data, _ := json.Marshal(msg)
+	h.broadcaster.submit(h.roomPeers("", ""), data)
+}
+
+// onUserChange 用户上下线时向所有在线客户端推送状态
+func (h *Hub) onUserChange(ev data.UserChange) {
+	wasOnline := ev.Old != nil && ev.Old.Online
+	isOnline := ev.New != nil && ev.New.Online
+	if wasOnline == isOnline {
+		return
+	}
+	user := ev.Old
+	if user == nil {
+		user = ev.New
+	}
+	if isOnline {
+		h.analytics.RecordLogin(user.Username, h.clock.Now())
+	}
+	msg := protocol.Message{
+		Type: protocol.MsgTypePresence,
+		Payload: mustMarshal(protocol.Presence{
+			Username: user.Username,
+			Online:   isOnline,
+		}),
+	}
+	data, _ := json.Marshal(msg)
+	h.broadcaster.submit(h.allClients(), data)
+}
+
+// allClients 返回所有已连接的客户端
+func (h *Hub) allClients() []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	clients := make([]*Client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// roomInfo 将房间转换为协议中的房间信息，附带玩家当前装备的称号
+func (h *Hub) roomInfo(room models.Room) protocol.RoomInfo {
+	return protocol.RoomInfo{
+		ID:         room.ID,
+		Name:       room.Name,
+		Host:       room.HostID,
+		Players:    room.Players,
+		MaxPlayers: room.MaxPlayers,
+		Status:     room.Status,
+		Map:        room.Map,
+		Rules:      service.RulesInfo(room.Rules),
+		Heroes:     room.Heroes,
+		Titles:     h.activeTitles(room.Players),
+		Region:     room.Region,
+		Instance:   room.Instance,
+	}
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"log"
+	"time"
+
+	"game/models"
+	"game/protocol"
+)
+
+// gameplayMessage 判断是否为对局操作消息，暂停期间这些消息会被丢弃
+func gameplayMessage(msgType protocol.MessageType) bool {
+	switch msgType {
+	case protocol.MsgTypePlayerAction, protocol.MsgTypeFire, protocol.MsgTypeHit, protocol.MsgTypeDeath,
+		protocol.MsgTypeBuy:
+		return true
+	}
+	return false
+}
+
+// keepForRejoin 判断断线的客户端是否应保留在线状态等待重连：开启了重连宽限期且正在对局中
+func (h *Hub) keepForRejoin(client *Client) bool {
+	if h.cfg.ReconnectGrace <= 0 || client.roomID == "" || client.spectator {
+		return false
+	}
+	s := h.session(client.roomID)
+	return s != nil && containsPlayer(s.players, client.username)
+}
+
+// connected 用户当前是否有 WebSocket 连接
+func (h *Hub) connected(username string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.clients {
+		if c.username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// isPaused 是否有玩家掉线等待重连
+func (s *roomSession) isPaused() bool {
+	return len(s.paused) > 0
+}
+
+// pause 玩家掉线后暂停对局：停止机器人和计时，通知房间内其他人开始倒计时
+func (s *roomSession) pause(username string) {
+	now := s.hub.clock.Now()
+	if !s.isPaused() && s.timer != nil {
+		s.timeLeft = s.timerEnds.Sub(now)
+		s.stopTimer()
+	}
+	s.paused[username] = now.Add(s.hub.cfg.ReconnectGrace)
+	if s.countdown == nil {
+		s.countdown = s.hub.clock.NewTicker(time.Second)
+	}
+	log.Printf("房间 %s 玩家 %s 掉线，对局暂停等待重连", s.roomID, username)
+	s.broadcastPaused(username, now)
+}
+
+// resume 掉线的玩家重新连接，所有掉线玩家都回来后继续对局
+func (s *roomSession) resume(username string) {
+	if _, ok := s.paused[username]; !ok {
+		return
+	}
+	delete(s.paused, username)
+	if st := s.stats[username]; st != nil {
+		st.Reconnects++
+	}
+	log.Printf("房间 %s 玩家 %s 已重连", s.roomID, username)
+	if s.isPaused() {
+		return
+	}
+
+	s.stopCountdown()
+	if s.timeLeft > 0 {
+		s.startTimer(s.timeLeft)
+		s.timeLeft = 0
+	}
+	s.broadcast(protocol.MsgTypeMatchResumed, protocol.MatchResumed{Player: username})
+	s.broadcastScoreboard()
+}
+
+// tickCountdown 推送倒计时，有玩家超过宽限期仍未重连时判负并返回 true
+func (s *roomSession) tickCountdown(now time.Time) bool {
+	for _, username := range s.players {
+		deadline, ok := s.paused[username]
+		if !ok {
+			continue
+		}
+		if !now.Before(deadline) {
+			return s.forfeit(username, models.ResultReasonDisconnectTimeout)
+		}
+		s.broadcastPaused(username, now)
+	}
+	return false
+}
+
+// broadcastPaused 广播暂停状态和剩余的重连时间
+func (s *roomSession) broadcastPaused(username string, now time.Time) {
+	left := s.paused[username].Sub(now)
+	s.broadcast(protocol.MsgTypeMatchPaused, protocol.MatchPaused{
+		Player:      username,
+		SecondsLeft: int((left + time.Second - 1) / time.Second),
+	})
+}
+
+// forfeit 玩家断线判负，双人对局中对手获胜；混战中该玩家出局，只剩一人时结束
+func (s *roomSession) forfeit(username, reason string) bool {
+	if st := s.stats[username]; st != nil {
+		st.Disconnected = true
+		st.Forfeited = true
+	}
+	if s.ffa {
+		delete(s.paused, username)
+		return s.eliminate(username, reason)
+	}
+	s.finish(protocol.GameOverInfo{
+		Winner: s.opponentOf(username),
+		Loser:  username,
+		Reason: reason,
+	})
+	return true
+}
+
+// surrender 玩家主动认输，与断线判负相同，但不标记为断线
+func (s *roomSession) surrender(username string) bool {
+	st := s.stats[username]
+	if st == nil || s.out[username] {
+		return false
+	}
+	st.Forfeited = true
+	if s.ffa {
+		return s.eliminate(username, models.ResultReasonSurrender)
+	}
+	s.finish(protocol.GameOverInfo{
+		Winner: s.opponentOf(username),
+		Loser:  username,
+		Reason: models.ResultReasonSurrender,
+	})
+	return true
+}
+
+// stopCountdown 停止倒计时
+func (s *roomSession) stopCountdown() {
+	if s.countdown != nil {
+		s.countdown.Stop()
+		s.countdown = nil
+	}
+}
+
+// countdownC 返回倒计时通道，未暂停时为 nil
+func (s *roomSession) countdownC() <-chan time.Time {
+	if s.countdown == nil {
+		return nil
+	}
+	return s.countdown.C()
+}
+
+// releasePaused 对局结束时仍未重连的玩家按正常断线处理，标记为离线
+func (s *roomSession) releasePaused() {
+	for username := range s.paused {
+		if !s.hub.connected(username) && s.hub.markOffline(username) {
+			log.Printf("用户 %s 未在宽限期内重连，已更新状态为离线", username)
+		}
+	}
+	s.paused = make(map[string]time.Time)
+	s.stopCountdown()
+}
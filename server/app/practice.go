@@ -0,0 +1,298 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"game/game"
+	"game/models"
+	"game/protocol"
+	"game/report"
+	"game/sim"
+)
+
+// 练习靶场参数，坐标与客户端战场一致：玩家位于战场左侧，固定靶生成在右半场
+const (
+	practiceTargetSize     = 40.0        // 靶的边长
+	practiceHitPoints      = 100         // 命中得分
+	practiceHeadshotPoints = 150         // 命中靶心（按爆头上报）的得分
+	practiceQuickBonus     = 50          // 快速命中的额外得分
+	practiceQuickHit       = time.Second // 靶出现后在该时间内命中算作快速命中
+)
+
+// 练习结束原因
+const (
+	practiceTimeUp  = "time_up"
+	practiceStopped = "stopped"
+)
+
+// practiceRoomID 返回玩家的练习在 SessionManager 中的登记ID，带前缀，不会与房间ID冲突
+func practiceRoomID(username string) string {
+	return "practice:" + username
+}
+
+// practiceEvent 投递给练习会话的事件
+type practiceEvent struct {
+	msg     protocol.Message
+	stop    bool // 玩家主动结束练习
+	left    bool // 玩家断开连接，结束练习且不再发送结果
+	abandon bool // 服务器关闭，由 SessionManager 投递
+}
+
+// practiceSession 单人练习靶场，由 game.SessionManager 在独立协程中运行。
+// 固定靶由会话生成，射击和命中只发给练习的玩家本人；成绩只计入个人最佳，不计入对局结果和评分
+type practiceSession struct {
+	hub       *Hub
+	id        string
+	client    *Client
+	userID    string
+	weapon    models.Weapon
+	duration  time.Duration
+	rng       sim.RNG
+	entities  *game.Entities // 固定靶和玩家子弹
+	startedAt time.Time
+	score     int
+	hits      int
+	shots     int
+	events    chan practiceEvent
+	done      chan struct{}
+}
+
+// startPractice 为大厅中的玩家开始单人练习，房间中、观战中或匹配中的玩家不能开始
+func (h *Hub) startPractice(client *Client, req protocol.StartPracticeRequest) {
+	if client.roomID != "" {
+		h.sendError(client, http.StatusBadRequest, "在房间中无法开始练习")
+		return
+	}
+	h.matcher.mu.Lock()
+	queued := h.matcher.queued(client.username)
+	h.matcher.mu.Unlock()
+	if queued {
+		h.sendError(client, http.StatusBadRequest, "匹配中无法开始练习")
+		return
+	}
+	hero := h.roster().Default()
+	if req.HeroID != "" {
+		var ok bool
+		if hero, ok = h.roster().Get(req.HeroID); !ok {
+			h.sendError(client, http.StatusBadRequest, "英雄不存在")
+			return
+		}
+	}
+	weapon, _ := h.roster().Weapon(hero, "")
+
+	p := &practiceSession{
+		hub:      h,
+		id:       practiceRoomID(client.username),
+		client:   client,
+		weapon:   weapon,
+		duration: h.cfg.PracticeDuration,
+		rng:      h.seeder.New(),
+		entities: game.NewEntities(),
+		events:   make(chan practiceEvent, 64),
+		done:     make(chan struct{}),
+	}
+	if user := h.userStore.FindByUsername(client.username); user != nil {
+		p.userID = user.UserID
+	}
+	if !h.sessions.Start(p.id, func() game.Session { return p }) {
+		h.sendError(client, http.StatusConflict, "练习已在进行中")
+	}
+}
+
+// practice 返回玩家进行中的练习
+func (h *Hub) practice(username string) *practiceSession {
+	p, _ := h.sessions.Get(practiceRoomID(username)).(*practiceSession)
+	return p
+}
+
+// stopPractice 结束玩家的练习，left 为 true 表示玩家已断开连接
+func (h *Hub) stopPractice(client *Client, left bool) {
+	if p := h.practice(client.username); p != nil && p.client == client {
+		p.post(practiceEvent{stop: true, left: left})
+	}
+}
+
+// leavesPractice 判断消息是否会让玩家离开大厅（进入房间、匹配或观战），此时先结束练习
+func leavesPractice(t protocol.MessageType) bool {
+	switch t {
+	case protocol.MsgTypeCreateRoom, protocol.MsgTypeJoinRoom, protocol.MsgTypeJoinQueue,
+		protocol.MsgTypeSpectate, protocol.MsgTypeAcceptInvite:
+		return true
+	}
+	return false
+}
+
+// post 投递事件，练习已结束时直接丢弃
+func (p *practiceSession) post(ev practiceEvent) {
+	select {
+	case p.events <- ev:
+	case <-p.done:
+	}
+}
+
+// Abandon 中止练习，实现 game.Session
+func (p *practiceSession) Abandon() {
+	p.post(practiceEvent{abandon: true})
+}
+
+// Run 练习主循环，实现 game.Session
+func (p *practiceSession) Run() {
+	defer close(p.done)
+	defer func() {
+		if r := recover(); r != nil {
+			report.Panic(r, debug.Stack(), map[string]string{"practice": p.id})
+		}
+	}()
+
+	p.begin()
+	timer := p.hub.clock.NewTicker(p.duration)
+	defer timer.Stop()
+	for {
+		select {
+		case ev := <-p.events:
+			if ev.stop || ev.abandon {
+				p.finish(practiceStopped, !ev.left)
+				return
+			}
+			p.handle(ev.msg)
+		case <-timer.C():
+			p.finish(practiceTimeUp, true)
+			return
+		}
+	}
+}
+
+// begin 生成初始的固定靶并通知玩家练习开始
+func (p *practiceSession) begin() {
+	p.startedAt = p.hub.clock.Now()
+	start := protocol.PracticeStart{
+		RoomID:   p.id,
+		Duration: int(p.duration.Seconds()),
+		Weapon:   weaponInfo(p.weapon),
+		Targets:  make([]protocol.EntitySpawn, 0, p.hub.cfg.PracticeTargets),
+	}
+	for i := 0; i < p.hub.cfg.PracticeTargets; i++ {
+		start.Targets = append(start.Targets, p.placeTarget())
+	}
+	p.send(protocol.MsgTypePracticeStart, start)
+}
+
+// placeTarget 在右半场的随机位置生成一个固定靶
+func (p *practiceSession) placeTarget() protocol.EntitySpawn {
+	ent := p.entities.Spawn(game.EntityTarget, "", p.hub.clock.Now(), 0)
+	return protocol.EntitySpawn{
+		ID:   ent.ID,
+		Kind: ent.Kind,
+		X:    fieldWidth/2 + p.rng.Float64()*(fieldWidth/2-practiceTargetSize),
+		Y:    p.rng.Float64() * (fieldHeight - practiceTargetSize),
+	}
+}
+
+// handle 处理玩家的射击和命中，其他对局消息在练习中没有意义，直接忽略
+func (p *practiceSession) handle(msg protocol.Message) {
+	now := p.hub.clock.Now()
+	for _, ent := range p.entities.Expire(now) {
+		p.send(protocol.MsgTypeEntityDespawn, protocol.EntityDespawn{ID: ent.ID, Kind: ent.Kind, Reason: protocol.DespawnExpired})
+	}
+
+	switch msg.Type {
+	case protocol.MsgTypeFire:
+		var fire protocol.FireAction
+		if protocol.DecodeBytes(msg.Payload, &fire) != nil {
+			return
+		}
+		p.shots++
+		ent := p.entities.Spawn(game.EntityBullet, p.client.username, now, bulletLifetime)
+		p.send(protocol.MsgTypeEntitySpawn, protocol.EntitySpawn{
+			ID:        ent.ID,
+			Kind:      ent.Kind,
+			OwnerID:   p.client.username,
+			X:         fire.X,
+			Y:         fire.Y,
+			VX:        float64(fire.Direction) * bulletSpeed,
+			ExpiresIn: bulletLifetime.Seconds(),
+			ClientRef: fire.BulletID,
+		})
+
+	case protocol.MsgTypeHit:
+		// 命中必须指明仍然存活的子弹和靶，同一颗子弹只能命中一次
+		var hit protocol.HitAction
+		if protocol.DecodeBytes(msg.Payload, &hit) != nil {
+			return
+		}
+		target, ok := p.entities.Get(hit.TargetID)
+		if !ok || target.Kind != game.EntityTarget {
+			return
+		}
+		if bullet, ok := p.entities.Get(hit.BulletID); !ok || bullet.Kind != game.EntityBullet {
+			return
+		}
+		p.entities.Despawn(hit.BulletID)
+		p.entities.Despawn(target.ID)
+		p.send(protocol.MsgTypeEntityDespawn, protocol.EntityDespawn{ID: target.ID, Kind: target.Kind, Reason: protocol.DespawnHit})
+
+		result := protocol.PracticeHit{TargetID: target.ID, Points: practiceHitPoints, Headshot: hit.Headshot}
+		if hit.Headshot {
+			result.Points = practiceHeadshotPoints
+		}
+		if now.Sub(target.SpawnedAt) <= practiceQuickHit {
+			result.Quick = true
+			result.Points += practiceQuickBonus
+		}
+		p.hits++
+		p.score += result.Points
+		result.Score, result.Hits, result.Shots = p.score, p.hits, p.shots
+		p.send(protocol.MsgTypePracticeHit, result)
+		p.send(protocol.MsgTypeEntitySpawn, p.placeTarget())
+	}
+}
+
+// finish 记录成绩并在 notify 为 true 时把成绩和个人最佳发给玩家
+func (p *practiceSession) finish(reason string, notify bool) {
+	result := protocol.PracticeResult{Score: p.score, Hits: p.hits, Shots: p.shots, Reason: reason}
+	if p.shots > 0 {
+		result.Accuracy = float64(p.hits) / float64(p.shots)
+	}
+	if p.userID != "" && p.hub.practiceScores != nil && p.shots > 0 {
+		best, improved := p.hub.practiceScores.Record(models.PracticeRecord{
+			UserID:   p.userID,
+			Username: p.client.username,
+			Score:    p.score,
+			Hits:     p.hits,
+			Shots:    p.shots,
+			Accuracy: result.Accuracy,
+			At:       p.hub.clock.Now(),
+		})
+		result.NewBest = improved
+		if best.Hits > 0 {
+			result.Best = practiceBest(best)
+		}
+	}
+	if notify {
+		p.send(protocol.MsgTypePracticeResult, result)
+	}
+}
+
+// send 把消息发给练习的玩家，玩家已断开时丢弃
+func (p *practiceSession) send(msgType protocol.MessageType, payload interface{}) {
+	data, err := json.Marshal(protocol.Message{Type: msgType, Payload: mustMarshal(payload)})
+	if err != nil {
+		return
+	}
+	p.hub.deliver(p.client, data)
+}
+
+// practiceBest 将个人最佳成绩转换为协议中的格式
+func practiceBest(record models.PracticeRecord) *protocol.PracticeBest {
+	return &protocol.PracticeBest{
+		Score:    record.Score,
+		Hits:     record.Hits,
+		Shots:    record.Shots,
+		Accuracy: record.Accuracy,
+		Runs:     record.Runs,
+		At:       record.At,
+	}
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"game/protocol"
+)
+
+// quotaWindow 消息配额的统计窗口
+const quotaWindow = time.Minute
+
+// topSendersLimit 统计中列出的发送消息最多的用户数
+const topSendersLimit = 10
+
+// typeCounter 一种消息的接收数和因超出配额被拒绝的数量
+type typeCounter struct {
+	received  int64
+	throttled int64
+}
+
+// userMessages 单个用户的消息计数和各类型最近一个窗口内的发送时间
+type userMessages struct {
+	counts map[protocol.MessageType]*typeCounter
+	recent map[protocol.MessageType][]time.Time
+}
+
+// messageQuotas 按用户和消息类型统计入站消息，并限制每个用户每分钟可发送的各类消息数；
+// 用户断开连接后只保留全局的按类型计数
+type messageQuotas struct {
+	mu     sync.Mutex
+	limits map[protocol.MessageType]int
+	totals map[protocol.MessageType]*typeCounter
+	users  map[string]*userMessages
+}
+
+// newMessageQuotas 创建消息配额，limits 为各消息类型每分钟的上限，未配置或为 0 的类型不限制
+func newMessageQuotas(limits map[string]int) *messageQuotas {
+	q := &messageQuotas{
+		limits: make(map[protocol.MessageType]int),
+		totals: make(map[protocol.MessageType]*typeCounter),
+		users:  make(map[string]*userMessages),
+	}
+	for msgType, limit := range limits {
+		if limit > 0 {
+			q.limits[protocol.MessageType(msgType)] = limit
+		}
+	}
+	return q
+}
+
+// allow 记录用户发送的一条消息，超出该类型配额时返回 false
+func (q *messageQuotas) allow(username string, msgType protocol.MessageType, now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	user := q.users[username]
+	if user == nil {
+		user = &userMessages{
+			counts: make(map[protocol.MessageType]*typeCounter),
+			recent: make(map[protocol.MessageType][]time.Time),
+		}
+		q.users[username] = user
+	}
+	count := counterFor(user.counts, msgType)
+	total := counterFor(q.totals, msgType)
+	count.received++
+	total.received++
+
+	limit, ok := q.limits[msgType]
+	if !ok {
+		return true
+	}
+	recent := user.recent[msgType][:0]
+	for _, t := range user.recent[msgType] {
+		if now.Sub(t) < quotaWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= limit {
+		user.recent[msgType] = recent
+		count.throttled++
+		total.throttled++
+		return false
+	}
+	user.recent[msgType] = append(recent, now)
+	return true
+}
+
+// forget 用户断开连接后清除其计数
+func (q *messageQuotas) forget(username string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.users, username)
+}
+
+// stats 返回各消息类型的全局计数，以及在线用户中发送消息最多的用户
+func (q *messageQuotas) stats() (map[string]protocol.MessageTypeStats, []protocol.UserMessageStats) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	types := make(map[string]protocol.MessageTypeStats, len(q.totals))
+	for msgType, c := range q.totals {
+		types[string(msgType)] = protocol.MessageTypeStats{
+			Received:  c.received,
+			Throttled: c.throttled,
+			Quota:     q.limits[msgType],
+		}
+	}
+
+	senders := make([]protocol.UserMessageStats, 0, len(q.users))
+	for username, user := range q.users {
+		s := protocol.UserMessageStats{Username: username, Types: make(map[string]int64, len(user.counts))}
+		for msgType, c := range user.counts {
+			s.Received += c.received
+			s.Throttled += c.throttled
+			s.Types[string(msgType)] = c.received
+		}
+		senders = append(senders, s)
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		if senders[i].Received != senders[j].Received {
+			return senders[i].Received > senders[j].Received
+		}
+		return senders[i].Username < senders[j].Username
+	})
+	if len(senders) > topSendersLimit {
+		senders = senders[:topSendersLimit]
+	}
+	return types, senders
+}
+
+// counterFor 返回消息类型的计数器，不存在时创建
+func counterFor(counts map[protocol.MessageType]*typeCounter, msgType protocol.MessageType) *typeCounter {
+	c := counts[msgType]
+	if c == nil {
+		c = &typeCounter{}
+		counts[msgType] = c
+	}
+	return c
+}
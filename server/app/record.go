@@ -0,0 +1,311 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/protocol"
+)
+
+// trafficRecorder 将入站流量逐行追加到 JSONL 文件，未启用录制时为 nil。
+// 内存中按房间索引每条记录在文件中的位置，申诉读取回放时只读该房间的记录，不扫描整个文件
+type trafficRecorder struct {
+	mu    sync.Mutex
+	file  *os.File
+	path  string
+	size  int64                  // 文件当前长度，即下一条记录的偏移
+	rooms map[string][]recordRef // 按房间ID索引的记录位置，按写入顺序排列
+}
+
+// recordRef 一条记录在录制文件中的位置
+type recordRef struct {
+	offset int64
+	length int // 不含换行符
+	at     time.Time
+}
+
+const (
+	// maxReplayRecords 申诉附带的一局回放最多包含的记录数
+	maxReplayRecords = 5000
+	// maxRecordLine 读取录制文件时单条记录的最大长度
+	maxRecordLine = 1 << 20
+	// maxExportChat 数据导出中最多包含的聊天消息数
+	maxExportChat = 5000
+)
+
+// newTrafficRecorder 打开录制文件，path 为空时不录制
+func newTrafficRecorder(path string) *trafficRecorder {
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0600)
+	if err != nil {
+		log.Printf("打开流量录制文件失败，不进行录制: %v", err)
+		return nil
+	}
+	r := &trafficRecorder{file: f, path: path, rooms: make(map[string][]recordRef)}
+	if err := r.index(); err != nil {
+		log.Printf("索引流量录制文件失败，不进行录制: %v", err)
+		f.Close()
+		return nil
+	}
+	log.Printf("警告: 已开启流量录制，所有入站消息将写入 %s", path)
+	return r
+}
+
+// index 启动时为已有的记录建立房间索引。进程崩溃时最后一条记录可能只写了一半，
+// 这时补一个换行符，让它单独成为一行被跳过，之后的追加不会接在它后面
+func (r *trafficRecorder) index() error {
+	reader := bufio.NewReader(r.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				if _, err := r.file.Write([]byte{'\n'}); err != nil {
+					return err
+				}
+				offset += int64(len(line)) + 1
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		r.add(line[:len(line)-1], offset)
+		offset += int64(len(line))
+	}
+	r.size = offset
+	return nil
+}
+
+// recordHead 建立索引和按用户过滤时需要的记录字段
+type recordHead struct {
+	Time     time.Time `json:"time"`
+	Username string    `json:"username"`
+	RoomID   string    `json:"room_id"`
+}
+
+// add 把偏移 offset 处的一条记录登记到所属房间的索引中，不属于房间的记录不登记
+func (r *trafficRecorder) add(line []byte, offset int64) {
+	var head recordHead
+	if json.Unmarshal(line, &head) != nil || head.RoomID == "" {
+		return
+	}
+	r.rooms[head.RoomID] = append(r.rooms[head.RoomID], recordRef{offset: offset, length: len(line), at: head.Time})
+}
+
+// removeUsers 从录制文件中删除 names 中任一用户的全部记录，注销账号时调用，返回删除的记录数。
+// 保留的记录先写入临时文件再原子替换，随后重新打开录制文件并重建索引
+func (r *trafficRecorder) removeUsers(names []string) (int, error) {
+	if r == nil {
+		return 0, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	src, err := os.Open(r.path)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	tmp := r.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	fail := func(err error) (int, error) {
+		f.Close()
+		os.Remove(tmp)
+		return 0, err
+	}
+
+	// index 保证文件以换行符结尾，每次读到的都是完整的一行
+	reader := bufio.NewReader(src)
+	w := bufio.NewWriter(f)
+	rooms := make(map[string][]recordRef)
+	var size int64
+	removed := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		var head recordHead
+		parsed := json.Unmarshal(line[:len(line)-1], &head) == nil
+		if parsed && slices.Contains(names, head.Username) {
+			removed++
+			continue
+		}
+		if _, err := w.Write(line); err != nil {
+			return fail(err)
+		}
+		if parsed && head.RoomID != "" {
+			rooms[head.RoomID] = append(rooms[head.RoomID], recordRef{offset: size, length: len(line) - 1, at: head.Time})
+		}
+		size += int64(len(line))
+	}
+	if removed == 0 {
+		return fail(nil)
+	}
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+
+	r.file.Close()
+	if err := os.Rename(tmp, r.path); err != nil {
+		os.Remove(tmp)
+		return 0, errors.Join(err, r.reopen())
+	}
+	if err := r.reopen(); err != nil {
+		return 0, err
+	}
+	r.rooms, r.size = rooms, size
+	return removed, nil
+}
+
+// reopen 以追加方式重新打开录制文件，调用方需持有锁
+func (r *trafficRecorder) reopen() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	r.file = f
+	return nil
+}
+
+// record 写入一条记录，每条记录独立写入，进程崩溃时最多丢失最后一条
+func (r *trafficRecorder) record(rec models.TrafficRecord) {
+	if r == nil {
+		return
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n, err := r.file.Write(append(line, '\n'))
+	if err != nil {
+		log.Printf("写入流量录制失败: %v", err)
+	}
+	if err == nil && rec.RoomID != "" {
+		r.rooms[rec.RoomID] = append(r.rooms[rec.RoomID], recordRef{offset: r.size, length: len(line), at: rec.Time})
+	}
+	r.size += int64(n)
+}
+
+// userChat 扫描录制文件，返回 names 中任一用户发出的聊天消息，超过上限时只保留最新的部分。
+// 只在锁内打开只读句柄并取得当前长度，扫描不阻塞录制
+func (r *trafficRecorder) userChat(names []string) []protocol.ChatMessageInfo {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	f, err := os.Open(r.path)
+	size := r.size
+	r.mu.Unlock()
+	if err != nil {
+		log.Printf("读取流量录制失败: %v", err)
+		return nil
+	}
+	defer f.Close()
+
+	var chat []protocol.ChatMessageInfo
+	reader := bufio.NewReader(io.LimitReader(f, size))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var rec models.TrafficRecord
+		if json.Unmarshal(line, &rec) != nil || rec.Event != models.TrafficMessage || !slices.Contains(names, rec.Username) {
+			continue
+		}
+		var msg protocol.Message
+		var req protocol.ChatRequest
+		if json.Unmarshal(rec.Message, &msg) != nil || msg.Type != protocol.MsgTypeChat || json.Unmarshal(msg.Payload, &req) != nil {
+			continue
+		}
+		if len(chat) == maxExportChat {
+			chat = chat[1:]
+		}
+		chat = append(chat, protocol.ChatMessageInfo{From: rec.Username, Channel: req.Channel, Text: req.Text, SentAt: rec.Time})
+	}
+	return chat
+}
+
+// recordEvent 以当前时间录制客户端的一次事件
+func (h *Hub) recordEvent(client *Client, event string, message []byte) {
+	if h.recorder == nil {
+		return
+	}
+	h.recorder.record(models.TrafficRecord{
+		Time:      h.clock.Now(),
+		Username:  client.username,
+		Event:     event,
+		RoomID:    client.roomID,
+		MessageID: client.messageID(),
+		Message:   message,
+	})
+}
+
+// roomRecords 从录制文件中读取房间在 from 到 to 之间的记录，超过上限时只保留最早的部分。
+// 只在锁内取出索引中的位置并打开单独的只读句柄，之后的读取不阻塞录制；
+// 句柄与位置来自同一个文件，读取期间录制文件被 removeUsers 替换也不会读错位置
+func (r *trafficRecorder) roomRecords(roomID string, from, to time.Time) []models.TrafficRecord {
+	if r == nil {
+		return nil
+	}
+	var refs []recordRef
+	r.mu.Lock()
+	for _, ref := range r.rooms[roomID] {
+		if len(refs) >= maxReplayRecords {
+			break
+		}
+		if !ref.at.Before(from) && !ref.at.After(to) && ref.length <= maxRecordLine {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	f, err := os.Open(r.path)
+	r.mu.Unlock()
+	if err != nil {
+		log.Printf("读取流量录制失败: %v", err)
+		return nil
+	}
+	defer f.Close()
+	records := make([]models.TrafficRecord, 0, len(refs))
+	for _, ref := range refs {
+		buf := make([]byte, ref.length)
+		if _, err := f.ReadAt(buf, ref.offset); err != nil {
+			log.Printf("读取流量录制失败: %v", err)
+			break
+		}
+		var rec models.TrafficRecord
+		if json.Unmarshal(buf, &rec) == nil {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"fmt"
+	"log"
+
+	"game/protocol"
+)
+
+// ReloadConfig 重新加载可热更新的数据文件，实现 api.ConfigReloader。
+// 文件无效时保留原配置并返回错误；进行中的对局继续使用开局时的配置
+func (h *Hub) ReloadConfig() (protocol.ConfigReloadResponse, error) {
+	if err := h.bots.Reload(); err != nil {
+		return protocol.ConfigReloadResponse{}, fmt.Errorf("重新加载机器人难度失败: %v", err)
+	}
+	roster, err := h.balance.Reload()
+	if err != nil {
+		return protocol.ConfigReloadResponse{}, fmt.Errorf("重新加载平衡配置失败: %v", err)
+	}
+	resp := protocol.ConfigReloadResponse{BalanceVersion: roster.Version()}
+	for _, p := range h.bots.All() {
+		resp.BotProfiles = append(resp.BotProfiles, p.ID)
+	}
+	log.Printf("已重新加载机器人难度 %v，平衡版本 %s", resp.BotProfiles, resp.BalanceVersion)
+	return resp, nil
+}
+
+// Balance 返回当前生效的平衡版本和已发布的版本，实现 api.BalanceManager
+func (h *Hub) Balance() protocol.BalanceInfo {
+	return protocol.BalanceInfo{Version: h.roster().Version(), Versions: h.balance.Versions()}
+}
+
+// PushBalance 发布新的平衡版本，实现 api.BalanceManager。只有之后开局的对局使用新版本
+func (h *Hub) PushBalance(content []byte) (protocol.BalanceInfo, error) {
+	roster, err := h.balance.Push(content)
+	if err != nil {
+		return protocol.BalanceInfo{}, err
+	}
+	log.Printf("已发布平衡版本 %s", roster.Version())
+	return h.Balance(), nil
+}
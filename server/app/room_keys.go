@@ -0,0 +1,161 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"game/crypto"
+	"game/protocol"
+)
+
+// sealedFrameTag 标记已用房间密钥加密的帧，writePump 直接写出标记之后的内容，不再按连接加密。
+// 其他消息都是以 '{' 开头的 JSON，不会与该标记混淆
+const sealedFrameTag byte = 0x01
+
+// 同一房间内不同用途的密钥
+const (
+	roomKeyPlayer    = "player"
+	roomKeySpectator = "spectator"
+)
+
+// roomKey 房间会话密钥，id 随加密帧发送
+type roomKey struct {
+	id  string
+	key []byte
+}
+
+// roomKeySet 单局对局的密钥：玩家密钥加密实时帧，观战者密钥只加密观战者收到的（可能延迟的）帧，
+// 观战者拿不到玩家密钥，无法解密实时帧
+type roomKeySet struct {
+	player    roomKey
+	spectator roomKey
+}
+
+// roomKeyring 进行中对局的房间密钥，按房间ID索引
+type roomKeyring struct {
+	mu    sync.Mutex
+	rooms map[string]roomKeySet
+	next  uint64
+}
+
+// newRoomKeyring 创建房间密钥表，未开启房间密钥时返回 nil
+func newRoomKeyring(enabled bool) *roomKeyring {
+	if !enabled {
+		return nil
+	}
+	return &roomKeyring{rooms: make(map[string]roomKeySet)}
+}
+
+// rekey 以随机盐为房间派生新的一组密钥，替换上一局的密钥
+func (r *roomKeyring) rekey(roomID string) (roomKeySet, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return roomKeySet{}, err
+	}
+	master := crypto.GetKey()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next++
+	keys := roomKeySet{
+		player: roomKey{
+			id:  fmt.Sprintf("k%dp", r.next),
+			key: crypto.DeriveRoomKey(master, roomID, salt, roomKeyPlayer),
+		},
+		spectator: roomKey{
+			id:  fmt.Sprintf("k%ds", r.next),
+			key: crypto.DeriveRoomKey(master, roomID, salt, roomKeySpectator),
+		},
+	}
+	r.rooms[roomID] = keys
+	return keys, nil
+}
+
+// get 返回房间当前的密钥
+func (r *roomKeyring) get(roomID string) (roomKeySet, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys, ok := r.rooms[roomID]
+	return keys, ok
+}
+
+// drop 对局结束后移除房间的密钥
+func (r *roomKeyring) drop(roomID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.rooms, roomID)
+}
+
+// rekeyRoom 对局开始时为房间派生密钥并下发给房间成员，未开启房间密钥时不做任何事。
+// 派生失败时房间没有密钥，对局帧退回按连接加密
+func (h *Hub) rekeyRoom(roomID string) {
+	if h.roomKeys == nil {
+		return
+	}
+	if _, err := h.roomKeys.rekey(roomID); err != nil {
+		log.Printf("生成房间 %s 的密钥失败: %v", roomID, err)
+		h.roomKeys.drop(roomID)
+		return
+	}
+	for _, c := range h.roomPeers(roomID, "") {
+		h.sendRoomKey(c)
+	}
+}
+
+// sendRoomKey 通过连接加密通道向房间成员下发当前对局的密钥，观战者拿到只读密钥
+func (h *Hub) sendRoomKey(c *Client) {
+	if h.roomKeys == nil || c.roomID == "" {
+		return
+	}
+	keys, ok := h.roomKeys.get(c.roomID)
+	if !ok {
+		return
+	}
+	key := keys.player
+	if c.spectator {
+		key = keys.spectator
+	}
+	data, _ := json.Marshal(protocol.Message{
+		Type: protocol.MsgTypeRoomKey,
+		Payload: mustMarshal(protocol.RoomKey{
+			RoomID:   c.roomID,
+			KeyID:    key.id,
+			Key:      base64.StdEncoding.EncodeToString(key.key),
+			ReadOnly: c.spectator,
+		}),
+	})
+	c.send <- data
+}
+
+// dropRoomKeys 对局结束后移除房间的密钥，下一局开始时重新派生
+func (h *Hub) dropRoomKeys(roomID string) {
+	if h.roomKeys != nil {
+		h.roomKeys.drop(roomID)
+	}
+}
+
+// seal 用房间密钥把对局帧加密一次，返回带 sealedFrameTag 的帧，发给所有接收者时共用；
+// 房间没有密钥或加密失败时原样返回，由 writePump 按连接加密
+func (h *Hub) seal(roomID string, spectator bool, data []byte) []byte {
+	if h.roomKeys == nil {
+		return data
+	}
+	keys, ok := h.roomKeys.get(roomID)
+	if !ok {
+		return data
+	}
+	key := keys.player
+	if spectator {
+		key = keys.spectator
+	}
+	sealed, err := crypto.SealFrame(key.id, key.key, data)
+	if err != nil {
+		log.Printf("加密房间 %s 的对局帧失败: %v", roomID, err)
+		return data
+	}
+	return append([]byte{sealedFrameTag}, sealed...)
+}
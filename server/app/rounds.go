@@ -0,0 +1,159 @@
+package app
+
+import (
+	"math"
+	"time"
+
+	"game/models"
+	"game/protocol"
+)
+
+// overtimeDuration 加时阶段的时长，仍未分出胜负时判平
+const overtimeDuration = time.Minute
+
+// resetHP 将所有玩家的生命值重置为起始生命值，见 startingHP
+func (s *roomSession) resetHP() {
+	for _, player := range s.players {
+		s.hp[player] = s.startingHP(player)
+	}
+}
+
+// applyHit 按伤害倍率结算一次命中，护甲先抵扣伤害，返回改写了实际伤害、剩余护甲和生命值的命中消息
+func (s *roomSession) applyHit(hit protocol.HitAction) protocol.HitAction {
+	if hit.Damage > 0 {
+		hit.Damage = int(math.Max(1, math.Round(float64(hit.Damage)*s.rules.DamageMultiplier)))
+		hit.Damage = s.absorb(hit.TargetID, hit.Damage)
+	}
+	hit.Armor = s.armorOf(hit.TargetID)
+	if hp, ok := s.hp[hit.TargetID]; ok {
+		hp = int(math.Max(0, float64(hp-hit.Damage)))
+		s.hp[hit.TargetID] = hp
+		hit.Remaining = hp
+	}
+	return hit
+}
+
+// nextRound 开始下一局，见 restartRound
+func (s *roomSession) nextRound() {
+	s.round++
+	s.restartRound()
+}
+
+// restartRound 重新开始当前这一局：重置生命值和命中记录，清空场上实体并抽取新的随机种子，开始购买阶段，通知房间内客户端重新布置
+func (s *roomSession) restartRound() {
+	s.zoneTicks = 0
+	s.resetHP()
+	s.lastHit = make(map[string]protocol.HitAction)
+	s.clearEntities()
+	s.newRoundSeed()
+	if s.bot != nil {
+		s.bot.bullets = nil
+	}
+	if s.economy() {
+		s.startBuyPhase()
+	}
+	s.broadcastRoundStart()
+}
+
+// broadcastRoundStart 广播本局开始的快照，启用经济系统时包含购买时间和所有玩家的装备
+func (s *roomSession) broadcastRoundStart() {
+	start := protocol.RoundStart{
+		Round:      s.round,
+		Seed:       s.seed,
+		StartingHP: s.rules.StartingHP,
+		RoundWins:  s.roundWins,
+		HP:         s.hp,
+		Loadouts:   s.loadoutInfos(),
+	}
+	if s.economy() {
+		start.BuyTime = int(buyPhaseDuration.Seconds())
+	}
+	s.broadcast(protocol.MsgTypeRoundStart, start)
+}
+
+// startOvertime 限时对局打平时按规则进入加时，返回是否进入了加时
+func (s *roomSession) startOvertime() bool {
+	if s.rules.Overtime == models.OvertimeNone {
+		return false
+	}
+	if leader, runnerUp := s.standings(); runnerUp != "" && s.ahead(leader, runnerUp) {
+		return false
+	}
+
+	hp := 1
+	if s.rules.Overtime == models.OvertimeReducedHP {
+		hp = (s.rules.StartingHP + 3) / 4
+	}
+	s.overtime = true
+	for _, player := range s.players {
+		s.hp[player] = hp
+	}
+	s.lastHit = make(map[string]protocol.HitAction)
+	s.broadcast(protocol.MsgTypeOvertime, protocol.OvertimeStart{
+		Mode:     s.rules.Overtime,
+		HP:       hp,
+		Duration: int(overtimeDuration.Seconds()),
+	})
+	return true
+}
+
+// finishOnTime 到达时间上限时结算：依次比较目标分数、赢下的局数、击杀数、剩余生命值，全部相同时为平局
+func (s *roomSession) finishOnTime() {
+	leader, runnerUp := s.standings()
+	gameOver := protocol.GameOverInfo{Reason: models.ResultReasonTimeLimit}
+	if runnerUp == "" || s.ahead(leader, runnerUp) {
+		gameOver.Winner = leader
+		gameOver.Loser = runnerUp
+	}
+	s.finish(gameOver)
+}
+
+// standings 返回当前战况排名前两位的玩家，混战中已出局的玩家不参与
+func (s *roomSession) standings() (leader, runnerUp string) {
+	for _, player := range s.players {
+		switch {
+		case s.out[player]:
+		case leader == "" || s.ahead(player, leader):
+			leader, runnerUp = player, leader
+		case runnerUp == "" || s.ahead(player, runnerUp):
+			runnerUp = player
+		}
+	}
+	return leader, runnerUp
+}
+
+// ahead 判断 a 的当前战况是否领先 b，占领控制点的分数优先比较
+func (s *roomSession) ahead(a, b string) bool {
+	switch {
+	case s.stats[a].Objective != s.stats[b].Objective:
+		return s.stats[a].Objective > s.stats[b].Objective
+	case s.roundWins[a] != s.roundWins[b]:
+		return s.roundWins[a] > s.roundWins[b]
+	case s.stats[a].Kills != s.stats[b].Kills:
+		return s.stats[a].Kills > s.stats[b].Kills
+	}
+	return s.hp[a] > s.hp[b]
+}
+
+// startTimer 开始（或重新开始）对局计时，d 后 timerC 触发
+func (s *roomSession) startTimer(d time.Duration) {
+	s.stopTimer()
+	s.timer = s.hub.clock.NewTicker(d)
+	s.timerEnds = s.hub.clock.Now().Add(d)
+}
+
+// stopTimer 停止对局计时
+func (s *roomSession) stopTimer() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// timerC 返回对局计时的到期通道，未计时时为 nil
+func (s *roomSession) timerC() <-chan time.Time {
+	if s.timer == nil {
+		return nil
+	}
+	return s.timer.C()
+}
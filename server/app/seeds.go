@@ -0,0 +1,58 @@
+package app
+
+import (
+	"log"
+	"math"
+
+	"game/models"
+	"game/protocol"
+	"game/sim"
+)
+
+// spreadTolerance 客户端上报的散布与服务器结果允许的误差（像素），用于容忍浮点舍入
+const spreadTolerance = 0.01
+
+// newRoundSeed 为新的一局抽取随机种子，并重置按种子结算的射击计数和暴击子弹
+func (s *roomSession) newRoundSeed() {
+	s.seed = s.seeds.Int63()
+	s.shots = make(map[string]uint64)
+	s.crits = make(map[string]bool)
+}
+
+// rollShot 按本局种子结算玩家下一次射击的纵向散布和是否暴击，见 protocol.GameStart
+func (s *roomSession) rollShot(player string, weapon models.Weapon) (spread float64, crit bool) {
+	n := s.shots[player]
+	s.shots[player]++
+	if weapon.Spread > 0 {
+		spread = (sim.Roll(s.seed, player+"/spread", n)*2 - 1) * weapon.Spread
+	}
+	if weapon.CritChance > 0 {
+		crit = sim.Roll(s.seed, player+"/crit", n) < weapon.CritChance
+	}
+	return spread, crit
+}
+
+// seedFire 按本局种子改写射击的散布，上报的结果与种子序列不符时记录日志；返回子弹是否暴击
+func (s *roomSession) seedFire(ev sessionEvent, fire *protocol.FireAction) bool {
+	weapon, _ := s.weapon(ev.client.username, fire.Weapon)
+	spread, crit := s.rollShot(ev.client.username, weapon)
+	if math.Abs(fire.Spread-spread) > spreadTolerance {
+		log.Printf("[%s] 用户 %s 上报的散布 %.2f 与本局种子的结果 %.2f 不符，按服务器结果转发", ev.msgID, ev.client.username, fire.Spread, spread)
+		s.violation(ev.client.username, models.ViolationSeedMismatch)
+	}
+	fire.Spread = spread
+	return crit
+}
+
+// seedHit 按子弹射击时的结算改写命中是否暴击和暴击伤害，上报的结果不符时记录日志
+func (s *roomSession) seedHit(ev sessionEvent, hit *protocol.HitAction, weapon models.Weapon) {
+	crit := hit.BulletID != "" && s.crits[hit.BulletID]
+	if hit.Crit != crit {
+		log.Printf("[%s] 用户 %s 上报的暴击结果与本局种子不符，子弹 %s", ev.msgID, ev.client.username, hit.BulletID)
+		s.violation(ev.client.username, models.ViolationSeedMismatch)
+	}
+	hit.Crit = crit
+	if crit {
+		hit.Damage = weapon.CritDamage(hit.Damage)
+	}
+}
@@ -1,44 +1,151 @@
 package app
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
 
 	"game/api"
+	"game/auth"
+	"game/cluster"
+	"game/config"
 	"game/data"
+	"game/mail"
+	"game/report"
 	"game/repository"
 	"game/service"
+	"game/validate"
 )
 
 // Server 定义服务器结构
 type Server struct {
-	router      *api.Router
-	userStore   *data.UserStore
-	roomStore   *data.RoomStore
-	resultStore *data.ResultStore
-	hub         *Hub
+	cfg           *config.Config
+	router        *api.Router
+	userStore     *data.UserStore
+	roomStore     *data.RoomStore
+	resultStore   *data.ResultStore
+	logins        *data.SessionStore
+	resultService service.ResultService
+	backupService service.BackupService
+	hub           *Hub
+	startedAt     time.Time
+	listener      net.Listener
+	httpServer    *http.Server
 }
 
-// NewServer 创建服务器实例
+// NewServer 创建服务器实例，配置从环境变量加载
 func NewServer() *Server {
-	// 初始化数据存储，对应三个本地数据库
-	userStore := data.NewUserStore()     //所有用户信息
-	roomStore := data.NewRoomStore()     //所有房间信息
-	resultStore := data.NewResultStore() //所有游戏结果信息，游戏结果不暴露给客户端
+	s, err := NewServerWithConfig(config.Load())
+	if err != nil {
+		log.Fatal(err)
+	}
+	return s
+}
+
+// NewServerWithConfig 按指定配置创建服务器实例
+func NewServerWithConfig(cfg *config.Config) (*Server, error) {
+	report.SetSlowThreshold(cfg.SlowThreshold)
+	if err := configureStorage(cfg); err != nil {
+		return nil, fmt.Errorf("初始化数据存储失败: %v", err)
+	}
+
+	// 初始化数据存储，对应三个本地数据库：用户信息、房间信息、游戏结果（游戏结果不暴露给客户端）
+	userStore, roomStore, resultStore := newStores(cfg)
+	if err := errors.Join(userStore.LoadErr(), roomStore.LoadErr(), resultStore.LoadErr()); err != nil {
+		return nil, fmt.Errorf("加载数据失败: %v", err)
+	}
+	if err := data.RecoverTransaction(userStore, roomStore); err != nil {
+		return nil, fmt.Errorf("恢复未完成的事务失败: %v", err)
+	}
 
 	// 初始化仓库
-	userRepo := repository.NewUserRepository(userStore)
-	roomRepo := repository.NewRoomRepository(roomStore)
+	userRepo, roomRepo := newRepositories(cfg, userStore, roomStore)
 	resultRepo := repository.NewResultRepository(resultStore)
+	logins := data.NewSessionStore()
+	uow := repository.NewUnitOfWork(userStore, roomStore)
+	backupRepo := repository.NewBackupRepository(data.NewBackupManager(cfg.BackupKeep, userStore, roomStore, resultStore))
 
 	// 初始化服务
-	userService := service.NewUserService(userRepo)
-	roomService := service.NewRoomService(roomRepo, userRepo, resultRepo)
+	sessionRepo := repository.NewSessionRepository(logins)
+	avatarRepo := repository.NewAvatarRepository(newAvatarStore(cfg))
+	words := newWordFilter(cfg)
+	userService := service.NewUserService(userRepo, roomRepo, resultRepo, sessionRepo, newPasswordPolicy(cfg), newMailer(cfg), repository.NewLoginHistoryRepository(newLoginHistoryStore(cfg)), repository.NewRefreshTokenRepository(newRefreshTokenStore(cfg)), avatarRepo, words)
+	roomLimiter := service.NewRoomLimiter(cfg.MaxRooms, cfg.MaxRoomsPerUserHour)
+	regions := service.NewRegions(cfg.Regions)
+	roomService := service.NewRoomService(roomRepo, userRepo, resultRepo, uow, roomLimiter, regions, words, cfg.RoomSwitchMode, cfg.RoomCreateKeyTTL, service.RoomSize{Min: cfg.RoomMinPlayers, Max: cfg.RoomMaxPlayers, Clamp: cfg.RoomClampPlayers})
+	resultService := service.NewResultService(resultRepo)
+	backupService := service.NewBackupService(backupRepo)
+	authService := service.NewAuthService(userRepo, newAuthProviders(cfg), cfg.AuthCallbackURL)
+	analytics := newAnalyticsStore(cfg)
+	analyticsService := service.NewAnalyticsService(repository.NewAnalyticsRepository(analytics), resultRepo)
+	avatarService := service.NewAvatarService(userRepo, sessionRepo, avatarRepo)
+	flagRepo := repository.NewCheatFlagRepository(newCheatFlagStore(cfg))
+	disputeRepo := repository.NewDisputeRepository(newDisputeStore(cfg))
+	moderation := service.NewModerationService(flagRepo, disputeRepo, cfg.CheatFlagThreshold)
+	titles := service.NewTitleService(userRepo, resultRepo, sessionRepo)
+	tournaments := service.NewTournamentService(repository.NewTournamentRepository(newTournamentStore(cfg)), userRepo, sessionRepo)
+	practice := service.NewPracticeService(repository.NewPracticeRepository(newPracticeStore(cfg)))
+	ladder := service.NewLadderService(repository.NewLadderRepository(newLadderStore(cfg)), userRepo)
+	events := service.NewEventService(repository.NewEventRepository(newEventStore(cfg)))
+	playlist := service.NewPlaylistService(repository.NewPlaylistRepository(newPlaylistStore(cfg)))
+	disputes := service.NewDisputeService(disputeRepo, repository.NewAdjustmentRepository(newAdjustmentStore(cfg)),
+		resultRepo, userRepo, sessionRepo, flagRepo, cfg.DisputeWindow)
+	playerSearch := service.NewPlayerSearch(userRepo, service.NewRateLimiter(cfg.PlayerSearchPerMinute, time.Minute))
+	userStore.OnChange(func(ev data.UserChange) {
+		playerSearch.Apply(ev.Old, ev.New)
+	})
 
 	// 初始化 Hub
-	hub := newHub(userStore, roomStore, resultStore)
+	heroes, err := data.LoadHeroRoster()
+	if err != nil {
+		return nil, fmt.Errorf("加载英雄数据失败: %v", err)
+	}
+	bots, err := data.LoadBotRoster()
+	if err != nil {
+		return nil, fmt.Errorf("加载机器人数据失败: %v", err)
+	}
+	registry := cluster.NewRegistry(cfg.RedisAddr, cfg.RedisPassword, instanceID(cfg), cfg.InstanceAddr)
+	if registry != nil {
+		logins.SetMirror(registry)
+		roomService.SetRoomDirectory(registry)
+	}
+	hub := newHub(cfg, userStore, roomStore, resultStore, logins, newInviteStore(cfg), newBalanceStore(cfg, heroes), roomService, regions, words, registry)
+	hub.analytics = analytics
+	hub.moderation = moderation
+	hub.titles = titles
+	hub.practiceScores = practice
+	hub.bots = bots
+	userService.SetSessionInvalidator(hub)
+	userService.SetUserDataEraser(hub)
+	userService.SetUserDataExporter(hub)
+	tournaments.SetRatings(hub)
+	tournaments.SetBracketPublisher(hub)
+	tournaments.SetHost(hub)
+	hub.tournaments = tournaments
+	hub.ladder = ladder
+	hub.challenges = newChallengeStore(cfg)
+	hub.events = events
+	hub.playlist = playlist
+	hub.disputes = disputes
+	disputes.SetEvidence(hub)
 
 	// 初始化路由器
-	router := api.NewRouter(userService, roomService)
+	router := api.NewRouter(cfg, userService, roomService, resultService, backupService, authService, analyticsService, avatarService, words)
+	router.SetMatchStats(hub)
+	router.SetModeration(moderation)
+	router.SetPlayerSearch(playerSearch)
+	router.SetTitles(titles)
+	router.SetConfigReloader(hub)
+	router.SetBalance(hub)
+	router.SetTournaments(tournaments)
+	router.SetLadder(ladder)
+	router.SetEvents(events)
+	router.SetPlaylist(playlist)
+	router.SetDisputes(disputes)
 
 	// 启动时的初始化清理
 	log.Println("正在执行初始化清理操作...")
@@ -48,6 +155,7 @@ func NewServer() *Server {
 	for _, room := range rooms {
 		roomStore.Remove(room.ID)
 	}
+	registry.ResetInstance()
 	log.Println("已清空所有房间")
 
 	// 2. 重置所有用户状态（离线，清除房间ID）
@@ -61,29 +169,336 @@ func NewServer() *Server {
 	}
 	log.Println("已重置所有用户状态")
 
-	return &Server{
-		router:      router,
-		userStore:   userStore,
-		roomStore:   roomStore,
-		resultStore: resultStore,
-		hub:         hub,
+	s := &Server{
+		cfg:           cfg,
+		router:        router,
+		userStore:     userStore,
+		roomStore:     roomStore,
+		resultStore:   resultStore,
+		logins:        logins,
+		resultService: resultService,
+		backupService: backupService,
+		hub:           hub,
+		startedAt:     hub.clock.Now(),
+	}
+	router.SetServerStats(s)
+	return s, nil
+}
+
+// instanceID 返回集群中本实例的标识，未配置时使用主机名和监听地址
+func instanceID(cfg *config.Config) string {
+	if cfg.InstanceID != "" {
+		return cfg.InstanceID
+	}
+	host, _ := os.Hostname()
+	return host + cfg.Addr
+}
+
+// newStores 按持久化配置创建用户、房间和游戏结果存储
+func newStores(cfg *config.Config) (*data.UserStore, *data.RoomStore, *data.ResultStore) {
+	if cfg.InMemory() {
+		log.Println("使用内存存储，数据不会写入磁盘")
+		return data.NewUserStoreInMemory(), data.NewRoomStoreInMemory(), data.NewResultStoreInMemory()
+	}
+	return data.NewUserStore(), data.NewRoomStore(), data.NewResultStore()
+}
+
+// newAnalyticsStore 按持久化配置创建运营统计存储
+func newAnalyticsStore(cfg *config.Config) *data.AnalyticsStore {
+	if cfg.InMemory() {
+		return data.NewAnalyticsStoreInMemory()
+	}
+	return data.NewAnalyticsStore()
+}
+
+// newAvatarStore 按配置创建头像存储
+func newAvatarStore(cfg *config.Config) data.AvatarStore {
+	if cfg.InMemory() {
+		return data.NewAvatarStoreInMemory()
 	}
+	return data.NewAvatarStore()
 }
 
-// Start 启动服务器
+// newWordFilter 按配置创建屏蔽词过滤器，首次启动时从屏蔽词列表文件导入初始屏蔽词
+func newWordFilter(cfg *config.Config) *service.WordFilter {
+	var seed []string
+	if cfg.WordListFile != "" {
+		list, err := validate.LoadList(cfg.WordListFile)
+		if err != nil {
+			log.Printf("读取屏蔽词列表失败: %v", err)
+		}
+		seed = list
+	}
+	var store *data.WordStore
+	if cfg.InMemory() {
+		store = data.NewWordStoreInMemory(seed)
+	} else {
+		store = data.NewWordStore(seed)
+	}
+	return service.NewWordFilter(repository.NewWordRepository(store), cfg.ChatFilterMode)
+}
+
+// newPasswordPolicy 按配置创建密码策略，弱密码列表文件读取失败时只使用内置列表
+func newPasswordPolicy(cfg *config.Config) *validate.PasswordPolicy {
+	var extra []string
+	if cfg.PasswordBlacklistFile != "" {
+		list, err := validate.LoadList(cfg.PasswordBlacklistFile)
+		if err != nil {
+			log.Printf("读取弱密码列表失败，只使用内置列表: %v", err)
+		}
+		extra = list
+	}
+	return validate.NewPasswordPolicy(cfg.PasswordMinLength, cfg.PasswordMinClasses, cfg.PasswordRejectCommon, extra)
+}
+
+// newAuthProviders 按配置注册第三方登录提供方
+func newAuthProviders(cfg *config.Config) *auth.Registry {
+	var providers []auth.Provider
+	if cfg.SteamLogin {
+		providers = append(providers, auth.NewSteamProvider(cfg.AuthCallbackURL))
+	}
+	if cfg.OAuthName != "" && cfg.OAuthClientID != "" {
+		providers = append(providers, auth.NewOAuth2Provider(auth.OAuth2Config{
+			Name:         cfg.OAuthName,
+			ClientID:     cfg.OAuthClientID,
+			ClientSecret: cfg.OAuthClientSecret,
+			AuthURL:      cfg.OAuthAuthURL,
+			TokenURL:     cfg.OAuthTokenURL,
+			UserInfoURL:  cfg.OAuthUserInfoURL,
+			Scopes:       cfg.OAuthScopes,
+		}))
+	}
+	return auth.NewRegistry(providers...)
+}
+
+// newRepositories 创建用户和房间仓库，配置了缓存容量时在存储前加一层查询缓存
+func newRepositories(cfg *config.Config, userStore *data.UserStore, roomStore *data.RoomStore) (repository.UserRepository, repository.RoomRepository) {
+	if cfg.CacheSize <= 0 {
+		return repository.NewUserRepository(userStore), repository.NewRoomRepository(roomStore)
+	}
+	return repository.NewCachedUserRepository(userStore, cfg.CacheSize),
+		repository.NewCachedRoomRepository(roomStore, cfg.CacheSize)
+}
+
+// newLoginHistoryStore 按持久化配置创建登录历史存储
+func newLoginHistoryStore(cfg *config.Config) *data.LoginHistoryStore {
+	if cfg.InMemory() {
+		return data.NewLoginHistoryStoreInMemory()
+	}
+	return data.NewLoginHistoryStore()
+}
+
+// newRefreshTokenStore 按持久化配置创建刷新令牌存储
+func newRefreshTokenStore(cfg *config.Config) *data.RefreshTokenStore {
+	if cfg.InMemory() {
+		return data.NewRefreshTokenStoreInMemory()
+	}
+	return data.NewRefreshTokenStore()
+}
+
+// newInviteStore 按持久化配置创建房间邀请存储
+func newInviteStore(cfg *config.Config) *data.InviteStore {
+	if cfg.InMemory() {
+		return data.NewInviteStoreInMemory()
+	}
+	return data.NewInviteStore()
+}
+
+// newCheatFlagStore 按存储模式创建作弊标记存储
+func newCheatFlagStore(cfg *config.Config) *data.CheatFlagStore {
+	if cfg.InMemory() {
+		return data.NewCheatFlagStoreInMemory()
+	}
+	return data.NewCheatFlagStore()
+}
+
+// newBalanceStore 按存储模式创建平衡版本存储，current 为启动时加载的 heroes.json
+func newBalanceStore(cfg *config.Config, current *data.HeroRoster) *data.BalanceStore {
+	if cfg.InMemory() {
+		return data.NewBalanceStoreInMemory(current)
+	}
+	return data.NewBalanceStore(current)
+}
+
+// newTournamentStore 按存储模式创建赛事存储
+func newTournamentStore(cfg *config.Config) *data.TournamentStore {
+	if cfg.InMemory() {
+		return data.NewTournamentStoreInMemory()
+	}
+	return data.NewTournamentStore()
+}
+
+// newLadderStore 按存储模式创建天梯存储
+func newLadderStore(cfg *config.Config) *data.LadderStore {
+	if cfg.InMemory() {
+		return data.NewLadderStoreInMemory()
+	}
+	return data.NewLadderStore()
+}
+
+// newEventStore 按存储模式创建限时活动存储
+func newEventStore(cfg *config.Config) *data.EventStore {
+	if cfg.InMemory() {
+		return data.NewEventStoreInMemory()
+	}
+	return data.NewEventStore()
+}
+
+// newPlaylistStore 按存储模式创建精选玩法存储
+func newPlaylistStore(cfg *config.Config) *data.PlaylistStore {
+	if cfg.InMemory() {
+		return data.NewPlaylistStoreInMemory()
+	}
+	return data.NewPlaylistStore()
+}
+
+// newDisputeStore 按存储模式创建结果申诉存储
+func newDisputeStore(cfg *config.Config) *data.DisputeStore {
+	if cfg.InMemory() {
+		return data.NewDisputeStoreInMemory()
+	}
+	return data.NewDisputeStore()
+}
+
+// newAdjustmentStore 按存储模式创建评分补偿存储
+func newAdjustmentStore(cfg *config.Config) *data.AdjustmentStore {
+	if cfg.InMemory() {
+		return data.NewAdjustmentStoreInMemory()
+	}
+	return data.NewAdjustmentStore()
+}
+
+// newChallengeStore 按存储模式创建天梯挑战存储
+func newChallengeStore(cfg *config.Config) *data.ChallengeStore {
+	if cfg.InMemory() {
+		return data.NewChallengeStoreInMemory()
+	}
+	return data.NewChallengeStore()
+}
+
+// newPracticeStore 按存储模式创建练习成绩存储
+func newPracticeStore(cfg *config.Config) *data.PracticeStore {
+	if cfg.InMemory() {
+		return data.NewPracticeStoreInMemory()
+	}
+	return data.NewPracticeStore()
+}
+
+// newMailer 配置了 SMTP 服务器时通过 SMTP 发信，否则只把邮件写入日志
+func newMailer(cfg *config.Config) mail.Mailer {
+	if cfg.SMTPAddr == "" {
+		return mail.LogMailer{}
+	}
+	return mail.NewSMTPMailer(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+}
+
+// Start 启动服务器，阻塞直到 HTTP 服务退出
 func (s *Server) Start() error {
+	if err := s.listen(); err != nil {
+		return err
+	}
+	return s.serve()
+}
+
+// StartWithConfig 按指定配置创建服务器并在后台启动，返回时已开始监听。
+// cfg.Addr 为空时监听 127.0.0.1 上的随机端口，测试通过 Addr 获取实际地址
+func StartWithConfig(cfg *config.Config) (*Server, error) {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:0"
+	}
+	s, err := NewServerWithConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.listen(); err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := s.serve(); err != nil {
+			log.Printf("HTTP 服务异常退出: %v", err)
+		}
+	}()
+	return s, nil
+}
+
+// Addr 返回实际监听地址，启动前为空
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Close 停止 HTTP 服务，中止进行中的对局并断开所有 WebSocket 连接
+func (s *Server) Close() error {
+	if s.httpServer == nil {
+		return nil
+	}
+	err := s.httpServer.Close()
+	s.hub.sessions.AbandonAll()
+	s.hub.closeAll()
+	return err
+}
+
+// listen 注册路由、启动后台任务并绑定监听地址
+func (s *Server) listen() error {
 	// 设置路由
 	s.router.SetupRoutes()
 
 	// 添加 WebSocket 路由，转发到 Hub
 	s.router.Engine.GET("/ws", s.serveWs)
 
+	// 公开服务器信息，供第三方服务器浏览器查询
+	s.router.Engine.GET("/serverinfo", s.handleServerInfo)
+
+	// 先占用端口，监听失败时不启动任何后台协程
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %v", s.cfg.Addr, err)
+	}
+	s.listener = ln
+	s.httpServer = &http.Server{Handler: s.router.Engine}
+
 	// 启动 Hub
 	go s.hub.run()
 	go s.hub.heartbeatCheck()
+	go s.hub.matchmakerLoop()
+	if s.hub.cluster != nil {
+		go s.hub.clusterLoop()
+	}
+	if s.cfg.LobbyIdleTimeout > 0 {
+		go s.hub.idleReaper()
+	}
+	if s.cfg.ResultRetention > 0 && s.cfg.ResultPruneInterval > 0 {
+		go s.resultPruner()
+	}
+	if s.cfg.ResultCompactInterval > 0 {
+		go s.resultCompactor()
+	}
+	if s.cfg.BackupInterval > 0 {
+		go s.backupScheduler()
+	}
+	if s.cfg.RepairInterval > 0 {
+		go s.roomRepairer()
+	}
+	if s.cfg.AnalyticsSampleInterval > 0 {
+		go s.hub.analyticsSampler()
+	}
+	if s.cfg.TournamentScheduleInterval > 0 {
+		go s.hub.tournamentScheduler()
+	}
+	if s.cfg.MasterServerURL != "" && s.cfg.AnnounceInterval > 0 {
+		go s.announcer()
+	}
+	return nil
+}
 
-	// 启动 HTTP 服务器
-	log.Println("游戏服务器启动在 http://localhost:8080")
-	log.Println("WebSocket: ws://localhost:8080/ws?username=xxx")
-	return s.router.Run(":8080")
+// serve 启动 HTTP 服务器，Close 导致的退出不视为错误
+func (s *Server) serve() error {
+	log.Printf("游戏服务器启动在 http://%s", s.Addr())
+	log.Printf("WebSocket: ws://%s/ws?username=xxx", s.Addr())
+	if err := s.httpServer.Serve(s.listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,616 @@
+package app
+
+import (
+	"log"
+	"math"
+	"runtime/debug"
+	"time"
+
+	"game/data"
+	"game/game"
+	"game/models"
+	"game/protocol"
+	"game/report"
+	"game/service"
+	"game/sim"
+)
+
+// sessionEvent 定义投递给游戏会话的一条客户端消息，left 表示该客户端已断开，rejoined 表示重新连接
+type sessionEvent struct {
+	client   *Client
+	msg      protocol.Message
+	msgID    string // 触发事件的消息ID，会话协程异步处理时用于日志
+	left     bool
+	rejoined bool // 掉线的玩家在宽限期内重新连接
+	started  bool // 开局消息已发出，由 Hub 投递，client 为 nil
+	abandon  bool // 对局被中止，由 SessionManager 投递，client 为 nil
+}
+
+// roomSession 定义单个房间的游戏会话，由 game.SessionManager 在独立协程中运行
+type roomSession struct {
+	hub       *Hub
+	roomID    string
+	mapName   string
+	rules     models.Rules
+	heroes    map[string]models.Hero // 玩家使用的英雄，按用户名索引
+	roster    *data.HeroRoster       // 开局时生效的平衡版本，对局中途推送的新版本不影响本局
+	startedAt time.Time
+	players   []string                        // 按入场顺序排列的玩家
+	stats     map[string]*models.PlayerResult // 玩家统计，按用户名索引
+	bot       *botPlayer                      // 房间中的机器人玩家，没有时为 nil
+	lastHit   map[string]protocol.HitAction   // 每名玩家最后一次被命中的信息，用于补全击杀播报
+	streaks   map[string]int                  // 当前连续击杀数，阵亡后清零
+	hp        map[string]int                  // 本局剩余生命值，按规则的起始生命值和伤害倍率结算
+	round     int                             // 当前局数，从 1 开始
+	roundWins map[string]int                  // 每名玩家赢下的局数
+	overtime  bool                            // 是否处于加时阶段
+	timer     sim.Ticker                      // 对局时间上限计时，未限时时为 nil
+	timerEnds time.Time                       // 计时到期时间
+	timeLeft  time.Duration                   // 暂停时保存的剩余时间
+	paused    map[string]time.Time            // 掉线等待重连的玩家及其宽限期截止时间
+	ffa       bool                            // 超过两名玩家时为混战模式，每名玩家各自为战
+	out       map[string]bool                 // 混战中已出局的玩家
+	outOrder  []string                        // 按出局先后排列的玩家
+	positions map[string]float64              // 玩家最近上报的纵向位置，用于地图目标判定
+	lastMove  map[string]time.Time            // 玩家最近一次移动的时间，用于计算允许的最大位移
+	loadouts  map[string]*loadout             // 多局制对局中玩家的金钱和购买的装备
+	buyEnds   time.Time                       // 本局购买阶段的截止时间
+	zoneTicks int                             // 本局已进行的目标结算次数，决定安全区收缩进度
+	countdown sim.Ticker                      // 暂停期间每秒推送倒计时
+	ticks     int64                           // 已推进的逻辑帧数
+	snapshots int64                           // 已生成的状态快照数
+	sendRates map[string]*snapshotRate        // 每个客户端的快照接收频率，按用户名索引
+	entities  *game.Entities                  // 子弹等实体的ID分配和存活登记
+	seeds     sim.RNG                         // 为每一局抽取随机种子
+	seed      int64                           // 本局的随机种子，散布和暴击按它生成的序列结算
+	shots     map[string]uint64               // 本局每名玩家的射击次数，即下一次射击在种子序列中的位置
+	crits     map[string]bool                 // 本局暴击的子弹，按实体ID索引
+	cheats    map[string]map[string]int       // 服务器校验发现的违规，按用户名和违规类型统计，对局结束后计入作弊标记
+	titles    map[string]string               // 玩家开局时装备的称号，用于击杀播报
+	kickVotes map[string]*kickVote            // 进行中的投票踢人，按目标用户名索引
+	vote      *matchVote                      // 进行中的对局内投票，没有时为 nil
+	events    chan sessionEvent
+	done      chan struct{}
+}
+
+// startSession 为房间启动游戏会话并返回第一局的随机种子，已存在时直接复用并返回 0
+func (h *Hub) startSession(room models.Room) int64 {
+	var seed int64
+	h.sessions.Start(room.ID, func() game.Session {
+		h.clearSpectatorChat(room.ID)
+		s := h.newSession(room)
+		seed = s.seed
+		return s
+	})
+	return seed
+}
+
+// abandonRemovedRoom 房间被删除时中止其进行中的对局，否则会话协程会一直运行下去
+func (h *Hub) abandonRemovedRoom(ev data.RoomChange) {
+	if ev.New == nil {
+		h.sessions.Abandon(ev.Old.ID)
+	}
+}
+
+// newSession 按房间的玩家、英雄和规则创建游戏会话
+func (h *Hub) newSession(room models.Room) *roomSession {
+	roster := h.roster()
+	stats := make(map[string]*models.PlayerResult)
+	heroes := make(map[string]models.Hero)
+	for _, player := range room.Players {
+		stats[player] = &models.PlayerResult{Username: player}
+		if user := h.userStore.FindByUsername(player); user != nil {
+			stats[player].UserID = user.UserID
+		}
+		hero, ok := roster.Get(room.Heroes[player])
+		if !ok {
+			hero = roster.Default()
+		}
+		heroes[player] = hero
+	}
+	for _, c := range h.roomPeers(room.ID, "") {
+		if st, ok := stats[c.username]; ok {
+			st.ClientVersion = c.version
+		}
+	}
+
+	s := &roomSession{
+		hub:       h,
+		roomID:    room.ID,
+		mapName:   room.Map,
+		rules:     room.Rules.WithDefaults(),
+		heroes:    heroes,
+		roster:    roster,
+		startedAt: h.clock.Now(),
+		players:   append([]string(nil), room.Players...),
+		stats:     stats,
+		lastHit:   make(map[string]protocol.HitAction),
+		streaks:   make(map[string]int),
+		hp:        make(map[string]int),
+		round:     1,
+		roundWins: make(map[string]int),
+		paused:    make(map[string]time.Time),
+		ffa:       len(room.Players) > 2,
+		out:       make(map[string]bool),
+		positions: make(map[string]float64),
+		lastMove:  make(map[string]time.Time),
+		loadouts:  make(map[string]*loadout),
+		sendRates: make(map[string]*snapshotRate),
+		entities:  game.NewEntities(),
+		seeds:     h.seeder.New(),
+		cheats:    make(map[string]map[string]int),
+		titles:    h.activeTitles(room.Players),
+		events:    make(chan sessionEvent, 256),
+		done:      make(chan struct{}),
+	}
+	s.resetHP()
+	s.initEconomy()
+	s.newRoundSeed()
+	for i, player := range room.Players {
+		if models.IsBot(player) {
+			s.bot = newBotPlayer(player, s.opponentOf(player), i, h.botProfileFor(player), h.seeder.New())
+			s.bot.profile.moveSpeed *= s.rules.MoveSpeed * heroes[player].Speed
+			break
+		}
+	}
+	return s
+}
+
+// session 返回房间当前的游戏会话
+func (h *Hub) session(roomID string) *roomSession {
+	s, _ := h.sessions.Get(roomID).(*roomSession)
+	return s
+}
+
+// dispatchToSession 将对局消息投递给客户端所在房间的游戏会话
+func (h *Hub) dispatchToSession(client *Client, msg protocol.Message) {
+	// 大厅中的玩家的对局消息属于练习
+	if client.roomID == "" {
+		if p := h.practice(client.username); p != nil && p.client == client {
+			p.post(practiceEvent{msg: msg})
+			return
+		}
+	}
+	s := h.session(client.roomID)
+	if s == nil {
+		client.logf("用户 %s 所在房间没有进行中的对局，忽略消息 %s", client.username, msg.Type)
+		return
+	}
+	s.post(sessionEvent{client: client, msg: msg, msgID: client.messageID()})
+}
+
+// dispatchStarted 通知游戏会话开局消息已发出，会话随后开始第一局的购买阶段
+func (h *Hub) dispatchStarted(roomID string) {
+	if s := h.session(roomID); s != nil {
+		s.post(sessionEvent{started: true})
+	}
+}
+
+// dispatchLeave 通知游戏会话玩家已断开连接
+func (h *Hub) dispatchLeave(client *Client) {
+	if s := h.session(client.roomID); s != nil {
+		s.post(sessionEvent{client: client, left: true})
+	}
+}
+
+// dispatchRejoin 通知游戏会话玩家已重新连接
+func (h *Hub) dispatchRejoin(client *Client) {
+	if s := h.session(client.roomID); s != nil {
+		s.post(sessionEvent{client: client, rejoined: true})
+	}
+}
+
+// post 投递事件，会话已结束时直接丢弃
+func (s *roomSession) post(ev sessionEvent) {
+	select {
+	case s.events <- ev:
+	case <-s.done:
+	}
+}
+
+// Abandon 中止对局，实现 game.Session
+func (s *roomSession) Abandon() {
+	s.post(sessionEvent{abandon: true})
+}
+
+// finishAfterPanic 以服务器错误结束对局。会话状态可能已被 panic 破坏，结算再次 panic 时只记录日志，
+// 保证 Run 仍能关闭 done，不会拖垮整个进程
+func (s *roomSession) finishAfterPanic() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("房间 %s 在 panic 后结算对局再次失败: %v", s.roomID, r)
+		}
+	}()
+	s.finish(protocol.GameOverInfo{Reason: models.ResultReasonServerError})
+}
+
+// Run 会话主循环，实现 game.Session。出现 panic 时只结束本房间的对局，不影响 Hub 和其他房间
+func (s *roomSession) Run() {
+	defer close(s.done)
+	defer func() {
+		if r := recover(); r != nil {
+			report.Panic(r, debug.Stack(), map[string]string{"room_id": s.roomID})
+			s.finishAfterPanic()
+		}
+	}()
+
+	// 逻辑帧按房间规则的帧率推进，状态快照按发送频率单独推送
+	ticker := s.hub.clock.NewTicker(s.tickInterval())
+	defer ticker.Stop()
+	var snapshotTick <-chan time.Time
+	if interval := s.snapshotInterval(); interval > 0 {
+		snapshots := s.hub.clock.NewTicker(interval)
+		defer snapshots.Stop()
+		snapshotTick = snapshots.C()
+	}
+
+	// 规则设置了时间上限时到时结算，打平时先进入加时
+	if s.rules.TimeLimit > 0 {
+		s.startTimer(time.Duration(s.rules.TimeLimit) * time.Second)
+	}
+	defer s.stopTimer()
+	defer s.stopCountdown()
+
+	// 启用地图目标时每秒结算一次控制点得分或区外伤害
+	var objectiveTick <-chan time.Time
+	if s.hasObjective() {
+		ticker := s.hub.clock.NewTicker(objectiveTickInterval)
+		defer ticker.Stop()
+		objectiveTick = ticker.C()
+	}
+
+	for {
+		// 暂停期间逻辑帧、快照、地图目标和对局计时都停止，只有倒计时继续
+		tick, snapshot, objective, timeUp := ticker.C(), snapshotTick, objectiveTick, s.timerC()
+		if s.isPaused() {
+			tick, snapshot, objective, timeUp = nil, nil, nil, nil
+		}
+
+		select {
+		case ev := <-s.events:
+			if s.handle(ev) {
+				return
+			}
+		case now := <-tick:
+			s.ticks++
+			s.expireEntities(now)
+			if s.bot != nil && s.tickBot(now) {
+				return
+			}
+		case <-snapshot:
+			s.sendSnapshot()
+		case <-objective:
+			if s.tickObjective() {
+				return
+			}
+		case now := <-s.countdownC():
+			if s.tickCountdown(now) {
+				return
+			}
+		case <-timeUp:
+			if !s.overtime && s.startOvertime() {
+				s.startTimer(overtimeDuration)
+				continue
+			}
+			s.finishOnTime()
+			return
+		}
+	}
+}
+
+// decode 严格解码对局消息的 payload，格式错误时通知上报方并返回 false
+func (s *roomSession) decode(ev sessionEvent, v any) bool {
+	if err := protocol.DecodeBytes(ev.msg.Payload, v); err != nil {
+		s.hub.sendDecodeError(ev.client, err)
+		return false
+	}
+	return true
+}
+
+// handle 处理一条对局消息，返回 true 表示对局已结束
+func (s *roomSession) handle(ev sessionEvent) bool {
+	if ev.started {
+		if s.economy() {
+			s.startBuyPhase()
+			s.broadcastRoundStart()
+		}
+		return false
+	}
+	if ev.abandon {
+		s.finish(protocol.GameOverInfo{Reason: models.ResultReasonAbandoned})
+		return true
+	}
+	sender := s.stats[ev.client.username]
+	if s.bot != nil && !ev.left {
+		s.bot.observe(ev.client.username, ev.msg, s.hub.clock.Now())
+	}
+
+	if ev.left {
+		if sender == nil {
+			return false
+		}
+		sender.Disconnects++
+		if s.hub.cfg.ReconnectGrace > 0 {
+			s.pause(ev.client.username)
+			return false
+		}
+		return s.forfeit(ev.client.username, models.ResultReasonDisconnect)
+	}
+	if ev.rejoined {
+		s.resume(ev.client.username)
+		return false
+	}
+	if s.isPaused() && gameplayMessage(ev.msg.Type) {
+		// 暂停期间丢弃对局操作，避免趁对手掉线得分
+		return false
+	}
+
+	switch ev.msg.Type {
+	case protocol.MsgTypePlayerAction:
+		var action protocol.PlayerAction
+		if !s.decode(ev, &action) {
+			break
+		}
+		if action.Action == "move_y" && s.hub.cfg.MovementValidation {
+			// 坐标不是有限数值时直接拒绝，超速或穿墙时按服务器认定的位置转发并纠正上报方
+			if math.IsNaN(action.Value) || math.IsInf(action.Value, 0) {
+				break
+			}
+			reported := action.Value
+			action.Value = s.validateMove(ev.client.username, reported, s.hub.clock.Now())
+			if action.Value != reported {
+				action.PlayerID = ev.client.username
+				ev.msg.Payload = mustMarshal(action)
+				s.correctMove(ev.client, action.Value, reported)
+				s.violation(ev.client.username, models.ViolationMoveCorrection)
+			}
+		}
+		s.trackPosition(ev.client.username, action)
+		s.hub.broadcastGameAction(ev.client, ev.msg)
+
+	case protocol.MsgTypeFire:
+		// 子弹ID由服务器分配，客户端上报的 bullet_id 只用于射击方对应本地子弹
+		var fire protocol.FireAction
+		if !s.decode(ev, &fire) {
+			break
+		}
+		if sender != nil {
+			sender.ShotsFired++
+		}
+		crit := s.seedFire(ev, &fire)
+		fire = s.spawnBullet(ev.client.username, fire, bulletLifetime)
+		if crit {
+			s.crits[fire.BulletID] = true
+		}
+		s.hub.broadcastGameAction(ev.client, protocol.Message{Type: ev.msg.Type, Payload: mustMarshal(fire)})
+
+	case protocol.MsgTypeHit:
+		// 命中由射击方上报，伤害由服务器按射击方英雄的武器、双方距离和是否爆头计算，
+		// 再按房间规则和护甲结算后转发；使用未装备（英雄自带或本局购买）的武器的命中直接丢弃
+		var hit protocol.HitAction
+		if !s.decode(ev, &hit) {
+			break
+		}
+		self := hit.TargetID == ev.client.username
+		if self && !s.rules.FriendlyFire {
+			break
+		}
+		if hit.BulletID != "" {
+			// 指明子弹的命中只在子弹仍然存活且属于上报方时有效，同一颗子弹只能命中一次
+			if bullet, ok := s.entities.Get(hit.BulletID); !ok || bullet.Owner != ev.client.username {
+				log.Printf("[%s] 用户 %s 上报的子弹 %s 不存在或不属于该用户，忽略命中", ev.msgID, ev.client.username, hit.BulletID)
+				s.violation(ev.client.username, models.ViolationInvalidHit)
+				break
+			}
+		}
+		weapon, ok := s.weapon(ev.client.username, hit.Weapon)
+		if !ok {
+			log.Printf("[%s] 用户 %s 上报了未装备的武器 %s，忽略命中", ev.msgID, ev.client.username, hit.Weapon)
+			s.violation(ev.client.username, models.ViolationInvalidHit)
+			break
+		}
+		hit.Distance = s.distance(ev.client.username, hit.TargetID)
+		hit.Weapon, hit.Damage = weapon.Name, weapon.DamageAt(hit.Distance, hit.Headshot)
+		s.seedHit(ev, &hit, weapon)
+		if hit.BulletID != "" {
+			s.despawn(hit.BulletID, protocol.DespawnHit)
+		}
+		hit = s.applyHit(hit)
+		if sender != nil && !self {
+			sender.ShotsHit++
+			sender.DamageDealt += hit.Damage
+			if hit.Headshot {
+				sender.Headshots++
+			}
+			s.lastHit[hit.TargetID] = hit
+		}
+		s.hub.broadcastGameAction(ev.client, protocol.Message{Type: ev.msg.Type, Payload: mustMarshal(hit)})
+		if _, tracked := s.hp[hit.TargetID]; tracked && !s.out[hit.TargetID] && hit.Remaining <= 0 {
+			// 生命值耗尽时由服务器判定阵亡，客户端上报的死亡不被采信
+			s.broadcast(protocol.MsgTypeDeath, map[string]string{"player_id": hit.TargetID})
+			return s.recordDeath(protocol.DeathAction{
+				PlayerID: hit.TargetID,
+				KillerID: ev.client.username,
+				Weapon:   hit.Weapon,
+				Headshot: hit.Headshot,
+			})
+		}
+
+	case protocol.MsgTypeDeath:
+		// 阵亡由服务器按命中结算判定，客户端上报的死亡只为兼容旧客户端而接收，不做处理
+
+	case protocol.MsgTypeEmote:
+		s.emote(ev)
+
+	case protocol.MsgTypeVoteKick:
+		return s.voteKick(ev)
+
+	case protocol.MsgTypeMatchVote:
+		return s.matchVote(ev)
+
+	case protocol.MsgTypeBuy:
+		var req protocol.BuyRequest
+		if !s.decode(ev, &req) {
+			break
+		}
+		s.buy(ev.client, req)
+
+	case protocol.MsgTypeGameOver:
+		// 客户端上报的胜负不可信，只当作上报方认输，胜者由服务器按对局玩家决定
+		return s.surrender(ev.client.username)
+	}
+	return false
+}
+
+// recordDeath 记录玩家阵亡并广播击杀播报和比分，对局结束时返回 true。
+// 双人对局中击杀归对手所有，对手赢下过半局数或局数打满时结束，否则开始下一局；
+// 混战模式见 recordFFADeath
+func (s *roomSession) recordDeath(death protocol.DeathAction) bool {
+	victim := death.PlayerID
+	if s.stats[victim] == nil || s.out[victim] {
+		return false
+	}
+	winner := s.opponentOf(victim)
+	if s.ffa {
+		// 混战中自杀或击杀者未知时不计击杀
+		winner = death.KillerID
+		if winner == victim || s.stats[winner] == nil {
+			winner = ""
+		}
+	}
+	s.stats[victim].Deaths++
+	s.streaks[victim] = 0
+	s.loseLoadout(victim)
+	if killer := s.stats[winner]; killer != nil {
+		killer.Kills++
+		s.earn(winner, killReward)
+		s.streaks[winner]++
+		if s.streaks[winner] > killer.LongestStreak {
+			killer.LongestStreak = s.streaks[winner]
+		}
+	}
+
+	var distance float64
+	if hit, ok := s.lastHit[victim]; ok {
+		distance = hit.Distance
+		if death.Weapon == "" && !death.Headshot {
+			death.Weapon = hit.Weapon
+			death.Headshot = hit.Headshot
+		}
+	}
+	s.broadcast(protocol.MsgTypeKillFeed, protocol.KillFeedEntry{
+		Killer:      winner,
+		Victim:      victim,
+		KillerTitle: s.titles[winner],
+		VictimTitle: s.titles[victim],
+		Weapon:      death.Weapon,
+		Headshot:    death.Headshot,
+		Distance:    distance,
+		Time:        s.hub.clock.Now(),
+	})
+	if s.ffa {
+		return s.recordFFADeath(victim, winner)
+	}
+	s.roundWins[winner]++
+	s.earn(winner, roundWinReward)
+	s.earn(victim, roundLossReward)
+	s.broadcastScoreboard()
+
+	if !s.overtime && s.roundWins[winner]*2 <= s.rules.Rounds && s.round < s.rules.Rounds {
+		s.nextRound()
+		return false
+	}
+	s.finish(protocol.GameOverInfo{
+		Winner: winner,
+		Loser:  victim,
+	})
+	return true
+}
+
+// broadcastScoreboard 向房间广播当前比分，客户端无需再从命中和阵亡事件自行推算
+func (s *roomSession) broadcastScoreboard() {
+	s.broadcast(protocol.MsgTypeScoreboard, protocol.Scoreboard{
+		RoomID:    s.roomID,
+		Elapsed:   int(s.hub.clock.Now().Sub(s.startedAt).Seconds()),
+		Round:     s.round,
+		RoundWins: s.roundWins,
+		Players:   service.PlayerSummaries(s.results()),
+	})
+}
+
+// opponentOf 返回对手用户名
+func (s *roomSession) opponentOf(username string) string {
+	for _, player := range s.players {
+		if player != username {
+			return player
+		}
+	}
+	return ""
+}
+
+// finish 汇总统计数据并结束对局
+func (s *roomSession) finish(gameOver protocol.GameOverInfo) {
+	if gameOver.Duration <= 0 {
+		gameOver.Duration = int(s.hub.clock.Now().Sub(s.startedAt).Seconds())
+	}
+	gameOver.Map = s.mapName
+	gameOver.BalanceVersion = s.roster.Version()
+	gameOver.Overtime = s.overtime
+	if s.ffa {
+		gameOver.Placements = s.ranking(gameOver.Winner)
+		if gameOver.Winner != "" {
+			gameOver.Loser = gameOver.Placements[len(gameOver.Placements)-1]
+		}
+	}
+	s.releasePaused()
+	players := s.results()
+	gameOver.MVP = pickMVP(players, gameOver.Winner)
+	s.hub.recordViolations(players, s.cheats)
+	s.hub.handleGameOver(s.roomID, gameOver, players)
+}
+
+// pickMVP 评选本局最佳玩家：依次比较击杀数、造成伤害、命中率，仍相同时优先胜者；
+// 中途弃赛的玩家不参与评选，没有任何击杀和伤害时不评选
+func pickMVP(players []models.PlayerResult, winner string) string {
+	var best *models.PlayerResult
+	for i := range players {
+		p := &players[i]
+		if p.Forfeited || (p.Kills == 0 && p.DamageDealt == 0) {
+			continue
+		}
+		if best == nil || betterMVP(p, best, winner) {
+			best = p
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.Username
+}
+
+// betterMVP 判断 a 是否优于 b
+func betterMVP(a, b *models.PlayerResult, winner string) bool {
+	switch {
+	case a.Kills != b.Kills:
+		return a.Kills > b.Kills
+	case a.DamageDealt != b.DamageDealt:
+		return a.DamageDealt > b.DamageDealt
+	case a.Accuracy != b.Accuracy:
+		return a.Accuracy > b.Accuracy
+	}
+	return a.Username == winner
+}
+
+// results 按入场顺序汇总玩家当前的得分和命中率
+func (s *roomSession) results() []models.PlayerResult {
+	players := make([]models.PlayerResult, 0, len(s.players))
+	for _, username := range s.players {
+		st := s.stats[username]
+		st.Score = st.Kills
+		if st.ShotsFired > 0 {
+			st.Accuracy = float64(st.ShotsHit) / float64(st.ShotsFired)
+		}
+		players = append(players, *st)
+	}
+	return players
+}
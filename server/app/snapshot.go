@@ -0,0 +1,120 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"game/protocol"
+)
+
+// 快照发送频率的自适应参数
+const (
+	maxSnapshotDivisor = 8  // 积压的客户端最低降到正常快照频率的 1/8
+	backlogSnapshots   = 3  // 连续这么多次快照时发送队列积压过半，接收频率减半
+	recoverSnapshots   = 20 // 连续这么多次快照时发送队列没有积压，接收频率加倍，直到恢复正常
+)
+
+// snapshotRate 单个客户端的快照接收频率
+type snapshotRate struct {
+	divisor int // 每 divisor 次快照发送一次
+	backlog int // 连续积压的次数
+	clear   int // 连续没有积压的次数
+}
+
+// adapt 按客户端发送队列的积压情况调整接收频率，频率变化时返回 true
+func (r *snapshotRate) adapt(queued, capacity int) bool {
+	if queued*2 >= capacity {
+		r.backlog, r.clear = r.backlog+1, 0
+		if r.backlog >= backlogSnapshots && r.divisor < maxSnapshotDivisor {
+			r.divisor, r.backlog = r.divisor*2, 0
+			return true
+		}
+		return false
+	}
+	r.backlog = 0
+	if queued == 0 {
+		r.clear++
+	}
+	if r.clear >= recoverSnapshots && r.divisor > 1 {
+		r.divisor, r.clear = r.divisor/2, 0
+		return true
+	}
+	return false
+}
+
+// tickInterval 返回对局的逻辑帧间隔，房间规则未设置帧率时使用服务器为该模式配置的帧率
+func (s *roomSession) tickInterval() time.Duration {
+	rate := s.rules.TickRate
+	if rate <= 0 {
+		rate = s.hub.cfg.ModeTickRates[s.rules.Objective]
+	}
+	if rate <= 0 {
+		rate = s.hub.cfg.TickRate
+	}
+	if rate <= 0 {
+		return botTickInterval
+	}
+	return time.Second / time.Duration(rate)
+}
+
+// snapshotInterval 返回快照发送间隔，不短于逻辑帧间隔；为 0 表示不发送快照
+func (s *roomSession) snapshotInterval() time.Duration {
+	rate := s.rules.SendRate
+	if rate <= 0 {
+		rate = s.hub.cfg.ModeSendRates[s.rules.Objective]
+	}
+	if rate <= 0 {
+		rate = s.hub.cfg.SendRate
+	}
+	if rate <= 0 {
+		return 0
+	}
+	return max(time.Second/time.Duration(rate), s.tickInterval())
+}
+
+// sendSnapshot 向房间推送当前状态快照。快照只是状态的最新值，积压的客户端跳过部分快照以免拖慢可靠的事件消息
+func (s *roomSession) sendSnapshot() {
+	s.snapshots++
+	snapshot := protocol.Snapshot{
+		RoomID:  s.roomID,
+		Tick:    s.ticks,
+		Players: make([]protocol.PlayerSnapshot, 0, len(s.players)),
+	}
+	for _, player := range s.players {
+		snapshot.Players = append(snapshot.Players, protocol.PlayerSnapshot{
+			Username: player,
+			Y:        s.positionOf(player),
+			HP:       s.hp[player],
+			Out:      s.out[player],
+		})
+	}
+	data, err := json.Marshal(protocol.Message{Type: protocol.MsgTypeSnapshot, Payload: mustMarshal(snapshot)})
+	if err != nil {
+		return
+	}
+
+	peers := s.hub.roomPeers(s.roomID, "")
+	due := make([]*Client, 0, len(peers))
+	seen := make(map[string]bool, len(peers))
+	for _, c := range peers {
+		seen[c.username] = true
+		rate := s.sendRates[c.username]
+		if rate == nil {
+			rate = &snapshotRate{divisor: 1}
+			s.sendRates[c.username] = rate
+		}
+		if s.hub.cfg.AdaptiveSendRate && rate.adapt(len(c.send), cap(c.send)) {
+			log.Printf("房间 %s 用户 %s 的快照接收频率调整为 1/%d", s.roomID, c.username, rate.divisor)
+		}
+		if s.snapshots%int64(rate.divisor) == 0 {
+			due = append(due, c)
+		}
+	}
+	for username := range s.sendRates {
+		if !seen[username] {
+			delete(s.sendRates, username)
+		}
+	}
+	s.hub.sendGame(s.roomID, due, data)
+}
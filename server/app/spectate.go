@@ -0,0 +1,177 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"game/protocol"
+)
+
+const (
+	// chatMaxLength 单条聊天消息的最大字符数
+	chatMaxLength = 200
+	// spectatorChatKeep 每个房间为对局结束后展示而保留的观战聊天条数
+	spectatorChatKeep = 200
+)
+
+// spectatorBlocked 判断观战者是否不能发送该类消息：观战者只能看和聊天，不能参与对局或管理房间
+func spectatorBlocked(msgType protocol.MessageType) bool {
+	switch msgType {
+	case protocol.MsgTypePlayerAction, protocol.MsgTypeFire, protocol.MsgTypeHit,
+		protocol.MsgTypeDeath, protocol.MsgTypeGameOver, protocol.MsgTypeBuy,
+		protocol.MsgTypeStartGame, protocol.MsgTypeAddBot:
+		return true
+	}
+	return false
+}
+
+// spectate 以观战者身份进入房间，之后会收到房间内的对局广播；已在观战其他房间时直接切换
+func (h *Hub) spectate(client *Client, req protocol.SpectateRequest) {
+	reply := func(resp protocol.JoinRoomResponse) {
+		data, _ := json.Marshal(protocol.Message{
+			Type:    protocol.MsgTypeSpectateResult,
+			Payload: mustMarshal(resp),
+		})
+		client.send <- data
+	}
+
+	if client.roomID != "" && !client.spectator {
+		reply(protocol.JoinRoomResponse{Message: "您已在房间中，无法观战"})
+		return
+	}
+	room := h.roomStore.GetByID(req.RoomID)
+	if room == nil {
+		reply(protocol.JoinRoomResponse{Message: "房间不存在"})
+		return
+	}
+
+	client.roomID = room.ID
+	client.spectator = true
+	reply(protocol.JoinRoomResponse{Success: true, Message: "开始观战", Room: h.roomInfo(*room)})
+	h.sendRoomKey(client)
+}
+
+// stopSpectate 结束观战回到大厅
+func (h *Hub) stopSpectate(client *Client) {
+	if !client.spectator {
+		return
+	}
+	client.roomID = ""
+	client.spectator = false
+}
+
+// chat 转发房间内的聊天消息，频道成员由 Hub 按身份决定：
+// 观战者只能发到观战频道，玩家只能发到房间频道，观战频道不会转发给玩家
+func (h *Hub) chat(client *Client, req protocol.ChatRequest) {
+	if client.roomID == "" {
+		h.sendError(client, http.StatusBadRequest, "不在房间中，无法聊天")
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return
+	}
+	if utf8.RuneCountInString(text) > chatMaxLength {
+		h.sendError(client, http.StatusBadRequest, "聊天消息过长")
+		return
+	}
+	text, err := h.words.FilterChat(text)
+	if err != nil {
+		h.sendError(client, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	channel := req.Channel
+	if channel == "" {
+		channel = protocol.ChatChannelRoom
+		if client.spectator {
+			channel = protocol.ChatChannelSpectator
+		}
+	}
+	switch {
+	case channel == protocol.ChatChannelSpectator && !client.spectator:
+		h.sendError(client, http.StatusForbidden, "只有观战者可以使用观战频道")
+		return
+	case channel == protocol.ChatChannelRoom && client.spectator:
+		h.sendError(client, http.StatusForbidden, "观战者不能在房间频道发言")
+		return
+	case channel != protocol.ChatChannelRoom && channel != protocol.ChatChannelSpectator:
+		h.sendError(client, http.StatusBadRequest, "未知的聊天频道")
+		return
+	}
+
+	msg := protocol.ChatMessageInfo{
+		From:    client.username,
+		Channel: channel,
+		Text:    text,
+		SentAt:  h.clock.Now(),
+	}
+	data, _ := json.Marshal(protocol.Message{
+		Type:    protocol.MsgTypeChat,
+		Payload: mustMarshal(msg),
+	})
+
+	recipients := h.roomPeers(client.roomID, "")
+	if channel == protocol.ChatChannelSpectator {
+		recipients = spectatorsOnly(recipients)
+		h.keepSpectatorChat(client.roomID, msg)
+	}
+	h.broadcaster.submit(recipients, data)
+}
+
+// spectatorsOnly 过滤出观战者
+func spectatorsOnly(clients []*Client) []*Client {
+	spectators := make([]*Client, 0, len(clients))
+	for _, c := range clients {
+		if c.spectator {
+			spectators = append(spectators, c)
+		}
+	}
+	return spectators
+}
+
+// keepSpectatorChat 对局进行中保留观战聊天，对局结束后按配置发给玩家
+func (h *Hub) keepSpectatorChat(roomID string, msg protocol.ChatMessageInfo) {
+	if !h.cfg.SpectatorChatAfterMatch || h.session(roomID) == nil {
+		return
+	}
+	h.spectatorChatMu.Lock()
+	defer h.spectatorChatMu.Unlock()
+	kept := append(h.spectatorChat[roomID], msg)
+	if len(kept) > spectatorChatKeep {
+		kept = kept[len(kept)-spectatorChatKeep:]
+	}
+	h.spectatorChat[roomID] = kept
+}
+
+// revealSpectatorChat 对局结束后把本局的观战聊天记录发给房间内的玩家
+func (h *Hub) revealSpectatorChat(roomID string) {
+	h.spectatorChatMu.Lock()
+	messages := h.spectatorChat[roomID]
+	delete(h.spectatorChat, roomID)
+	h.spectatorChatMu.Unlock()
+	if len(messages) == 0 {
+		return
+	}
+
+	players := make([]*Client, 0)
+	for _, c := range h.roomPeers(roomID, "") {
+		if !c.spectator {
+			players = append(players, c)
+		}
+	}
+	data, _ := json.Marshal(protocol.Message{
+		Type:    protocol.MsgTypeChatHistory,
+		Payload: mustMarshal(protocol.ChatHistory{RoomID: roomID, Messages: messages}),
+	})
+	h.broadcaster.submit(players, data)
+}
+
+// clearSpectatorChat 新对局开始时丢弃上一局遗留的观战聊天记录
+func (h *Hub) clearSpectatorChat(roomID string) {
+	h.spectatorChatMu.Lock()
+	defer h.spectatorChatMu.Unlock()
+	delete(h.spectatorChat, roomID)
+}
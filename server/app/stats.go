@@ -0,0 +1,129 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"game/data"
+	"game/protocol"
+	"game/report"
+)
+
+// Version 服务器构建版本，发布构建时通过 -ldflags "-X game/app.Version=..." 注入
+var Version = "dev"
+
+// slowOpsLimit 统计中列出的慢操作数量
+const slowOpsLimit = 10
+
+// meterWindow 消息速率统计的时间窗口（秒）
+const meterWindow = 60
+
+// messageMeter 统计消息总数和最近一分钟的速率，按秒分桶
+type messageMeter struct {
+	total atomic.Int64
+
+	mu      sync.Mutex
+	buckets [meterWindow]int64
+	seconds [meterWindow]int64 // 每个桶对应的 Unix 秒，用于识别过期的桶
+}
+
+// add 记录一条消息
+func (m *messageMeter) add(now time.Time) {
+	m.total.Add(1)
+	sec := now.Unix()
+	i := sec % meterWindow
+	m.mu.Lock()
+	if m.seconds[i] != sec {
+		m.seconds[i], m.buckets[i] = sec, 0
+	}
+	m.buckets[i]++
+	m.mu.Unlock()
+}
+
+// rate 返回最近一分钟的每秒平均消息数
+func (m *messageMeter) rate(now time.Time) float64 {
+	sec := now.Unix()
+	var n int64
+	m.mu.Lock()
+	for i := range m.buckets {
+		if sec-m.seconds[i] < meterWindow {
+			n += m.buckets[i]
+		}
+	}
+	m.mu.Unlock()
+	return float64(n) / meterWindow
+}
+
+// storeFiles 文件持久化时统计大小的数据文件
+var storeFiles = []string{"users.json", "rooms.json", "game_results.jsonl"}
+
+// Stats 汇总服务器实例运行统计
+func (s *Server) Stats() protocol.ServerStats {
+	now := s.hub.clock.Now()
+	stats := protocol.ServerStats{
+		StartedAt: s.startedAt,
+		Uptime:    int(now.Sub(s.startedAt).Seconds()),
+		Version:   Version,
+		Rooms:     make(map[string]int),
+		Messages: protocol.MessageStats{
+			Received:       s.hub.received.total.Load(),
+			Sent:           s.hub.sent.total.Load(),
+			ReceivedPerSec: s.hub.received.rate(now),
+			SentPerSec:     s.hub.sent.rate(now),
+		},
+	}
+	stats.Messages.Types, stats.Messages.TopSenders = s.hub.quotas.stats()
+
+	s.hub.mu.RLock()
+	for c := range s.hub.clients {
+		stats.Connections++
+		if c.spectator {
+			stats.Spectators++
+		}
+	}
+	s.hub.mu.RUnlock()
+
+	rooms := s.roomStore.GetAll()
+	for _, room := range rooms {
+		stats.Rooms[room.Status]++
+	}
+	year, month, day := now.Date()
+	today := s.resultStore.FindSince(time.Date(year, month, day, 0, 0, 0, 0, now.Location()))
+	stats.MatchesToday = len(today)
+	stats.Abandons = serverAbandons(today)
+
+	stats.Stores = protocol.StoreStats{
+		Users:    len(s.userStore.GetAll()),
+		Rooms:    len(rooms),
+		Results:  len(s.resultStore.GetAll()),
+		Sessions: s.logins.Count(),
+	}
+	if !s.cfg.InMemory() {
+		stats.Stores.Files = make(map[string]int64)
+		for _, name := range storeFiles {
+			if info, err := os.Stat(filepath.Join(data.DataDir, name)); err == nil {
+				stats.Stores.Files[name] = info.Size()
+			}
+		}
+	}
+	for _, op := range report.SlowOps(slowOpsLimit) {
+		stats.SlowOps = append(stats.SlowOps, protocol.SlowOpInfo{
+			Kind:    op.Kind,
+			Name:    op.Name,
+			Count:   op.Count,
+			MaxMs:   durationMs(op.Max),
+			AvgMs:   durationMs(op.Total / time.Duration(op.Count)),
+			LastAt:  op.Last,
+			Context: op.Tags,
+		})
+	}
+	return stats
+}
+
+// durationMs 将时长转换为毫秒
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
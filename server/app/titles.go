@@ -0,0 +1,43 @@
+package app
+
+import (
+	"log"
+
+	"game/models"
+)
+
+// activeTitles 返回玩家当前装备的称号，按用户名索引，机器人和未装备称号的玩家不出现；
+// 未启用称号服务时返回 nil
+func (h *Hub) activeTitles(players []string) map[string]string {
+	if h.titles == nil {
+		return nil
+	}
+	var titles map[string]string
+	for _, player := range players {
+		if models.IsBot(player) {
+			continue
+		}
+		if title := h.titles.Active(player); title != "" {
+			if titles == nil {
+				titles = make(map[string]string)
+			}
+			titles[player] = title
+		}
+	}
+	return titles
+}
+
+// awardTitles 对局结果写入后检查玩家新达成的成就，授予对应的称号
+func (h *Hub) awardTitles(players []models.PlayerResult) {
+	if h.titles == nil {
+		return
+	}
+	for _, p := range players {
+		if models.IsBot(p.Username) {
+			continue
+		}
+		for _, t := range h.titles.AwardAchievements(p.Username) {
+			log.Printf("用户 %s 获得称号 %s", p.Username, t.Key())
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"game/data"
+	"game/models"
+	"game/protocol"
+	"game/service"
+)
+
+// Rating 返回玩家当前的匹配评分，实现 service.RatingSource，用于赛事种子
+func (h *Hub) Rating(username string) int {
+	return h.playerRating(username)
+}
+
+// PublishBracket 把对阵表推送给本实例上在线的报名玩家和关注该赛事的连接，实现 service.BracketPublisher；
+// 不在线的玩家通过 /tournaments/:id 查询
+func (h *Hub) PublishBracket(t models.Tournament) {
+	targets := make(map[*Client]bool)
+	for _, e := range t.Entrants {
+		for _, c := range h.userClients(e.Username) {
+			targets[c] = true
+		}
+	}
+	h.followersMu.Lock()
+	for c, id := range h.followers {
+		if id == t.ID {
+			targets[c] = true
+		}
+	}
+	h.followersMu.Unlock()
+
+	info := service.TournamentInfo(t)
+	notices := make([]matchNotice, 0, len(targets))
+	for c := range targets {
+		notices = append(notices, matchNotice{client: c, msgType: protocol.MsgTypeBracket, payload: info})
+	}
+	h.sendNotices(notices)
+}
+
+// OpenTournamentMatch 为赛事比赛创建房间并把双方拉入，实现 service.TournamentHost。
+// 双方都必须在本实例在线且不在其他房间中，留在已决出胜负的赛事比赛房间中的玩家随之离开该房间；
+// 排队匹配、练习和观战随之结束
+func (h *Hub) OpenTournamentMatch(t models.Tournament, m models.BracketMatch) (string, error) {
+	players := []string{m.Player1, m.Player2}
+	clients := make([]*Client, 0, len(players))
+	for _, player := range players {
+		c, ok := h.tournamentClient(player)
+		if c == nil {
+			return "", fmt.Errorf("玩家 %s 不在线", player)
+		}
+		if !ok {
+			return "", fmt.Errorf("玩家 %s 正在其他房间中", player)
+		}
+		clients = append(clients, c)
+	}
+	for _, c := range clients {
+		h.leaveQueue(c.username, "有玩家进入了赛事比赛")
+		h.stopPractice(c, false)
+		h.stopSpectate(c)
+	}
+	err := data.RunTransaction(h.userStore, h.roomStore, func(tx *data.Txn) error {
+		for _, c := range clients {
+			if c.roomID != "" {
+				service.RemoveMember(tx, c.roomID, c.username)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("离开上一场比赛的房间失败: %v", err)
+	}
+	for _, c := range clients {
+		c.roomID = ""
+	}
+
+	room := models.Room{
+		ID:         h.newID("room"),
+		Name:       t.Name + " " + m.ID,
+		HostID:     players[0],
+		Players:    players,
+		MaxPlayers: len(players),
+		Status:     "ready",
+		CreatedAt:  h.clock.Now(),
+		Map:        models.DefaultMap,
+		Rules:      models.DefaultRules(),
+		Tournament: t.ID,
+		MatchID:    m.ID,
+	}
+	if err := h.openRoom(room, clients, "赛事比赛已开始"); err != nil {
+		return "", err
+	}
+	return room.ID, nil
+}
+
+// tournamentClient 返回玩家在本实例上的连接，不在线时为 nil；ok 表示可以开始赛事比赛：
+// 不在房间中、只在观战，或者留在已决出胜负的赛事比赛房间中
+func (h *Hub) tournamentClient(username string) (c *Client, ok bool) {
+	conns := h.userClients(username)
+	if len(conns) == 0 {
+		return nil, false
+	}
+	c = conns[0]
+	if c.roomID == "" || c.spectator {
+		return c, true
+	}
+	room := h.roomStore.GetByID(c.roomID)
+	if room == nil || room.Tournament == "" || room.Status == "playing" {
+		return c, false
+	}
+	t, err := h.tournaments.Get(room.Tournament)
+	if err != nil {
+		return c, true
+	}
+	i := t.Match(room.MatchID)
+	return c, i < 0 || t.Matches[i].Winner != ""
+}
+
+// MatchReady 玩家在线且可以开始赛事比赛，实现 service.TournamentHost
+func (h *Hub) MatchReady(username string) bool {
+	_, ok := h.tournamentClient(username)
+	return ok
+}
+
+// RoomOpen 房间是否仍然存在，实现 service.TournamentHost
+func (h *Hub) RoomOpen(roomID string) bool {
+	return h.roomStore.GetByID(roomID) != nil
+}
+
+// NotifyMatch 通知在线的双方比赛即将开始，实现 service.TournamentHost
+func (h *Hub) NotifyMatch(t models.Tournament, m models.BracketMatch) {
+	notice := protocol.MatchNoticeInfo{TournamentID: t.ID, TournamentName: t.Name, Match: service.BracketMatchInfo(m)}
+	var notices []matchNotice
+	for _, player := range []string{m.Player1, m.Player2} {
+		for _, c := range h.userClients(player) {
+			notices = append(notices, matchNotice{client: c, msgType: protocol.MsgTypeMatchNotice, payload: notice})
+		}
+	}
+	h.sendNotices(notices)
+}
+
+// tournamentScheduler 定期推进启用自动赛程的赛事
+func (h *Hub) tournamentScheduler() {
+	ticker := h.clock.NewTicker(h.cfg.TournamentScheduleInterval)
+	defer ticker.Stop()
+	for now := range ticker.C() {
+		h.tournaments.RunSchedule(now, h.cfg.TournamentNoticeLead)
+	}
+}
+
+// reportTournamentResult 赛事比赛的房间结束对局后把结果计入对阵表
+func (h *Hub) reportTournamentResult(result models.GameResult) {
+	if h.tournaments == nil {
+		return
+	}
+	room := h.roomStore.GetByID(result.RoomID)
+	if room == nil || room.Tournament == "" {
+		return
+	}
+	if _, err := h.tournaments.ReportResult(room.Tournament, room.MatchID, room.ID, result.Winner); err != nil {
+		log.Printf("记录赛事 %s 比赛 %s 的结果失败: %v", room.Tournament, room.MatchID, err)
+	}
+}
+
+// followTournament 关注赛事并立即收到当前对阵表，之后对阵表的每次变化都会推送；赛事ID为空时取消关注
+func (h *Hub) followTournament(client *Client, req protocol.FollowTournamentRequest) {
+	if req.TournamentID == "" {
+		h.unfollowTournament(client)
+		return
+	}
+	t, err := h.tournaments.Get(req.TournamentID)
+	if err != nil {
+		h.sendError(client, http.StatusNotFound, err.Error())
+		return
+	}
+	h.followersMu.Lock()
+	h.followers[client] = t.ID
+	h.followersMu.Unlock()
+	h.sendNotices([]matchNotice{{client: client, msgType: protocol.MsgTypeBracket, payload: service.TournamentInfo(t)}})
+}
+
+// unfollowTournament 取消关注赛事，连接断开时调用
+func (h *Hub) unfollowTournament(client *Client) {
+	h.followersMu.Lock()
+	delete(h.followers, client)
+	h.followersMu.Unlock()
+}
+
+// listLiveMatches 返回所有正在进行的赛事比赛及其观战人数，玩家发送 spectate 携带比赛的房间ID即可观战
+func (h *Hub) listLiveMatches(client *Client) {
+	live := h.tournaments.LiveMatches()
+	resp := protocol.LiveMatchListResponse{Matches: make([]protocol.LiveMatchInfo, 0, len(live))}
+	for _, l := range live {
+		spectators := 0
+		for _, c := range h.roomPeers(l.Match.RoomID, "") {
+			if c.spectator {
+				spectators++
+			}
+		}
+		resp.Matches = append(resp.Matches, protocol.LiveMatchInfo{
+			TournamentID:   l.TournamentID,
+			TournamentName: l.TournamentName,
+			Match:          service.BracketMatchInfo(l.Match),
+			Spectators:     spectators,
+		})
+	}
+	data, _ := json.Marshal(protocol.Message{
+		Type:    protocol.MsgTypeLiveMatches,
+		Payload: mustMarshal(resp),
+	})
+	client.send <- data
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"game/data"
+	"game/models"
+	"game/protocol"
+	"game/service"
+)
+
+// minVoteKickPlayers 允许投票踢人的最少在场真人玩家数，机器人不计入。
+// 只有两名真人时唯一的投票者就是对手，踢出即直接获胜，因此不允许
+const minVoteKickPlayers = 3
+
+// kickVote 针对一名玩家进行中的投票
+type kickVote struct {
+	voters  map[string]bool
+	expires time.Time
+}
+
+// voteKick 处理玩家的踢人投票：目标必须是仍在场的其他玩家，投票者是除目标外仍在场的真人玩家。
+// 窗口内过半数投票者同意时目标被踢出对局，按弃赛处理并计入违规供审核，返回 true 表示对局已结束
+func (s *roomSession) voteKick(ev sessionEvent) bool {
+	var req protocol.VoteKickRequest
+	if !s.decode(ev, &req) {
+		return false
+	}
+	voter, target := ev.client.username, req.Username
+	reason := ""
+	switch {
+	case s.hub.cfg.VoteKickWindow <= 0:
+		reason = "服务器未开启投票踢人"
+	case s.humansInPlay() < minVoteKickPlayers:
+		reason = "三名以上真人玩家在场时才能投票踢人"
+	case s.stats[voter] == nil || s.out[voter]:
+		reason = "只有在场的玩家可以投票"
+	case voter == target:
+		reason = "不能投票踢出自己"
+	case s.stats[target] == nil || s.out[target]:
+		reason = "对方不在对局中"
+	case models.IsBot(target):
+		reason = "不能投票踢出机器人"
+	}
+	if reason != "" {
+		s.hub.sendError(ev.client, http.StatusBadRequest, reason)
+		return false
+	}
+
+	now := s.hub.clock.Now()
+	if s.kickVotes == nil {
+		s.kickVotes = make(map[string]*kickVote)
+	}
+	vote := s.kickVotes[target]
+	if vote == nil || !now.Before(vote.expires) {
+		vote = &kickVote{voters: make(map[string]bool), expires: now.Add(s.hub.cfg.VoteKickWindow)}
+		s.kickVotes[target] = vote
+	}
+	vote.voters[voter] = true
+
+	// 已出局的投票者不再计票
+	eligible, votes := 0, 0
+	for _, p := range s.players {
+		if p == target || s.out[p] || models.IsBot(p) {
+			continue
+		}
+		eligible++
+		if vote.voters[p] {
+			votes++
+		}
+	}
+	status := protocol.VoteKickStatus{
+		Target:    target,
+		Votes:     votes,
+		Needed:    eligible/2 + 1,
+		ExpiresAt: vote.expires,
+	}
+	if votes < status.Needed {
+		s.broadcast(protocol.MsgTypeVoteKickStatus, status)
+		return false
+	}
+
+	delete(s.kickVotes, target)
+	status.Kicked = true
+	s.broadcast(protocol.MsgTypeVoteKickStatus, status)
+	log.Printf("房间 %s 玩家 %s 被 %d/%d 名玩家投票踢出对局", s.roomID, target, votes, eligible)
+	s.stats[target].VoteKicked = true
+	s.violation(target, models.ViolationVoteKicked)
+	return s.forfeit(target, models.ResultReasonVoteKick)
+}
+
+// humansInPlay 返回仍在场的真人玩家数
+func (s *roomSession) humansInPlay() int {
+	n := 0
+	for _, p := range s.players {
+		if !s.out[p] && !models.IsBot(p) {
+			n++
+		}
+	}
+	return n
+}
+
+// removeVoteKicked 对局结束后把被投票踢出的玩家移出房间并通知其回到大厅
+func (h *Hub) removeVoteKicked(roomID string, players []models.PlayerResult) {
+	for _, p := range players {
+		if !p.VoteKicked {
+			continue
+		}
+		removed := false
+		err := data.RunTransaction(h.userStore, h.roomStore, func(tx *data.Txn) error {
+			_, removed = service.RemoveMember(tx, roomID, p.Username)
+			return nil
+		})
+		if err != nil || !removed {
+			continue
+		}
+		notices := make([]matchNotice, 0, 1)
+		for _, c := range h.userClients(p.Username) {
+			if c.roomID != roomID || c.spectator {
+				continue
+			}
+			c.roomID = ""
+			notices = append(notices, matchNotice{client: c, msgType: protocol.MsgTypeKicked, payload: protocol.KickedNotice{RoomID: roomID}})
+		}
+		h.sendNotices(notices)
+	}
+}
@@ -2,16 +2,27 @@ package app
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"game/api"
+	"game/cluster"
+	"game/config"
 	"game/crypto"
 	"game/data"
+	"game/game"
 	"game/models"
 	"game/protocol"
+	"game/report"
+	"game/service"
+	"game/sim"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -34,33 +45,125 @@ type Client struct {
 	username string
 	roomID   string
 	lastPing time.Time
+
+	lastActive time.Time // 最近一次收到非心跳消息的时间
+	idleWarned bool      // 是否已发送空闲警告
+	version    string    // 客户端版本，连接时通过 version 参数上报
+	sessionID  string    // 建立连接时绑定的登录会话，会话被远程注销时断开
+	spectator  bool      // 是否以观战者身份在 roomID 房间中
+	region     string    // 客户端所在区域，创建房间和匹配时使用
+	connID     string    // 连接ID，与消息序号组成消息ID
+	msgSeq     atomic.Uint64
+}
+
+// messageID 返回客户端当前正在处理的消息ID，附加到日志、错误响应和流量录制中
+func (c *Client) messageID() string {
+	return fmt.Sprintf("%s-%d", c.connID, c.msgSeq.Load())
+}
+
+// logf 输出带当前消息ID的日志
+func (c *Client) logf(format string, args ...any) {
+	log.Printf("[%s] "+format, append([]any{c.messageID()}, args...)...)
 }
 
 // Hub 定义 WebSocket 中心结构，这里就是WS服务端
 type Hub struct {
-	clients      map[*Client]bool // 这里存储所有活跃的客户端
-	broadcast    chan []byte
-	register     chan *Client
-	unregister   chan *Client
-	userStore    *data.UserStore
-	roomStore    *data.RoomStore
-	resultStore  *data.ResultStore
-	mu           sync.RWMutex
-	heartbeatMap map[string]time.Time
+	clients        map[*Client]bool // 这里存储所有活跃的客户端
+	broadcast      chan []byte
+	register       chan *Client
+	unregister     chan *Client
+	userStore      *data.UserStore
+	roomStore      *data.RoomStore
+	resultStore    *data.ResultStore
+	mu             sync.RWMutex
+	heartbeatMap   map[string]time.Time
+	broadcaster    *broadcastPool
+	cfg            *config.Config
+	rooms          service.RoomService  // 创建、加入房间和开始游戏与 HTTP 接口共用的房间业务逻辑
+	balance        *data.BalanceStore   // 当前的英雄、武器和护甲平衡版本，对局开局时取用
+	bots           *data.BotRoster      // 机器人难度配置，可热更新
+	sessions       *game.SessionManager // 进行中的对局，按房间ID索引
+	clock          sim.Clock            // 心跳、空闲和对局计时使用的时间来源
+	seeder         *sim.Seeder          // 为每局对局派生随机数源
+	chaos          *chaosInjector
+	moderation     service.ModerationService
+	titles         service.TitleService
+	practiceScores service.PracticeService
+	tournaments    service.TournamentService
+	ladder         service.LadderService
+	events         service.EventService
+	playlist       service.PlaylistService
+	disputes       service.DisputeService
+	recorder       *trafficRecorder       // 诊断用的入站流量录制，未开启时为 nil
+	spectatorDelay *spectatorDelay        // 观战延迟缓冲，未开启时为 nil
+	roomKeys       *roomKeyring           // 对局中的房间会话密钥，未开启时为 nil
+	matcher        *matchmaker            // 匹配队列
+	regions        *service.Regions       // 可用区域
+	words          *service.WordFilter    // 房间名和聊天消息的屏蔽词过滤
+	cluster        *cluster.Registry      // 跨实例注册表，单实例运行时为 nil
+	received       *messageMeter          // 收到的客户端消息
+	sent           *messageMeter          // 发给客户端的消息
+	quotas         *messageQuotas         // 按用户和类型统计入站消息并限制发送频率
+	analytics      *data.AnalyticsStore   // 登录和在线人数统计
+	logins         *data.SessionStore     // 登录会话，用户离线时全部结束
+	invites        *data.InviteStore      // 等待回应的房间邀请
+	challenges     *data.ChallengeStore   // 等待回应的天梯挑战
+	presence       *lobbyPresence         // 大厅在线名单及其订阅者
+	drops          map[string]pendingDrop // 断线后等待重新加入房间的成员，按用户名索引
+	conns          *service.ConnLimiter   // 并发连接数上限，连接建立前占用名额，客户端移出 clients 时归还
+	ids            atomic.Uint64          // newID 使用的递增序号
+	dropsMu        sync.Mutex
+
+	spectatorChat   map[string][]protocol.ChatMessageInfo // 进行中对局的观战聊天记录，按房间ID索引
+	spectatorChatMu sync.Mutex
+
+	followers   map[*Client]string // 关注赛事对阵表的连接及其关注的赛事ID
+	followersMu sync.Mutex
 }
 
 // newHub 创建 Hub 实例
-func newHub(userStore *data.UserStore, roomStore *data.RoomStore, resultStore *data.ResultStore) *Hub {
-	return &Hub{
+func newHub(cfg *config.Config, userStore *data.UserStore, roomStore *data.RoomStore, resultStore *data.ResultStore, logins *data.SessionStore, invites *data.InviteStore, balance *data.BalanceStore, rooms service.RoomService, regions *service.Regions, words *service.WordFilter, registry *cluster.Registry) *Hub {
+	h := &Hub{
 		clients:      make(map[*Client]bool),
+		conns:        service.NewConnLimiter(cfg.MaxConnections),
 		broadcast:    make(chan []byte, 256),
 		register:     make(chan *Client),
 		unregister:   make(chan *Client),
 		userStore:    userStore,
 		roomStore:    roomStore,
 		resultStore:  resultStore,
+		logins:       logins,
+		invites:      invites,
+		presence:     newLobbyPresence(),
+		drops:        make(map[string]pendingDrop),
 		heartbeatMap: make(map[string]time.Time),
+		cfg:          cfg,
+		rooms:        rooms,
+		balance:      balance,
+		regions:      regions,
+		words:        words,
+		cluster:      registry,
+		received:     &messageMeter{},
+		sent:         &messageMeter{},
+		quotas:       newMessageQuotas(cfg.MessageQuotas),
+		sessions:     game.NewSessionManager(),
+		clock:        cfg.Clock,
+		seeder:       sim.NewSeeder(cfg.Seed),
+
+		spectatorChat: make(map[string][]protocol.ChatMessageInfo),
+		followers:     make(map[*Client]string),
+	}
+	if h.clock == nil {
+		h.clock = sim.RealClock{}
 	}
+	h.chaos = newChaosInjector(cfg, h.seeder.New())
+	h.recorder = newTrafficRecorder(cfg.RecordFile)
+	h.broadcaster = newBroadcastPool(h, broadcastWorkers, broadcastQueueSize)
+	h.spectatorDelay = newSpectatorDelay(h, cfg.SpectatorDelay)
+	h.roomKeys = newRoomKeyring(cfg.RoomKeys)
+	h.matcher = newMatchmaker()
+	h.watchStores()
+	return h
 }
 
 // run 运行 Hub
@@ -70,26 +173,58 @@ func (h *Hub) run() {
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
-			h.heartbeatMap[client.username] = time.Now()
+			h.heartbeatMap[client.username] = h.clock.Now()
 			h.mu.Unlock()
+			h.recordEvent(client, models.TrafficConnect, nil)
+			if !h.cluster.ClaimPresence(client.username) {
+				log.Printf("用户 %s 同时连接到了其他实例", client.username)
+			}
+
+			// 对局中掉线的玩家在宽限期内重连，恢复暂停的对局
+			if client.roomID != "" {
+				h.sendRoomKey(client)
+				h.dispatchRejoin(client)
+			}
+			h.pushPendingInvites(client)
+			h.pushPendingChallenges(client)
+			h.pushActiveEvents(client)
+			h.updateLobbyPresence(client.username)
 
 		case client := <-h.unregister:
+			// 在加锁前查询游戏会话，保持对局管理器的锁先于 h.mu 的加锁顺序
+			keep := h.keepForRejoin(client)
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
+			_, removed := h.clients[client]
+			if removed {
 				delete(h.clients, client)
 				delete(h.heartbeatMap, client.username)
 				close(client.send)
+				h.conns.Release()
 
-				// 更新用户状态：离线，清除房间ID
-				user := h.userStore.FindByUsername(client.username)
-				if user != nil && user.Online {
-					user.Online = false
-					user.RoomID = ""
-					h.userStore.Update(client.username, *user)
+				// 更新用户状态：离线，清除房间ID；对局中的玩家保留在线状态和房间，等待重连
+				if keep {
+					log.Printf("用户 %s 在对局中断开连接，等待重连", client.username)
+				} else if h.markOffline(client.username) {
 					log.Printf("用户 %s 断开连接，已更新状态为离线", client.username)
 				}
 			}
 			h.mu.Unlock()
+			if removed {
+				h.recordEvent(client, models.TrafficDisconnect, nil)
+				h.cluster.ReleasePresence(client.username)
+				h.leaveQueue(client.username, "有玩家断开了连接")
+				h.stopPractice(client, true)
+				h.unfollowTournament(client)
+				h.quotas.forget(client.username)
+				h.presence.forget(client)
+				h.updateLobbyPresence(client.username)
+			}
+
+			// 对局中断线的玩家交给游戏会话暂停等待或判负；房间内的其他成员收到断线通知
+			if removed && client.roomID != "" && !client.spectator {
+				h.dispatchLeave(client)
+				h.playerDisconnected(client)
+			}
 
 		case message := <-h.broadcast:
 			h.mu.RLock()
@@ -100,13 +235,10 @@ func (h *Hub) run() {
 					close(client.send)
 					delete(h.clients, client)
 					delete(h.heartbeatMap, client.username)
+					h.conns.Release()
 
 					// 更新用户状态：离线，清除房间ID
-					user := h.userStore.FindByUsername(client.username)
-					if user != nil && user.Online {
-						user.Online = false
-						user.RoomID = ""
-						h.userStore.Update(client.username, *user)
+					if h.markOffline(client.username) {
 						log.Printf("用户 %s 连接超时，已更新状态为离线", client.username)
 					}
 				}
@@ -116,25 +248,33 @@ func (h *Hub) run() {
 	}
 }
 
-// heartbeatCheck 检查心跳
+// heartbeatCheck 检查心跳。超时用户的连接被关闭，由注销流程标记离线并通知所在的房间；
+// 同时把断线超过宽限期的房间成员移出房间
 func (h *Hub) heartbeatCheck() {
-	for {
-		time.Sleep(1 * time.Second)
+	ticker := h.clock.NewTicker(time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C() {
 		h.mu.Lock()
-		now := time.Now()
 		for username, lastPing := range h.heartbeatMap {
-			if now.Sub(lastPing) > 10*time.Second {
-				user := h.userStore.FindByUsername(username)
-				if user != nil && user.Online {
-					user.Online = false
-					user.RoomID = ""
-					h.userStore.Update(username, *user)
-					log.Printf("用户 %s 心跳超时，已自动下线", username)
+			if now.Sub(lastPing) <= 10*time.Second {
+				continue
+			}
+			delete(h.heartbeatMap, username)
+			closed := false
+			for c := range h.clients {
+				if c.username == username {
+					c.conn.Close()
+					closed = true
 				}
-				delete(h.heartbeatMap, username)
+			}
+			if closed {
+				log.Printf("用户 %s 心跳超时，已断开连接", username)
+			} else if h.markOffline(username) {
+				log.Printf("用户 %s 心跳超时，已自动下线", username)
 			}
 		}
 		h.mu.Unlock()
+		h.dropDisconnected(now)
 	}
 }
 
@@ -161,6 +301,134 @@ func (h *Hub) HasActiveConnection(username string) bool {
 	return false
 }
 
+// closeAll 关闭所有 WebSocket 连接，readPump 退出后按正常断线流程注销
+func (h *Hub) closeAll() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		client.conn.Close()
+	}
+}
+
+// ConnectionCount 返回当前活跃的 WebSocket 连接数
+func (h *Hub) ConnectionCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// newID 生成带前缀的房间、结果、邀请等ID：Hub 时钟的时间加上进程内递增的序号。
+// 模拟时钟不前进时序号保证ID不重复，固定种子重放时生成的ID序列相同
+func (h *Hub) newID(prefix string) string {
+	return fmt.Sprintf("%s_%d_%d", prefix, h.clock.Now().UnixNano(), h.ids.Add(1))
+}
+
+// DisconnectUser 通知并断开指定用户的所有连接
+func (h *Hub) DisconnectUser(username string, reason string) {
+	h.disconnect(func(c *Client) bool { return c.username == username }, reason)
+}
+
+// DisconnectSession 通知并断开使用指定登录会话建立的连接
+func (h *Hub) DisconnectSession(sessionID string, reason string) {
+	h.disconnect(func(c *Client) bool { return c.sessionID == sessionID }, reason)
+}
+
+// DisconnectOthers 通知并断开用户除 keepSessionID 会话之外的所有连接，包括未绑定会话的连接
+func (h *Hub) DisconnectOthers(username, keepSessionID string, reason string) {
+	h.disconnect(func(c *Client) bool {
+		return c.username == username && (keepSessionID == "" || c.sessionID != keepSessionID)
+	}, reason)
+}
+
+// EraseUser 删除或匿名化注销用户在邀请、挑战、天梯、赛事、练习、作弊标记、申诉和流量录制中的数据，
+// 实现 service.UserDataEraser
+func (h *Hub) EraseUser(userID string, names []string, alias string) {
+	h.invites.RemoveUser(userID)
+	h.challenges.RemoveUser(userID)
+	h.ladder.RemoveUser(userID)
+	h.tournaments.RemoveUser(userID, alias)
+	h.practiceScores.RemoveUser(userID)
+	h.moderation.RemoveUser(userID)
+	h.disputes.RemoveUser(userID, names, alias)
+	if _, err := h.recorder.removeUsers(names); err != nil {
+		log.Printf("从流量录制中删除用户 %s 的记录失败: %v", userID, err)
+	}
+}
+
+// ExportUser 补充用户的房间邀请、天梯排名、报名过的赛事、提出的申诉和录制中的聊天消息，
+// 实现 service.UserDataExporter。申诉附带的回放和违规记录涉及其他玩家，不导出
+func (h *Hub) ExportUser(userID string, names []string, data *service.UserData) {
+	data.Invites = h.invites.ByUser(userID)
+	data.LadderPosition = h.ladder.Position(userID)
+	data.Tournaments = make([]models.Tournament, 0)
+	for _, t := range h.tournaments.List() {
+		if t.Registered(userID) {
+			data.Tournaments = append(data.Tournaments, t)
+		}
+	}
+	data.Disputes = h.disputes.ByUser(userID)
+	for i := range data.Disputes {
+		data.Disputes[i].Replay, data.Disputes[i].Flags = nil, nil
+	}
+	data.Chat = h.recorder.userChat(names)
+}
+
+// disconnect 向匹配的连接发送下线通知后关闭连接
+func (h *Hub) disconnect(match func(c *Client) bool, reason string) {
+	msg := protocol.Message{
+		Type:    protocol.MsgTypeLoggedOut,
+		Payload: mustMarshal(protocol.LoggedOutNotice{Reason: reason}),
+	}
+	data, _ := json.Marshal(msg)
+
+	h.mu.RLock()
+	targets := make([]*Client, 0)
+	for c := range h.clients {
+		if match(c) {
+			targets = append(targets, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range targets {
+		h.deliver(c, data)
+		// 留出时间让下线通知写出后再关闭连接
+		time.AfterFunc(time.Second, func() { c.conn.Close() })
+	}
+}
+
+// sendError 向客户端发送结构化错误消息
+func (h *Hub) sendError(client *Client, code int, message string) {
+	h.sendErrorResponse(client, protocol.ErrorResponse{Code: code, Message: message})
+}
+
+// sendDecodeError 通知客户端消息格式错误，附带出错的字段；消息超过大小上限时错误码为 413
+func (h *Hub) sendDecodeError(client *Client, err error) {
+	var decodeErr *protocol.DecodeError
+	if !errors.As(err, &decodeErr) {
+		decodeErr = &protocol.DecodeError{Reason: err.Error()}
+	}
+	code := http.StatusBadRequest
+	if decodeErr.TooLarge {
+		code = http.StatusRequestEntityTooLarge
+	}
+	h.sendErrorResponse(client, protocol.ErrorResponse{
+		Code:    code,
+		Message: "消息格式错误: " + decodeErr.Reason,
+		Field:   decodeErr.Field,
+	})
+}
+
+// sendErrorResponse 发送错误消息，附加客户端当前的消息ID
+func (h *Hub) sendErrorResponse(client *Client, resp protocol.ErrorResponse) {
+	resp.RequestID = client.messageID()
+	data, _ := json.Marshal(protocol.Message{
+		Type:    protocol.MsgTypeError,
+		Payload: mustMarshal(resp),
+	})
+	client.send <- data
+}
+
 // readPump 读取消息
 func (c *Client) readPump() {
 	defer func() {
@@ -170,11 +438,30 @@ func (c *Client) readPump() {
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.conn.SetPongHandler(func(string) error {
 		c.hub.mu.Lock()
-		c.hub.heartbeatMap[c.username] = time.Now()
+		c.hub.heartbeatMap[c.username] = c.hub.clock.Now()
 		c.hub.mu.Unlock()
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 		return nil
 	})
+
+	handle := func(message []byte) { c.hub.safeHandleMessage(c, message) }
+	if c.hub.chaos.enabledFor(c.username) {
+		// 收到的消息经混沌注入后由单独的协程按序处理，注销前等待延迟中的消息处理完
+		in := make(chan []byte, 256)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for message := range c.hub.chaos.pipe(in, nil) {
+				c.hub.safeHandleMessage(c, message)
+			}
+		}()
+		defer func() {
+			close(in)
+			<-done
+		}()
+		handle = func(message []byte) { in <- message }
+	}
+
 	for {
 		// 调用websocketAPI 读取消息
 		_, message, err := c.conn.ReadMessage()
@@ -183,35 +470,71 @@ func (c *Client) readPump() {
 		}
 
 		// 解密消息
+		c.msgSeq.Add(1)
 		decryptedMsg, err := crypto.Decrypt(string(message))
 		if err != nil {
-			log.Printf("解密消息失败: %v", err)
+			c.logf("解密消息失败: %v", err)
+			continue
+		}
+
+		if limit := c.hub.cfg.MaxBodyBytes; limit > 0 && len(decryptedMsg) > limit {
+			c.hub.sendDecodeError(c, &protocol.DecodeError{Reason: fmt.Sprintf("内容超过 %d 字节", limit), TooLarge: true})
 			continue
 		}
 
-		c.hub.handleMessage(c, []byte(decryptedMsg))
+		c.hub.received.add(c.hub.clock.Now())
+		c.hub.recordEvent(c, models.TrafficMessage, []byte(decryptedMsg))
+		handle([]byte(decryptedMsg))
 	}
 }
 
+// safeHandleMessage 处理消息并捕获 panic，上报错误后通知客户端而不是断开连接
+func (h *Hub) safeHandleMessage(client *Client, message []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			report.Panic(r, debug.Stack(), map[string]string{
+				"username":   client.username,
+				"room_id":    client.roomID,
+				"message_id": client.messageID(),
+			})
+			h.sendError(client, http.StatusInternalServerError, "服务器内部错误")
+		}
+	}()
+	h.handleMessage(client, message)
+}
+
 // writePump 写入消息
 func (c *Client) writePump() {
-	ticker := time.NewTicker(2 * time.Second) // 心跳间隔，默认每2秒发送一次心跳
+	ticker := c.hub.clock.NewTicker(2 * time.Second) // 心跳间隔，默认每2秒发送一次心跳
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
 		// 连接关闭时，通知Hub注销客户端
 		c.hub.unregister <- c
 	}()
+
+	var send <-chan []byte = c.send
+	if c.hub.chaos.enabledFor(c.username) {
+		stop := make(chan struct{})
+		defer close(stop)
+		send = c.hub.chaos.pipe(c.send, stop)
+	}
+
 	for {
 		select {
-		case message, ok := <-c.send:
+		case message, ok := <-send:
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
-			// 对称加密消息
+			// 已用房间密钥加密的对局帧直接写出，其余消息按连接对称加密
+			if len(message) > 0 && message[0] == sealedFrameTag {
+				c.conn.WriteMessage(websocket.TextMessage, message[1:])
+				c.hub.sent.add(c.hub.clock.Now())
+				continue
+			}
 			encryptedMsg, err := crypto.Encrypt(string(message))
 			if err != nil {
 				log.Printf("加密消息失败: %v", err)
@@ -219,8 +542,9 @@ func (c *Client) writePump() {
 			}
 
 			c.conn.WriteMessage(websocket.TextMessage, []byte(encryptedMsg))
+			c.hub.sent.add(c.hub.clock.Now())
 
-		case <-ticker.C:
+		case <-ticker.C():
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
@@ -232,318 +556,475 @@ func (c *Client) writePump() {
 // handleMessage 处理消息
 func (h *Hub) handleMessage(client *Client, message []byte) {
 	var msg protocol.Message
-	if err := json.Unmarshal(message, &msg); err != nil {
+	if err := protocol.DecodeBytes(message, &msg); err != nil {
+		h.sendDecodeError(client, err)
+		return
+	}
+
+	defer report.Track(report.SlowMessage, string(msg.Type), time.Now(), map[string]string{
+		"username":   client.username,
+		"room_id":    client.roomID,
+		"message_id": client.messageID(),
+	})
+
+	if !h.quotas.allow(client.username, msg.Type, h.clock.Now()) {
+		h.sendError(client, http.StatusTooManyRequests, fmt.Sprintf("%s 消息发送过于频繁，请稍后再试", msg.Type))
 		return
 	}
 
+	if msg.Type != protocol.MsgTypeHeartbeat {
+		h.touch(client)
+	}
+
+	if client.spectator && spectatorBlocked(msg.Type) {
+		h.sendError(client, http.StatusForbidden, "观战中无法进行该操作")
+		return
+	}
+
+	// 进入房间、匹配或观战前先结束进行中的练习
+	if leavesPractice(msg.Type) {
+		h.stopPractice(client, false)
+	}
+
 	switch msg.Type {
 	case protocol.MsgTypeHeartbeat:
 		h.mu.Lock()
-		h.heartbeatMap[client.username] = time.Now()
+		h.heartbeatMap[client.username] = h.clock.Now()
 		h.mu.Unlock()
 		reply := protocol.Message{Type: protocol.MsgTypeHeartbeatReply}
 		data, _ := json.Marshal(reply)
 		client.send <- data
 
-	case protocol.MsgTypePlayerAction:
-		var action protocol.PlayerAction
-		json.Unmarshal(msg.Payload, &action)
-		h.broadcastGameAction(client, msg)
+	// 对局内消息交给房间独立的游戏会话协程处理
+	case protocol.MsgTypePlayerAction, protocol.MsgTypeFire, protocol.MsgTypeHit,
+		protocol.MsgTypeDeath, protocol.MsgTypeGameOver, protocol.MsgTypeBuy, protocol.MsgTypeVoteKick,
+		protocol.MsgTypeMatchVote:
+		h.dispatchToSession(client, msg)
+
+	case protocol.MsgTypeStartGame:
+		h.startGame(client) // 对房主所在的客户端启动游戏
+
+	case protocol.MsgTypeAddBot:
+		var req protocol.AddBotRequest
+		if len(msg.Payload) > 0 {
+			if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+				h.sendDecodeError(client, err)
+				break
+			}
+		}
+		h.addBot(client, req)
 
-	case protocol.MsgTypeFire:
-		var fire protocol.FireAction
-		json.Unmarshal(msg.Payload, &fire)
-		h.broadcastGameAction(client, msg)
+	case protocol.MsgTypeStartPractice:
+		var req protocol.StartPracticeRequest
+		if len(msg.Payload) > 0 {
+			if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+				h.sendDecodeError(client, err)
+				break
+			}
+		}
+		h.startPractice(client, req)
 
-	case protocol.MsgTypeHit:
-		var hit protocol.HitAction
-		json.Unmarshal(msg.Payload, &hit)
-		h.broadcastGameAction(client, msg)
+	case protocol.MsgTypeStopPractice:
+		h.stopPractice(client, false)
 
-	case protocol.MsgTypeDeath:
-		var death struct {
-			PlayerID string `json:"player_id"`
+	case protocol.MsgTypeSpectate:
+		var req protocol.SpectateRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
+			break
 		}
-		json.Unmarshal(msg.Payload, &death)
-		h.handleDeath(client, death.PlayerID)
+		h.spectate(client, req)
 
-	case protocol.MsgTypeGameOver:
-		var gameOver protocol.GameOverInfo
-		json.Unmarshal(msg.Payload, &gameOver)
-		h.handleGameOver(gameOver)
+	case protocol.MsgTypeListHeroes:
+		h.listHeroes(client)
 
-	case protocol.MsgTypeStartGame:
-		h.startGame(client) // 对房主所在的客户端启动游戏
+	case protocol.MsgTypeListBots:
+		h.listBots(client)
 
-	// 创建房间管理相关消息处理
-	case protocol.MsgTypeCreateRoom:
-		var createReq protocol.CreateRoomRequest
-		if err := json.Unmarshal(msg.Payload, &createReq); err != nil {
+	case protocol.MsgTypeFollowBracket:
+		var req protocol.FollowTournamentRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
 			break
 		}
+		h.followTournament(client, req)
+
+	case protocol.MsgTypeListLive:
+		h.listLiveMatches(client)
 
-		room := models.Room{
-			ID:         fmt.Sprintf("room_%d", time.Now().UnixNano()),
-			Name:       createReq.Name,
-			HostID:     client.username,
-			Players:    []string{client.username},
-			MaxPlayers: createReq.MaxPlayers,
-			Status:     "waiting",
-			CreatedAt:  time.Now(),
+	case protocol.MsgTypeSelectHero:
+		var req protocol.SelectHeroRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
+			break
 		}
-		h.roomStore.Add(room)
+		h.selectHero(client, req)
+
+	case protocol.MsgTypeJoinQueue:
+		h.joinQueue(client)
+
+	case protocol.MsgTypeCancelQueue:
+		h.cancelQueue(client)
+
+	case protocol.MsgTypeAcceptBotMatch:
+		h.acceptBotMatch(client)
 
-		client.roomID = room.ID
-		user := h.userStore.FindByUsername(client.username)
-		if user != nil {
-			user.RoomID = room.ID
-			h.userStore.Update(client.username, *user)
+	case protocol.MsgTypeAcceptMatch:
+		var req protocol.AcceptMatchRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
+			break
 		}
+		h.acceptMatch(client, req)
+
+	case protocol.MsgTypeStopSpectate:
+		h.stopSpectate(client)
 
-		// 返回房间信息给客户端
-		roomInfo := protocol.RoomInfo{
-			ID:         room.ID,
-			Name:       room.Name,
-			Host:       room.HostID,
-			Players:    room.Players,
-			MaxPlayers: room.MaxPlayers,
-			Status:     room.Status,
+	case protocol.MsgTypeChat:
+		var req protocol.ChatRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
+			break
 		}
-		respMsg := protocol.Message{
-			Type: protocol.MsgTypeJoinRoomResult,
-			Payload: mustMarshal(protocol.JoinRoomResponse{
-				Success: true,
-				Message: "房间创建成功",
-				Room:    roomInfo,
-			}),
+		h.chat(client, req)
+
+	case protocol.MsgTypeTyping:
+		var req protocol.TypingRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
+			break
 		}
-		respData, _ := json.Marshal(respMsg)
-		client.send <- respData
+		h.typing(client, req)
 
-	case protocol.MsgTypeRoomList:
-		// 返回房间列表给客户端
-		rooms := h.roomStore.GetAll()
-		roomInfos := make([]protocol.RoomInfo, 0)
-		for _, room := range rooms {
-			if room.Status != "playing" {
-				roomInfos = append(roomInfos, protocol.RoomInfo{
-					ID:         room.ID,
-					Name:       room.Name,
-					Host:       room.HostID,
-					Players:    room.Players,
-					MaxPlayers: room.MaxPlayers,
-					Status:     room.Status,
-				})
-			}
+	case protocol.MsgTypeEmote:
+		h.emote(client, msg)
+
+	case protocol.MsgTypeInvite:
+		var req protocol.InviteRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
+			break
 		}
+		h.invite(client, req)
 
-		respMsg := protocol.Message{
-			Type:    protocol.MsgTypeRoomList,
-			Payload: mustMarshal(protocol.RoomListResponse{Rooms: roomInfos}),
+	case protocol.MsgTypeAcceptInvite:
+		var req protocol.AcceptInviteRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
+			break
 		}
-		respData, _ := json.Marshal(respMsg)
-		client.send <- respData
+		h.acceptInvite(client, req)
 
-	case protocol.MsgTypeJoinRoom:
-		var joinReq protocol.JoinRoomRequest //获取前端发送的加入房间ID
-		if err := json.Unmarshal(msg.Payload, &joinReq); err != nil {
+	case protocol.MsgTypeChallenge:
+		var req protocol.ChallengeRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
 			break
 		}
+		h.challenge(client, req)
 
-		room := h.roomStore.GetByID(joinReq.RoomID)
-		if room == nil {
-			respMsg := protocol.Message{
-				Type: protocol.MsgTypeJoinRoomResult,
-				Payload: mustMarshal(protocol.JoinRoomResponse{
-					Success: false,
-					Message: "房间不存在",
-				}),
-			}
-			respData, _ := json.Marshal(respMsg)
-			client.send <- respData
+	case protocol.MsgTypeChallengeReply:
+		var req protocol.AcceptChallengeRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
 			break
 		}
+		h.acceptChallenge(client, req)
 
-		if room.Status == "playing" {
-			respMsg := protocol.Message{
-				Type: protocol.MsgTypeJoinRoomResult,
-				Payload: mustMarshal(protocol.JoinRoomResponse{
-					Success: false,
-					Message: "游戏进行中，无法加入",
-				}),
-			}
-			respData, _ := json.Marshal(respMsg)
-			client.send <- respData
+	case protocol.MsgTypeKickPlayer:
+		var req protocol.KickPlayerRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
 			break
 		}
+		h.kickPlayer(client, req)
 
-		if len(room.Players) >= room.MaxPlayers {
-			respMsg := protocol.Message{
-				Type: protocol.MsgTypeJoinRoomResult,
-				Payload: mustMarshal(protocol.JoinRoomResponse{
-					Success: false,
-					Message: "房间已满",
-				}),
-			}
-			respData, _ := json.Marshal(respMsg)
-			client.send <- respData
+	case protocol.MsgTypeLobbyPresence:
+		var req protocol.LobbyPresenceRequest
+		if err := protocol.DecodeBytes(msg.Payload, &req); err != nil {
+			h.sendDecodeError(client, err)
 			break
 		}
+		h.subscribeLobby(client, req)
 
-		for _, player := range room.Players {
-			if player == client.username {
-				respMsg := protocol.Message{
-					Type: protocol.MsgTypeJoinRoomResult,
-					Payload: mustMarshal(protocol.JoinRoomResponse{
-						Success: false,
-						Message: "您已在房间中",
-					}),
-				}
-				respData, _ := json.Marshal(respMsg)
-				client.send <- respData
+	// 创建房间管理相关消息处理
+	case protocol.MsgTypeCreateRoom:
+		var createReq protocol.CreateRoomRequest
+		if err := protocol.DecodeBytes(msg.Payload, &createReq); err != nil {
+			h.sendDecodeError(client, err)
+			break
+		}
+
+		h.createRoom(client, createReq)
+
+	case protocol.MsgTypeRoomList:
+		// 返回房间列表给客户端，可按区域或保存的筛选条件筛选
+		var listReq protocol.RoomListRequest
+		if len(msg.Payload) > 0 {
+			if err := protocol.DecodeBytes(msg.Payload, &listReq); err != nil {
+				h.sendDecodeError(client, err)
 				break
 			}
 		}
-
-		// 添加玩家到房间
-		room.Players = append(room.Players, client.username)
-		if len(room.Players) >= 2 {
-			room.Status = "ready"
+		region := strings.ToLower(strings.TrimSpace(listReq.Region))
+		// Filter 为用户保存的筛选条件名称
+		filter := models.RoomFilter{}
+		if listReq.Filter != "" {
+			f := h.rooms.RoomFilter(client.username, listReq.Filter)
+			if f == nil {
+				h.sendError(client, http.StatusNotFound, "筛选条件不存在")
+				break
+			}
+			filter = *f
 		}
-		h.roomStore.Update(*room)
-
-		client.roomID = room.ID
-		user := h.userStore.FindByUsername(client.username)
-		if user != nil {
-			user.RoomID = room.ID // 更新用户所在房间ID
-			h.userStore.Update(client.username, *user)
+		rooms := h.rooms.GetAllRooms()
+		roomInfos := make([]protocol.RoomInfo, 0)
+		for _, room := range rooms {
+			if room.Status != "playing" && (region == "" || room.Region == region) && filter.Match(room) {
+				roomInfos = append(roomInfos, h.roomInfo(room))
+			}
 		}
 
-		// 返回加入结果给客户端
-		roomInfo := protocol.RoomInfo{
-			ID:         room.ID,
-			Name:       room.Name,
-			Host:       room.HostID,
-			Players:    room.Players,
-			MaxPlayers: room.MaxPlayers,
-			Status:     room.Status,
-		}
 		respMsg := protocol.Message{
-			Type: protocol.MsgTypeJoinRoomResult,
-			Payload: mustMarshal(protocol.JoinRoomResponse{
-				Success: true,
-				Message: "加入房间成功",
-				Room:    roomInfo,
-			}),
+			Type:    protocol.MsgTypeRoomList,
+			Payload: mustMarshal(protocol.RoomListResponse{Rooms: roomInfos}),
 		}
 		respData, _ := json.Marshal(respMsg)
 		client.send <- respData
 
-		// 向房间内的其他玩家广播房间信息更新
-		broadcastMsg := protocol.Message{
-			Type: protocol.MsgTypeJoinRoomResult,
-			Payload: mustMarshal(protocol.JoinRoomResponse{
-				Success: true,
-				Message: client.username + " 加入了房间",
-				Room:    roomInfo,
-			}),
-		}
-		broadcastData, _ := json.Marshal(broadcastMsg)
-
-		h.mu.RLock()
-		for c := range h.clients {
-			if c.roomID == room.ID && c.username != client.username {
-				c.send <- broadcastData
-			}
+	case protocol.MsgTypeJoinRoom:
+		var joinReq protocol.JoinRoomRequest //获取前端发送的加入房间ID
+		if err := protocol.DecodeBytes(msg.Payload, &joinReq); err != nil {
+			h.sendDecodeError(client, err)
+			break
 		}
-		h.mu.RUnlock()
+		h.joinRoom(client, joinReq.RoomID)
 	}
 }
 
-// broadcastGameAction 广播游戏动作
-func (h *Hub) broadcastGameAction(sender *Client, msg protocol.Message) {
-	h.mu.RLock()
-	for client := range h.clients {
-		if client.roomID == sender.roomID && client.username != sender.username {
-			data, _ := json.Marshal(msg)
-			client.send <- data
+// createRoom 通过 RoomService 创建房间，与 HTTP 创建房间的校验和规则一致；
+// 请求未指定区域时使用客户端所在的区域
+func (h *Hub) createRoom(client *Client, req protocol.CreateRoomRequest) {
+	if req.Region == "" {
+		req.Region = client.region
+	}
+	result, err := h.rooms.CreateRoom(req, client.username)
+	if err != nil {
+		resp := protocol.ErrorResponse{Code: roomErrorStatus(err), Message: err.Error(), Reason: service.RoomErrorCode(err)}
+		if resp.Code == http.StatusInternalServerError {
+			client.logf("创建房间失败: %v", err)
+			resp.Message = "创建房间失败"
 		}
+		h.sendErrorResponse(client, resp)
+		return
 	}
-	h.mu.RUnlock()
+
+	client.roomID = result.Room.ID
+	client.spectator = false
+	respMsg := protocol.Message{
+		Type: protocol.MsgTypeJoinRoomResult,
+		Payload: mustMarshal(protocol.JoinRoomResponse{
+			Success: true,
+			Message: result.Message,
+			Room:    h.roomInfo(*result.Room),
+		}),
+	}
+	respData, _ := json.Marshal(respMsg)
+	client.send <- respData
+	if result.Existing {
+		return
+	}
+	h.announceLeave(result.Left, client.username)
+	h.recordEvent(client, models.TrafficRoomCreated, nil)
+}
+
+// roomErrorStatus 返回创建或加入房间失败时发给客户端的状态码，与 HTTP 接口一致
+func roomErrorStatus(err error) int {
+	switch {
+	case service.RoomErrorCode(err) == service.ErrCodeRoomNameTaken:
+		return http.StatusConflict
+	case service.RoomErrorCode(err) != "", errors.Is(err, service.ErrInvalidRules), errors.Is(err, service.ErrUnknownRegion):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAlreadyInRoom), errors.Is(err, service.ErrInMatch):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrRoomCreateTooFrequent):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrRoomLimitReached):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
 }
 
-// handleDeath 处理死亡事件
-func (h *Hub) handleDeath(loserClient *Client, loserID string) {
-	room := h.roomStore.GetByID(loserClient.roomID)
-	if room == nil || len(room.Players) < 2 {
+// joinRoom 通过 RoomService 把客户端加入指定房间，结果发给客户端并通知房间内的其他玩家。
+// 已在其他房间中时按配置拒绝或先离开原房间；已在该房间玩家列表中时重新进入
+func (h *Hub) joinRoom(client *Client, roomID string) {
+	result, err := h.rooms.JoinRoom(protocol.JoinRoomRequest{RoomID: roomID}, client.username)
+	if err != nil {
+		resp := protocol.JoinRoomResponse{Message: err.Error(), Code: service.ErrCodeAlreadyInRoom}
+		if roomErrorStatus(err) != http.StatusConflict {
+			client.logf("加入房间失败: %v", err)
+			resp = protocol.JoinRoomResponse{Message: "加入房间失败"}
+		}
+		respData, _ := json.Marshal(protocol.Message{Type: protocol.MsgTypeJoinRoomResult, Payload: mustMarshal(resp)})
+		client.send <- respData
+		return
+	}
+	if result.Room == nil {
+		resp := protocol.JoinRoomResponse{Success: false, Message: result.Message}
+		// 房间由其他实例托管时让客户端转移过去，房间的实时流量保持在同一实例上
+		if h.rooms.GetRoomByID(roomID) == nil {
+			if handoff := h.rooms.Handoff(roomID, client.username); handoff != nil {
+				resp = protocol.JoinRoomResponse{Message: "房间位于其他服务器，请重新连接", Handoff: handoff}
+			}
+		}
+		respData, _ := json.Marshal(protocol.Message{Type: protocol.MsgTypeJoinRoomResult, Payload: mustMarshal(resp)})
+		client.send <- respData
 		return
 	}
 
-	var winner string
-	for _, player := range room.Players {
-		if player != loserID {
-			winner = player
-			break
+	room := *result.Room
+	client.roomID = room.ID
+	client.spectator = false
+	h.announceLeave(result.Left, client.username)
+
+	// 返回加入结果给客户端
+	info := h.roomInfo(room)
+	respMsg := protocol.Message{
+		Type: protocol.MsgTypeJoinRoomResult,
+		Payload: mustMarshal(protocol.JoinRoomResponse{
+			Success: true,
+			Message: result.Message,
+			Room:    info,
+		}),
+	}
+	respData, _ := json.Marshal(respMsg)
+	client.send <- respData
+
+	// 向房间内的其他玩家广播房间信息更新
+	broadcastMsg := protocol.Message{
+		Type: protocol.MsgTypeJoinRoomResult,
+		Payload: mustMarshal(protocol.JoinRoomResponse{
+			Success: true,
+			Message: client.username + " 加入了房间",
+			Room:    info,
+		}),
+	}
+	broadcastData, _ := json.Marshal(broadcastMsg)
+
+	h.mu.RLock()
+	for c := range h.clients {
+		if c.roomID == room.ID && c.username != client.username {
+			c.send <- broadcastData
 		}
 	}
+	h.mu.RUnlock()
+}
 
-	gameOver := protocol.GameOverInfo{
-		Winner: winner,
-		Loser:  loserID,
+// announceLeave 用户加入或创建其他房间时自动离开了原房间，通知原房间内的其他成员；left 为 nil 时不通知
+func (h *Hub) announceLeave(left *models.Room, username string) {
+	if left == nil {
+		return
 	}
+	update, _ := json.Marshal(protocol.Message{
+		Type: protocol.MsgTypeJoinRoomResult,
+		Payload: mustMarshal(protocol.JoinRoomResponse{
+			Success: true,
+			Message: username + " 离开了房间",
+			Room:    h.roomInfo(*left),
+		}),
+	})
+	h.broadcaster.submit(h.roomPeers(left.ID, username), update)
+}
 
-	h.handleGameOver(gameOver)
+// broadcastGameAction 广播游戏动作，消息只序列化一次后交给工作池扇出
+func (h *Hub) broadcastGameAction(sender *Client, msg protocol.Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("序列化游戏动作失败: %v", err)
+		return
+	}
+	h.sendGame(sender.roomID, h.roomPeers(sender.roomID, sender.username), data)
 }
 
-// handleGameOver 处理游戏结束事件
-func (h *Hub) handleGameOver(gameOver protocol.GameOverInfo) {
+// handleGameOver 处理游戏结束事件：记录结果、重置房间并把结果摘要通知房间内玩家
+func (h *Hub) handleGameOver(roomID string, gameOver protocol.GameOverInfo, players []models.PlayerResult) {
 	result := models.GameResult{
-		ID:       fmt.Sprintf("result_%d", time.Now().UnixNano()),
-		RoomID:   "",
-		Winner:   gameOver.Winner,
-		Loser:    gameOver.Loser,
-		PlayTime: time.Now(),
-		Duration: gameOver.Duration,
+		ID:         h.newID("result"),
+		RoomID:     roomID,
+		Winner:     gameOver.Winner,
+		Loser:      gameOver.Loser,
+		PlayTime:   h.clock.Now(),
+		Duration:   gameOver.Duration,
+		Reason:     gameOver.Reason,
+		Map:        gameOver.Map,
+		Players:    players,
+		MVP:        gameOver.MVP,
+		Overtime:   gameOver.Overtime,
+		Placements: gameOver.Placements,
+
+		BalanceVersion: gameOver.BalanceVersion,
+	}
+	if mult := h.events.WinMultiplier(result.PlayTime); mult > 1 {
+		result.WinMultiplier = mult
+	}
+	for _, p := range players {
+		if models.IsBot(p.Username) {
+			result.BotMatch = true
+		}
+		if h.moderation.Flagged(p.UserID) {
+			result.Flagged = append(result.Flagged, p.Username)
+		}
 	}
 	h.resultStore.Add(result)
+	h.reportTournamentResult(result)
+	h.settleChallenge(result)
+	h.penalizeLeavers(result)
+	h.awardTitles(players)
 
-	room := h.roomStore.GetByID("")
-	if room != nil {
+	h.roomStore.Modify(roomID, func(room *models.Room) bool {
 		room.Status = "waiting"
-		h.roomStore.Update(*room)
-	}
+		return true
+	})
 
+	gameOver.Players = service.PlayerSummaries(players)
 	msg := protocol.Message{
 		Type:    protocol.MsgTypeGameOver,
 		Payload: mustMarshal(gameOver),
 	}
 	data, _ := json.Marshal(msg)
-	h.broadcast <- data
+	h.sendGame(roomID, h.roomPeers(roomID, ""), data)
+	h.revealSpectatorChat(roomID)
+	h.dropRoomKeys(roomID)
+	h.removeVoteKicked(roomID, players)
 }
 
 // startGame 处理开始游戏事件
 func (h *Hub) startGame(client *Client) {
-	room := h.roomStore.GetByID(client.roomID) // 从房间存储中获取房间，在房间中开始游戏
-	if room == nil {
+	// 只有房主可以开始游戏，由 RoomService 在存储锁内检查并更新房间状态
+	// 所有玩家都锁定英雄后才能开始
+	started, err := h.rooms.StartGame(client.roomID, client.username, func(r *models.Room) error {
+		if missing := h.lockHeroes(r); len(missing) > 0 {
+			return errors.New(heroesMissingMessage(missing))
+		}
+		return nil
+	})
+	if errors.Is(err, service.ErrNotHost) {
 		return
 	}
-
-	if client.username != room.HostID {
+	if err != nil {
+		h.sendError(client, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	room.Status = "playing"
-	h.roomStore.Update(*room)
+	if started == nil {
+		return
+	}
+	room := *started
+	h.rekeyRoom(room.ID) // 先下发密钥，对局协程启动后的第一帧即可按房间加密
+	seed := h.startSession(room)
 
 	gameStart := protocol.Message{ // 游戏开始消息，准备广播
-		Type: protocol.MsgTypeGameStart,
-		Payload: mustMarshal(protocol.RoomInfo{
-			ID:         room.ID,
-			Name:       room.Name,
-			Players:    room.Players,
-			MaxPlayers: room.MaxPlayers,
-			Status:     "playing",
-		}),
+		Type:    protocol.MsgTypeGameStart,
+		Payload: mustMarshal(protocol.GameStart{RoomInfo: h.roomInfo(room), Seed: seed}),
 	}
 	data, _ := json.Marshal(gameStart)
 
@@ -554,6 +1035,7 @@ func (h *Hub) startGame(client *Client) {
 		}
 	}
 	h.mu.RUnlock()
+	h.dispatchStarted(room.ID)
 }
 
 // serveWs 处理 WebSocket 连接
@@ -564,37 +1046,112 @@ func (s *Server) serveWs(c *gin.Context) {
 		return
 	}
 
-	// 1. 检查该用户是否已经有活跃的WebSocket连接，用于检查用户已登录
-	if s.hub.HasActiveConnection(username) {
+	// 检查并占用一个连接名额，连接注册到 Hub 之前的任何提前返回都要归还
+	if !s.hub.conns.Acquire() {
+		log.Printf("拒绝连接: 用户 %s，连接数已达上限 %d", username, s.cfg.MaxConnections)
+		c.JSON(http.StatusServiceUnavailable, protocol.ErrorResponse{
+			Code:    http.StatusServiceUnavailable,
+			Message: "服务器连接数已满，请稍后再试",
+		})
+		return
+	}
+	registered := false
+	defer func() {
+		if !registered {
+			s.hub.conns.Release()
+		}
+	}()
+
+	// 1. 检查该用户是否已经有活跃的WebSocket连接，用于检查用户已登录；集群模式下同时检查其他实例
+	if s.hub.HasActiveConnection(username) || s.hub.cluster.ConnectedElsewhere(username) {
 		log.Printf("拒绝重复连接: 用户 %s 已存在活跃的WebSocket连接", username)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "用户已登录"})
 		return
 	}
 
-	// 2. 检查用户是否已登录
+	// 2. 检查用户是否已登录；集群模式下用户可能在其他实例上登录，有共享会话即视为已登录
 	user := s.userStore.FindByUsername(username)
+	if user != nil && !user.Online && s.hub.cluster != nil && len(s.logins.ListByUser(user.UserID)) > 0 {
+		s.userStore.Modify(username, func(u *models.User) bool {
+			u.Online = true
+			u.LoginTime = time.Now()
+			return true
+		})
+		user.Online = true
+	}
 	if user == nil || !user.Online {
 		log.Printf("拒绝未登录连接: 用户 %s 未登录或不存在", username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录"})
 		return
 	}
 
+	// 3. 绑定登录会话：客户端可通过 session 参数指定，未指定时使用最近一次登录
+	sessionID := c.Query("session")
+	if sessionID != "" {
+		if session := s.logins.Get(sessionID); session == nil || session.UserID != user.UserID {
+			log.Printf("拒绝连接: 用户 %s 的会话 %s 不存在或已注销", username, sessionID)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "会话已失效，请重新登录"})
+			return
+		}
+	} else if sessions := s.logins.ListByUser(user.UserID); len(sessions) > 0 {
+		sessionID = sessions[0].ID
+	}
+
+	// 4. 集群模式下从其他实例转移过来的连接凭令牌加入房间
+	var handoffRoom string
+	if token := c.Query("handoff"); token != "" {
+		roomID, ok := s.hub.cluster.RedeemHandoff(token, username)
+		if !ok {
+			log.Printf("拒绝连接: 用户 %s 的转移令牌无效或已过期", username)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "转移令牌无效或已过期"})
+			return
+		}
+		handoffRoom = roomID
+	}
+
+	// 5. 确定客户端区域：连接参数优先，其次是登录会话和用户最近所在的区域
+	region := s.hub.regions.Resolve(c.Query("region"), nil)
+	if region == "" {
+		if session := s.logins.Get(sessionID); session != nil {
+			region = session.Region
+		}
+	}
+	if region == "" {
+		region = user.Region
+	}
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Println("Upgrade error:", err)
 		return
 	}
+	if sessionID != "" {
+		s.logins.Touch(sessionID, time.Now())
+	}
 
 	client := &Client{
-		hub:      s.hub,
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		username: username,
-		roomID:   user.RoomID,
+		hub:        s.hub,
+		conn:       conn,
+		send:       make(chan []byte, 256),
+		username:   username,
+		roomID:     user.RoomID,
+		lastActive: s.hub.clock.Now(),
+		version:    c.Query("version"),
+		sessionID:  sessionID,
+		region:     region,
+		connID:     api.NewRequestID(),
 	}
 
-	log.Printf("用户 %s 建立WebSocket连接成功", username)
+	log.Printf("用户 %s 建立WebSocket连接成功，连接ID %s", username, client.connID)
 	s.hub.register <- client
+	registered = true
+	if handoffRoom != "" && client.roomID == "" {
+		join, _ := json.Marshal(protocol.Message{
+			Type:    protocol.MsgTypeJoinRoom,
+			Payload: mustMarshal(protocol.JoinRoomRequest{RoomID: handoffRoom}),
+		})
+		s.hub.handleMessage(client, join)
+	}
 
 	go client.writePump()
 	go client.readPump()
@@ -608,3 +1165,31 @@ func mustMarshal(v interface{}) json.RawMessage {
 	}
 	return data
 }
+
+// markOffline 将在线用户标记为离线并清除房间ID，同时结束用户的登录会话，返回是否发生了变更
+func (h *Hub) markOffline(username string) bool {
+	var userID string
+	changed := h.userStore.Modify(username, func(user *models.User) bool {
+		userID = user.UserID
+		if !user.Online {
+			return false
+		}
+		user.Online = false
+		user.RoomID = ""
+		return true
+	})
+	if changed {
+		h.logins.RemoveUser(userID)
+	}
+	return changed
+}
+
+// containsPlayer 判断玩家是否在列表中
+func containsPlayer(players []string, username string) bool {
+	for _, player := range players {
+		if player == username {
+			return true
+		}
+	}
+	return false
+}
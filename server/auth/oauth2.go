@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OAuth2Config 通用 OAuth2 授权码模式提供方的配置
+type OAuth2Config struct {
+	Name         string // 提供方名称，例如 github
+	ClientID     string
+	ClientSecret string
+	AuthURL      string   // 授权页地址
+	TokenURL     string   // 换取 access token 的地址
+	UserInfoURL  string   // 使用 access token 获取用户信息的地址
+	Scopes       []string // 申请的权限范围
+}
+
+// OAuth2Provider 通用 OAuth2 授权码模式登录，兼容 OpenID Connect 的 userinfo 以及 GitHub 等常见返回格式
+type OAuth2Provider struct {
+	cfg OAuth2Config
+}
+
+// NewOAuth2Provider 创建通用 OAuth2 提供方
+func NewOAuth2Provider(cfg OAuth2Config) *OAuth2Provider {
+	return &OAuth2Provider{cfg: cfg}
+}
+
+// Name 返回配置的提供方名称
+func (p *OAuth2Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthURL 返回授权页地址
+func (p *OAuth2Provider) AuthURL(state, callbackURL string) string {
+	params := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.cfg.ClientID},
+		"redirect_uri":  {callbackURL},
+		"state":         {state},
+	}
+	if len(p.cfg.Scopes) > 0 {
+		params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	}
+	sep := "?"
+	if strings.Contains(p.cfg.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.cfg.AuthURL + sep + params.Encode()
+}
+
+// Exchange 用回调中的授权码换取 access token，再读取用户信息
+func (p *OAuth2Provider) Exchange(ctx context.Context, params url.Values, callbackURL string) (Identity, error) {
+	if e := params.Get("error"); e != "" {
+		return Identity{}, fmt.Errorf("%s 拒绝了授权: %s", p.cfg.Name, e)
+	}
+	code := params.Get("code")
+	if code == "" {
+		return Identity{}, fmt.Errorf("回调缺少授权码")
+	}
+
+	token, err := p.exchangeCode(ctx, code, callbackURL)
+	if err != nil {
+		return Identity{}, err
+	}
+	info, err := p.userInfo(ctx, token)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	id := Identity{
+		Provider: p.cfg.Name,
+		Subject:  firstString(info, "sub", "id"),
+		Username: firstString(info, "preferred_username", "login", "username", "name"),
+	}
+	// OIDC 明确标记未验证的邮箱不能使用
+	if verified, ok := info["email_verified"].(bool); !ok || verified {
+		id.Email = firstString(info, "email")
+	}
+	if id.Subject == "" {
+		return Identity{}, fmt.Errorf("%s 返回的用户信息缺少用户ID", p.cfg.Name)
+	}
+	return id, nil
+}
+
+// exchangeCode 用授权码换取 access token
+func (p *OAuth2Provider) exchangeCode(ctx context.Context, code, callbackURL string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {callbackURL},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := doJSON(req, &resp); err != nil {
+		return "", fmt.Errorf("换取 %s access token 失败: %v", p.cfg.Name, err)
+	}
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("换取 %s access token 失败: %s", p.cfg.Name, resp.Error)
+	}
+	return resp.AccessToken, nil
+}
+
+// userInfo 读取用户信息
+func (p *OAuth2Provider) userInfo(ctx context.Context, token string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	var info map[string]interface{}
+	if err := doJSON(req, &info); err != nil {
+		return nil, fmt.Errorf("读取 %s 用户信息失败: %v", p.cfg.Name, err)
+	}
+	return info, nil
+}
+
+// doJSON 发送请求并解析 JSON 响应
+func doJSON(req *http.Request, v interface{}) error {
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回 %s", req.URL.Host, res.Status)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// firstString 按顺序取第一个非空字段，数字ID 转为字符串
+func firstString(info map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := info[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+// Package auth 定义第三方登录提供方接口及其实现（Steam OpenID、通用 OAuth2）
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Identity 第三方账号身份，Provider 与 Subject 一起唯一标识一个外部账号
+type Identity struct {
+	Provider string
+	Subject  string // 提供方内的用户唯一ID，例如 SteamID
+	Username string // 提供方给出的用户名或昵称，仅作为自动创建账号时的用户名建议
+	Email    string // 提供方给出的已验证邮箱，可为空
+}
+
+// Provider 第三方登录提供方
+type Provider interface {
+	// Name 提供方名称，用于路由和账号关联，例如 steam
+	Name() string
+	// AuthURL 返回跳转到提供方登录页的地址，登录完成后提供方携带 state 回调 callbackURL
+	AuthURL(state, callbackURL string) string
+	// Exchange 校验回调参数并返回用户身份
+	Exchange(ctx context.Context, params url.Values, callbackURL string) (Identity, error)
+}
+
+// httpClient 访问提供方接口使用的 HTTP 客户端
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Registry 按名称索引已启用的提供方
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry 创建提供方注册表
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// Get 返回指定名称的提供方
+func (r *Registry) Get(name string) (Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Names 返回所有已启用提供方的名称
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	steamOpenIDEndpoint = "https://steamcommunity.com/openid/login"
+	openIDNamespace     = "http://specs.openid.net/auth/2.0"
+	openIDIdentifier    = "http://specs.openid.net/auth/2.0/identifier_select"
+)
+
+// steamIDPattern 从 claimed_id 中提取 64 位 SteamID
+var steamIDPattern = regexp.MustCompile(`^https?://steamcommunity\.com/openid/id/(\d+)$`)
+
+// SteamProvider 通过 Steam OpenID 2.0 登录。Steam 不返回昵称和邮箱，只能得到 SteamID
+type SteamProvider struct {
+	// Realm 向 Steam 声明的站点地址，需要是回调地址的前缀，例如 https://game.example.com
+	Realm string
+}
+
+// NewSteamProvider 创建 Steam 登录提供方
+func NewSteamProvider(realm string) *SteamProvider {
+	return &SteamProvider{Realm: realm}
+}
+
+// Name 返回 steam
+func (p *SteamProvider) Name() string {
+	return "steam"
+}
+
+// AuthURL 返回 Steam 登录页地址，state 通过 return_to 的查询参数带回
+func (p *SteamProvider) AuthURL(state, callbackURL string) string {
+	params := url.Values{
+		"openid.ns":         {openIDNamespace},
+		"openid.mode":       {"checkid_setup"},
+		"openid.return_to":  {withState(callbackURL, state)},
+		"openid.realm":      {p.Realm},
+		"openid.identity":   {openIDIdentifier},
+		"openid.claimed_id": {openIDIdentifier},
+	}
+	return steamOpenIDEndpoint + "?" + params.Encode()
+}
+
+// Exchange 将回调参数原样提交给 Steam 做 check_authentication 校验，通过后从 claimed_id 取出 SteamID
+func (p *SteamProvider) Exchange(ctx context.Context, params url.Values, callbackURL string) (Identity, error) {
+	if params.Get("openid.mode") != "id_res" {
+		return Identity{}, fmt.Errorf("Steam 登录未完成: %s", params.Get("openid.mode"))
+	}
+	// return_to 必须指向本服务的回调地址，防止其他站点的断言被重放到这里
+	if !strings.HasPrefix(params.Get("openid.return_to"), callbackURL) {
+		return Identity{}, fmt.Errorf("Steam 回调地址不匹配")
+	}
+	match := steamIDPattern.FindStringSubmatch(params.Get("openid.claimed_id"))
+	if match == nil {
+		return Identity{}, fmt.Errorf("无法识别的 Steam 账号: %s", params.Get("openid.claimed_id"))
+	}
+
+	verify := url.Values{}
+	for key, values := range params {
+		if strings.HasPrefix(key, "openid.") {
+			verify[key] = values
+		}
+	}
+	verify.Set("openid.mode", "check_authentication")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, steamOpenIDEndpoint, strings.NewReader(verify.Encode()))
+	if err != nil {
+		return Identity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return Identity{}, fmt.Errorf("校验 Steam 登录失败: %v", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil {
+		return Identity{}, err
+	}
+	if !strings.Contains(string(body), "is_valid:true") {
+		return Identity{}, fmt.Errorf("Steam 登录校验未通过")
+	}
+
+	steamID := match[1]
+	suffix := steamID
+	if len(suffix) > 6 {
+		suffix = suffix[len(suffix)-6:]
+	}
+	return Identity{
+		Provider: p.Name(),
+		Subject:  steamID,
+		Username: "steam_" + suffix,
+	}, nil
+}
+
+// withState 在回调地址上追加 state 参数
+func withState(callbackURL, state string) string {
+	sep := "?"
+	if strings.Contains(callbackURL, "?") {
+		sep = "&"
+	}
+	return callbackURL + sep + "state=" + url.QueryEscape(state)
+}
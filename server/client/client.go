@@ -0,0 +1,236 @@
+// Package client 是游戏服务器的无界面客户端，实现登录、加密 WebSocket 通信和房间操作，
+// 供压测工具和集成脚本复用
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"game/crypto"
+	"game/protocol"
+
+	"github.com/gorilla/websocket"
+)
+
+// Handler 处理服务器推送的一类消息，在客户端的读协程中调用，不能阻塞
+type Handler func(msg protocol.Message)
+
+// Client 单个玩家的连接
+type Client struct {
+	BaseURL  string // 服务器 HTTP 地址，例如 http://localhost:8080
+	Username string
+	Version  string // 连接时上报的客户端版本，可为空
+	Session  string // 登录返回的会话ID，Connect 时携带
+
+	http *http.Client
+	conn *websocket.Conn
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	handlers map[protocol.MessageType][]Handler
+	waiters  map[protocol.MessageType][]chan protocol.Message
+
+	done    chan struct{}
+	readErr error
+}
+
+// New 创建客户端
+func New(baseURL, username string) *Client {
+	return &Client{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		Username: username,
+		http:     &http.Client{Timeout: 10 * time.Second},
+		handlers: make(map[protocol.MessageType][]Handler),
+		waiters:  make(map[protocol.MessageType][]chan protocol.Message),
+	}
+}
+
+// Register 注册账号
+func (c *Client) Register(password, email string) (protocol.RegisterResponse, error) {
+	var resp protocol.RegisterResponse
+	err := c.postJSON("/user/register", protocol.RegisterRequest{
+		Username: c.Username,
+		Password: password,
+		Email:    email,
+	}, &resp)
+	return resp, err
+}
+
+// Login 登录，建立 WebSocket 连接前必须先登录成功
+func (c *Client) Login(password string) (protocol.LoginResponse, error) {
+	var resp protocol.LoginResponse
+	err := c.postJSON("/user/login", protocol.LoginRequest{
+		Username: c.Username,
+		Password: password,
+	}, &resp)
+	if err == nil && !resp.Success {
+		err = fmt.Errorf("登录失败: %s", resp.Message)
+	}
+	if err == nil {
+		c.Session = resp.SessionID
+	}
+	return resp, err
+}
+
+// Logout 登出
+func (c *Client) Logout() error {
+	var resp map[string]interface{}
+	return c.postJSON("/user/logout", map[string]string{"username": c.Username}, &resp)
+}
+
+func (c *Client) postJSON(path string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	res, err := c.http.Post(c.BaseURL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 500 {
+		return fmt.Errorf("%s 返回 %s", path, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(resp)
+}
+
+// On 注册消息处理函数，需在 Connect 之前调用
+func (c *Client) On(msgType protocol.MessageType, fn Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[msgType] = append(c.handlers[msgType], fn)
+}
+
+// Connect 建立 WebSocket 连接并启动读协程
+func (c *Client) Connect() error {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	u.Path = "/ws"
+	q := url.Values{"username": {c.Username}}
+	if c.Version != "" {
+		q.Set("version", c.Version)
+	}
+	if c.Session != "" {
+		q.Set("session", c.Session)
+	}
+	u.RawQuery = q.Encode()
+
+	conn, res, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		if res != nil {
+			return fmt.Errorf("连接 WebSocket 失败: %s", res.Status)
+		}
+		return fmt.Errorf("连接 WebSocket 失败: %v", err)
+	}
+	c.conn = conn
+	c.done = make(chan struct{})
+	go c.readLoop()
+	return nil
+}
+
+// readLoop 解密服务器消息并分发给处理函数，连接断开后关闭 done
+func (c *Client) readLoop() {
+	defer close(c.done)
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.readErr = err
+			return
+		}
+		plain, err := crypto.Decrypt(string(data))
+		if err != nil {
+			continue
+		}
+		var msg protocol.Message
+		if err := json.Unmarshal([]byte(plain), &msg); err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		handlers := c.handlers[msg.Type]
+		waiters := c.waiters[msg.Type]
+		delete(c.waiters, msg.Type)
+		c.mu.Unlock()
+		for _, fn := range handlers {
+			fn(msg)
+		}
+		for _, ch := range waiters {
+			ch <- msg
+		}
+	}
+}
+
+// Send 加密并发送一条消息，payload 为 nil 时不带负载
+func (c *Client) Send(msgType protocol.MessageType, payload interface{}) error {
+	msg := protocol.Message{Type: msgType}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msg.Payload = raw
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	encrypted, err := crypto.Encrypt(string(data))
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.conn == nil {
+		return fmt.Errorf("尚未建立连接")
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return c.conn.WriteMessage(websocket.TextMessage, []byte(encrypted))
+}
+
+// Done 返回连接断开时关闭的通道
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err 返回读协程退出的原因
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.readErr
+	default:
+		return nil
+	}
+}
+
+// Close 正常关闭连接并等待读协程退出
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	c.writeMu.Lock()
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.writeMu.Unlock()
+
+	select {
+	case <-c.done:
+	case <-time.After(2 * time.Second):
+	}
+	return c.conn.Close()
+}
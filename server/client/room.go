@@ -0,0 +1,97 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"game/protocol"
+)
+
+// ReplyTimeout 等待服务器应答的默认超时
+var ReplyTimeout = 10 * time.Second
+
+// Request 发送消息并等待 reply 类型的应答，服务器返回 error 消息时转为错误
+func (c *Client) Request(msgType protocol.MessageType, payload interface{}, reply protocol.MessageType) (protocol.Message, error) {
+	replyCh := c.Await(reply)
+	errCh := c.Await(protocol.MsgTypeError)
+	if err := c.Send(msgType, payload); err != nil {
+		return protocol.Message{}, err
+	}
+
+	select {
+	case msg := <-replyCh:
+		return msg, nil
+	case msg := <-errCh:
+		var resp protocol.ErrorResponse
+		json.Unmarshal(msg.Payload, &resp)
+		return protocol.Message{}, fmt.Errorf("%s 失败: %s", msgType, resp.Message)
+	case <-c.done:
+		return protocol.Message{}, fmt.Errorf("等待 %s 时连接已断开", reply)
+	case <-time.After(ReplyTimeout):
+		return protocol.Message{}, fmt.Errorf("等待 %s 超时", reply)
+	}
+}
+
+// Await 登记一次性等待，下一条 msgType 消息到达时投递到返回的通道。
+// 需要在触发该消息的操作之前调用，避免错过应答
+func (c *Client) Await(msgType protocol.MessageType) <-chan protocol.Message {
+	ch := make(chan protocol.Message, 1)
+	c.mu.Lock()
+	c.waiters[msgType] = append(c.waiters[msgType], ch)
+	c.mu.Unlock()
+	return ch
+}
+
+// CreateRoom 创建房间，创建者自动加入
+func (c *Client) CreateRoom(name string, maxPlayers int) (protocol.RoomInfo, error) {
+	return c.roomRequest(protocol.MsgTypeCreateRoom, protocol.CreateRoomRequest{
+		Name:       name,
+		MaxPlayers: maxPlayers,
+	}, protocol.MsgTypeJoinRoomResult)
+}
+
+// JoinRoom 加入房间
+func (c *Client) JoinRoom(roomID string) (protocol.RoomInfo, error) {
+	return c.roomRequest(protocol.MsgTypeJoinRoom, protocol.JoinRoomRequest{RoomID: roomID}, protocol.MsgTypeJoinRoomResult)
+}
+
+// Spectate 以观战者身份进入房间
+func (c *Client) Spectate(roomID string) (protocol.RoomInfo, error) {
+	return c.roomRequest(protocol.MsgTypeSpectate, protocol.SpectateRequest{RoomID: roomID}, protocol.MsgTypeSpectateResult)
+}
+
+// Chat 发送聊天消息，channel 为空时由服务器按身份选择频道
+func (c *Client) Chat(channel, text string) error {
+	return c.Send(protocol.MsgTypeChat, protocol.ChatRequest{Channel: channel, Text: text})
+}
+
+func (c *Client) roomRequest(msgType protocol.MessageType, payload interface{}, reply protocol.MessageType) (protocol.RoomInfo, error) {
+	msg, err := c.Request(msgType, payload, reply)
+	if err != nil {
+		return protocol.RoomInfo{}, err
+	}
+	var resp protocol.JoinRoomResponse
+	if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+		return protocol.RoomInfo{}, err
+	}
+	if !resp.Success {
+		return protocol.RoomInfo{}, fmt.Errorf("%s 失败: %s", msgType, resp.Message)
+	}
+	return resp.Room, nil
+}
+
+// StartGame 房主开始游戏，等待服务器广播 game_start
+func (c *Client) StartGame() error {
+	_, err := c.Request(protocol.MsgTypeStartGame, nil, protocol.MsgTypeGameStart)
+	return err
+}
+
+// Heartbeat 发送一次心跳并返回往返时延
+func (c *Client) Heartbeat() (time.Duration, error) {
+	start := time.Now()
+	if _, err := c.Request(protocol.MsgTypeHeartbeat, nil, protocol.MsgTypeHeartbeatReply); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
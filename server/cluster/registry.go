@@ -0,0 +1,362 @@
+// Package cluster 在多实例部署时通过 Redis 共享登录会话、在线状态和房间归属，
+// 未配置 Redis 时 Registry 为 nil，所有方法退化为单实例行为。需要 Redis 6.2 及以上版本（使用 GETDEL）
+package cluster
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"game/models"
+	"game/protocol"
+)
+
+const (
+	// PresenceTTL 在线状态的有效期，连接所在实例需在到期前刷新
+	PresenceTTL = 30 * time.Second
+	// instanceTTL 实例存活标记的有效期，过期实例的房间不再出现在大厅中
+	instanceTTL = 30 * time.Second
+	// RefreshInterval 刷新在线状态和实例存活标记的间隔
+	RefreshInterval = 10 * time.Second
+	// handoffTTL 转移令牌的有效期
+	handoffTTL = 30 * time.Second
+
+	keyPrefix       = "game:"
+	keyRooms        = keyPrefix + "rooms"         // 房间ID -> 房间 JSON
+	keySessionOwner = keyPrefix + "session_owner" // 会话ID -> 用户ID
+)
+
+// sessionsKey 用户的登录会话，按稳定的用户ID索引：会话ID -> 会话 JSON
+func sessionsKey(userID string) string { return keyPrefix + "sessions:" + userID }
+
+// presenceKey 用户的在线状态，值为持有连接的实例
+func presenceKey(username string) string { return keyPrefix + "presence:" + username }
+
+// instanceKey 实例存活标记，值为客户端连接该实例使用的地址
+func instanceKey(instance string) string { return keyPrefix + "instance:" + instance }
+
+// handoffKey 转移令牌
+func handoffKey(token string) string { return keyPrefix + "handoff:" + token }
+
+// Registry 跨实例的会话、在线状态和房间注册表。Redis 不可用时记录日志并按本实例的数据继续运行
+type Registry struct {
+	instance  string
+	advertise string // 客户端连接本实例使用的地址
+	redis     *redisConn
+}
+
+// NewRegistry 创建注册表，addr 为空时返回 nil 表示单实例模式；advertise 为空时使用实例标识
+func NewRegistry(addr, password, instance, advertise string) *Registry {
+	if addr == "" {
+		return nil
+	}
+	if advertise == "" {
+		advertise = instance
+	}
+	log.Printf("集群模式: 实例 %s 使用 Redis %s 共享会话和房间", instance, addr)
+	return &Registry{instance: instance, advertise: advertise, redis: &redisConn{addr: addr, password: password}}
+}
+
+// Instance 返回本实例的标识
+func (r *Registry) Instance() string {
+	if r == nil {
+		return ""
+	}
+	return r.instance
+}
+
+// do 执行 Redis 命令，失败时记录日志
+func (r *Registry) do(args ...string) (interface{}, bool) {
+	reply, err := r.redis.do(args...)
+	if err != nil {
+		log.Printf("集群注册表 %s 失败: %v", args[0], err)
+		return nil, false
+	}
+	return reply, true
+}
+
+// stringList 把数组回复转换为字符串列表，忽略空值
+func stringList(reply interface{}) []string {
+	items, _ := reply.([]interface{})
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// PutSession 登记或更新登录会话
+func (r *Registry) PutSession(session models.Session) {
+	if r == nil {
+		return
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return
+	}
+	r.do("HSET", sessionsKey(session.UserID), session.ID, string(data))
+	r.do("HSET", keySessionOwner, session.ID, session.UserID)
+}
+
+// GetSession 根据ID查找任一实例登记的会话
+func (r *Registry) GetSession(id string) *models.Session {
+	if r == nil {
+		return nil
+	}
+	owner, _ := r.do("HGET", keySessionOwner, id)
+	userID, _ := owner.(string)
+	if userID == "" {
+		return nil
+	}
+	reply, _ := r.do("HGET", sessionsKey(userID), id)
+	data, _ := reply.(string)
+	var session models.Session
+	if data == "" || json.Unmarshal([]byte(data), &session) != nil {
+		return nil
+	}
+	return &session
+}
+
+// ListSessions 返回用户在所有实例上的会话
+func (r *Registry) ListSessions(userID string) []models.Session {
+	if r == nil {
+		return nil
+	}
+	reply, _ := r.do("HVALS", sessionsKey(userID))
+	var list []models.Session
+	for _, data := range stringList(reply) {
+		var session models.Session
+		if json.Unmarshal([]byte(data), &session) == nil {
+			list = append(list, session)
+		}
+	}
+	return list
+}
+
+// DeleteSession 注销会话
+func (r *Registry) DeleteSession(id string) {
+	if r == nil {
+		return
+	}
+	owner, _ := r.do("HGET", keySessionOwner, id)
+	if userID, _ := owner.(string); userID != "" {
+		r.do("HDEL", sessionsKey(userID), id)
+	}
+	r.do("HDEL", keySessionOwner, id)
+}
+
+// DeleteUserSessions 注销用户的所有会话
+func (r *Registry) DeleteUserSessions(userID string) {
+	if r == nil {
+		return
+	}
+	reply, _ := r.do("HKEYS", sessionsKey(userID))
+	if ids := stringList(reply); len(ids) > 0 {
+		r.do(append([]string{"HDEL", keySessionOwner}, ids...)...)
+	}
+	r.do("DEL", sessionsKey(userID))
+}
+
+// ClaimPresence 为本实例上建立的连接登记在线状态，用户已连接到其他实例时返回 false
+func (r *Registry) ClaimPresence(username string) bool {
+	if r == nil {
+		return true
+	}
+	ttl := strconv.Itoa(int(PresenceTTL.Seconds()))
+	reply, ok := r.do("SET", presenceKey(username), r.instance, "NX", "EX", ttl)
+	if !ok || reply != nil {
+		return true
+	}
+	holder, _ := r.do("GET", presenceKey(username))
+	return holder == nil || holder == r.instance
+}
+
+// RefreshPresence 刷新本实例上连接的在线状态
+func (r *Registry) RefreshPresence(usernames []string) {
+	if r == nil {
+		return
+	}
+	ttl := strconv.Itoa(int(PresenceTTL.Seconds()))
+	for _, username := range usernames {
+		r.do("SET", presenceKey(username), r.instance, "EX", ttl)
+	}
+}
+
+// ReleasePresence 连接断开时清除在线状态，只清除本实例登记的
+func (r *Registry) ReleasePresence(username string) {
+	if r == nil {
+		return
+	}
+	if holder, _ := r.do("GET", presenceKey(username)); holder == r.instance {
+		r.do("DEL", presenceKey(username))
+	}
+}
+
+// ConnectedElsewhere 判断用户是否已连接到其他实例
+func (r *Registry) ConnectedElsewhere(username string) bool {
+	if r == nil {
+		return false
+	}
+	holder, _ := r.do("GET", presenceKey(username))
+	s, _ := holder.(string)
+	return s != "" && s != r.instance
+}
+
+// Heartbeat 刷新本实例的存活标记
+func (r *Registry) Heartbeat() {
+	if r == nil {
+		return
+	}
+	r.do("SET", instanceKey(r.instance), r.advertise, "EX", strconv.Itoa(int(instanceTTL.Seconds())))
+}
+
+// PutRoom 登记本实例托管的房间
+func (r *Registry) PutRoom(room models.Room) {
+	if r == nil {
+		return
+	}
+	room.Instance = r.instance
+	data, err := json.Marshal(room)
+	if err != nil {
+		return
+	}
+	r.do("HSET", keyRooms, room.ID, string(data))
+}
+
+// DeleteRoom 删除房间登记
+func (r *Registry) DeleteRoom(id string) {
+	if r == nil {
+		return
+	}
+	r.do("HDEL", keyRooms, id)
+}
+
+// rooms 返回所有登记的房间
+func (r *Registry) rooms() []models.Room {
+	reply, _ := r.do("HVALS", keyRooms)
+	var rooms []models.Room
+	for _, data := range stringList(reply) {
+		var room models.Room
+		if json.Unmarshal([]byte(data), &room) == nil {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms
+}
+
+// RemoteRooms 返回其他存活实例托管的房间，Instance 字段为托管实例
+func (r *Registry) RemoteRooms() []models.Room {
+	if r == nil {
+		return nil
+	}
+	rooms := r.rooms()
+	// 托管实例的存活标记用一次 MGET 批量查询
+	var instances []string
+	args := []string{"MGET"}
+	for _, room := range rooms {
+		if room.Instance == r.instance || slices.Contains(instances, room.Instance) {
+			continue
+		}
+		instances = append(instances, room.Instance)
+		args = append(args, instanceKey(room.Instance))
+	}
+	if len(instances) == 0 {
+		return nil
+	}
+	reply, _ := r.do(args...)
+	values, _ := reply.([]interface{})
+	alive := make(map[string]bool, len(instances))
+	for i, v := range values {
+		if s, _ := v.(string); s != "" && i < len(instances) {
+			alive[instances[i]] = true
+		}
+	}
+	var remote []models.Room
+	for _, room := range rooms {
+		if room.Instance != r.instance && alive[room.Instance] {
+			remote = append(remote, room)
+		}
+	}
+	return remote
+}
+
+// Handoff 房间由其他存活实例托管时签发转移令牌，客户端凭令牌连接到该实例后自动加入房间；
+// 房间不存在或由本实例托管时返回 nil
+func (r *Registry) Handoff(roomID, username string) *protocol.Handoff {
+	if r == nil {
+		return nil
+	}
+	reply, _ := r.do("HGET", keyRooms, roomID)
+	data, _ := reply.(string)
+	var room models.Room
+	if data == "" || json.Unmarshal([]byte(data), &room) != nil || room.Instance == r.instance {
+		return nil
+	}
+	addr, _ := r.do("GET", instanceKey(room.Instance))
+	target, _ := addr.(string)
+	if target == "" {
+		return nil
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil
+	}
+	token := hex.EncodeToString(buf)
+	grant, _ := json.Marshal(handoffGrant{Username: username, RoomID: roomID, Instance: room.Instance})
+	ttl := strconv.Itoa(int(handoffTTL.Seconds()))
+	if _, ok := r.do("SET", handoffKey(token), string(grant), "EX", ttl); !ok {
+		return nil
+	}
+	return &protocol.Handoff{
+		Instance:  room.Instance,
+		Addr:      target,
+		RoomID:    roomID,
+		Token:     token,
+		ExpiresIn: int(handoffTTL.Seconds()),
+	}
+}
+
+// handoffGrant 转移令牌对应的授权
+type handoffGrant struct {
+	Username string `json:"username"`
+	RoomID   string `json:"room_id"`
+	Instance string `json:"instance"`
+}
+
+// RedeemHandoff 兑换转移令牌，令牌只能由签发时指定的用户在目标实例上使用一次，返回要加入的房间。
+// 用 GETDEL 原子地取出并删除令牌，两个连接同时兑换时只有一个能拿到；用户或实例不符的兑换同样会使令牌作废
+func (r *Registry) RedeemHandoff(token, username string) (string, bool) {
+	if r == nil || token == "" {
+		return "", false
+	}
+	reply, _ := r.do("GETDEL", handoffKey(token))
+	data, _ := reply.(string)
+	var grant handoffGrant
+	if data == "" || json.Unmarshal([]byte(data), &grant) != nil {
+		return "", false
+	}
+	if grant.Username != username || grant.Instance != r.instance {
+		return "", false
+	}
+	return grant.RoomID, true
+}
+
+// ResetInstance 实例启动时清除上次运行遗留的房间登记，本地房间在启动时已全部清空
+func (r *Registry) ResetInstance() {
+	if r == nil {
+		return
+	}
+	for _, room := range r.rooms() {
+		if room.Instance == r.instance {
+			r.do("HDEL", keyRooms, room.ID)
+		}
+	}
+	r.Heartbeat()
+}
@@ -0,0 +1,130 @@
+package cluster
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// redisTimeout 单条 Redis 命令的连接和读写超时
+const redisTimeout = 2 * time.Second
+
+// redisError Redis 返回的错误回复
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisConn 最小化的 Redis 客户端：单连接串行执行命令，出错时关闭连接并在下一条命令时重连
+type redisConn struct {
+	mu       sync.Mutex
+	addr     string
+	password string
+	conn     net.Conn
+	r        *bufio.Reader
+}
+
+// do 执行一条命令，回复为 string、int64、[]interface{} 或 nil
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		if err := c.dial(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.roundTrip(args)
+	var re redisError
+	if err != nil && !errors.As(err, &re) {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return reply, err
+}
+
+// dial 建立连接，配置了密码时先认证
+func (c *redisConn) dial() error {
+	conn, err := net.DialTimeout("tcp", c.addr, redisTimeout)
+	if err != nil {
+		return fmt.Errorf("连接 Redis 失败: %w", err)
+	}
+	c.conn, c.r = conn, bufio.NewReader(conn)
+	if c.password != "" {
+		if _, err := c.roundTrip([]string{"AUTH", c.password}); err != nil {
+			conn.Close()
+			c.conn = nil
+			return fmt.Errorf("Redis 认证失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// roundTrip 发送命令并读取一条回复
+func (c *redisConn) roundTrip(args []string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(redisTimeout))
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, "\r\n"...)
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, "\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply 按 RESP 协议读取一条回复
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("redis: 无效的回复 %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				var re redisError
+				if !errors.As(err, &re) {
+					return nil, err
+				}
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: 未知的回复类型 %q", kind)
+}
@@ -0,0 +1,212 @@
+// loadbot 无界面压测工具：模拟 N 个玩家登录、建立加密 WebSocket、两两组队开局，
+// 按脚本循环发送对局动作，结束后输出心跳往返和对局消息转发的时延分位数。
+//
+// 用法: go run ./cmd/loadbot -addr http://localhost:8080 -players 20 -duration 1m
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"game/client"
+	"game/protocol"
+)
+
+type options struct {
+	addr       string
+	players    int
+	duration   time.Duration
+	ramp       time.Duration
+	prefix     string
+	password   string
+	scriptPath string
+	version    string
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.addr, "addr", "http://localhost:8080", "服务器 HTTP 地址")
+	flag.IntVar(&opts.players, "players", 2, "模拟玩家数，两人一个房间，奇数时向上取偶")
+	flag.DurationVar(&opts.duration, "duration", 30*time.Second, "开局后的压测时长")
+	flag.DurationVar(&opts.ramp, "ramp", 50*time.Millisecond, "相邻两个房间的启动间隔")
+	flag.StringVar(&opts.prefix, "prefix", "loadbot_", "模拟玩家用户名前缀")
+	flag.StringVar(&opts.password, "password", "loadbot123", "模拟玩家密码，账号不存在时自动注册")
+	flag.StringVar(&opts.scriptPath, "script", "", "动作脚本 JSON 文件，为空时使用内置脚本")
+	flag.StringVar(&opts.version, "version", "", "连接时上报的客户端版本")
+	flag.Parse()
+
+	script := defaultScript()
+	if opts.scriptPath != "" {
+		var err error
+		if script, err = loadScript(opts.scriptPath); err != nil {
+			log.Fatalf("加载脚本失败: %v", err)
+		}
+	}
+	if opts.players < 2 {
+		opts.players = 2
+	}
+	rooms := (opts.players + 1) / 2
+
+	rec := newRecorder()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < rooms; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := runRoom(i, opts, script, rec); err != nil {
+				rec.errors.Add(1)
+				log.Printf("房间 %d: %v", i, err)
+			}
+		}(i)
+		time.Sleep(opts.ramp)
+	}
+	wg.Wait()
+
+	rec.report(os.Stdout, time.Since(start))
+}
+
+// runRoom 让一对玩家建房、加入、开局并回放脚本，直到压测时长结束
+func runRoom(i int, opts options, script []step, rec *recorder) error {
+	host, err := connect(fmt.Sprintf("%s%d", opts.prefix, 2*i), opts, rec)
+	if err != nil {
+		return err
+	}
+	defer disconnect(host)
+	guest, err := connect(fmt.Sprintf("%s%d", opts.prefix, 2*i+1), opts, rec)
+	if err != nil {
+		return err
+	}
+	defer disconnect(guest)
+
+	room, err := host.CreateRoom(fmt.Sprintf("loadbot-%d", i), 2)
+	if err != nil {
+		return err
+	}
+	guestStarted := guest.Await(protocol.MsgTypeGameStart)
+	if _, err := guest.JoinRoom(room.ID); err != nil {
+		return err
+	}
+	if err := host.StartGame(); err != nil {
+		return err
+	}
+	select {
+	case <-guestStarted:
+	case <-time.After(client.ReplyTimeout):
+		return fmt.Errorf("%s 未收到开局消息", guest.Username)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.duration)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, c := range []*client.Client{host, guest} {
+		wg.Add(2)
+		go func(c *client.Client) {
+			defer wg.Done()
+			replay(ctx, c, script, rec)
+		}(c)
+		go func(c *client.Client) {
+			defer wg.Done()
+			heartbeat(ctx, c, rec)
+		}(c)
+	}
+	wg.Wait()
+	return nil
+}
+
+// connect 登录（必要时先注册）并建立 WebSocket 连接，对手转发来的带时间戳的动作计入转发时延
+func connect(username string, opts options, rec *recorder) (*client.Client, error) {
+	c := client.New(opts.addr, username)
+	c.Version = opts.version
+
+	if _, err := c.Login(opts.password); err != nil {
+		if _, regErr := c.Register(opts.password, username+"@loadbot.local"); regErr != nil {
+			return nil, regErr
+		}
+		if _, err := c.Login(opts.password); err != nil {
+			return nil, fmt.Errorf("%s: %v", username, err)
+		}
+	}
+
+	relayed := func(msg protocol.Message) {
+		rec.received.Add(1)
+		if sent, ok := sentAt(msg.Payload); ok {
+			rec.observe("relay", time.Since(sent))
+		}
+	}
+	c.On(protocol.MsgTypePlayerAction, relayed)
+	c.On(protocol.MsgTypeFire, relayed)
+
+	if err := c.Connect(); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("%s: %v", username, err)
+	}
+	return c, nil
+}
+
+func disconnect(c *client.Client) {
+	c.Close()
+	c.Logout()
+}
+
+// replay 循环执行脚本直到 ctx 结束或连接断开
+func replay(ctx context.Context, c *client.Client, script []step, rec *recorder) {
+	for i := 0; ; i = (i + 1) % len(script) {
+		s := script[i]
+		now := time.Now().UnixNano()
+		var err error
+		switch s.Action {
+		case "move_y":
+			err = c.Send(protocol.MsgTypePlayerAction, timedAction{
+				PlayerAction: protocol.PlayerAction{PlayerID: c.Username, Action: "move_y", Value: s.Value},
+				SentAt:       now,
+			})
+		case "fire":
+			err = c.Send(protocol.MsgTypeFire, timedFire{
+				FireAction: protocol.FireAction{PlayerID: c.Username, BulletID: fmt.Sprintf("bullet_%d", now)},
+				SentAt:     now,
+			})
+		}
+		if err != nil {
+			rec.errors.Add(1)
+			return
+		}
+		rec.sent.Add(1)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.Done():
+			rec.errors.Add(1)
+			log.Printf("%s: 连接断开: %v", c.Username, c.Err())
+			return
+		case <-time.After(s.Delay):
+		}
+	}
+}
+
+// heartbeat 每秒发送一次心跳并记录往返时延
+func heartbeat(ctx context.Context, c *client.Client, rec *recorder) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			rtt, err := c.Heartbeat()
+			if err != nil {
+				rec.errors.Add(1)
+				continue
+			}
+			rec.observe("heartbeat", rtt)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"game/protocol"
+)
+
+// step 脚本中的一个动作，执行后等待 Delay 再执行下一步
+type step struct {
+	Action string        `json:"action"` // move_y 或 fire
+	Value  float64       `json:"value"`  // move_y 的纵坐标
+	Delay  time.Duration `json:"-"`
+
+	DelayMS int `json:"delay_ms"`
+}
+
+// defaultScript 内置脚本：上下往返移动，每移动 10 次开火一次，约 30 条消息每秒
+func defaultScript() []step {
+	var steps []step
+	for i := 0; i < 60; i++ {
+		y := 170 + 150*math.Sin(float64(i)*2*math.Pi/60)
+		steps = append(steps, step{Action: "move_y", Value: y, Delay: 33 * time.Millisecond})
+		if i%10 == 9 {
+			steps = append(steps, step{Action: "fire", Delay: 0})
+		}
+	}
+	return steps
+}
+
+// loadScript 读取 JSON 脚本文件，格式为 [{"action":"move_y","value":120,"delay_ms":33}, ...]
+func loadScript(path string) ([]step, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var steps []step
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("解析脚本失败: %v", err)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("脚本为空")
+	}
+	for i := range steps {
+		switch steps[i].Action {
+		case "move_y", "fire":
+		default:
+			return nil, fmt.Errorf("第 %d 步: 未知动作 %q", i+1, steps[i].Action)
+		}
+		steps[i].Delay = time.Duration(steps[i].DelayMS) * time.Millisecond
+	}
+	return steps, nil
+}
+
+// timedAction 在玩家动作中附带发送时间，对手收到转发后据此计算转发时延。
+// 服务器原样转发对局消息，多出的字段不影响正常客户端
+type timedAction struct {
+	protocol.PlayerAction
+	SentAt int64 `json:"sent_at"`
+}
+
+type timedFire struct {
+	protocol.FireAction
+	SentAt int64 `json:"sent_at"`
+}
+
+// sentAt 取出消息中附带的发送时间，没有时返回 false
+func sentAt(payload json.RawMessage) (time.Time, bool) {
+	var v struct {
+		SentAt int64 `json:"sent_at"`
+	}
+	if json.Unmarshal(payload, &v) != nil || v.SentAt == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, v.SentAt), true
+}
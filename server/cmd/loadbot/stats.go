@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// recorder 汇总所有模拟玩家的时延样本和计数
+type recorder struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+
+	sent, received, errors atomic.Int64
+}
+
+func newRecorder() *recorder {
+	return &recorder{samples: make(map[string][]time.Duration)}
+}
+
+func (r *recorder) observe(metric string, d time.Duration) {
+	r.mu.Lock()
+	r.samples[metric] = append(r.samples[metric], d)
+	r.mu.Unlock()
+}
+
+// percentile 返回已排序样本的第 p 百分位
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p / 100)
+	return sorted[idx]
+}
+
+// report 输出每项指标的样本数和 p50/p90/p99/max
+func (r *recorder) report(w io.Writer, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fmt.Fprintf(w, "持续 %s，发送 %d 条，接收 %d 条，错误 %d 次\n",
+		elapsed.Round(time.Millisecond), r.sent.Load(), r.received.Load(), r.errors.Load())
+	if secs := elapsed.Seconds(); secs > 0 {
+		fmt.Fprintf(w, "吞吐: 发送 %.1f 条/秒，接收 %.1f 条/秒\n",
+			float64(r.sent.Load())/secs, float64(r.received.Load())/secs)
+	}
+
+	metrics := make([]string, 0, len(r.samples))
+	for name := range r.samples {
+		metrics = append(metrics, name)
+	}
+	sort.Strings(metrics)
+
+	fmt.Fprintf(w, "%-12s %8s %10s %10s %10s %10s\n", "指标", "样本数", "p50", "p90", "p99", "max")
+	for _, name := range metrics {
+		s := append([]time.Duration(nil), r.samples[name]...)
+		sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+		fmt.Fprintf(w, "%-12s %8d %10s %10s %10s %10s\n", name, len(s),
+			round(percentile(s, 50)), round(percentile(s, 90)), round(percentile(s, 99)), round(s[len(s)-1]))
+	}
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(10 * time.Microsecond)
+}
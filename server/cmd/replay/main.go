@@ -0,0 +1,207 @@
+// replay 将服务器录制的入站流量（GAME_RECORD_FILE）回放到开发服务器，用于复现玩家报告的不同步等问题。
+// 每个录制中的用户对应一个客户端，按录制时间间隔依次发送消息；房间ID 和用户名会映射为回放环境中的值。
+//
+// 用法: go run ./cmd/replay -addr http://localhost:8080 -file traffic.jsonl -speed 2
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"game/client"
+	"game/models"
+	"game/protocol"
+)
+
+type replayer struct {
+	addr     string
+	password string
+	prefix   string
+
+	clients map[string]*client.Client // 按录制中的用户名索引
+	created map[string]string         // 用户最近一次回放 create_room 得到的新房间ID
+	renames map[string]string         // 录制中的用户名、房间ID 到回放环境中对应值的映射
+}
+
+func main() {
+	addr := flag.String("addr", "http://localhost:8080", "开发服务器 HTTP 地址")
+	file := flag.String("file", "", "录制文件路径")
+	speed := flag.Float64("speed", 1, "回放速度倍数，0 表示不等待、尽快发送")
+	password := flag.String("password", "replay123", "回放用户的密码，账号不存在时自动注册")
+	prefix := flag.String("prefix", "replay_", "回放用户名前缀，为空时使用录制中的原用户名")
+	users := flag.String("users", "", "只回放这些用户的流量，逗号分隔，为空时回放全部")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("打开录制文件失败: %v", err)
+	}
+	defer f.Close()
+
+	only := make(map[string]bool)
+	for _, u := range strings.Split(*users, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			only[u] = true
+		}
+	}
+
+	r := &replayer{
+		addr:     *addr,
+		password: *password,
+		prefix:   *prefix,
+		clients:  make(map[string]*client.Client),
+		created:  make(map[string]string),
+		renames:  make(map[string]string),
+	}
+	defer r.closeAll()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var last time.Time
+	line, replayed := 0, 0
+	for scanner.Scan() {
+		line++
+		var rec models.TrafficRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			log.Printf("第 %d 行格式错误，已跳过: %v", line, err)
+			continue
+		}
+		if len(only) > 0 && !only[rec.Username] {
+			continue
+		}
+
+		// 按录制中的时间间隔等待
+		if !last.IsZero() && *speed > 0 {
+			if gap := rec.Time.Sub(last); gap > 0 {
+				time.Sleep(time.Duration(float64(gap) / *speed))
+			}
+		}
+		last = rec.Time
+
+		if err := r.apply(rec); err != nil {
+			log.Printf("第 %d 行（%s %s）回放失败: %v", line, rec.Username, rec.Event, err)
+			continue
+		}
+		replayed++
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("读取录制文件失败: %v", err)
+	}
+	log.Printf("回放完成，共 %d 条记录", replayed)
+}
+
+// apply 回放一条记录
+func (r *replayer) apply(rec models.TrafficRecord) error {
+	switch rec.Event {
+	case models.TrafficConnect:
+		_, err := r.client(rec.Username)
+		return err
+
+	case models.TrafficDisconnect:
+		if c, ok := r.clients[rec.Username]; ok {
+			c.Close()
+			c.Logout()
+			delete(r.clients, rec.Username)
+		}
+		return nil
+
+	case models.TrafficRoomCreated:
+		// 录制中的房间ID 对应本次回放 create_room 得到的房间
+		if id, ok := r.created[rec.Username]; ok && rec.RoomID != "" {
+			r.renames[rec.RoomID] = id
+			delete(r.created, rec.Username)
+		}
+		return nil
+
+	case models.TrafficMessage:
+		return r.send(rec)
+	}
+	return fmt.Errorf("未知事件 %q", rec.Event)
+}
+
+// send 回放一条客户端消息，录制开始时已在线的用户在第一条消息时建立连接
+func (r *replayer) send(rec models.TrafficRecord) error {
+	c, err := r.client(rec.Username)
+	if err != nil {
+		return err
+	}
+	var msg protocol.Message
+	if err := json.Unmarshal(rec.Message, &msg); err != nil {
+		return err
+	}
+	payload := r.rename(msg.Payload)
+
+	if msg.Type == protocol.MsgTypeCreateRoom {
+		// 等待服务器分配新房间ID，供后续 room_created 记录建立映射
+		reply, err := c.Request(msg.Type, payload, protocol.MsgTypeJoinRoomResult)
+		if err != nil {
+			return err
+		}
+		var resp protocol.JoinRoomResponse
+		if err := json.Unmarshal(reply.Payload, &resp); err == nil && resp.Success {
+			r.created[rec.Username] = resp.Room.ID
+		}
+		return nil
+	}
+	return c.Send(msg.Type, payload)
+}
+
+// client 返回录制用户对应的回放客户端，不存在时登录（必要时先注册）并建立连接
+func (r *replayer) client(username string) (*client.Client, error) {
+	if c, ok := r.clients[username]; ok {
+		return c, nil
+	}
+	name := r.prefix + username
+	c := client.New(r.addr, name)
+	if _, err := c.Login(r.password); err != nil {
+		if _, regErr := c.Register(r.password, name+"@replay.local"); regErr != nil {
+			return nil, regErr
+		}
+		if _, err := c.Login(r.password); err != nil {
+			return nil, err
+		}
+	}
+	c.On(protocol.MsgTypeError, func(msg protocol.Message) {
+		var resp protocol.ErrorResponse
+		json.Unmarshal(msg.Payload, &resp)
+		log.Printf("%s 收到错误: %s", name, resp.Message)
+	})
+	if err := c.Connect(); err != nil {
+		c.Logout()
+		return nil, err
+	}
+	r.clients[username] = c
+	if name != username {
+		r.renames[username] = name
+	}
+	return c, nil
+}
+
+// rename 将负载中出现的录制用户名和房间ID 替换为回放环境中的值，只替换完整的 JSON 字符串
+func (r *replayer) rename(payload json.RawMessage) json.RawMessage {
+	if len(payload) == 0 || len(r.renames) == 0 {
+		return payload
+	}
+	s := string(payload)
+	for from, to := range r.renames {
+		s = strings.ReplaceAll(s, `"`+from+`"`, `"`+to+`"`)
+	}
+	return json.RawMessage(s)
+}
+
+func (r *replayer) closeAll() {
+	for _, c := range r.clients {
+		c.Close()
+		c.Logout()
+	}
+}
@@ -0,0 +1,583 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"game/sim"
+)
+
+// 数据持久化方式
+const (
+	PersistenceFile = "file" // 保存到 data 目录下的 JSON 文件
+	PersistenceNone = "none" // 只保存在内存中，进程退出即丢失，用于测试和临时服务器
+)
+
+// Config 定义服务器运行参数，默认值见 Default，可通过环境变量覆盖
+type Config struct {
+	// 数据持久化方式，见 PersistenceFile、PersistenceNone
+	Persistence string
+
+	// 大厅空闲超时：不在房间内且长时间无消息的连接会被断开，0 表示不启用
+	LobbyIdleTimeout time.Duration
+	// 断开前提前多久发送空闲警告
+	LobbyIdleWarning time.Duration
+
+	// 容量限制，0 表示不限制
+	MaxConnections      int // 最大并发 WebSocket 连接数
+	MaxRooms            int // 最大房间数
+	MaxRoomsPerUserHour int // 每个用户每小时最多创建的房间数
+	MaxBodyBytes        int // HTTP 请求体和解密后单条 WebSocket 消息的最大字节数
+	AvatarMaxBytes      int // 上传头像的请求体最大字节数，替代该接口的 MaxBodyBytes
+
+	// 创建房间时允许的人数上限范围，未指定人数时使用 RoomMaxPlayers；
+	// RoomClampPlayers 为 true 时把超出范围的人数调整到边界，否则拒绝创建
+	RoomMinPlayers   int
+	RoomMaxPlayers   int
+	RoomClampPlayers bool
+
+	// 每个用户每分钟可发送的各类 WebSocket 消息数，键为消息类型，未列出或为 0 的类型不限制；
+	// 环境变量格式为 create_room=5,chat=60，设置后替换整个默认配额
+	MessageQuotas map[string]int
+
+	// 每个客户端 IP 每分钟可以发起的玩家搜索次数，防止批量抓取用户名；0 表示不限制
+	PlayerSearchPerMinute int
+
+	// 管理接口令牌，通过 X-Admin-Token 请求头校验，为空时管理接口不可用
+	AdminToken string
+
+	// 服务间调用方（赛事服务、统计采集等）的 HMAC 签名密钥，键为密钥ID，环境变量格式为 tournament=secret1,stats=secret2；
+	// 配置后这些调用方可以用签名代替管理令牌访问管理接口。SignatureWindow 为签名时间戳允许的最大偏差，
+	// 窗口内同一 nonce 只能使用一次，防止请求被重放
+	SigningKeys     map[string]string
+	SignatureWindow time.Duration
+
+	// 游戏结果保留时长，超过的结果会被清理，0 表示永久保留
+	ResultRetention time.Duration
+	// 清理前是否按月压缩归档
+	ResultArchive bool
+	// 后台清理任务执行间隔
+	ResultPruneInterval time.Duration
+	// 游戏结果日志压缩检查间隔
+	ResultCompactInterval time.Duration
+
+	// users.json 静态加密口令，为空时尝试从 UsersKeyFile 读取，都为空则明文保存
+	UsersKey     string
+	UsersKeyFile string
+
+	// 未指定难度时机器人对手使用的难度：easy、normal、hard
+	BotDifficulty string
+
+	// 用户、房间查询缓存容量，0 表示不使用缓存
+	CacheSize int
+
+	// 数据目录自动备份间隔，0 表示不自动备份
+	BackupInterval time.Duration
+	// 保留的备份份数，0 表示不清理
+	BackupKeep int
+
+	// 用户与房间引用一致性检查间隔，0 表示不自动检查
+	RepairInterval time.Duration
+	// 房间中的玩家离线超过该时长后被一致性检查移出房间
+	RepairOfflineAfter time.Duration
+
+	// HTTP 监听地址，端口为 0 时由系统分配
+	Addr string
+	// 随机数主种子，0 表示每次启动随机；固定后机器人等对局随机行为可以重放
+	Seed int64
+	// 时间来源，为空时使用系统时间；测试中可注入 sim.FakeClock，无需真实等待即可触发心跳超时等逻辑
+	Clock sim.Clock
+
+	// 混沌测试：对 WebSocket 消息双向注入延迟、丢弃和乱序，只用于测试环境，默认全部关闭
+	ChaosDelay       time.Duration // 每条消息的固定延迟
+	ChaosJitter      time.Duration // 在固定延迟上追加的随机延迟上限，延迟不同的消息会乱序到达
+	ChaosDropRate    float64       // 丢弃消息的概率，0~1
+	ChaosReorderRate float64       // 消息与下一条交换顺序的概率，0~1
+	ChaosUsers       []string      // 只对这些用户生效，为空时对所有连接生效
+
+	// 密码策略：最少字符数、至少包含的字符类别数（小写、大写、数字、符号，1 表示不要求）、是否拒绝常见弱密码，
+	// 以及追加的弱密码列表文件（每行一个）
+	PasswordMinLength     int
+	PasswordMinClasses    int
+	PasswordRejectCommon  bool
+	PasswordBlacklistFile string
+
+	// 屏蔽词：初始屏蔽词列表文件（每行一个，只在数据目录中还没有屏蔽词数据时导入，之后通过管理接口维护），
+	// 以及聊天消息包含屏蔽词时的处理方式 mask、reject 或 off；用户名和房间名包含屏蔽词时总是拒绝
+	WordListFile   string
+	ChatFilterMode string
+
+	// 第三方登录回调使用的服务器对外地址，例如 https://game.example.com
+	AuthCallbackURL string
+	// 是否启用 Steam 登录
+	SteamLogin bool
+	// 通用 OAuth2 登录，OAuthName 与 OAuthClientID 都不为空时启用
+	OAuthName         string
+	OAuthClientID     string
+	OAuthClientSecret string
+	OAuthAuthURL      string
+	OAuthTokenURL     string
+	OAuthUserInfoURL  string
+	OAuthScopes       []string
+
+	// 发信用的 SMTP 服务器（host:port）、认证信息和发件人地址；SMTPAddr 为空时邮件只写入日志
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+
+	// 流量录制文件：设置后把所有解密后的入站 WebSocket 消息追加写入该文件，供 cmd/replay 回放排查问题。
+	// 录制内容包含玩家的全部操作，只应在调试时开启
+	RecordFile string
+
+	// 对局中玩家断线后等待重连的宽限期，期间对局暂停；0 表示断线立即判负
+	ReconnectGrace time.Duration
+
+	// 房间邀请的有效期，被邀请的用户不在线时上线后仍能在有效期内收到
+	InviteTTL time.Duration
+
+	// 天梯挑战的有效期，以及最多可以挑战排名高出多少位的玩家，0 表示不限
+	ChallengeTTL   time.Duration
+	ChallengeRange int
+
+	// 创建房间请求幂等键的有效期，期间用相同的键重试会返回原先创建的房间
+	RoomCreateKeyTTL time.Duration
+
+	// 用户已在房间中时创建或加入其他房间的处理方式：reject 拒绝并返回 already_in_room，
+	// leave 先自动离开原房间；原房间正在对局时总是拒绝
+	RoomSwitchMode string
+
+	// 对局结束后是否把本局观战频道的聊天记录发给对局玩家；对局中观战聊天始终只在观战者之间转发
+	SpectatorChatAfterMatch bool
+
+	// 观战者接收对局消息的延迟，防止观战者给玩家实时报点；0 表示不延迟，玩家始终实时接收
+	SpectatorDelay time.Duration
+
+	// 对局开始时为房间派生会话密钥，对局广播帧按房间只加密一次，观战者拿到单独的只读密钥；
+	// 需要客户端支持 room_key 消息，默认关闭，仍按连接逐个加密
+	RoomKeys bool
+
+	// 单人练习靶场的时长，以及场上同时存在的固定靶数量
+	PracticeDuration time.Duration
+	PracticeTargets  int
+
+	// 赛事自动赛程的检查间隔，0 表示不自动开赛和判负；以及比赛开始前提前通知双方的时间
+	TournamentScheduleInterval time.Duration
+	TournamentNoticeLead       time.Duration
+
+	// 可用区域列表，为空时不限制区域名称；以及匹配时只与同区域玩家配对的等待时间，超过后放宽到所有区域
+	Regions          []string
+	MatchRegionWiden time.Duration
+
+	// 匹配成功后等待双方确认的时间，以及拒绝或未确认对局的玩家重新匹配前的冷却时间
+	MatchAcceptTimeout   time.Duration
+	MatchDeclineCooldown time.Duration
+	// 玩家累计的违规分达到该值时标记为疑似作弊等待审核：期间其对局不计入评分，匹配时只与同样被标记的玩家配对；0 表示不标记
+	CheatFlagThreshold int
+	// 中途弃赛（断线且未在宽限期内重连）的玩家重新匹配前的冷却时间，按最近几局中的弃赛次数成倍增加；0 表示不惩罚
+	LeaverCooldown time.Duration
+	// 对局中投票踢人的窗口：发起后在该时间内除目标外过半数的在场玩家同意即踢出，只在三人以上的对局中可用；0 表示不允许投票踢人
+	VoteKickWindow time.Duration
+	// 对局内投票（平局结束、重开当前局、下一场换图）的窗口，窗口内达到法定票数即生效；0 表示不允许对局内投票
+	MatchVoteWindow time.Duration
+	// 对局结束后玩家可以对结果提出申诉的期限；0 表示不接受申诉
+	DisputeWindow time.Duration
+	// 排队超过该时间仍未配对时提供与机器人的对局，0 表示不提供；MatchBotAuto 为 true 时直接创建而不等待玩家确认
+	MatchBotBackfill time.Duration
+	MatchBotAuto     bool
+
+	// 是否在服务器端校验玩家移动：超过速度上限或穿过地图障碍物的位置会被修正并通知客户端
+	MovementValidation bool
+
+	// 对局默认的服务器逻辑帧率和状态快照发送频率（Hz），SendRate 为 0 表示不发送快照。
+	// ModeTickRates、ModeSendRates 按模式（地图目标，如 king_of_the_hill）覆盖默认值，环境变量格式同 MessageQuotas；
+	// 房间规则中设置的帧率优先于两者。AdaptiveSendRate 为 true 时，发送队列持续积压的客户端会自动降低快照接收频率
+	TickRate         int
+	SendRate         int
+	ModeTickRates    map[string]int
+	ModeSendRates    map[string]int
+	AdaptiveSendRate bool
+
+	// 集群模式：多个实例通过 Redis 共享登录会话、在线状态和房间归属，RedisAddr 为空时单实例运行。
+	// InstanceID 为空时使用主机名和监听地址；InstanceAddr 为客户端连接本实例使用的地址，转移客户端时下发，为空时使用 InstanceID
+	RedisAddr     string
+	RedisPassword string
+	InstanceID    string
+	InstanceAddr  string
+
+	// 公开服务器：在 /serverinfo 和向主服务器宣告时展示的名称和客户端连接地址；
+	// MasterServerURL 不为空时每隔 AnnounceInterval 向该地址宣告本服务器，为空时不宣告
+	ServerName       string
+	PublicAddr       string
+	MasterServerURL  string
+	AnnounceInterval time.Duration
+
+	// 在线连接数的采样间隔，用于统计峰值和平均在线数，0 表示不采样
+	AnalyticsSampleInterval time.Duration
+
+	// 慢操作阈值：HTTP 请求、Hub 处理一条消息或存储写盘超过该时间时记录警告，并在 /admin/stats 中列出；0 表示不检测
+	SlowThreshold time.Duration
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Persistence: PersistenceFile,
+
+		LobbyIdleTimeout: 10 * time.Minute,
+		LobbyIdleWarning: 30 * time.Second,
+
+		MaxConnections:      1000,
+		MaxRooms:            200,
+		MaxRoomsPerUserHour: 20,
+		MaxBodyBytes:        64 << 10,
+		AvatarMaxBytes:      1 << 20,
+		RoomMinPlayers:      2,
+		RoomMaxPlayers:      16,
+		MessageQuotas: map[string]int{
+			"create_room":    5,
+			"join_room":      20,
+			"room_list":      60,
+			"add_bot":        10,
+			"spectate":       20,
+			"join_queue":     10,
+			"chat":           60,
+			"typing":         120,
+			"emote":          20,
+			"invite":         10,
+			"lobby_presence": 10,
+		},
+
+		PlayerSearchPerMinute: 30,
+
+		PracticeDuration: time.Minute,
+		PracticeTargets:  3,
+
+		TournamentScheduleInterval: 15 * time.Second,
+		TournamentNoticeLead:       5 * time.Minute,
+
+		SignatureWindow: 5 * time.Minute,
+
+		ResultRetention:     90 * 24 * time.Hour,
+		ResultArchive:       true,
+		ResultPruneInterval: 24 * time.Hour,
+
+		ResultCompactInterval: time.Hour,
+
+		BotDifficulty: "normal",
+
+		CacheSize: 1024,
+
+		BackupInterval: 6 * time.Hour,
+		BackupKeep:     28,
+
+		RepairInterval:     5 * time.Minute,
+		RepairOfflineAfter: 30 * time.Minute,
+
+		PasswordMinLength:    6,
+		PasswordMinClasses:   1,
+		PasswordRejectCommon: true,
+		ChatFilterMode:       "mask",
+
+		AuthCallbackURL: "http://localhost:8080",
+
+		MailFrom: "noreply@localhost",
+
+		ReconnectGrace:   30 * time.Second,
+		InviteTTL:        30 * time.Minute,
+		RoomCreateKeyTTL: 5 * time.Minute,
+		RoomSwitchMode:   "reject",
+
+		ChallengeTTL:   10 * time.Minute,
+		ChallengeRange: 3,
+
+		MatchRegionWiden:     20 * time.Second,
+		MatchAcceptTimeout:   10 * time.Second,
+		MatchDeclineCooldown: 30 * time.Second,
+		LeaverCooldown:       2 * time.Minute,
+		VoteKickWindow:       30 * time.Second,
+		MatchVoteWindow:      30 * time.Second,
+		DisputeWindow:        24 * time.Hour,
+		CheatFlagThreshold:   20,
+		MatchBotBackfill:     90 * time.Second,
+
+		TickRate:         60,
+		SendRate:         20,
+		AdaptiveSendRate: true,
+
+		MovementValidation: true,
+		ServerName:         "FPS 游戏服务器",
+		AnnounceInterval:   time.Minute,
+
+		AnalyticsSampleInterval: time.Minute,
+
+		SlowThreshold: 200 * time.Millisecond,
+
+		Addr:  ":8080",
+		Clock: sim.RealClock{},
+	}
+}
+
+// Load 加载配置：先取默认值，再读取 GAME_ 前缀的环境变量
+func Load() *Config {
+	cfg := Default()
+	cfg.Persistence = envString("GAME_PERSISTENCE", cfg.Persistence)
+	cfg.LobbyIdleTimeout = envDuration("GAME_LOBBY_IDLE_TIMEOUT", cfg.LobbyIdleTimeout)
+	cfg.LobbyIdleWarning = envDuration("GAME_LOBBY_IDLE_WARNING", cfg.LobbyIdleWarning)
+	cfg.MaxConnections = envInt("GAME_MAX_CONNECTIONS", cfg.MaxConnections)
+	cfg.MaxRooms = envInt("GAME_MAX_ROOMS", cfg.MaxRooms)
+	cfg.MaxRoomsPerUserHour = envInt("GAME_MAX_ROOMS_PER_USER_HOUR", cfg.MaxRoomsPerUserHour)
+	cfg.MaxBodyBytes = envInt("GAME_MAX_BODY_BYTES", cfg.MaxBodyBytes)
+	cfg.AvatarMaxBytes = envInt("GAME_AVATAR_MAX_BYTES", cfg.AvatarMaxBytes)
+	cfg.RoomMinPlayers = envInt("GAME_ROOM_MIN_PLAYERS", cfg.RoomMinPlayers)
+	cfg.RoomMaxPlayers = envInt("GAME_ROOM_MAX_PLAYERS", cfg.RoomMaxPlayers)
+	cfg.RoomClampPlayers = envBool("GAME_ROOM_CLAMP_PLAYERS", cfg.RoomClampPlayers)
+	cfg.MessageQuotas = envIntMap("GAME_MESSAGE_QUOTAS", cfg.MessageQuotas)
+	cfg.PlayerSearchPerMinute = envInt("GAME_PLAYER_SEARCH_PER_MINUTE", cfg.PlayerSearchPerMinute)
+	cfg.AdminToken = envString("GAME_ADMIN_TOKEN", cfg.AdminToken)
+	cfg.SigningKeys = envStringMap("GAME_SIGNING_KEYS", cfg.SigningKeys)
+	cfg.SignatureWindow = envDuration("GAME_SIGNATURE_WINDOW", cfg.SignatureWindow)
+	cfg.ResultRetention = envDuration("GAME_RESULT_RETENTION", cfg.ResultRetention)
+	cfg.ResultArchive = envBool("GAME_RESULT_ARCHIVE", cfg.ResultArchive)
+	cfg.ResultPruneInterval = envDuration("GAME_RESULT_PRUNE_INTERVAL", cfg.ResultPruneInterval)
+	cfg.ResultCompactInterval = envDuration("GAME_RESULT_COMPACT_INTERVAL", cfg.ResultCompactInterval)
+	cfg.UsersKey = envString("GAME_USERS_KEY", cfg.UsersKey)
+	cfg.UsersKeyFile = envString("GAME_USERS_KEY_FILE", cfg.UsersKeyFile)
+	cfg.BotDifficulty = envString("GAME_BOT_DIFFICULTY", cfg.BotDifficulty)
+	cfg.CacheSize = envInt("GAME_CACHE_SIZE", cfg.CacheSize)
+	cfg.BackupInterval = envDuration("GAME_BACKUP_INTERVAL", cfg.BackupInterval)
+	cfg.BackupKeep = envInt("GAME_BACKUP_KEEP", cfg.BackupKeep)
+	cfg.RepairInterval = envDuration("GAME_REPAIR_INTERVAL", cfg.RepairInterval)
+	cfg.RepairOfflineAfter = envDuration("GAME_REPAIR_OFFLINE_AFTER", cfg.RepairOfflineAfter)
+	cfg.Addr = envString("GAME_ADDR", cfg.Addr)
+	cfg.Seed = int64(envInt("GAME_SEED", int(cfg.Seed)))
+	cfg.ChaosDelay = envDuration("GAME_CHAOS_DELAY", cfg.ChaosDelay)
+	cfg.ChaosJitter = envDuration("GAME_CHAOS_JITTER", cfg.ChaosJitter)
+	cfg.ChaosDropRate = envFloat("GAME_CHAOS_DROP_RATE", cfg.ChaosDropRate)
+	cfg.ChaosReorderRate = envFloat("GAME_CHAOS_REORDER_RATE", cfg.ChaosReorderRate)
+	cfg.ChaosUsers = envList("GAME_CHAOS_USERS", cfg.ChaosUsers)
+	cfg.RecordFile = envString("GAME_RECORD_FILE", cfg.RecordFile)
+	cfg.ReconnectGrace = envDuration("GAME_RECONNECT_GRACE", cfg.ReconnectGrace)
+	cfg.InviteTTL = envDuration("GAME_INVITE_TTL", cfg.InviteTTL)
+	cfg.ChallengeTTL = envDuration("GAME_CHALLENGE_TTL", cfg.ChallengeTTL)
+	cfg.ChallengeRange = envInt("GAME_CHALLENGE_RANGE", cfg.ChallengeRange)
+	cfg.RoomCreateKeyTTL = envDuration("GAME_ROOM_CREATE_KEY_TTL", cfg.RoomCreateKeyTTL)
+	cfg.RoomSwitchMode = envString("GAME_ROOM_SWITCH_MODE", cfg.RoomSwitchMode)
+	cfg.SpectatorChatAfterMatch = envBool("GAME_SPECTATOR_CHAT_AFTER_MATCH", cfg.SpectatorChatAfterMatch)
+	cfg.SpectatorDelay = envDuration("GAME_SPECTATOR_DELAY", cfg.SpectatorDelay)
+	cfg.RoomKeys = envBool("GAME_ROOM_KEYS", cfg.RoomKeys)
+	cfg.PracticeDuration = envDuration("GAME_PRACTICE_DURATION", cfg.PracticeDuration)
+	cfg.PracticeTargets = envInt("GAME_PRACTICE_TARGETS", cfg.PracticeTargets)
+	cfg.TournamentScheduleInterval = envDuration("GAME_TOURNAMENT_SCHEDULE_INTERVAL", cfg.TournamentScheduleInterval)
+	cfg.TournamentNoticeLead = envDuration("GAME_TOURNAMENT_NOTICE_LEAD", cfg.TournamentNoticeLead)
+	cfg.Regions = envList("GAME_REGIONS", cfg.Regions)
+	cfg.MatchRegionWiden = envDuration("GAME_MATCH_REGION_WIDEN", cfg.MatchRegionWiden)
+	cfg.MatchAcceptTimeout = envDuration("GAME_MATCH_ACCEPT_TIMEOUT", cfg.MatchAcceptTimeout)
+	cfg.MatchDeclineCooldown = envDuration("GAME_MATCH_DECLINE_COOLDOWN", cfg.MatchDeclineCooldown)
+	cfg.LeaverCooldown = envDuration("GAME_LEAVER_COOLDOWN", cfg.LeaverCooldown)
+	cfg.VoteKickWindow = envDuration("GAME_VOTE_KICK_WINDOW", cfg.VoteKickWindow)
+	cfg.MatchVoteWindow = envDuration("GAME_MATCH_VOTE_WINDOW", cfg.MatchVoteWindow)
+	cfg.DisputeWindow = envDuration("GAME_DISPUTE_WINDOW", cfg.DisputeWindow)
+	cfg.CheatFlagThreshold = envInt("GAME_CHEAT_FLAG_THRESHOLD", cfg.CheatFlagThreshold)
+	cfg.MatchBotBackfill = envDuration("GAME_MATCH_BOT_BACKFILL", cfg.MatchBotBackfill)
+	cfg.MatchBotAuto = envBool("GAME_MATCH_BOT_AUTO", cfg.MatchBotAuto)
+	cfg.MovementValidation = envBool("GAME_MOVEMENT_VALIDATION", cfg.MovementValidation)
+	cfg.TickRate = envInt("GAME_TICK_RATE", cfg.TickRate)
+	cfg.SendRate = envInt("GAME_SEND_RATE", cfg.SendRate)
+	cfg.ModeTickRates = envIntMap("GAME_MODE_TICK_RATES", cfg.ModeTickRates)
+	cfg.ModeSendRates = envIntMap("GAME_MODE_SEND_RATES", cfg.ModeSendRates)
+	cfg.AdaptiveSendRate = envBool("GAME_ADAPTIVE_SEND_RATE", cfg.AdaptiveSendRate)
+	cfg.RedisAddr = envString("GAME_REDIS_ADDR", cfg.RedisAddr)
+	cfg.RedisPassword = envString("GAME_REDIS_PASSWORD", cfg.RedisPassword)
+	cfg.InstanceID = envString("GAME_INSTANCE_ID", cfg.InstanceID)
+	cfg.InstanceAddr = envString("GAME_INSTANCE_ADDR", cfg.InstanceAddr)
+	cfg.ServerName = envString("GAME_SERVER_NAME", cfg.ServerName)
+	cfg.PublicAddr = envString("GAME_PUBLIC_ADDR", cfg.PublicAddr)
+	cfg.MasterServerURL = envString("GAME_MASTER_URL", cfg.MasterServerURL)
+	cfg.AnnounceInterval = envDuration("GAME_ANNOUNCE_INTERVAL", cfg.AnnounceInterval)
+	cfg.AnalyticsSampleInterval = envDuration("GAME_ANALYTICS_SAMPLE_INTERVAL", cfg.AnalyticsSampleInterval)
+	cfg.SlowThreshold = envDuration("GAME_SLOW_THRESHOLD", cfg.SlowThreshold)
+	cfg.PasswordMinLength = envInt("GAME_PASSWORD_MIN_LENGTH", cfg.PasswordMinLength)
+	cfg.PasswordMinClasses = envInt("GAME_PASSWORD_MIN_CLASSES", cfg.PasswordMinClasses)
+	cfg.PasswordRejectCommon = envBool("GAME_PASSWORD_REJECT_COMMON", cfg.PasswordRejectCommon)
+	cfg.PasswordBlacklistFile = envString("GAME_PASSWORD_BLACKLIST_FILE", cfg.PasswordBlacklistFile)
+	cfg.WordListFile = envString("GAME_WORD_LIST_FILE", cfg.WordListFile)
+	cfg.ChatFilterMode = envString("GAME_CHAT_FILTER_MODE", cfg.ChatFilterMode)
+	cfg.AuthCallbackURL = envString("GAME_AUTH_CALLBACK_URL", cfg.AuthCallbackURL)
+	cfg.SteamLogin = envBool("GAME_STEAM_LOGIN", cfg.SteamLogin)
+	cfg.OAuthName = envString("GAME_OAUTH_NAME", cfg.OAuthName)
+	cfg.OAuthClientID = envString("GAME_OAUTH_CLIENT_ID", cfg.OAuthClientID)
+	cfg.OAuthClientSecret = envString("GAME_OAUTH_CLIENT_SECRET", cfg.OAuthClientSecret)
+	cfg.OAuthAuthURL = envString("GAME_OAUTH_AUTH_URL", cfg.OAuthAuthURL)
+	cfg.OAuthTokenURL = envString("GAME_OAUTH_TOKEN_URL", cfg.OAuthTokenURL)
+	cfg.OAuthUserInfoURL = envString("GAME_OAUTH_USERINFO_URL", cfg.OAuthUserInfoURL)
+	cfg.OAuthScopes = envList("GAME_OAUTH_SCOPES", cfg.OAuthScopes)
+	cfg.SMTPAddr = envString("GAME_SMTP_ADDR", cfg.SMTPAddr)
+	cfg.SMTPUsername = envString("GAME_SMTP_USERNAME", cfg.SMTPUsername)
+	cfg.SMTPPassword = envString("GAME_SMTP_PASSWORD", cfg.SMTPPassword)
+	cfg.MailFrom = envString("GAME_MAIL_FROM", cfg.MailFrom)
+
+	if cfg.Persistence == PersistenceNone {
+		// 内存模式下没有可归档或备份的文件
+		cfg.ResultArchive = false
+		cfg.BackupInterval = 0
+	}
+	return cfg
+}
+
+// InMemory 是否使用纯内存存储
+func (c *Config) InMemory() bool {
+	return c.Persistence == PersistenceNone
+}
+
+// ChaosEnabled 是否启用了任何混沌注入
+func (c *Config) ChaosEnabled() bool {
+	return c.ChaosDelay > 0 || c.ChaosJitter > 0 || c.ChaosDropRate > 0 || c.ChaosReorderRate > 0
+}
+
+// envString 读取字符串环境变量
+func envString(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
+
+// envBool 读取布尔环境变量，格式错误时使用默认值
+func envBool(key string, def bool) bool {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("配置 %s 格式错误: %v，使用默认值 %t", key, err, def)
+		return def
+	}
+	return b
+}
+
+// envInt 读取整数环境变量，格式错误时使用默认值
+func envInt(key string, def int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("配置 %s 格式错误: %v，使用默认值 %d", key, err, def)
+		return def
+	}
+	return n
+}
+
+// envFloat 读取浮点数环境变量，格式错误时使用默认值
+func envFloat(key string, def float64) float64 {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("配置 %s 格式错误: %v，使用默认值 %g", key, err, def)
+		return def
+	}
+	return f
+}
+
+// envList 读取逗号分隔的列表环境变量，忽略空项
+func envList(key string, def []string) []string {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// envIntMap 读取 key=数字 逗号分隔的环境变量，格式错误的项会被忽略
+func envIntMap(key string, def map[string]int) map[string]int {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	m := make(map[string]int)
+	for _, item := range strings.Split(v, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found {
+			if item = strings.TrimSpace(item); item != "" {
+				log.Printf("配置 %s 的项 %q 格式错误，已忽略", key, item)
+			}
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			log.Printf("配置 %s 的项 %q 格式错误: %v，已忽略", key, item, err)
+			continue
+		}
+		m[strings.TrimSpace(name)] = n
+	}
+	return m
+}
+
+// envStringMap 读取 key=value 逗号分隔的环境变量，格式错误或值为空的项会被忽略
+func envStringMap(key string, def map[string]string) map[string]string {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	m := make(map[string]string)
+	for _, item := range strings.Split(v, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found || strings.TrimSpace(name) == "" {
+			if item = strings.TrimSpace(item); item != "" {
+				log.Printf("配置 %s 的项格式错误，已忽略", key)
+			}
+			continue
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if value == "" {
+			log.Printf("配置 %s 的项 %s 值为空，已忽略", key, name)
+			continue
+		}
+		m[name] = value
+	}
+	return m
+}
+
+// envDuration 读取时长环境变量（如 30s、10m），格式错误时使用默认值
+func envDuration(key string, def time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("配置 %s 格式错误: %v，使用默认值 %s", key, err, def)
+		return def
+	}
+	return d
+}
+
+// UsersSecret 返回 users.json 的加密口令，优先使用环境变量，其次读取密钥文件
+func (c *Config) UsersSecret() (string, error) {
+	if c.UsersKey != "" {
+		return c.UsersKey, nil
+	}
+	if c.UsersKeyFile == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(c.UsersKeyFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
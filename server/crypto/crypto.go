@@ -3,10 +3,14 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // Crypto 提供数据传输加密解密功能，这里是对称加密功能
@@ -35,7 +39,61 @@ func GetKey() []byte {
 
 // 加密逻辑
 func Encrypt(plaintext string) (string, error) {
-	block, err := aes.NewCipher(encryptionKey)
+	return EncryptWithKey(encryptionKey, plaintext)
+}
+
+// 解密逻辑
+func Decrypt(encryptedBase64 string) (string, error) {
+	return DecryptWithKey(encryptionKey, encryptedBase64)
+}
+
+// DeriveKey 将任意长度的口令派生为 32 字节的 AES-256 密钥
+func DeriveKey(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// DeriveRoomKey 由主密钥为房间派生 32 字节的会话密钥（HMAC-SHA256），
+// salt 每局随机生成，purpose 区分同一房间的不同密钥（如玩家密钥和观战者密钥）
+func DeriveRoomKey(master []byte, roomID string, salt []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, master)
+	mac.Write([]byte(roomID))
+	mac.Write([]byte{0})
+	mac.Write(salt)
+	mac.Write([]byte{0})
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// SealFrame 使用房间密钥加密一帧，返回 "<keyID>.<Base64(nonce+密文)>"，
+// keyID 不能包含 "."，客户端据此区分房间帧和连接密钥加密的消息
+func SealFrame(keyID string, key []byte, frame []byte) (string, error) {
+	sealed, err := EncryptWithKey(key, string(frame))
+	if err != nil {
+		return "", err
+	}
+	return keyID + "." + sealed, nil
+}
+
+// SignRequest 计算服务间 HTTP 请求的签名：对 方法、路径（含查询串）、时间戳、nonce 和请求体的 SHA-256
+// 逐行拼接后做 HMAC-SHA256，返回十六进制字符串。调用方和服务器使用同一函数，避免规范化方式不一致
+func SignRequest(secret []byte, method, path, timestamp, nonce string, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodySum[:]),
+	}, "\n")
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EncryptWithKey 使用指定密钥进行 AES-GCM 加密，返回 Base64 编码的 nonce+密文
+func EncryptWithKey(key []byte, plaintext string) (string, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", fmt.Errorf("创建加密块失败: %v", err)
 	}
@@ -54,14 +112,14 @@ func Encrypt(plaintext string) (string, error) {
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// 解密逻辑
-func Decrypt(encryptedBase64 string) (string, error) {
+// DecryptWithKey 使用指定密钥解密 EncryptWithKey 的输出
+func DecryptWithKey(key []byte, encryptedBase64 string) (string, error) {
 	ciphertext, err := base64.StdEncoding.DecodeString(encryptedBase64)
 	if err != nil {
 		return "", fmt.Errorf("Base64解码失败: %v", err)
 	}
 
-	block, err := aes.NewCipher(encryptionKey)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", fmt.Errorf("创建解密块失败: %v", err)
 	}
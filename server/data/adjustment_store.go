@@ -0,0 +1,116 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// AdjustmentStore 评分补偿记录存储，按写入顺序保存，file 为空时为纯内存存储
+type AdjustmentStore struct {
+	mu          sync.RWMutex
+	adjustments []models.RatingAdjustment
+	totals      map[string]int // 每名用户的补偿合计，按用户ID索引
+	file        string
+}
+
+// NewAdjustmentStore 创建保存到 rating_adjustments.json 的评分补偿存储
+func NewAdjustmentStore() *AdjustmentStore {
+	ensureDataDir()
+	s := &AdjustmentStore{
+		totals: make(map[string]int),
+		file:   filepath.Join(DataDir, "rating_adjustments.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewAdjustmentStoreInMemory 创建不读写文件的评分补偿存储
+func NewAdjustmentStoreInMemory() *AdjustmentStore {
+	return &AdjustmentStore{totals: make(map[string]int)}
+}
+
+func (s *AdjustmentStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载评分补偿失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.RatingAdjustmentsData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析评分补偿失败: %v\n", err)
+		return
+	}
+	s.adjustments = stored.Adjustments
+	for _, a := range s.adjustments {
+		s.totals[a.UserID] += a.Delta
+	}
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *AdjustmentStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "rating_adjustments", time.Now(), nil)
+	content, err := json.MarshalIndent(models.RatingAdjustmentsData{Adjustments: s.adjustments}, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化评分补偿失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存评分补偿失败: %v\n", err)
+	}
+}
+
+// Add 写入一局结果的评分补偿。每局结果只补偿一次，已有该结果的补偿时不写入并返回 false
+func (s *AdjustmentStore) Add(resultID string, adjustments []models.RatingAdjustment) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.ContainsFunc(s.adjustments, func(a models.RatingAdjustment) bool { return a.ResultID == resultID }) {
+		return false
+	}
+	for _, a := range adjustments {
+		a.ResultID = resultID
+		s.adjustments = append(s.adjustments, a)
+		s.totals[a.UserID] += a.Delta
+	}
+	s.save()
+	return true
+}
+
+// Total 返回用户的评分补偿合计
+func (s *AdjustmentStore) Total(userID string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.totals[userID]
+}
+
+// RemoveUser 删除用户的全部评分补偿，注销账号时调用，返回删除的数量
+func (s *AdjustmentStore) RemoveUser(userID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.adjustments[:0]
+	for _, a := range s.adjustments {
+		if a.UserID != userID {
+			kept = append(kept, a)
+		}
+	}
+	removed := len(s.adjustments) - len(kept)
+	if removed > 0 {
+		clear(s.adjustments[len(kept):])
+		s.adjustments = kept
+		delete(s.totals, userID)
+		s.save()
+	}
+	return removed
+}
@@ -0,0 +1,140 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// analyticsDateLayout 统计按天汇总使用的日期格式
+const analyticsDateLayout = "2006-01-02"
+
+// AnalyticsStore 运营统计存储：登录事件和在线连接数采样按天汇总，file 为空时为纯内存存储
+type AnalyticsStore struct {
+	mu   sync.RWMutex
+	days map[string]*models.DailyAnalytics
+	file string
+}
+
+// NewAnalyticsStore 创建保存到 analytics.json 的统计存储
+func NewAnalyticsStore() *AnalyticsStore {
+	ensureDataDir()
+	s := &AnalyticsStore{
+		days: make(map[string]*models.DailyAnalytics),
+		file: filepath.Join(DataDir, "analytics.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewAnalyticsStoreInMemory 创建不读写文件的统计存储
+func NewAnalyticsStoreInMemory() *AnalyticsStore {
+	return &AnalyticsStore{days: make(map[string]*models.DailyAnalytics)}
+}
+
+func (s *AnalyticsStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载统计数据失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.AnalyticsData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析统计数据失败: %v\n", err)
+		return
+	}
+	for i := range stored.Days {
+		day := stored.Days[i]
+		s.days[day.Date] = &day
+	}
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *AnalyticsStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "analytics", time.Now(), nil)
+	stored := models.AnalyticsData{Days: s.sorted()}
+	content, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化统计数据失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存统计数据失败: %v\n", err)
+	}
+}
+
+// day 返回 at 所在日期的统计，不存在时创建，调用方需持有写锁
+func (s *AnalyticsStore) day(at time.Time) *models.DailyAnalytics {
+	date := at.Format(analyticsDateLayout)
+	d, ok := s.days[date]
+	if !ok {
+		d = &models.DailyAnalytics{Date: date, ActiveUsers: make([]string, 0)}
+		s.days[date] = d
+	}
+	return d
+}
+
+// RecordLogin 记录一次登录
+func (s *AnalyticsStore) RecordLogin(username string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.day(at)
+	d.Logins++
+	if !slices.Contains(d.ActiveUsers, username) {
+		d.ActiveUsers = append(d.ActiveUsers, username)
+	}
+	s.save()
+}
+
+// RecordConcurrency 记录一次在线连接数采样
+func (s *AnalyticsStore) RecordConcurrency(connections int, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.day(at)
+	d.Samples++
+	d.ConcurrentSum += connections
+	if connections > d.PeakConcurrent || d.PeakAt.IsZero() {
+		d.PeakConcurrent = connections
+		d.PeakAt = at
+	}
+	s.save()
+}
+
+// Since 返回 since 所在日期及之后的每日统计，按日期升序
+func (s *AnalyticsStore) Since(since time.Time) []models.DailyAnalytics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	from := since.Format(analyticsDateLayout)
+	list := make([]models.DailyAnalytics, 0)
+	for _, d := range s.sorted() {
+		if d.Date >= from {
+			list = append(list, d)
+		}
+	}
+	return list
+}
+
+// sorted 返回按日期升序排列的统计副本，调用方需持有锁
+func (s *AnalyticsStore) sorted() []models.DailyAnalytics {
+	list := make([]models.DailyAnalytics, 0, len(s.days))
+	for _, d := range s.days {
+		day := *d
+		day.ActiveUsers = slices.Clone(d.ActiveUsers)
+		list = append(list, day)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Date < list[j].Date })
+	return list
+}
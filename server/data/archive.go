@@ -0,0 +1,64 @@
+package data
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"game/models"
+)
+
+// ArchiveDir 归档文件目录
+func ArchiveDir() string {
+	return filepath.Join(DataDir, "archive")
+}
+
+// ArchiveResults 按对局月份将结果追加写入压缩归档文件 archive/results-YYYY-MM.jsonl.gz
+// 每次追加写入一个独立的 gzip 成员，标准 gzip 读取器会自动连续解压
+func ArchiveResults(results []models.GameResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	ensureDataDir()
+	if err := os.MkdirAll(ArchiveDir(), 0755); err != nil {
+		return fmt.Errorf("创建归档目录失败: %v", err)
+	}
+
+	byMonth := make(map[string][]models.GameResult)
+	for _, r := range results {
+		month := r.PlayTime.Format("2006-01")
+		byMonth[month] = append(byMonth[month], r)
+	}
+
+	for month, group := range byMonth {
+		file := filepath.Join(ArchiveDir(), "results-"+month+".jsonl.gz")
+		if err := appendGzipLines(file, group); err != nil {
+			return fmt.Errorf("写入归档 %s 失败: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// appendGzipLines 以 JSONL 格式压缩追加写入一组结果
+func appendGzipLines(file string, results []models.GameResult) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			zw.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
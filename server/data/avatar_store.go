@@ -0,0 +1,107 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"game/report"
+)
+
+// ErrAvatarNotFound 用户没有上传过头像
+var ErrAvatarNotFound = errors.New("头像不存在")
+
+// AvatarStore 用户头像存储，按用户ID保存处理后的 PNG 图片。默认实现保存在数据目录下，
+// 换用对象存储等外部存储时只需实现该接口
+type AvatarStore interface {
+	Put(userID string, content []byte) error
+	// Get 返回用户的头像，没有头像时返回 ErrAvatarNotFound
+	Get(userID string) ([]byte, error)
+	// Delete 删除用户的头像，没有头像时不报错
+	Delete(userID string) error
+}
+
+// fileAvatarStore 把每个头像保存为 avatars 目录下的一个文件
+type fileAvatarStore struct {
+	dir string
+}
+
+// NewAvatarStore 创建保存到数据目录下 avatars 目录的头像存储
+func NewAvatarStore() AvatarStore {
+	ensureDataDir()
+	dir := filepath.Join(DataDir, "avatars")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("创建头像目录失败: %v\n", err)
+	}
+	return &fileAvatarStore{dir: dir}
+}
+
+// path 返回用户头像的文件路径，用户ID由服务器生成，只包含十六进制字符
+func (s *fileAvatarStore) path(userID string) string {
+	return filepath.Join(s.dir, filepath.Base(userID)+".png")
+}
+
+// Put 保存用户的头像，覆盖旧头像
+func (s *fileAvatarStore) Put(userID string, content []byte) error {
+	defer report.Track(report.SlowStore, "avatars", time.Now(), map[string]string{"user_id": userID})
+	return writeFileAtomic(s.path(userID), content, 0644)
+}
+
+// Get 读取用户的头像
+func (s *fileAvatarStore) Get(userID string) ([]byte, error) {
+	content, err := os.ReadFile(s.path(userID))
+	if os.IsNotExist(err) {
+		return nil, ErrAvatarNotFound
+	}
+	return content, err
+}
+
+// Delete 删除用户的头像文件
+func (s *fileAvatarStore) Delete(userID string) error {
+	if err := os.Remove(s.path(userID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// memoryAvatarStore 只保存在内存中的头像存储
+type memoryAvatarStore struct {
+	mu      sync.RWMutex
+	avatars map[string][]byte
+}
+
+// NewAvatarStoreInMemory 创建不读写文件的头像存储
+func NewAvatarStoreInMemory() AvatarStore {
+	return &memoryAvatarStore{avatars: make(map[string][]byte)}
+}
+
+// Put 保存用户的头像
+func (s *memoryAvatarStore) Put(userID string, content []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.avatars[userID] = slices.Clone(content)
+	return nil
+}
+
+// Get 返回用户的头像
+func (s *memoryAvatarStore) Get(userID string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	content, ok := s.avatars[userID]
+	if !ok {
+		return nil, ErrAvatarNotFound
+	}
+	return slices.Clone(content), nil
+}
+
+// Delete 删除用户的头像
+func (s *memoryAvatarStore) Delete(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.avatars, userID)
+	return nil
+}
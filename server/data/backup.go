@@ -0,0 +1,301 @@
+package data
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"game/models"
+)
+
+// backupFiles 备份中包含的数据文件，恢复时只会写回这些文件。
+// 登录会话只保存在内存中，没有数据文件；heroes.json、bots.json 是运营维护的配置，不在备份范围内
+var backupFiles = append([]string{"users.json", "rooms.json", "game_results.jsonl"}, standaloneBackupFiles...)
+
+// standaloneBackupFiles 其余存储的数据文件，这些存储都以原子替换的方式整文件写入，运行中直接读取磁盘文件也是完整的
+var standaloneBackupFiles = []string{
+	"refresh_tokens.json", "login_history.json", "invites.json", "cheat_flags.json",
+	"tournaments.json", "ladder.json", "challenges.json", "events.json", "playlist.json",
+	"disputes.json", "rating_adjustments.json", "practice.json", "analytics.json", "banned_words.json",
+}
+
+const backupTimeLayout = "20060102-150405"
+
+// snapshotter 能够在一致状态下读取自身数据文件的存储
+type snapshotter interface {
+	snapshot() (name string, content []byte, err error)
+}
+
+// fileSnapshot 直接读取磁盘文件，用于服务器未运行时的离线备份
+type fileSnapshot string
+
+func (f fileSnapshot) snapshot() (string, []byte, error) {
+	if f == "" {
+		return "", nil, fmt.Errorf("内存存储没有可备份的数据文件")
+	}
+	content, err := os.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return filepath.Base(string(f)), nil, nil
+	}
+	return filepath.Base(string(f)), content, err
+}
+
+// BackupDir 备份文件目录
+func BackupDir() string {
+	return filepath.Join(DataDir, "backups")
+}
+
+// BackupManager 将数据文件打包为带时间戳的 backups/backup-YYYYMMDD-HHMMSS.tar.gz，并只保留最近 keep 份
+type BackupManager struct {
+	mu      sync.Mutex
+	keep    int
+	sources []snapshotter
+}
+
+// NewBackupManager 创建运行中服务器使用的备份管理器，用户、房间和游戏结果在对应存储的锁内读取
+func NewBackupManager(keep int, users *UserStore, rooms *RoomStore, results *ResultStore) *BackupManager {
+	sources := []snapshotter{users, rooms, results}
+	for _, name := range standaloneBackupFiles {
+		sources = append(sources, fileSnapshot(filepath.Join(DataDir, name)))
+	}
+	return &BackupManager{keep: keep, sources: sources}
+}
+
+// NewFileBackupManager 创建直接读取磁盘文件的备份管理器，只能在持有数据目录锁时使用
+func NewFileBackupManager(keep int) *BackupManager {
+	sources := make([]snapshotter, 0, len(backupFiles))
+	for _, name := range backupFiles {
+		sources = append(sources, fileSnapshot(filepath.Join(DataDir, name)))
+	}
+	return &BackupManager{keep: keep, sources: sources}
+}
+
+// Create 创建一份备份并按保留份数清理旧备份
+func (m *BackupManager) Create() (models.BackupInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(BackupDir(), 0700); err != nil {
+		return models.BackupInfo{}, fmt.Errorf("创建备份目录失败: %v", err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	now := time.Now()
+	for _, src := range m.sources {
+		name, content, err := src.snapshot()
+		if err != nil {
+			return models.BackupInfo{}, fmt.Errorf("读取 %s 失败: %v", name, err)
+		}
+		if content == nil {
+			continue
+		}
+		hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return models.BackupInfo{}, err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return models.BackupInfo{}, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return models.BackupInfo{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return models.BackupInfo{}, err
+	}
+
+	name := "backup-" + now.Format(backupTimeLayout) + ".tar.gz"
+	path := filepath.Join(BackupDir(), name)
+	// 同一秒内重复备份时追加序号，避免覆盖
+	for i := 1; fileExists(path); i++ {
+		name = fmt.Sprintf("backup-%s-%d.tar.gz", now.Format(backupTimeLayout), i)
+		path = filepath.Join(BackupDir(), name)
+	}
+	if err := writeFileAtomic(path, buf.Bytes(), 0600); err != nil {
+		return models.BackupInfo{}, fmt.Errorf("写入备份失败: %v", err)
+	}
+
+	if err := m.rotate(); err != nil {
+		fmt.Printf("清理旧备份失败: %v\n", err)
+	}
+	return models.BackupInfo{Name: name, Size: int64(buf.Len()), CreatedAt: now}, nil
+}
+
+// rotate 删除超出保留份数的最旧备份，keep 为 0 时不清理
+func (m *BackupManager) rotate() error {
+	if m.keep <= 0 {
+		return nil
+	}
+	backups, err := ListBackups()
+	if err != nil {
+		return err
+	}
+	for i := m.keep; i < len(backups); i++ {
+		if err := os.Remove(filepath.Join(BackupDir(), backups[i].Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListBackups 列出所有备份，最新的在前
+func ListBackups() ([]models.BackupInfo, error) {
+	entries, err := os.ReadDir(BackupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []models.BackupInfo{}, nil
+		}
+		return nil, err
+	}
+	backups := make([]models.BackupInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !isBackupName(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, models.BackupInfo{
+			Name:      e.Name(),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// RestoreBackup 用指定备份覆盖数据文件，恢复前会先备份当前数据
+// 存储只在启动时读取文件，因此恢复只能在服务器停止、由当前进程持有数据目录锁时执行
+func RestoreBackup(name string, keep int) (models.BackupInfo, error) {
+	if lockFile == nil {
+		return models.BackupInfo{}, fmt.Errorf("恢复备份前必须先锁定数据目录")
+	}
+	if !isBackupName(name) {
+		return models.BackupInfo{}, fmt.Errorf("无效的备份名称: %s", name)
+	}
+
+	files, err := readBackup(filepath.Join(BackupDir(), name))
+	if err != nil {
+		return models.BackupInfo{}, fmt.Errorf("读取备份 %s 失败: %v", name, err)
+	}
+
+	previous, err := NewFileBackupManager(keep).Create()
+	if err != nil {
+		return models.BackupInfo{}, fmt.Errorf("备份当前数据失败，已取消恢复: %v", err)
+	}
+
+	for _, file := range backupFiles {
+		path := filepath.Join(DataDir, file)
+		content, ok := files[file]
+		if !ok {
+			// 备份时该文件不存在，恢复后也不应保留
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return previous, err
+			}
+			continue
+		}
+		if err := writeFileAtomic(path, content, 0600); err != nil {
+			return previous, fmt.Errorf("恢复 %s 失败: %v", file, err)
+		}
+	}
+	return previous, nil
+}
+
+// readBackup 读取备份中的数据文件，忽略不认识的条目
+func readBackup(path string) (map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !isBackupFile(hdr.Name) {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = content
+	}
+	return files, nil
+}
+
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, "backup-") && strings.HasSuffix(name, ".tar.gz") &&
+		filepath.Base(name) == name
+}
+
+func isBackupFile(name string) bool {
+	for _, f := range backupFiles {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// writeFileAtomic 先写临时文件再替换，避免中途失败留下半个文件
+func writeFileAtomic(path string, content []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// snapshot 在锁内读取 users.json
+func (s *UserStore) snapshot() (string, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fileSnapshot(s.file).snapshot()
+}
+
+// snapshot 在锁内读取 rooms.json
+func (s *RoomStore) snapshot() (string, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fileSnapshot(s.file).snapshot()
+}
+
+// snapshot 在锁内读取游戏结果日志
+func (s *ResultStore) snapshot() (string, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fileSnapshot(s.file).snapshot()
+}
@@ -0,0 +1,114 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// balanceVersion 平衡版本号的格式，版本号同时是 balance 目录下的文件名
+var balanceVersion = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+var (
+	// ErrBalanceVersionRequired 推送的平衡配置没有填写版本号
+	ErrBalanceVersionRequired = errors.New("平衡配置必须填写版本号")
+	// ErrBalanceVersionExists 版本号已被使用，已发布的版本不能修改
+	ErrBalanceVersionExists = errors.New("平衡版本已存在")
+)
+
+// BalanceStore 管理当前生效的平衡版本。每个推送的版本保存为 balance 目录下的 <版本号>.json，
+// heroes.json 始终是当前版本；替换版本后只有之后开局的对局使用新配置，进行中的对局保留开局时的版本。
+// dir 为空时为纯内存存储，推送的版本不写入文件
+type BalanceStore struct {
+	mu      sync.RWMutex
+	current *HeroRoster
+	dir     string
+}
+
+// NewBalanceStore 创建平衡版本存储，current 为启动时从 heroes.json 加载的版本
+func NewBalanceStore(current *HeroRoster) *BalanceStore {
+	return &BalanceStore{current: current, dir: filepath.Join(DataDir, "balance")}
+}
+
+// NewBalanceStoreInMemory 创建不读写文件的平衡版本存储
+func NewBalanceStoreInMemory(current *HeroRoster) *BalanceStore {
+	return &BalanceStore{current: current}
+}
+
+// Current 返回当前生效的版本
+func (s *BalanceStore) Current() *HeroRoster {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Push 校验并发布新的平衡版本，成功后立即成为当前版本。版本号必须填写且未被使用过
+func (s *BalanceStore) Push(content []byte) (*HeroRoster, error) {
+	roster, err := ParseHeroRoster(content)
+	if err != nil {
+		return nil, err
+	}
+	if roster.Version() == DefaultBalanceVersion {
+		return nil, ErrBalanceVersionRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if roster.Version() == s.current.Version() {
+		return nil, ErrBalanceVersionExists
+	}
+	if s.dir != "" {
+		ensureDataDir()
+		if err := os.MkdirAll(s.dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建平衡版本目录失败: %v", err)
+		}
+		file := filepath.Join(s.dir, roster.Version()+".json")
+		if _, err := os.Stat(file); err == nil {
+			return nil, ErrBalanceVersionExists
+		}
+		if err := writeFileAtomic(file, content, 0644); err != nil {
+			return nil, fmt.Errorf("保存平衡版本失败: %v", err)
+		}
+		if err := writeFileAtomic(filepath.Join(DataDir, "heroes.json"), content, 0644); err != nil {
+			return nil, fmt.Errorf("更新 heroes.json 失败: %v", err)
+		}
+	}
+	s.current = roster
+	return roster, nil
+}
+
+// Reload 重新读取 heroes.json 作为当前版本，文件无效时保留当前版本并返回错误
+func (s *BalanceStore) Reload() (*HeroRoster, error) {
+	roster, err := LoadHeroRoster()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.current = roster
+	s.mu.Unlock()
+	return roster, nil
+}
+
+// Versions 返回已发布的版本号，按名称排序
+func (s *BalanceStore) Versions() []string {
+	if s.dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil
+	}
+	var versions []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			versions = append(versions, name)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
@@ -0,0 +1,120 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"game/models"
+)
+
+// botProfileID 机器人难度ID的格式，ID 会编码进机器人的用户名
+var botProfileID = regexp.MustCompile(`^[a-z0-9_]{1,16}$`)
+
+// defaultBotProfiles 内置机器人难度，bots.json 不存在时使用
+var defaultBotProfiles = []models.BotProfile{
+	{ID: "easy", Name: "简单", ReactionMs: 400, Accuracy: 0.25, Aggression: 0.2, MoveSpeed: 2},
+	{ID: "normal", Name: "普通", ReactionMs: 250, Accuracy: 0.625, Aggression: 0.6, MoveSpeed: 3.5},
+	{ID: "hard", Name: "困难", ReactionMs: 120, Accuracy: 0.875, Aggression: 0.9, MoveSpeed: 5},
+}
+
+// BotRoster 机器人难度配置，启动时从数据目录下的 bots.json 加载，可通过 Reload 热更新；
+// 已经开局的机器人保留开局时的配置
+type BotRoster struct {
+	mu       sync.RWMutex
+	profiles []models.BotProfile
+	byID     map[string]models.BotProfile
+}
+
+// NewBotRoster 用指定配置创建机器人难度表，profiles 为空时使用内置难度
+func NewBotRoster(profiles []models.BotProfile) *BotRoster {
+	r := &BotRoster{}
+	r.set(profiles)
+	return r
+}
+
+// LoadBotRoster 读取数据目录下的 bots.json，文件不存在时使用内置难度
+func LoadBotRoster() (*BotRoster, error) {
+	profiles, err := readBotProfiles()
+	if err != nil {
+		return nil, err
+	}
+	return NewBotRoster(profiles), nil
+}
+
+// Reload 重新读取 bots.json，文件无效时保留当前配置并返回错误
+func (r *BotRoster) Reload() error {
+	profiles, err := readBotProfiles()
+	if err != nil {
+		return err
+	}
+	r.set(profiles)
+	return nil
+}
+
+// set 替换全部难度配置
+func (r *BotRoster) set(profiles []models.BotProfile) {
+	if len(profiles) == 0 {
+		profiles = defaultBotProfiles
+	}
+	byID := make(map[string]models.BotProfile, len(profiles))
+	for _, p := range profiles {
+		byID[p.ID] = p
+	}
+	r.mu.Lock()
+	r.profiles, r.byID = profiles, byID
+	r.mu.Unlock()
+}
+
+// readBotProfiles 读取并校验 bots.json，文件不存在时返回 nil
+func readBotProfiles() ([]models.BotProfile, error) {
+	data, err := os.ReadFile(filepath.Join(DataDir, "bots.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var botsData models.BotsData
+	if err := json.Unmarshal(data, &botsData); err != nil {
+		return nil, fmt.Errorf("解析机器人数据失败: %v", err)
+	}
+	if len(botsData.Profiles) == 0 {
+		return nil, fmt.Errorf("机器人数据为空")
+	}
+	seen := make(map[string]bool, len(botsData.Profiles))
+	for _, p := range botsData.Profiles {
+		switch {
+		case p.ID == "" || seen[p.ID]:
+			return nil, fmt.Errorf("机器人难度ID为空或重复: %q", p.ID)
+		case !botProfileID.MatchString(p.ID):
+			return nil, fmt.Errorf("机器人难度ID只能包含小写字母、数字和下划线，最长 16 个字符: %q", p.ID)
+		case p.ReactionMs < 0:
+			return nil, fmt.Errorf("机器人难度 %s 的反应时间无效", p.ID)
+		case p.Accuracy < 0 || p.Accuracy > 1 || p.Aggression < 0 || p.Aggression > 1:
+			return nil, fmt.Errorf("机器人难度 %s 的准确度和攻击性必须在 0~1 之间", p.ID)
+		case p.MoveSpeed <= 0:
+			return nil, fmt.Errorf("机器人难度 %s 的移动速度必须大于 0", p.ID)
+		}
+		seen[p.ID] = true
+	}
+	return botsData.Profiles, nil
+}
+
+// All 返回全部难度，按数据文件中的顺序排列
+func (r *BotRoster) All() []models.BotProfile {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]models.BotProfile(nil), r.profiles...)
+}
+
+// Get 根据ID查找难度
+func (r *BotRoster) Get(id string) (models.BotProfile, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.byID[id]
+	return p, ok
+}
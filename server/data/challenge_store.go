@@ -0,0 +1,143 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// ChallengeStore 天梯挑战存储，按挑战ID索引，file 为空时为纯内存存储
+type ChallengeStore struct {
+	mu         sync.Mutex
+	challenges map[string]models.Challenge
+	file       string
+}
+
+// NewChallengeStore 创建保存到 challenges.json 的挑战存储
+func NewChallengeStore() *ChallengeStore {
+	ensureDataDir()
+	s := &ChallengeStore{
+		challenges: make(map[string]models.Challenge),
+		file:       filepath.Join(DataDir, "challenges.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewChallengeStoreInMemory 创建不读写文件的挑战存储
+func NewChallengeStoreInMemory() *ChallengeStore {
+	return &ChallengeStore{challenges: make(map[string]models.Challenge)}
+}
+
+func (s *ChallengeStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载天梯挑战失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.ChallengesData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析天梯挑战失败: %v\n", err)
+		return
+	}
+	for _, c := range stored.Challenges {
+		s.challenges[c.ID] = c
+	}
+}
+
+// save 清理过期挑战后写入文件，调用方需持有锁
+func (s *ChallengeStore) save() {
+	now := time.Now()
+	for id, c := range s.challenges {
+		if !now.Before(c.ExpiresAt) {
+			delete(s.challenges, id)
+		}
+	}
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "challenges", time.Now(), nil)
+	stored := models.ChallengesData{Challenges: make([]models.Challenge, 0, len(s.challenges))}
+	for _, c := range s.challenges {
+		stored.Challenges = append(stored.Challenges, c)
+	}
+	sort.Slice(stored.Challenges, func(i, j int) bool { return stored.Challenges[i].CreatedAt.Before(stored.Challenges[j].CreatedAt) })
+	content, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化天梯挑战失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存天梯挑战失败: %v\n", err)
+	}
+}
+
+// Add 保存一个新挑战，同一挑战方对同一玩家只保留最新的挑战
+func (s *ChallengeStore) Add(challenge models.Challenge) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, old := range s.challenges {
+		if old.FromID == challenge.FromID && old.ToID == challenge.ToID {
+			delete(s.challenges, id)
+		}
+	}
+	s.challenges[challenge.ID] = challenge
+	s.save()
+}
+
+// Take 取出发给用户ID为 toID 的用户的挑战，挑战不存在、不属于该用户或已过期时返回 nil；
+// 取出后挑战即被删除
+func (s *ChallengeStore) Take(id, toID string, now time.Time) *models.Challenge {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.challenges[id]
+	if !ok || c.ToID != toID {
+		return nil
+	}
+	delete(s.challenges, id)
+	s.save()
+	if !now.Before(c.ExpiresAt) {
+		return nil
+	}
+	return &c
+}
+
+// Pending 返回发给用户ID为 toID 的用户、尚未过期的挑战，按发出时间排列
+func (s *ChallengeStore) Pending(toID string, now time.Time) []models.Challenge {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	challenges := make([]models.Challenge, 0)
+	for _, c := range s.challenges {
+		if c.ToID == toID && now.Before(c.ExpiresAt) {
+			challenges = append(challenges, c)
+		}
+	}
+	sort.Slice(challenges, func(i, j int) bool { return challenges[i].CreatedAt.Before(challenges[j].CreatedAt) })
+	return challenges
+}
+
+// RemoveUser 删除用户发出或收到的全部挑战，注销账号时调用，返回删除的数量
+func (s *ChallengeStore) RemoveUser(userID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for id, c := range s.challenges {
+		if c.FromID == userID || c.ToID == userID {
+			delete(s.challenges, id)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.save()
+	}
+	return removed
+}
@@ -0,0 +1,128 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// CheatFlagStore 作弊标记存储，按稳定用户ID索引，file 为空时为纯内存存储
+type CheatFlagStore struct {
+	mu    sync.RWMutex
+	flags map[string]models.CheatFlag
+	file  string
+}
+
+// NewCheatFlagStore 创建保存到 cheat_flags.json 的作弊标记存储
+func NewCheatFlagStore() *CheatFlagStore {
+	ensureDataDir()
+	s := &CheatFlagStore{
+		flags: make(map[string]models.CheatFlag),
+		file:  filepath.Join(DataDir, "cheat_flags.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewCheatFlagStoreInMemory 创建不读写文件的作弊标记存储
+func NewCheatFlagStoreInMemory() *CheatFlagStore {
+	return &CheatFlagStore{flags: make(map[string]models.CheatFlag)}
+}
+
+func (s *CheatFlagStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载作弊标记失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.CheatFlagsData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析作弊标记失败: %v\n", err)
+		return
+	}
+	for _, flag := range stored.Flags {
+		s.flags[flag.UserID] = flag
+	}
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *CheatFlagStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "cheat_flags", time.Now(), nil)
+	stored := models.CheatFlagsData{Flags: make([]models.CheatFlag, 0, len(s.flags))}
+	for _, flag := range s.flags {
+		stored.Flags = append(stored.Flags, flag)
+	}
+	sort.Slice(stored.Flags, func(i, j int) bool { return stored.Flags[i].UserID < stored.Flags[j].UserID })
+	content, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化作弊标记失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存作弊标记失败: %v\n", err)
+	}
+}
+
+// Get 返回用户的作弊标记
+func (s *CheatFlagStore) Get(userID string) (models.CheatFlag, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flag, ok := s.flags[userID]
+	flag.Violations = maps.Clone(flag.Violations)
+	return flag, ok
+}
+
+// All 返回所有作弊标记，按用户ID排列
+func (s *CheatFlagStore) All() []models.CheatFlag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flags := make([]models.CheatFlag, 0, len(s.flags))
+	for _, flag := range s.flags {
+		flag.Violations = maps.Clone(flag.Violations)
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].UserID < flags[j].UserID })
+	return flags
+}
+
+// Modify 在写锁内修改用户的作弊标记并保存，记录不存在时 fn 收到只有用户ID的空记录；fn 返回 false 时不保存
+func (s *CheatFlagStore) Modify(userID string, fn func(flag *models.CheatFlag) bool) models.CheatFlag {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flag, ok := s.flags[userID]
+	if !ok {
+		flag = models.CheatFlag{UserID: userID}
+	}
+	flag.Violations = maps.Clone(flag.Violations)
+	if fn(&flag) {
+		s.flags[userID] = flag
+		s.save()
+	}
+	flag.Violations = maps.Clone(flag.Violations)
+	return flag
+}
+
+// Remove 删除用户的作弊标记，注销账号时调用，返回记录是否存在
+func (s *CheatFlagStore) Remove(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.flags[userID]; !ok {
+		return false
+	}
+	delete(s.flags, userID)
+	s.save()
+	return true
+}
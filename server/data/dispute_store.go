@@ -0,0 +1,184 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// DisputeStore 结果申诉存储，按申诉ID索引，file 为空时为纯内存存储
+type DisputeStore struct {
+	mu       sync.RWMutex
+	disputes map[string]models.Dispute
+	file     string
+}
+
+// NewDisputeStore 创建保存到 disputes.json 的申诉存储
+func NewDisputeStore() *DisputeStore {
+	ensureDataDir()
+	s := &DisputeStore{
+		disputes: make(map[string]models.Dispute),
+		file:     filepath.Join(DataDir, "disputes.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewDisputeStoreInMemory 创建不读写文件的申诉存储
+func NewDisputeStoreInMemory() *DisputeStore {
+	return &DisputeStore{disputes: make(map[string]models.Dispute)}
+}
+
+func (s *DisputeStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载申诉失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.DisputesData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析申诉失败: %v\n", err)
+		return
+	}
+	for _, d := range stored.Disputes {
+		s.disputes[d.ID] = d
+	}
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *DisputeStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "disputes", time.Now(), nil)
+	content, err := json.MarshalIndent(models.DisputesData{Disputes: s.sorted()}, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化申诉失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存申诉失败: %v\n", err)
+	}
+}
+
+// sorted 返回按提出时间排列的申诉副本，调用方需持有锁
+func (s *DisputeStore) sorted() []models.Dispute {
+	disputes := make([]models.Dispute, 0, len(s.disputes))
+	for _, d := range s.disputes {
+		disputes = append(disputes, d.Clone())
+	}
+	sort.Slice(disputes, func(i, j int) bool {
+		if !disputes[i].CreatedAt.Equal(disputes[j].CreatedAt) {
+			return disputes[i].CreatedAt.Before(disputes[j].CreatedAt)
+		}
+		return disputes[i].ID < disputes[j].ID
+	})
+	return disputes
+}
+
+// All 返回所有申诉，最早提出的在前
+func (s *DisputeStore) All() []models.Dispute {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted()
+}
+
+// Add 保存新申诉；同一用户已对同一结果提出过申诉时不保存并返回 false
+func (s *DisputeStore) Add(d models.Dispute) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.disputes {
+		if existing.ResultID == d.ResultID && existing.UserID == d.UserID {
+			return false
+		}
+	}
+	s.disputes[d.ID] = d.Clone()
+	s.save()
+	return true
+}
+
+// Modify 在写锁内修改申诉并保存，申诉不存在时返回 false；fn 返回 false 时不保存
+func (s *DisputeStore) Modify(id string, fn func(d *models.Dispute) bool) (models.Dispute, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.disputes[id]
+	if !ok {
+		return models.Dispute{}, false
+	}
+	d = d.Clone()
+	if fn(&d) {
+		s.disputes[id] = d.Clone()
+		s.save()
+	}
+	return d, true
+}
+
+// RemoveUser 删除用户提出的全部申诉；其他申诉中附带的对局结果把 names 中的用户名替换为 alias，
+// 并去掉该用户的违规记录、评分补偿和回放中该用户的入站记录。注销账号时调用，返回删除或修改的申诉数
+func (s *DisputeStore) RemoveUser(userID string, names []string, alias string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := 0
+	for id, d := range s.disputes {
+		if d.UserID == userID {
+			delete(s.disputes, id)
+			changed++
+			continue
+		}
+		d = d.Clone()
+		touched := false
+		for _, name := range names {
+			if renamePlayer(&d.Result, name, alias) {
+				touched = true
+			}
+		}
+		flags := d.Flags[:0]
+		for _, flag := range d.Flags {
+			if flag.UserID != userID {
+				flags = append(flags, flag)
+			}
+		}
+		if len(flags) < len(d.Flags) {
+			d.Flags = flags
+			touched = true
+		}
+		replay := d.Replay[:0]
+		for _, rec := range d.Replay {
+			if !slices.Contains(names, rec.Username) {
+				replay = append(replay, rec)
+			}
+		}
+		if len(replay) < len(d.Replay) {
+			d.Replay = replay
+			touched = true
+		}
+		adjustments := d.Adjustments[:0]
+		for _, a := range d.Adjustments {
+			if a.UserID != userID {
+				adjustments = append(adjustments, a)
+			}
+		}
+		if len(adjustments) < len(d.Adjustments) {
+			d.Adjustments = adjustments
+			touched = true
+		}
+		if touched {
+			s.disputes[id] = d
+			changed++
+		}
+	}
+	if changed > 0 {
+		s.save()
+	}
+	return changed
+}
@@ -0,0 +1,80 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"game/crypto"
+)
+
+// encryptedFile 加密数据文件的外层结构
+type encryptedFile struct {
+	Encryption string `json:"encryption"`
+	Data       string `json:"data"`
+}
+
+const encryptionAlgorithm = "aes-256-gcm"
+
+var (
+	keyMu    sync.RWMutex
+	usersKey []byte
+)
+
+// SetUsersKey 设置 users.json 的静态加密密钥，nil 表示以明文保存
+func SetUsersKey(key []byte) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	usersKey = key
+}
+
+func getUsersKey() []byte {
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	return usersKey
+}
+
+// sealFile 使用密钥加密文件内容，key 为 nil 时原样返回
+func sealFile(key []byte, plain []byte) ([]byte, error) {
+	if key == nil {
+		return plain, nil
+	}
+	sealed, err := crypto.EncryptWithKey(key, string(plain))
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(encryptedFile{
+		Encryption: encryptionAlgorithm,
+		Data:       sealed,
+	}, "", "  ")
+}
+
+// openFile 识别并解密加密文件，明文文件原样返回
+func openFile(key []byte, raw []byte) ([]byte, bool, error) {
+	var envelope encryptedFile
+	if err := json.Unmarshal(raw, &envelope); err != nil || envelope.Encryption == "" {
+		return raw, false, nil
+	}
+	if envelope.Encryption != encryptionAlgorithm {
+		return nil, true, fmt.Errorf("不支持的加密算法: %s", envelope.Encryption)
+	}
+	if key == nil {
+		return nil, true, fmt.Errorf("文件已加密，但未配置解密密钥")
+	}
+	plain, err := crypto.DecryptWithKey(key, envelope.Data)
+	if err != nil {
+		return nil, true, err
+	}
+	return []byte(plain), true, nil
+}
+
+// EncryptUsersFile 用当前密钥重写 users.json，用于把已有的明文文件迁移为加密存储
+func EncryptUsersFile() error {
+	if getUsersKey() == nil {
+		return fmt.Errorf("未配置用户数据加密密钥")
+	}
+	store := NewUserStore()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.save()
+}
@@ -0,0 +1,115 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// EventStore 限时活动存储，按活动ID索引，file 为空时为纯内存存储
+type EventStore struct {
+	mu     sync.RWMutex
+	events map[string]models.Event
+	file   string
+}
+
+// NewEventStore 创建保存到 events.json 的活动存储
+func NewEventStore() *EventStore {
+	ensureDataDir()
+	s := &EventStore{
+		events: make(map[string]models.Event),
+		file:   filepath.Join(DataDir, "events.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewEventStoreInMemory 创建不读写文件的活动存储
+func NewEventStoreInMemory() *EventStore {
+	return &EventStore{events: make(map[string]models.Event)}
+}
+
+func (s *EventStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载活动失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.EventsData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析活动失败: %v\n", err)
+		return
+	}
+	for _, e := range stored.Events {
+		s.events[e.ID] = e
+	}
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *EventStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "events", time.Now(), nil)
+	content, err := json.MarshalIndent(models.EventsData{Events: s.sorted()}, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化活动失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存活动失败: %v\n", err)
+	}
+}
+
+// sorted 返回按开始时间排列的活动副本，调用方需持有锁
+func (s *EventStore) sorted() []models.Event {
+	events := make([]models.Event, 0, len(s.events))
+	for _, e := range s.events {
+		e.Modes = append([]string(nil), e.Modes...)
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartsAt.Equal(events[j].StartsAt) {
+			return events[i].StartsAt.Before(events[j].StartsAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events
+}
+
+// All 返回所有活动，按开始时间排列
+func (s *EventStore) All() []models.Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted()
+}
+
+// Create 保存新活动
+func (s *EventStore) Create(e models.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.Modes = append([]string(nil), e.Modes...)
+	s.events[e.ID] = e
+	s.save()
+}
+
+// Remove 删除活动，活动不存在时返回 false
+func (s *EventStore) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.events[id]; !ok {
+		return false
+	}
+	delete(s.events, id)
+	s.save()
+	return true
+}
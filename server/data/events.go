@@ -0,0 +1,194 @@
+package data
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"game/models"
+)
+
+// ChangeOp 定义存储变更类型
+type ChangeOp string
+
+const (
+	ChangeAdded   ChangeOp = "added"
+	ChangeUpdated ChangeOp = "updated"
+	ChangeRemoved ChangeOp = "removed"
+)
+
+// UserChange 用户变更事件，Old 为变更前的状态（新增时为 nil），New 为变更后的状态（删除时为 nil）
+type UserChange struct {
+	Op  ChangeOp
+	Old *models.User
+	New *models.User
+}
+
+// RoomChange 房间变更事件，Old、New 含义同 UserChange
+type RoomChange struct {
+	Op  ChangeOp
+	Old *models.Room
+	New *models.Room
+}
+
+// changeFeed 按发布顺序在独立协程中投递变更通知。
+// 通知在存储锁内发布、锁外执行，监听函数可以安全地读写存储
+type changeFeed struct {
+	mu      sync.Mutex
+	pending []func()
+	wake    chan struct{}
+}
+
+// publish 排队一次投递，不会阻塞调用方
+func (f *changeFeed) publish(deliver func()) {
+	f.mu.Lock()
+	if f.wake == nil {
+		f.wake = make(chan struct{}, 1)
+		go f.run()
+	}
+	f.pending = append(f.pending, deliver)
+	f.mu.Unlock()
+
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (f *changeFeed) run() {
+	for range f.wake {
+		for {
+			f.mu.Lock()
+			batch := f.pending
+			f.pending = nil
+			f.mu.Unlock()
+			if len(batch) == 0 {
+				break
+			}
+			for _, deliver := range batch {
+				safeDeliver(deliver)
+			}
+		}
+	}
+}
+
+// safeDeliver 执行一次投递，监听函数 panic 不影响后续通知
+func safeDeliver(deliver func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("存储变更监听函数 panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	deliver()
+}
+
+// changeOp 根据变更前后状态判断变更类型
+func changeOp(hasOld, hasNew bool) ChangeOp {
+	switch {
+	case !hasOld:
+		return ChangeAdded
+	case !hasNew:
+		return ChangeRemoved
+	}
+	return ChangeUpdated
+}
+
+// OnChange 订阅用户变更，通知在后台协程中按变更顺序调用
+func (s *UserStore) OnChange(fn func(UserChange)) {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// OnInvalidate 注册缓存失效回调，在写操作的存储锁内同步调用，参数为用户名；
+// 回调必须足够轻量且不能访问 UserStore
+func (s *UserStore) OnInvalidate(fn func(username string)) {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+	s.invalidators = append(s.invalidators, fn)
+}
+
+// emit 发布用户变更，调用方需持有存储写锁以保证通知顺序与变更顺序一致
+func (s *UserStore) emit(old, new *models.User) {
+	s.listenMu.RLock()
+	listeners := s.listeners
+	invalidators := s.invalidators
+	s.listenMu.RUnlock()
+
+	for _, fn := range invalidators {
+		if old != nil {
+			fn(old.Username)
+		}
+		if new != nil {
+			fn(new.Username)
+		}
+	}
+	if len(listeners) == 0 {
+		return
+	}
+
+	ev := UserChange{Op: changeOp(old != nil, new != nil)}
+	if old != nil {
+		u := *old
+		ev.Old = &u
+	}
+	if new != nil {
+		u := *new
+		ev.New = &u
+	}
+	s.feed.publish(func() {
+		for _, fn := range listeners {
+			fn(ev)
+		}
+	})
+}
+
+// OnChange 订阅房间变更，通知在后台协程中按变更顺序调用
+func (s *RoomStore) OnChange(fn func(RoomChange)) {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// OnInvalidate 注册缓存失效回调，在写操作的存储锁内同步调用，参数为房间ID；
+// 回调必须足够轻量且不能访问 RoomStore
+func (s *RoomStore) OnInvalidate(fn func(id string)) {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+	s.invalidators = append(s.invalidators, fn)
+}
+
+// emit 发布房间变更，调用方需持有存储写锁以保证通知顺序与变更顺序一致
+func (s *RoomStore) emit(old, new *models.Room) {
+	s.listenMu.RLock()
+	listeners := s.listeners
+	invalidators := s.invalidators
+	s.listenMu.RUnlock()
+
+	for _, fn := range invalidators {
+		if old != nil {
+			fn(old.ID)
+		}
+		if new != nil {
+			fn(new.ID)
+		}
+	}
+	if len(listeners) == 0 {
+		return
+	}
+
+	ev := RoomChange{Op: changeOp(old != nil, new != nil)}
+	if old != nil {
+		r := old.Clone()
+		ev.Old = &r
+	}
+	if new != nil {
+		r := new.Clone()
+		ev.New = &r
+	}
+	s.feed.publish(func() {
+		for _, fn := range listeners {
+			fn(ev)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"game/models"
+)
+
+// defaultWeapons 内置武器配置，heroes.json 不存在时使用
+var defaultWeapons = []models.Weapon{
+	{Name: "rifle", Damage: 1, HeadshotMultiplier: 2, FalloffStart: 700, FalloffEnd: 900, FalloffMin: 0.5},
+	{Name: "pistol", Damage: 1, HeadshotMultiplier: 1.5, FalloffStart: 300, FalloffEnd: 600, FalloffMin: 0.5},
+	{Name: "smg", Damage: 1, HeadshotMultiplier: 1.5, FalloffStart: 250, FalloffEnd: 500, FalloffMin: 0.4},
+	{Name: "shotgun", Damage: 3, HeadshotMultiplier: 1, FalloffStart: 150, FalloffEnd: 450, FalloffMin: 0.2},
+	{Name: "sniper", Damage: 3, HeadshotMultiplier: 2, Price: 4500},
+	{Name: "lmg", Damage: 2, HeadshotMultiplier: 1.5, FalloffStart: 600, FalloffEnd: 900, FalloffMin: 0.5, Price: 3000},
+}
+
+// defaultArmor 内置护甲，heroes.json 不存在时使用
+var defaultArmor = []models.Armor{
+	{Name: "vest", Price: 650, Points: 1},
+	{Name: "heavy_armor", Price: 1500, Points: 3},
+}
+
+// defaultHeroes 内置英雄阵容，heroes.json 不存在时使用；第一名为机器人和未选择时的默认英雄
+var defaultHeroes = []models.Hero{
+	{ID: "rifleman", Name: "步枪手", Speed: 1, HP: 1, Loadout: []string{"rifle", "pistol"}},
+	{ID: "scout", Name: "侦察兵", Speed: 1.4, HP: 0.6, Loadout: []string{"smg"}},
+	{ID: "heavy", Name: "重装兵", Speed: 0.7, HP: 1.6, Loadout: []string{"shotgun"}},
+}
+
+// DefaultBalanceVersion 内置阵容的平衡版本号
+const DefaultBalanceVersion = "builtin"
+
+// HeroRoster 英雄阵容、武器和护甲配置，即一个平衡版本，只读；当前版本由 BalanceStore 管理
+type HeroRoster struct {
+	version string
+	heroes  []models.Hero
+	byID    map[string]models.Hero
+	weapons map[string]models.Weapon
+	armor   map[string]models.Armor
+}
+
+// NewHeroRoster 用指定配置创建阵容，heroes 为空时使用内置阵容、武器和护甲
+func NewHeroRoster(weapons []models.Weapon, armor []models.Armor, heroes []models.Hero) *HeroRoster {
+	if len(heroes) == 0 {
+		weapons, armor, heroes = defaultWeapons, defaultArmor, defaultHeroes
+	}
+	r := &HeroRoster{
+		version: DefaultBalanceVersion,
+		heroes:  heroes,
+		byID:    make(map[string]models.Hero, len(heroes)),
+		weapons: make(map[string]models.Weapon, len(weapons)),
+		armor:   make(map[string]models.Armor, len(armor)),
+	}
+	for _, hero := range heroes {
+		r.byID[hero.ID] = hero
+	}
+	for _, w := range weapons {
+		r.weapons[w.Name] = w
+	}
+	for _, a := range armor {
+		r.armor[a.Name] = a
+	}
+	return r
+}
+
+// LoadHeroRoster 读取数据目录下的 heroes.json，文件不存在时使用内置阵容
+func LoadHeroRoster() (*HeroRoster, error) {
+	data, err := os.ReadFile(filepath.Join(DataDir, "heroes.json"))
+	if os.IsNotExist(err) {
+		return NewHeroRoster(nil, nil, nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseHeroRoster(data)
+}
+
+// ParseHeroRoster 解析并校验 heroes.json 格式的平衡配置，未填写版本号时使用 DefaultBalanceVersion
+func ParseHeroRoster(data []byte) (*HeroRoster, error) {
+	var heroesData models.HeroesData
+	if err := json.Unmarshal(data, &heroesData); err != nil {
+		return nil, fmt.Errorf("解析英雄数据失败: %v", err)
+	}
+	if heroesData.Version != "" && !balanceVersion.MatchString(heroesData.Version) {
+		return nil, fmt.Errorf("平衡版本号只能包含字母、数字、点、下划线和连字符，最长 32 个字符: %q", heroesData.Version)
+	}
+	if len(heroesData.Heroes) == 0 {
+		return nil, fmt.Errorf("英雄数据为空")
+	}
+
+	// 武器和护甲共用购买时的物品名，不能重名
+	names := make(map[string]bool, len(heroesData.Weapons)+len(heroesData.Armor))
+	for _, w := range heroesData.Weapons {
+		switch {
+		case w.Name == "" || names[w.Name]:
+			return nil, fmt.Errorf("武器名为空或重复: %q", w.Name)
+		case w.Damage <= 0 || w.HeadshotMultiplier < 0:
+			return nil, fmt.Errorf("武器 %s 的伤害和爆头倍率无效", w.Name)
+		case w.FalloffStart < 0 || (w.FalloffStart > 0 && w.FalloffEnd < w.FalloffStart):
+			return nil, fmt.Errorf("武器 %s 的衰减距离无效", w.Name)
+		case w.FalloffMin < 0 || w.FalloffMin > 1:
+			return nil, fmt.Errorf("武器 %s 的最小伤害比例必须在 0~1 之间", w.Name)
+		case w.Price < 0:
+			return nil, fmt.Errorf("武器 %s 的价格无效", w.Name)
+		}
+		names[w.Name] = true
+	}
+	for _, a := range heroesData.Armor {
+		switch {
+		case a.Name == "" || names[a.Name]:
+			return nil, fmt.Errorf("护甲名为空或与其他物品重复: %q", a.Name)
+		case a.Price <= 0 || a.Points <= 0:
+			return nil, fmt.Errorf("护甲 %s 的价格和护甲值必须大于 0", a.Name)
+		}
+		names[a.Name] = true
+	}
+
+	seen := make(map[string]bool, len(heroesData.Heroes))
+	for _, hero := range heroesData.Heroes {
+		switch {
+		case hero.ID == "" || seen[hero.ID]:
+			return nil, fmt.Errorf("英雄ID为空或重复: %q", hero.ID)
+		case hero.Speed <= 0 || hero.HP <= 0:
+			return nil, fmt.Errorf("英雄 %s 的速度和生命值倍率必须大于 0", hero.ID)
+		case len(hero.Loadout) == 0:
+			return nil, fmt.Errorf("英雄 %s 没有可用武器", hero.ID)
+		}
+		for _, name := range hero.Loadout {
+			if _, ok := findWeapon(heroesData.Weapons, name); !ok {
+				return nil, fmt.Errorf("英雄 %s 使用了未定义的武器 %q", hero.ID, name)
+			}
+		}
+		seen[hero.ID] = true
+	}
+	r := NewHeroRoster(heroesData.Weapons, heroesData.Armor, heroesData.Heroes)
+	if heroesData.Version != "" {
+		r.version = heroesData.Version
+	}
+	return r, nil
+}
+
+// findWeapon 按名称查找武器
+func findWeapon(weapons []models.Weapon, name string) (models.Weapon, bool) {
+	for _, w := range weapons {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return models.Weapon{}, false
+}
+
+// Version 返回平衡版本号
+func (r *HeroRoster) Version() string {
+	return r.version
+}
+
+// All 返回全部英雄，按数据文件中的顺序排列
+func (r *HeroRoster) All() []models.Hero {
+	return append([]models.Hero(nil), r.heroes...)
+}
+
+// Get 根据ID查找英雄
+func (r *HeroRoster) Get(id string) (models.Hero, bool) {
+	hero, ok := r.byID[id]
+	return hero, ok
+}
+
+// Default 返回默认英雄，用于机器人和没有锁定英雄的玩家
+func (r *HeroRoster) Default() models.Hero {
+	return r.heroes[0]
+}
+
+// Weapon 返回英雄装备中的指定武器，name 为空时返回默认武器；不在英雄装备中时返回 false
+func (r *HeroRoster) Weapon(hero models.Hero, name string) (models.Weapon, bool) {
+	if name == "" && len(hero.Loadout) > 0 {
+		name = hero.Loadout[0]
+	}
+	if !slices.Contains(hero.Loadout, name) {
+		return models.Weapon{}, false
+	}
+	w, ok := r.weapons[name]
+	return w, ok
+}
+
+// WeaponNamed 按名称查找武器，不限于某个英雄的装备
+func (r *HeroRoster) WeaponNamed(name string) (models.Weapon, bool) {
+	w, ok := r.weapons[name]
+	return w, ok
+}
+
+// ShopWeapon 返回购买阶段出售的武器，价格为 0 的武器不出售
+func (r *HeroRoster) ShopWeapon(name string) (models.Weapon, bool) {
+	w, ok := r.weapons[name]
+	return w, ok && w.Price > 0
+}
+
+// ShopArmor 返回购买阶段出售的护甲
+func (r *HeroRoster) ShopArmor(name string) (models.Armor, bool) {
+	a, ok := r.armor[name]
+	return a, ok
+}
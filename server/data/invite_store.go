@@ -0,0 +1,174 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// InviteStore 房间邀请存储，按邀请ID索引，file 为空时为纯内存存储
+type InviteStore struct {
+	mu      sync.Mutex
+	invites map[string]models.Invite
+	file    string
+}
+
+// NewInviteStore 创建保存到 invites.json 的邀请存储
+func NewInviteStore() *InviteStore {
+	ensureDataDir()
+	s := &InviteStore{
+		invites: make(map[string]models.Invite),
+		file:    filepath.Join(DataDir, "invites.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewInviteStoreInMemory 创建不读写文件的邀请存储
+func NewInviteStoreInMemory() *InviteStore {
+	return &InviteStore{invites: make(map[string]models.Invite)}
+}
+
+func (s *InviteStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载房间邀请失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.InvitesData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析房间邀请失败: %v\n", err)
+		return
+	}
+	for _, invite := range stored.Invites {
+		s.invites[invite.ID] = invite
+	}
+}
+
+// save 清理过期邀请后写入文件，调用方需持有锁
+func (s *InviteStore) save() {
+	now := time.Now()
+	for id, invite := range s.invites {
+		if !now.Before(invite.ExpiresAt) {
+			delete(s.invites, id)
+		}
+	}
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "invites", time.Now(), nil)
+	stored := models.InvitesData{Invites: make([]models.Invite, 0, len(s.invites))}
+	for _, invite := range s.invites {
+		stored.Invites = append(stored.Invites, invite)
+	}
+	sort.Slice(stored.Invites, func(i, j int) bool { return stored.Invites[i].CreatedAt.Before(stored.Invites[j].CreatedAt) })
+	content, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化房间邀请失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存房间邀请失败: %v\n", err)
+	}
+}
+
+// Add 保存一个新邀请，同一房主对同一用户的同一房间只保留最新的邀请
+func (s *InviteStore) Add(invite models.Invite) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, old := range s.invites {
+		if old.FromID == invite.FromID && old.ToID == invite.ToID && old.RoomID == invite.RoomID {
+			delete(s.invites, id)
+		}
+	}
+	s.invites[invite.ID] = invite
+	s.save()
+}
+
+// Take 取出发给用户ID为 toID 的用户的邀请，邀请不存在、不属于该用户或已过期时返回 nil；
+// 取出后邀请即被删除，过期的邀请也会一并删除
+func (s *InviteStore) Take(id, toID string, now time.Time) *models.Invite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invite, ok := s.invites[id]
+	if !ok || invite.ToID != toID {
+		return nil
+	}
+	delete(s.invites, id)
+	s.save()
+	if !now.Before(invite.ExpiresAt) {
+		return nil
+	}
+	return &invite
+}
+
+// Pending 返回发给用户ID为 toID 的用户、尚未过期的邀请，按发出时间排列
+func (s *InviteStore) Pending(toID string, now time.Time) []models.Invite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invites := make([]models.Invite, 0)
+	for _, invite := range s.invites {
+		if invite.ToID == toID && now.Before(invite.ExpiresAt) {
+			invites = append(invites, invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool { return invites[i].CreatedAt.Before(invites[j].CreatedAt) })
+	return invites
+}
+
+// RemoveRoom 删除某个房间的全部邀请，房间解散或开局后调用，返回删除的数量
+func (s *InviteStore) RemoveRoom(roomID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for id, invite := range s.invites {
+		if invite.RoomID == roomID {
+			delete(s.invites, id)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.save()
+	}
+	return removed
+}
+
+// RemoveUser 删除用户发出或收到的全部邀请，注销账号时调用，返回删除的数量
+func (s *InviteStore) RemoveUser(userID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for id, invite := range s.invites {
+		if invite.FromID == userID || invite.ToID == userID {
+			delete(s.invites, id)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.save()
+	}
+	return removed
+}
+
+// ByUser 返回用户发出或收到的全部邀请，包括已过期尚未清理的，按发出时间排列
+func (s *InviteStore) ByUser(userID string) []models.Invite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invites := make([]models.Invite, 0)
+	for _, invite := range s.invites {
+		if invite.FromID == userID || invite.ToID == userID {
+			invites = append(invites, invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool { return invites[i].CreatedAt.Before(invites[j].CreatedAt) })
+	return invites
+}
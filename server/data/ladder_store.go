@@ -0,0 +1,127 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// LadderStore 天梯排名存储，玩家按排名从高到低排列，file 为空时为纯内存存储
+type LadderStore struct {
+	mu      sync.RWMutex
+	players []models.LadderEntry
+	file    string
+}
+
+// NewLadderStore 创建保存到 ladder.json 的天梯存储
+func NewLadderStore() *LadderStore {
+	ensureDataDir()
+	s := &LadderStore{file: filepath.Join(DataDir, "ladder.json")}
+	s.load()
+	return s
+}
+
+// NewLadderStoreInMemory 创建不读写文件的天梯存储
+func NewLadderStoreInMemory() *LadderStore {
+	return &LadderStore{}
+}
+
+func (s *LadderStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载天梯失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.LadderData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析天梯失败: %v\n", err)
+		return
+	}
+	s.players = stored.Players
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *LadderStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "ladder", time.Now(), nil)
+	content, err := json.MarshalIndent(models.LadderData{Players: s.players}, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化天梯失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存天梯失败: %v\n", err)
+	}
+}
+
+// All 返回按排名排列的天梯副本
+func (s *LadderStore) All() []models.LadderEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]models.LadderEntry(nil), s.players...)
+}
+
+// Position 返回用户的排名，从 1 开始，不在天梯上时返回 0
+func (s *LadderStore) Position(userID string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index(userID) + 1
+}
+
+// Join 把玩家加入天梯末尾，已在天梯上时不变，返回其排名
+func (s *LadderStore) Join(entry models.LadderEntry) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.index(entry.UserID); i >= 0 {
+		return i + 1
+	}
+	s.players = append(s.players, entry)
+	s.save()
+	return len(s.players)
+}
+
+// Swap 在 lowerID 的排名低于 higherID 时交换双方的排名，返回是否交换
+func (s *LadderStore) Swap(lowerID, higherID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lower, higher := s.index(lowerID), s.index(higherID)
+	if lower < 0 || higher < 0 || lower <= higher {
+		return false
+	}
+	s.players[lower], s.players[higher] = s.players[higher], s.players[lower]
+	s.save()
+	return true
+}
+
+// Remove 把用户移出天梯，排在其后的玩家依次前移一名，注销账号时调用，返回用户是否在天梯上
+func (s *LadderStore) Remove(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(userID)
+	if i < 0 {
+		return false
+	}
+	s.players = append(s.players[:i], s.players[i+1:]...)
+	s.save()
+	return true
+}
+
+// index 返回用户在天梯中的下标，不在天梯上时返回 -1，调用方需持有锁
+func (s *LadderStore) index(userID string) int {
+	for i, p := range s.players {
+		if p.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
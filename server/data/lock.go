@@ -0,0 +1,28 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockFile 持有数据目录锁，进程存活期间必须保持引用，否则文件被回收后锁会失效
+var lockFile *os.File
+
+// LockDataDir 获取数据目录的排他锁，防止多个服务器进程同时读写同一份数据
+func LockDataDir() error {
+	if lockFile != nil {
+		return nil
+	}
+	ensureDataDir()
+	path := filepath.Join(DataDir, ".lock")
+	f, err := lockExclusive(path)
+	if err != nil {
+		holder, _ := os.ReadFile(path)
+		return fmt.Errorf("数据目录 %s 已被其他进程占用（%s）: %v", DataDir, holder, err)
+	}
+	f.Truncate(0)
+	f.WriteAt([]byte(fmt.Sprintf("pid %d", os.Getpid())), 0)
+	lockFile = f
+	return nil
+}
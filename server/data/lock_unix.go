@@ -0,0 +1,21 @@
+//go:build !windows
+
+package data
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockExclusive 打开锁文件并加非阻塞排他锁
+func lockExclusive(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
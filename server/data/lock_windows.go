@@ -0,0 +1,27 @@
+//go:build windows
+
+package data
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockExclusive 以不共享模式打开锁文件，其他进程再次打开会失败
+func lockExclusive(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(name,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		0, // 不共享
+		nil,
+		syscall.OPEN_ALWAYS,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
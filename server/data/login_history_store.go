@@ -0,0 +1,116 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// loginHistoryLimit 每个用户保留的最近登录记录数
+const loginHistoryLimit = 50
+
+// LoginHistoryStore 登录历史存储，每个用户只保留最近的记录，file 为空时为纯内存存储
+type LoginHistoryStore struct {
+	mu      sync.RWMutex
+	records map[string][]models.LoginRecord // 按用户ID分组，按时间升序
+	file    string
+}
+
+// NewLoginHistoryStore 创建保存到 login_history.json 的登录历史存储
+func NewLoginHistoryStore() *LoginHistoryStore {
+	ensureDataDir()
+	s := &LoginHistoryStore{
+		records: make(map[string][]models.LoginRecord),
+		file:    filepath.Join(DataDir, "login_history.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewLoginHistoryStoreInMemory 创建不读写文件的登录历史存储
+func NewLoginHistoryStoreInMemory() *LoginHistoryStore {
+	return &LoginHistoryStore{records: make(map[string][]models.LoginRecord)}
+}
+
+func (s *LoginHistoryStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载登录历史失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.LoginHistoryData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析登录历史失败: %v\n", err)
+		return
+	}
+	sort.SliceStable(stored.Records, func(i, j int) bool { return stored.Records[i].Time.Before(stored.Records[j].Time) })
+	for _, record := range stored.Records {
+		s.records[record.UserID] = append(s.records[record.UserID], record)
+	}
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *LoginHistoryStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "login_history", time.Now(), nil)
+	stored := models.LoginHistoryData{Records: make([]models.LoginRecord, 0)}
+	for _, list := range s.records {
+		stored.Records = append(stored.Records, list...)
+	}
+	sort.SliceStable(stored.Records, func(i, j int) bool { return stored.Records[i].Time.Before(stored.Records[j].Time) })
+	content, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化登录历史失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存登录历史失败: %v\n", err)
+	}
+}
+
+// Add 追加一条登录记录，超过保留数量时丢弃该用户最早的记录
+func (s *LoginHistoryStore) Add(record models.LoginRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := append(s.records[record.UserID], record)
+	if len(list) > loginHistoryLimit {
+		list = slices.Clone(list[len(list)-loginHistoryLimit:])
+	}
+	s.records[record.UserID] = list
+	s.save()
+}
+
+// ListByUser 返回用户ID为 userID 的用户的登录记录，最新的在前
+func (s *LoginHistoryStore) ListByUser(userID string) []models.LoginRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := slices.Clone(s.records[userID])
+	slices.Reverse(list)
+	if list == nil {
+		list = make([]models.LoginRecord, 0)
+	}
+	return list
+}
+
+// RemoveUser 删除用户ID为 userID 的用户的全部登录记录
+func (s *LoginHistoryStore) RemoveUser(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[userID]; !ok {
+		return
+	}
+	delete(s.records, userID)
+	s.save()
+}
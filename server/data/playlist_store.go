@@ -0,0 +1,81 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// PlaylistStore 快速匹配精选玩法列表存储，file 为空时为纯内存存储
+type PlaylistStore struct {
+	mu       sync.RWMutex
+	playlist models.Playlist
+	file     string
+}
+
+// NewPlaylistStore 创建保存到 playlist.json 的精选玩法存储
+func NewPlaylistStore() *PlaylistStore {
+	ensureDataDir()
+	s := &PlaylistStore{file: filepath.Join(DataDir, "playlist.json")}
+	s.load()
+	return s
+}
+
+// NewPlaylistStoreInMemory 创建不读写文件的精选玩法存储
+func NewPlaylistStoreInMemory() *PlaylistStore {
+	return &PlaylistStore{}
+}
+
+func (s *PlaylistStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载精选玩法失败: %v\n", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(content, &s.playlist); err != nil {
+		fmt.Printf("解析精选玩法失败: %v\n", err)
+	}
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *PlaylistStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "playlist", time.Now(), nil)
+	content, err := json.MarshalIndent(s.playlist, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化精选玩法失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0644); err != nil {
+		fmt.Printf("保存精选玩法失败: %v\n", err)
+	}
+}
+
+// Get 返回精选玩法列表
+func (s *PlaylistStore) Get() models.Playlist {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p := s.playlist
+	p.Entries = slices.Clone(p.Entries)
+	return p
+}
+
+// Set 替换精选玩法列表
+func (s *PlaylistStore) Set(p models.Playlist) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.Entries = slices.Clone(p.Entries)
+	s.playlist = p
+	s.save()
+}
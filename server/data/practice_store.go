@@ -0,0 +1,111 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// PracticeStore 练习靶场个人最佳成绩存储，按稳定用户ID索引，file 为空时为纯内存存储
+type PracticeStore struct {
+	mu      sync.RWMutex
+	records map[string]models.PracticeRecord
+	file    string
+}
+
+// NewPracticeStore 创建保存到 practice.json 的练习成绩存储
+func NewPracticeStore() *PracticeStore {
+	ensureDataDir()
+	s := &PracticeStore{
+		records: make(map[string]models.PracticeRecord),
+		file:    filepath.Join(DataDir, "practice.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewPracticeStoreInMemory 创建不读写文件的练习成绩存储
+func NewPracticeStoreInMemory() *PracticeStore {
+	return &PracticeStore{records: make(map[string]models.PracticeRecord)}
+}
+
+func (s *PracticeStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载练习成绩失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.PracticeData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析练习成绩失败: %v\n", err)
+		return
+	}
+	for _, record := range stored.Records {
+		s.records[record.UserID] = record
+	}
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *PracticeStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "practice", time.Now(), nil)
+	stored := models.PracticeData{Records: make([]models.PracticeRecord, 0, len(s.records))}
+	for _, record := range s.records {
+		stored.Records = append(stored.Records, record)
+	}
+	sort.Slice(stored.Records, func(i, j int) bool { return stored.Records[i].UserID < stored.Records[j].UserID })
+	content, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化练习成绩失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存练习成绩失败: %v\n", err)
+	}
+}
+
+// Get 返回用户的个人最佳成绩
+func (s *PracticeStore) Get(userID string) (models.PracticeRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.records[userID]
+	return record, ok
+}
+
+// Modify 在写锁内修改用户的成绩记录并保存，记录不存在时 fn 收到只有用户ID的空记录；fn 返回 false 时不保存
+func (s *PracticeStore) Modify(userID string, fn func(record *models.PracticeRecord) bool) models.PracticeRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[userID]
+	if !ok {
+		record = models.PracticeRecord{UserID: userID}
+	}
+	if fn(&record) {
+		s.records[userID] = record
+		s.save()
+	}
+	return record
+}
+
+// Remove 删除用户的成绩记录，注销账号时调用，返回记录是否存在
+func (s *PracticeStore) Remove(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[userID]; !ok {
+		return false
+	}
+	delete(s.records, userID)
+	s.save()
+	return true
+}
@@ -0,0 +1,141 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// RefreshTokenStore 刷新令牌存储，按令牌摘要索引，file 为空时为纯内存存储
+type RefreshTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]models.RefreshToken
+	file   string
+}
+
+// NewRefreshTokenStore 创建保存到 refresh_tokens.json 的刷新令牌存储
+func NewRefreshTokenStore() *RefreshTokenStore {
+	ensureDataDir()
+	s := &RefreshTokenStore{
+		tokens: make(map[string]models.RefreshToken),
+		file:   filepath.Join(DataDir, "refresh_tokens.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewRefreshTokenStoreInMemory 创建不读写文件的刷新令牌存储
+func NewRefreshTokenStoreInMemory() *RefreshTokenStore {
+	return &RefreshTokenStore{tokens: make(map[string]models.RefreshToken)}
+}
+
+func (s *RefreshTokenStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载刷新令牌失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.RefreshTokensData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析刷新令牌失败: %v\n", err)
+		return
+	}
+	for _, token := range stored.Tokens {
+		s.tokens[token.Hash] = token
+	}
+}
+
+// save 清理过期令牌后写入文件，调用方需持有锁
+func (s *RefreshTokenStore) save() {
+	now := time.Now()
+	for hash, token := range s.tokens {
+		if !now.Before(token.ExpiresAt) {
+			delete(s.tokens, hash)
+		}
+	}
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "refresh_tokens", time.Now(), nil)
+	stored := models.RefreshTokensData{Tokens: make([]models.RefreshToken, 0, len(s.tokens))}
+	for _, token := range s.tokens {
+		stored.Tokens = append(stored.Tokens, token)
+	}
+	sort.Slice(stored.Tokens, func(i, j int) bool { return stored.Tokens[i].CreatedAt.Before(stored.Tokens[j].CreatedAt) })
+	content, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化刷新令牌失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存刷新令牌失败: %v\n", err)
+	}
+}
+
+// Add 保存一个新令牌
+func (s *RefreshTokenStore) Add(token models.RefreshToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token.Hash] = token
+	s.save()
+}
+
+// Rotate 将摘要为 hash 的有效令牌标记为已轮换并保存同一 Family 的 next，返回被轮换的令牌。
+// 令牌不存在或已过期时返回 nil；令牌已被轮换过时吊销整个 Family 并返回 nil
+func (s *RefreshTokenStore) Rotate(hash string, next models.RefreshToken, now time.Time) *models.RefreshToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[hash]
+	if !ok || !now.Before(token.ExpiresAt) {
+		return nil
+	}
+	if !token.RotatedAt.IsZero() {
+		s.removeFamily(token.Family)
+		s.save()
+		return nil
+	}
+	token.RotatedAt = now
+	s.tokens[hash] = token
+	next.Family, next.UserID = token.Family, token.UserID
+	s.tokens[next.Hash] = next
+	s.save()
+	return &token
+}
+
+// RemoveUser 吊销用户ID为 userID 的用户的全部令牌，返回吊销的有效令牌数
+func (s *RefreshTokenStore) RemoveUser(userID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed, active := false, 0
+	for hash, token := range s.tokens {
+		if token.UserID == userID {
+			if token.RotatedAt.IsZero() {
+				active++
+			}
+			delete(s.tokens, hash)
+			removed = true
+		}
+	}
+	if removed {
+		s.save()
+	}
+	return active
+}
+
+// removeFamily 删除同一 Family 的全部令牌，调用方需持有锁
+func (s *RefreshTokenStore) removeFamily(family string) {
+	for hash, token := range s.tokens {
+		if token.Family == family {
+			delete(s.tokens, hash)
+		}
+	}
+}
@@ -0,0 +1,484 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// ResultStore 游戏结果存储，以追加写入的 JSONL 日志持久化：
+// 首行为 {"schema_version":N} 版本头，之后每条记录一行，记录一局结果只需追加一行，
+// 同一 ID 的后续记录覆盖之前的记录，日志中被覆盖或删除的行由 Compact 定期重写清理；
+// file 为空时为纯内存存储，数据不落盘
+type ResultStore struct {
+	mu      sync.RWMutex
+	results []models.GameResult
+	file    string
+	journal *os.File
+	lines   int // 日志当前行数，大于结果数时说明存在可压缩的冗余行
+	loadErr error
+}
+
+func NewResultStore() *ResultStore {
+	ensureDataDir()
+	file := filepath.Join(DataDir, "game_results.jsonl")
+	store := &ResultStore{
+		results: make([]models.GameResult, 0),
+		file:    file,
+	}
+	store.load()
+	return store
+}
+
+// NewResultStoreInMemory 创建不读写文件的游戏结果存储，用于测试和临时服务器
+func NewResultStoreInMemory() *ResultStore {
+	return &ResultStore{results: make([]models.GameResult, 0)}
+}
+
+func (s *ResultStore) load() {
+	if err := trimTornTail(s.file); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("修复游戏结果日志末尾失败: %v\n", err)
+	}
+	f, err := os.Open(s.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.migrateLegacy()
+			return
+		}
+		fmt.Printf("加载游戏结果数据失败: %v\n", err)
+		return
+	}
+	defer f.Close()
+
+	index := make(map[string]int)
+	version := 0
+	first := true
+	var migrateErr error
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if first {
+			first = false
+			if v, ok := parseJournalHeader(line); ok {
+				version = v
+				if err := checkVersion(s.file, version, ResultsSchemaVersion); err != nil {
+					s.fail(err)
+					return
+				}
+				continue
+			}
+		}
+		if migrateErr != nil {
+			// 升级失败的不是最后一行，说明数据本身有问题
+			s.fail(fmt.Errorf("升级游戏结果记录失败（第 %d 行）: %w", s.lines, migrateErr))
+			return
+		}
+		s.lines++
+		if version < ResultsSchemaVersion {
+			migratedLine, err := migrateRecord(s.file, line, version)
+			if err != nil {
+				// 可能是写了一半的末行，先记下，读到下一行时再报错
+				migrateErr = err
+				continue
+			}
+			line = migratedLine
+		}
+		var result models.GameResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			// 写了一半的末行已在加载前截掉，其余损坏的行跳过即可
+			fmt.Printf("跳过损坏的游戏结果记录（第 %d 行）: %v\n", s.lines, err)
+			continue
+		}
+		if i, ok := index[result.ID]; ok {
+			s.results[i] = result
+			continue
+		}
+		index[result.ID] = len(s.results)
+		s.results = append(s.results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Printf("读取游戏结果日志失败: %v\n", err)
+	}
+	if migrateErr != nil {
+		fmt.Printf("跳过损坏的游戏结果记录（第 %d 行）: %v\n", s.lines, migrateErr)
+	}
+	if version < ResultsSchemaVersion {
+		if err := s.compact(); err != nil {
+			fmt.Printf("保存升级后的游戏结果失败: %v\n", err)
+			return
+		}
+		fmt.Printf("游戏结果数据已升级到版本 %d\n", ResultsSchemaVersion)
+	}
+}
+
+// fail 记录加载时无法继续的错误，并停止写盘以免覆盖原日志
+func (s *ResultStore) fail(err error) {
+	s.loadErr = err
+	s.file = ""
+	s.results = make([]models.GameResult, 0)
+}
+
+// LoadErr 返回加载时遇到的无法继续的错误（如版本过高、升级失败），此时存储不会写盘
+func (s *ResultStore) LoadErr() error {
+	return s.loadErr
+}
+
+// trimTornTail 截掉日志末尾没有换行符的半行：进程崩溃时最后一次追加可能只写了一半，
+// 留在文件里的话下一次追加会直接接在它后面，连同新记录一起变成损坏行
+func trimTornTail(name string) error {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	good := int64(0)
+	buf := make([]byte, 4096)
+	for end := size; end > 0; {
+		start := max(end-int64(len(buf)), 0)
+		chunk := buf[:end-start]
+		if _, err := f.ReadAt(chunk, start); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			good = start + int64(i) + 1
+			break
+		}
+		end = start
+	}
+	if good == size {
+		return nil
+	}
+	fmt.Printf("截掉游戏结果日志末尾不完整的记录（%d 字节）\n", size-good)
+	return f.Truncate(good)
+}
+
+// parseJournalHeader 解析日志首行的版本头
+func parseJournalHeader(line []byte) (int, bool) {
+	var header map[string]interface{}
+	if err := json.Unmarshal(line, &header); err != nil {
+		return 0, false
+	}
+	if _, isRecord := header["id"]; isRecord {
+		return 0, false
+	}
+	if _, ok := header["schema_version"]; !ok {
+		return 0, false
+	}
+	return schemaVersion(header), true
+}
+
+// migrateRecord 将一条旧版本记录升级到当前版本
+func migrateRecord(name string, line []byte, version int) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(line, &doc); err != nil {
+		return nil, err
+	}
+	if err := applyMigrations(name, doc, version, ResultsSchemaVersion, resultMigrations); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// migrateLegacy 将旧版 game_results.json 转换为 JSONL 日志
+func (s *ResultStore) migrateLegacy() {
+	legacy := filepath.Join(filepath.Dir(s.file), "game_results.json")
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		return
+	}
+	var resultsData models.GameResultsData
+	if err := json.Unmarshal(data, &resultsData); err != nil {
+		fmt.Printf("解析旧版游戏结果数据失败: %v\n", err)
+		return
+	}
+	s.results = resultsData.Results
+	if err := s.compact(); err != nil {
+		fmt.Printf("迁移游戏结果数据失败: %v\n", err)
+		return
+	}
+	if err := os.Rename(legacy, legacy+".migrated"); err != nil {
+		fmt.Printf("重命名旧版游戏结果文件失败: %v\n", err)
+	}
+	fmt.Printf("已将 %d 条游戏结果迁移到 %s\n", len(s.results), s.file)
+}
+
+// appendLine 向日志末尾追加一条记录
+func (s *ResultStore) appendLine(result models.GameResult) {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "results append", time.Now(), map[string]string{"result_id": result.ID})
+	if s.journal == nil {
+		f, err := os.OpenFile(s.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Printf("打开游戏结果日志失败: %v\n", err)
+			return
+		}
+		s.journal = f
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		fmt.Printf("序列化游戏结果失败: %v\n", err)
+		return
+	}
+	if _, err := s.journal.Write(append(data, '\n')); err != nil {
+		fmt.Printf("写入游戏结果日志失败: %v\n", err)
+		return
+	}
+	s.lines++
+}
+
+// compact 用当前内存中的结果重写日志：先写临时文件再原子替换
+func (s *ResultStore) compact() error {
+	if s.file == "" {
+		return nil
+	}
+	defer report.Track(report.SlowStore, "results", time.Now(), map[string]string{"records": strconv.Itoa(len(s.results))})
+	tmp := s.file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(map[string]int{"schema_version": ResultsSchemaVersion}); err != nil {
+		f.Close()
+		return err
+	}
+	for _, r := range s.results {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if s.journal != nil {
+		s.journal.Close()
+		s.journal = nil
+	}
+	if err := os.Rename(tmp, s.file); err != nil {
+		return err
+	}
+	s.lines = len(s.results)
+	return nil
+}
+
+// Compact 日志存在冗余行时重写日志，返回是否执行了压缩
+func (s *ResultStore) Compact() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lines <= len(s.results) {
+		return false, nil
+	}
+	if err := s.compact(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 所有查询方法返回数据副本，修改副本不会影响存储
+
+func (s *ResultStore) Add(result models.GameResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, result.Clone())
+	s.appendLine(result)
+}
+
+// Update 更新已有结果，通过追加一条同 ID 的新记录实现
+func (s *ResultStore) Update(result models.GameResult) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.results {
+		if s.results[i].ID == result.ID {
+			s.results[i] = result.Clone()
+			s.appendLine(result)
+			return true
+		}
+	}
+	return false
+}
+
+// RenamePlayer 将结果中出现的玩家名替换为 alias，用于注销用户后的匿名化，返回修改的结果数
+func (s *ResultStore) RenamePlayer(username, alias string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := 0
+	for i := range s.results {
+		if renamePlayer(&s.results[i], username, alias) {
+			s.appendLine(s.results[i])
+			changed++
+		}
+	}
+	return changed
+}
+
+// renamePlayer 将一条结果中出现的玩家名替换为 alias 并清除该玩家的用户ID等身份信息，返回结果是否被修改
+func renamePlayer(r *models.GameResult, username, alias string) bool {
+	touched := false
+	if r.Winner == username {
+		r.Winner = alias
+		touched = true
+	}
+	if r.Loser == username {
+		r.Loser = alias
+		touched = true
+	}
+	if r.MVP == username {
+		r.MVP = alias
+		touched = true
+	}
+	for j := range r.Players {
+		if r.Players[j].Username == username {
+			r.Players[j].Username = alias
+			r.Players[j].UserID = ""
+			r.Players[j].ClientVersion = ""
+			touched = true
+		}
+	}
+	for j := range r.Placements {
+		if r.Placements[j] == username {
+			r.Placements[j] = alias
+			touched = true
+		}
+	}
+	for j := range r.Flagged {
+		if r.Flagged[j] == username {
+			r.Flagged[j] = alias
+			touched = true
+		}
+	}
+	return touched
+}
+
+// TagPlayer 为结果中以 username 参与、尚未记录用户ID的玩家补上 userID，用户改名前调用以保留其历史结果，返回修改的结果数
+func (s *ResultStore) TagPlayer(username, userID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := 0
+	for i := range s.results {
+		r := &s.results[i]
+		touched := false
+		for j := range r.Players {
+			if r.Players[j].Username == username && r.Players[j].UserID == "" {
+				r.Players[j].UserID = userID
+				touched = true
+			}
+		}
+		if touched {
+			s.appendLine(*r)
+			changed++
+		}
+	}
+	return changed
+}
+
+func (s *ResultStore) GetAll() []models.GameResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]models.GameResult, len(s.results))
+	for i := range s.results {
+		result[i] = s.results[i].Clone()
+	}
+	return result
+}
+
+// FindByRoom 查找指定房间的游戏结果，按时间倒序
+func (s *ResultStore) FindByRoom(roomID string) []models.GameResult {
+	results, _ := s.Query(func(r models.GameResult) bool {
+		return r.RoomID == roomID
+	}, 0, 0)
+	return results
+}
+
+// FindByPlayer 查找胜负方为 username 的游戏结果，按时间倒序
+func (s *ResultStore) FindByPlayer(username string) []models.GameResult {
+	results, _ := s.Query(func(r models.GameResult) bool {
+		return r.Winner == username || r.Loser == username
+	}, 0, 0)
+	return results
+}
+
+// FindByUser 查找用户参与的游戏结果：玩家记录了 userID 的结果，以及胜负方为当前用户名 username 的结果
+func (s *ResultStore) FindByUser(userID, username string) []models.GameResult {
+	results, _ := s.Query(func(r models.GameResult) bool {
+		return (userID != "" && r.NameOf(userID) != "") || r.Winner == username || r.Loser == username
+	}, 0, 0)
+	return results
+}
+
+// FindSince 查找 since 及之后进行的游戏结果，按时间倒序
+func (s *ResultStore) FindSince(since time.Time) []models.GameResult {
+	results, _ := s.Query(func(r models.GameResult) bool {
+		return !r.PlayTime.Before(since)
+	}, 0, 0)
+	return results
+}
+
+// Query 按条件筛选结果，按时间倒序返回 [offset, offset+limit) 区间以及匹配总数，limit 为 0 表示不限制
+func (s *ResultStore) Query(match func(models.GameResult) bool, offset, limit int) ([]models.GameResult, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	page := make([]models.GameResult, 0)
+	total := 0
+	for i := len(s.results) - 1; i >= 0; i-- {
+		if match != nil && !match(s.results[i]) {
+			continue
+		}
+		if total >= offset && (limit <= 0 || len(page) < limit) {
+			page = append(page, s.results[i].Clone())
+		}
+		total++
+	}
+	return page, total
+}
+
+// RemoveBefore 删除对局时间早于 cutoff 的结果，返回删除数量
+func (s *ResultStore) RemoveBefore(cutoff time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := make([]models.GameResult, 0, len(s.results))
+	for _, r := range s.results {
+		if !r.PlayTime.Before(cutoff) {
+			kept = append(kept, r)
+		}
+	}
+	removed := len(s.results) - len(kept)
+	if removed > 0 {
+		s.results = kept
+		// 删除无法用追加表示，直接重写日志
+		if err := s.compact(); err != nil {
+			fmt.Printf("保存游戏结果数据失败: %v\n", err)
+		}
+	}
+	return removed
+}
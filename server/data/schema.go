@@ -0,0 +1,153 @@
+package data
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// 各数据文件当前的 schema 版本，没有 schema_version 字段的旧文件视为版本 0
+const (
+	UsersSchemaVersion   = 2
+	RoomsSchemaVersion   = 1
+	ResultsSchemaVersion = 1
+)
+
+// migration 将文档从 from 版本升级到 from+1
+type migration struct {
+	from  int
+	desc  string
+	apply func(doc map[string]interface{}) error
+}
+
+// userMigrations users.json 的升级步骤
+var userMigrations = []migration{
+	{from: 0, desc: "明文密码转换为 MD5 摘要", apply: hashPlaintextPasswords},
+	{from: 1, desc: "为用户分配稳定ID", apply: assignUserIDs},
+}
+
+// roomMigrations rooms.json 的升级步骤
+var roomMigrations = []migration{
+	{from: 0, desc: "添加 schema_version", apply: func(map[string]interface{}) error { return nil }},
+}
+
+// resultMigrations 游戏结果日志的升级步骤，逐条作用于每一行记录
+var resultMigrations = []migration{
+	{from: 0, desc: "玩家列表由用户名转换为玩家对象", apply: expandResultPlayers},
+}
+
+// schemaVersion 读取文档中的 schema_version
+func schemaVersion(doc map[string]interface{}) int {
+	v, ok := doc["schema_version"].(float64)
+	if !ok {
+		return 0
+	}
+	return int(v)
+}
+
+// checkVersion 检查文件版本是否高于当前程序支持的版本
+func checkVersion(name string, version, current int) error {
+	if version > current {
+		return fmt.Errorf("%s 的 schema_version 为 %d，高于当前程序支持的 %d，请升级服务器", name, version, current)
+	}
+	return nil
+}
+
+// migrateDocument 将整个 JSON 文档升级到 current 版本，返回升级后的内容以及是否发生了升级
+func migrateDocument(name string, raw []byte, current int, migrations []migration) ([]byte, bool, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, false, err
+	}
+	version := schemaVersion(doc)
+	if err := checkVersion(name, version, current); err != nil {
+		return nil, false, err
+	}
+	if version == current {
+		return raw, false, nil
+	}
+	if err := applyMigrations(name, doc, version, current, migrations); err != nil {
+		return nil, false, err
+	}
+	doc["schema_version"] = current
+	out, err := json.Marshal(doc)
+	return out, true, err
+}
+
+// applyMigrations 依次执行 version 到 current 之间的升级步骤
+func applyMigrations(name string, doc map[string]interface{}, version, current int, migrations []migration) error {
+	for v := version; v < current; v++ {
+		var step *migration
+		for i := range migrations {
+			if migrations[i].from == v {
+				step = &migrations[i]
+				break
+			}
+		}
+		if step == nil {
+			return fmt.Errorf("%s 缺少从版本 %d 升级的步骤", name, v)
+		}
+		if err := step.apply(doc); err != nil {
+			return fmt.Errorf("%s 执行升级 %d -> %d（%s）失败: %v", name, v, v+1, step.desc, err)
+		}
+	}
+	return nil
+}
+
+// NewUserID 生成随机的用户ID
+func NewUserID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成用户ID失败: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
+
+var md5Hex = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// hashPlaintextPasswords 将仍为明文的密码转换为 MD5 摘要
+func hashPlaintextPasswords(doc map[string]interface{}) error {
+	users, _ := doc["users"].([]interface{})
+	for _, u := range users {
+		user, ok := u.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		password, _ := user["password"].(string)
+		if password == "" || md5Hex.MatchString(password) {
+			continue
+		}
+		sum := md5.Sum([]byte(password))
+		user["password"] = hex.EncodeToString(sum[:])
+	}
+	return nil
+}
+
+// assignUserIDs 为没有ID的用户生成稳定ID
+func assignUserIDs(doc map[string]interface{}) error {
+	users, _ := doc["users"].([]interface{})
+	for _, u := range users {
+		user, ok := u.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id, _ := user["id"].(string); id == "" {
+			user["id"] = NewUserID()
+		}
+	}
+	return nil
+}
+
+// expandResultPlayers 将旧记录中字符串形式的玩家列表转换为玩家统计对象
+func expandResultPlayers(doc map[string]interface{}) error {
+	players, _ := doc["players"].([]interface{})
+	for i, p := range players {
+		if username, ok := p.(string); ok {
+			players[i] = map[string]interface{}{"username": username}
+		}
+	}
+	return nil
+}
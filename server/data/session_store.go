@@ -0,0 +1,137 @@
+package data
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+)
+
+// SessionMirror 登录会话的共享副本，集群模式下由跨实例注册表实现，使一个实例上的登录对所有实例可见
+type SessionMirror interface {
+	PutSession(session models.Session)
+	GetSession(id string) *models.Session
+	ListSessions(userID string) []models.Session
+	DeleteSession(id string)
+	DeleteUserSessions(userID string)
+}
+
+// SessionStore 登录会话存储，只保存在内存中：服务器启动时所有用户都会被重置为离线，旧会话本就全部失效。
+// 设置了 SessionMirror 时写操作同步到副本，本地找不到的会话再从副本查找
+type SessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]models.Session
+	mirror   SessionMirror
+}
+
+// NewSessionStore 创建会话存储
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: make(map[string]models.Session)}
+}
+
+// SetMirror 设置会话的共享副本，需在使用存储之前调用
+func (s *SessionStore) SetMirror(mirror SessionMirror) {
+	s.mirror = mirror
+}
+
+// Add 添加会话
+func (s *SessionStore) Add(session models.Session) {
+	s.mu.Lock()
+	s.sessions[session.ID] = session
+	s.mu.Unlock()
+	if s.mirror != nil {
+		s.mirror.PutSession(session)
+	}
+}
+
+// Get 根据ID查找会话
+func (s *SessionStore) Get(id string) *models.Session {
+	s.mu.RLock()
+	session, ok := s.sessions[id]
+	s.mu.RUnlock()
+	if !ok {
+		if s.mirror != nil {
+			return s.mirror.GetSession(id)
+		}
+		return nil
+	}
+	return &session
+}
+
+// ListByUser 返回用户ID为 userID 的用户的所有会话，最新创建的在前
+func (s *SessionStore) ListByUser(userID string) []models.Session {
+	s.mu.RLock()
+	list := make([]models.Session, 0)
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			list = append(list, session)
+		}
+	}
+	s.mu.RUnlock()
+	if s.mirror != nil {
+		for _, session := range s.mirror.ListSessions(userID) {
+			if !slices.ContainsFunc(list, func(local models.Session) bool { return local.ID == session.ID }) {
+				list = append(list, session)
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Count 返回本实例上的会话数
+func (s *SessionStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.sessions)
+}
+
+// Touch 更新会话的最近使用时间，只在本实例上登录的会话会同步到副本
+func (s *SessionStore) Touch(id string, at time.Time) {
+	s.mu.Lock()
+	session, ok := s.sessions[id]
+	if ok {
+		session.LastSeen = at
+		s.sessions[id] = session
+	}
+	s.mu.Unlock()
+	if ok && s.mirror != nil {
+		s.mirror.PutSession(session)
+	}
+}
+
+// Remove 删除会话，返回是否存在；会话可能是在其他实例上登录的，同时从副本中删除
+func (s *SessionStore) Remove(id string) bool {
+	s.mu.Lock()
+	_, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	if s.mirror != nil {
+		if !ok {
+			ok = s.mirror.GetSession(id) != nil
+		}
+		s.mirror.DeleteSession(id)
+	}
+	return ok
+}
+
+// RemoveUser 删除用户ID为 userID 的用户的所有会话，返回本实例上删除的数量
+func (s *SessionStore) RemoveUser(userID string) int {
+	s.mu.Lock()
+	n := 0
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+			n++
+		}
+	}
+	s.mu.Unlock()
+	if s.mirror != nil {
+		s.mirror.DeleteUserSessions(userID)
+	}
+	return n
+}
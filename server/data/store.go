@@ -2,12 +2,18 @@ package data
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"sync"
+	"time"
 
 	"game/models"
+	"game/report"
+	"game/validate"
 )
 
 var (
@@ -17,31 +23,48 @@ var (
 func init() {
 	// 直接使用当前目录下的 data 目录，而不是基于可执行文件的位置
 	DataDir = "data"
-	fmt.Printf("数据目录: %s\n", DataDir)
-	if err := os.MkdirAll(DataDir, 0755); err != nil {
-		fmt.Printf("创建数据目录失败: %v\n", err)
-	}
 }
 
-type UserStore struct {
-	mu    sync.RWMutex
-	users []models.User
-	file  string
+// ensureDataDir 首次使用文件存储时创建数据目录，内存存储不会触及文件系统
+func ensureDataDir() {
+	dataDirOnce.Do(func() {
+		fmt.Printf("数据目录: %s\n", DataDir)
+		if err := os.MkdirAll(DataDir, 0755); err != nil {
+			fmt.Printf("创建数据目录失败: %v\n", err)
+		}
+	})
 }
 
-type RoomStore struct {
-	mu    sync.RWMutex
-	rooms []models.Room
-	file  string
+var dataDirOnce sync.Once
+
+// UserStore 用户存储，file 为空时为纯内存存储，数据不落盘
+type UserStore struct {
+	mu      sync.RWMutex
+	users   []models.User
+	file    string
+	loadErr error
+
+	listenMu     sync.RWMutex
+	listeners    []func(UserChange)
+	invalidators []func(username string)
+	feed         changeFeed
 }
 
-type ResultStore struct {
+// RoomStore 房间存储，file 为空时为纯内存存储，数据不落盘
+type RoomStore struct {
 	mu      sync.RWMutex
-	results []models.GameResult
+	rooms   []models.Room
 	file    string
+	loadErr error
+
+	listenMu     sync.RWMutex
+	listeners    []func(RoomChange)
+	invalidators []func(id string)
+	feed         changeFeed
 }
 
 func NewUserStore() *UserStore {
+	ensureDataDir()
 	file := filepath.Join(DataDir, "users.json")
 	store := &UserStore{
 		users: make([]models.User, 0),
@@ -51,7 +74,13 @@ func NewUserStore() *UserStore {
 	return store
 }
 
+// NewUserStoreInMemory 创建不读写文件的用户存储，用于测试和临时服务器
+func NewUserStoreInMemory() *UserStore {
+	return &UserStore{users: make([]models.User, 0)}
+}
+
 func NewRoomStore() *RoomStore {
+	ensureDataDir()
 	file := filepath.Join(DataDir, "rooms.json")
 	store := &RoomStore{
 		rooms: make([]models.Room, 0),
@@ -61,14 +90,9 @@ func NewRoomStore() *RoomStore {
 	return store
 }
 
-func NewResultStore() *ResultStore {
-	file := filepath.Join(DataDir, "game_results.json")
-	store := &ResultStore{
-		results: make([]models.GameResult, 0),
-		file:    file,
-	}
-	store.load()
-	return store
+// NewRoomStoreInMemory 创建不读写文件的房间存储，用于测试和临时服务器
+func NewRoomStoreInMemory() *RoomStore {
+	return &RoomStore{rooms: make([]models.Room, 0)}
 }
 
 func (s *UserStore) load() {
@@ -81,6 +105,23 @@ func (s *UserStore) load() {
 		fmt.Printf("加载用户数据失败: %v\n", err)
 		return
 	}
+	data, encrypted, err := openFile(getUsersKey(), data)
+	if err != nil {
+		// 无法解密时继续运行会在下次保存时覆盖原文件，停止写盘并交给调用方退出
+		s.loadErr = fmt.Errorf("解密用户数据失败: %w", err)
+		s.file = ""
+		return
+	}
+	if !encrypted && getUsersKey() != nil {
+		fmt.Println("用户数据为明文，将在下次保存时加密")
+	}
+	data, migrated, err := migrateDocument(s.file, data, UsersSchemaVersion, userMigrations)
+	if err != nil {
+		// 不再写回文件，以免覆盖无法升级的原数据
+		s.loadErr = fmt.Errorf("升级用户数据失败: %w", err)
+		s.file = ""
+		return
+	}
 	var usersData models.UsersData
 	if err := json.Unmarshal(data, &usersData); err != nil {
 		fmt.Printf("解析用户数据失败: %v\n", err)
@@ -88,25 +129,81 @@ func (s *UserStore) load() {
 		return
 	}
 	s.users = usersData.Users
+	if migrated {
+		s.save()
+		fmt.Printf("用户数据已升级到版本 %d\n", UsersSchemaVersion)
+	}
+}
+
+// LoadErr 返回加载时遇到的无法继续的错误（如版本过高、升级失败），此时存储不会写盘
+func (s *UserStore) LoadErr() error {
+	return s.loadErr
 }
 
-func (s *UserStore) save() {
-	usersData := models.UsersData{Users: s.users}
+func (s *UserStore) save() error {
+	if s.file == "" {
+		return nil
+	}
+	defer report.Track(report.SlowStore, "users", time.Now(), map[string]string{"records": strconv.Itoa(len(s.users))})
+	usersData := models.UsersData{SchemaVersion: UsersSchemaVersion, Users: s.users}
 	data, err := json.MarshalIndent(usersData, "", "  ")
 	if err != nil {
 		fmt.Printf("序列化用户数据失败: %v\n", err)
-		return
+		return err
 	}
-	if err := os.WriteFile(s.file, data, 0644); err != nil {
+	if data, err = sealFile(getUsersKey(), data); err != nil {
+		fmt.Printf("加密用户数据失败: %v\n", err)
+		return err
+	}
+	if err := os.WriteFile(s.file, data, 0600); err != nil {
 		fmt.Printf("保存用户数据失败: %v\n", err)
+		return err
 	}
+	return nil
 }
 
+// 所有查询方法返回数据副本，修改副本不会影响存储；需要修改时调用 Update 或 Modify 写回
+
 func (s *UserStore) Add(user models.User) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.users = append(s.users, user)
+	if user.UserID == "" {
+		user.UserID = NewUserID()
+	}
+	s.users = append(s.users, user.Clone())
 	s.save()
+	s.emit(nil, &user)
+}
+
+// 创建用户时的唯一性冲突
+var (
+	ErrUsernameTaken = errors.New("用户名已存在")
+	ErrEmailTaken    = errors.New("该邮箱已被注册")
+)
+
+// Create 添加新用户，用户名或邮箱与已有用户规范化后相同即视为冲突，其他用户用过的旧用户名同样不可用；
+// 检查与写入在同一把锁内完成。未指定 UserID 时生成新的用户ID
+func (s *UserStore) Create(user models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, email := validate.FoldUsername(user.Username), validate.FoldEmail(user.Email)
+	for i := range s.users {
+		if claimsName(s.users[i], name) {
+			return ErrUsernameTaken
+		}
+		if email != "" && validate.FoldEmail(s.users[i].Email) == email {
+			return ErrEmailTaken
+		}
+	}
+	if user.UserID == "" {
+		user.UserID = NewUserID()
+	}
+	s.users = append(s.users, user.Clone())
+	if err := s.save(); err != nil {
+		return err
+	}
+	s.emit(nil, &user)
+	return nil
 }
 
 func (s *UserStore) FindByUsername(username string) *models.User {
@@ -114,7 +211,8 @@ func (s *UserStore) FindByUsername(username string) *models.User {
 	defer s.mu.RUnlock()
 	for i := range s.users {
 		if s.users[i].Username == username {
-			return &s.users[i]
+			user := s.users[i].Clone()
+			return &user
 		}
 	}
 	return nil
@@ -125,7 +223,66 @@ func (s *UserStore) FindByEmail(email string) *models.User {
 	defer s.mu.RUnlock()
 	for i := range s.users {
 		if s.users[i].Email == email {
-			return &s.users[i]
+			user := s.users[i].Clone()
+			return &user
+		}
+	}
+	return nil
+}
+
+// FindByID 根据稳定的用户ID查找用户
+func (s *UserStore) FindByID(id string) *models.User {
+	if id == "" {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := range s.users {
+		if s.users[i].UserID == id {
+			user := s.users[i].Clone()
+			return &user
+		}
+	}
+	return nil
+}
+
+// claimsName 判断规范化后的用户名 folded 是否为 user 的当前或旧用户名
+func claimsName(user models.User, folded string) bool {
+	if validate.FoldUsername(user.Username) == folded {
+		return true
+	}
+	for _, prev := range user.PreviousNames {
+		if validate.FoldUsername(prev.Username) == folded {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByPreviousName 查找曾经使用过指定用户名的用户，不区分大小写
+func (s *UserStore) FindByPreviousName(username string) *models.User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name := validate.FoldUsername(username)
+	for i := range s.users {
+		for _, prev := range s.users[i].PreviousNames {
+			if validate.FoldUsername(prev.Username) == name {
+				user := s.users[i].Clone()
+				return &user
+			}
+		}
+	}
+	return nil
+}
+
+// FindByExternalAccount 查找关联了指定第三方账号的用户
+func (s *UserStore) FindByExternalAccount(provider, subject string) *models.User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := range s.users {
+		if s.users[i].HasExternalAccount(provider, subject) {
+			user := s.users[i].Clone()
+			return &user
 		}
 	}
 	return nil
@@ -136,8 +293,108 @@ func (s *UserStore) Update(username string, user models.User) bool {
 	defer s.mu.Unlock()
 	for i := range s.users {
 		if s.users[i].Username == username {
+			old := s.users[i]
+			user.UserID = old.UserID
+			s.users[i] = user.Clone()
+			s.save()
+			s.emit(&old, &user)
+			return true
+		}
+	}
+	return false
+}
+
+// Modify 在存储锁内读取、修改并写回用户，fn 返回 false 表示放弃修改，对 UserID 的修改会被忽略；
+// 返回用户是否存在且已写回。fn 中不能再访问 UserStore，否则会死锁
+func (s *UserStore) Modify(username string, fn func(user *models.User) bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.users {
+		if s.users[i].Username == username {
+			old := s.users[i]
+			user := old.Clone()
+			if !fn(&user) {
+				return false
+			}
+			user.UserID = old.UserID
 			s.users[i] = user
 			s.save()
+			s.emit(&old, &user)
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeEmail 将用户邮箱替换为 email 并清除待确认的修改，email 与其他用户规范化后相同时返回 ErrEmailTaken；
+// 检查与写入在同一把锁内完成。用户不存在时返回 false
+func (s *UserStore) ChangeEmail(username, email string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	folded := validate.FoldEmail(email)
+	index := -1
+	for i := range s.users {
+		if s.users[i].Username == username {
+			index = i
+		} else if validate.FoldEmail(s.users[i].Email) == folded {
+			return false, ErrEmailTaken
+		}
+	}
+	if index < 0 {
+		return false, nil
+	}
+	old := s.users[index]
+	user := old
+	user.Email = email
+	user.PendingEmail, user.EmailTokenHash, user.EmailTokenExpires = "", "", time.Time{}
+	s.users[index] = user
+	if err := s.save(); err != nil {
+		return false, err
+	}
+	s.emit(&old, &user)
+	return true, nil
+}
+
+// Rename 将用户名 username 改为 newName，并把旧用户名记入历史；newName 规范化后与其他用户的当前或旧用户名相同时
+// 返回 ErrUsernameTaken。改名后用户需要用新用户名重新登录，因此同时标记为离线。用户不存在时返回 false
+func (s *UserStore) Rename(username, newName string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	folded := validate.FoldUsername(newName)
+	index := -1
+	for i := range s.users {
+		if s.users[i].Username == username {
+			index = i
+		} else if claimsName(s.users[i], folded) {
+			return false, ErrUsernameTaken
+		}
+	}
+	if index < 0 {
+		return false, nil
+	}
+	old := s.users[index]
+	user := old
+	user.Username = newName
+	user.PreviousNames = append(slices.Clone(old.PreviousNames), models.NameChange{Username: username, ChangedAt: at})
+	user.Online = false
+	s.users[index] = user
+	if err := s.save(); err != nil {
+		s.users[index] = old
+		return false, err
+	}
+	s.emit(&old, &user)
+	return true, nil
+}
+
+func (s *UserStore) Remove(username string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.users {
+		if s.users[i].Username == username {
+			old := s.users[i]
+			s.users = append(s.users[:i], s.users[i+1:]...)
+			s.save()
+			s.emit(&old, nil)
 			return true
 		}
 	}
@@ -148,7 +405,9 @@ func (s *UserStore) GetAll() []models.User {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	result := make([]models.User, len(s.users))
-	copy(result, s.users)
+	for i := range s.users {
+		result[i] = s.users[i].Clone()
+	}
 	return result
 }
 
@@ -162,6 +421,13 @@ func (s *RoomStore) load() {
 		fmt.Printf("加载房间数据失败: %v\n", err)
 		return
 	}
+	data, migrated, err := migrateDocument(s.file, data, RoomsSchemaVersion, roomMigrations)
+	if err != nil {
+		// 不再写回文件，以免覆盖无法升级的原数据
+		s.loadErr = fmt.Errorf("升级房间数据失败: %w", err)
+		s.file = ""
+		return
+	}
 	var roomsData models.RoomsData
 	if err := json.Unmarshal(data, &roomsData); err != nil {
 		fmt.Printf("解析房间数据失败: %v\n", err)
@@ -169,25 +435,45 @@ func (s *RoomStore) load() {
 		return
 	}
 	s.rooms = roomsData.Rooms
+	if migrated {
+		s.save()
+		fmt.Printf("房间数据已升级到版本 %d\n", RoomsSchemaVersion)
+	}
 }
 
-func (s *RoomStore) save() {
-	roomsData := models.RoomsData{Rooms: s.rooms}
+// LoadErr 返回加载时遇到的无法继续的错误（如版本过高、升级失败），此时存储不会写盘
+func (s *RoomStore) LoadErr() error {
+	return s.loadErr
+}
+
+func (s *RoomStore) save() error {
+	if s.file == "" {
+		return nil
+	}
+	defer report.Track(report.SlowStore, "rooms", time.Now(), map[string]string{"records": strconv.Itoa(len(s.rooms))})
+	roomsData := models.RoomsData{SchemaVersion: RoomsSchemaVersion, Rooms: s.rooms}
 	data, err := json.MarshalIndent(roomsData, "", "  ")
 	if err != nil {
 		fmt.Printf("序列化房间数据失败: %v\n", err)
-		return
+		return err
 	}
 	if err := os.WriteFile(s.file, data, 0644); err != nil {
 		fmt.Printf("保存房间数据失败: %v\n", err)
+		return err
 	}
+	return nil
 }
 
+// 所有查询方法返回数据副本，修改副本不会影响存储；需要修改时调用 Update 或 Modify 写回
+
+// Add 添加房间，房间的修订号从 1 开始
 func (s *RoomStore) Add(room models.Room) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.rooms = append(s.rooms, room)
+	room.Version = 1
+	s.rooms = append(s.rooms, room.Clone())
 	s.save()
+	s.emit(nil, &room)
 }
 
 func (s *RoomStore) GetByID(id string) *models.Room {
@@ -195,7 +481,8 @@ func (s *RoomStore) GetByID(id string) *models.Room {
 	defer s.mu.RUnlock()
 	for i := range s.rooms {
 		if s.rooms[i].ID == id {
-			return &s.rooms[i]
+			room := s.rooms[i].Clone()
+			return &room
 		}
 	}
 	return nil
@@ -205,17 +492,56 @@ func (s *RoomStore) GetAll() []models.Room {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	result := make([]models.Room, len(s.rooms))
-	copy(result, s.rooms)
+	for i := range s.rooms {
+		result[i] = s.rooms[i].Clone()
+	}
 	return result
 }
 
-func (s *RoomStore) Update(room models.Room) bool {
+// 按修订号更新房间时的失败原因
+var (
+	ErrRoomNotFound    = errors.New("房间不存在")
+	ErrVersionConflict = errors.New("房间已被其他请求修改")
+)
+
+// Update 按修订号写回房间（比较并交换）：room.Version 必须与存储中的修订号相同，
+// 否则说明读取之后房间已被修改，返回 ErrVersionConflict，调用方应重新读取后重试。
+// 写入成功后修订号加一
+func (s *RoomStore) Update(room models.Room) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for i := range s.rooms {
 		if s.rooms[i].ID == room.ID {
+			old := s.rooms[i]
+			if room.Version != old.Version {
+				return ErrVersionConflict
+			}
+			room.Version++
+			s.rooms[i] = room.Clone()
+			s.save()
+			s.emit(&old, &room)
+			return nil
+		}
+	}
+	return ErrRoomNotFound
+}
+
+// Modify 在存储锁内读取、修改并写回房间，fn 返回 false 表示放弃修改；
+// 返回房间是否存在且已写回，写回时修订号加一。fn 中不能再访问 RoomStore，否则会死锁
+func (s *RoomStore) Modify(id string, fn func(room *models.Room) bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.rooms {
+		if s.rooms[i].ID == id {
+			old := s.rooms[i]
+			room := old.Clone()
+			if !fn(&room) {
+				return false
+			}
+			room.Version = old.Version + 1
 			s.rooms[i] = room
 			s.save()
+			s.emit(&old, &room)
 			return true
 		}
 	}
@@ -227,56 +553,12 @@ func (s *RoomStore) Remove(id string) bool {
 	defer s.mu.Unlock()
 	for i := range s.rooms {
 		if s.rooms[i].ID == id {
+			old := s.rooms[i]
 			s.rooms = append(s.rooms[:i], s.rooms[i+1:]...)
 			s.save()
+			s.emit(&old, nil)
 			return true
 		}
 	}
 	return false
-}
-
-func (s *ResultStore) load() {
-	data, err := os.ReadFile(s.file)
-	if err != nil {
-		if os.IsNotExist(err) {
-			s.results = make([]models.GameResult, 0)
-			return
-		}
-		fmt.Printf("加载游戏结果数据失败: %v\n", err)
-		return
-	}
-	var resultsData models.GameResultsData
-	if err := json.Unmarshal(data, &resultsData); err != nil {
-		fmt.Printf("解析游戏结果数据失败: %v\n", err)
-		s.results = make([]models.GameResult, 0)
-		return
-	}
-	s.results = resultsData.Results
-}
-
-func (s *ResultStore) save() {
-	resultsData := models.GameResultsData{Results: s.results}
-	data, err := json.MarshalIndent(resultsData, "", "  ")
-	if err != nil {
-		fmt.Printf("序列化游戏结果数据失败: %v\n", err)
-		return
-	}
-	if err := os.WriteFile(s.file, data, 0644); err != nil {
-		fmt.Printf("保存游戏结果数据失败: %v\n", err)
-	}
-}
-
-func (s *ResultStore) Add(result models.GameResult) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.results = append(s.results, result)
-	s.save()
-}
-
-func (s *ResultStore) GetAll() []models.GameResult {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	result := make([]models.GameResult, len(s.results))
-	copy(result, s.results)
-	return result
-}
+}
\ No newline at end of file
@@ -0,0 +1,126 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// TournamentStore 赛事存储，按赛事ID索引，file 为空时为纯内存存储
+type TournamentStore struct {
+	mu          sync.RWMutex
+	tournaments map[string]models.Tournament
+	file        string
+}
+
+// NewTournamentStore 创建保存到 tournaments.json 的赛事存储
+func NewTournamentStore() *TournamentStore {
+	ensureDataDir()
+	s := &TournamentStore{
+		tournaments: make(map[string]models.Tournament),
+		file:        filepath.Join(DataDir, "tournaments.json"),
+	}
+	s.load()
+	return s
+}
+
+// NewTournamentStoreInMemory 创建不读写文件的赛事存储
+func NewTournamentStoreInMemory() *TournamentStore {
+	return &TournamentStore{tournaments: make(map[string]models.Tournament)}
+}
+
+func (s *TournamentStore) load() {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载赛事失败: %v\n", err)
+		}
+		return
+	}
+	var stored models.TournamentsData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析赛事失败: %v\n", err)
+		return
+	}
+	for _, t := range stored.Tournaments {
+		s.tournaments[t.ID] = t
+	}
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *TournamentStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "tournaments", time.Now(), nil)
+	stored := models.TournamentsData{Tournaments: s.sorted()}
+	content, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化赛事失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0600); err != nil {
+		fmt.Printf("保存赛事失败: %v\n", err)
+	}
+}
+
+// sorted 返回按创建时间排列的赛事副本，调用方需持有锁
+func (s *TournamentStore) sorted() []models.Tournament {
+	tournaments := make([]models.Tournament, 0, len(s.tournaments))
+	for _, t := range s.tournaments {
+		tournaments = append(tournaments, t.Clone())
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		if !tournaments[i].CreatedAt.Equal(tournaments[j].CreatedAt) {
+			return tournaments[i].CreatedAt.Before(tournaments[j].CreatedAt)
+		}
+		return tournaments[i].ID < tournaments[j].ID
+	})
+	return tournaments
+}
+
+// Get 根据ID查找赛事
+func (s *TournamentStore) Get(id string) (models.Tournament, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tournaments[id]
+	return t.Clone(), ok
+}
+
+// All 返回所有赛事，按创建时间排列
+func (s *TournamentStore) All() []models.Tournament {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted()
+}
+
+// Create 保存新赛事
+func (s *TournamentStore) Create(t models.Tournament) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tournaments[t.ID] = t.Clone()
+	s.save()
+}
+
+// Modify 在写锁内修改赛事并保存，赛事不存在时返回 false；fn 返回 false 时不保存
+func (s *TournamentStore) Modify(id string, fn func(t *models.Tournament) bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tournaments[id]
+	if !ok {
+		return false
+	}
+	t = t.Clone()
+	if fn(&t) {
+		s.tournaments[id] = t
+		s.save()
+	}
+	return true
+}
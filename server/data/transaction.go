@@ -0,0 +1,255 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"game/models"
+)
+
+// txRecord 一次事务提交的全部变更，同时也是重做日志的内容
+type txRecord struct {
+	Users        []models.User `json:"users,omitempty"`
+	Rooms        []models.Room `json:"rooms,omitempty"`
+	RemovedRooms []string      `json:"removed_rooms,omitempty"`
+}
+
+// Txn 同时修改用户和房间的事务，变更先暂存，fn 成功返回后一起提交
+type Txn struct {
+	users     *UserStore
+	rooms     *RoomStore
+	userEdits map[string]models.User
+	roomEdits map[string]*models.Room // nil 表示删除
+	userOrder []string
+	roomOrder []string
+}
+
+// txJournalFile 事务重做日志：提交时先落盘，两个存储都保存成功后删除；
+// 启动时若仍存在，说明上次提交中途崩溃，由 RecoverTransaction 重放
+func txJournalFile() string {
+	return filepath.Join(DataDir, "transaction.journal")
+}
+
+// RunTransaction 在用户和房间存储上执行事务，fn 返回错误时所有暂存的变更都会丢弃。
+// 事务期间持有两个存储的写锁（先房间后用户），fn 中不能再直接访问这两个存储
+func RunTransaction(users *UserStore, rooms *RoomStore, fn func(tx *Txn) error) error {
+	rooms.mu.Lock()
+	defer rooms.mu.Unlock()
+	users.mu.Lock()
+	defer users.mu.Unlock()
+
+	tx := &Txn{
+		users:     users,
+		rooms:     rooms,
+		userEdits: make(map[string]models.User),
+		roomEdits: make(map[string]*models.Room),
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+// User 读取用户副本，包含本事务中尚未提交的修改
+func (tx *Txn) User(username string) *models.User {
+	if user, ok := tx.userEdits[username]; ok {
+		return &user
+	}
+	for i := range tx.users.users {
+		if tx.users.users[i].Username == username {
+			user := tx.users.users[i]
+			return &user
+		}
+	}
+	return nil
+}
+
+// UpdateUser 暂存对已有用户的修改，用户不存在时返回 false
+func (tx *Txn) UpdateUser(user models.User) bool {
+	if tx.User(user.Username) == nil {
+		return false
+	}
+	if _, ok := tx.userEdits[user.Username]; !ok {
+		tx.userOrder = append(tx.userOrder, user.Username)
+	}
+	tx.userEdits[user.Username] = user
+	return true
+}
+
+// Room 读取房间副本，包含本事务中尚未提交的修改
+func (tx *Txn) Room(id string) *models.Room {
+	if room, ok := tx.roomEdits[id]; ok {
+		if room == nil {
+			return nil
+		}
+		clone := room.Clone()
+		return &clone
+	}
+	for i := range tx.rooms.rooms {
+		if tx.rooms.rooms[i].ID == id {
+			room := tx.rooms.rooms[i].Clone()
+			return &room
+		}
+	}
+	return nil
+}
+
+// PutRoom 暂存房间的新增或修改
+func (tx *Txn) PutRoom(room models.Room) {
+	if _, ok := tx.roomEdits[room.ID]; !ok {
+		tx.roomOrder = append(tx.roomOrder, room.ID)
+	}
+	clone := room.Clone()
+	tx.roomEdits[room.ID] = &clone
+}
+
+// RemoveRoom 暂存房间删除
+func (tx *Txn) RemoveRoom(id string) {
+	if _, ok := tx.roomEdits[id]; !ok {
+		tx.roomOrder = append(tx.roomOrder, id)
+	}
+	tx.roomEdits[id] = nil
+}
+
+// commit 写重做日志后应用变更并保存两个存储
+func (tx *Txn) commit() error {
+	var record txRecord
+	for _, username := range tx.userOrder {
+		record.Users = append(record.Users, tx.userEdits[username])
+	}
+	for _, id := range tx.roomOrder {
+		if room := tx.roomEdits[id]; room != nil {
+			record.Rooms = append(record.Rooms, *room)
+		} else {
+			record.RemovedRooms = append(record.RemovedRooms, id)
+		}
+	}
+	if len(record.Users) == 0 && len(record.Rooms) == 0 && len(record.RemovedRooms) == 0 {
+		return nil
+	}
+
+	durable := tx.users.file != "" || tx.rooms.file != ""
+	if durable {
+		if err := writeTxJournal(record); err != nil {
+			return fmt.Errorf("写入事务日志失败: %v", err)
+		}
+	}
+	if err := applyTxRecord(tx.users, tx.rooms, record); err != nil {
+		// 日志已落盘，事务视为已提交，下次启动时重放
+		return err
+	}
+	if durable {
+		if err := os.Remove(txJournalFile()); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("删除事务日志失败: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// applyTxRecord 将变更应用到内存并保存，调用方需持有两个存储的写锁
+func applyTxRecord(users *UserStore, rooms *RoomStore, record txRecord) error {
+	for _, user := range record.Users {
+		for i := range users.users {
+			if users.users[i].Username == user.Username {
+				old := users.users[i]
+				users.users[i] = user
+				users.emit(&old, &user)
+				break
+			}
+		}
+	}
+	for _, room := range record.Rooms {
+		// 事务持有存储写锁，不会与其他写入冲突，直接在当前修订号上加一
+		var old *models.Room
+		for i := range rooms.rooms {
+			if rooms.rooms[i].ID == room.ID {
+				prev := rooms.rooms[i]
+				old = &prev
+				room.Version = prev.Version + 1
+				rooms.rooms[i] = room.Clone()
+				break
+			}
+		}
+		if old == nil {
+			room.Version = 1
+			rooms.rooms = append(rooms.rooms, room.Clone())
+		}
+		rooms.emit(old, &room)
+	}
+	for _, id := range record.RemovedRooms {
+		for i := range rooms.rooms {
+			if rooms.rooms[i].ID == id {
+				old := rooms.rooms[i]
+				rooms.rooms = append(rooms.rooms[:i], rooms.rooms[i+1:]...)
+				rooms.emit(&old, nil)
+				break
+			}
+		}
+	}
+
+	if len(record.Rooms) > 0 || len(record.RemovedRooms) > 0 {
+		if err := rooms.save(); err != nil {
+			return err
+		}
+	}
+	if len(record.Users) > 0 {
+		if err := users.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTxJournal 将事务记录写入重做日志并刷盘
+func writeTxJournal(record txRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	tmp := txJournalFile() + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, txJournalFile())
+}
+
+// RecoverTransaction 重放上次未完成提交的事务，需要在存储加载后、对外服务前调用
+func RecoverTransaction(users *UserStore, rooms *RoomStore) error {
+	data, err := os.ReadFile(txJournalFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var record txRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		// 日志没有完整写入（重命名前崩溃不会出现这种情况），说明事务未提交
+		fmt.Printf("事务日志损坏，已丢弃: %v\n", err)
+		return os.Remove(txJournalFile())
+	}
+
+	rooms.mu.Lock()
+	defer rooms.mu.Unlock()
+	users.mu.Lock()
+	defer users.mu.Unlock()
+	if err := applyTxRecord(users, rooms, record); err != nil {
+		return err
+	}
+	fmt.Println("已重放未完成的事务")
+	return os.Remove(txJournalFile())
+}
@@ -0,0 +1,123 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/report"
+)
+
+// WordStore 屏蔽词存储，词统一转为小写，file 为空时为纯内存存储
+type WordStore struct {
+	mu    sync.RWMutex
+	words []string // 按字母序排列，不重复
+	file  string
+}
+
+// NewWordStore 创建保存到 banned_words.json 的屏蔽词存储；文件不存在时用 seed 初始化
+func NewWordStore(seed []string) *WordStore {
+	ensureDataDir()
+	s := &WordStore{file: filepath.Join(DataDir, "banned_words.json")}
+	if !s.load() {
+		s.add(seed)
+	}
+	return s
+}
+
+// NewWordStoreInMemory 创建不读写文件的屏蔽词存储，初始内容为 seed
+func NewWordStoreInMemory(seed []string) *WordStore {
+	s := &WordStore{}
+	s.add(seed)
+	return s
+}
+
+// load 读取文件，返回文件是否存在
+func (s *WordStore) load() bool {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("加载屏蔽词失败: %v\n", err)
+			return true
+		}
+		return false
+	}
+	var stored models.WordListData
+	if err := json.Unmarshal(content, &stored); err != nil {
+		fmt.Printf("解析屏蔽词失败: %v\n", err)
+		return true
+	}
+	s.words = stored.Words
+	return true
+}
+
+// save 写入文件，调用方需持有写锁
+func (s *WordStore) save() {
+	if s.file == "" {
+		return
+	}
+	defer report.Track(report.SlowStore, "banned_words", time.Now(), nil)
+	content, err := json.MarshalIndent(models.WordListData{Words: s.words}, "", "  ")
+	if err != nil {
+		fmt.Printf("序列化屏蔽词失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(s.file, content, 0644); err != nil {
+		fmt.Printf("保存屏蔽词失败: %v\n", err)
+	}
+}
+
+// All 返回全部屏蔽词
+func (s *WordStore) All() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.words)
+}
+
+// Add 添加屏蔽词，忽略空词和已存在的词，返回实际添加的数量
+func (s *WordStore) Add(words []string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.add(words)
+	if n > 0 {
+		s.save()
+	}
+	return n
+}
+
+// add 添加屏蔽词并保持有序，调用方需持有写锁
+func (s *WordStore) add(words []string) int {
+	n := 0
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" {
+			continue
+		}
+		i, found := slices.BinarySearch(s.words, word)
+		if found {
+			continue
+		}
+		s.words = slices.Insert(s.words, i, word)
+		n++
+	}
+	return n
+}
+
+// Remove 删除屏蔽词，不区分大小写，返回是否存在
+func (s *WordStore) Remove(word string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, found := slices.BinarySearch(s.words, strings.ToLower(strings.TrimSpace(word)))
+	if !found {
+		return false
+	}
+	s.words = slices.Delete(s.words, i, i+1)
+	s.save()
+	return true
+}
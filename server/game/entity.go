@@ -0,0 +1,106 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 实体类型
+const (
+	EntityBullet     = "bullet"     // 子弹
+	EntityPickup     = "pickup"     // 地图上的拾取物
+	EntityProjectile = "projectile" // 手雷、火箭等抛射物
+	EntityTarget     = "target"     // 练习靶场的固定靶
+)
+
+// Entity 对局中由服务器分配ID的实体
+type Entity struct {
+	ID        string
+	Kind      string
+	Owner     string // 生成实体的玩家，拾取物为空
+	SpawnedAt time.Time
+	ExpiresAt time.Time // 到期后自动移除，零值表示不会过期
+	seq       uint64
+}
+
+// Entities 单局对局的实体ID分配器和存活实体登记表。
+// ID 由类型和对局内单调递增的序号组成，同一局内不会重复，客户端不再自行生成；
+// 不是并发安全的，只能在对局协程中使用
+type Entities struct {
+	next uint64
+	live map[string]Entity
+}
+
+// NewEntities 创建 Entities 实例
+func NewEntities() *Entities {
+	return &Entities{live: make(map[string]Entity)}
+}
+
+// Spawn 分配ID并登记实体，ttl 不大于 0 时实体不会过期
+func (e *Entities) Spawn(kind, owner string, now time.Time, ttl time.Duration) Entity {
+	e.next++
+	ent := Entity{
+		ID:        fmt.Sprintf("%s_%d", kind, e.next),
+		seq:       e.next,
+		Kind:      kind,
+		Owner:     owner,
+		SpawnedAt: now,
+	}
+	if ttl > 0 {
+		ent.ExpiresAt = now.Add(ttl)
+	}
+	e.live[ent.ID] = ent
+	return ent
+}
+
+// Get 返回存活的实体
+func (e *Entities) Get(id string) (Entity, bool) {
+	ent, ok := e.live[id]
+	return ent, ok
+}
+
+// Despawn 移除实体，实体不存在或已被移除时返回 false
+func (e *Entities) Despawn(id string) (Entity, bool) {
+	ent, ok := e.live[id]
+	if ok {
+		delete(e.live, id)
+	}
+	return ent, ok
+}
+
+// Expire 移除并返回在 now 之前到期的实体，按生成顺序排列
+func (e *Entities) Expire(now time.Time) []Entity {
+	var expired []Entity
+	for id, ent := range e.live {
+		if !ent.ExpiresAt.IsZero() && !now.Before(ent.ExpiresAt) {
+			expired = append(expired, ent)
+			delete(e.live, id)
+		}
+	}
+	sortBySpawn(expired)
+	return expired
+}
+
+// Clear 移除并返回所有存活的实体，按生成顺序排列，用于换局时清场
+func (e *Entities) Clear() []Entity {
+	cleared := make([]Entity, 0, len(e.live))
+	for _, ent := range e.live {
+		cleared = append(cleared, ent)
+	}
+	e.live = make(map[string]Entity)
+	sortBySpawn(cleared)
+	return cleared
+}
+
+// Len 返回存活实体数
+func (e *Entities) Len() int {
+	return len(e.live)
+}
+
+// sortBySpawn 按分配ID的先后排序
+func sortBySpawn(entities []Entity) {
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].seq < entities[j].seq
+	})
+}
@@ -0,0 +1,81 @@
+// Package game 管理服务器上进行中的对局。
+// 对局的模拟逻辑（逻辑帧、操作校验、回放等）由 Session 的实现负责，SessionManager 按房间ID登记对局，
+// 在开始游戏时启动、在对局结束或被中止时回收，Hub 只负责把客户端消息投递给对应的对局
+package game
+
+import "sync"
+
+// Session 一局进行中的对局
+type Session interface {
+	// Run 运行对局直到结束，返回后对局从 SessionManager 中移除
+	Run()
+	// Abandon 请求中止对局，对局应尽快结束并从 Run 返回；对局已结束时不做任何事
+	Abandon()
+}
+
+// SessionManager 按房间ID管理进行中的对局，每个房间同时最多一局
+type SessionManager struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewSessionManager 创建 SessionManager 实例
+func NewSessionManager() *SessionManager {
+	return &SessionManager{sessions: make(map[string]Session)}
+}
+
+// Start 为房间创建对局并在独立协程中运行，房间已有进行中的对局时不调用 create 并返回 false。
+// create 在管理器的锁内调用，不能再访问 SessionManager
+func (m *SessionManager) Start(roomID string, create func() Session) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sessions[roomID]; ok {
+		return false
+	}
+	s := create()
+	m.sessions[roomID] = s
+	go func() {
+		defer m.remove(roomID, s)
+		s.Run()
+	}()
+	return true
+}
+
+// Get 返回房间进行中的对局，没有时返回 nil
+func (m *SessionManager) Get(roomID string) Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sessions[roomID]
+}
+
+// Abandon 中止房间进行中的对局，没有对局时返回 false
+func (m *SessionManager) Abandon(roomID string) bool {
+	s := m.Get(roomID)
+	if s == nil {
+		return false
+	}
+	s.Abandon()
+	return true
+}
+
+// AbandonAll 中止所有进行中的对局，用于服务器关闭
+func (m *SessionManager) AbandonAll() {
+	m.mu.Lock()
+	sessions := make([]Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, s)
+	}
+	m.mu.Unlock()
+	for _, s := range sessions {
+		s.Abandon()
+	}
+}
+
+// remove 对局结束后移除登记，房间已开始新的对局时保留新对局
+func (m *SessionManager) remove(roomID string, s Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions[roomID] == s {
+		delete(m.sessions, roomID)
+	}
+}
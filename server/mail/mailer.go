@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mailer 定义邮件发送接口
+type Mailer interface {
+	// Send 向 to 发送一封纯文本邮件
+	Send(to, subject, body string) error
+}
+
+// LogMailer 默认的发送实现，只把邮件写入日志，用于未配置 SMTP 的开发环境
+type LogMailer struct{}
+
+// Send 将邮件内容写入日志
+func (LogMailer) Send(to, subject, body string) error {
+	log.Printf("[mail] 收件人 %s，主题 %s\n%s", to, subject, body)
+	return nil
+}
+
+// SMTPMailer 通过 SMTP 服务器发送邮件，username 为空时不做认证
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer 创建 SMTPMailer 实例，addr 格式为 host:port
+func NewSMTPMailer(addr, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send 发送邮件
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("发送邮件到 %s 失败: %v", to, err)
+	}
+	return nil
+}
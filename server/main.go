@@ -3,9 +3,18 @@ package main
 import (
 	"game/app"
 	"log"
+	"os"
 )
 
 func main() {
+	// 带参数时执行运维命令，例如 game-server encrypt-users
+	if len(os.Args) > 1 {
+		if err := app.RunCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("命令执行失败: %v", err)
+		}
+		return
+	}
+
 	// 创建并启动服务器
 	server := app.NewServer()
 	if err := server.Start(); err != nil {
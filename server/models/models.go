@@ -1,41 +1,861 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"strings"
+	"time"
+)
 
 type User struct {
+	UserID    string    `json:"id"`       // 创建时生成的稳定ID，不可修改；会话、登录历史、刷新令牌和游戏结果都按它关联用户
+	Username  string    `json:"username"` // 用于登录、显示和查找，可以修改
+	Password  string    `json:"password"`
+	Email     string    `json:"email"`
+	Online    bool      `json:"online"`
+	LoginTime time.Time `json:"login_time"`
+	RoomID    string    `json:"room_id"`
+	Region    string    `json:"region,omitempty"` // 最近一次登录所在的区域
+
+	ExternalAccounts []ExternalAccount `json:"external_accounts,omitempty"` // 关联的第三方账号
+
+	// 待确认的新邮箱，以及发到新邮箱的确认令牌（只保存 SHA-256 摘要）和过期时间；确认后才替换 Email
+	PendingEmail      string    `json:"pending_email,omitempty"`
+	EmailTokenHash    string    `json:"email_token_hash,omitempty"`
+	EmailTokenExpires time.Time `json:"email_token_expires,omitempty"`
+
+	// 用过的旧用户名，按修改时间升序；旧用户名不能再被其他用户使用，仍可用于查找该用户
+	PreviousNames []NameChange `json:"previous_names,omitempty"`
+
+	AvatarUpdatedAt time.Time `json:"avatar_updated_at,omitempty"` // 最近一次上传头像的时间，零值表示没有头像
+
+	RoomFilters []RoomFilter `json:"room_filters,omitempty"` // 保存的房间列表筛选条件，按名称区分
+
+	HideFromSearch bool `json:"hide_from_search,omitempty"` // 隐私设置：不出现在玩家搜索结果中，仍可按完整用户名查找
+
+	// 拥有的称号和徽章，以及当前装备的称号（Title.Key()，为空表示未装备）
+	Titles      []Title `json:"titles,omitempty"`
+	ActiveTitle string  `json:"active_title,omitempty"`
+}
+
+// 称号来源
+const (
+	TitleSourceAchievement = "achievement" // 达成成就后自动授予
+	TitleSourceSeason      = "season"      // 赛季排名结算时授予
+)
+
+// Title 用户拥有的称号或徽章，ID 为称号定义的ID，赛季称号另带赛季名，同一称号在不同赛季可以分别获得
+type Title struct {
+	ID        string    `json:"id"`
+	Season    string    `json:"season,omitempty"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// Key 称号在用户拥有的称号中的唯一标识，赛季称号为 "ID@赛季"
+func (t Title) Key() string {
+	if t.Season == "" {
+		return t.ID
+	}
+	return t.ID + "@" + t.Season
+}
+
+// RoomFilter 用户保存的房间列表筛选条件，为空的条件不限制
+type RoomFilter struct {
+	Name    string `json:"name"`
+	Map     string `json:"map,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Mode    string `json:"mode,omitempty"`     // 地图目标，见 ObjectiveKingOfTheHill 等
+	NotFull bool   `json:"not_full,omitempty"` // 只显示还有空位的房间
+}
+
+// Match 判断房间是否满足筛选条件
+func (f RoomFilter) Match(room Room) bool {
+	switch {
+	case f.Map != "" && room.Map != f.Map:
+		return false
+	case f.Region != "" && room.Region != f.Region:
+		return false
+	case f.Mode != "" && room.Rules.Objective != f.Mode:
+		return false
+	case f.NotFull && len(room.Players) >= room.MaxPlayers:
+		return false
+	}
+	return true
+}
+
+// Clone 返回用户的深拷贝，修改副本不会影响原用户
+func (u User) Clone() User {
+	u.ExternalAccounts = append([]ExternalAccount(nil), u.ExternalAccounts...)
+	u.PreviousNames = append([]NameChange(nil), u.PreviousNames...)
+	u.RoomFilters = append([]RoomFilter(nil), u.RoomFilters...)
+	u.Titles = append([]Title(nil), u.Titles...)
+	return u
+}
+
+// RoomFilter 按名称查找用户保存的筛选条件，不存在时返回 nil
+func (u User) RoomFilter(name string) *RoomFilter {
+	for _, f := range u.RoomFilters {
+		if f.Name == name {
+			return &f
+		}
+	}
+	return nil
+}
+
+// NameChange 一次用户名修改，Username 为修改前的用户名
+type NameChange struct {
+	Username  string    `json:"username"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// LastRenamed 返回最近一次修改用户名的时间，从未修改过时为零值
+func (u User) LastRenamed() time.Time {
+	if len(u.PreviousNames) == 0 {
+		return time.Time{}
+	}
+	return u.PreviousNames[len(u.PreviousNames)-1].ChangedAt
+}
+
+// ExternalAccount 关联到用户的第三方登录账号
+type ExternalAccount struct {
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// Session 一次登录会话及其设备信息
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`  // 会话所属用户的稳定ID
+	Username  string    `json:"username"` // 登录时的用户名
+	UserAgent string    `json:"user_agent"`
+	Device    string    `json:"device"` // 由 UserAgent 推断的设备类型，例如 Windows、Android
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`        // 最近一次使用该会话建立 WebSocket 连接的时间
+	Region    string    `json:"region,omitempty"` // 登录时指定或按测速推断的区域
+}
+
+// RefreshToken 长期有效的刷新令牌，只保存令牌的 SHA-256 摘要。每次刷新都会轮换为同一 Family 的新令牌，
+// 已轮换的令牌保留到过期，再次使用说明令牌可能被盗用，此时整个 Family 被吊销
+type RefreshToken struct {
+	Hash      string    `json:"hash"`
+	Family    string    `json:"family"`  // 同一次登录轮换出的令牌共用的标识
+	UserID    string    `json:"user_id"` // 令牌所属用户的稳定ID
+	Device    string    `json:"device"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RotatedAt time.Time `json:"rotated_at,omitempty"` // 已被轮换的时间，为零值时是当前有效的令牌
+}
+
+// RefreshTokensData refresh_tokens.json 的文件结构
+type RefreshTokensData struct {
+	Tokens []RefreshToken `json:"tokens"`
+}
+
+// Invite 房主发出的房间邀请，被邀请的用户不在线时保留到过期，上线后推送
+type Invite struct {
+	ID        string    `json:"id"`
+	RoomID    string    `json:"room_id"`
+	RoomName  string    `json:"room_name"`
+	FromID    string    `json:"from_id"` // 邀请方的稳定用户ID
+	From      string    `json:"from"`    // 邀请时邀请方的用户名
+	ToID      string    `json:"to_id"`   // 被邀请方的稳定用户ID
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// InvitesData invites.json 的文件结构
+type InvitesData struct {
+	Invites []Invite `json:"invites"`
+}
+
+// 作弊标记状态
+const (
+	CheatFlagPending   = "pending"   // 违规分达到阈值，等待审核；期间该玩家参与的对局不计入评分
+	CheatFlagCleared   = "cleared"   // 审核后认定没有作弊，违规分清零
+	CheatFlagConfirmed = "confirmed" // 审核后认定作弊，该玩家参与的对局不再计入评分
+)
+
+// 对局中服务器校验发现的违规类型
+const (
+	ViolationMoveCorrection = "move_correction" // 位置超出速度上限或穿过障碍物
+	ViolationInvalidHit     = "invalid_hit"     // 使用未装备的武器或不属于自己、已移除的子弹上报命中
+	ViolationSeedMismatch   = "seed_mismatch"   // 上报的散布或暴击与本局随机种子的结果不符
+	ViolationVoteKicked     = "vote_kicked"     // 对局中被其他玩家投票踢出
+)
+
+// CheatFlag 玩家的违规记录和作弊标记，按稳定用户ID索引；Status 为空表示违规分尚未达到阈值
+type CheatFlag struct {
+	UserID     string         `json:"user_id"`
+	Username   string         `json:"username"`             // 最近一次记录违规时的用户名
+	Score      int            `json:"score"`                // 按违规类型加权累计的违规分
+	Violations map[string]int `json:"violations,omitempty"` // 按违规类型统计的次数
+	Status     string         `json:"status,omitempty"`
+	FlaggedAt  time.Time      `json:"flagged_at,omitempty"`
+	ReviewedAt time.Time      `json:"reviewed_at,omitempty"`
+	Note       string         `json:"note,omitempty"` // 审核备注
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// Flagged 是否处于待审核或已确认作弊状态
+func (f CheatFlag) Flagged() bool {
+	return f.Status == CheatFlagPending || f.Status == CheatFlagConfirmed
+}
+
+// CheatFlagsData cheat_flags.json 的文件结构
+type CheatFlagsData struct {
+	Flags []CheatFlag `json:"flags"`
+}
+
+// 结果申诉状态
+const (
+	DisputePending  = "pending"  // 等待审核
+	DisputeUpheld   = "upheld"   // 申诉成立，通过评分补偿记录撤销该局对评分的影响
+	DisputeRejected = "rejected" // 申诉不成立，结果维持不变
+)
+
+// Dispute 玩家对已记录的对局结果提出的申诉。创建时保存结果、对局期间的流量录制和对局玩家的违规记录快照，供审核使用
+type Dispute struct {
+	ID         string    `json:"id"`
+	ResultID   string    `json:"result_id"`
+	UserID     string    `json:"user_id"`
 	Username   string    `json:"username"`
-	Password   string    `json:"password"`
-	Email      string    `json:"email"`
-	Online     bool      `json:"online"`
-	LoginTime  time.Time `json:"login_time"`
-	RoomID     string    `json:"room_id"`
+	Reason     string    `json:"reason"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReviewedAt time.Time `json:"reviewed_at,omitempty"`
+	Note       string    `json:"note,omitempty"` // 审核备注
+
+	Result      GameResult         `json:"result"`
+	Replay      []TrafficRecord    `json:"replay,omitempty"`      // 对局期间房间的入站流量录制，未开启录制时为空
+	Flags       []CheatFlag        `json:"flags,omitempty"`       // 申诉时对局玩家的违规记录
+	Adjustments []RatingAdjustment `json:"adjustments,omitempty"` // 申诉成立时写入的评分补偿
+}
+
+// Clone 返回申诉的深拷贝，回放中的原始消息不会被修改，共享底层数据
+func (d Dispute) Clone() Dispute {
+	d.Result = d.Result.Clone()
+	d.Replay = append([]TrafficRecord(nil), d.Replay...)
+	flags := make([]CheatFlag, 0, len(d.Flags))
+	for _, f := range d.Flags {
+		f.Violations = maps.Clone(f.Violations)
+		flags = append(flags, f)
+	}
+	d.Flags = flags
+	d.Adjustments = append([]RatingAdjustment(nil), d.Adjustments...)
+	return d
+}
+
+// DisputesData disputes.json 的文件结构
+type DisputesData struct {
+	Disputes []Dispute `json:"disputes"`
+}
+
+// RatingAdjustment 评分补偿记录：申诉成立时抵消一局结果为玩家带来的评分变化，估算评分时与历史胜负一起计入
+type RatingAdjustment struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Delta     int       `json:"delta"`
+	ResultID  string    `json:"result_id"`
+	DisputeID string    `json:"dispute_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RatingAdjustmentsData rating_adjustments.json 的文件结构
+type RatingAdjustmentsData struct {
+	Adjustments []RatingAdjustment `json:"adjustments"`
+}
+
+// PracticeRecord 练习靶场的个人最佳成绩，按稳定用户ID保存；得分相同时命中率高的成绩更好
+type PracticeRecord struct {
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"`
+	Score    int       `json:"score"`
+	Hits     int       `json:"hits"`
+	Shots    int       `json:"shots"`
+	Accuracy float64   `json:"accuracy"`
+	Runs     int       `json:"runs"` // 完成的练习次数，包括没有刷新纪录的练习
+	At       time.Time `json:"at"`   // 创造最佳成绩的时间
+}
+
+// Beats 判断本次成绩是否优于 best
+func (r PracticeRecord) Beats(best PracticeRecord) bool {
+	if r.Score != best.Score {
+		return r.Score > best.Score
+	}
+	return r.Accuracy > best.Accuracy
+}
+
+// PracticeData practice.json 的文件结构
+type PracticeData struct {
+	Records []PracticeRecord `json:"records"`
+}
+
+// 赛事状态
+const (
+	TournamentOpen     = "open"     // 报名中
+	TournamentSeeded   = "seeded"   // 报名已截止，已按评分生成对阵表，比赛进行中
+	TournamentFinished = "finished" // 决赛已决出冠军
+)
+
+// 赛制
+const (
+	TournamentSingleElimination = "single_elimination" // 单败淘汰
+	TournamentDoubleElimination = "double_elimination" // 双败淘汰，胜者组的败者落入败者组，输两场才被淘汰
+	TournamentRoundRobin        = "round_robin"        // 按种子蛇形分组，小组内单循环
+)
+
+// 双败淘汰中比赛所在的分区，胜者组和单败淘汰的比赛为空
+const (
+	BracketLosers = "losers" // 败者组
+	BracketFinal  = "final"  // 总决赛，败者组冠军赢下首场时加赛第 2 轮
+)
+
+// Tournament 赛事。报名截止时按报名玩家当时的评分确定种子并生成对阵表
+type Tournament struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Format      string              `json:"format"`
+	Status      string              `json:"status"`
+	MaxEntrants int                 `json:"max_entrants,omitempty"` // 报名人数上限，0 表示不限
+	GroupSize   int                 `json:"group_size,omitempty"`   // 循环赛每组人数上限，0 表示所有玩家一组
+	CreatedAt   time.Time           `json:"created_at"`
+	SeededAt    time.Time           `json:"seeded_at"`
+	Entrants    []TournamentEntrant `json:"entrants"`
+	Matches     []BracketMatch      `json:"matches,omitempty"` // 对阵表，按轮次和本轮位置排列
+	Champion    string              `json:"champion,omitempty"`
+
+	// 自动赛程：StartsAt 为零时由管理员手动开始比赛。StartsAt 前双方已就绪的比赛在 StartsAt 开赛，之后就绪的比赛
+	// 在 BreakMinutes 分钟后开赛；开赛 GraceMinutes 分钟后仍未到场的一方判负，双方都缺席时种子靠前的一方晋级
+	StartsAt     time.Time `json:"starts_at,omitempty"`
+	BreakMinutes int       `json:"break_minutes,omitempty"`
+	GraceMinutes int       `json:"grace_minutes,omitempty"`
+}
+
+// TournamentEntrant 报名的玩家，Seed 和 Rating 在生成对阵表时确定，1 号种子评分最高
+type TournamentEntrant struct {
+	UserID       string    `json:"user_id"`
+	Username     string    `json:"username"`
+	RegisteredAt time.Time `json:"registered_at"`
+	Seed         int       `json:"seed,omitempty"`
+	Rating       int       `json:"rating,omitempty"`
+}
+
+// BracketMatch 对阵表中的一场比赛。第一轮中玩家为空表示轮空，之后的轮次中表示尚未决出；
+// 败者组中双方都轮空的比赛 Bye 为 true 且没有胜者
+type BracketMatch struct {
+	ID      string `json:"id"`
+	Round   int    `json:"round"` // 轮次，从 1 开始
+	Slot    int    `json:"slot"`  // 在本轮中的位置，从 0 开始，胜者进入下一轮 Slot/2 的比赛
+	Player1 string `json:"player1,omitempty"`
+	Player2 string `json:"player2,omitempty"`
+	Seed1   int    `json:"seed1,omitempty"`
+	Seed2   int    `json:"seed2,omitempty"`
+	Bye     bool   `json:"bye,omitempty"` // 一方轮空，另一方直接晋级
+	Winner  string `json:"winner,omitempty"`
+	Bracket string `json:"bracket,omitempty"` // 双败淘汰中所在的分区，胜者组为空
+	Group   int    `json:"group,omitempty"`   // 循环赛的小组，从 1 开始
+	RoomID  string `json:"room_id,omitempty"` // 正在进行这场比赛的房间，决出胜者后保留
+
+	// 自动赛程：开赛时间和缺席判负的截止时间，Notified 表示已提前通知双方，Forfeit 表示胜者因对手缺席获胜
+	ScheduledAt time.Time `json:"scheduled_at,omitempty"`
+	Deadline    time.Time `json:"deadline,omitempty"`
+	Notified    bool      `json:"notified,omitempty"`
+	Forfeit     bool      `json:"forfeit,omitempty"`
+}
+
+// Clone 返回赛事的深拷贝
+func (t Tournament) Clone() Tournament {
+	t.Entrants = append([]TournamentEntrant(nil), t.Entrants...)
+	t.Matches = append([]BracketMatch(nil), t.Matches...)
+	return t
+}
+
+// Match 按ID查找对阵表中的比赛，返回其下标，不存在时返回 -1
+func (t Tournament) Match(id string) int {
+	for i, m := range t.Matches {
+		if m.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Registered 判断用户是否已报名
+func (t Tournament) Registered(userID string) bool {
+	for _, e := range t.Entrants {
+		if e.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// TournamentsData tournaments.json 的文件结构
+type TournamentsData struct {
+	Tournaments []Tournament `json:"tournaments"`
+}
+
+// LadderEntry 天梯上的一名玩家，排名即在天梯中的位置
+type LadderEntry struct {
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"` // 加入天梯时的用户名
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// LadderData ladder.json 的文件结构，按排名从高到低排列
+type LadderData struct {
+	Players []LadderEntry `json:"players"`
+}
+
+// Challenge 天梯挑战：挑战方向排名更高的玩家发起 1v1 对局，被挑战方需在有效期内接受
+type Challenge struct {
+	ID        string    `json:"id"`
+	FromID    string    `json:"from_id"` // 挑战方的稳定用户ID
+	From      string    `json:"from"`    // 挑战时挑战方的用户名
+	ToID      string    `json:"to_id"`   // 被挑战方的稳定用户ID
+	To        string    `json:"to"`      // 挑战时被挑战方的用户名
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ChallengesData challenges.json 的文件结构
+type ChallengesData struct {
+	Challenges []Challenge `json:"challenges"`
+}
+
+// Event 管理员安排的限时活动，在 StartsAt 到 EndsAt 之间自动生效
+type Event struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	WinMultiplier float64   `json:"win_multiplier,omitempty"` // 活动期间赢下的对局计入评分的倍率，0 表示不加成
+	Modes         []string  `json:"modes,omitempty"`          // 活动期间匹配对局轮换使用的地图目标
+	RotateMinutes int       `json:"rotate_minutes,omitempty"` // 地图目标的轮换间隔，0 表示每小时轮换
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Active 活动在 now 时是否生效
+func (e Event) Active(now time.Time) bool {
+	return !now.Before(e.StartsAt) && now.Before(e.EndsAt)
+}
+
+// EventsData events.json 的文件结构
+type EventsData struct {
+	Events []Event `json:"events"`
+}
+
+// PlaylistEntry 精选玩法列表中的一项：快速匹配使用的地图和地图目标
+type PlaylistEntry struct {
+	Name      string `json:"name"`
+	Map       string `json:"map"`
+	Objective string `json:"objective,omitempty"`
+}
+
+// Playlist 快速匹配按时间轮换的精选玩法，也是 playlist.json 的文件结构。
+// 从 StartsAt 起每隔 RotateMinutes 分钟换到下一项，列表为空时快速匹配使用默认地图和规则
+type Playlist struct {
+	Entries       []PlaylistEntry `json:"entries"`
+	RotateMinutes int             `json:"rotate_minutes"`
+	StartsAt      time.Time       `json:"starts_at"`
+}
+
+// Current 返回 now 时轮换到的玩法及其下标，列表为空时下标为 -1
+func (p Playlist) Current(now time.Time) (PlaylistEntry, int) {
+	if len(p.Entries) == 0 || p.RotateMinutes <= 0 {
+		return PlaylistEntry{}, -1
+	}
+	elapsed := max(now.Sub(p.StartsAt), 0)
+	i := int(elapsed/(time.Duration(p.RotateMinutes)*time.Minute)) % len(p.Entries)
+	return p.Entries[i], i
+}
+
+// NextRotation 返回 now 之后下一次轮换的时间，列表为空时为零值
+func (p Playlist) NextRotation(now time.Time) time.Time {
+	if len(p.Entries) == 0 || p.RotateMinutes <= 0 {
+		return time.Time{}
+	}
+	rotate := time.Duration(p.RotateMinutes) * time.Minute
+	elapsed := max(now.Sub(p.StartsAt), 0)
+	return p.StartsAt.Add((elapsed/rotate + 1) * rotate)
+}
+
+// HasExternalAccount 判断用户是否已关联指定的第三方账号
+func (u User) HasExternalAccount(provider, subject string) bool {
+	for _, a := range u.ExternalAccounts {
+		if a.Provider == provider && a.Subject == subject {
+			return true
+		}
+	}
+	return false
 }
 
 type UsersData struct {
-	Users []User `json:"users"`
+	SchemaVersion int    `json:"schema_version"`
+	Users         []User `json:"users"`
 }
 
 type Room struct {
-	ID          string        `json:"id"`
-	Name        string        `json:"name"`
-	HostID      string        `json:"host_id"`
-	Players     []string      `json:"players"`
-	MaxPlayers  int           `json:"max_players"`
-	Status      string        `json:"status"`
-	CreatedAt   time.Time     `json:"created_at"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	HostID     string            `json:"host_id"`
+	Players    []string          `json:"players"`
+	MaxPlayers int               `json:"max_players"`
+	Status     string            `json:"status"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Map        string            `json:"map"`
+	Rules      Rules             `json:"rules"`
+	Heroes     map[string]string `json:"heroes,omitempty"`     // 玩家在对局开始前锁定的英雄ID，按用户名索引
+	Region     string            `json:"region,omitempty"`     // 房间所在区域，为空表示不限区域
+	Instance   string            `json:"instance,omitempty"`   // 集群模式下托管房间的实例，只出现在其他实例的房间中
+	Banned     []string          `json:"banned,omitempty"`     // 被房主封禁的用户名，房间存在期间不能再加入或观战
+	CreateKey  string            `json:"create_key,omitempty"` // 创建房间请求的幂等键，客户端重试时据此返回已创建的房间
+	Tournament string            `json:"tournament,omitempty"` // 赛事比赛的房间所属的赛事ID，对局结果计入对阵表
+	MatchID    string            `json:"match_id,omitempty"`   // 赛事比赛在对阵表中的ID
+	Challenger string            `json:"challenger,omitempty"` // 天梯挑战房间中发起挑战的玩家，对局决出胜负后结算天梯排名
+	Version    int64             `json:"version"`              // 修订号，每次写入存储时加一，按修订号更新时用于检测并发修改
+}
+
+// Rules 房间的对局规则，创建房间时指定，由游戏会话执行
+type Rules struct {
+	StartingHP       int     `json:"starting_hp"`
+	DamageMultiplier float64 `json:"damage_multiplier"` // 命中伤害倍率
+	Gravity          float64 `json:"gravity"`           // 重力倍率，由客户端物理模拟使用
+	MoveSpeed        float64 `json:"move_speed"`        // 移动速度倍率
+	FriendlyFire     bool    `json:"friendly_fire"`     // 是否结算对自己（及队友）的伤害
+	Rounds           int     `json:"rounds"`            // 局数，先赢下过半局数的玩家获胜
+	TimeLimit        int     `json:"time_limit"`        // 对局时间上限（秒），0 表示不限时
+	Overtime         string  `json:"overtime"`          // 限时对局打平时的加时方式，见 OvertimeSuddenDeath 等
+	KillTarget       int     `json:"kill_target"`       // 混战模式（超过两名玩家）的目标击杀数，0 表示最后存活者获胜
+	Objective        string  `json:"objective"`         // 地图目标，见 ObjectiveKingOfTheHill 等
+	TickRate         int     `json:"tick_rate"`         // 服务器逻辑帧率（Hz），0 表示使用服务器配置
+	SendRate         int     `json:"send_rate"`         // 状态快照发送频率（Hz），0 表示使用服务器配置，不超过逻辑帧率
+}
+
+// 地图目标
+const (
+	ObjectiveNone          = "none"             // 没有地图目标，只按击杀决胜
+	ObjectiveKingOfTheHill = "king_of_the_hill" // 独自占领中央控制点累积分数，先达到目标分数者获胜
+	ObjectiveShrinkingZone = "shrinking_zone"   // 安全区随时间收缩，停留在区外持续掉血
+)
+
+// 加时方式
+const (
+	OvertimeNone        = "none"         // 不加时，直接判平
+	OvertimeSuddenDeath = "sudden_death" // 先命中者获胜
+	OvertimeReducedHP   = "reduced_hp"   // 以起始生命值的四分之一（至少 1）重新开打
+)
+
+// DefaultRules 返回默认规则，与客户端原有的固定参数一致
+func DefaultRules() Rules {
+	return Rules{
+		StartingHP:       5,
+		DamageMultiplier: 1,
+		Gravity:          1,
+		MoveSpeed:        1,
+		Rounds:           1,
+		Overtime:         OvertimeSuddenDeath,
+		Objective:        ObjectiveNone,
+	}
+}
+
+// WithDefaults 用默认值补全未设置（为零）的字段，FriendlyFire 与 TimeLimit 的零值本身有意义，保持不变
+func (r Rules) WithDefaults() Rules {
+	def := DefaultRules()
+	if r.StartingHP == 0 {
+		r.StartingHP = def.StartingHP
+	}
+	if r.DamageMultiplier == 0 {
+		r.DamageMultiplier = def.DamageMultiplier
+	}
+	if r.Gravity == 0 {
+		r.Gravity = def.Gravity
+	}
+	if r.MoveSpeed == 0 {
+		r.MoveSpeed = def.MoveSpeed
+	}
+	if r.Rounds == 0 {
+		r.Rounds = def.Rounds
+	}
+	if r.Overtime == "" {
+		r.Overtime = def.Overtime
+	}
+	if r.Objective == "" {
+		r.Objective = def.Objective
+	}
+	return r
+}
+
+// DefaultMap 未指定地图时使用的默认地图
+const DefaultMap = "default"
+
+// BotPrefix 服务器机器人玩家的用户名前缀，注册用户不能使用
+const BotPrefix = "bot_"
+
+// IsBot 判断玩家是否为服务器机器人
+func IsBot(username string) bool {
+	return strings.HasPrefix(username, BotPrefix)
+}
+
+// Clone 返回房间的深拷贝，修改副本不会影响原房间
+func (r Room) Clone() Room {
+	r.Players = append([]string(nil), r.Players...)
+	r.Banned = append([]string(nil), r.Banned...)
+	if r.Heroes != nil {
+		heroes := make(map[string]string, len(r.Heroes))
+		for player, hero := range r.Heroes {
+			heroes[player] = hero
+		}
+		r.Heroes = heroes
+	}
+	return r
+}
+
+// CreatedWith 判断房间是否由 hostID 在 since 之后用幂等键 key 创建，key 为空时总是 false
+func (r Room) CreatedWith(hostID, key string, since time.Time) bool {
+	return key != "" && r.CreateKey == key && r.HostID == hostID && !r.CreatedAt.Before(since)
+}
+
+// IsBanned 判断用户是否被房主封禁，用户名不区分大小写
+func (r Room) IsBanned(username string) bool {
+	for _, banned := range r.Banned {
+		if strings.EqualFold(banned, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hero 可选英雄，属性由服务器在对局中执行
+type Hero struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Speed   float64  `json:"speed"`   // 移动速度倍率，与房间规则的移动速度倍率相乘
+	HP      float64  `json:"hp"`      // 生命值倍率，与房间规则的起始生命值相乘（取整，至少 1）
+	Loadout []string `json:"loadout"` // 可用武器名，第一把为默认武器
+}
+
+// Weapon 武器配置，命中伤害以这里的数值为准
+type Weapon struct {
+	Name               string  `json:"name"`
+	Damage             int     `json:"damage"`              // 基础伤害
+	HeadshotMultiplier float64 `json:"headshot_multiplier"` // 爆头伤害倍率，0 表示爆头不加成
+	FalloffStart       float64 `json:"falloff_start"`       // 距离（像素）超过该值后伤害开始衰减，0 表示不衰减
+	FalloffEnd         float64 `json:"falloff_end"`         // 距离达到该值时衰减到 FalloffMin
+	FalloffMin         float64 `json:"falloff_min"`         // 最远距离时的伤害比例，0~1
+	Price              int     `json:"price,omitempty"`     // 多局制购买阶段的价格，0 表示不出售，只能随英雄获得
+	Spread             float64 `json:"spread,omitempty"`    // 射击时纵向散布的最大偏移（像素），由每局的随机种子决定
+	CritChance         float64 `json:"crit_chance,omitempty"`
+	CritMultiplier     float64 `json:"crit_multiplier,omitempty"` // 暴击伤害倍率，暴击按 CritChance 的概率由每局的随机种子决定
+}
+
+// Armor 购买阶段出售的护甲，护甲值先于生命值抵扣伤害
+type Armor struct {
+	Name   string `json:"name"`
+	Price  int    `json:"price"`
+	Points int    `json:"points"` // 购买后护甲值补足到该值
+}
+
+// DamageAt 按命中距离和是否爆头计算伤害：超过 FalloffStart 后线性衰减，
+// 到 FalloffEnd 时为基础伤害的 FalloffMin 倍，爆头再乘以 HeadshotMultiplier，结果至少为 1
+func (w Weapon) DamageAt(distance float64, headshot bool) int {
+	damage := float64(w.Damage)
+	if w.FalloffStart > 0 && distance > w.FalloffStart {
+		scale := w.FalloffMin
+		if w.FalloffEnd > w.FalloffStart && distance < w.FalloffEnd {
+			scale = 1 - (1-w.FalloffMin)*(distance-w.FalloffStart)/(w.FalloffEnd-w.FalloffStart)
+		}
+		damage *= scale
+	}
+	if headshot && w.HeadshotMultiplier > 0 {
+		damage *= w.HeadshotMultiplier
+	}
+	return int(math.Max(1, math.Round(damage)))
+}
+
+// CritDamage 暴击时的伤害，CritMultiplier 不大于 1 时不加成
+func (w Weapon) CritDamage(damage int) int {
+	if w.CritMultiplier <= 1 {
+		return damage
+	}
+	return int(math.Round(float64(damage) * w.CritMultiplier))
+}
+
+type HeroesData struct {
+	Version string   `json:"version,omitempty"` // 平衡版本号，对局结果中记录对局使用的版本
+	Weapons []Weapon `json:"weapons"`
+	Armor   []Armor  `json:"armor"`
+	Heroes  []Hero   `json:"heroes"`
+}
+
+// BotProfile 机器人难度配置，ID 即添加机器人时选择的难度
+type BotProfile struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	ReactionMs int     `json:"reaction_ms"` // 看到对手移动到开始跟随的延迟
+	Accuracy   float64 `json:"accuracy"`    // 0~1，越高瞄准偏差越小，开火前要求对得越准
+	Aggression float64 `json:"aggression"`  // 0~1，越高开火越频繁
+	MoveSpeed  float64 `json:"move_speed"`  // 每帧移动像素
+}
+
+type BotsData struct {
+	Profiles []BotProfile `json:"profiles"`
 }
 
 type RoomsData struct {
-	Rooms []Room `json:"rooms"`
+	SchemaVersion int    `json:"schema_version"`
+	Rooms         []Room `json:"rooms"`
 }
 
 type GameResult struct {
-	ID          string    `json:"id"`
-	RoomID      string    `json:"room_id"`
-	Winner      string    `json:"winner"`
-	Loser       string    `json:"loser"`
-	PlayTime    time.Time `json:"play_time"`
-	Duration    int       `json:"duration"`
+	ID         string         `json:"id"`
+	RoomID     string         `json:"room_id"`
+	Winner     string         `json:"winner"`
+	Loser      string         `json:"loser"`
+	PlayTime   time.Time      `json:"play_time"`
+	Duration   int            `json:"duration"`
+	Reason     string         `json:"reason,omitempty"` // 非正常结束的原因，正常结束为空
+	Map        string         `json:"map,omitempty"`
+	Players    []PlayerResult `json:"players,omitempty"`
+	MVP        string         `json:"mvp,omitempty"`        // 本局最佳玩家
+	Overtime   bool           `json:"overtime,omitempty"`   // 是否进入了加时
+	Placements []string       `json:"placements,omitempty"` // 混战模式的最终名次，第一名在前
+	BotMatch   bool           `json:"bot_match,omitempty"`  // 有机器人参与的对局，不计入匹配评分
+	Flagged    []string       `json:"flagged,omitempty"`    // 对局结束时处于作弊标记状态的玩家
+
+	BalanceVersion string  `json:"balance_version,omitempty"` // 对局开局时生效的平衡版本
+	WinMultiplier  float64 `json:"win_multiplier,omitempty"`  // 对局结束时生效的活动评分倍率，胜者按该倍率计入评分，0 表示不加成
+}
+
+// Clone 返回游戏结果的深拷贝
+func (r GameResult) Clone() GameResult {
+	r.Players = append([]PlayerResult(nil), r.Players...)
+	r.Placements = append([]string(nil), r.Placements...)
+	r.Flagged = append([]string(nil), r.Flagged...)
+	return r
+}
+
+// NameOf 返回 userID 对应的玩家在这局中使用的用户名，玩家未被记录用户ID时返回空字符串
+func (r GameResult) NameOf(userID string) string {
+	if userID == "" {
+		return ""
+	}
+	for _, p := range r.Players {
+		if p.UserID == userID {
+			return p.Username
+		}
+	}
+	return ""
+}
+
+// PlayerResult 单个玩家在一局中的统计数据，由服务器游戏会话统计
+type PlayerResult struct {
+	Username      string  `json:"username"`          // 对局时的用户名，用户改名后保持不变
+	UserID        string  `json:"user_id,omitempty"` // 用户的稳定ID，机器人为空
+	Score         int     `json:"score"`
+	Kills         int     `json:"kills"`
+	Deaths        int     `json:"deaths"`
+	ShotsFired    int     `json:"shots_fired"`
+	ShotsHit      int     `json:"shots_hit"`
+	Accuracy      float64 `json:"accuracy"`
+	DamageDealt   int     `json:"damage_dealt"`
+	LongestStreak int     `json:"longest_streak"`      // 最长连续击杀数，阵亡后重新计算
+	Objective     int     `json:"objective,omitempty"` // 占领控制点累积的目标分数
+	Headshots     int     `json:"headshots"`           // 爆头命中次数
+	Disconnected  bool    `json:"disconnected,omitempty"`
+	Forfeited     bool    `json:"forfeited,omitempty"`
+	VoteKicked    bool    `json:"vote_kicked,omitempty"` // 被其他玩家投票踢出，同时记为弃赛
+	Disconnects   int     `json:"disconnects,omitempty"` // 对局中掉线的次数
+	Reconnects    int     `json:"reconnects,omitempty"`  // 掉线后在宽限期内重连成功的次数
+	ClientVersion string  `json:"client_version,omitempty"`
+}
+
+// 对局结束原因
+const (
+	ResultReasonServerError       = "server_error"       // 服务器内部错误导致对局中止
+	ResultReasonDisconnect        = "disconnect"         // 玩家中途断线判负（未开启重连宽限期）
+	ResultReasonDisconnectTimeout = "disconnect_timeout" // 玩家断线后未在宽限期内重连，判负
+	ResultReasonTimeLimit         = "time_limit"         // 达到房间规则的时间上限（含加时）
+	ResultReasonAbandoned         = "abandoned"          // 对局被服务器中止，例如服务器关闭
+	ResultReasonVoteKick          = "vote_kick"          // 玩家被其他玩家投票踢出，判负
+	ResultReasonVoteDraw          = "vote_draw"          // 所有在场玩家投票同意以平局结束
+	ResultReasonSurrender         = "surrender"          // 玩家主动认输判负
+)
+
+// PlayerStats 玩家历史战绩汇总，由游戏结果计算得出
+type PlayerStats struct {
+	Matches    int     `json:"matches"`
+	Wins       int     `json:"wins"`
+	Losses     int     `json:"losses"`
+	Kills      int     `json:"kills"`
+	Deaths     int     `json:"deaths"`
+	ShotsFired int     `json:"shots_fired"`
+	ShotsHit   int     `json:"shots_hit"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// LoginRecord 一次登录尝试，Reason 为失败原因，NewIP 表示该用户此前没有从这个 IP 成功登录过
+type LoginRecord struct {
+	Time      time.Time `json:"time"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"` // 登录时使用的用户名
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Device    string    `json:"device"`
+	Method    string    `json:"method"` // password 或第三方登录提供方
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
+	NewIP     bool      `json:"new_ip,omitempty"`
+}
+
+// LoginHistoryData login_history.json 的文件结构
+type LoginHistoryData struct {
+	Records []LoginRecord `json:"records"`
+}
+
+// DailyAnalytics 一天的运营统计，按服务器本地日期汇总
+type DailyAnalytics struct {
+	Date           string    `json:"date"`         // 日期，格式 2006-01-02
+	ActiveUsers    []string  `json:"active_users"` // 当天登录过的用户
+	Logins         int       `json:"logins"`
+	PeakConcurrent int       `json:"peak_concurrent"`
+	PeakAt         time.Time `json:"peak_at,omitempty"`
+	Samples        int       `json:"samples"`        // 在线连接数采样次数
+	ConcurrentSum  int       `json:"concurrent_sum"` // 采样值之和，用于计算平均在线数
+}
+
+// AnalyticsData analytics.json 的文件结构
+type AnalyticsData struct {
+	Days []DailyAnalytics `json:"days"`
+}
+
+// BackupInfo 数据备份文件信息
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 流量录制事件类型
+const (
+	TrafficConnect     = "connect"      // 建立 WebSocket 连接
+	TrafficMessage     = "message"      // 收到一条客户端消息
+	TrafficDisconnect  = "disconnect"   // 连接断开
+	TrafficRoomCreated = "room_created" // 服务器为该用户创建了房间，RoomID 为新房间，回放时用于映射房间ID
+)
+
+// TrafficRecord 诊断模式录制的一条入站流量，Message 为解密后的原始消息
+type TrafficRecord struct {
+	Time      time.Time       `json:"time"`
+	Username  string          `json:"username"`
+	Event     string          `json:"event"`
+	RoomID    string          `json:"room_id,omitempty"`    // 事件发生时用户所在的房间
+	MessageID string          `json:"message_id,omitempty"` // 连接ID加消息序号，与日志和错误响应中的ID一致
+	Message   json.RawMessage `json:"message,omitempty"`
 }
 
 type GameResultsData struct {
@@ -66,3 +886,8 @@ type Bullet struct {
 	VX       float64 `json:"vx"`
 	OwnerID  string  `json:"owner_id"`
 }
+
+// WordListData banned_words.json 的文件结构，Words 为小写的屏蔽词，按字母序排列
+type WordListData struct {
+	Words []string `json:"words"`
+}
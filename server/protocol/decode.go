@@ -0,0 +1,78 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DecodeError 请求体或消息解码失败的原因，Field 为出错的字段（能确定时）
+type DecodeError struct {
+	Field    string
+	Reason   string
+	TooLarge bool // 超过大小上限，HTTP 请求应返回 413
+}
+
+func (e *DecodeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("字段 %s: %s", e.Field, e.Reason)
+	}
+	return e.Reason
+}
+
+// Decode 严格解码 r 中的一个 JSON 值到 v：拒绝未知字段和值之后的多余内容，
+// 解码过程中的 panic 也转换为错误；返回的错误均为 *DecodeError
+func Decode(r io.Reader, v any) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &DecodeError{Reason: fmt.Sprintf("解码失败: %v", p)}
+		}
+	}()
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err != nil {
+			return decodeError(err)
+		}
+		return &DecodeError{Reason: "JSON 值之后有多余内容"}
+	}
+	return nil
+}
+
+// DecodeBytes 严格解码一段 JSON，用于 WebSocket 消息及其 payload
+func DecodeBytes(data []byte, v any) error {
+	return Decode(bytes.NewReader(data), v)
+}
+
+// decodeError 将 encoding/json 的错误转换为面向客户端的 DecodeError
+func decodeError(err error) *DecodeError {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		sizeErr   *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &sizeErr):
+		return &DecodeError{Reason: fmt.Sprintf("内容超过 %d 字节", sizeErr.Limit), TooLarge: true}
+	case errors.Is(err, io.EOF):
+		return &DecodeError{Reason: "内容为空"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{Reason: "JSON 不完整"}
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{Reason: fmt.Sprintf("JSON 格式错误（第 %d 字节）", syntaxErr.Offset)}
+	case errors.As(err, &typeErr):
+		return &DecodeError{Field: typeErr.Field, Reason: fmt.Sprintf("类型应为 %s", typeErr.Type)}
+	}
+	// 未知字段没有单独的错误类型，格式为 json: unknown field "name"
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &DecodeError{Field: strings.Trim(name, `"`), Reason: "未知字段"}
+	}
+	return &DecodeError{Reason: err.Error()}
+}
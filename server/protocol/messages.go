@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"encoding/json"
+	"time"
 )
 
 // 这里是所有通信协议
@@ -28,6 +29,84 @@ const (
 	MsgTypeDeath          MessageType = "death"
 	MsgTypeGameOver       MessageType = "game_over"
 	MsgTypeError          MessageType = "error"
+	MsgTypeIdleWarning    MessageType = "idle_warning"
+	MsgTypeLoggedOut      MessageType = "logged_out"
+	MsgTypeRoomUpdate     MessageType = "room_update"
+	MsgTypePresence       MessageType = "presence"
+	MsgTypeAddBot         MessageType = "add_bot"
+	MsgTypeSpectate       MessageType = "spectate"
+	MsgTypeSpectateResult MessageType = "spectate_result"
+	MsgTypeStopSpectate   MessageType = "stop_spectate"
+	MsgTypeChat           MessageType = "chat"
+	MsgTypeChatHistory    MessageType = "chat_history"
+	MsgTypeKillFeed       MessageType = "kill_feed"
+	MsgTypeScoreboard     MessageType = "scoreboard"
+	MsgTypeRoundStart     MessageType = "round_start"
+	MsgTypeOvertime       MessageType = "overtime"
+	MsgTypeRespawn        MessageType = "respawn"
+	MsgTypeZoneState      MessageType = "zone_state"
+	MsgTypeMatchPaused    MessageType = "match_paused"
+	MsgTypeMatchResumed   MessageType = "match_resumed"
+	MsgTypeListHeroes     MessageType = "list_heroes"
+	MsgTypeHeroList       MessageType = "hero_list"
+	MsgTypeSelectHero     MessageType = "select_hero"
+	MsgTypeHeroSelected   MessageType = "hero_selected"
+	MsgTypeMoveCorrection MessageType = "move_correction"
+	MsgTypeBuy            MessageType = "buy"
+	MsgTypeBuyResult      MessageType = "buy_result"
+	MsgTypeJoinQueue      MessageType = "join_queue"
+	MsgTypeQueueResult    MessageType = "queue_result"
+	MsgTypeMatchReady     MessageType = "match_ready"
+	MsgTypeAcceptMatch    MessageType = "accept_match"
+	MsgTypeMatchCancelled MessageType = "match_cancelled"
+	MsgTypeQueueStatus    MessageType = "queue_status"
+	MsgTypeCancelQueue    MessageType = "cancel_queue"
+	MsgTypeQueueLeft      MessageType = "queue_left"
+	MsgTypeBotMatchOffer  MessageType = "bot_match_offer"
+	MsgTypeAcceptBotMatch MessageType = "accept_bot_match"
+	MsgTypeTyping         MessageType = "typing"
+	MsgTypeEmote          MessageType = "emote"
+	MsgTypeInvite         MessageType = "invite"
+	MsgTypeInviteResult   MessageType = "invite_result"
+	MsgTypeInvitation     MessageType = "invitation"
+	MsgTypeAcceptInvite   MessageType = "accept_invite"
+	MsgTypeKickPlayer     MessageType = "kick_player"
+	MsgTypeKicked         MessageType = "kicked"
+	MsgTypeLobbyPresence  MessageType = "lobby_presence"
+	MsgTypeLobbyDelta     MessageType = "lobby_presence_delta"
+	MsgTypeDisconnected   MessageType = "player_disconnected"
+	MsgTypeSnapshot       MessageType = "snapshot"
+	MsgTypeEntitySpawn    MessageType = "entity_spawn"
+	MsgTypeEntityDespawn  MessageType = "entity_despawn"
+	MsgTypeRoomKey        MessageType = "room_key"
+	MsgTypeStartPractice  MessageType = "start_practice"
+	MsgTypeStopPractice   MessageType = "stop_practice"
+	MsgTypePracticeStart  MessageType = "practice_start"
+	MsgTypePracticeHit    MessageType = "practice_hit"
+	MsgTypePracticeResult MessageType = "practice_result"
+	MsgTypeListBots       MessageType = "list_bots"
+	MsgTypeBotList        MessageType = "bot_list"
+	MsgTypeBracket        MessageType = "tournament_bracket"
+	MsgTypeFollowBracket  MessageType = "follow_tournament"
+	MsgTypeListLive       MessageType = "list_tournament_matches"
+	MsgTypeLiveMatches    MessageType = "tournament_matches"
+	MsgTypeMatchNotice    MessageType = "tournament_match_notice"
+	MsgTypeChallenge      MessageType = "challenge"
+	MsgTypeChallengeAck   MessageType = "challenge_result"
+	MsgTypeChallenged     MessageType = "challenge_received"
+	MsgTypeChallengeReply MessageType = "accept_challenge"
+	MsgTypeLadderResult   MessageType = "ladder_result"
+	MsgTypeEvents         MessageType = "active_events"
+	MsgTypeVoteKick       MessageType = "vote_kick"
+	MsgTypeVoteKickStatus MessageType = "vote_kick_status"
+	MsgTypeMatchVote      MessageType = "match_vote"
+	MsgTypeMatchVoteState MessageType = "match_vote_status"
+)
+
+// 聊天频道
+const (
+	ChatChannelRoom      = "room"      // 房间频道：玩家发言，房间内玩家和观战者都能看到
+	ChatChannelSpectator = "spectator" // 观战频道：只在观战者之间转发，防止给对局玩家报点
 )
 
 type Message struct {
@@ -44,26 +123,93 @@ type RegisterRequest struct {
 type RegisterResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
+	Code    string `json:"code,omitempty"` // 失败原因代码，例如违反密码策略时的 password_too_short
 }
 
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username  string         `json:"username"`
+	Password  string         `json:"password"`
+	Region    string         `json:"region,omitempty"`    // 客户端指定的区域
+	Latencies map[string]int `json:"latencies,omitempty"` // 客户端对各区域的测速结果（毫秒），未指定区域时选延迟最低的区域
+	// RememberMe 为 true 时同时签发长期有效的刷新令牌，会话失效后可用 /user/refresh 免密码重新登录
+	RememberMe bool `json:"remember_me,omitempty"`
+}
+
+// RefreshRequest 使用刷新令牌重新登录，旧令牌随即失效，响应中返回轮换后的新令牌
+type RefreshRequest struct {
+	RefreshToken string         `json:"refresh_token"`
+	Region       string         `json:"region,omitempty"`
+	Latencies    map[string]int `json:"latencies,omitempty"`
 }
 
 type LoginResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 	Token   string `json:"token,omitempty"`
+	// SessionID 本次登录的会话ID，建立 WebSocket 连接时通过 session 参数携带
+	SessionID string `json:"session_id,omitempty"`
+	// Region 服务器为本次登录确定的区域，用于房间列表筛选和匹配
+	Region string `json:"region,omitempty"`
+	// RefreshToken 登录时要求记住登录或刷新成功时签发的刷新令牌，只返回这一次
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// RejoinRoom 用户上次所在、仍然存在的房间，客户端可提示用户重新加入
+	RejoinRoom *RoomInfo `json:"rejoin_room,omitempty"`
+}
+
+// SessionInfo 登录会话信息
+type SessionInfo struct {
+	ID        string    `json:"id"` // 会话句柄，用于注销会话，不能代替会话ID认证
+	Device    string    `json:"device"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	Region    string    `json:"region,omitempty"`
+	Current   bool      `json:"current"` // 是否为发起请求的会话
+}
+
+// SessionListResponse 会话列表响应
+type SessionListResponse struct {
+	Sessions []SessionInfo `json:"sessions"`
 }
 
 type RoomInfo struct {
-	ID         string   `json:"id"`
-	Name       string   `json:"name"`
-	Host       string   `json:"host"`
-	Players    []string `json:"players"`
-	MaxPlayers int      `json:"max_players"`
-	Status     string   `json:"status"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Host       string            `json:"host"`
+	Players    []string          `json:"players"`
+	MaxPlayers int               `json:"max_players"`
+	Status     string            `json:"status"`
+	Map        string            `json:"map"`
+	Rules      RoomRules         `json:"rules"`
+	Heroes     map[string]string `json:"heroes,omitempty"` // 玩家已锁定的英雄ID
+	Titles     map[string]string `json:"titles,omitempty"` // 玩家当前装备的称号，按用户名索引，未装备的玩家不出现
+	Region     string            `json:"region,omitempty"`
+	Instance   string            `json:"instance,omitempty"` // 集群模式下托管该房间的实例，为空表示当前实例
+}
+
+// GameStart 开始游戏消息，Seed 为第一局的随机种子，之后每局的种子在 round_start 中下发。
+// 散布和暴击等共享的随机效果按 sim.Roll(Seed, stream, n) 计算：stream 为 "用户名/spread" 或 "用户名/crit"，
+// n 为该玩家本局的第几次射击（从 0 开始）
+type GameStart struct {
+	RoomInfo
+	Seed int64 `json:"seed"`
+}
+
+// RoomRules 房间对局规则，创建房间时未设置的字段使用默认值
+type RoomRules struct {
+	StartingHP       int     `json:"starting_hp,omitempty"`
+	DamageMultiplier float64 `json:"damage_multiplier,omitempty"`
+	Gravity          float64 `json:"gravity,omitempty"`
+	MoveSpeed        float64 `json:"move_speed,omitempty"`
+	FriendlyFire     bool    `json:"friendly_fire"`
+	Rounds           int     `json:"rounds,omitempty"`
+	TimeLimit        int     `json:"time_limit"`            // 秒，0 表示不限时
+	Overtime         string  `json:"overtime,omitempty"`    // 打平时的加时方式：none、sudden_death、reduced_hp
+	KillTarget       int     `json:"kill_target,omitempty"` // 混战模式的目标击杀数，0 表示最后存活者获胜
+	Objective        string  `json:"objective,omitempty"`   // 地图目标：none、king_of_the_hill、shrinking_zone
+	TickRate         int     `json:"tick_rate,omitempty"`   // 服务器逻辑帧率（Hz），0 表示使用服务器配置
+	SendRate         int     `json:"send_rate,omitempty"`   // 状态快照发送频率（Hz），0 表示使用服务器配置
 }
 
 type RoomListResponse struct {
@@ -71,8 +217,45 @@ type RoomListResponse struct {
 }
 
 type CreateRoomRequest struct {
-	Name       string `json:"name"`
-	MaxPlayers int    `json:"max_players"`
+	Name       string     `json:"name"`
+	MaxPlayers int        `json:"max_players"`
+	Map        string     `json:"map,omitempty"`
+	Rules      *RoomRules `json:"rules,omitempty"`
+	Region     string     `json:"region,omitempty"` // 为空时使用房主所在区域
+	// IdempotencyKey 客户端为每次创建生成的唯一键，重试时携带相同的键，有效期内返回原先创建的房间而不会重复创建
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// RoomListRequest 房间列表请求，Region 不为空时只返回该区域的房间，Filter 为用户保存的筛选条件名称
+type RoomListRequest struct {
+	Region string `json:"region,omitempty"`
+	Filter string `json:"filter,omitempty"`
+}
+
+// RoomFilterInfo 保存的房间列表筛选条件，为空的条件不限制；Mode 为地图目标，例如 king_of_the_hill
+type RoomFilterInfo struct {
+	Name    string `json:"name"`
+	Map     string `json:"map,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	NotFull bool   `json:"not_full,omitempty"`
+}
+
+// SaveRoomFilterRequest 保存房间列表筛选条件，同名的条件会被覆盖
+type SaveRoomFilterRequest struct {
+	Username  string         `json:"username"`
+	SessionID string         `json:"session_id"`
+	Filter    RoomFilterInfo `json:"filter"`
+}
+
+// RoomFilterListResponse 用户保存的房间列表筛选条件
+type RoomFilterListResponse struct {
+	Filters []RoomFilterInfo `json:"filters"`
+}
+
+// AddBotRequest 房主请求加入机器人对手，Difficulty 为 bot_list 中的难度ID，缺省使用服务器配置
+type AddBotRequest struct {
+	Difficulty string `json:"difficulty,omitempty"`
 }
 
 type JoinRoomRequest struct {
@@ -83,6 +266,18 @@ type JoinRoomResponse struct {
 	Success bool     `json:"success"`
 	Message string   `json:"message"`
 	Room    RoomInfo `json:"room,omitempty"`
+	Handoff *Handoff `json:"handoff,omitempty"` // 房间由其他实例托管时返回，客户端需重新连接到该实例
+	Code    string   `json:"code,omitempty"`    // 失败原因代码，例如已在其他房间中时的 already_in_room
+}
+
+// Handoff 集群模式下把客户端转移到托管房间的实例：客户端用 Token 作为 handoff 参数连接 Addr 的 /ws，
+// 连接建立后自动加入 RoomID 房间；Token 一次有效，ExpiresIn 秒后过期
+type Handoff struct {
+	Instance  string `json:"instance"`
+	Addr      string `json:"addr"`
+	RoomID    string `json:"room_id"`
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expires_in"`
 }
 
 type CreateRoomResponse struct {
@@ -97,18 +292,480 @@ type PlayerAction struct {
 	Value    float64 `json:"value"`
 }
 
+// FireAction 射击消息，BulletID 由服务器分配后转发；客户端上报的 bullet_id 只作为 EntitySpawn.ClientRef 回传
+// Weapon 为空时使用英雄的默认武器；Spread 为客户端按本局种子算出的纵向散布，与服务器的结果不符时按服务器的结果转发
 type FireAction struct {
 	PlayerID  string  `json:"player_id"`
 	Direction int     `json:"direction"`
 	BulletID  string  `json:"bullet_id"`
 	X         float64 `json:"x"`
 	Y         float64 `json:"y"`
+	Weapon    string  `json:"weapon,omitempty"`
+	Spread    float64 `json:"spread,omitempty"`
 }
 
 type HitAction struct {
-	TargetID  string `json:"target_id"`
-	Damage    int    `json:"damage"`
-	Remaining int    `json:"remaining"`
+	TargetID  string  `json:"target_id"`
+	BulletID  string  `json:"bullet_id,omitempty"` // 命中的子弹，服务器分配的ID；子弹已被移除时命中无效
+	Damage    int     `json:"damage"`
+	Remaining int     `json:"remaining"`
+	Weapon    string  `json:"weapon,omitempty"`
+	Headshot  bool    `json:"headshot,omitempty"`
+	Distance  float64 `json:"distance,omitempty"` // 服务器按双方位置计算的命中距离，客户端上报的值会被覆盖
+	Armor     int     `json:"armor,omitempty"`    // 目标剩余护甲值
+	Crit      bool    `json:"crit,omitempty"`     // 子弹是否暴击，由服务器按本局种子判定，客户端上报的值会被覆盖
+}
+
+// StartPracticeRequest 开始单人练习，在大厅中（不在房间、匹配队列或观战中）才能开始
+type StartPracticeRequest struct {
+	HeroID string `json:"hero_id,omitempty"` // 使用的英雄，为空时使用默认英雄
+}
+
+// PracticeStart 练习开始，Targets 为初始的固定靶，之后被击中的靶通过 entity_despawn 移除、
+// 新靶通过 entity_spawn 生成。射击（fire）和命中（hit）消息与对局相同，命中的 target_id 为靶的实体ID
+type PracticeStart struct {
+	RoomID   string        `json:"room_id"`
+	Duration int           `json:"duration"` // 练习时长（秒）
+	Weapon   WeaponInfo    `json:"weapon"`
+	Targets  []EntitySpawn `json:"targets"`
+}
+
+// PracticeHit 服务器确认的一次命中，Points 为本次得分，Score 为累计得分
+type PracticeHit struct {
+	TargetID string `json:"target_id"`
+	Points   int    `json:"points"`
+	Headshot bool   `json:"headshot,omitempty"`
+	Quick    bool   `json:"quick,omitempty"` // 靶出现后很快命中，获得额外得分
+	Score    int    `json:"score"`
+	Hits     int    `json:"hits"`
+	Shots    int    `json:"shots"`
+}
+
+// PracticeResult 练习结束时的成绩和个人最佳，Reason 为 time_up、stopped
+type PracticeResult struct {
+	Score    int           `json:"score"`
+	Hits     int           `json:"hits"`
+	Shots    int           `json:"shots"`
+	Accuracy float64       `json:"accuracy"`
+	Reason   string        `json:"reason"`
+	NewBest  bool          `json:"new_best,omitempty"`
+	Best     *PracticeBest `json:"best,omitempty"` // 个人最佳，没有有效成绩时为空
+}
+
+// PracticeBest 练习靶场的个人最佳成绩
+type PracticeBest struct {
+	Score    int       `json:"score"`
+	Hits     int       `json:"hits"`
+	Shots    int       `json:"shots"`
+	Accuracy float64   `json:"accuracy"`
+	Runs     int       `json:"runs"` // 完成的练习次数
+	At       time.Time `json:"at"`
+}
+
+// DeathAction 击杀方上报的阵亡消息，Weapon、Headshot 为空时取该玩家最后一次被命中的信息，
+// KillerID 为空时击杀归上报方；双人对局中击杀始终归阵亡玩家的对手
+type DeathAction struct {
+	PlayerID string `json:"player_id"`
+	KillerID string `json:"killer_id,omitempty"`
+	Weapon   string `json:"weapon,omitempty"`
+	Headshot bool   `json:"headshot,omitempty"`
+}
+
+// KillFeedEntry 击杀播报
+type KillFeedEntry struct {
+	Killer      string    `json:"killer"`
+	Victim      string    `json:"victim"`
+	KillerTitle string    `json:"killer_title,omitempty"` // 双方当前装备的称号
+	VictimTitle string    `json:"victim_title,omitempty"`
+	Weapon      string    `json:"weapon,omitempty"`
+	Headshot    bool      `json:"headshot"`
+	Distance    float64   `json:"distance,omitempty"` // 致命一击的命中距离
+	Time        time.Time `json:"time"`
+}
+
+// Scoreboard 对局中的实时比分，每次击杀后广播
+type Scoreboard struct {
+	RoomID    string          `json:"room_id"`
+	Elapsed   int             `json:"elapsed"` // 对局已进行的秒数
+	Round     int             `json:"round"`   // 当前局数，从 1 开始
+	RoundWins map[string]int  `json:"round_wins"`
+	Players   []PlayerSummary `json:"players"`
+}
+
+// OvertimeStart 限时对局打平后进入加时，所有玩家生命值重置为 HP
+type OvertimeStart struct {
+	Mode     string `json:"mode"`
+	HP       int    `json:"hp"`
+	Duration int    `json:"duration"` // 加时时长（秒），仍未分出胜负时判平
+}
+
+// MatchPaused 玩家掉线导致对局暂停，暂停期间每秒推送一次剩余的重连时间
+type MatchPaused struct {
+	Player      string `json:"player"`
+	SecondsLeft int    `json:"seconds_left"`
+}
+
+// MatchResumed 掉线的玩家已重连，对局继续
+type MatchResumed struct {
+	Player string `json:"player"`
+}
+
+// PlayerDisconnected 房间成员断开连接（包括心跳超时），发给房间内的其他成员。
+// 玩家在 Grace 秒内重新加入房间时保留位置，否则移出房间；对局中的掉线另见 MatchPaused
+type PlayerDisconnected struct {
+	RoomID  string `json:"room_id"`
+	Player  string `json:"player"`
+	Grace   int    `json:"grace"`
+	InMatch bool   `json:"in_match,omitempty"`
+}
+
+// ZoneState 地图目标的当前状态，对局中每秒广播一次。
+// Top、Bottom 为控制点或安全区的纵向范围；Holder 为独自占领控制点的玩家，无人或多人争夺时为空
+type ZoneState struct {
+	Mode   string         `json:"mode"`
+	Top    float64        `json:"top"`
+	Bottom float64        `json:"bottom"`
+	Holder string         `json:"holder,omitempty"`
+	Scores map[string]int `json:"scores,omitempty"`
+	Target int            `json:"target,omitempty"` // 获胜所需的目标分数
+}
+
+// QueueResult 进入匹配队列的结果，Cooldown 为仍需等待的秒数
+type QueueResult struct {
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+	Cooldown int    `json:"cooldown,omitempty"`
+}
+
+// QueueStatus 排队期间定期推送的队列状态，时间单位为秒
+type QueueStatus struct {
+	Position      int    `json:"position"` // 在队列中的位置，从 1 开始
+	QueueLength   int    `json:"queue_length"`
+	Waited        int    `json:"waited"`
+	EstimatedWait int    `json:"estimated_wait"`          // 预计还需等待的时间，没有历史配对数据时为 -1
+	SearchRegion  string `json:"search_region,omitempty"` // 当前只搜索该区域的对手，为空表示搜索所有区域
+	WidenIn       int    `json:"widen_in,omitempty"`      // 距离放宽到所有区域的时间
+}
+
+// BotMatchOffer 排队较久仍未配对时提供与机器人的对局，玩家发送 accept_bot_match 接受，
+// 接受前仍留在队列中继续匹配真人对手
+type BotMatchOffer struct {
+	Difficulty string `json:"difficulty"`
+	Waited     int    `json:"waited"` // 已等待的秒数
+}
+
+// MatchReady 匹配到对手，需要在 Seconds 秒内确认
+type MatchReady struct {
+	MatchID string   `json:"match_id"`
+	Players []string `json:"players"`
+	Seconds int      `json:"seconds"`
+}
+
+// AcceptMatchRequest 确认或拒绝配对结果
+type AcceptMatchRequest struct {
+	MatchID string `json:"match_id"`
+	Accept  bool   `json:"accept"`
+}
+
+// InviteRequest 邀请在线玩家加入自己所在的房间
+type InviteRequest struct {
+	Username string `json:"username"`
+}
+
+// InviteResult 邀请的处理结果，发给邀请方；对方接受或拒绝时也通过该消息通知
+type InviteResult struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// InvitationInfo 发给被邀请玩家的房间邀请，在 ExpiresAt 之前可以接受
+type InvitationInfo struct {
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	RoomID    string    `json:"room_id"`
+	RoomName  string    `json:"room_name"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AcceptInviteRequest 接受或拒绝房间邀请，接受后自动加入邀请方的房间
+type AcceptInviteRequest struct {
+	InviteID string `json:"invite_id"`
+	Accept   bool   `json:"accept"`
+}
+
+// ChallengeRequest 向天梯上排名更高的玩家发起 1v1 挑战，挑战方不在天梯上时先加入天梯末尾
+type ChallengeRequest struct {
+	Username string `json:"username"`
+}
+
+// ChallengeResult 挑战的处理结果，发给挑战方；对方接受或拒绝时也通过该消息通知
+type ChallengeResult struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// ChallengeInfo 发给被挑战玩家的天梯挑战，在 ExpiresAt 之前可以接受
+type ChallengeInfo struct {
+	ID           string    `json:"id"`
+	From         string    `json:"from"`
+	FromPosition int       `json:"from_position"`
+	ToPosition   int       `json:"to_position"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// AcceptChallengeRequest 接受或拒绝天梯挑战，接受后服务器为双方创建房间，双方都需要在线且不在其他房间中
+type AcceptChallengeRequest struct {
+	ChallengeID string `json:"challenge_id"`
+	Accept      bool   `json:"accept"`
+}
+
+// LadderResult 挑战对局决出胜负后推送给双方的天梯结算，挑战方获胜时双方交换排名
+type LadderResult struct {
+	Challenger         string `json:"challenger"`
+	Challenged         string `json:"challenged"`
+	Winner             string `json:"winner"`
+	Swapped            bool   `json:"swapped"`
+	ChallengerPosition int    `json:"challenger_position"`
+	ChallengedPosition int    `json:"challenged_position"`
+}
+
+// LadderEntryInfo 天梯上的一名玩家
+type LadderEntryInfo struct {
+	Position int    `json:"position"`
+	Username string `json:"username"`
+}
+
+// LadderResponse 按排名排列的天梯，由 /ladder 返回
+type LadderResponse struct {
+	Players []LadderEntryInfo `json:"players"`
+}
+
+// CreateEventRequest 管理端安排限时活动。WinMultiplier 为活动期间胜场计入评分的倍率，0 表示不加成；
+// Modes 为活动期间匹配对局轮换使用的地图目标，每隔 RotateMinutes 分钟（0 表示 60 分钟）换到下一个
+type CreateEventRequest struct {
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	WinMultiplier float64   `json:"win_multiplier,omitempty"`
+	Modes         []string  `json:"modes,omitempty"`
+	RotateMinutes int       `json:"rotate_minutes,omitempty"`
+}
+
+// EventInfo 限时活动，Active 表示当前正在进行，Mode 为进行中的活动当前轮换到的地图目标
+type EventInfo struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	WinMultiplier float64   `json:"win_multiplier,omitempty"`
+	Modes         []string  `json:"modes,omitempty"`
+	RotateMinutes int       `json:"rotate_minutes,omitempty"`
+	Active        bool      `json:"active"`
+	Mode          string    `json:"mode,omitempty"`
+}
+
+// PlaylistEntryInfo 精选玩法，Objective 为空表示没有地图目标
+type PlaylistEntryInfo struct {
+	Name      string `json:"name"`
+	Map       string `json:"map"`
+	Objective string `json:"objective,omitempty"`
+}
+
+// SetPlaylistRequest 管理端替换快速匹配的精选玩法列表，每隔 RotateMinutes 分钟（0 表示 60 分钟）换到下一项，
+// 从提交时起重新开始轮换；Entries 为空时停止轮换，快速匹配恢复默认地图和规则
+type SetPlaylistRequest struct {
+	Entries       []PlaylistEntryInfo `json:"entries"`
+	RotateMinutes int                 `json:"rotate_minutes,omitempty"`
+}
+
+// PlaylistInfo 精选玩法列表及当前轮换到的一项，列表为空时没有 Current 和 NextRotation
+type PlaylistInfo struct {
+	Entries       []PlaylistEntryInfo `json:"entries"`
+	RotateMinutes int                 `json:"rotate_minutes,omitempty"`
+	Current       *PlaylistEntryInfo  `json:"current,omitempty"`
+	NextRotation  *time.Time          `json:"next_rotation,omitempty"`
+}
+
+// EventListResponse 活动列表，按开始时间排列；登录时也通过 active_events 推送正在进行的活动
+type EventListResponse struct {
+	Events []EventInfo `json:"events"`
+}
+
+// KickPlayerRequest 房主把玩家踢出房间，Ban 为 true 时同时封禁该用户名，房间存在期间不能再加入；
+// 封禁不在房间中的用户时只加入封禁名单
+type KickPlayerRequest struct {
+	Username string `json:"username"`
+	Ban      bool   `json:"ban"`
+}
+
+// KickedNotice 通知被踢出房间的玩家
+type KickedNotice struct {
+	RoomID string `json:"room_id"`
+	Banned bool   `json:"banned"`
+}
+
+// VoteKickRequest 对局中投票把玩家踢出对局，第一票发起投票，之后的投票在同一窗口内累计
+type VoteKickRequest struct {
+	Username string `json:"username"`
+}
+
+// 对局内投票的类型
+const (
+	MatchVoteDraw         = "draw"          // 以平局结束对局，需要所有在场玩家同意
+	MatchVoteRestartRound = "restart_round" // 重新开始当前这一局，需要过半数在场玩家同意
+	MatchVoteChangeMap    = "change_map"    // 下一场对局换到 Map 指定的地图，需要过半数在场玩家同意
+)
+
+// MatchVoteRequest 对局内投票，第一票发起投票，同一时间只能有一个投票；窗口内再次发送同样的投票即为同意
+type MatchVoteRequest struct {
+	Kind string `json:"kind"`
+	Map  string `json:"map,omitempty"` // change_map 的目标地图
+}
+
+// MatchVoteStatus 对局内投票的进度，每次投票后广播给房间；Passed 为 true 时投票已通过并生效
+type MatchVoteStatus struct {
+	Kind      string    `json:"kind"`
+	Map       string    `json:"map,omitempty"`
+	Votes     int       `json:"votes"`
+	Needed    int       `json:"needed"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Passed    bool      `json:"passed,omitempty"`
+}
+
+// VoteKickStatus 投票踢人的进度，每次投票后广播给房间；Kicked 为 true 时目标已被踢出对局并判为弃赛，
+// 对局结束后移出房间
+type VoteKickStatus struct {
+	Target    string    `json:"target"`
+	Votes     int       `json:"votes"`
+	Needed    int       `json:"needed"` // 除目标外过半数的在场玩家
+	ExpiresAt time.Time `json:"expires_at"`
+	Kicked    bool      `json:"kicked,omitempty"`
+}
+
+// MatchCancelled 待确认的对局被取消，Requeued 表示已自动回到匹配队列
+type MatchCancelled struct {
+	MatchID  string `json:"match_id"`
+	Reason   string `json:"reason"`
+	Requeued bool   `json:"requeued"`
+}
+
+// MatchStats 匹配队列统计，用于调整匹配参数；Queue 只在管理接口中返回
+type MatchStats struct {
+	QueueLength        int                `json:"queue_length"`
+	Pending            int                `json:"pending"`          // 等待确认的对局数
+	AvgWaitSeconds     float64            `json:"avg_wait_seconds"` // 队列中玩家当前的平均等待时间
+	RatingDistribution []RatingBucket     `json:"rating_distribution"`
+	Quality            MatchQuality       `json:"quality"`
+	Queue              []QueuedPlayerInfo `json:"queue,omitempty"`
+}
+
+// RatingBucket 队列中评分落在 [Min, Max) 区间的玩家数
+type RatingBucket struct {
+	Min     int `json:"min"`
+	Max     int `json:"max"`
+	Players int `json:"players"`
+}
+
+// MatchQuality 服务器启动以来的配对质量统计
+type MatchQuality struct {
+	Proposed           int     `json:"proposed"`  // 配对成功、发出确认提示的对局数
+	Accepted           int     `json:"accepted"`  // 双方都确认并创建房间的对局数
+	Cancelled          int     `json:"cancelled"` // 拒绝、未确认或断线取消的对局数
+	AcceptRate         float64 `json:"accept_rate"`
+	AvgPairWaitSeconds float64 `json:"avg_pair_wait_seconds"` // 配对时双方的平均等待时间
+	AvgRatingGap       float64 `json:"avg_rating_gap"`        // 配对双方的平均评分差
+	SameRegionRate     float64 `json:"same_region_rate"`      // 双方区域相同的配对占比
+}
+
+// QueuedPlayerInfo 队列中的玩家
+type QueuedPlayerInfo struct {
+	Username    string  `json:"username"`
+	Region      string  `json:"region,omitempty"`
+	Rating      int     `json:"rating"`
+	WaitSeconds float64 `json:"wait_seconds"`
+}
+
+// BuyRequest 购买阶段购买武器或护甲
+type BuyRequest struct {
+	Item string `json:"item"`
+}
+
+// LoadoutInfo 玩家的金钱和本局购买的装备
+type LoadoutInfo struct {
+	Money   int      `json:"money"`
+	Weapons []string `json:"weapons"`
+	Armor   int      `json:"armor"`
+}
+
+// BuyResult 购买结果，Loadout 为购买后（失败时为当前）的装备
+type BuyResult struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message"`
+	Item    string      `json:"item"`
+	Loadout LoadoutInfo `json:"loadout"`
+}
+
+// MoveCorrection 上报的位置超出速度上限或穿过障碍物，客户端应将自己的位置修正为 Y
+type MoveCorrection struct {
+	Y        float64 `json:"y"`
+	Reported float64 `json:"reported"`
+}
+
+// HeroInfo 可选英雄的属性
+type HeroInfo struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Speed   float64      `json:"speed"`
+	HP      float64      `json:"hp"`
+	Loadout []WeaponInfo `json:"loadout"`
+}
+
+// WeaponInfo 英雄可用的武器，伤害随距离衰减的规则见 FalloffStart 等字段
+type WeaponInfo struct {
+	Name               string  `json:"name"`
+	Damage             int     `json:"damage"`
+	HeadshotMultiplier float64 `json:"headshot_multiplier"`
+	FalloffStart       float64 `json:"falloff_start"`
+	FalloffEnd         float64 `json:"falloff_end"`
+	FalloffMin         float64 `json:"falloff_min"`
+	Spread             float64 `json:"spread,omitempty"`
+	CritChance         float64 `json:"crit_chance,omitempty"`
+	CritMultiplier     float64 `json:"crit_multiplier,omitempty"`
+}
+
+// HeroListResponse 英雄列表
+type HeroListResponse struct {
+	Heroes []HeroInfo `json:"heroes"`
+}
+
+// SelectHeroRequest 对局开始前锁定英雄
+type SelectHeroRequest struct {
+	HeroID string `json:"hero_id"`
+}
+
+// HeroSelected 房间内有玩家锁定了英雄
+type HeroSelected struct {
+	Player string `json:"player"`
+	HeroID string `json:"hero_id"`
+}
+
+// Respawn 混战模式设置了目标击杀数时，阵亡玩家以 HP 生命值复活
+type Respawn struct {
+	Player string `json:"player"`
+	HP     int    `json:"hp"`
+}
+
+// RoundStart 多局制对局中新一局开始，玩家生命值重置为规则中的起始生命值
+type RoundStart struct {
+	Round      int                    `json:"round"`
+	Seed       int64                  `json:"seed"` // 本局的随机种子，见 GameStart
+	StartingHP int                    `json:"starting_hp"`
+	RoundWins  map[string]int         `json:"round_wins"`
+	HP         map[string]int         `json:"hp"`                 // 每名玩家按英雄折算后的起始生命值
+	BuyTime    int                    `json:"buy_time,omitempty"` // 购买阶段的时长（秒），未启用经济系统时为 0
+	Loadouts   map[string]LoadoutInfo `json:"loadouts,omitempty"` // 每名玩家的金钱和装备
 }
 
 type GameState struct {
@@ -136,12 +793,733 @@ type BulletState struct {
 }
 
 type GameOverInfo struct {
-	Winner   string `json:"winner"`
-	Loser    string `json:"loser"`
-	Duration int    `json:"duration"`
+	Winner     string          `json:"winner"`
+	Loser      string          `json:"loser"`
+	Duration   int             `json:"duration"`
+	Reason     string          `json:"reason,omitempty"`
+	Map        string          `json:"map,omitempty"`
+	Players    []PlayerSummary `json:"players,omitempty"`
+	MVP        string          `json:"mvp,omitempty"`
+	Overtime   bool            `json:"overtime,omitempty"`
+	Placements []string        `json:"placements,omitempty"` // 混战模式的最终名次，第一名在前
+
+	BalanceVersion string `json:"balance_version,omitempty"` // 对局使用的平衡版本
+}
+
+// PlayerSummary 对局结束时单个玩家的统计摘要
+type PlayerSummary struct {
+	Username      string  `json:"username"`
+	Score         int     `json:"score"`
+	Kills         int     `json:"kills"`
+	Deaths        int     `json:"deaths"`
+	ShotsFired    int     `json:"shots_fired"`
+	ShotsHit      int     `json:"shots_hit"`
+	Accuracy      float64 `json:"accuracy"`
+	DamageDealt   int     `json:"damage_dealt"`
+	LongestStreak int     `json:"longest_streak"`
+	Objective     int     `json:"objective,omitempty"`
+	Headshots     int     `json:"headshots"`
+	Disconnected  bool    `json:"disconnected,omitempty"`
+	Forfeited     bool    `json:"forfeited,omitempty"`
+	Disconnects   int     `json:"disconnects,omitempty"`
+	Reconnects    int     `json:"reconnects,omitempty"`
 }
 
 type ErrorResponse struct {
-	Code    int    `json:"code"`
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Field     string `json:"field,omitempty"`      // 请求格式错误时出错的字段
+	Reason    string `json:"reason,omitempty"`     // 失败原因代码，例如房间重名时的 room_name_taken
+	RequestID string `json:"request_id,omitempty"` // HTTP 请求ID或触发错误的 WebSocket 消息ID，用于对应服务器日志
+}
+
+// IdleWarning 大厅空闲断开前的警告
+type IdleWarning struct {
+	Seconds int    `json:"seconds"`
 	Message string `json:"message"`
 }
+
+// RoomUpdate 大厅房间变更推送，Op 为 added、updated、removed
+type RoomUpdate struct {
+	Op   string   `json:"op"`
+	Room RoomInfo `json:"room"`
+}
+
+// Presence 用户上下线推送
+type Presence struct {
+	Username string `json:"username"`
+	Online   bool   `json:"online"`
+}
+
+// 大厅在线名单变化类型
+const (
+	LobbyPresenceJoin  = "join"  // 玩家上线或对局结束，可以被邀请
+	LobbyPresenceLeave = "leave" // 玩家下线或进入对局
+)
+
+// LobbyPresenceRequest 订阅或取消订阅大厅在线名单，订阅时立即返回一次完整名单
+type LobbyPresenceRequest struct {
+	Subscribe bool `json:"subscribe"`
+}
+
+// LobbyPlayer 大厅在线名单中的玩家
+type LobbyPlayer struct {
+	Username  string `json:"username"`
+	Rating    int    `json:"rating"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+// LobbyPresenceList 在线且不在对局中的玩家，按用户名排列；Playlist 为快速匹配当前轮换的精选玩法，未配置时为空
+type LobbyPresenceList struct {
+	Players  []LobbyPlayer `json:"players"`
+	Playlist *PlaylistInfo `json:"playlist,omitempty"`
+}
+
+// LobbyPresenceDelta 订阅后推送的名单变化，Op 为 LobbyPresenceJoin 或 LobbyPresenceLeave；离开时 Player 只有用户名
+type LobbyPresenceDelta struct {
+	Op     string      `json:"op"`
+	Player LobbyPlayer `json:"player"`
+}
+
+// ServerInfo 公开服务器信息，由 /serverinfo 返回，也是向主服务器宣告的内容
+type ServerInfo struct {
+	Name       string   `json:"name"`
+	Addr       string   `json:"addr,omitempty"` // 客户端连接地址，为空时由主服务器按来源地址确定
+	Regions    []string `json:"regions,omitempty"`
+	Players    int      `json:"players"`               // 当前在线连接数
+	MaxPlayers int      `json:"max_players,omitempty"` // 最大连接数，0 表示不限制
+	Rooms      int      `json:"rooms"`
+	Modes      []string `json:"modes"`
+	Maps       []string `json:"maps"`
+}
+
+// BotProfileInfo 可选的机器人难度
+type BotProfileInfo struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	ReactionMs int     `json:"reaction_ms"`
+	Accuracy   float64 `json:"accuracy"`
+	Aggression float64 `json:"aggression"`
+	Default    bool    `json:"default,omitempty"` // 不指定难度时使用的难度
+}
+
+// BotListResponse 机器人难度列表
+type BotListResponse struct {
+	Profiles []BotProfileInfo `json:"profiles"`
+}
+
+// ConfigReloadResponse 热更新数据文件的结果，由 /admin/config/reload 返回
+type ConfigReloadResponse struct {
+	BotProfiles    []string `json:"bot_profiles"`    // 重新加载后的机器人难度ID
+	BalanceVersion string   `json:"balance_version"` // 重新加载后的平衡版本
+}
+
+// BalanceInfo 平衡版本信息，由 /admin/balance 返回；推送新版本后只影响之后开局的对局
+type BalanceInfo struct {
+	Version  string   `json:"version"`            // 当前生效的版本
+	Versions []string `json:"versions,omitempty"` // 已发布的全部版本
+}
+
+// ServerStats 服务器实例运行统计，由 /admin/stats 返回
+type ServerStats struct {
+	StartedAt    time.Time      `json:"started_at"`
+	Uptime       int            `json:"uptime"` // 运行时长（秒）
+	Version      string         `json:"version"`
+	Connections  int            `json:"connections"`
+	Spectators   int            `json:"spectators"`
+	Rooms        map[string]int `json:"rooms"`         // 按房间状态统计的房间数
+	MatchesToday int            `json:"matches_today"` // 本地时间今天结束的对局数
+	Messages     MessageStats   `json:"messages"`
+	Stores       StoreStats     `json:"stores"`
+	SlowOps      []SlowOpInfo   `json:"slow_ops,omitempty"` // 按最长耗时排序的慢操作
+	Abandons     AbandonStats   `json:"abandons"`           // 本地时间今天结束的对局的掉线和弃赛统计
+}
+
+// AbandonStats 对局中途掉线和弃赛的统计。玩家统计中 Abandons 为弃赛（断线且未在宽限期内重连）的对局数，
+// 服务器统计中为有玩家弃赛的对局数；ReconnectRate 为掉线后在宽限期内重连成功的比例
+type AbandonStats struct {
+	Matches       int     `json:"matches"`
+	Abandons      int     `json:"abandons"`
+	AbandonRate   float64 `json:"abandon_rate"`
+	Disconnects   int     `json:"disconnects"`
+	Reconnects    int     `json:"reconnects"`
+	ReconnectRate float64 `json:"reconnect_rate"`
+}
+
+// PlayerAbandonInfo 玩家的弃赛统计，由 /match/abandons 返回；Recent 为最近几局的统计，决定弃赛惩罚的冷却时间，
+// Cooldown 为仍需等待的秒数
+type PlayerAbandonInfo struct {
+	Username string       `json:"username"`
+	Recent   AbandonStats `json:"recent"`
+	Total    AbandonStats `json:"total"`
+	Cooldown int          `json:"cooldown,omitempty"`
+}
+
+// MessageStats WebSocket 消息吞吐量，速率为最近一分钟的平均值
+type MessageStats struct {
+	Received       int64                       `json:"received"`
+	Sent           int64                       `json:"sent"`
+	ReceivedPerSec float64                     `json:"received_per_sec"`
+	SentPerSec     float64                     `json:"sent_per_sec"`
+	Types          map[string]MessageTypeStats `json:"types"`       // 按消息类型统计的入站消息
+	TopSenders     []UserMessageStats          `json:"top_senders"` // 在线用户中发送消息最多的用户
+}
+
+// MessageTypeStats 一种入站消息的累计数量，Throttled 为超出配额被拒绝的数量，Quota 为每个用户每分钟的上限（0 表示不限制）
+type MessageTypeStats struct {
+	Received  int64 `json:"received"`
+	Throttled int64 `json:"throttled"`
+	Quota     int   `json:"quota,omitempty"`
+}
+
+// UserMessageStats 用户本次连接发送的消息数，Types 按消息类型统计
+type UserMessageStats struct {
+	Username  string           `json:"username"`
+	Received  int64            `json:"received"`
+	Throttled int64            `json:"throttled"`
+	Types     map[string]int64 `json:"types"`
+}
+
+// SlowOpInfo 超过慢操作阈值的一类操作，耗时单位为毫秒
+type SlowOpInfo struct {
+	Kind    string            `json:"kind"` // http、message 或 store
+	Name    string            `json:"name"`
+	Count   int64             `json:"count"`
+	MaxMs   float64           `json:"max_ms"`
+	AvgMs   float64           `json:"avg_ms"`
+	LastAt  time.Time         `json:"last_at"`
+	Context map[string]string `json:"context,omitempty"` // 耗时最长的一次的上下文，如请求ID、用户名
+}
+
+// StoreStats 各存储的记录数，文件持久化时附带数据文件大小（字节）
+type StoreStats struct {
+	Users    int              `json:"users"`
+	Rooms    int              `json:"rooms"`
+	Results  int              `json:"results"`
+	Sessions int              `json:"sessions"`
+	Files    map[string]int64 `json:"files,omitempty"`
+}
+
+// AnalyticsDayInfo 一天的运营指标
+type AnalyticsDayInfo struct {
+	Date           string  `json:"date"`
+	ActiveUsers    int     `json:"active_users"`
+	Logins         int     `json:"logins"`
+	PeakConcurrent int     `json:"peak_concurrent"`
+	AvgConcurrent  float64 `json:"avg_concurrent"`
+	Matches        int     `json:"matches"`
+	AvgMatchLength float64 `json:"avg_match_length"` // 平均对局时长（秒）
+}
+
+// AnalyticsResponse 运营统计查询响应，按日期升序
+type AnalyticsResponse struct {
+	Days []AnalyticsDayInfo `json:"days"`
+}
+
+// ResultInfo 游戏结果信息
+type ResultInfo struct {
+	ID         string          `json:"id"`
+	RoomID     string          `json:"room_id"`
+	Winner     string          `json:"winner"`
+	Loser      string          `json:"loser"`
+	PlayTime   time.Time       `json:"play_time"`
+	Duration   int             `json:"duration"`
+	Reason     string          `json:"reason,omitempty"`
+	Map        string          `json:"map,omitempty"`
+	Players    []PlayerSummary `json:"players,omitempty"`
+	MVP        string          `json:"mvp,omitempty"`
+	Overtime   bool            `json:"overtime,omitempty"`
+	Placements []string        `json:"placements,omitempty"`
+	BotMatch   bool            `json:"bot_match,omitempty"`
+}
+
+// CheatFlagInfo 玩家的作弊标记，Score 为按违规类型加权累计的违规分，Violations 为各类违规的次数
+type CheatFlagInfo struct {
+	UserID     string         `json:"user_id"`
+	Username   string         `json:"username"`
+	Score      int            `json:"score"`
+	Violations map[string]int `json:"violations,omitempty"`
+	Status     string         `json:"status"`
+	FlaggedAt  time.Time      `json:"flagged_at"`
+	ReviewedAt *time.Time     `json:"reviewed_at,omitempty"`
+	Note       string         `json:"note,omitempty"`
+}
+
+// CheatFlagListResponse 作弊标记审核队列，最早标记的在前；查询待审核队列时附带待审核的对局结果申诉
+type CheatFlagListResponse struct {
+	Flags    []CheatFlagInfo `json:"flags"`
+	Disputes []DisputeInfo   `json:"disputes,omitempty"`
+}
+
+// ReviewFlagRequest 审核作弊标记，Verdict 为 cleared（排除嫌疑，违规分清零）或 confirmed（确认作弊）
+type ReviewFlagRequest struct {
+	Verdict string `json:"verdict"`
+	Note    string `json:"note,omitempty"`
+}
+
+// DisputeRequest 玩家对自己参与的对局结果提出申诉，需在对局结束后的申诉期限内提出
+type DisputeRequest struct {
+	Username  string `json:"username"`
+	SessionID string `json:"session_id"`
+	Reason    string `json:"reason"`
+}
+
+// ReplayRecordInfo 申诉附带的一条对局回放记录，即对局期间房间的入站流量
+type ReplayRecordInfo struct {
+	Time      time.Time       `json:"time"`
+	Username  string          `json:"username"`
+	Event     string          `json:"event"`
+	MessageID string          `json:"message_id,omitempty"`
+	Message   json.RawMessage `json:"message,omitempty"`
+}
+
+// RatingAdjustmentInfo 申诉成立时写入的评分补偿
+type RatingAdjustmentInfo struct {
+	Username string `json:"username"`
+	Delta    int    `json:"delta"`
+}
+
+// DisputeInfo 结果申诉。Replay 和 Flags 为提出申诉时的对局回放和对局玩家的违规记录，只在审核队列中返回
+type DisputeInfo struct {
+	ID          string                 `json:"id"`
+	ResultID    string                 `json:"result_id"`
+	Username    string                 `json:"username"`
+	Reason      string                 `json:"reason"`
+	Status      string                 `json:"status"`
+	CreatedAt   time.Time              `json:"created_at"`
+	ReviewedAt  *time.Time             `json:"reviewed_at,omitempty"`
+	Note        string                 `json:"note,omitempty"`
+	Result      ResultInfo             `json:"result"`
+	Replay      []ReplayRecordInfo     `json:"replay,omitempty"`
+	Flags       []CheatFlagInfo        `json:"flags,omitempty"`
+	Adjustments []RatingAdjustmentInfo `json:"adjustments,omitempty"`
+}
+
+// DisputeListResponse 申诉审核队列，最早提出的在前
+type DisputeListResponse struct {
+	Disputes []DisputeInfo `json:"disputes"`
+}
+
+// ReviewDisputeRequest 审核申诉，Verdict 为 upheld（申诉成立，撤销该局对评分的影响）或 rejected（维持结果）
+type ReviewDisputeRequest struct {
+	Verdict string `json:"verdict"`
+	Note    string `json:"note,omitempty"`
+}
+
+// WordListRequest 管理接口添加屏蔽词请求
+type WordListRequest struct {
+	Words []string `json:"words"`
+}
+
+// WordListResponse 当前的屏蔽词列表，ChatMode 为聊天消息的处理方式
+type WordListResponse struct {
+	Words    []string `json:"words"`
+	ChatMode string   `json:"chat_mode"`
+}
+
+// ResultListResponse 游戏结果分页查询响应
+type ResultListResponse struct {
+	Results []ResultInfo `json:"results"`
+	Total   int          `json:"total"`
+	Offset  int          `json:"offset"`
+	Limit   int          `json:"limit"`
+}
+
+// LoggedOutNotice 服务器强制下线通知
+type LoggedOutNotice struct {
+	Reason string `json:"reason"`
+}
+
+// ChangeEmailRequest 修改邮箱请求，需要确认当前密码；新邮箱收到确认令牌并确认后才生效
+type ChangeEmailRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	NewEmail string `json:"new_email"`
+}
+
+// LoginRecordInfo 一次登录尝试，NewIP 表示该 IP 此前没有成功登录过
+type LoginRecordInfo struct {
+	Time      time.Time `json:"time"`
+	IP        string    `json:"ip"`
+	Device    string    `json:"device"`
+	UserAgent string    `json:"user_agent"`
+	Method    string    `json:"method"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
+	NewIP     bool      `json:"new_ip,omitempty"`
+}
+
+// LoginHistoryResponse 用户最近的登录记录，最新的在前
+type LoginHistoryResponse struct {
+	Logins []LoginRecordInfo `json:"logins"`
+}
+
+// ChangePasswordRequest 修改密码请求，SessionID 为发起请求的会话，修改后只保留该会话
+type ChangePasswordRequest struct {
+	Username    string `json:"username"`
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+	SessionID   string `json:"session_id"`
+}
+
+// ChangeUsernameRequest 修改用户名请求，需要再次确认密码
+type ChangeUsernameRequest struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	NewUsername string `json:"new_username"`
+}
+
+// RecentOpponentInfo 最近交手过的对手，Matches、Wins、Losses 为近期对局中与该对手的交手次数和胜负
+type RecentOpponentInfo struct {
+	Username   string    `json:"username"`
+	AvatarURL  string    `json:"avatar_url,omitempty"`
+	Online     bool      `json:"online"`
+	LastPlayed time.Time `json:"last_played"`
+	Matches    int       `json:"matches"`
+	Wins       int       `json:"wins"`
+	Losses     int       `json:"losses"`
+}
+
+// RecentOpponentsResponse 最近的对手列表，最近交手的在前
+type RecentOpponentsResponse struct {
+	Opponents []RecentOpponentInfo `json:"opponents"`
+}
+
+// UserLookupResponse 按用户名查找用户的结果，Username 为当前用户名，PreviousNames 为曾用名（按修改时间升序）
+type UserLookupResponse struct {
+	ID            string   `json:"id"`
+	Username      string   `json:"username"`
+	PreviousNames []string `json:"previous_names"`
+	AvatarURL     string   `json:"avatar_url,omitempty"` // 没有头像时为空
+}
+
+// TitleInfo 称号或徽章，Key 为装备时使用的标识，赛季称号为 "ID@赛季"；GrantedAt 为空表示尚未获得
+type TitleInfo struct {
+	Key         string     `json:"key"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Kind        string     `json:"kind"`   // title 或 badge
+	Source      string     `json:"source"` // achievement 或 season
+	Season      string     `json:"season,omitempty"`
+	Description string     `json:"description"`
+	GrantedAt   *time.Time `json:"granted_at,omitempty"`
+}
+
+// TitleListResponse 用户拥有的称号、当前装备的称号以及全部称号列表
+type TitleListResponse struct {
+	Active  string      `json:"active,omitempty"`
+	Owned   []TitleInfo `json:"owned"`
+	Catalog []TitleInfo `json:"catalog"`
+}
+
+// SelectTitleRequest 装备称号，Key 为空时卸下称号
+type SelectTitleRequest struct {
+	Username  string `json:"username"`
+	SessionID string `json:"session_id"`
+	Key       string `json:"key"`
+}
+
+// GrantTitleRequest 管理端授予称号，赛季称号需要指定赛季
+type GrantTitleRequest struct {
+	TitleID string `json:"title_id"`
+	Season  string `json:"season,omitempty"`
+}
+
+// CreateTournamentRequest 管理端创建赛事，MaxEntrants 为 0 表示不限人数。
+// Format 为空时为单败淘汰；GroupSize 只用于循环赛，为 0 表示所有玩家一组，否则至少为 3。
+// StartsAt 不为空时启用自动赛程，BreakMinutes 为比赛就绪到开赛的间隔，GraceMinutes 为开赛后等待缺席玩家的时长
+type CreateTournamentRequest struct {
+	Name         string     `json:"name"`
+	Format       string     `json:"format,omitempty"`
+	MaxEntrants  int        `json:"max_entrants,omitempty"`
+	GroupSize    int        `json:"group_size,omitempty"`
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	BreakMinutes int        `json:"break_minutes,omitempty"`
+	GraceMinutes int        `json:"grace_minutes,omitempty"`
+}
+
+// TournamentRegisterRequest 玩家报名赛事
+type TournamentRegisterRequest struct {
+	Username  string `json:"username"`
+	SessionID string `json:"session_id"`
+}
+
+// TournamentEntrantInfo 报名的玩家，生成对阵表后附带种子和当时的评分
+type TournamentEntrantInfo struct {
+	Username string `json:"username"`
+	Seed     int    `json:"seed,omitempty"`
+	Rating   int    `json:"rating,omitempty"`
+}
+
+// BracketMatchInfo 对阵表中的一场比赛，玩家为空表示轮空或尚未决出
+type BracketMatchInfo struct {
+	ID      string `json:"id"`
+	Bracket string `json:"bracket,omitempty"` // 双败淘汰中的分区：胜者组为空，losers 为败者组，final 为总决赛
+	Group   int    `json:"group,omitempty"`   // 循环赛的小组
+	Round   int    `json:"round"`
+	Slot    int    `json:"slot"`
+	Player1 string `json:"player1,omitempty"`
+	Player2 string `json:"player2,omitempty"`
+	Seed1   int    `json:"seed1,omitempty"`
+	Seed2   int    `json:"seed2,omitempty"`
+	Bye     bool   `json:"bye,omitempty"`
+	Winner  string `json:"winner,omitempty"`
+	RoomID  string `json:"room_id,omitempty"` // 进行这场比赛的房间，观战时发送 spectate 携带该房间ID
+
+	// 自动赛程的开赛时间和缺席判负的截止时间，Forfeit 表示胜者因对手缺席获胜
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+	Forfeit     bool       `json:"forfeit,omitempty"`
+}
+
+// TournamentInfo 赛事信息和对阵表，生成对阵表时也通过 tournament_bracket 推送给在线的报名玩家
+type TournamentInfo struct {
+	ID          string                  `json:"id"`
+	Name        string                  `json:"name"`
+	Format      string                  `json:"format"`
+	Status      string                  `json:"status"`
+	MaxEntrants int                     `json:"max_entrants,omitempty"`
+	GroupSize   int                     `json:"group_size,omitempty"`
+	CreatedAt   time.Time               `json:"created_at"`
+	Rounds      int                     `json:"rounds,omitempty"`
+	Entrants    []TournamentEntrantInfo `json:"entrants"`
+	Matches     []BracketMatchInfo      `json:"matches,omitempty"`
+	Standings   []StandingInfo          `json:"standings,omitempty"`
+	Champion    string                  `json:"champion,omitempty"`
+
+	// 自动赛程，StartsAt 为空时由管理员手动开始比赛
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	BreakMinutes int        `json:"break_minutes,omitempty"`
+	GraceMinutes int        `json:"grace_minutes,omitempty"`
+}
+
+// StandingInfo 根据已记录的比赛结果计算的战绩排名，循环赛按小组分别排名，轮空不计入战绩
+type StandingInfo struct {
+	Group    int    `json:"group,omitempty"`
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	Seed     int    `json:"seed"`
+	Played   int    `json:"played"`
+	Wins     int    `json:"wins"`
+	Losses   int    `json:"losses"`
+}
+
+// FollowTournamentRequest 关注赛事，之后对阵表的每次变化都会通过 tournament_bracket 推送；
+// TournamentID 为空表示取消关注。每个连接同时只关注一个赛事
+type FollowTournamentRequest struct {
+	TournamentID string `json:"tournament_id"`
+}
+
+// LiveMatchInfo 正在进行的赛事比赛
+type LiveMatchInfo struct {
+	TournamentID   string           `json:"tournament_id"`
+	TournamentName string           `json:"tournament_name"`
+	Match          BracketMatchInfo `json:"match"`
+	Spectators     int              `json:"spectators"`
+}
+
+// MatchNoticeInfo 自动赛程的比赛即将开始时通过 tournament_match_notice 通知双方，到开赛时间房间自动创建
+type MatchNoticeInfo struct {
+	TournamentID   string           `json:"tournament_id"`
+	TournamentName string           `json:"tournament_name"`
+	Match          BracketMatchInfo `json:"match"`
+}
+
+// LiveMatchListResponse 所有正在进行的赛事比赛，由 /tournaments/live 和 tournament_matches 返回
+type LiveMatchListResponse struct {
+	Matches []LiveMatchInfo `json:"matches"`
+}
+
+// TournamentListResponse 赛事列表，按创建时间排列
+type TournamentListResponse struct {
+	Tournaments []TournamentInfo `json:"tournaments"`
+}
+
+// PlayerSearchResult 玩家搜索结果，用于邀请好友和私聊时的自动补全
+type PlayerSearchResult struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	Online    bool   `json:"online"`
+}
+
+// PlayerSearchResponse 按用户名前缀搜索玩家的结果，按用户名排序，不包含设置了隐藏的玩家
+type PlayerSearchResponse struct {
+	Players []PlayerSearchResult `json:"players"`
+}
+
+// SearchPrivacyRequest 设置是否在玩家搜索中隐藏自己
+type SearchPrivacyRequest struct {
+	Username       string `json:"username"`
+	SessionID      string `json:"session_id"`
+	HideFromSearch bool   `json:"hide_from_search"`
+}
+
+// ConfirmEmailRequest 确认修改邮箱，Token 为发送到新邮箱的确认令牌
+type ConfirmEmailRequest struct {
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+// DeleteAccountRequest 注销账号请求，需要再次确认密码
+type DeleteAccountRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ProfileInfo 用户资料，不包含密码
+type ProfileInfo struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	AvatarURL string    `json:"avatar_url,omitempty"` // 没有头像时为空
+	Email     string    `json:"email"`
+	Online    bool      `json:"online"`
+	LoginTime time.Time `json:"login_time"`
+	RoomID    string    `json:"room_id"`
+}
+
+// StatsInfo 玩家历史战绩
+type StatsInfo struct {
+	Matches    int     `json:"matches"`
+	Wins       int     `json:"wins"`
+	Losses     int     `json:"losses"`
+	Kills      int     `json:"kills"`
+	Deaths     int     `json:"deaths"`
+	ShotsFired int     `json:"shots_fired"`
+	ShotsHit   int     `json:"shots_hit"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// ChatMessageInfo 聊天记录
+type ChatMessageInfo struct {
+	From    string    `json:"from"`
+	Channel string    `json:"channel"`
+	Text    string    `json:"text"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// SpectateRequest 观战请求
+type SpectateRequest struct {
+	RoomID string `json:"room_id"`
+}
+
+// ChatRequest 客户端发送的聊天消息，Channel 为空时观战者默认发到观战频道、玩家发到房间频道
+type ChatRequest struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+// TypingRequest 客户端开始或停止输入聊天消息时发送
+type TypingRequest struct {
+	Typing bool `json:"typing"`
+}
+
+// TypingInfo 转发给房间内其他成员的输入状态，只用于实时展示，不保存
+type TypingInfo struct {
+	From   string `json:"from"`
+	Typing bool   `json:"typing"`
+}
+
+// EmoteRequest 客户端发送的表情动作，Emote 必须是服务器支持的表情之一
+type EmoteRequest struct {
+	Emote string `json:"emote"`
+}
+
+// EmoteInfo 转发给房间内其他成员的表情动作，对局中发送时 MatchTime 为距开局的秒数
+type EmoteInfo struct {
+	From      string    `json:"from"`
+	Emote     string    `json:"emote"`
+	SentAt    time.Time `json:"sent_at"`
+	MatchTime float64   `json:"match_time,omitempty"`
+}
+
+// ChatHistory 一组聊天记录，对局结束后把观战频道的记录发给玩家时使用
+type ChatHistory struct {
+	RoomID   string            `json:"room_id"`
+	Messages []ChatMessageInfo `json:"messages"`
+}
+
+// UserExportResponse 用户数据导出。ChatHistory 来自服务器的流量录制，未开启录制时聊天消息不落盘，该字段省略；
+// 申诉不包括回放和违规记录
+type UserExportResponse struct {
+	ExportedAt  time.Time          `json:"exported_at"`
+	Profile     ProfileInfo        `json:"profile"`
+	Stats       StatsInfo          `json:"stats"`
+	Results     []ResultInfo       `json:"results"`
+	Sessions    []SessionInfo      `json:"sessions"`
+	Logins      []LoginRecordInfo  `json:"logins"`
+	Titles      []TitleInfo        `json:"titles"`
+	ActiveTitle string             `json:"active_title,omitempty"`
+	RoomFilters []RoomFilterInfo   `json:"room_filters"`
+	Invites     []InviteExportInfo `json:"invites"`
+	Ladder      *LadderEntryInfo   `json:"ladder,omitempty"` // 不在天梯上时为空
+	Tournaments []TournamentInfo   `json:"tournaments"`
+	Disputes    []DisputeInfo      `json:"disputes"`
+	ChatHistory []ChatMessageInfo  `json:"chat_history,omitempty"`
+}
+
+// InviteExportInfo 数据导出中用户发出或收到、尚未处理的房间邀请
+type InviteExportInfo struct {
+	ID        string    `json:"id"`
+	Sent      bool      `json:"sent"` // 由该用户发出
+	From      string    `json:"from"`
+	RoomID    string    `json:"room_id"`
+	RoomName  string    `json:"room_name"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Snapshot 对局状态快照，按房间的快照发送频率推送；发送队列持续积压的客户端会降低接收频率，
+// 快照可以丢弃，命中、阵亡等事件消息始终可靠送达
+type Snapshot struct {
+	RoomID  string           `json:"room_id"`
+	Tick    int64            `json:"tick"` // 生成快照时的逻辑帧序号
+	Players []PlayerSnapshot `json:"players"`
+}
+
+// EntitySpawn 服务器分配ID并生成实体，Kind 为 bullet、pickup、projectile；
+// 射击方收到的消息中 ClientRef 为射击时上报的本地ID，用于把本地预测的子弹换成服务器ID
+type EntitySpawn struct {
+	ID        string  `json:"id"`
+	Kind      string  `json:"kind"`
+	OwnerID   string  `json:"owner_id,omitempty"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	VX        float64 `json:"vx,omitempty"`
+	ExpiresIn float64 `json:"expires_in,omitempty"` // 距自动移除的秒数，0 表示不会过期
+	ClientRef string  `json:"client_ref,omitempty"`
+}
+
+// 实体移除原因
+const (
+	DespawnHit        = "hit"         // 子弹命中目标
+	DespawnExpired    = "expired"     // 存活时间到期或飞出场地
+	DespawnRoundReset = "round_reset" // 换局清场
+)
+
+// EntityDespawn 实体被移除，客户端收到后删除对应的实体
+type EntityDespawn struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"`
+}
+
+// RoomKey 对局的房间会话密钥，通过连接加密通道下发。开启房间密钥后，对局广播帧不再按连接加密，
+// 而是以 "<key_id>.<Base64(nonce+密文)>" 的形式发送，客户端按 key_id 选择密钥解密；
+// 其他消息仍是连接密钥加密的纯 Base64，不含 "."。
+// 观战者拿到的是只读密钥，只能解密延迟后的观战帧，无法解密玩家的实时帧；
+// 客户端发往服务器的消息始终使用连接密钥，房间密钥不能用于发送
+type RoomKey struct {
+	RoomID   string `json:"room_id"`
+	KeyID    string `json:"key_id"`
+	Key      string `json:"key"` // Base64 编码的 32 字节 AES-256 密钥
+	ReadOnly bool   `json:"read_only,omitempty"`
+}
+
+// PlayerSnapshot 快照中单个玩家的状态
+type PlayerSnapshot struct {
+	Username string  `json:"username"`
+	Y        float64 `json:"y"`
+	HP       int     `json:"hp"`
+	Out      bool    `json:"out,omitempty"` // 混战中已出局
+}
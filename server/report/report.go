@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Reporter 定义错误上报接口，可接入 Sentry 等外部服务
+type Reporter interface {
+	// Report 上报一次错误，stack 为发生位置的调用栈，tags 为附加上下文
+	Report(err error, stack []byte, tags map[string]string)
+}
+
+// LogReporter 默认的上报实现，只写入日志
+type LogReporter struct{}
+
+// Report 将错误写入日志
+func (LogReporter) Report(err error, stack []byte, tags map[string]string) {
+	log.Printf("[error] %v tags=%v\n%s", err, tags, stack)
+}
+
+var (
+	mu       sync.RWMutex
+	reporter Reporter = LogReporter{}
+)
+
+// SetReporter 替换全局错误上报器，传入 nil 时恢复为日志上报
+func SetReporter(r Reporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	if r == nil {
+		r = LogReporter{}
+	}
+	reporter = r
+}
+
+// Report 通过全局上报器上报错误
+func Report(err error, stack []byte, tags map[string]string) {
+	mu.RLock()
+	r := reporter
+	mu.RUnlock()
+	r.Report(err, stack, tags)
+}
+
+// Panic 将 recover() 得到的值转换为错误后上报
+func Panic(recovered interface{}, stack []byte, tags map[string]string) {
+	err, ok := recovered.(error)
+	if !ok {
+		err = fmt.Errorf("panic: %v", recovered)
+	}
+	Report(err, stack, tags)
+}
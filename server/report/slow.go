@@ -0,0 +1,87 @@
+package report
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 慢操作类别
+const (
+	SlowHTTP    = "http"    // HTTP 请求处理
+	SlowMessage = "message" // Hub 处理一条 WebSocket 消息
+	SlowStore   = "store"   // 存储写盘，期间持有存储的写锁
+)
+
+// SlowOp 同一操作超过阈值的累计情况，Tags 为耗时最长的一次的上下文
+type SlowOp struct {
+	Kind  string
+	Name  string
+	Count int64
+	Max   time.Duration
+	Total time.Duration
+	Last  time.Time
+	Tags  map[string]string
+}
+
+var (
+	slowMu        sync.Mutex
+	slowThreshold time.Duration
+	slowOps       = make(map[string]*SlowOp)
+)
+
+// SetSlowThreshold 设置慢操作阈值，0 表示不检测
+func SetSlowThreshold(d time.Duration) {
+	slowMu.Lock()
+	defer slowMu.Unlock()
+	slowThreshold = d
+}
+
+// Track 统计从 start 开始的一次操作耗时，超过阈值时写警告日志并计入慢操作统计，返回耗时。
+// 通常以 defer report.Track(kind, name, time.Now(), tags) 的形式调用
+func Track(kind, name string, start time.Time, tags map[string]string) time.Duration {
+	elapsed := time.Since(start)
+	slowMu.Lock()
+	defer slowMu.Unlock()
+	if slowThreshold <= 0 || elapsed < slowThreshold {
+		return elapsed
+	}
+	log.Printf("[slow] %s %s 耗时 %v，超过阈值 %v tags=%v", kind, name, elapsed, slowThreshold, tags)
+
+	key := kind + " " + name
+	op := slowOps[key]
+	if op == nil {
+		op = &SlowOp{Kind: kind, Name: name}
+		slowOps[key] = op
+	}
+	op.Count++
+	op.Total += elapsed
+	op.Last = start.Add(elapsed)
+	if elapsed > op.Max {
+		op.Max = elapsed
+		op.Tags = tags
+	}
+	return elapsed
+}
+
+// SlowOps 返回按最长耗时降序排列的慢操作，limit 大于 0 时最多返回 limit 个
+func SlowOps(limit int) []SlowOp {
+	slowMu.Lock()
+	ops := make([]SlowOp, 0, len(slowOps))
+	for _, op := range slowOps {
+		ops = append(ops, *op)
+	}
+	slowMu.Unlock()
+
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Max != ops[j].Max {
+			return ops[i].Max > ops[j].Max
+		}
+		return ops[i].Kind+ops[i].Name < ops[j].Kind+ops[j].Name
+	})
+	if limit > 0 && len(ops) > limit {
+		ops = ops[:limit]
+	}
+	return ops
+}
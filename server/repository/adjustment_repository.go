@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// AdjustmentRepository 定义评分补偿数据访问接口
+type AdjustmentRepository interface {
+	Add(resultID string, adjustments []models.RatingAdjustment) bool
+	Total(userID string) int
+	RemoveUser(userID string) int
+}
+
+// adjustmentRepository 实现 AdjustmentRepository 接口
+type adjustmentRepository struct {
+	store *data.AdjustmentStore
+}
+
+// NewAdjustmentRepository 创建 AdjustmentRepository 实例
+func NewAdjustmentRepository(store *data.AdjustmentStore) AdjustmentRepository {
+	return &adjustmentRepository{store: store}
+}
+
+// Add 写入一局结果的评分补偿
+func (r *adjustmentRepository) Add(resultID string, adjustments []models.RatingAdjustment) bool {
+	return r.store.Add(resultID, adjustments)
+}
+
+// Total 返回用户的评分补偿合计
+func (r *adjustmentRepository) Total(userID string) int {
+	return r.store.Total(userID)
+}
+
+// RemoveUser 删除用户的全部评分补偿
+func (r *adjustmentRepository) RemoveUser(userID string) int {
+	return r.store.RemoveUser(userID)
+}
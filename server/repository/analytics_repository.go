@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+	"time"
+)
+
+// AnalyticsRepository 定义运营统计数据访问接口
+type AnalyticsRepository interface {
+	Since(since time.Time) []models.DailyAnalytics
+}
+
+// analyticsRepository 实现 AnalyticsRepository 接口
+type analyticsRepository struct {
+	store *data.AnalyticsStore
+}
+
+// NewAnalyticsRepository 创建 AnalyticsRepository 实例
+func NewAnalyticsRepository(store *data.AnalyticsStore) AnalyticsRepository {
+	return &analyticsRepository{store: store}
+}
+
+// Since 返回指定日期及之后的每日统计
+func (r *analyticsRepository) Since(since time.Time) []models.DailyAnalytics {
+	return r.store.Since(since)
+}
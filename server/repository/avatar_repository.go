@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"game/data"
+)
+
+// ErrAvatarNotFound 用户没有上传过头像
+var ErrAvatarNotFound = data.ErrAvatarNotFound
+
+// AvatarRepository 定义用户头像数据访问接口
+type AvatarRepository interface {
+	Put(userID string, content []byte) error
+	Get(userID string) ([]byte, error)
+	Delete(userID string) error
+}
+
+// avatarRepository 实现 AvatarRepository 接口
+type avatarRepository struct {
+	store data.AvatarStore
+}
+
+// NewAvatarRepository 创建 AvatarRepository 实例
+func NewAvatarRepository(store data.AvatarStore) AvatarRepository {
+	return &avatarRepository{store: store}
+}
+
+// Put 保存用户的头像
+func (r *avatarRepository) Put(userID string, content []byte) error {
+	return r.store.Put(userID, content)
+}
+
+// Get 读取用户的头像，没有头像时返回 ErrAvatarNotFound
+func (r *avatarRepository) Get(userID string) ([]byte, error) {
+	return r.store.Get(userID)
+}
+
+// Delete 删除用户的头像
+func (r *avatarRepository) Delete(userID string) error {
+	return r.store.Delete(userID)
+}
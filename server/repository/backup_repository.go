@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// BackupRepository 定义数据备份访问接口
+type BackupRepository interface {
+	Create() (models.BackupInfo, error)
+	List() ([]models.BackupInfo, error)
+}
+
+// backupRepository 实现 BackupRepository 接口
+type backupRepository struct {
+	manager *data.BackupManager
+}
+
+// NewBackupRepository 创建 BackupRepository 实例
+func NewBackupRepository(manager *data.BackupManager) BackupRepository {
+	return &backupRepository{manager: manager}
+}
+
+// Create 创建一份备份
+func (r *backupRepository) Create() (models.BackupInfo, error) {
+	return r.manager.Create()
+}
+
+// List 列出所有备份
+func (r *backupRepository) List() ([]models.BackupInfo, error) {
+	return data.ListBackups()
+}
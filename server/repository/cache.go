@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"container/list"
+	"sync"
+
+	"game/data"
+	"game/models"
+)
+
+// CacheStats 缓存命中统计
+type CacheStats struct {
+	Name      string `json:"name"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+}
+
+var (
+	cachesMu sync.Mutex
+	caches   []*lruCache
+)
+
+// CacheStatistics 返回所有已创建缓存的统计信息
+func CacheStatistics() []CacheStats {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+	stats := make([]CacheStats, 0, len(caches))
+	for _, c := range caches {
+		stats = append(stats, c.stats())
+	}
+	return stats
+}
+
+type cacheEntry struct {
+	key   string
+	value interface{}
+}
+
+// lruCache 固定容量的 LRU 缓存。
+// generation 在每次失效时递增，未命中回源期间发生过失效的结果不会写入缓存，避免缓存旧数据
+type lruCache struct {
+	mu         sync.Mutex
+	name       string
+	capacity   int
+	order      *list.List
+	items      map[string]*list.Element
+	generation uint64
+
+	hits, misses, evictions uint64
+}
+
+func newLRUCache(name string, capacity int) *lruCache {
+	c := &lruCache{
+		name:     name,
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+	cachesMu.Lock()
+	caches = append(caches, c)
+	cachesMu.Unlock()
+	return c
+}
+
+// get 查找缓存，未命中时同时返回当前的失效代数，供 put 判断是否可以写入
+func (c *lruCache) get(key string) (interface{}, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		c.hits++
+		return el.Value.(*cacheEntry).value, c.generation, true
+	}
+	c.misses++
+	return nil, c.generation, false
+}
+
+// put 写入缓存，generation 与当前代数不一致时放弃写入
+func (c *lruCache) put(key string, value interface{}, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		el.Value.(*cacheEntry).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+		c.evictions++
+	}
+}
+
+// invalidate 删除缓存项并递增失效代数
+func (c *lruCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+func (c *lruCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Name:      c.name,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Size:      c.order.Len(),
+		Capacity:  c.capacity,
+	}
+}
+
+// cachedUserRepository 为按用户名查询增加读穿透缓存，其余操作直接转发
+type cachedUserRepository struct {
+	UserRepository
+	cache *lruCache
+}
+
+// NewCachedUserRepository 创建带缓存的 UserRepository，store 的每次写入都会同步使对应缓存失效
+func NewCachedUserRepository(store *data.UserStore, capacity int) UserRepository {
+	r := &cachedUserRepository{
+		UserRepository: NewUserRepository(store),
+		cache:          newLRUCache("users", capacity),
+	}
+	store.OnInvalidate(r.cache.invalidate)
+	return r
+}
+
+// FindByUsername 根据用户名查找用户，优先读取缓存
+func (r *cachedUserRepository) FindByUsername(username string) *models.User {
+	v, generation, ok := r.cache.get(username)
+	if ok {
+		user := v.(models.User).Clone()
+		return &user
+	}
+	user := r.UserRepository.FindByUsername(username)
+	if user != nil {
+		r.cache.put(username, user.Clone(), generation)
+	}
+	return user
+}
+
+// cachedRoomRepository 为按ID查询房间增加读穿透缓存，其余操作直接转发
+type cachedRoomRepository struct {
+	RoomRepository
+	cache *lruCache
+}
+
+// NewCachedRoomRepository 创建带缓存的 RoomRepository，store 的每次写入都会同步使对应缓存失效
+func NewCachedRoomRepository(store *data.RoomStore, capacity int) RoomRepository {
+	r := &cachedRoomRepository{
+		RoomRepository: NewRoomRepository(store),
+		cache:          newLRUCache("rooms", capacity),
+	}
+	store.OnInvalidate(r.cache.invalidate)
+	return r
+}
+
+// GetByID 根据ID查找房间，优先读取缓存
+func (r *cachedRoomRepository) GetByID(id string) *models.Room {
+	v, generation, ok := r.cache.get(id)
+	if ok {
+		room := v.(models.Room).Clone()
+		return &room
+	}
+	room := r.RoomRepository.GetByID(id)
+	if room != nil {
+		r.cache.put(id, room.Clone(), generation)
+	}
+	return room
+}
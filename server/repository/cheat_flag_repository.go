@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// CheatFlagRepository 定义作弊标记数据访问接口
+type CheatFlagRepository interface {
+	Get(userID string) (models.CheatFlag, bool)
+	All() []models.CheatFlag
+	Modify(userID string, fn func(flag *models.CheatFlag) bool) models.CheatFlag
+	Remove(userID string) bool
+}
+
+// cheatFlagRepository 实现 CheatFlagRepository 接口
+type cheatFlagRepository struct {
+	store *data.CheatFlagStore
+}
+
+// NewCheatFlagRepository 创建 CheatFlagRepository 实例
+func NewCheatFlagRepository(store *data.CheatFlagStore) CheatFlagRepository {
+	return &cheatFlagRepository{store: store}
+}
+
+// Get 返回用户的作弊标记
+func (r *cheatFlagRepository) Get(userID string) (models.CheatFlag, bool) {
+	return r.store.Get(userID)
+}
+
+// All 返回所有作弊标记
+func (r *cheatFlagRepository) All() []models.CheatFlag {
+	return r.store.All()
+}
+
+// Modify 在存储锁内修改用户的作弊标记
+func (r *cheatFlagRepository) Modify(userID string, fn func(flag *models.CheatFlag) bool) models.CheatFlag {
+	return r.store.Modify(userID, fn)
+}
+
+// Remove 删除用户的作弊标记
+func (r *cheatFlagRepository) Remove(userID string) bool {
+	return r.store.Remove(userID)
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// DisputeRepository 定义结果申诉数据访问接口
+type DisputeRepository interface {
+	All() []models.Dispute
+	Add(d models.Dispute) bool
+	Modify(id string, fn func(d *models.Dispute) bool) (models.Dispute, bool)
+	RemoveUser(userID string, names []string, alias string) int
+}
+
+// disputeRepository 实现 DisputeRepository 接口
+type disputeRepository struct {
+	store *data.DisputeStore
+}
+
+// NewDisputeRepository 创建 DisputeRepository 实例
+func NewDisputeRepository(store *data.DisputeStore) DisputeRepository {
+	return &disputeRepository{store: store}
+}
+
+// All 返回所有申诉
+func (r *disputeRepository) All() []models.Dispute {
+	return r.store.All()
+}
+
+// Add 保存新申诉
+func (r *disputeRepository) Add(d models.Dispute) bool {
+	return r.store.Add(d)
+}
+
+// Modify 在存储锁内修改申诉
+func (r *disputeRepository) Modify(id string, fn func(d *models.Dispute) bool) (models.Dispute, bool) {
+	return r.store.Modify(id, fn)
+}
+
+// RemoveUser 删除用户提出的申诉，并匿名化其他申诉中该用户的数据
+func (r *disputeRepository) RemoveUser(userID string, names []string, alias string) int {
+	return r.store.RemoveUser(userID, names, alias)
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// EventRepository 定义限时活动数据访问接口
+type EventRepository interface {
+	All() []models.Event
+	Create(e models.Event)
+	Remove(id string) bool
+}
+
+// eventRepository 实现 EventRepository 接口
+type eventRepository struct {
+	store *data.EventStore
+}
+
+// NewEventRepository 创建 EventRepository 实例
+func NewEventRepository(store *data.EventStore) EventRepository {
+	return &eventRepository{store: store}
+}
+
+// All 返回所有活动
+func (r *eventRepository) All() []models.Event {
+	return r.store.All()
+}
+
+// Create 保存新活动
+func (r *eventRepository) Create(e models.Event) {
+	r.store.Create(e)
+}
+
+// Remove 删除活动
+func (r *eventRepository) Remove(id string) bool {
+	return r.store.Remove(id)
+}
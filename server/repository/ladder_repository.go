@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// LadderRepository 定义天梯数据访问接口
+type LadderRepository interface {
+	All() []models.LadderEntry
+	Position(userID string) int
+	Join(entry models.LadderEntry) int
+	Swap(lowerID, higherID string) bool
+	Remove(userID string) bool
+}
+
+// ladderRepository 实现 LadderRepository 接口
+type ladderRepository struct {
+	store *data.LadderStore
+}
+
+// NewLadderRepository 创建 LadderRepository 实例
+func NewLadderRepository(store *data.LadderStore) LadderRepository {
+	return &ladderRepository{store: store}
+}
+
+// All 返回按排名排列的天梯
+func (r *ladderRepository) All() []models.LadderEntry {
+	return r.store.All()
+}
+
+// Position 返回用户的排名，不在天梯上时返回 0
+func (r *ladderRepository) Position(userID string) int {
+	return r.store.Position(userID)
+}
+
+// Join 把玩家加入天梯末尾，返回其排名
+func (r *ladderRepository) Join(entry models.LadderEntry) int {
+	return r.store.Join(entry)
+}
+
+// Swap 在 lowerID 的排名低于 higherID 时交换双方的排名
+func (r *ladderRepository) Swap(lowerID, higherID string) bool {
+	return r.store.Swap(lowerID, higherID)
+}
+
+// Remove 把用户移出天梯
+func (r *ladderRepository) Remove(userID string) bool {
+	return r.store.Remove(userID)
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// LoginHistoryRepository 定义登录历史数据访问接口
+type LoginHistoryRepository interface {
+	Add(record models.LoginRecord)
+	ListByUser(userID string) []models.LoginRecord
+	RemoveUser(userID string)
+}
+
+// loginHistoryRepository 实现 LoginHistoryRepository 接口
+type loginHistoryRepository struct {
+	store *data.LoginHistoryStore
+}
+
+// NewLoginHistoryRepository 创建 LoginHistoryRepository 实例
+func NewLoginHistoryRepository(store *data.LoginHistoryStore) LoginHistoryRepository {
+	return &loginHistoryRepository{store: store}
+}
+
+// Add 追加一条登录记录
+func (r *loginHistoryRepository) Add(record models.LoginRecord) {
+	r.store.Add(record)
+}
+
+// ListByUser 返回用户的登录记录，最新的在前
+func (r *loginHistoryRepository) ListByUser(userID string) []models.LoginRecord {
+	return r.store.ListByUser(userID)
+}
+
+// RemoveUser 删除用户的全部登录记录
+func (r *loginHistoryRepository) RemoveUser(userID string) {
+	r.store.RemoveUser(userID)
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// PlaylistRepository 定义精选玩法数据访问接口
+type PlaylistRepository interface {
+	Get() models.Playlist
+	Set(p models.Playlist)
+}
+
+// playlistRepository 实现 PlaylistRepository 接口
+type playlistRepository struct {
+	store *data.PlaylistStore
+}
+
+// NewPlaylistRepository 创建 PlaylistRepository 实例
+func NewPlaylistRepository(store *data.PlaylistStore) PlaylistRepository {
+	return &playlistRepository{store: store}
+}
+
+// Get 返回精选玩法列表
+func (r *playlistRepository) Get() models.Playlist {
+	return r.store.Get()
+}
+
+// Set 替换精选玩法列表
+func (r *playlistRepository) Set(p models.Playlist) {
+	r.store.Set(p)
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// PracticeRepository 定义练习成绩数据访问接口
+type PracticeRepository interface {
+	Get(userID string) (models.PracticeRecord, bool)
+	Modify(userID string, fn func(record *models.PracticeRecord) bool) models.PracticeRecord
+	Remove(userID string) bool
+}
+
+// practiceRepository 实现 PracticeRepository 接口
+type practiceRepository struct {
+	store *data.PracticeStore
+}
+
+// NewPracticeRepository 创建 PracticeRepository 实例
+func NewPracticeRepository(store *data.PracticeStore) PracticeRepository {
+	return &practiceRepository{store: store}
+}
+
+// Get 返回用户的个人最佳成绩
+func (r *practiceRepository) Get(userID string) (models.PracticeRecord, bool) {
+	return r.store.Get(userID)
+}
+
+// Modify 在存储锁内修改用户的成绩记录
+func (r *practiceRepository) Modify(userID string, fn func(record *models.PracticeRecord) bool) models.PracticeRecord {
+	return r.store.Modify(userID, fn)
+}
+
+// Remove 删除用户的成绩记录
+func (r *practiceRepository) Remove(userID string) bool {
+	return r.store.Remove(userID)
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+	"time"
+)
+
+// RefreshTokenRepository 定义刷新令牌数据访问接口
+type RefreshTokenRepository interface {
+	Add(token models.RefreshToken)
+	Rotate(hash string, next models.RefreshToken, now time.Time) *models.RefreshToken
+	RemoveUser(userID string) int
+}
+
+// refreshTokenRepository 实现 RefreshTokenRepository 接口
+type refreshTokenRepository struct {
+	store *data.RefreshTokenStore
+}
+
+// NewRefreshTokenRepository 创建 RefreshTokenRepository 实例
+func NewRefreshTokenRepository(store *data.RefreshTokenStore) RefreshTokenRepository {
+	return &refreshTokenRepository{store: store}
+}
+
+// Add 保存新令牌
+func (r *refreshTokenRepository) Add(token models.RefreshToken) {
+	r.store.Add(token)
+}
+
+// Rotate 轮换令牌，返回被轮换的令牌，令牌无效或被重复使用时返回 nil
+func (r *refreshTokenRepository) Rotate(hash string, next models.RefreshToken, now time.Time) *models.RefreshToken {
+	return r.store.Rotate(hash, next, now)
+}
+
+// RemoveUser 吊销用户的全部令牌
+func (r *refreshTokenRepository) RemoveUser(userID string) int {
+	return r.store.RemoveUser(userID)
+}
@@ -3,12 +3,33 @@ package repository
 import (
 	"game/data"
 	"game/models"
+	"time"
 )
 
+// ResultQuery 定义游戏结果查询条件，零值字段表示不过滤
+type ResultQuery struct {
+	RoomID string
+	Player string
+	Since  time.Time
+	Offset int
+	Limit  int
+}
+
 // ResultRepository 定义游戏结果数据访问接口
 type ResultRepository interface {
 	Add(result models.GameResult)
 	GetAll() []models.GameResult
+	FindByRoom(roomID string) []models.GameResult
+	FindByPlayer(username string) []models.GameResult
+	FindByUser(userID, username string) []models.GameResult
+	FindSince(since time.Time) []models.GameResult
+	Query(q ResultQuery) ([]models.GameResult, int)
+	FindBefore(cutoff time.Time) []models.GameResult
+	RemoveBefore(cutoff time.Time) int
+	Archive(results []models.GameResult) error
+	RenamePlayer(username, alias string) int
+	TagPlayer(username, userID string) int
+	Compact() (bool, error)
 }
 
 // resultRepository 实现 ResultRepository 接口
@@ -29,4 +50,73 @@ func (r *resultRepository) Add(result models.GameResult) {
 // GetAll 获取所有游戏结果
 func (r *resultRepository) GetAll() []models.GameResult {
 	return r.store.GetAll()
+}
+
+// FindByRoom 查找指定房间的游戏结果
+func (r *resultRepository) FindByRoom(roomID string) []models.GameResult {
+	return r.store.FindByRoom(roomID)
+}
+
+// FindByPlayer 查找指定玩家参与的游戏结果
+func (r *resultRepository) FindByPlayer(username string) []models.GameResult {
+	return r.store.FindByPlayer(username)
+}
+
+// FindSince 查找指定时间之后的游戏结果
+func (r *resultRepository) FindSince(since time.Time) []models.GameResult {
+	return r.store.FindSince(since)
+}
+
+// Query 按组合条件分页查询游戏结果，返回当前页和匹配总数
+func (r *resultRepository) Query(q ResultQuery) ([]models.GameResult, int) {
+	return r.store.Query(func(result models.GameResult) bool {
+		if q.RoomID != "" && result.RoomID != q.RoomID {
+			return false
+		}
+		if q.Player != "" && result.Winner != q.Player && result.Loser != q.Player {
+			return false
+		}
+		if !q.Since.IsZero() && result.PlayTime.Before(q.Since) {
+			return false
+		}
+		return true
+	}, q.Offset, q.Limit)
+}
+
+// FindBefore 查找指定时间之前的游戏结果
+func (r *resultRepository) FindBefore(cutoff time.Time) []models.GameResult {
+	results, _ := r.store.Query(func(result models.GameResult) bool {
+		return result.PlayTime.Before(cutoff)
+	}, 0, 0)
+	return results
+}
+
+// RemoveBefore 删除指定时间之前的游戏结果
+func (r *resultRepository) RemoveBefore(cutoff time.Time) int {
+	return r.store.RemoveBefore(cutoff)
+}
+
+// Archive 将游戏结果写入按月压缩的归档文件
+func (r *resultRepository) Archive(results []models.GameResult) error {
+	return data.ArchiveResults(results)
+}
+
+// FindByUser 按稳定用户ID和当前用户名查找用户参与的游戏结果
+func (r *resultRepository) FindByUser(userID, username string) []models.GameResult {
+	return r.store.FindByUser(userID, username)
+}
+
+// TagPlayer 为以 username 参与的玩家记录补上用户ID
+func (r *resultRepository) TagPlayer(username, userID string) int {
+	return r.store.TagPlayer(username, userID)
+}
+
+// RenamePlayer 替换结果中的玩家名
+func (r *resultRepository) RenamePlayer(username, alias string) int {
+	return r.store.RenamePlayer(username, alias)
+}
+
+// Compact 日志存在冗余行时重写日志，返回是否执行了压缩
+func (r *resultRepository) Compact() (bool, error) {
+	return r.store.Compact()
 }
\ No newline at end of file
@@ -5,12 +5,20 @@ import (
 	"game/models"
 )
 
+// 按修订号更新房间时的失败原因
+var (
+	ErrRoomNotFound    = data.ErrRoomNotFound
+	ErrVersionConflict = data.ErrVersionConflict
+)
+
 // RoomRepository 定义房间数据访问接口
 type RoomRepository interface {
 	Add(room models.Room)
 	GetByID(id string) *models.Room
 	GetAll() []models.Room
-	Update(room models.Room) bool
+	// Update 按修订号写回房间，读取后房间已被修改时返回 ErrVersionConflict
+	Update(room models.Room) error
+	Modify(id string, fn func(room *models.Room) bool) bool
 	Remove(id string) bool
 }
 
@@ -39,11 +47,16 @@ func (r *roomRepository) GetAll() []models.Room {
 	return r.store.GetAll()
 }
 
-// Update 更新房间信息
-func (r *roomRepository) Update(room models.Room) bool {
+// Update 按修订号更新房间信息
+func (r *roomRepository) Update(room models.Room) error {
 	return r.store.Update(room)
 }
 
+// Modify 原子地读取、修改并写回房间
+func (r *roomRepository) Modify(id string, fn func(room *models.Room) bool) bool {
+	return r.store.Modify(id, fn)
+}
+
 // Remove 删除房间
 func (r *roomRepository) Remove(id string) bool {
 	return r.store.Remove(id)
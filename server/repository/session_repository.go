@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// SessionRepository 定义登录会话数据访问接口
+type SessionRepository interface {
+	Add(session models.Session)
+	Get(id string) *models.Session
+	ListByUser(userID string) []models.Session
+	Remove(id string) bool
+	RemoveUser(userID string) int
+}
+
+// sessionRepository 实现 SessionRepository 接口
+type sessionRepository struct {
+	store *data.SessionStore
+}
+
+// NewSessionRepository 创建 SessionRepository 实例
+func NewSessionRepository(store *data.SessionStore) SessionRepository {
+	return &sessionRepository{store: store}
+}
+
+// Add 添加会话
+func (r *sessionRepository) Add(session models.Session) {
+	r.store.Add(session)
+}
+
+// Get 根据ID查找会话
+func (r *sessionRepository) Get(id string) *models.Session {
+	return r.store.Get(id)
+}
+
+// ListByUser 返回用户的所有会话
+func (r *sessionRepository) ListByUser(userID string) []models.Session {
+	return r.store.ListByUser(userID)
+}
+
+// Remove 删除会话
+func (r *sessionRepository) Remove(id string) bool {
+	return r.store.Remove(id)
+}
+
+// RemoveUser 删除用户的所有会话
+func (r *sessionRepository) RemoveUser(userID string) int {
+	return r.store.RemoveUser(userID)
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// TournamentRepository 定义赛事数据访问接口
+type TournamentRepository interface {
+	Get(id string) (models.Tournament, bool)
+	All() []models.Tournament
+	Create(t models.Tournament)
+	Modify(id string, fn func(t *models.Tournament) bool) bool
+}
+
+// tournamentRepository 实现 TournamentRepository 接口
+type tournamentRepository struct {
+	store *data.TournamentStore
+}
+
+// NewTournamentRepository 创建 TournamentRepository 实例
+func NewTournamentRepository(store *data.TournamentStore) TournamentRepository {
+	return &tournamentRepository{store: store}
+}
+
+// Get 根据ID查找赛事
+func (r *tournamentRepository) Get(id string) (models.Tournament, bool) {
+	return r.store.Get(id)
+}
+
+// All 返回所有赛事
+func (r *tournamentRepository) All() []models.Tournament {
+	return r.store.All()
+}
+
+// Create 保存新赛事
+func (r *tournamentRepository) Create(t models.Tournament) {
+	r.store.Create(t)
+}
+
+// Modify 在存储锁内修改赛事
+func (r *tournamentRepository) Modify(id string, fn func(t *models.Tournament) bool) bool {
+	return r.store.Modify(id, fn)
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"game/data"
+	"game/models"
+)
+
+// Tx 定义事务内可用的用户和房间操作，读取结果包含本事务尚未提交的修改
+type Tx interface {
+	User(username string) *models.User
+	UpdateUser(user models.User) bool
+	Room(id string) *models.Room
+	PutRoom(room models.Room)
+	RemoveRoom(id string)
+}
+
+// UnitOfWork 定义跨用户、房间存储的事务接口：fn 返回 nil 时所有修改一起提交，否则全部丢弃
+type UnitOfWork interface {
+	Do(fn func(tx Tx) error) error
+}
+
+// unitOfWork 实现 UnitOfWork 接口
+type unitOfWork struct {
+	users *data.UserStore
+	rooms *data.RoomStore
+}
+
+// NewUnitOfWork 创建 UnitOfWork 实例
+func NewUnitOfWork(users *data.UserStore, rooms *data.RoomStore) UnitOfWork {
+	return &unitOfWork{users: users, rooms: rooms}
+}
+
+// Do 在事务中执行 fn
+func (u *unitOfWork) Do(fn func(tx Tx) error) error {
+	return data.RunTransaction(u.users, u.rooms, func(tx *data.Txn) error {
+		return fn(tx)
+	})
+}
@@ -3,15 +3,30 @@ package repository
 import (
 	"game/data"
 	"game/models"
+	"time"
+)
+
+// 创建用户时的唯一性冲突
+var (
+	ErrUsernameTaken = data.ErrUsernameTaken
+	ErrEmailTaken    = data.ErrEmailTaken
 )
 
 // UserRepository 定义用户数据访问接口
 type UserRepository interface {
 	Add(user models.User)
+	Create(user models.User) error
 	FindByUsername(username string) *models.User
+	FindByID(id string) *models.User
 	FindByEmail(email string) *models.User
+	FindByExternalAccount(provider, subject string) *models.User
+	FindByPreviousName(username string) *models.User
 	Update(username string, user models.User) bool
+	Modify(username string, fn func(user *models.User) bool) bool
+	ChangeEmail(username, email string) (bool, error)
+	Rename(username, newName string, at time.Time) (bool, error)
 	GetAll() []models.User
+	Remove(username string) bool
 }
 
 // userRepository 实现 UserRepository 接口
@@ -29,22 +44,62 @@ func (r *userRepository) Add(user models.User) {
 	r.store.Add(user)
 }
 
+// Create 添加新用户，用户名或邮箱不区分大小写重复时返回 ErrUsernameTaken 或 ErrEmailTaken
+func (r *userRepository) Create(user models.User) error {
+	return r.store.Create(user)
+}
+
 // FindByUsername 根据用户名查找用户
 func (r *userRepository) FindByUsername(username string) *models.User {
 	return r.store.FindByUsername(username)
 }
 
+// FindByID 根据稳定的用户ID查找用户
+func (r *userRepository) FindByID(id string) *models.User {
+	return r.store.FindByID(id)
+}
+
 // FindByEmail 根据邮箱查找用户
 func (r *userRepository) FindByEmail(email string) *models.User {
 	return r.store.FindByEmail(email)
 }
 
+// FindByExternalAccount 查找关联了指定第三方账号的用户
+func (r *userRepository) FindByExternalAccount(provider, subject string) *models.User {
+	return r.store.FindByExternalAccount(provider, subject)
+}
+
+// FindByPreviousName 查找曾经使用过指定用户名的用户
+func (r *userRepository) FindByPreviousName(username string) *models.User {
+	return r.store.FindByPreviousName(username)
+}
+
 // Update 更新用户信息
 func (r *userRepository) Update(username string, user models.User) bool {
 	return r.store.Update(username, user)
 }
 
+// Modify 原子地读取、修改并写回用户
+func (r *userRepository) Modify(username string, fn func(user *models.User) bool) bool {
+	return r.store.Modify(username, fn)
+}
+
+// ChangeEmail 替换用户邮箱，邮箱不区分大小写被其他用户占用时返回 ErrEmailTaken
+func (r *userRepository) ChangeEmail(username, email string) (bool, error) {
+	return r.store.ChangeEmail(username, email)
+}
+
+// Rename 修改用户名并记录旧用户名，新用户名被占用时返回 ErrUsernameTaken
+func (r *userRepository) Rename(username, newName string, at time.Time) (bool, error) {
+	return r.store.Rename(username, newName, at)
+}
+
 // GetAll 获取所有用户
 func (r *userRepository) GetAll() []models.User {
 	return r.store.GetAll()
+}
+
+// Remove 删除用户
+func (r *userRepository) Remove(username string) bool {
+	return r.store.Remove(username)
 }
\ No newline at end of file
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"game/data"
+)
+
+// WordRepository 定义屏蔽词数据访问接口
+type WordRepository interface {
+	All() []string
+	Add(words []string) int
+	Remove(word string) bool
+}
+
+// wordRepository 实现 WordRepository 接口
+type wordRepository struct {
+	store *data.WordStore
+}
+
+// NewWordRepository 创建 WordRepository 实例
+func NewWordRepository(store *data.WordStore) WordRepository {
+	return &wordRepository{store: store}
+}
+
+// All 返回全部屏蔽词
+func (r *wordRepository) All() []string {
+	return r.store.All()
+}
+
+// Add 添加屏蔽词，返回实际添加的数量
+func (r *wordRepository) Add(words []string) int {
+	return r.store.Add(words)
+}
+
+// Remove 删除屏蔽词，返回是否存在
+func (r *wordRepository) Remove(word string) bool {
+	return r.store.Remove(word)
+}
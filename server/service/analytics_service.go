@@ -0,0 +1,86 @@
+package service
+
+import (
+	"game/repository"
+	"time"
+)
+
+const (
+	defaultAnalyticsDays = 30  // 默认统计天数
+	maxAnalyticsDays     = 365 // 最多统计天数
+)
+
+// AnalyticsDay 一天的运营指标
+type AnalyticsDay struct {
+	Date           string
+	ActiveUsers    int
+	Logins         int
+	PeakConcurrent int
+	AvgConcurrent  float64
+	Matches        int
+	AvgMatchLength float64 // 平均对局时长（秒）
+}
+
+// AnalyticsService 定义运营统计业务逻辑接口
+type AnalyticsService interface {
+	// Daily 返回截至 now 最近 days 天的每日指标，days 超出范围时使用默认值或上限
+	Daily(now time.Time, days int) []AnalyticsDay
+}
+
+// analyticsService 实现 AnalyticsService 接口
+type analyticsService struct {
+	analyticsRepo repository.AnalyticsRepository
+	resultRepo    repository.ResultRepository
+}
+
+// NewAnalyticsService 创建 AnalyticsService 实例
+func NewAnalyticsService(analyticsRepo repository.AnalyticsRepository, resultRepo repository.ResultRepository) AnalyticsService {
+	return &analyticsService{analyticsRepo: analyticsRepo, resultRepo: resultRepo}
+}
+
+// Daily 汇总每日活跃用户、峰值和平均在线数，以及当天结束的对局数和平均时长
+func (s *analyticsService) Daily(now time.Time, days int) []AnalyticsDay {
+	if days <= 0 {
+		days = defaultAnalyticsDays
+	}
+	days = min(days, maxAnalyticsDays)
+	year, month, day := now.Date()
+	since := time.Date(year, month, day-days+1, 0, 0, 0, 0, now.Location())
+
+	list := make([]AnalyticsDay, 0, days)
+	index := make(map[string]int, days)
+	for d := since; !d.After(now); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		index[date] = len(list)
+		list = append(list, AnalyticsDay{Date: date})
+	}
+
+	for _, a := range s.analyticsRepo.Since(since) {
+		i, ok := index[a.Date]
+		if !ok {
+			continue
+		}
+		list[i].ActiveUsers = len(a.ActiveUsers)
+		list[i].Logins = a.Logins
+		list[i].PeakConcurrent = a.PeakConcurrent
+		if a.Samples > 0 {
+			list[i].AvgConcurrent = float64(a.ConcurrentSum) / float64(a.Samples)
+		}
+	}
+
+	durations := make([]int, len(list))
+	for _, r := range s.resultRepo.FindSince(since) {
+		i, ok := index[r.PlayTime.In(now.Location()).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		list[i].Matches++
+		durations[i] += r.Duration
+	}
+	for i := range list {
+		if list[i].Matches > 0 {
+			list[i].AvgMatchLength = float64(durations[i]) / float64(list[i].Matches)
+		}
+	}
+	return list
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"game/auth"
+	"game/models"
+	"game/repository"
+	"game/validate"
+)
+
+const (
+	// authStateTTL 第三方登录流程的有效期，超时未回调的 state 作废
+	authStateTTL = 10 * time.Minute
+	// maxPendingAuth 同时进行中的第三方登录流程上限。开始登录不需要认证，不设上限时可以被反复调用占满内存
+	maxPendingAuth = 10000
+)
+
+var (
+	// ErrUnknownProvider 请求了未启用的第三方登录提供方
+	ErrUnknownProvider = errors.New("不支持的登录方式")
+	// ErrAuthBusy 进行中的第三方登录流程已达上限
+	ErrAuthBusy = errors.New("登录请求过多，请稍后再试")
+)
+
+// AuthService 定义第三方登录业务逻辑接口
+type AuthService interface {
+	Providers() []string
+	// BeginLogin 开始第三方登录，返回提供方登录页地址
+	BeginLogin(provider string) (string, error)
+	// BeginLink 校验已有账号的密码后开始关联第三方账号，返回提供方登录页地址
+	BeginLink(provider, username, password string) (string, error)
+	// CompleteLogin 处理提供方回调：关联账号，或登录已关联的用户，首次登录时自动创建用户。
+	// 前三个返回值与 UserService.Login 相同，最后一个表示用户是否因此登录（关联流程不登录）
+	CompleteLogin(ctx context.Context, provider string, params url.Values) (bool, string, string, bool)
+}
+
+// pendingAuth 一次进行中的第三方登录流程
+type pendingAuth struct {
+	provider string
+	linkTo   string // 关联流程的目标用户，登录流程为空
+	expires  time.Time
+}
+
+// authService 实现 AuthService 接口
+type authService struct {
+	userRepo     repository.UserRepository
+	providers    *auth.Registry
+	callbackBase string
+
+	mu      sync.Mutex
+	pending map[string]pendingAuth // 按 state 索引
+}
+
+// NewAuthService 创建 AuthService 实例，callbackBase 为服务器对外地址，回调地址为 callbackBase/auth/{provider}/callback
+func NewAuthService(userRepo repository.UserRepository, providers *auth.Registry, callbackBase string) AuthService {
+	return &authService{
+		userRepo:     userRepo,
+		providers:    providers,
+		callbackBase: strings.TrimRight(callbackBase, "/"),
+		pending:      make(map[string]pendingAuth),
+	}
+}
+
+// Providers 返回已启用的提供方名称
+func (s *authService) Providers() []string {
+	return s.providers.Names()
+}
+
+// BeginLogin 开始第三方登录
+func (s *authService) BeginLogin(provider string) (string, error) {
+	return s.begin(provider, "")
+}
+
+// BeginLink 校验密码后开始关联第三方账号
+func (s *authService) BeginLink(provider, username, password string) (string, error) {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil || user.Password != hashPassword(password) {
+		return "", errors.New("用户名或密码错误")
+	}
+	return s.begin(provider, username)
+}
+
+func (s *authService) begin(name, linkTo string) (string, error) {
+	p, ok := s.providers.Get(name)
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+	state, err := newAuthState()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	for k, v := range s.pending {
+		if now.After(v.expires) {
+			delete(s.pending, k)
+		}
+	}
+	if len(s.pending) >= maxPendingAuth {
+		s.mu.Unlock()
+		return "", ErrAuthBusy
+	}
+	s.pending[state] = pendingAuth{provider: name, linkTo: linkTo, expires: now.Add(authStateTTL)}
+	s.mu.Unlock()
+
+	return p.AuthURL(state, s.callbackURL(name)), nil
+}
+
+// CompleteLogin 处理提供方回调
+func (s *authService) CompleteLogin(ctx context.Context, name string, params url.Values) (bool, string, string, bool) {
+	p, ok := s.providers.Get(name)
+	if !ok {
+		return false, ErrUnknownProvider.Error(), "", false
+	}
+
+	// state 只能使用一次
+	state := params.Get("state")
+	s.mu.Lock()
+	flow, ok := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+	if !ok || flow.provider != name || time.Now().After(flow.expires) {
+		return false, "登录已过期，请重新登录", "", false
+	}
+
+	id, err := p.Exchange(ctx, params, s.callbackURL(name))
+	if err != nil {
+		log.Printf("%s 登录失败: %v", name, err)
+		return false, "第三方登录失败", "", false
+	}
+
+	if flow.linkTo != "" {
+		if err := s.link(flow.linkTo, id); err != nil {
+			return false, err.Error(), "", false
+		}
+		return true, "已关联 " + name + " 账号", flow.linkTo, false
+	}
+
+	username := ""
+	if user := s.userRepo.FindByExternalAccount(id.Provider, id.Subject); user != nil {
+		username = user.Username
+	} else {
+		username, err = s.createExternalUser(id)
+		if err != nil {
+			log.Printf("为 %s 账号 %s 创建用户失败: %v", name, id.Subject, err)
+			return false, "创建账号失败，请稍后重试", "", false
+		}
+		log.Printf("%s 账号 %s 首次登录，已创建用户 %s", name, id.Subject, username)
+	}
+
+	if !markOnline(s.userRepo, username) {
+		return false, "用户已登录", "", false
+	}
+	return true, "登录成功", username, true
+}
+
+// link 将第三方账号关联到已有用户，一个第三方账号只能关联一个用户
+func (s *authService) link(username string, id auth.Identity) error {
+	if owner := s.userRepo.FindByExternalAccount(id.Provider, id.Subject); owner != nil {
+		if owner.Username == username {
+			return nil
+		}
+		return errors.New("该第三方账号已关联其他用户")
+	}
+	linked := s.userRepo.Modify(username, func(user *models.User) bool {
+		user.ExternalAccounts = append(user.ExternalAccounts,
+			models.ExternalAccount{Provider: id.Provider, Subject: id.Subject, LinkedAt: time.Now()})
+		return true
+	})
+	if !linked {
+		return errors.New("用户不存在")
+	}
+	return nil
+}
+
+// createExternalUser 为首次登录的第三方账号创建用户，用户名冲突时追加序号；
+// 第三方账号没有本地密码，只能通过第三方登录，之后可在资料中设置邮箱等信息
+func (s *authService) createExternalUser(id auth.Identity) (string, error) {
+	base := externalUsername(id)
+	email, err := validate.Email(id.Email)
+	if err != nil {
+		email = ""
+	}
+
+	for i := 1; i <= 100; i++ {
+		name := base
+		if i > 1 {
+			suffix := fmt.Sprintf("_%d", i)
+			name = truncateRunes(base, validate.UsernameMaxLength-len(suffix)) + suffix
+		}
+		user := models.User{
+			Username: name,
+			Email:    email,
+			ExternalAccounts: []models.ExternalAccount{
+				{Provider: id.Provider, Subject: id.Subject, LinkedAt: time.Now()},
+			},
+		}
+		switch err := s.userRepo.Create(user); {
+		case err == nil:
+			return name, nil
+		case errors.Is(err, repository.ErrEmailTaken):
+			// 邮箱已属于其他用户，不自动合并账号，新用户不带邮箱
+			email = ""
+			i--
+		case errors.Is(err, repository.ErrUsernameTaken):
+		default:
+			return "", err
+		}
+	}
+	return "", errors.New("无法生成可用的用户名")
+}
+
+// externalUsername 由第三方身份生成符合规则的用户名建议
+func externalUsername(id auth.Identity) string {
+	name := strings.Trim(truncateRunes(keepRunes(id.Username, "_"), validate.UsernameMaxLength-3), "_")
+	if validate.Username(name) == nil {
+		return name
+	}
+
+	subject := keepRunes(id.Subject, "")
+	if len(subject) > 8 {
+		subject = subject[len(subject)-8:]
+	}
+	name = id.Provider + "_" + subject
+	if validate.Username(name) == nil {
+		return name
+	}
+	return "player_" + subject
+}
+
+// keepRunes 只保留字母、数字以及 extra 中的字符
+func keepRunes(s, extra string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(extra, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// truncateRunes 截取前 n 个字符
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+func (s *authService) callbackURL(provider string) string {
+	return s.callbackBase + "/auth/" + provider + "/callback"
+}
+
+// newAuthState 生成随机 state，防止回调被跨站伪造
+func newAuthState() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
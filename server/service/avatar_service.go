@@ -0,0 +1,146 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"game/models"
+	"game/repository"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	avatarSize    = 128  // 处理后头像的边长（像素），较小的图片不放大
+	avatarMaxSide = 4096 // 上传图片允许的最大边长，解码前按图片头检查，避免超大图片占用内存
+)
+
+// AvatarService 定义用户头像业务逻辑接口
+type AvatarService interface {
+	// Upload 校验登录会话后把上传的图片裁剪缩放为正方形 PNG 头像并保存，返回是否成功和提示信息
+	Upload(username, sessionID string, content []byte) (bool, string)
+	// Avatar 按当前或曾用的用户名返回用户头像和上传时间，没有头像时返回 false
+	Avatar(username string) ([]byte, time.Time, bool)
+}
+
+// avatarService 实现 AvatarService 接口
+type avatarService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	avatarRepo  repository.AvatarRepository
+}
+
+// NewAvatarService 创建 AvatarService 实例
+func NewAvatarService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, avatarRepo repository.AvatarRepository) AvatarService {
+	return &avatarService{userRepo: userRepo, sessionRepo: sessionRepo, avatarRepo: avatarRepo}
+}
+
+// Upload 处理并保存头像，sessionID 必须是该用户当前有效的登录会话
+func (s *avatarService) Upload(username, sessionID string, content []byte) (bool, string) {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return false, "用户不存在"
+	}
+	if session := s.sessionRepo.Get(sessionID); session == nil || session.UserID != user.UserID {
+		return false, "会话已失效，请重新登录"
+	}
+	avatar, err := normalizeAvatar(content)
+	if err != nil {
+		return false, err.Error()
+	}
+	if err := s.avatarRepo.Put(user.UserID, avatar); err != nil {
+		log.Printf("保存用户 %s 的头像失败: %v", username, err)
+		return false, "保存头像失败，请稍后重试"
+	}
+	s.userRepo.Modify(username, func(user *models.User) bool {
+		user.AvatarUpdatedAt = time.Now()
+		return true
+	})
+	return true, "头像已更新"
+}
+
+// Avatar 查找用户的头像，用户改名后旧用户名仍然可用
+func (s *avatarService) Avatar(username string) ([]byte, time.Time, bool) {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		user = s.userRepo.FindByPreviousName(username)
+	}
+	if user == nil || user.AvatarUpdatedAt.IsZero() {
+		return nil, time.Time{}, false
+	}
+	content, err := s.avatarRepo.Get(user.UserID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrAvatarNotFound) {
+			log.Printf("读取用户 %s 的头像失败: %v", user.Username, err)
+		}
+		return nil, time.Time{}, false
+	}
+	return content, user.AvatarUpdatedAt, true
+}
+
+// AvatarURL 返回用户头像的访问地址，附带上传时间作为版本号，头像更新后客户端缓存自然失效；没有头像时返回空字符串
+func AvatarURL(user models.User) string {
+	if user.AvatarUpdatedAt.IsZero() {
+		return ""
+	}
+	return "/avatars/" + url.PathEscape(user.Username) + "?v=" + strconv.FormatInt(user.AvatarUpdatedAt.Unix(), 10)
+}
+
+// normalizeAvatar 校验上传的 PNG、JPEG 或 GIF 图片，居中裁剪为正方形并缩小到 avatarSize 后重新编码为 PNG；
+// 重新编码同时丢弃了原图中的元数据
+func normalizeAvatar(content []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.New("不支持的图片格式，请上传 PNG、JPEG 或 GIF 图片")
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, errors.New("图片尺寸无效")
+	}
+	if cfg.Width > avatarMaxSide || cfg.Height > avatarMaxSide {
+		return nil, fmt.Errorf("图片尺寸不能超过 %dx%d", avatarMaxSide, avatarMaxSide)
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.New("图片已损坏，无法解码")
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, cropResize(img, avatarSize)); err != nil {
+		return nil, fmt.Errorf("编码头像失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// cropResize 从图片中心裁剪出最大的正方形，按区域平均缩小到边长 size，正方形小于 size 时保持原尺寸
+func cropResize(src image.Image, size int) *image.NRGBA {
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	size = min(size, side)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for dy := 0; dy < size; dy++ {
+		sy0, sy1 := y0+dy*side/size, y0+(dy+1)*side/size
+		for dx := 0; dx < size; dx++ {
+			sx0, sx1 := x0+dx*side/size, x0+(dx+1)*side/size
+			var r, g, bl, a, n uint64
+			for y := sy0; y < sy1; y++ {
+				for x := sx0; x < sx1; x++ {
+					cr, cg, cb, ca := src.At(x, y).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			avg := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)}
+			dst.Set(dx, dy, avg)
+		}
+	}
+	return dst
+}
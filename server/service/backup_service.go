@@ -0,0 +1,32 @@
+package service
+
+import (
+	"game/models"
+	"game/repository"
+)
+
+// BackupService 定义数据备份业务逻辑接口
+type BackupService interface {
+	CreateBackup() (models.BackupInfo, error)
+	ListBackups() ([]models.BackupInfo, error)
+}
+
+// backupService 实现 BackupService 接口
+type backupService struct {
+	backupRepo repository.BackupRepository
+}
+
+// NewBackupService 创建 BackupService 实例
+func NewBackupService(backupRepo repository.BackupRepository) BackupService {
+	return &backupService{backupRepo: backupRepo}
+}
+
+// CreateBackup 立即创建一份备份
+func (s *backupService) CreateBackup() (models.BackupInfo, error) {
+	return s.backupRepo.Create()
+}
+
+// ListBackups 列出所有备份，最新的在前
+func (s *backupService) ListBackups() ([]models.BackupInfo, error) {
+	return s.backupRepo.List()
+}
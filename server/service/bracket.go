@@ -0,0 +1,155 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"game/models"
+)
+
+// seedEntrants 按评分从高到低确定种子，评分相同时先报名的玩家种子靠前；rating 为 nil 时按报名顺序
+func seedEntrants(entrants []models.TournamentEntrant, rating func(username string) int) []models.TournamentEntrant {
+	seeded := append([]models.TournamentEntrant(nil), entrants...)
+	for i := range seeded {
+		if rating != nil {
+			seeded[i].Rating = rating(seeded[i].Username)
+		}
+	}
+	sort.SliceStable(seeded, func(i, j int) bool {
+		if seeded[i].Rating != seeded[j].Rating {
+			return seeded[i].Rating > seeded[j].Rating
+		}
+		return seeded[i].RegisteredAt.Before(seeded[j].RegisteredAt)
+	})
+	for i := range seeded {
+		seeded[i].Seed = i + 1
+	}
+	return seeded
+}
+
+// bracketSize 返回容纳 n 名玩家的对阵表大小，即不小于 n 的 2 的幂，至少为 2
+func bracketSize(n int) int {
+	size := 2
+	for size < n {
+		size *= 2
+	}
+	return size
+}
+
+// seedOrder 返回对阵表第一轮各位置上的种子号，相邻两个位置为一场比赛。
+// 每一轮都是剩余的最高种子对最低种子，高种子在后面的轮次才会相遇；超出人数的种子号即轮空，总是落在最高的种子对面
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		sum := 2*len(order) + 1
+		next := make([]int, 0, 2*len(order))
+		for _, seed := range order {
+			next = append(next, seed, sum-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// generateBracket 按赛事的赛制为已按种子排列的玩家生成全部比赛
+func generateBracket(t models.Tournament) []models.BracketMatch {
+	switch t.Format {
+	case models.TournamentDoubleElimination:
+		return doubleEliminationBracket(t.Entrants)
+	case models.TournamentRoundRobin:
+		return roundRobinMatches(t.Entrants, t.GroupSize)
+	default:
+		return singleEliminationBracket(t.Entrants)
+	}
+}
+
+// recordWinner 比赛 i 决出胜者后按赛制推进对阵表，决出冠军时结束赛事
+func recordWinner(t *models.Tournament, i int) {
+	var champion string
+	switch t.Format {
+	case models.TournamentDoubleElimination:
+		t.Matches, champion = advanceDoubleElimination(t.Matches, i)
+	case models.TournamentRoundRobin:
+		champion = roundRobinChampion(*t)
+	default:
+		m := t.Matches[i]
+		if m.Round == t.Matches[len(t.Matches)-1].Round {
+			champion = m.Winner
+		} else {
+			advanceWinner(t.Matches, m)
+		}
+	}
+	if champion != "" {
+		t.Champion = champion
+		t.Status = models.TournamentFinished
+	}
+}
+
+// eliminationRounds 生成容纳 size 名玩家的淘汰赛各轮的空比赛，按轮次和本轮位置排列，ID 为 <prefix><轮次>-M<位置>
+func eliminationRounds(prefix string, size int) []models.BracketMatch {
+	var matches []models.BracketMatch
+	for round, count := 1, size/2; count >= 1; round, count = round+1, count/2 {
+		for slot := 0; slot < count; slot++ {
+			matches = append(matches, models.BracketMatch{
+				ID:    fmt.Sprintf("%s%d-M%d", prefix, round, slot+1),
+				Round: round,
+				Slot:  slot,
+			})
+		}
+	}
+	return matches
+}
+
+// seatFirstRound 按种子顺序把玩家放入第一轮的比赛，人数不是 2 的幂时以轮空补足，轮空的比赛直接判给另一方
+func seatFirstRound(matches []models.BracketMatch, entrants []models.TournamentEntrant, size int) {
+	seat := func(seed int) (string, int) {
+		if seed > len(entrants) {
+			return "", 0
+		}
+		return entrants[seed-1].Username, seed
+	}
+	order := seedOrder(size)
+	for slot := 0; slot < size/2; slot++ {
+		m := &matches[slot]
+		m.Player1, m.Seed1 = seat(order[2*slot])
+		m.Player2, m.Seed2 = seat(order[2*slot+1])
+		if m.Player2 == "" {
+			m.Bye = true
+			m.Winner = m.Player1
+		}
+	}
+}
+
+// singleEliminationBracket 为已按种子排列的玩家生成单败淘汰对阵表，按轮次和本轮位置排列。
+// 轮空方对面的玩家直接进入第二轮
+func singleEliminationBracket(entrants []models.TournamentEntrant) []models.BracketMatch {
+	size := bracketSize(len(entrants))
+	matches := eliminationRounds("R", size)
+	seatFirstRound(matches, entrants, size)
+	for _, m := range matches[:size/2] {
+		if m.Bye {
+			advanceWinner(matches, m)
+		}
+	}
+	return matches
+}
+
+// advanceWinner 将已决出胜者的比赛的胜者放入下一轮对应的位置，决赛没有下一轮
+func advanceWinner(matches []models.BracketMatch, m models.BracketMatch) {
+	seed := m.Seed1
+	if m.Winner == m.Player2 {
+		seed = m.Seed2
+	}
+	for i := range matches {
+		next := &matches[i]
+		if next.Round != m.Round+1 || next.Slot != m.Slot/2 {
+			continue
+		}
+		if m.Slot%2 == 0 {
+			next.Player1, next.Seed1 = m.Winner, seed
+		} else {
+			next.Player2, next.Seed2 = m.Winner, seed
+		}
+		return
+	}
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"game/models"
+	"game/protocol"
+	"game/repository"
+)
+
+var (
+	// ErrDisputeDisabled 服务器未开启结果申诉
+	ErrDisputeDisabled = errors.New("服务器未开启结果申诉")
+	// ErrDisputeNotFound 申诉不存在
+	ErrDisputeNotFound = errors.New("申诉不存在")
+	// ErrDisputeResult 对局结果不存在或已超过申诉期限
+	ErrDisputeResult = errors.New("对局结果不存在或已超过申诉期限")
+	// ErrDisputeUserNotFound 申诉的用户不存在
+	ErrDisputeUserNotFound = errors.New("用户不存在")
+	// ErrDisputeSession 登录会话无效
+	ErrDisputeSession = errors.New("会话已失效，请重新登录")
+	// ErrDisputePlayer 申诉的用户没有参与这局对局
+	ErrDisputePlayer = errors.New("只有参与对局的玩家可以申诉")
+	// ErrDisputeReason 申诉理由为空或过长
+	ErrDisputeReason = errors.New("申诉理由无效")
+	// ErrDisputeDuplicate 已对这局结果提出过申诉
+	ErrDisputeDuplicate = errors.New("已对该对局提出过申诉")
+	// ErrDisputeReviewed 申诉已经审核过
+	ErrDisputeReviewed = errors.New("申诉已审核")
+	// ErrDisputeVerdict 审核结果不是 upheld 或 rejected
+	ErrDisputeVerdict = errors.New("审核结果必须是 upheld 或 rejected")
+)
+
+// maxDisputeReason 申诉理由的最大字符数
+const maxDisputeReason = 500
+
+// DisputeEvidence 由连接层实现，提供申诉附带的对局回放和评分规则
+type DisputeEvidence interface {
+	// Replay 返回对局期间房间的入站流量录制，未开启录制时为空
+	Replay(result models.GameResult) []models.TrafficRecord
+	// RatingDeltas 按当前的计分规则返回一局结果为各玩家带来的评分变化，按用户ID索引
+	RatingDeltas(result models.GameResult) map[string]int
+}
+
+// DisputeService 定义结果申诉业务逻辑接口
+type DisputeService interface {
+	// SetEvidence 设置回放和评分规则来源，Hub 创建后注入
+	SetEvidence(evidence DisputeEvidence)
+	// Create 玩家在申诉期限内对自己参与的对局结果提出申诉，附带回放和对局玩家的违规记录快照
+	Create(resultID string, req protocol.DisputeRequest, now time.Time) (models.Dispute, error)
+	// Queue 返回指定状态的申诉，status 为空时返回待审核的申诉，最早提出的在前
+	Queue(status string) []models.Dispute
+	// Review 审核申诉，verdict 为 upheld 时写入评分补偿，抵消该局为各玩家带来的评分变化
+	Review(id, verdict, note string) (models.Dispute, error)
+	// Adjustment 返回用户的评分补偿合计
+	Adjustment(userID string) int
+	// ByUser 返回用户提出的申诉，最早提出的在前
+	ByUser(userID string) []models.Dispute
+	// RemoveUser 删除用户提出的申诉和评分补偿，其他申诉中该用户的名字（包括 names 中的曾用名）替换为 alias，注销账号时调用
+	RemoveUser(userID string, names []string, alias string) int
+}
+
+// disputeService 实现 DisputeService 接口
+type disputeService struct {
+	disputeRepo    repository.DisputeRepository
+	adjustmentRepo repository.AdjustmentRepository
+	resultRepo     repository.ResultRepository
+	userRepo       repository.UserRepository
+	sessionRepo    repository.SessionRepository
+	flagRepo       repository.CheatFlagRepository
+	evidence       DisputeEvidence
+	window         time.Duration
+}
+
+// NewDisputeService 创建 DisputeService 实例，window 为对局结束后可以申诉的期限，不大于 0 时不接受申诉
+func NewDisputeService(disputeRepo repository.DisputeRepository, adjustmentRepo repository.AdjustmentRepository, resultRepo repository.ResultRepository,
+	userRepo repository.UserRepository, sessionRepo repository.SessionRepository, flagRepo repository.CheatFlagRepository, window time.Duration) DisputeService {
+	return &disputeService{
+		disputeRepo:    disputeRepo,
+		adjustmentRepo: adjustmentRepo,
+		resultRepo:     resultRepo,
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		flagRepo:       flagRepo,
+		window:         window,
+	}
+}
+
+// SetEvidence 设置回放和评分规则来源
+func (s *disputeService) SetEvidence(evidence DisputeEvidence) {
+	s.evidence = evidence
+}
+
+// Create 提出申诉，每名玩家对每局结果只能申诉一次
+func (s *disputeService) Create(resultID string, req protocol.DisputeRequest, now time.Time) (models.Dispute, error) {
+	if s.window <= 0 {
+		return models.Dispute{}, ErrDisputeDisabled
+	}
+	user := s.userRepo.FindByUsername(req.Username)
+	if user == nil {
+		return models.Dispute{}, ErrDisputeUserNotFound
+	}
+	if session := s.sessionRepo.Get(req.SessionID); session == nil || session.UserID != user.UserID {
+		return models.Dispute{}, ErrDisputeSession
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || utf8.RuneCountInString(reason) > maxDisputeReason {
+		return models.Dispute{}, fmt.Errorf("%w: 理由不能为空且不超过 %d 个字符", ErrDisputeReason, maxDisputeReason)
+	}
+
+	var result *models.GameResult
+	for _, r := range s.resultRepo.FindSince(now.Add(-s.window)) {
+		if r.ID == resultID {
+			result = &r
+			break
+		}
+	}
+	if result == nil {
+		return models.Dispute{}, ErrDisputeResult
+	}
+	if result.NameOf(user.UserID) == "" {
+		return models.Dispute{}, ErrDisputePlayer
+	}
+
+	dispute := models.Dispute{
+		ID:        fmt.Sprintf("dispute_%d", now.UnixNano()),
+		ResultID:  result.ID,
+		UserID:    user.UserID,
+		Username:  user.Username,
+		Reason:    reason,
+		Status:    models.DisputePending,
+		CreatedAt: now,
+		Result:    result.Clone(),
+	}
+	if s.evidence != nil {
+		dispute.Replay = s.evidence.Replay(*result)
+	}
+	for _, p := range result.Players {
+		if p.UserID == "" {
+			continue
+		}
+		if flag, ok := s.flagRepo.Get(p.UserID); ok {
+			dispute.Flags = append(dispute.Flags, flag)
+		}
+	}
+	if !s.disputeRepo.Add(dispute) {
+		return models.Dispute{}, ErrDisputeDuplicate
+	}
+	return dispute, nil
+}
+
+// Queue 返回指定状态的申诉
+func (s *disputeService) Queue(status string) []models.Dispute {
+	if status == "" {
+		status = models.DisputePending
+	}
+	queue := make([]models.Dispute, 0)
+	for _, d := range s.disputeRepo.All() {
+		if d.Status == status {
+			queue = append(queue, d)
+		}
+	}
+	return queue
+}
+
+// ByUser 返回用户提出的申诉
+func (s *disputeService) ByUser(userID string) []models.Dispute {
+	disputes := make([]models.Dispute, 0)
+	for _, d := range s.disputeRepo.All() {
+		if d.UserID == userID {
+			disputes = append(disputes, d)
+		}
+	}
+	return disputes
+}
+
+// Review 审核待审核的申诉。申诉成立时按审核时的计分规则写入补偿；同一局结果已被其他申诉补偿过时不重复补偿
+func (s *disputeService) Review(id, verdict, note string) (models.Dispute, error) {
+	if verdict != models.DisputeUpheld && verdict != models.DisputeRejected {
+		return models.Dispute{}, ErrDisputeVerdict
+	}
+	var err error
+	dispute, found := s.disputeRepo.Modify(id, func(d *models.Dispute) bool {
+		if d.Status != models.DisputePending {
+			err = ErrDisputeReviewed
+			return false
+		}
+		now := time.Now()
+		d.Status = verdict
+		d.Note = note
+		d.ReviewedAt = now
+		if verdict == models.DisputeUpheld {
+			adjustments := s.compensate(*d, now)
+			if s.adjustmentRepo.Add(d.ResultID, adjustments) {
+				d.Adjustments = adjustments
+			}
+		}
+		return true
+	})
+	if !found {
+		return models.Dispute{}, ErrDisputeNotFound
+	}
+	if err != nil {
+		return models.Dispute{}, err
+	}
+	return dispute, nil
+}
+
+// compensate 生成抵消申诉结果评分变化的补偿记录，按结果中的玩家顺序排列
+func (s *disputeService) compensate(d models.Dispute, now time.Time) []models.RatingAdjustment {
+	if s.evidence == nil {
+		return nil
+	}
+	deltas := s.evidence.RatingDeltas(d.Result)
+	var adjustments []models.RatingAdjustment
+	for _, p := range d.Result.Players {
+		delta := deltas[p.UserID]
+		if p.UserID == "" || delta == 0 {
+			continue
+		}
+		adjustments = append(adjustments, models.RatingAdjustment{
+			UserID:    p.UserID,
+			Username:  p.Username,
+			Delta:     -delta,
+			ResultID:  d.ResultID,
+			DisputeID: d.ID,
+			CreatedAt: now,
+		})
+	}
+	return adjustments
+}
+
+// Adjustment 返回用户的评分补偿合计
+func (s *disputeService) Adjustment(userID string) int {
+	if userID == "" {
+		return 0
+	}
+	return s.adjustmentRepo.Total(userID)
+}
+
+// RemoveUser 删除用户的评分补偿和提出的申诉，并匿名化其他申诉中的该用户，返回删除或修改的申诉数
+func (s *disputeService) RemoveUser(userID string, names []string, alias string) int {
+	s.adjustmentRepo.RemoveUser(userID)
+	return s.disputeRepo.RemoveUser(userID, names, alias)
+}
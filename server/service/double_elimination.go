@@ -0,0 +1,149 @@
+package service
+
+import (
+	"fmt"
+	"math/bits"
+
+	"game/models"
+)
+
+// feed 比赛一方的来源：另一场比赛的胜者或败者
+type feed struct {
+	match int // 来源比赛在对阵表中的下标
+	loser bool
+}
+
+// doubleEliminationBracket 为已按种子排列的玩家生成双败淘汰对阵表。
+// 胜者组与单败淘汰相同；胜者组每轮的败者落入败者组，败者组再输一场即被淘汰；
+// 胜者组冠军与败者组冠军在总决赛相遇，败者组冠军赢下首场时双方各负一场，加赛一场定冠军
+func doubleEliminationBracket(entrants []models.TournamentEntrant) []models.BracketMatch {
+	size := bracketSize(len(entrants))
+	matches := eliminationRounds("W", size)
+	seatFirstRound(matches, entrants, size)
+
+	// 败者组共 2*(胜者组轮数-1) 轮：奇数轮是败者组内部对决，偶数轮迎战胜者组下一轮的败者
+	rounds := bits.TrailingZeros(uint(size))
+	for round := 1; round <= 2*(rounds-1); round++ {
+		count := size >> ((round+1)/2 + 1)
+		for slot := 0; slot < count; slot++ {
+			matches = append(matches, models.BracketMatch{
+				ID:      fmt.Sprintf("L%d-M%d", round, slot+1),
+				Bracket: models.BracketLosers,
+				Round:   round,
+				Slot:    slot,
+			})
+		}
+	}
+	matches = append(matches, models.BracketMatch{ID: "F1-M1", Bracket: models.BracketFinal, Round: 1})
+	settleDoubleElimination(matches)
+	return matches
+}
+
+// advanceDoubleElimination 比赛 i 决出胜者后把双方送入后续比赛，返回更新后的对阵表和冠军，冠军未决出时为空
+func advanceDoubleElimination(matches []models.BracketMatch, i int) ([]models.BracketMatch, string) {
+	m := matches[i]
+	if m.Bracket != models.BracketFinal {
+		settleDoubleElimination(matches)
+		return matches, ""
+	}
+	if m.Round == 2 || m.Winner == m.Player1 {
+		return matches, m.Winner
+	}
+	// 胜者组冠军在总决赛首场落败，双方各负一场，加赛一场
+	return append(matches, models.BracketMatch{
+		ID:      "F2-M1",
+		Bracket: models.BracketFinal,
+		Round:   2,
+		Player1: m.Player1,
+		Player2: m.Player2,
+		Seed1:   m.Seed1,
+		Seed2:   m.Seed2,
+	}), ""
+}
+
+// settleDoubleElimination 根据已结束的比赛填入后续比赛的双方。来源已结束却没有玩家的一方视为轮空，
+// 只有一方的比赛直接判给该方，双方都轮空的比赛标记为轮空且没有胜者，重复直到没有变化
+func settleDoubleElimination(matches []models.BracketMatch) {
+	for changed := true; changed; {
+		changed = false
+		for i := range matches {
+			m := &matches[i]
+			if m.Winner != "" || m.Bye {
+				continue
+			}
+			feeds, ok := doubleEliminationFeeds(matches, *m)
+			if !ok {
+				continue
+			}
+			p1, s1, done1 := feedPlayer(matches, feeds[0])
+			p2, s2, done2 := feedPlayer(matches, feeds[1])
+			if m.Player1 != p1 || m.Player2 != p2 {
+				m.Player1, m.Seed1 = p1, s1
+				m.Player2, m.Seed2 = p2, s2
+				changed = true
+			}
+			if done1 && done2 && (p1 == "" || p2 == "") {
+				m.Bye = true
+				m.Winner = p1 + p2
+				changed = true
+			}
+		}
+	}
+}
+
+// feedPlayer 返回来源比赛送出的玩家及其种子；来源比赛尚未结束时 done 为 false，结束但没有玩家送出时玩家为空
+func feedPlayer(matches []models.BracketMatch, f feed) (player string, seed int, done bool) {
+	src := matches[f.match]
+	if src.Winner == "" && !src.Bye {
+		return "", 0, false
+	}
+	first := src.Winner == src.Player1
+	if f.loser {
+		if src.Bye {
+			return "", 0, true
+		}
+		first = !first
+	}
+	if first {
+		return src.Player1, src.Seed1, true
+	}
+	return src.Player2, src.Seed2, true
+}
+
+// doubleEliminationFeeds 返回比赛双方的来源，胜者组第一轮和加赛的双方已确定，没有来源
+func doubleEliminationFeeds(matches []models.BracketMatch, m models.BracketMatch) ([2]feed, bool) {
+	find := func(bracket string, round, slot int) int {
+		for i, c := range matches {
+			if c.Bracket == bracket && c.Round == round && c.Slot == slot {
+				return i
+			}
+		}
+		return -1
+	}
+	rounds := 0
+	for _, c := range matches {
+		if c.Bracket == "" {
+			rounds = max(rounds, c.Round)
+		}
+	}
+
+	switch {
+	case m.Bracket == "" && m.Round > 1:
+		return [2]feed{{match: find("", m.Round-1, 2*m.Slot)}, {match: find("", m.Round-1, 2*m.Slot+1)}}, true
+	case m.Bracket == models.BracketLosers && m.Round == 1:
+		return [2]feed{{match: find("", 1, 2*m.Slot), loser: true}, {match: find("", 1, 2*m.Slot+1), loser: true}}, true
+	case m.Bracket == models.BracketLosers && m.Round%2 == 0:
+		// 胜者组的败者按相反的位置落入败者组，避免刚交过手的玩家很快再次相遇
+		wround := m.Round/2 + 1
+		count := 1 << (rounds - wround)
+		return [2]feed{{match: find(models.BracketLosers, m.Round-1, m.Slot)}, {match: find("", wround, count-1-m.Slot), loser: true}}, true
+	case m.Bracket == models.BracketLosers:
+		return [2]feed{{match: find(models.BracketLosers, m.Round-1, 2*m.Slot)}, {match: find(models.BracketLosers, m.Round-1, 2*m.Slot+1)}}, true
+	case m.Bracket == models.BracketFinal && m.Round == 1:
+		if rounds == 1 {
+			return [2]feed{{match: find("", 1, 0)}, {match: find("", 1, 0), loser: true}}, true
+		}
+		return [2]feed{{match: find("", rounds, 0)}, {match: find(models.BracketLosers, 2*(rounds-1), 0)}}, true
+	}
+	return [2]feed{}, false
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"game/models"
+	"game/protocol"
+	"game/repository"
+)
+
+var (
+	// ErrEventNotFound 活动不存在
+	ErrEventNotFound = errors.New("活动不存在")
+	// ErrEventName 活动名称为空
+	ErrEventName = errors.New("活动名称不能为空")
+	// ErrEventTime 活动的结束时间不晚于开始时间
+	ErrEventTime = errors.New("活动的结束时间必须晚于开始时间")
+	// ErrEventMultiplier 评分倍率无效
+	ErrEventMultiplier = errors.New("评分倍率必须为 0 或 1~5 之间")
+	// ErrEventRotate 地图目标轮换间隔为负数
+	ErrEventRotate = errors.New("轮换间隔不能为负数")
+	// ErrEventMode 活动轮换的地图目标未知
+	ErrEventMode = errors.New("未知的地图目标")
+)
+
+// maxWinMultiplier 活动评分倍率上限
+const maxWinMultiplier = 5
+
+// defaultRotateMinutes 未设置轮换间隔时地图目标每小时轮换一次
+const defaultRotateMinutes = 60
+
+// EventService 定义限时活动业务逻辑接口
+type EventService interface {
+	// Create 安排新的限时活动
+	Create(req protocol.CreateEventRequest) (models.Event, error)
+	// Remove 删除活动，进行中的活动立即失效
+	Remove(id string) error
+	// List 返回尚未结束的活动，all 为 true 时包含已结束的活动
+	List(now time.Time, all bool) []models.Event
+	// Active 返回 now 时正在进行的活动
+	Active(now time.Time) []models.Event
+	// WinMultiplier 返回 now 时生效的胜场评分倍率，多个活动同时进行时取最大值，没有加成时为 1
+	WinMultiplier(now time.Time) float64
+	// Mode 返回 now 时匹配对局应使用的地图目标，没有轮换活动时为空；多个活动同时轮换时以最早开始的为准
+	Mode(now time.Time) string
+}
+
+// eventService 实现 EventService 接口
+type eventService struct {
+	eventRepo repository.EventRepository
+}
+
+// NewEventService 创建 EventService 实例
+func NewEventService(eventRepo repository.EventRepository) EventService {
+	return &eventService{eventRepo: eventRepo}
+}
+
+// Create 校验并保存活动
+func (s *eventService) Create(req protocol.CreateEventRequest) (models.Event, error) {
+	name := strings.TrimSpace(req.Name)
+	switch {
+	case name == "":
+		return models.Event{}, ErrEventName
+	case req.StartsAt.IsZero() || !req.EndsAt.After(req.StartsAt):
+		return models.Event{}, ErrEventTime
+	case req.WinMultiplier != 0 && (req.WinMultiplier < 1 || req.WinMultiplier > maxWinMultiplier):
+		return models.Event{}, ErrEventMultiplier
+	case req.RotateMinutes < 0:
+		return models.Event{}, ErrEventRotate
+	}
+	for _, mode := range req.Modes {
+		if mode != models.ObjectiveNone && mode != models.ObjectiveKingOfTheHill && mode != models.ObjectiveShrinkingZone {
+			return models.Event{}, fmt.Errorf("%w %s", ErrEventMode, mode)
+		}
+	}
+
+	event := models.Event{
+		ID:            fmt.Sprintf("event_%d", time.Now().UnixNano()),
+		Name:          name,
+		Description:   strings.TrimSpace(req.Description),
+		StartsAt:      req.StartsAt,
+		EndsAt:        req.EndsAt,
+		WinMultiplier: req.WinMultiplier,
+		Modes:         append([]string(nil), req.Modes...),
+		RotateMinutes: req.RotateMinutes,
+		CreatedAt:     time.Now(),
+	}
+	s.eventRepo.Create(event)
+	return event, nil
+}
+
+// Remove 删除活动
+func (s *eventService) Remove(id string) error {
+	if !s.eventRepo.Remove(id) {
+		return ErrEventNotFound
+	}
+	return nil
+}
+
+// List 返回活动列表
+func (s *eventService) List(now time.Time, all bool) []models.Event {
+	events := s.eventRepo.All()
+	if all {
+		return events
+	}
+	upcoming := make([]models.Event, 0, len(events))
+	for _, e := range events {
+		if now.Before(e.EndsAt) {
+			upcoming = append(upcoming, e)
+		}
+	}
+	return upcoming
+}
+
+// Active 返回正在进行的活动
+func (s *eventService) Active(now time.Time) []models.Event {
+	var active []models.Event
+	for _, e := range s.eventRepo.All() {
+		if e.Active(now) {
+			active = append(active, e)
+		}
+	}
+	return active
+}
+
+// WinMultiplier 返回生效的评分倍率
+func (s *eventService) WinMultiplier(now time.Time) float64 {
+	mult := 1.0
+	for _, e := range s.Active(now) {
+		mult = max(mult, e.WinMultiplier)
+	}
+	return mult
+}
+
+// Mode 返回当前轮换到的地图目标，活动按开始时间排列
+func (s *eventService) Mode(now time.Time) string {
+	for _, e := range s.Active(now) {
+		if mode := eventMode(e, now); mode != "" {
+			return mode
+		}
+	}
+	return ""
+}
+
+// eventMode 返回活动在 now 时轮换到的地图目标：从开始时间起每隔轮换间隔换到下一个
+func eventMode(e models.Event, now time.Time) string {
+	if len(e.Modes) == 0 || !e.Active(now) {
+		return ""
+	}
+	rotate := time.Duration(e.RotateMinutes) * time.Minute
+	if rotate <= 0 {
+		rotate = defaultRotateMinutes * time.Minute
+	}
+	return e.Modes[int(now.Sub(e.StartsAt)/rotate)%len(e.Modes)]
+}
+
+// EventInfo 转换为返回给客户端的活动信息
+func EventInfo(e models.Event, now time.Time) protocol.EventInfo {
+	return protocol.EventInfo{
+		ID:            e.ID,
+		Name:          e.Name,
+		Description:   e.Description,
+		StartsAt:      e.StartsAt,
+		EndsAt:        e.EndsAt,
+		WinMultiplier: e.WinMultiplier,
+		Modes:         e.Modes,
+		RotateMinutes: e.RotateMinutes,
+		Active:        e.Active(now),
+		Mode:          eventMode(e, now),
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"game/models"
+	"game/repository"
+)
+
+var (
+	// ErrLadderSelf 不能挑战自己
+	ErrLadderSelf = errors.New("不能挑战自己")
+	// ErrLadderNotRanked 被挑战方不在天梯上
+	ErrLadderNotRanked = errors.New("对方不在天梯上")
+	// ErrLadderBelow 只能挑战排名更高的玩家
+	ErrLadderBelow = errors.New("只能挑战排名比自己高的玩家")
+	// ErrLadderRange 被挑战方的排名高出太多
+	ErrLadderRange = errors.New("对方的排名超出了可挑战的范围")
+)
+
+// LadderService 定义天梯业务逻辑接口
+type LadderService interface {
+	// Ladder 返回按排名排列的天梯，用户名为玩家当前的用户名
+	Ladder() []models.LadderEntry
+	// Join 把玩家加入天梯末尾，已在天梯上时不变，返回其排名
+	Join(user models.User) int
+	// Challengeable 校验挑战方能否挑战对方：对方必须在天梯上且排名更高，maxRange 大于 0 时最多高出 maxRange 位。
+	// 挑战方不在天梯上时先加入天梯末尾
+	Challengeable(from, to models.User, maxRange int) error
+	// Settle 结算挑战对局：挑战方获胜且排名仍低于被挑战方时交换双方排名，返回是否交换
+	Settle(challengerID, challengedID, winnerID string) bool
+	// Position 返回用户的排名，不在天梯上时返回 0
+	Position(userID string) int
+	// RemoveUser 把用户移出天梯，排在其后的玩家依次前移，注销账号时调用
+	RemoveUser(userID string) bool
+}
+
+// ladderService 实现 LadderService 接口
+type ladderService struct {
+	ladderRepo repository.LadderRepository
+	userRepo   repository.UserRepository
+}
+
+// NewLadderService 创建 LadderService 实例
+func NewLadderService(ladderRepo repository.LadderRepository, userRepo repository.UserRepository) LadderService {
+	return &ladderService{ladderRepo: ladderRepo, userRepo: userRepo}
+}
+
+// Ladder 返回天梯排名，已删除的账号保留加入时的用户名
+func (s *ladderService) Ladder() []models.LadderEntry {
+	players := s.ladderRepo.All()
+	for i, p := range players {
+		if user := s.userRepo.FindByID(p.UserID); user != nil {
+			players[i].Username = user.Username
+		}
+	}
+	return players
+}
+
+// Join 把玩家加入天梯末尾
+func (s *ladderService) Join(user models.User) int {
+	return s.ladderRepo.Join(models.LadderEntry{UserID: user.UserID, Username: user.Username, JoinedAt: time.Now()})
+}
+
+// Challengeable 校验挑战方能否挑战对方
+func (s *ladderService) Challengeable(from, to models.User, maxRange int) error {
+	if from.UserID == to.UserID {
+		return ErrLadderSelf
+	}
+	fromPos := s.Join(from)
+	toPos := s.ladderRepo.Position(to.UserID)
+	switch {
+	case toPos == 0:
+		return ErrLadderNotRanked
+	case toPos > fromPos:
+		return ErrLadderBelow
+	case maxRange > 0 && fromPos-toPos > maxRange:
+		return ErrLadderRange
+	}
+	return nil
+}
+
+// Settle 结算挑战对局，被挑战方获胜时排名不变
+func (s *ladderService) Settle(challengerID, challengedID, winnerID string) bool {
+	if winnerID != challengerID {
+		return false
+	}
+	return s.ladderRepo.Swap(challengerID, challengedID)
+}
+
+// Position 返回用户的排名
+func (s *ladderService) Position(userID string) int {
+	return s.ladderRepo.Position(userID)
+}
+
+// RemoveUser 把用户移出天梯
+func (s *ladderService) RemoveUser(userID string) bool {
+	return s.ladderRepo.Remove(userID)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRoomLimitReached 服务器房间总数达到上限
+	ErrRoomLimitReached = errors.New("服务器房间数量已达上限")
+	// ErrRoomCreateTooFrequent 用户在一小时内创建房间过多
+	ErrRoomCreateTooFrequent = errors.New("创建房间过于频繁，请稍后再试")
+)
+
+// RoomLimiter 限制房间总数以及每个用户每小时可创建的房间数，0 表示不限制
+type RoomLimiter struct {
+	mu         sync.Mutex
+	maxRooms   int
+	maxPerHour int
+	created    map[string][]time.Time
+}
+
+// NewRoomLimiter 创建 RoomLimiter 实例
+func NewRoomLimiter(maxRooms, maxPerHour int) *RoomLimiter {
+	return &RoomLimiter{
+		maxRooms:   maxRooms,
+		maxPerHour: maxPerHour,
+		created:    make(map[string][]time.Time),
+	}
+}
+
+// Allow 检查用户能否再创建一个房间，允许时记录本次创建
+func (l *RoomLimiter) Allow(username string, activeRooms int) error {
+	if l.maxRooms > 0 && activeRooms >= l.maxRooms {
+		return ErrRoomLimitReached
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	recent := l.created[username][:0]
+	for _, t := range l.created[username] {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	if l.maxPerHour > 0 && len(recent) >= l.maxPerHour {
+		l.created[username] = recent
+		return ErrRoomCreateTooFrequent
+	}
+	l.created[username] = append(recent, now)
+	return nil
+}
+
+// ConnLimiter 限制并发连接数，检查和占用在同一把锁内完成，max 不大于 0 时不限制
+type ConnLimiter struct {
+	mu     sync.Mutex
+	max    int
+	active int
+}
+
+// NewConnLimiter 创建 ConnLimiter 实例
+func NewConnLimiter(max int) *ConnLimiter {
+	return &ConnLimiter{max: max}
+}
+
+// Acquire 占用一个连接名额，已满时返回 false
+func (l *ConnLimiter) Acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.max > 0 && l.active >= l.max {
+		return false
+	}
+	l.active++
+	return true
+}
+
+// Release 归还 Acquire 占用的名额
+func (l *ConnLimiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active > 0 {
+		l.active--
+	}
+}
+
+// ErrSearchTooFrequent 短时间内搜索次数过多
+var ErrSearchTooFrequent = errors.New("搜索过于频繁，请稍后再试")
+
+// RateLimiter 按键（如客户端 IP）限制一个时间窗口内的请求次数，limit 不大于 0 时不限制
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	hits      map[string][]time.Time
+	lastPrune time.Time
+}
+
+// NewRateLimiter 创建 RateLimiter 实例
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Allow 检查 key 能否再发起一次请求，允许时记录本次请求
+func (l *RateLimiter) Allow(key string) bool {
+	if l.limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	// 定期清理窗口内没有请求的键，避免大量一次性的键撑大统计表
+	if now.Sub(l.lastPrune) >= l.window {
+		for k, hits := range l.hits {
+			if len(hits) == 0 || now.Sub(hits[len(hits)-1]) >= l.window {
+				delete(l.hits, k)
+			}
+		}
+		l.lastPrune = now
+	}
+	recent := l.hits[key][:0]
+	for _, t := range l.hits[key] {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= l.limit {
+		l.hits[key] = recent
+		return false
+	}
+	l.hits[key] = append(recent, now)
+	return true
+}
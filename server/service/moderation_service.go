@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"game/models"
+	"game/repository"
+)
+
+var (
+	// ErrFlagNotFound 用户没有可审核的作弊标记
+	ErrFlagNotFound = errors.New("该用户没有作弊标记")
+	// ErrInvalidVerdict 审核结果不是 cleared 或 confirmed
+	ErrInvalidVerdict = errors.New("审核结果必须是 cleared 或 confirmed")
+)
+
+// violationWeights 各类违规计入违规分的权重，位置修正可能由网络抖动引起，权重最低
+var violationWeights = map[string]int{
+	models.ViolationMoveCorrection: 1,
+	models.ViolationSeedMismatch:   2,
+	models.ViolationInvalidHit:     3,
+	models.ViolationVoteKicked:     2,
+}
+
+// ModerationService 定义作弊标记业务逻辑接口，审核队列同时列出待审核的对局结果申诉
+type ModerationService interface {
+	// RecordViolations 累计一局对局中的违规，违规分达到阈值时标记为待审核，返回记录以及是否为本次新标记
+	RecordViolations(userID, username string, violations map[string]int) (models.CheatFlag, bool)
+	// Flagged 用户是否处于待审核或已确认作弊状态
+	Flagged(userID string) bool
+	// Queue 返回指定状态的作弊标记，status 为空时返回待审核的标记，按标记时间排列
+	Queue(status string) []models.CheatFlag
+	// PendingDisputes 返回待审核的对局结果申诉，最早提出的在前；申诉的审核结果不同于作弊标记，由 DisputeService 审核
+	PendingDisputes() []models.Dispute
+	// Review 审核作弊标记，verdict 为 cleared 时清零违规分
+	Review(userID, verdict, note string) (models.CheatFlag, error)
+	// RemoveUser 删除用户的作弊标记，注销账号时调用
+	RemoveUser(userID string) bool
+}
+
+// moderationService 实现 ModerationService 接口
+type moderationService struct {
+	flagRepo    repository.CheatFlagRepository
+	disputeRepo repository.DisputeRepository
+	threshold   int
+}
+
+// NewModerationService 创建 ModerationService 实例，threshold 为标记所需的违规分，不大于 0 时只累计不标记
+func NewModerationService(flagRepo repository.CheatFlagRepository, disputeRepo repository.DisputeRepository, threshold int) ModerationService {
+	return &moderationService{flagRepo: flagRepo, disputeRepo: disputeRepo, threshold: threshold}
+}
+
+// RecordViolations 累计违规，已确认作弊或待审核的用户只累计次数
+func (s *moderationService) RecordViolations(userID, username string, violations map[string]int) (models.CheatFlag, bool) {
+	flagged := false
+	flag := s.flagRepo.Modify(userID, func(flag *models.CheatFlag) bool {
+		if len(violations) == 0 {
+			return false
+		}
+		if flag.Violations == nil {
+			flag.Violations = make(map[string]int)
+		}
+		for kind, n := range violations {
+			flag.Violations[kind] += n
+			flag.Score += n * violationWeights[kind]
+		}
+		now := time.Now()
+		flag.Username = username
+		flag.UpdatedAt = now
+		if s.threshold > 0 && !flag.Flagged() && flag.Score >= s.threshold {
+			flag.Status = models.CheatFlagPending
+			flag.FlaggedAt = now
+			flagged = true
+		}
+		return true
+	})
+	return flag, flagged
+}
+
+// Flagged 用户是否处于待审核或已确认作弊状态
+func (s *moderationService) Flagged(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	flag, ok := s.flagRepo.Get(userID)
+	return ok && flag.Flagged()
+}
+
+// Queue 返回指定状态的作弊标记，最早标记的在前
+func (s *moderationService) Queue(status string) []models.CheatFlag {
+	if status == "" {
+		status = models.CheatFlagPending
+	}
+	queue := make([]models.CheatFlag, 0)
+	for _, flag := range s.flagRepo.All() {
+		if flag.Status == status {
+			queue = append(queue, flag)
+		}
+	}
+	sortFlags(queue)
+	return queue
+}
+
+// PendingDisputes 返回待审核的对局结果申诉
+func (s *moderationService) PendingDisputes() []models.Dispute {
+	queue := make([]models.Dispute, 0)
+	for _, d := range s.disputeRepo.All() {
+		if d.Status == models.DisputePending {
+			queue = append(queue, d)
+		}
+	}
+	return queue
+}
+
+// Review 审核作弊标记，只有已被标记（待审核、已确认或已排除）的用户可以审核
+func (s *moderationService) Review(userID, verdict, note string) (models.CheatFlag, error) {
+	if verdict != models.CheatFlagCleared && verdict != models.CheatFlagConfirmed {
+		return models.CheatFlag{}, ErrInvalidVerdict
+	}
+	found := false
+	flag := s.flagRepo.Modify(userID, func(flag *models.CheatFlag) bool {
+		if flag.Status == "" {
+			return false
+		}
+		found = true
+		flag.Status = verdict
+		flag.Note = note
+		flag.ReviewedAt = time.Now()
+		if verdict == models.CheatFlagCleared {
+			flag.Score = 0
+			flag.Violations = nil
+		}
+		return true
+	})
+	if !found {
+		return models.CheatFlag{}, ErrFlagNotFound
+	}
+	return flag, nil
+}
+
+// sortFlags 按标记时间排列，同时标记的按用户ID排列
+func sortFlags(flags []models.CheatFlag) {
+	sort.Slice(flags, func(i, j int) bool {
+		if !flags[i].FlaggedAt.Equal(flags[j].FlaggedAt) {
+			return flags[i].FlaggedAt.Before(flags[j].FlaggedAt)
+		}
+		return flags[i].UserID < flags[j].UserID
+	})
+}
+
+// RemoveUser 删除用户的作弊标记
+func (s *moderationService) RemoveUser(userID string) bool {
+	return s.flagRepo.Remove(userID)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"game/models"
+	"game/repository"
+	"game/validate"
+)
+
+// 玩家搜索：前缀的最短长度，以及单次最多返回的结果数
+const (
+	playerSearchMinPrefix = 2
+	playerSearchLimit     = 10
+)
+
+// ErrSearchPrefixTooShort 搜索关键字过短，避免用单个字符遍历所有用户名
+var ErrSearchPrefixTooShort = errors.New("搜索关键字至少需要 2 个字符")
+
+// searchEntry 索引中的一个玩家，folded 为规范化后的用户名
+type searchEntry struct {
+	folded string
+	user   models.User
+}
+
+// PlayerSearch 按用户名前缀搜索玩家，不区分大小写。索引是按规范化用户名排序的切片，前缀查询用二分查找定位；
+// 启动时从用户仓库建立，之后通过 Apply 随用户变更增量维护。设置了隐藏的玩家不会进入索引
+type PlayerSearch struct {
+	mu      sync.RWMutex
+	entries []searchEntry
+	limiter *RateLimiter
+}
+
+// NewPlayerSearch 创建 PlayerSearch 实例并建立索引，limiter 按调用方限制搜索频率
+func NewPlayerSearch(userRepo repository.UserRepository, limiter *RateLimiter) *PlayerSearch {
+	p := &PlayerSearch{limiter: limiter}
+	for _, user := range userRepo.GetAll() {
+		if !user.HideFromSearch {
+			p.entries = append(p.entries, searchEntry{folded: validate.FoldUsername(user.Username), user: user})
+		}
+	}
+	sort.Slice(p.entries, func(i, j int) bool {
+		return p.entries[i].folded < p.entries[j].folded
+	})
+	return p
+}
+
+// Apply 按用户变更更新索引，old 为 nil 表示新增，user 为 nil 表示删除
+func (p *PlayerSearch) Apply(old, user *models.User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if old != nil {
+		p.remove(*old)
+	}
+	if user != nil && !user.HideFromSearch {
+		p.insert(*user)
+	}
+}
+
+// remove 从索引中移除用户，调用方需持有写锁
+func (p *PlayerSearch) remove(user models.User) {
+	folded := validate.FoldUsername(user.Username)
+	for i := p.lowerBound(folded); i < len(p.entries) && p.entries[i].folded == folded; i++ {
+		if p.entries[i].user.UserID == user.UserID {
+			p.entries = append(p.entries[:i], p.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// insert 按顺序插入用户，调用方需持有写锁
+func (p *PlayerSearch) insert(user models.User) {
+	folded := validate.FoldUsername(user.Username)
+	i := p.lowerBound(folded)
+	p.entries = append(p.entries, searchEntry{})
+	copy(p.entries[i+1:], p.entries[i:])
+	p.entries[i] = searchEntry{folded: folded, user: user}
+}
+
+// lowerBound 返回第一个规范化用户名不小于 folded 的位置
+func (p *PlayerSearch) lowerBound(folded string) int {
+	return sort.Search(len(p.entries), func(i int) bool {
+		return p.entries[i].folded >= folded
+	})
+}
+
+// Search 返回用户名以 query 开头的玩家，按用户名排序，最多 playerSearchLimit 个；
+// client 标识调用方（如客户端 IP），超过频率限制时返回 ErrSearchTooFrequent
+func (p *PlayerSearch) Search(client, query string) ([]models.User, error) {
+	prefix := validate.FoldUsername(strings.TrimSpace(query))
+	if utf8.RuneCountInString(prefix) < playerSearchMinPrefix {
+		return nil, ErrSearchPrefixTooShort
+	}
+	if !p.limiter.Allow(client) {
+		return nil, ErrSearchTooFrequent
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	users := make([]models.User, 0, playerSearchLimit)
+	for i := p.lowerBound(prefix); i < len(p.entries) && len(users) < playerSearchLimit; i++ {
+		if !strings.HasPrefix(p.entries[i].folded, prefix) {
+			break
+		}
+		users = append(users, p.entries[i].user)
+	}
+	return users, nil
+}
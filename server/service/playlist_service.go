@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"game/models"
+	"game/protocol"
+	"game/repository"
+)
+
+var (
+	// ErrPlaylistEntry 精选玩法缺少名称，或列表过长
+	ErrPlaylistEntry = errors.New("精选玩法无效")
+	// ErrPlaylistRotate 轮换间隔为负数
+	ErrPlaylistRotate = errors.New("轮换间隔不能为负数")
+)
+
+// maxPlaylistEntries 精选玩法列表的最大长度
+const maxPlaylistEntries = 20
+
+// PlaylistService 定义快速匹配精选玩法业务逻辑接口
+type PlaylistService interface {
+	// Get 返回精选玩法列表
+	Get() models.Playlist
+	// Set 校验并替换精选玩法列表，从 now 起重新开始轮换
+	Set(req protocol.SetPlaylistRequest, now time.Time) (models.Playlist, error)
+	// Current 返回 now 时快速匹配使用的玩法，没有配置列表时 ok 为 false
+	Current(now time.Time) (entry models.PlaylistEntry, ok bool)
+}
+
+// playlistService 实现 PlaylistService 接口
+type playlistService struct {
+	playlistRepo repository.PlaylistRepository
+}
+
+// NewPlaylistService 创建 PlaylistService 实例
+func NewPlaylistService(playlistRepo repository.PlaylistRepository) PlaylistService {
+	return &playlistService{playlistRepo: playlistRepo}
+}
+
+// Get 返回精选玩法列表
+func (s *playlistService) Get() models.Playlist {
+	return s.playlistRepo.Get()
+}
+
+// Set 替换精选玩法列表，未指定地图的玩法使用默认地图
+func (s *playlistService) Set(req protocol.SetPlaylistRequest, now time.Time) (models.Playlist, error) {
+	if req.RotateMinutes < 0 {
+		return models.Playlist{}, ErrPlaylistRotate
+	}
+	if len(req.Entries) > maxPlaylistEntries {
+		return models.Playlist{}, fmt.Errorf("%w: 最多 %d 项", ErrPlaylistEntry, maxPlaylistEntries)
+	}
+	playlist := models.Playlist{
+		Entries:       make([]models.PlaylistEntry, 0, len(req.Entries)),
+		RotateMinutes: req.RotateMinutes,
+		StartsAt:      now,
+	}
+	if playlist.RotateMinutes == 0 {
+		playlist.RotateMinutes = defaultRotateMinutes
+	}
+	for _, e := range req.Entries {
+		entry := models.PlaylistEntry{
+			Name:      strings.TrimSpace(e.Name),
+			Map:       strings.TrimSpace(e.Map),
+			Objective: e.Objective,
+		}
+		if entry.Name == "" {
+			return models.Playlist{}, fmt.Errorf("%w: 名称不能为空", ErrPlaylistEntry)
+		}
+		if entry.Map == "" {
+			entry.Map = models.DefaultMap
+		}
+		if entry.Objective != models.ObjectiveNone && entry.Objective != models.ObjectiveKingOfTheHill && entry.Objective != models.ObjectiveShrinkingZone {
+			return models.Playlist{}, fmt.Errorf("%w: 未知的地图目标 %s", ErrPlaylistEntry, entry.Objective)
+		}
+		playlist.Entries = append(playlist.Entries, entry)
+	}
+	s.playlistRepo.Set(playlist)
+	return playlist, nil
+}
+
+// Current 返回当前轮换到的玩法
+func (s *playlistService) Current(now time.Time) (models.PlaylistEntry, bool) {
+	entry, i := s.playlistRepo.Get().Current(now)
+	return entry, i >= 0
+}
+
+// PlaylistInfo 转换为返回给客户端的精选玩法列表
+func PlaylistInfo(p models.Playlist, now time.Time) protocol.PlaylistInfo {
+	info := protocol.PlaylistInfo{Entries: make([]protocol.PlaylistEntryInfo, 0, len(p.Entries))}
+	for _, e := range p.Entries {
+		info.Entries = append(info.Entries, playlistEntryInfo(e))
+	}
+	if current, i := p.Current(now); i >= 0 {
+		entry := playlistEntryInfo(current)
+		next := p.NextRotation(now)
+		info.RotateMinutes = p.RotateMinutes
+		info.Current = &entry
+		info.NextRotation = &next
+	}
+	return info
+}
+
+func playlistEntryInfo(e models.PlaylistEntry) protocol.PlaylistEntryInfo {
+	return protocol.PlaylistEntryInfo{Name: e.Name, Map: e.Map, Objective: e.Objective}
+}
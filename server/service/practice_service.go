@@ -0,0 +1,56 @@
+package service
+
+import (
+	"game/models"
+	"game/repository"
+)
+
+// PracticeService 定义练习靶场成绩业务逻辑接口
+type PracticeService interface {
+	// Record 记录一次练习成绩，优于个人最佳时替换，返回最新的个人最佳以及本次是否刷新了纪录
+	Record(run models.PracticeRecord) (models.PracticeRecord, bool)
+	// Best 返回用户的个人最佳，没有有效成绩时返回 false
+	Best(userID string) (models.PracticeRecord, bool)
+	// RemoveUser 删除用户的成绩记录，注销账号时调用
+	RemoveUser(userID string) bool
+}
+
+// practiceService 实现 PracticeService 接口
+type practiceService struct {
+	practiceRepo repository.PracticeRepository
+}
+
+// NewPracticeService 创建 PracticeService 实例
+func NewPracticeService(practiceRepo repository.PracticeRepository) PracticeService {
+	return &practiceService{practiceRepo: practiceRepo}
+}
+
+// Record 累计练习次数，本次成绩优于个人最佳时替换；没有任何命中的练习不会成为个人最佳
+func (s *practiceService) Record(run models.PracticeRecord) (models.PracticeRecord, bool) {
+	improved := false
+	best := s.practiceRepo.Modify(run.UserID, func(record *models.PracticeRecord) bool {
+		runs := record.Runs + 1
+		if run.Hits > 0 && (record.Hits == 0 || run.Beats(*record)) {
+			*record = run
+			improved = true
+		}
+		record.Username = run.Username
+		record.Runs = runs
+		return true
+	})
+	return best, improved
+}
+
+// Best 返回用户的个人最佳
+func (s *practiceService) Best(userID string) (models.PracticeRecord, bool) {
+	record, ok := s.practiceRepo.Get(userID)
+	if !ok || record.Hits == 0 {
+		return models.PracticeRecord{}, false
+	}
+	return record, true
+}
+
+// RemoveUser 删除用户的成绩记录
+func (s *practiceService) RemoveUser(userID string) bool {
+	return s.practiceRepo.Remove(userID)
+}
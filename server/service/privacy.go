@@ -0,0 +1,25 @@
+package service
+
+import "game/models"
+
+// SetSearchPrivacy 设置是否在玩家搜索中隐藏，sessionID 必须是该用户当前有效的登录会话
+func (s *userService) SetSearchPrivacy(username, sessionID string, hide bool) (bool, string) {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return false, "用户不存在"
+	}
+	if session := s.sessionRepo.Get(sessionID); session == nil || session.UserID != user.UserID {
+		return false, "会话已失效，请重新登录"
+	}
+	s.userRepo.Modify(username, func(user *models.User) bool {
+		if user.HideFromSearch == hide {
+			return false
+		}
+		user.HideFromSearch = hide
+		return true
+	})
+	if hide {
+		return true, "已在玩家搜索中隐藏"
+	}
+	return true, "已在玩家搜索中显示"
+}
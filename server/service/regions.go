@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownRegion 区域不在服务器配置的区域列表中
+var ErrUnknownRegion = errors.New("未知的区域")
+
+// Regions 服务器配置的可用区域，列表为空时不限制区域名称
+type Regions struct {
+	names []string
+}
+
+// NewRegions 创建 Regions 实例，区域名不区分大小写
+func NewRegions(names []string) *Regions {
+	r := &Regions{}
+	for _, name := range names {
+		if name = normalizeRegion(name); name != "" && !slices.Contains(r.names, name) {
+			r.names = append(r.names, name)
+		}
+	}
+	return r
+}
+
+// normalizeRegion 统一区域名的大小写和空白
+func normalizeRegion(region string) string {
+	return strings.ToLower(strings.TrimSpace(region))
+}
+
+// Check 校验区域并返回规范化后的名称，空区域表示不指定，始终有效
+func (r *Regions) Check(region string) (string, error) {
+	region = normalizeRegion(region)
+	if region == "" || len(r.names) == 0 || slices.Contains(r.names, region) {
+		return region, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+}
+
+// Resolve 确定玩家所在区域：优先使用客户端指定的有效区域，
+// 其次按客户端对各区域的测速结果（毫秒）选择延迟最低的已知区域，都没有时返回空
+func (r *Regions) Resolve(requested string, latencies map[string]int) string {
+	if region, err := r.Check(requested); err == nil && region != "" {
+		return region
+	}
+	best, bestLatency := "", 0
+	for name, latency := range latencies {
+		region, err := r.Check(name)
+		if err != nil || region == "" || latency < 0 {
+			continue
+		}
+		if best == "" || latency < bestLatency || (latency == bestLatency && region < best) {
+			best, bestLatency = region, latency
+		}
+	}
+	return best
+}
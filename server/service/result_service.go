@@ -0,0 +1,84 @@
+package service
+
+import (
+	"game/models"
+	"game/protocol"
+	"game/repository"
+	"time"
+)
+
+const (
+	defaultResultPageSize = 20  // 默认每页条数
+	maxResultPageSize     = 100 // 每页最大条数
+)
+
+// ResultService 定义游戏结果业务逻辑接口
+type ResultService interface {
+	QueryResults(q repository.ResultQuery) ([]models.GameResult, int, repository.ResultQuery)
+	PruneResults(cutoff time.Time, archive bool) (int, error)
+}
+
+// resultService 实现 ResultService 接口
+type resultService struct {
+	resultRepo repository.ResultRepository
+}
+
+// NewResultService 创建 ResultService 实例
+func NewResultService(resultRepo repository.ResultRepository) ResultService {
+	return &resultService{resultRepo: resultRepo}
+}
+
+// QueryResults 分页查询游戏结果，返回当前页、匹配总数以及实际使用的分页参数
+func (s *resultService) QueryResults(q repository.ResultQuery) ([]models.GameResult, int, repository.ResultQuery) {
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultResultPageSize
+	}
+	if q.Limit > maxResultPageSize {
+		q.Limit = maxResultPageSize
+	}
+
+	results, total := s.resultRepo.Query(q)
+	return results, total, q
+}
+
+// PruneResults 清理 cutoff 之前的游戏结果，archive 为 true 时先写入归档，归档失败则不删除
+func (s *resultService) PruneResults(cutoff time.Time, archive bool) (int, error) {
+	if archive {
+		old := s.resultRepo.FindBefore(cutoff)
+		if len(old) == 0 {
+			return 0, nil
+		}
+		if err := s.resultRepo.Archive(old); err != nil {
+			return 0, err
+		}
+	}
+	return s.resultRepo.RemoveBefore(cutoff), nil
+}
+
+// PlayerSummaries 将玩家统计转换为协议中的摘要
+func PlayerSummaries(players []models.PlayerResult) []protocol.PlayerSummary {
+	summaries := make([]protocol.PlayerSummary, 0, len(players))
+	for _, p := range players {
+		summaries = append(summaries, protocol.PlayerSummary{
+			Username:      p.Username,
+			Score:         p.Score,
+			Kills:         p.Kills,
+			Deaths:        p.Deaths,
+			ShotsFired:    p.ShotsFired,
+			ShotsHit:      p.ShotsHit,
+			Accuracy:      p.Accuracy,
+			DamageDealt:   p.DamageDealt,
+			LongestStreak: p.LongestStreak,
+			Objective:     p.Objective,
+			Headshots:     p.Headshots,
+			Disconnected:  p.Disconnected,
+			Forfeited:     p.Forfeited,
+			Disconnects:   p.Disconnects,
+			Reconnects:    p.Reconnects,
+		})
+	}
+	return summaries
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"game/models"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// 每个用户最多保存的筛选条件数量，以及筛选条件名称的最大长度
+const (
+	roomFilterLimit   = 10
+	roomFilterNameMax = 32
+)
+
+// RoomFilters 返回用户保存的房间列表筛选条件，用户不存在时返回 nil
+func (s *userService) RoomFilters(username string) []models.RoomFilter {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return nil
+	}
+	return user.RoomFilters
+}
+
+// SaveRoomFilter 保存筛选条件，sessionID 必须是该用户当前有效的登录会话；同名的条件会被覆盖
+func (s *userService) SaveRoomFilter(username, sessionID string, filter models.RoomFilter) (bool, string) {
+	filter.Name = strings.TrimSpace(filter.Name)
+	filter.Region = strings.ToLower(strings.TrimSpace(filter.Region))
+	filter.Map = strings.TrimSpace(filter.Map)
+	switch {
+	case filter.Name == "":
+		return false, "筛选条件名称不能为空"
+	case utf8.RuneCountInString(filter.Name) > roomFilterNameMax:
+		return false, fmt.Sprintf("筛选条件名称不能超过 %d 个字符", roomFilterNameMax)
+	case filter.Mode != "" && filter.Mode != models.ObjectiveNone && filter.Mode != models.ObjectiveKingOfTheHill && filter.Mode != models.ObjectiveShrinkingZone:
+		return false, "未知的游戏模式: " + filter.Mode
+	}
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return false, "用户不存在"
+	}
+	if session := s.sessionRepo.Get(sessionID); session == nil || session.UserID != user.UserID {
+		return false, "会话已失效，请重新登录"
+	}
+
+	// 修改副本，避免改动存储中旧用户记录共用的切片
+	full := false
+	s.userRepo.Modify(username, func(user *models.User) bool {
+		filters := slices.Clone(user.RoomFilters)
+		if i := slices.IndexFunc(filters, func(f models.RoomFilter) bool { return f.Name == filter.Name }); i >= 0 {
+			filters[i] = filter
+		} else if len(filters) >= roomFilterLimit {
+			full = true
+			return false
+		} else {
+			filters = append(filters, filter)
+		}
+		user.RoomFilters = filters
+		return true
+	})
+	if full {
+		return false, fmt.Sprintf("最多只能保存 %d 个筛选条件", roomFilterLimit)
+	}
+	return true, "筛选条件已保存"
+}
+
+// DeleteRoomFilter 删除筛选条件，sessionID 必须是该用户当前有效的登录会话
+func (s *userService) DeleteRoomFilter(username, sessionID, name string) (bool, string) {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return false, "用户不存在"
+	}
+	if session := s.sessionRepo.Get(sessionID); session == nil || session.UserID != user.UserID {
+		return false, "会话已失效，请重新登录"
+	}
+	deleted := s.userRepo.Modify(username, func(user *models.User) bool {
+		i := slices.IndexFunc(user.RoomFilters, func(f models.RoomFilter) bool { return f.Name == name })
+		if i < 0 {
+			return false
+		}
+		user.RoomFilters = slices.Delete(slices.Clone(user.RoomFilters), i, i+1)
+		return true
+	})
+	if !deleted {
+		return false, "筛选条件不存在"
+	}
+	return true, "筛选条件已删除"
+}
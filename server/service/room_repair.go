@@ -0,0 +1,131 @@
+package service
+
+import (
+	"game/models"
+	"game/repository"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// 一致性修复的类型
+const (
+	RepairMissingRoom   = "missing_room"   // 用户的房间ID指向已删除的房间，已清除
+	RepairNotMember     = "not_member"     // 用户的房间ID指向的房间中没有该用户，已清除
+	RepairMissingUser   = "missing_user"   // 房间中的玩家已不存在，已移出房间
+	RepairOfflinePlayer = "offline_player" // 房间中的玩家离线超过时限，已移出房间
+	repairOfflineKeySep = "\x00"
+)
+
+// RoomRepair 一次一致性修复
+type RoomRepair struct {
+	Kind     string `json:"kind"`
+	Username string `json:"username"`
+	RoomID   string `json:"room_id"`
+}
+
+// offlineTracker 记录房间中离线玩家最早被发现离线的时间，用于判断离线是否超过时限
+type offlineTracker struct {
+	mu    sync.Mutex
+	since map[string]time.Time // 键为房间ID和用户名
+}
+
+// RepairRooms 检查用户与房间之间的引用并修复不一致的记录：房间ID失效的用户被清除房间ID，
+// 不存在的玩家以及离线超过 offlineAfter 的玩家被移出房间（房主由下一位玩家接任，没有玩家的房间被删除）。
+// 离线时长从某次检查首次发现玩家离线时开始计算；对局中的房间不处理。每项修复都会记录日志
+func (s *roomService) RepairRooms(offlineAfter time.Duration) []RoomRepair {
+	now := time.Now()
+	repairs := make([]RoomRepair, 0)
+
+	for _, user := range s.userRepo.GetAll() {
+		if user.RoomID == "" {
+			continue
+		}
+		var kind string
+		s.uow.Do(func(tx repository.Tx) error {
+			u := tx.User(user.Username)
+			if u == nil || u.RoomID == "" {
+				return nil
+			}
+			room := tx.Room(u.RoomID)
+			switch {
+			case room == nil:
+				kind = RepairMissingRoom
+			case !slices.Contains(room.Players, u.Username):
+				kind = RepairNotMember
+			default:
+				return nil
+			}
+			u.RoomID = ""
+			tx.UpdateUser(*u)
+			return nil
+		})
+		if kind != "" {
+			repairs = append(repairs, RoomRepair{Kind: kind, Username: user.Username, RoomID: user.RoomID})
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, room := range s.roomRepo.GetAll() {
+		if room.Status == "playing" {
+			continue
+		}
+		for _, player := range room.Players {
+			if models.IsBot(player) {
+				continue
+			}
+			kind := ""
+			switch user := s.userRepo.FindByUsername(player); {
+			case user == nil:
+				kind = RepairMissingUser
+			case !user.Online:
+				key := room.ID + repairOfflineKeySep + player
+				seen[key] = true
+				if now.Sub(s.offline.firstSeen(key, now)) >= offlineAfter {
+					kind = RepairOfflinePlayer
+				}
+			}
+			if kind == "" {
+				continue
+			}
+			removed := false
+			s.uow.Do(func(tx repository.Tx) error {
+				_, removed = RemoveMember(tx, room.ID, player)
+				return nil
+			})
+			if removed {
+				repairs = append(repairs, RoomRepair{Kind: kind, Username: player, RoomID: room.ID})
+			}
+		}
+	}
+	s.offline.retain(seen)
+
+	for _, r := range repairs {
+		log.Printf("一致性修复 %s: 用户 %s，房间 %s", r.Kind, r.Username, r.RoomID)
+	}
+	return repairs
+}
+
+// firstSeen 返回首次发现离线的时间，第一次发现时记为 now
+func (t *offlineTracker) firstSeen(key string, now time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	since, ok := t.since[key]
+	if !ok {
+		t.since[key] = now
+		since = now
+	}
+	return since
+}
+
+// retain 只保留本次检查仍然离线的玩家，玩家上线或离开房间后重新计时
+func (t *offlineTracker) retain(keys map[string]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.since {
+		if !keys[key] {
+			delete(t.since, key)
+		}
+	}
+}
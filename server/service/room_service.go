@@ -1,21 +1,50 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"game/models"
 	"game/protocol"
 	"game/repository"
+	"slices"
 	"time"
 )
 
+// ErrNotHost 只有房主可以执行的操作由其他玩家发起
+var ErrNotHost = errors.New("只有房主可以开始游戏")
+
+// RoomResult 创建或加入房间的结果
+type RoomResult struct {
+	Room     *models.Room // 创建或加入的房间，加入被拒绝时为 nil
+	Left     *models.Room // 按 RoomSwitchLeave 自动离开的原房间，没有离开或原房间随之解散时为 nil
+	Message  string       // 给用户的提示，加入被拒绝时为拒绝原因
+	Existing bool         // 幂等键重试时为 true，Room 为原先创建的房间
+}
+
+// RoomDirectory 由集群注册表实现，提供其他实例托管的房间
+type RoomDirectory interface {
+	RemoteRooms() []models.Room
+	// Handoff 房间由其他实例托管时返回转移信息，否则返回 nil
+	Handoff(roomID, username string) *protocol.Handoff
+}
+
 // RoomService 定义房间业务逻辑接口
 type RoomService interface {
-	CreateRoom(req protocol.CreateRoomRequest, hostID string) (*models.Room, error)
-	JoinRoom(req protocol.JoinRoomRequest, username string) (*models.Room, string, error)
+	CreateRoom(req protocol.CreateRoomRequest, hostID string) (*RoomResult, error)
+	JoinRoom(req protocol.JoinRoomRequest, username string) (*RoomResult, error)
 	GetRoomByID(roomID string) *models.Room
 	GetAllRooms() []models.Room
-	UpdateRoom(room models.Room) bool
+	// UpdateRoom 按修订号更新房间，读取后房间已被修改时返回 repository.ErrVersionConflict
+	UpdateRoom(room models.Room) error
 	RemoveRoom(roomID string) bool
-	StartGame(roomID string, hostID string) bool
+	// StartGame 房主开始游戏，prepare 不为 nil 时在存储锁内做额外的检查和准备，返回错误时不开始
+	StartGame(roomID string, hostID string, prepare func(room *models.Room) error) (*models.Room, error)
+	SetRoomDirectory(directory RoomDirectory)
+	Handoff(roomID, username string) *protocol.Handoff
+	// RoomFilter 返回用户保存的名为 name 的房间列表筛选条件，不存在时返回 nil
+	RoomFilter(username, name string) *models.RoomFilter
+	// RepairRooms 修复用户与房间之间不一致的引用，返回所做的修复
+	RepairRooms(offlineAfter time.Duration) []RoomRepair
 }
 
 // roomService 实现 RoomService 接口
@@ -23,87 +52,173 @@ type roomService struct {
 	roomRepo   repository.RoomRepository
 	userRepo   repository.UserRepository
 	resultRepo repository.ResultRepository
+	uow        repository.UnitOfWork
+	limiter    *RoomLimiter
+	regions    *Regions
+	words      *WordFilter   // 创建房间时检查房间名
+	switchMode string        // 用户已在其他房间中时的处理方式，见 RoomSwitchReject
+	keyTTL     time.Duration // 创建房间幂等键的有效期
+	size       RoomSize      // 允许的房间人数上限范围
+	directory  RoomDirectory // 集群模式下的跨实例房间目录，单实例运行时为 nil
+	offline    *offlineTracker
 }
 
 // NewRoomService 创建 RoomService 实例
-func NewRoomService(roomRepo repository.RoomRepository, userRepo repository.UserRepository, resultRepo repository.ResultRepository) RoomService {
+func NewRoomService(roomRepo repository.RoomRepository, userRepo repository.UserRepository, resultRepo repository.ResultRepository, uow repository.UnitOfWork, limiter *RoomLimiter, regions *Regions, words *WordFilter, switchMode string, keyTTL time.Duration, size RoomSize) RoomService {
 	return &roomService{
 		roomRepo:   roomRepo,
 		userRepo:   userRepo,
 		resultRepo: resultRepo,
+		uow:        uow,
+		limiter:    limiter,
+		regions:    regions,
+		words:      words,
+		switchMode: switchMode,
+		keyTTL:     keyTTL,
+		size:       size,
+		offline:    &offlineTracker{since: make(map[string]time.Time)},
 	}
 }
 
-// CreateRoom 处理创建房间逻辑
-func (s *roomService) CreateRoom(req protocol.CreateRoomRequest, hostID string) (*models.Room, error) {
-	// 创建新房间
-	room := models.Room{
-		ID:         "room_" + time.Now().Format("20060102150405"), //从当前时间中生成房间ID，按照20060102150405格式
-		Name:       req.Name,
-		HostID:     hostID,
-		Players:    []string{hostID},
-		MaxPlayers: req.MaxPlayers,
-		Status:     "waiting",
-		CreatedAt:  time.Now(),
+// CreatedRoom 返回房主用同一幂等键在有效期内创建、房主仍在其中的房间，没有时返回 nil
+func CreatedRoom(host *models.User, room func(id string) *models.Room, key string, since time.Time) *models.Room {
+	if key == "" || host == nil || host.RoomID == "" {
+		return nil
+	}
+	if r := room(host.RoomID); r != nil && r.CreatedWith(host.Username, key, since) {
+		return r
 	}
+	return nil
+}
 
-	// 保存房间
-	s.roomRepo.Add(room)
+// CreateRoom 处理创建房间逻辑，HTTP 和 WebSocket 创建房间都经过这里
+func (s *roomService) CreateRoom(req protocol.CreateRoomRequest, hostID string) (*RoomResult, error) {
+	// 带幂等键的重试直接返回原先创建的房间，不再校验和计入创建频率
+	since := time.Now().Add(-s.keyTTL)
+	if room := CreatedRoom(s.userRepo.FindByUsername(hostID), s.roomRepo.GetByID, req.IdempotencyKey, since); room != nil {
+		return &RoomResult{Room: room, Message: "房间创建成功", Existing: true}, nil
+	}
 
-	// 更新用户的房间ID
-	user := s.userRepo.FindByUsername(hostID)
-	if user != nil {
-		user.RoomID = room.ID
-		s.userRepo.Update(hostID, *user)
+	// 校验对局规则
+	rules, err := RoomRules(req.Rules)
+	if err != nil {
+		return nil, err
 	}
 
-	return &room, nil
-}
+	// 校验房间名和人数上限，房间名去掉首尾空白，人数按配置调整
+	if err := s.checkRoomRequest(&req); err != nil {
+		return nil, err
+	}
 
-// JoinRoom 处理加入房间逻辑
-func (s *roomService) JoinRoom(req protocol.JoinRoomRequest, username string) (*models.Room, string, error) {
-	// 查找房间
-	room := s.roomRepo.GetByID(req.RoomID)
-	if room == nil {
-		return nil, "房间不存在", nil
+	// 校验区域，未指定时使用房主最近所在的区域
+	region, err := s.regions.Check(req.Region)
+	if err != nil {
+		return nil, err
+	}
+	if host := s.userRepo.FindByUsername(hostID); region == "" && host != nil {
+		region = host.Region
 	}
 
-	// 检查房间是否已满
-	if len(room.Players) >= room.MaxPlayers {
-		return nil, "房间已满", nil
+	// 检查容量限制
+	if err := s.limiter.Allow(hostID, len(s.roomRepo.GetAll())); err != nil {
+		return nil, err
 	}
 
-	// 检查房间状态
-	if room.Status == "playing" {
-		return nil, "游戏进行中，无法加入", nil
+	mapName := req.Map
+	if mapName == "" {
+		mapName = models.DefaultMap
 	}
 
-	// 检查用户是否已在房间中
-	for _, player := range room.Players {
-		if player == username {
-			return nil, "您已在房间中", nil
+	// 创建新房间
+	room := models.Room{
+		ID:         fmt.Sprintf("room_%d", time.Now().UnixNano()),
+		Name:       req.Name,
+		HostID:     hostID,
+		Players:    []string{hostID},
+		MaxPlayers: req.MaxPlayers,
+		Status:     "waiting",
+		CreatedAt:  time.Now(),
+		Map:        mapName,
+		Rules:      rules,
+		Region:     region,
+		CreateKey:  req.IdempotencyKey,
+	}
+
+	// 保存房间并更新用户的房间ID，两者一起提交；房主已在其他房间中时按配置拒绝或先离开。
+	// 并发的重试在事务中再检查一次幂等键
+	result := &RoomResult{Room: &room, Message: "房间创建成功"}
+	err = s.uow.Do(func(tx repository.Tx) error {
+		if existing := CreatedRoom(tx.User(hostID), tx.Room, req.IdempotencyKey, since); existing != nil {
+			result = &RoomResult{Room: existing, Message: "房间创建成功", Existing: true}
+			return nil
+		}
+		var err error
+		if result.Left, err = ReleaseRoom(tx, hostID, "", s.switchMode); err != nil {
+			return err
 		}
+		tx.PutRoom(room)
+		if user := tx.User(hostID); user != nil {
+			user.RoomID = room.ID
+			tx.UpdateUser(*user)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 添加用户到房间
-	room.Players = append(room.Players, username)
+	return result, nil
+}
 
-	// 更新房间状态
-	if len(room.Players) >= 2 {
-		room.Status = "ready"
+// JoinRoom 处理加入房间逻辑，HTTP 和 WebSocket 加入房间都经过这里。
+// 加入被拒绝时返回的结果中 Room 为 nil，Message 为拒绝原因
+func (s *roomService) JoinRoom(req protocol.JoinRoomRequest, username string) (*RoomResult, error) {
+	// 在事务中检查并加入房间，房间和用户的房间ID一起提交
+	result := &RoomResult{Message: "房间不存在"}
+	err := s.uow.Do(func(tx repository.Tx) error {
+		room := tx.Room(req.RoomID)
+		if room == nil {
+			return nil
+		}
+		if result.Message = joinRejectReason(room, username); result.Message != "" {
+			return nil
+		}
+		var err error
+		if result.Left, err = ReleaseRoom(tx, username, room.ID, s.switchMode); err != nil {
+			return err
+		}
+		if slices.Contains(room.Players, username) {
+			// 已在房间玩家列表中（例如断线后重新登录）时重新进入，不占用新的位置
+			result.Message = "已重新加入房间"
+		} else {
+			result.Message = "加入房间成功"
+			room.Players = append(room.Players, username)
+		}
+		if len(room.Players) >= 2 {
+			room.Status = "ready"
+		}
+		tx.PutRoom(*room)
+		if user := tx.User(username); user != nil {
+			user.RoomID = room.ID
+			tx.UpdateUser(*user)
+		}
+		result.Room = room
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 保存房间
-	s.roomRepo.Update(*room)
+	return result, nil
+}
 
-	// 更新用户的房间ID
+// RoomFilter 查找用户保存的房间列表筛选条件
+func (s *roomService) RoomFilter(username, name string) *models.RoomFilter {
 	user := s.userRepo.FindByUsername(username)
-	if user != nil {
-		user.RoomID = room.ID
-		s.userRepo.Update(username, *user)
+	if user == nil {
+		return nil
 	}
-
-	return room, "加入房间成功", nil
+	return user.RoomFilter(name)
 }
 
 // GetRoomByID 根据ID获取房间
@@ -111,37 +226,99 @@ func (s *roomService) GetRoomByID(roomID string) *models.Room {
 	return s.roomRepo.GetByID(roomID)
 }
 
-// GetAllRooms 获取所有房间
+// SetRoomDirectory 设置跨实例房间目录
+func (s *roomService) SetRoomDirectory(directory RoomDirectory) {
+	s.directory = directory
+}
+
+// Handoff 集群模式下房间由其他实例托管时，返回把玩家转移到该实例的信息
+func (s *roomService) Handoff(roomID, username string) *protocol.Handoff {
+	if s.directory == nil {
+		return nil
+	}
+	return s.directory.Handoff(roomID, username)
+}
+
+// GetAllRooms 获取所有房间，集群模式下包括其他实例托管的房间
 func (s *roomService) GetAllRooms() []models.Room {
-	return s.roomRepo.GetAll()
+	rooms := s.roomRepo.GetAll()
+	if s.directory != nil {
+		rooms = append(rooms, s.directory.RemoteRooms()...)
+	}
+	return rooms
 }
 
 // UpdateRoom 更新房间信息
-func (s *roomService) UpdateRoom(room models.Room) bool {
+func (s *roomService) UpdateRoom(room models.Room) error {
 	return s.roomRepo.Update(room)
 }
 
+// roomUpdateRetries 按修订号更新房间冲突时的最多尝试次数
+const roomUpdateRetries = 5
+
+// updateRoom 读取房间并由 fn 修改后按修订号写回，写回前房间被其他请求修改时重新读取并重试；
+// fn 返回 false 表示放弃修改。返回写回后的房间，房间不存在或放弃修改时为 nil
+func updateRoom(repo repository.RoomRepository, id string, fn func(room *models.Room) bool) (*models.Room, error) {
+	for range roomUpdateRetries {
+		room := repo.GetByID(id)
+		if room == nil || !fn(room) {
+			return nil, nil
+		}
+		err := repo.Update(*room)
+		switch {
+		case err == nil:
+			room.Version++
+			return room, nil
+		case errors.Is(err, repository.ErrRoomNotFound):
+			return nil, nil
+		case !errors.Is(err, repository.ErrVersionConflict):
+			return nil, err
+		}
+	}
+	return nil, repository.ErrVersionConflict
+}
+
 // RemoveRoom 删除房间
 func (s *roomService) RemoveRoom(roomID string) bool {
 	return s.roomRepo.Remove(roomID)
 }
 
-// StartGame 处理开始游戏逻辑
-func (s *roomService) StartGame(roomID string, hostID string) bool {
-	// 查找房间
-	room := s.roomRepo.GetByID(roomID)
-	if room == nil {
-		return false
+// StartGame 处理开始游戏逻辑，房间不存在时返回 nil 和 nil
+func (s *roomService) StartGame(roomID string, hostID string, prepare func(room *models.Room) error) (*models.Room, error) {
+	// 只有房主可以开始游戏，在存储锁内检查并更新房间状态
+	var started *models.Room
+	var err error
+	s.roomRepo.Modify(roomID, func(room *models.Room) bool {
+		if room.HostID != hostID {
+			err = ErrNotHost
+			return false
+		}
+		if prepare != nil {
+			if err = prepare(room); err != nil {
+				return false
+			}
+		}
+		room.Status = "playing"
+		clone := room.Clone()
+		started = &clone
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
+	return started, nil
+}
 
-	// 检查是否是房主
-	if room.HostID != hostID {
-		return false
+// joinRejectReason 检查玩家能否加入房间，可以加入时返回空字符串
+func joinRejectReason(room *models.Room, username string) string {
+	if room.Status == "playing" {
+		return "游戏进行中，无法加入"
 	}
-
-	// 更新房间状态
-	room.Status = "playing"
-	s.roomRepo.Update(*room)
-
-	return true
-}
+	if room.IsBanned(username) {
+		return "您已被房主禁止加入该房间"
+	}
+	if len(room.Players) >= room.MaxPlayers && !slices.Contains(room.Players, username) {
+		return "房间已满"
+	}
+	return ""
+}
\ No newline at end of file
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"game/models"
+	"game/repository"
+	"slices"
+)
+
+// 用户已在一个房间中时创建或加入其他房间的处理方式
+const (
+	RoomSwitchReject = "reject" // 拒绝并返回 ErrAlreadyInRoom，用户需先离开原房间
+	RoomSwitchLeave  = "leave"  // 在同一事务中先离开原房间
+)
+
+// 用户已在其他房间中，不能再创建或加入房间
+var (
+	ErrAlreadyInRoom = errors.New("您已在其他房间中，请先离开原房间")
+	ErrInMatch       = errors.New("您所在的房间正在对局中，无法离开")
+)
+
+// ErrCodeAlreadyInRoom 加入房间因用户已在其他房间中失败时返回给客户端的失败代码
+const ErrCodeAlreadyInRoom = "already_in_room"
+
+// ReleaseRoom 在事务中处理用户当前所在的房间，保证一个用户同时只在一个房间中。
+// 用户不在房间中、已在 targetRoomID 房间中，或者用户的房间ID已失效时直接返回（失效的房间ID会被清除）；
+// 否则按 mode 拒绝，或把用户移出原房间（原房间没有玩家时删除）并返回移出后的原房间，原房间被删除时返回 nil。
+// 原房间正在对局时总是拒绝
+func ReleaseRoom(tx repository.Tx, username, targetRoomID, mode string) (*models.Room, error) {
+	user := tx.User(username)
+	if user == nil || user.RoomID == "" || user.RoomID == targetRoomID {
+		return nil, nil
+	}
+	old := tx.Room(user.RoomID)
+	if old == nil || !slices.Contains(old.Players, username) {
+		user.RoomID = ""
+		tx.UpdateUser(*user)
+		return nil, nil
+	}
+	if old.Status == "playing" {
+		return nil, ErrInMatch
+	}
+	if mode != RoomSwitchLeave {
+		return nil, ErrAlreadyInRoom
+	}
+
+	removePlayer(old, username)
+	delete(old.Heroes, username)
+	user.RoomID = ""
+	tx.UpdateUser(*user)
+	if len(old.Players) == 0 {
+		tx.RemoveRoom(old.ID)
+		return nil, nil
+	}
+	tx.PutRoom(*old)
+	return old, nil
+}
+
+// RemoveMember 在事务中把玩家移出房间，房主离开时由下一位玩家接任，房间没有玩家时删除；
+// 玩家的房间ID指向该房间时一并清除。房间正在对局或玩家不在房间中时不做修改。
+// 返回移出后的房间（房间被删除时为 nil）以及是否移出了玩家
+func RemoveMember(tx repository.Tx, roomID, username string) (*models.Room, bool) {
+	room := tx.Room(roomID)
+	if room == nil || room.Status == "playing" || !removePlayer(room, username) {
+		return nil, false
+	}
+	delete(room.Heroes, username)
+	if user := tx.User(username); user != nil && user.RoomID == roomID {
+		user.RoomID = ""
+		tx.UpdateUser(*user)
+	}
+	if len(room.Players) == 0 {
+		tx.RemoveRoom(roomID)
+		return nil, true
+	}
+	tx.PutRoom(*room)
+	return room, true
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"game/protocol"
+	"game/validate"
+	"strings"
+)
+
+// 创建房间校验失败的代码，房间名格式的违规代码见 validate.CodeRoomNameEmpty 等
+const (
+	ErrCodeRoomNameTaken   = "room_name_taken"
+	ErrCodeRoomNameProfane = "room_name_profane"
+	ErrCodeMaxPlayers      = "max_players_out_of_range"
+)
+
+// RoomError 创建房间的请求不符合规则，Code 为失败代码，随错误消息一起返回给客户端
+type RoomError struct {
+	Code    string
+	Message string
+	err     error // 对应的哨兵错误，例如 ErrNameProfane
+}
+
+func (e *RoomError) Error() string {
+	return e.Message
+}
+
+func (e *RoomError) Unwrap() error {
+	return e.err
+}
+
+// RoomSize 创建房间时允许的人数上限范围，Clamp 为 true 时把超出范围的人数调整到边界，否则拒绝
+type RoomSize struct {
+	Min   int
+	Max   int
+	Clamp bool
+}
+
+// players 返回校验后的房间人数上限，未指定（0）时使用范围上限
+func (r RoomSize) players(n int) (int, error) {
+	switch {
+	case n == 0:
+		return r.Max, nil
+	case n >= r.Min && n <= r.Max:
+		return n, nil
+	case r.Clamp:
+		return min(max(n, r.Min), r.Max), nil
+	}
+	return 0, &RoomError{Code: ErrCodeMaxPlayers, Message: fmt.Sprintf("房间人数必须在 %d-%d 之间", r.Min, r.Max)}
+}
+
+// checkRoomRequest 校验并规范化创建房间请求中的房间名和人数上限：房间名格式、屏蔽词以及
+// 与现有房间（不区分大小写）是否重名
+func (s *roomService) checkRoomRequest(req *protocol.CreateRoomRequest) error {
+	name, policyErr := validate.RoomName(req.Name)
+	if policyErr != nil {
+		return &RoomError{Code: policyErr.Code, Message: policyErr.Message}
+	}
+	if err := s.words.CheckName(name); err != nil {
+		return &RoomError{Code: ErrCodeRoomNameProfane, Message: err.Error(), err: err}
+	}
+	if s.roomNameTaken(name) {
+		return &RoomError{Code: ErrCodeRoomNameTaken, Message: "已有同名的房间，请换一个名称"}
+	}
+	players, err := s.size.players(req.MaxPlayers)
+	if err != nil {
+		return err
+	}
+	req.Name, req.MaxPlayers = name, players
+	return nil
+}
+
+// roomNameTaken 判断是否已有同名的房间，集群模式下包括其他实例托管的房间
+func (s *roomService) roomNameTaken(name string) bool {
+	for _, room := range s.GetAllRooms() {
+		if strings.EqualFold(room.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RoomErrorCode 返回创建房间请求不符合规则时的失败代码，其他错误返回空字符串
+func RoomErrorCode(err error) string {
+	var roomErr *RoomError
+	if errors.As(err, &roomErr) {
+		return roomErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"game/models"
+	"game/protocol"
+)
+
+// roundRobinMatches 把已按种子排列的玩家蛇形分入各小组，使各组实力接近，组内单循环，每轮每人最多一场。
+// groupSize 为每组人数上限，0 表示所有玩家一组；人数为奇数的小组每轮有一人轮空，轮空不生成比赛
+func roundRobinMatches(entrants []models.TournamentEntrant, groupSize int) []models.BracketMatch {
+	groups := 1
+	if groupSize > 0 {
+		groups = (len(entrants) + groupSize - 1) / groupSize
+	}
+	members := make([][]models.TournamentEntrant, groups)
+	for i, e := range entrants {
+		g := i % groups
+		if (i/groups)%2 == 1 {
+			g = groups - 1 - g
+		}
+		members[g] = append(members[g], e)
+	}
+
+	var matches []models.BracketMatch
+	for g, group := range members {
+		// 轮转法：固定第一个位置，其余位置每轮顺移一位，首尾依次配对
+		ring := make([]int, len(group), len(group)+1)
+		for i := range ring {
+			ring[i] = i
+		}
+		if len(ring)%2 == 1 {
+			ring = append(ring, -1)
+		}
+		n := len(ring)
+		for round := 1; round < n; round++ {
+			slot := 0
+			for i := 0; i < n/2; i++ {
+				a, b := ring[i], ring[n-1-i]
+				if a < 0 || b < 0 {
+					continue
+				}
+				matches = append(matches, models.BracketMatch{
+					ID:      fmt.Sprintf("G%d-R%d-M%d", g+1, round, slot+1),
+					Group:   g + 1,
+					Round:   round,
+					Slot:    slot,
+					Player1: group[a].Username,
+					Player2: group[b].Username,
+					Seed1:   group[a].Seed,
+					Seed2:   group[b].Seed,
+				})
+				slot++
+			}
+			ring = append([]int{ring[0], ring[n-1]}, ring[1:n-1]...)
+		}
+	}
+	return matches
+}
+
+// roundRobinChampion 所有比赛结束后返回冠军：各小组第一名中胜率最高者，胜率相同时种子靠前；仍有比赛未结束时返回空
+func roundRobinChampion(t models.Tournament) string {
+	for _, m := range t.Matches {
+		if m.Winner == "" {
+			return ""
+		}
+	}
+	var leaders []protocol.StandingInfo
+	for _, s := range standings(t) {
+		if s.Rank == 1 && s.Played > 0 {
+			leaders = append(leaders, s)
+		}
+	}
+	if len(leaders) == 0 {
+		return ""
+	}
+	sort.SliceStable(leaders, func(i, j int) bool {
+		a, b := leaders[i], leaders[j]
+		if a.Wins*b.Played != b.Wins*a.Played {
+			return a.Wins*b.Played > b.Wins*a.Played
+		}
+		return a.Seed < b.Seed
+	})
+	return leaders[0].Username
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"game/models"
+	"game/protocol"
+)
+
+// ErrInvalidRules 房间规则超出允许范围
+var ErrInvalidRules = errors.New("房间规则无效")
+
+// 房间规则的取值范围
+const (
+	minStartingHP       = 1
+	maxStartingHP       = 100
+	minDamageMultiplier = 0.1
+	maxDamageMultiplier = 10.0
+	minGravity          = 0.1
+	maxGravity          = 5.0
+	minMoveSpeed        = 0.25
+	maxMoveSpeed        = 4.0
+	maxRounds           = 15
+	minTimeLimit        = 30
+	maxTimeLimit        = 3600
+	maxKillTarget       = 100
+	minTickRate         = 10
+	maxTickRate         = 128
+)
+
+// RoomRules 将创建房间请求中的规则补全默认值并校验，req 为 nil 时使用默认规则
+func RoomRules(req *protocol.RoomRules) (models.Rules, error) {
+	if req == nil {
+		return models.DefaultRules(), nil
+	}
+	rules := models.Rules{
+		StartingHP:       req.StartingHP,
+		DamageMultiplier: req.DamageMultiplier,
+		Gravity:          req.Gravity,
+		MoveSpeed:        req.MoveSpeed,
+		FriendlyFire:     req.FriendlyFire,
+		Rounds:           req.Rounds,
+		TimeLimit:        req.TimeLimit,
+		Overtime:         req.Overtime,
+		KillTarget:       req.KillTarget,
+		Objective:        req.Objective,
+		TickRate:         req.TickRate,
+		SendRate:         req.SendRate,
+	}.WithDefaults()
+
+	switch {
+	case rules.StartingHP < minStartingHP || rules.StartingHP > maxStartingHP:
+		return rules, fmt.Errorf("%w: 起始生命值必须在 %d~%d 之间", ErrInvalidRules, minStartingHP, maxStartingHP)
+	case rules.DamageMultiplier < minDamageMultiplier || rules.DamageMultiplier > maxDamageMultiplier:
+		return rules, fmt.Errorf("%w: 伤害倍率必须在 %g~%g 之间", ErrInvalidRules, minDamageMultiplier, maxDamageMultiplier)
+	case rules.Gravity < minGravity || rules.Gravity > maxGravity:
+		return rules, fmt.Errorf("%w: 重力倍率必须在 %g~%g 之间", ErrInvalidRules, minGravity, maxGravity)
+	case rules.MoveSpeed < minMoveSpeed || rules.MoveSpeed > maxMoveSpeed:
+		return rules, fmt.Errorf("%w: 移动速度倍率必须在 %g~%g 之间", ErrInvalidRules, minMoveSpeed, maxMoveSpeed)
+	case rules.Rounds < 1 || rules.Rounds > maxRounds:
+		return rules, fmt.Errorf("%w: 局数必须在 1~%d 之间", ErrInvalidRules, maxRounds)
+	case rules.TimeLimit != 0 && (rules.TimeLimit < minTimeLimit || rules.TimeLimit > maxTimeLimit):
+		return rules, fmt.Errorf("%w: 时间上限必须为 0 或 %d~%d 秒", ErrInvalidRules, minTimeLimit, maxTimeLimit)
+	case rules.Overtime != models.OvertimeNone && rules.Overtime != models.OvertimeSuddenDeath && rules.Overtime != models.OvertimeReducedHP:
+		return rules, fmt.Errorf("%w: 未知的加时方式 %s", ErrInvalidRules, rules.Overtime)
+	case rules.KillTarget < 0 || rules.KillTarget > maxKillTarget:
+		return rules, fmt.Errorf("%w: 目标击杀数必须在 0~%d 之间", ErrInvalidRules, maxKillTarget)
+	case rules.Objective != models.ObjectiveNone && rules.Objective != models.ObjectiveKingOfTheHill && rules.Objective != models.ObjectiveShrinkingZone:
+		return rules, fmt.Errorf("%w: 未知的地图目标 %s", ErrInvalidRules, rules.Objective)
+	case rules.TickRate != 0 && (rules.TickRate < minTickRate || rules.TickRate > maxTickRate):
+		return rules, fmt.Errorf("%w: 逻辑帧率必须为 0 或 %d~%d", ErrInvalidRules, minTickRate, maxTickRate)
+	case rules.SendRate < 0 || rules.SendRate > maxTickRate:
+		return rules, fmt.Errorf("%w: 快照发送频率必须在 0~%d 之间", ErrInvalidRules, maxTickRate)
+	case rules.TickRate != 0 && rules.SendRate > rules.TickRate:
+		return rules, fmt.Errorf("%w: 快照发送频率不能超过逻辑帧率", ErrInvalidRules)
+	}
+	return rules, nil
+}
+
+// RulesInfo 将房间规则转换为协议中的规则信息
+func RulesInfo(rules models.Rules) protocol.RoomRules {
+	rules = rules.WithDefaults()
+	return protocol.RoomRules{
+		StartingHP:       rules.StartingHP,
+		DamageMultiplier: rules.DamageMultiplier,
+		Gravity:          rules.Gravity,
+		MoveSpeed:        rules.MoveSpeed,
+		FriendlyFire:     rules.FriendlyFire,
+		Rounds:           rules.Rounds,
+		TimeLimit:        rules.TimeLimit,
+		Overtime:         rules.Overtime,
+		KillTarget:       rules.KillTarget,
+		Objective:        rules.Objective,
+		TickRate:         rules.TickRate,
+		SendRate:         rules.SendRate,
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"sort"
+
+	"game/models"
+	"game/protocol"
+)
+
+// standings 根据已记录的比赛结果计算战绩排名，轮空不计入战绩。循环赛按小组分别排名，胜场多者在前；
+// 淘汰赛所有玩家一起排名，负场少者在前，负场相同时胜场多者在前，即走得更远的玩家在前。战绩相同时种子靠前
+func standings(t models.Tournament) []protocol.StandingInfo {
+	if len(t.Matches) == 0 {
+		return nil
+	}
+	rows := make(map[string]*protocol.StandingInfo, len(t.Entrants))
+	list := make([]*protocol.StandingInfo, 0, len(t.Entrants))
+	for _, e := range t.Entrants {
+		row := &protocol.StandingInfo{Username: e.Username, Seed: e.Seed}
+		rows[e.Username] = row
+		list = append(list, row)
+	}
+	for _, m := range t.Matches {
+		for _, p := range []string{m.Player1, m.Player2} {
+			if row := rows[p]; row != nil && m.Group > 0 {
+				row.Group = m.Group
+			}
+		}
+		if m.Winner == "" || m.Bye {
+			continue
+		}
+		loser := m.Player1
+		if m.Winner == m.Player1 {
+			loser = m.Player2
+		}
+		if row := rows[m.Winner]; row != nil {
+			row.Played++
+			row.Wins++
+		}
+		if row := rows[loser]; row != nil {
+			row.Played++
+			row.Losses++
+		}
+	}
+
+	elimination := t.Format != models.TournamentRoundRobin
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		switch {
+		case a.Group != b.Group:
+			return a.Group < b.Group
+		case elimination && a.Losses != b.Losses:
+			return a.Losses < b.Losses
+		case a.Wins != b.Wins:
+			return a.Wins > b.Wins
+		case a.Losses != b.Losses:
+			return a.Losses < b.Losses
+		}
+		return a.Seed < b.Seed
+	})
+	result := make([]protocol.StandingInfo, len(list))
+	for i, row := range list {
+		row.Rank = 1
+		if i > 0 && list[i-1].Group == row.Group {
+			row.Rank = result[i-1].Rank + 1
+		}
+		result[i] = *row
+	}
+	return result
+}
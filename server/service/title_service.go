@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"game/models"
+	"game/repository"
+)
+
+var (
+	// ErrUnknownTitle 称号ID不在称号列表中
+	ErrUnknownTitle = errors.New("称号不存在")
+	// ErrTitleNotOwned 用户没有获得该称号
+	ErrTitleNotOwned = errors.New("尚未获得该称号")
+	// ErrTitleSeasonRequired 授予赛季称号时必须指定赛季
+	ErrTitleSeasonRequired = errors.New("赛季称号必须指定赛季")
+	// ErrTitleUserNotFound 用户不存在
+	ErrTitleUserNotFound = errors.New("用户不存在")
+	// ErrTitleSession 登录会话无效
+	ErrTitleSession = errors.New("会话已失效，请重新登录")
+)
+
+// 称号的展示形式：称号显示在名字前，徽章显示为图标
+const (
+	TitleKindTitle = "title"
+	TitleKindBadge = "badge"
+)
+
+// TitleDef 称号定义。成就称号在对局结束后按玩家战绩自动授予，赛季称号由赛季结算通过管理接口授予
+type TitleDef struct {
+	ID          string
+	Name        string
+	Kind        string
+	Source      string
+	Description string
+	earned      func(stats models.PlayerStats) bool // 成就达成条件，赛季称号为 nil
+}
+
+// titleCatalog 所有称号，按展示顺序排列
+var titleCatalog = []TitleDef{
+	{ID: "first_win", Name: "初露锋芒", Kind: TitleKindBadge, Source: models.TitleSourceAchievement, Description: "赢得第一场对局",
+		earned: func(s models.PlayerStats) bool { return s.Wins >= 1 }},
+	{ID: "veteran", Name: "身经百战", Kind: TitleKindTitle, Source: models.TitleSourceAchievement, Description: "完成 100 场对局",
+		earned: func(s models.PlayerStats) bool { return s.Matches >= 100 }},
+	{ID: "sharpshooter", Name: "神枪手", Kind: TitleKindTitle, Source: models.TitleSourceAchievement, Description: "累计射击 500 次以上且命中率不低于 50%",
+		earned: func(s models.PlayerStats) bool { return s.ShotsFired >= 500 && s.Accuracy >= 0.5 }},
+	{ID: "reaper", Name: "收割者", Kind: TitleKindTitle, Source: models.TitleSourceAchievement, Description: "累计击杀 1000 名对手",
+		earned: func(s models.PlayerStats) bool { return s.Kills >= 1000 }},
+	{ID: "season_champion", Name: "赛季冠军", Kind: TitleKindTitle, Source: models.TitleSourceSeason, Description: "赛季排名第一"},
+	{ID: "season_top10", Name: "赛季十强", Kind: TitleKindBadge, Source: models.TitleSourceSeason, Description: "赛季排名前十"},
+	{ID: "season_top100", Name: "赛季百强", Kind: TitleKindBadge, Source: models.TitleSourceSeason, Description: "赛季排名前一百"},
+}
+
+// FindTitle 按ID查找称号定义
+func FindTitle(id string) (TitleDef, bool) {
+	i := slices.IndexFunc(titleCatalog, func(d TitleDef) bool { return d.ID == id })
+	if i < 0 {
+		return TitleDef{}, false
+	}
+	return titleCatalog[i], true
+}
+
+// TitleDisplay 返回称号的展示文本，赛季称号附带赛季名；未知称号返回空字符串
+func TitleDisplay(t models.Title) string {
+	def, ok := FindTitle(t.ID)
+	if !ok {
+		return ""
+	}
+	if t.Season != "" {
+		return def.Name + " · " + t.Season
+	}
+	return def.Name
+}
+
+// TitleService 定义称号业务逻辑接口
+type TitleService interface {
+	// Catalog 返回所有称号定义
+	Catalog() []TitleDef
+	// Titles 返回用户拥有的称号以及当前装备的称号，用户不存在时返回 ErrTitleUserNotFound
+	Titles(username string) ([]models.Title, string, error)
+	// Select 校验登录会话后装备称号，key 为空时卸下称号
+	Select(username, sessionID, key string) error
+	// Grant 授予称号，赛季称号需要指定赛季；已拥有时不重复授予
+	Grant(username, titleID, season string) (models.Title, error)
+	// AwardAchievements 按用户的战绩授予新达成的成就称号，返回新获得的称号
+	AwardAchievements(username string) []models.Title
+	// Active 返回用户当前装备的称号的展示文本，未装备时为空
+	Active(username string) string
+}
+
+// titleService 实现 TitleService 接口
+type titleService struct {
+	userRepo    repository.UserRepository
+	resultRepo  repository.ResultRepository
+	sessionRepo repository.SessionRepository
+}
+
+// NewTitleService 创建 TitleService 实例
+func NewTitleService(userRepo repository.UserRepository, resultRepo repository.ResultRepository, sessionRepo repository.SessionRepository) TitleService {
+	return &titleService{userRepo: userRepo, resultRepo: resultRepo, sessionRepo: sessionRepo}
+}
+
+// Catalog 返回所有称号定义
+func (s *titleService) Catalog() []TitleDef {
+	return titleCatalog
+}
+
+// Titles 返回用户拥有的称号以及当前装备的称号
+func (s *titleService) Titles(username string) ([]models.Title, string, error) {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return nil, "", ErrTitleUserNotFound
+	}
+	return user.Titles, user.ActiveTitle, nil
+}
+
+// Select 装备称号，只能装备已拥有的称号
+func (s *titleService) Select(username, sessionID, key string) error {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return ErrTitleUserNotFound
+	}
+	if session := s.sessionRepo.Get(sessionID); session == nil || session.UserID != user.UserID {
+		return ErrTitleSession
+	}
+	if key != "" && !slices.ContainsFunc(user.Titles, func(t models.Title) bool { return t.Key() == key }) {
+		return ErrTitleNotOwned
+	}
+	s.userRepo.Modify(username, func(user *models.User) bool {
+		if user.ActiveTitle == key {
+			return false
+		}
+		user.ActiveTitle = key
+		return true
+	})
+	return nil
+}
+
+// Grant 授予称号，赛季称号按赛季分别授予，成就称号忽略 season
+func (s *titleService) Grant(username, titleID, season string) (models.Title, error) {
+	def, ok := FindTitle(titleID)
+	if !ok {
+		return models.Title{}, ErrUnknownTitle
+	}
+	season = strings.TrimSpace(season)
+	if def.Source != models.TitleSourceSeason {
+		season = ""
+	} else if season == "" {
+		return models.Title{}, ErrTitleSeasonRequired
+	}
+	title := models.Title{ID: def.ID, Season: season, GrantedAt: time.Now()}
+	found := s.userRepo.Modify(username, func(user *models.User) bool {
+		if i := slices.IndexFunc(user.Titles, func(t models.Title) bool { return t.Key() == title.Key() }); i >= 0 {
+			title = user.Titles[i]
+			return false
+		}
+		user.Titles = append(slices.Clone(user.Titles), title)
+		return true
+	})
+	if !found && s.userRepo.FindByUsername(username) == nil {
+		return models.Title{}, ErrTitleUserNotFound
+	}
+	return title, nil
+}
+
+// AwardAchievements 按用户的全部战绩检查成就，授予尚未拥有的成就称号
+func (s *titleService) AwardAchievements(username string) []models.Title {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return nil
+	}
+	stats := computeStats(user.UserID, username, s.resultRepo.FindByUser(user.UserID, username))
+	var earned []models.Title
+	now := time.Now()
+	for _, def := range titleCatalog {
+		owned := slices.ContainsFunc(user.Titles, func(t models.Title) bool { return t.ID == def.ID })
+		if def.earned != nil && !owned && def.earned(stats) {
+			earned = append(earned, models.Title{ID: def.ID, GrantedAt: now})
+		}
+	}
+	if len(earned) == 0 {
+		return nil
+	}
+	s.userRepo.Modify(username, func(user *models.User) bool {
+		titles := slices.Clone(user.Titles)
+		for _, t := range earned {
+			if !slices.ContainsFunc(titles, func(owned models.Title) bool { return owned.Key() == t.Key() }) {
+				titles = append(titles, t)
+			}
+		}
+		user.Titles = titles
+		return true
+	})
+	return earned
+}
+
+// Active 返回用户当前装备的称号的展示文本
+func (s *titleService) Active(username string) string {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil || user.ActiveTitle == "" {
+		return ""
+	}
+	for _, t := range user.Titles {
+		if t.Key() == user.ActiveTitle {
+			return TitleDisplay(t)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"time"
+
+	"game/models"
+)
+
+// RunSchedule 推进所有启用自动赛程、正在进行的赛事
+func (s *tournamentService) RunSchedule(now time.Time, lead time.Duration) {
+	if s.host == nil {
+		return
+	}
+	for _, t := range s.tournamentRepo.All() {
+		if t.Status == models.TournamentSeeded && !t.StartsAt.IsZero() {
+			s.runSchedule(t.ID, now, lead)
+		}
+	}
+}
+
+// runSchedule 推进一个赛事的赛程。安排开赛时间和通知在存储锁内完成，创建房间和判负在锁外逐场进行
+func (s *tournamentService) runSchedule(id string, now time.Time, lead time.Duration) {
+	var notify []models.BracketMatch
+	var result models.Tournament
+	s.tournamentRepo.Modify(id, func(t *models.Tournament) bool {
+		grace := time.Duration(t.GraceMinutes) * time.Minute
+		changed := false
+		for i := range t.Matches {
+			m := &t.Matches[i]
+			switch {
+			case m.RoomID != "" && m.Winner == "" && !s.host.RoomOpen(m.RoomID):
+				// 双方都离开后比赛房间已解散，重新开赛并再给一次宽限时间
+				m.RoomID = ""
+				m.ScheduledAt = now
+				m.Deadline = now.Add(grace)
+				changed = true
+			case m.ScheduledAt.IsZero() && schedulable(*t, i):
+				m.ScheduledAt = now.Add(time.Duration(t.BreakMinutes) * time.Minute)
+				if now.Before(t.StartsAt) {
+					m.ScheduledAt = t.StartsAt
+				}
+				m.Deadline = m.ScheduledAt.Add(grace)
+				changed = true
+			}
+			if !m.ScheduledAt.IsZero() && !m.Notified && m.Winner == "" && !now.Before(m.ScheduledAt.Add(-lead)) {
+				m.Notified = true
+				notify = append(notify, *m)
+				changed = true
+			}
+		}
+		if changed {
+			result = t.Clone()
+		}
+		return changed
+	})
+	if result.ID != "" {
+		for _, m := range notify {
+			s.host.NotifyMatch(result, m)
+		}
+		s.publish(result)
+	}
+
+	t, ok := s.tournamentRepo.Get(id)
+	if !ok {
+		return
+	}
+	for _, m := range t.Matches {
+		if m.ScheduledAt.IsZero() || now.Before(m.ScheduledAt) || !startable(m) {
+			continue
+		}
+		ready1, ready2 := s.host.MatchReady(m.Player1), s.host.MatchReady(m.Player2)
+		switch {
+		case ready1 && ready2:
+			// 创建失败时下一次检查重试
+			s.StartMatch(id, m.ID)
+		case now.Before(m.Deadline):
+			// 宽限时间内继续等待缺席的一方
+		case ready1:
+			s.forfeit(id, m.ID, m.Player1)
+		case ready2:
+			s.forfeit(id, m.ID, m.Player2)
+		case m.Seed2 < m.Seed1:
+			s.forfeit(id, m.ID, m.Player2)
+		default:
+			s.forfeit(id, m.ID, m.Player1)
+		}
+	}
+}
+
+// schedulable 比赛双方已决出、尚未开始，且双方都没有排在前面还未结束的比赛。
+// 循环赛中每名玩家同时有多场比赛，按轮次依次安排
+func schedulable(t models.Tournament, i int) bool {
+	m := t.Matches[i]
+	if !startable(m) {
+		return false
+	}
+	for _, prev := range t.Matches[:i] {
+		if prev.Winner != "" || prev.Bye {
+			continue
+		}
+		for _, p := range []string{prev.Player1, prev.Player2} {
+			if p != "" && (p == m.Player1 || p == m.Player2) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// forfeit 比赛超过宽限时间仍未开始，判 winner 获胜；比赛已经开始或结束时不做修改
+func (s *tournamentService) forfeit(id, matchID, winner string) {
+	var result models.Tournament
+	s.tournamentRepo.Modify(id, func(t *models.Tournament) bool {
+		i := t.Match(matchID)
+		if i < 0 || !startable(t.Matches[i]) {
+			return false
+		}
+		t.Matches[i].Winner = winner
+		t.Matches[i].Forfeit = true
+		recordWinner(t, i)
+		result = t.Clone()
+		return true
+	})
+	if result.ID != "" {
+		s.publish(result)
+	}
+}
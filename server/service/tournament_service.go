@@ -0,0 +1,477 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"game/models"
+	"game/protocol"
+	"game/repository"
+)
+
+// maxTournamentName 赛事名称的最大长度（字符数）
+const maxTournamentName = 32
+
+// minGroupSize 循环赛分组时每组人数上限的最小值，保证每个小组至少有 2 名玩家
+const minGroupSize = 3
+
+var (
+	// ErrTournamentNotFound 赛事不存在
+	ErrTournamentNotFound = errors.New("赛事不存在")
+	// ErrTournamentName 赛事名称为空或过长
+	ErrTournamentName = errors.New("赛事名称不能为空且不能超过 32 个字符")
+	// ErrTournamentFormat 不支持的赛制
+	ErrTournamentFormat = errors.New("赛制只能是 single_elimination、double_elimination 或 round_robin")
+	// ErrTournamentGroupSize 循环赛每组人数上限过小
+	ErrTournamentGroupSize = errors.New("循环赛每组人数上限为 0 或不少于 3 人")
+	// ErrTournamentSchedule 自动赛程的间隔或宽限时间为负数
+	ErrTournamentSchedule = errors.New("赛程的间隔和宽限时间不能为负数")
+	// ErrTournamentClosed 赛事已生成对阵表，不再接受报名
+	ErrTournamentClosed = errors.New("赛事报名已截止")
+	// ErrTournamentFull 报名人数已达上限
+	ErrTournamentFull = errors.New("赛事报名人数已满")
+	// ErrTournamentRegistered 用户已报名该赛事
+	ErrTournamentRegistered = errors.New("已报名该赛事")
+	// ErrTournamentTooFew 报名人数不足，无法生成对阵表
+	ErrTournamentTooFew = errors.New("报名人数不足 2 人，无法生成对阵表")
+	// ErrTournamentUserNotFound 用户不存在
+	ErrTournamentUserNotFound = errors.New("用户不存在")
+	// ErrTournamentSession 登录会话无效
+	ErrTournamentSession = errors.New("会话已失效，请重新登录")
+	// ErrTournamentNotRunning 赛事尚未生成对阵表或已经结束
+	ErrTournamentNotRunning = errors.New("赛事未在进行中")
+	// ErrBracketMatchNotFound 对阵表中没有该比赛
+	ErrBracketMatchNotFound = errors.New("比赛不存在")
+	// ErrBracketMatchNotReady 比赛双方尚未决出，或比赛已经开始或结束
+	ErrBracketMatchNotReady = errors.New("比赛双方尚未决出或比赛已开始")
+	// ErrTournamentHost 没有设置为比赛创建房间的一方
+	ErrTournamentHost = errors.New("赛事房间服务不可用")
+)
+
+// RatingSource 由连接层实现，提供玩家当前的匹配评分
+type RatingSource interface {
+	Rating(username string) int
+}
+
+// BracketPublisher 由连接层实现，把对阵表推送给在线的报名玩家
+type BracketPublisher interface {
+	PublishBracket(t models.Tournament)
+}
+
+// TournamentHost 由连接层实现，为赛事比赛创建房间并把双方拉入房间，返回房间ID
+type TournamentHost interface {
+	OpenTournamentMatch(t models.Tournament, m models.BracketMatch) (string, error)
+	// MatchReady 玩家在线且不在其他房间中，可以开始比赛
+	MatchReady(username string) bool
+	// RoomOpen 房间是否仍然存在
+	RoomOpen(roomID string) bool
+	// NotifyMatch 通知双方自动赛程的比赛即将开始
+	NotifyMatch(t models.Tournament, m models.BracketMatch)
+}
+
+// LiveMatch 正在进行的赛事比赛
+type LiveMatch struct {
+	TournamentID   string
+	TournamentName string
+	Match          models.BracketMatch
+}
+
+// TournamentService 定义赛事业务逻辑接口
+type TournamentService interface {
+	// Create 创建报名中的赛事，赛制为空时为单败淘汰，分组人数只用于循环赛，设置开赛时间时启用自动赛程
+	Create(req protocol.CreateTournamentRequest) (models.Tournament, error)
+	// List 返回所有赛事，按创建时间排列
+	List() []models.Tournament
+	// Get 根据ID查找赛事，不存在时返回 ErrTournamentNotFound
+	Get(id string) (models.Tournament, error)
+	// Register 校验登录会话后为用户报名赛事
+	Register(id, username, sessionID string) (models.Tournament, error)
+	// Seed 截止报名，按评分确定种子并按赛制生成对阵表，随后推送给报名玩家
+	Seed(id string) (models.Tournament, error)
+	// StartMatch 为双方都已决出的比赛创建房间，对阵表随即推送
+	StartMatch(id, matchID string) (models.Tournament, error)
+	// ReportResult 记录比赛房间的对局结果，胜者晋级下一轮；没有胜者时比赛仍在原房间进行，双方可以再次开局
+	ReportResult(id, matchID, roomID, winner string) (models.Tournament, error)
+	// LiveMatches 返回所有赛事中正在进行的比赛
+	LiveMatches() []LiveMatch
+	// RunSchedule 推进所有启用自动赛程的赛事：为就绪的比赛安排开赛时间，提前 lead 通知双方，
+	// 到时间创建房间，超过宽限时间仍缺席的一方判负。由连接层定期调用
+	RunSchedule(now time.Time, lead time.Duration)
+	// RemoveUser 注销账号时调用：报名中的赛事移除用户的报名，已生成对阵表的赛事保留对阵，
+	// 把用户在报名、对阵和冠军中的名字替换为 alias，返回修改的赛事数
+	RemoveUser(userID, alias string) int
+	// SetRatings 设置评分来源，Hub 创建后注入
+	SetRatings(ratings RatingSource)
+	// SetBracketPublisher 设置对阵表的推送方，Hub 创建后注入
+	SetBracketPublisher(publisher BracketPublisher)
+	// SetHost 设置为比赛创建房间的一方，Hub 创建后注入
+	SetHost(host TournamentHost)
+}
+
+// tournamentService 实现 TournamentService 接口
+type tournamentService struct {
+	tournamentRepo repository.TournamentRepository
+	userRepo       repository.UserRepository
+	sessionRepo    repository.SessionRepository
+	ratings        RatingSource
+	publisher      BracketPublisher
+	host           TournamentHost
+}
+
+// NewTournamentService 创建 TournamentService 实例
+func NewTournamentService(tournamentRepo repository.TournamentRepository, userRepo repository.UserRepository, sessionRepo repository.SessionRepository) TournamentService {
+	return &tournamentService{tournamentRepo: tournamentRepo, userRepo: userRepo, sessionRepo: sessionRepo}
+}
+
+// SetRatings 设置评分来源
+func (s *tournamentService) SetRatings(ratings RatingSource) {
+	s.ratings = ratings
+}
+
+// SetBracketPublisher 设置对阵表的推送方
+func (s *tournamentService) SetBracketPublisher(publisher BracketPublisher) {
+	s.publisher = publisher
+}
+
+// SetHost 设置为比赛创建房间的一方
+func (s *tournamentService) SetHost(host TournamentHost) {
+	s.host = host
+}
+
+// Create 创建报名中的赛事
+func (s *tournamentService) Create(req protocol.CreateTournamentRequest) (models.Tournament, error) {
+	name, format, groupSize := strings.TrimSpace(req.Name), req.Format, req.GroupSize
+	if name == "" || utf8.RuneCountInString(name) > maxTournamentName {
+		return models.Tournament{}, ErrTournamentName
+	}
+	switch format {
+	case "":
+		format = models.TournamentSingleElimination
+	case models.TournamentSingleElimination, models.TournamentDoubleElimination, models.TournamentRoundRobin:
+	default:
+		return models.Tournament{}, ErrTournamentFormat
+	}
+	if format != models.TournamentRoundRobin {
+		groupSize = 0
+	} else if groupSize < 0 || groupSize > 0 && groupSize < minGroupSize {
+		return models.Tournament{}, ErrTournamentGroupSize
+	}
+	if req.BreakMinutes < 0 || req.GraceMinutes < 0 {
+		return models.Tournament{}, ErrTournamentSchedule
+	}
+	now := time.Now()
+	t := models.Tournament{
+		ID:          fmt.Sprintf("tournament_%d", now.UnixNano()),
+		Name:        name,
+		Format:      format,
+		Status:      models.TournamentOpen,
+		MaxEntrants: max(req.MaxEntrants, 0),
+		GroupSize:   groupSize,
+		CreatedAt:   now,
+	}
+	if req.StartsAt != nil {
+		t.StartsAt = *req.StartsAt
+		t.BreakMinutes = req.BreakMinutes
+		t.GraceMinutes = req.GraceMinutes
+	}
+	s.tournamentRepo.Create(t)
+	return t, nil
+}
+
+// List 返回所有赛事
+func (s *tournamentService) List() []models.Tournament {
+	return s.tournamentRepo.All()
+}
+
+// Get 根据ID查找赛事
+func (s *tournamentService) Get(id string) (models.Tournament, error) {
+	t, ok := s.tournamentRepo.Get(id)
+	if !ok {
+		return models.Tournament{}, ErrTournamentNotFound
+	}
+	return t, nil
+}
+
+// Register 为用户报名赛事，报名按稳定用户ID去重
+func (s *tournamentService) Register(id, username, sessionID string) (models.Tournament, error) {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return models.Tournament{}, ErrTournamentUserNotFound
+	}
+	if session := s.sessionRepo.Get(sessionID); session == nil || session.UserID != user.UserID {
+		return models.Tournament{}, ErrTournamentSession
+	}
+
+	var result models.Tournament
+	var err error
+	found := s.tournamentRepo.Modify(id, func(t *models.Tournament) bool {
+		switch {
+		case t.Status != models.TournamentOpen:
+			err = ErrTournamentClosed
+		case t.Registered(user.UserID):
+			err = ErrTournamentRegistered
+		case t.MaxEntrants > 0 && len(t.Entrants) >= t.MaxEntrants:
+			err = ErrTournamentFull
+		default:
+			t.Entrants = append(t.Entrants, models.TournamentEntrant{
+				UserID:       user.UserID,
+				Username:     user.Username,
+				RegisteredAt: time.Now(),
+			})
+			result = t.Clone()
+			return true
+		}
+		return false
+	})
+	if !found {
+		return models.Tournament{}, ErrTournamentNotFound
+	}
+	return result, err
+}
+
+// Seed 截止报名并生成对阵表。种子按生成时的评分确定，之后评分变化不影响对阵
+func (s *tournamentService) Seed(id string) (models.Tournament, error) {
+	var rating func(username string) int
+	if s.ratings != nil {
+		rating = s.ratings.Rating
+	}
+
+	var result models.Tournament
+	var err error
+	found := s.tournamentRepo.Modify(id, func(t *models.Tournament) bool {
+		switch {
+		case t.Status != models.TournamentOpen:
+			err = ErrTournamentClosed
+		case len(t.Entrants) < 2:
+			err = ErrTournamentTooFew
+		default:
+			t.Entrants = seedEntrants(t.Entrants, rating)
+			t.Matches = generateBracket(*t)
+			t.Status = models.TournamentSeeded
+			t.SeededAt = time.Now()
+			result = t.Clone()
+			return true
+		}
+		return false
+	})
+	if !found {
+		return models.Tournament{}, ErrTournamentNotFound
+	}
+	if err != nil {
+		return models.Tournament{}, err
+	}
+	s.publish(result)
+	return result, nil
+}
+
+// StartMatch 为比赛创建房间。房间在赛事存储锁外创建，记录房间时再次确认比赛没有被同时开始
+func (s *tournamentService) StartMatch(id, matchID string) (models.Tournament, error) {
+	t, ok := s.tournamentRepo.Get(id)
+	if !ok {
+		return models.Tournament{}, ErrTournamentNotFound
+	}
+	if t.Status != models.TournamentSeeded {
+		return models.Tournament{}, ErrTournamentNotRunning
+	}
+	i := t.Match(matchID)
+	if i < 0 {
+		return models.Tournament{}, ErrBracketMatchNotFound
+	}
+	if !startable(t.Matches[i]) {
+		return models.Tournament{}, ErrBracketMatchNotReady
+	}
+	if s.host == nil {
+		return models.Tournament{}, ErrTournamentHost
+	}
+	roomID, err := s.host.OpenTournamentMatch(t, t.Matches[i])
+	if err != nil {
+		return models.Tournament{}, err
+	}
+
+	var result models.Tournament
+	s.tournamentRepo.Modify(id, func(t *models.Tournament) bool {
+		i := t.Match(matchID)
+		if i < 0 || !startable(t.Matches[i]) {
+			err = ErrBracketMatchNotReady
+			return false
+		}
+		t.Matches[i].RoomID = roomID
+		result = t.Clone()
+		return true
+	})
+	if err != nil {
+		return models.Tournament{}, err
+	}
+	s.publish(result)
+	return result, nil
+}
+
+// startable 比赛双方都已决出、尚未开始且没有胜者
+func startable(m models.BracketMatch) bool {
+	return m.Player1 != "" && m.Player2 != "" && m.Winner == "" && m.RoomID == ""
+}
+
+// ReportResult 记录比赛结果，只接受当前进行这场比赛的房间上报的结果；按赛制决出冠军后赛事结束
+func (s *tournamentService) ReportResult(id, matchID, roomID, winner string) (models.Tournament, error) {
+	var result models.Tournament
+	var err error
+	found := s.tournamentRepo.Modify(id, func(t *models.Tournament) bool {
+		i := t.Match(matchID)
+		if i < 0 {
+			err = ErrBracketMatchNotFound
+			return false
+		}
+		m := &t.Matches[i]
+		if m.RoomID != roomID || m.Winner != "" {
+			err = ErrBracketMatchNotReady
+			return false
+		}
+		if winner != m.Player1 && winner != m.Player2 {
+			// 中止或平局的对局不决定胜负，双方留在原房间再次开局
+			result = t.Clone()
+			return false
+		}
+		m.Winner = winner
+		recordWinner(t, i)
+		result = t.Clone()
+		return true
+	})
+	if !found {
+		return models.Tournament{}, ErrTournamentNotFound
+	}
+	if err != nil {
+		return models.Tournament{}, err
+	}
+	s.publish(result)
+	return result, nil
+}
+
+// LiveMatches 返回正在进行的比赛，按赛事创建时间和对阵表顺序排列
+func (s *tournamentService) LiveMatches() []LiveMatch {
+	var live []LiveMatch
+	for _, t := range s.tournamentRepo.All() {
+		if t.Status != models.TournamentSeeded {
+			continue
+		}
+		for _, m := range t.Matches {
+			if m.RoomID != "" && m.Winner == "" {
+				live = append(live, LiveMatch{TournamentID: t.ID, TournamentName: t.Name, Match: m})
+			}
+		}
+	}
+	return live
+}
+
+// publish 推送对阵表
+func (s *tournamentService) publish(t models.Tournament) {
+	if s.publisher != nil {
+		s.publisher.PublishBracket(t)
+	}
+}
+
+// TournamentInfo 将赛事转换为协议中的赛事信息
+func TournamentInfo(t models.Tournament) protocol.TournamentInfo {
+	info := protocol.TournamentInfo{
+		ID:          t.ID,
+		Name:        t.Name,
+		Format:      t.Format,
+		Status:      t.Status,
+		MaxEntrants: t.MaxEntrants,
+		GroupSize:   t.GroupSize,
+		CreatedAt:   t.CreatedAt,
+		Standings:   standings(t),
+		Champion:    t.Champion,
+		Entrants:    make([]protocol.TournamentEntrantInfo, 0, len(t.Entrants)),
+
+		StartsAt:     optionalTime(t.StartsAt),
+		BreakMinutes: t.BreakMinutes,
+		GraceMinutes: t.GraceMinutes,
+	}
+	for _, e := range t.Entrants {
+		info.Entrants = append(info.Entrants, protocol.TournamentEntrantInfo{Username: e.Username, Seed: e.Seed, Rating: e.Rating})
+	}
+	for _, m := range t.Matches {
+		info.Rounds = max(info.Rounds, m.Round)
+		info.Matches = append(info.Matches, BracketMatchInfo(m))
+	}
+	return info
+}
+
+// BracketMatchInfo 将对阵表中的比赛转换为协议中的格式
+func BracketMatchInfo(m models.BracketMatch) protocol.BracketMatchInfo {
+	return protocol.BracketMatchInfo{
+		ID:      m.ID,
+		Bracket: m.Bracket,
+		Group:   m.Group,
+		Round:   m.Round,
+		Slot:    m.Slot,
+		Player1: m.Player1,
+		Player2: m.Player2,
+		Seed1:   m.Seed1,
+		Seed2:   m.Seed2,
+		Bye:     m.Bye,
+		Winner:  m.Winner,
+		RoomID:  m.RoomID,
+
+		ScheduledAt: optionalTime(m.ScheduledAt),
+		Deadline:    optionalTime(m.Deadline),
+		Forfeit:     m.Forfeit,
+	}
+}
+
+// optionalTime 零值时间返回 nil，在协议中省略
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// RemoveUser 从所有报名过的赛事中移除或匿名化用户
+func (s *tournamentService) RemoveUser(userID, alias string) int {
+	changed := 0
+	for _, t := range s.tournamentRepo.All() {
+		if !t.Registered(userID) {
+			continue
+		}
+		if s.tournamentRepo.Modify(t.ID, func(t *models.Tournament) bool { return removeEntrant(t, userID, alias) }) {
+			changed++
+		}
+	}
+	return changed
+}
+
+// removeEntrant 报名中的赛事直接删除用户的报名；已生成对阵表时删除会打乱对阵，
+// 只把报名、对阵和冠军中的用户名替换为 alias 并清除用户ID。返回用户是否报名了该赛事
+func removeEntrant(t *models.Tournament, userID, alias string) bool {
+	i := slices.IndexFunc(t.Entrants, func(e models.TournamentEntrant) bool { return e.UserID == userID })
+	if i < 0 {
+		return false
+	}
+	if t.Status == models.TournamentOpen {
+		t.Entrants = slices.Delete(t.Entrants, i, i+1)
+		return true
+	}
+	name := t.Entrants[i].Username
+	t.Entrants[i].UserID, t.Entrants[i].Username = "", alias
+	for j := range t.Matches {
+		m := &t.Matches[j]
+		if m.Player1 == name {
+			m.Player1 = alias
+		}
+		if m.Player2 == name {
+			m.Player2 = alias
+		}
+		if m.Winner == name {
+			m.Winner = alias
+		}
+	}
+	if t.Champion == name {
+		t.Champion = alias
+	}
+	return true
+}
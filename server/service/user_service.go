@@ -2,80 +2,231 @@ package service
 
 import (
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"game/mail"
 	"game/models"
 	"game/protocol"
 	"game/repository"
+	"game/validate"
+	"log"
+	"slices"
 	"strings"
 	"time"
 )
 
+// SessionInvalidator 由连接层实现，用于强制断开用户的在线连接
+type SessionInvalidator interface {
+	DisconnectUser(username string, reason string)
+	// DisconnectSession 只断开使用指定登录会话建立的连接
+	DisconnectSession(sessionID string, reason string)
+	// DisconnectOthers 断开用户除 keepSessionID 会话之外的所有连接，keepSessionID 为空时断开全部连接
+	DisconnectOthers(username, keepSessionID string, reason string)
+}
+
+// UserDataEraser 由连接层实现，注销账号时删除或匿名化用户在邀请、挑战、天梯、赛事、练习、
+// 作弊标记、申诉和流量录制中留下的数据。names 为用户当前及曾用的用户名，alias 为匿名化后的名字
+type UserDataEraser interface {
+	EraseUser(userID string, names []string, alias string)
+}
+
+// UserDataExporter 由连接层实现，导出数据时补充用户在邀请、天梯、赛事、申诉和流量录制中的数据，
+// names 为用户当前及曾用的用户名
+type UserDataExporter interface {
+	ExportUser(userID string, names []string, data *UserData)
+}
+
 // UserService 定义用户业务逻辑接口
 type UserService interface {
-	Register(req protocol.RegisterRequest) (bool, string)
+	Register(req protocol.RegisterRequest) (bool, string, string)
 	Login(req protocol.LoginRequest) (bool, string, string)
 	Logout(username string)
+	DeleteAccount(req protocol.DeleteAccountRequest) (bool, string)
+	DeleteUser(username string) bool
+	SetSessionInvalidator(sessions SessionInvalidator)
+	SetUserDataEraser(eraser UserDataEraser)
+	SetUserDataExporter(exporter UserDataExporter)
+	ExportData(username string) *UserData
+	// StartSession 登录成功后记录一次登录会话及其设备信息和区域
+	StartSession(username, userAgent, ip, region string) models.Session
+	ListSessions(username string) []models.Session
+	// Authorize 校验登录会话属于该用户，查看或修改账号数据前调用
+	Authorize(username, sessionID string) bool
+	// RevokeSession 注销用户的指定会话并断开对应连接，handle 为 SessionHandle 返回的会话句柄；
+	// 会话不存在或不属于该用户时返回 false
+	RevokeSession(username, handle string) bool
+	// ChangeEmail 校验密码后向新邮箱发送确认令牌，确认前邮箱不变
+	ChangeEmail(req protocol.ChangeEmailRequest) (bool, string)
+	// ConfirmEmail 使用确认令牌把邮箱替换为待确认的新邮箱
+	ConfirmEmail(req protocol.ConfirmEmailRequest) (bool, string)
+	// IssueRefreshToken 为登录成功的用户签发新的刷新令牌
+	IssueRefreshToken(username, userAgent string) string
+	// Refresh 使用刷新令牌重新登录，返回是否成功、提示信息、用户名和轮换后的新令牌
+	Refresh(refreshToken, userAgent string) (bool, string, string, string)
+	// RecordLogin 记录一次登录尝试，成功登录来自用户从未使用过的 IP 时发邮件提醒
+	RecordLogin(record models.LoginRecord)
+	// LoginHistory 返回用户最近的登录记录，最新的在前
+	LoginHistory(username string) []models.LoginRecord
+	// ChangePassword 校验旧密码后修改密码，并注销发起请求的会话之外的所有会话，返回是否成功、提示信息和违规代码
+	ChangePassword(req protocol.ChangePasswordRequest) (bool, string, string)
+	// ChangeUsername 校验密码后修改用户名，旧用户名记入历史，冷却期内不能再次修改
+	ChangeUsername(req protocol.ChangeUsernameRequest) (bool, string)
+	// LookupUser 按当前或曾用的用户名查找用户
+	LookupUser(username string) *models.User
+	// RecentOpponents 根据用户最近的对局结果返回交手过的真人对手，最近交手的在前
+	RecentOpponents(username string) []RecentOpponent
+	// RejoinRoom 返回用户上次所在、仍然存在且可以重新进入的房间，没有时返回 nil
+	RejoinRoom(username string) *models.Room
+	// RoomFilters 返回用户保存的房间列表筛选条件
+	RoomFilters(username string) []models.RoomFilter
+	// SaveRoomFilter 校验登录会话后保存房间列表筛选条件，同名的条件会被覆盖
+	SaveRoomFilter(username, sessionID string, filter models.RoomFilter) (bool, string)
+	// DeleteRoomFilter 校验登录会话后删除房间列表筛选条件
+	DeleteRoomFilter(username, sessionID, name string) (bool, string)
+	// SetSearchPrivacy 校验登录会话后设置是否在玩家搜索中隐藏
+	SetSearchPrivacy(username, sessionID string, hide bool) (bool, string)
+}
+
+// 令牌有效期：修改邮箱的确认令牌，以及记住登录的刷新令牌
+const (
+	emailTokenTTL   = 24 * time.Hour
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// usernameChangeCooldown 两次修改用户名之间的最短间隔
+const usernameChangeCooldown = 30 * 24 * time.Hour
+
+// UserData 汇总一个用户在各个存储中的数据，用于数据导出
+type UserData struct {
+	User     models.User // 包括称号和保存的房间筛选条件
+	Stats    models.PlayerStats
+	Results  []models.GameResult
+	Sessions []models.Session
+	Logins   []models.LoginRecord
+
+	// 以下由 UserDataExporter 补充
+	Invites        []models.Invite            // 用户发出或收到的房间邀请
+	LadderPosition int                        // 天梯排名，0 表示不在天梯上
+	Tournaments    []models.Tournament        // 报名过的赛事
+	Disputes       []models.Dispute           // 用户提出的申诉
+	Chat           []protocol.ChatMessageInfo // 流量录制中用户发出的聊天消息，未开启录制时为空
+}
+
+// 最近对手列表：最多返回的对手数量，以及统计时检查的最近对局数量
+const (
+	recentOpponentsLimit   = 20
+	recentOpponentsResults = 100
+)
+
+// RecentOpponent 最近交手过的对手及近期与其交手的次数和胜负
+type RecentOpponent struct {
+	User       models.User
+	LastPlayed time.Time
+	Matches    int
+	Wins       int
+	Losses     int
 }
 
 // userService 实现 UserService 接口
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	roomRepo    repository.RoomRepository
+	resultRepo  repository.ResultRepository
+	sessionRepo repository.SessionRepository
+	sessions    SessionInvalidator
+	eraser      UserDataEraser
+	exporter    UserDataExporter
+	passwords   *validate.PasswordPolicy // 注册以及修改、重置密码时校验新密码
+	mailer      mail.Mailer              // 发送邮箱确认令牌等通知邮件
+	loginRepo   repository.LoginHistoryRepository
+	refreshRepo repository.RefreshTokenRepository
+	avatarRepo  repository.AvatarRepository
+	words       *WordFilter // 注册和修改用户名时检查屏蔽词
 }
 
 // NewUserService 创建 UserService 实例
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+func NewUserService(userRepo repository.UserRepository, roomRepo repository.RoomRepository, resultRepo repository.ResultRepository, sessionRepo repository.SessionRepository, passwords *validate.PasswordPolicy, mailer mail.Mailer, loginRepo repository.LoginHistoryRepository, refreshRepo repository.RefreshTokenRepository, avatarRepo repository.AvatarRepository, words *WordFilter) UserService {
+	return &userService{
+		userRepo:    userRepo,
+		roomRepo:    roomRepo,
+		resultRepo:  resultRepo,
+		sessionRepo: sessionRepo,
+		passwords:   passwords,
+		mailer:      mailer,
+		loginRepo:   loginRepo,
+		refreshRepo: refreshRepo,
+		avatarRepo:  avatarRepo,
+		words:       words,
+	}
 }
 
-// Register 处理用户注册逻辑
-func (s *userService) Register(req protocol.RegisterRequest) (bool, string) {
-	// 验证用户名是否包含空格
-	if strings.Contains(req.Username, " ") {
-		return false, "用户名不能包含空格"
-	}
+// SetSessionInvalidator 设置连接层的下线回调，Hub 创建后注入
+func (s *userService) SetSessionInvalidator(sessions SessionInvalidator) {
+	s.sessions = sessions
+}
 
-	// 验证用户名长度
-	if len(req.Username) < 3 || len(req.Username) > 20 {
-		return false, "用户名长度必须在3-20之间"
-	}
+// SetUserDataEraser 设置注销账号时清理其他存储的回调，Hub 创建后注入
+func (s *userService) SetUserDataEraser(eraser UserDataEraser) {
+	s.eraser = eraser
+}
 
-	// 验证密码长度
-	if len(req.Password) < 6 {
-		return false, "密码长度至少6位"
-	}
+// SetUserDataExporter 设置导出数据时补充其他存储数据的回调，Hub 创建后注入
+func (s *userService) SetUserDataExporter(exporter UserDataExporter) {
+	s.exporter = exporter
+}
 
-	// 验证邮箱格式
-	if !strings.Contains(req.Email, "@") {
-		return false, "邮箱格式不正确"
+// Register 处理用户注册逻辑，返回是否成功、提示信息和失败代码（目前只有违反密码策略时有代码）
+func (s *userService) Register(req protocol.RegisterRequest) (bool, string, string) {
+	// 验证用户名：长度、字符、保留名、屏蔽词
+	if err := validate.Username(req.Username); err != nil {
+		return false, err.Error(), ""
 	}
-
-	// 检查用户名是否已存在
-	if existingUser := s.userRepo.FindByUsername(req.Username); existingUser != nil {
-		return false, "用户名已存在"
+	if err := s.words.CheckName(req.Username); err != nil {
+		return false, err.Error(), ""
 	}
 
-	// 检查邮箱是否已被注册
-	if existingEmail := s.userRepo.FindByEmail(req.Email); existingEmail != nil {
-		return false, "该邮箱已被注册"
+	// 验证密码是否符合密码策略
+	if msg, code := s.checkPassword(req.Username, req.Password); code != "" {
+		return false, msg, code
 	}
 
-	// MD5加密密码
-	passwordHash := md5.Sum([]byte(req.Password))
-	passwordHex := hex.EncodeToString(passwordHash[:])
+	// 验证邮箱格式
+	email, err := validate.Email(req.Email)
+	if err != nil {
+		return false, err.Error(), ""
+	}
 
 	// 创建新用户
 	user := models.User{
 		Username:  req.Username,
-		Password:  passwordHex,
-		Email:     req.Email,
+		Password:  hashPassword(req.Password),
+		Email:     email,
 		Online:    false,
 		LoginTime: time.Time{},
 		RoomID:    "",
 	}
 
-	// 保存用户
-	s.userRepo.Add(user)
-	return true, "注册成功"
+	// 保存用户，用户名和邮箱不区分大小写唯一
+	if err := s.userRepo.Create(user); err != nil {
+		if errors.Is(err, repository.ErrUsernameTaken) || errors.Is(err, repository.ErrEmailTaken) {
+			return false, err.Error(), ""
+		}
+		log.Printf("保存用户 %s 失败: %v", req.Username, err)
+		return false, "注册失败，请稍后重试", ""
+	}
+	return true, "注册成功", ""
+}
+
+// checkPassword 按密码策略校验新密码，不符合时返回提示信息和违规代码，符合时代码为空
+func (s *userService) checkPassword(username, password string) (string, string) {
+	if err := s.passwords.Check(username, password); err != nil {
+		return err.Message, err.Code
+	}
+	return "", ""
 }
 
 // Login 处理用户登录逻辑
@@ -87,32 +238,732 @@ func (s *userService) Login(req protocol.LoginRequest) (bool, string, string) {
 	}
 
 	// MD5加密输入密码并验证
-	passwordHash := md5.Sum([]byte(req.Password))
-	passwordHex := hex.EncodeToString(passwordHash[:])
-	if user.Password != passwordHex {
+	if user.Password != hashPassword(req.Password) {
 		return false, "密码错误", ""
 	}
 
-	// 检查用户是否已在线
-	if user.Online {
+	if !markOnline(s.userRepo, req.Username) {
 		return false, "用户已登录", ""
 	}
 
-	// 更新用户状态
-	user.Online = true
-	user.LoginTime = time.Now()
-	user.RoomID = ""
-	s.userRepo.Update(req.Username, *user)
-
 	return true, "登录成功", req.Username
 }
 
-// Logout 处理用户登出逻辑
+// markOnline 在存储锁内检查并更新在线状态，避免同一账号并发登录；用户已在线时返回 false
+func markOnline(userRepo repository.UserRepository, username string) bool {
+	return userRepo.Modify(username, func(user *models.User) bool {
+		if user.Online {
+			return false
+		}
+		user.Online = true
+		user.LoginTime = time.Now()
+		user.RoomID = ""
+		return true
+	})
+}
+
+// Logout 处理用户登出逻辑，同时结束用户的所有登录会话并吊销刷新令牌
 func (s *userService) Logout(username string) {
-	user := s.userRepo.FindByUsername(username)
-	if user != nil {
+	s.userRepo.Modify(username, func(user *models.User) bool {
 		user.Online = false
 		user.RoomID = ""
-		s.userRepo.Update(username, *user)
+		return true
+	})
+	if id := s.userID(username); id != "" {
+		s.sessionRepo.RemoveUser(id)
+		s.refreshRepo.RemoveUser(id)
+	}
+}
+
+// userID 返回用户名对应的稳定用户ID，用户不存在时返回空字符串
+func (s *userService) userID(username string) string {
+	if user := s.userRepo.FindByUsername(username); user != nil {
+		return user.UserID
+	}
+	return ""
+}
+
+// IssueRefreshToken 签发一个新 Family 的刷新令牌，存储中只保存摘要
+func (s *userService) IssueRefreshToken(username, userAgent string) string {
+	token := newToken()
+	now := time.Now()
+	s.refreshRepo.Add(models.RefreshToken{
+		Hash:      hashToken(token),
+		Family:    newSessionID(),
+		UserID:    s.userID(username),
+		Device:    deviceName(userAgent),
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTokenTTL),
+	})
+	return token
+}
+
+// Refresh 校验并轮换刷新令牌，再按密码登录的规则标记用户在线。轮换成功后即使用户已在线导致登录失败，
+// 也返回新令牌，旧令牌已经失效；已轮换过的令牌被再次使用时整个 Family 被吊销
+func (s *userService) Refresh(refreshToken, userAgent string) (bool, string, string, string) {
+	if refreshToken == "" {
+		return false, "刷新令牌不能为空", "", ""
+	}
+	next := newToken()
+	now := time.Now()
+	rotated := s.refreshRepo.Rotate(hashToken(refreshToken), models.RefreshToken{
+		Hash:      hashToken(next),
+		Device:    deviceName(userAgent),
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTokenTTL),
+	}, now)
+	if rotated == nil {
+		return false, "刷新令牌无效或已过期，请重新登录", "", ""
+	}
+	// 令牌按用户ID关联，用户改名后仍然有效，登录时使用当前用户名
+	user := s.userRepo.FindByID(rotated.UserID)
+	if user == nil {
+		s.refreshRepo.RemoveUser(rotated.UserID)
+		return false, "用户不存在", "", ""
+	}
+	if !markOnline(s.userRepo, user.Username) {
+		return false, "用户已登录", "", next
+	}
+	return true, "登录成功", user.Username, next
+}
+
+// StartSession 记录一次登录会话，区域不为空时同时记为用户最近所在的区域
+func (s *userService) StartSession(username, userAgent, ip, region string) models.Session {
+	now := time.Now()
+	session := models.Session{
+		ID:        newSessionID(),
+		UserID:    s.userID(username),
+		Username:  username,
+		UserAgent: userAgent,
+		Device:    deviceName(userAgent),
+		IP:        ip,
+		CreatedAt: now,
+		LastSeen:  now,
+		Region:    region,
+	}
+	s.sessionRepo.Add(session)
+	if region != "" {
+		s.userRepo.Modify(username, func(user *models.User) bool {
+			if user.Region == region {
+				return false
+			}
+			user.Region = region
+			return true
+		})
+	}
+	return session
+}
+
+// RecordLogin 补全时间和设备后记录一次登录尝试；不存在的用户不记录，避免撞库请求撑大存储。
+// 成功登录的 IP 在该用户之前的成功登录中从未出现过时标记为新 IP，并异步发邮件提醒；首次登录不提醒
+func (s *userService) RecordLogin(record models.LoginRecord) {
+	user := s.userRepo.FindByUsername(record.Username)
+	if user == nil {
+		return
+	}
+	record.UserID = user.UserID
+	record.Time = time.Now()
+	record.Device = deviceName(record.UserAgent)
+	if record.Success {
+		seen, known := false, false
+		for _, previous := range s.loginRepo.ListByUser(user.UserID) {
+			if previous.Success {
+				seen = true
+				known = known || previous.IP == record.IP
+			}
+		}
+		record.NewIP = seen && !known
+	}
+	s.loginRepo.Add(record)
+
+	if record.NewIP && user.Email != "" {
+		body := fmt.Sprintf("你好 %s：\n\n你的账号于 %s 从新的 IP 地址 %s（%s）登录。如果这不是你本人的操作，请立即修改密码。\n",
+			record.Username, record.Time.Format("2006-01-02 15:04"), record.IP, record.Device)
+		go func() {
+			if err := s.mailer.Send(user.Email, "新 IP 登录提醒", body); err != nil {
+				log.Printf("发送用户 %s 的新 IP 登录提醒失败: %v", record.Username, err)
+			}
+		}()
+	}
+}
+
+// LoginHistory 返回用户最近的登录记录，用户不存在时返回空列表
+func (s *userService) LoginHistory(username string) []models.LoginRecord {
+	id := s.userID(username)
+	if id == "" {
+		return make([]models.LoginRecord, 0)
+	}
+	return s.loginRepo.ListByUser(id)
+}
+
+// ListSessions 返回用户当前的登录会话，最新的在前；用户不存在时返回空列表
+func (s *userService) ListSessions(username string) []models.Session {
+	id := s.userID(username)
+	if id == "" {
+		return make([]models.Session, 0)
+	}
+	return s.sessionRepo.ListByUser(id)
+}
+
+// Authorize 校验登录会话属于该用户
+func (s *userService) Authorize(username, sessionID string) bool {
+	id := s.userID(username)
+	if id == "" || sessionID == "" {
+		return false
+	}
+	session := s.sessionRepo.Get(sessionID)
+	return session != nil && session.UserID == id
+}
+
+// SessionHandle 返回会话的句柄：会话ID摘要的前缀。会话ID可以用于认证，列表中只返回句柄，注销时按句柄查找
+func SessionHandle(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// RevokeSession 注销指定会话；用户没有其他会话时同时标记为离线，之后需要重新登录
+func (s *userService) RevokeSession(username, handle string) bool {
+	id := s.userID(username)
+	if id == "" {
+		return false
+	}
+	var session *models.Session
+	for _, candidate := range s.sessionRepo.ListByUser(id) {
+		if SessionHandle(candidate.ID) == handle {
+			session = &candidate
+			break
+		}
+	}
+	if session == nil {
+		return false
+	}
+	sessionID := session.ID
+	s.sessionRepo.Remove(sessionID)
+	if s.sessions != nil {
+		s.sessions.DisconnectSession(sessionID, "会话已被远程注销")
+	}
+	if len(s.sessionRepo.ListByUser(id)) == 0 {
+		s.userRepo.Modify(username, func(user *models.User) bool {
+			if !user.Online {
+				return false
+			}
+			user.Online = false
+			user.RoomID = ""
+			return true
+		})
+	}
+	log.Printf("用户 %s 注销了会话 %s（%s, %s）", username, handle, session.Device, session.IP)
+	return true
+}
+
+// newToken 生成随机的刷新令牌
+func newToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成令牌失败: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
+
+// newSessionID 生成随机会话ID
+func newSessionID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成会话ID失败: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
+
+// deviceName 根据 User-Agent 粗略推断设备类型，用于在会话列表中展示
+func deviceName(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	for _, d := range []struct{ key, name string }{
+		{"android", "Android"},
+		{"iphone", "iPhone"},
+		{"ipad", "iPad"},
+		{"windows", "Windows"},
+		{"mac os", "macOS"},
+		{"linux", "Linux"},
+		{"go-http-client", "Go 客户端"},
+	} {
+		if strings.Contains(ua, d.key) {
+			return d.name
+		}
+	}
+	if ua == "" {
+		return "未知设备"
+	}
+	return "其他设备"
+}
+
+// ChangeEmail 处理修改邮箱请求：确认密码并检查新邮箱未被占用后，记录待确认的新邮箱和令牌摘要，
+// 再把令牌发送到新邮箱；重复申请会使之前的令牌失效
+func (s *userService) ChangeEmail(req protocol.ChangeEmailRequest) (bool, string) {
+	user := s.userRepo.FindByUsername(req.Username)
+	if user == nil {
+		return false, "用户不存在"
+	}
+	if user.Password != hashPassword(req.Password) {
+		return false, "密码错误"
+	}
+	email, err := validate.Email(req.NewEmail)
+	if err != nil {
+		return false, err.Error()
+	}
+	if validate.FoldEmail(email) == validate.FoldEmail(user.Email) {
+		return false, "新邮箱与当前邮箱相同"
+	}
+	if other := s.userRepo.FindByEmail(email); other != nil && other.Username != user.Username {
+		return false, repository.ErrEmailTaken.Error()
+	}
+
+	token := newSessionID()
+	expires := time.Now().Add(emailTokenTTL)
+	if !s.userRepo.Modify(req.Username, func(user *models.User) bool {
+		user.PendingEmail = email
+		user.EmailTokenHash = hashToken(token)
+		user.EmailTokenExpires = expires
+		return true
+	}) {
+		return false, "用户不存在"
+	}
+
+	body := fmt.Sprintf("你好 %s：\n\n你正在把账号邮箱修改为 %s，确认令牌为：\n\n%s\n\n令牌在 %s 前有效。如果这不是你本人的操作，请忽略这封邮件并尽快修改密码。\n",
+		req.Username, email, token, expires.Format("2006-01-02 15:04"))
+	if err := s.mailer.Send(email, "确认修改邮箱", body); err != nil {
+		log.Printf("发送用户 %s 的邮箱确认邮件失败: %v", req.Username, err)
+		return false, "确认邮件发送失败，请稍后重试"
+	}
+	return true, "确认令牌已发送到新邮箱"
+}
+
+// ConfirmEmail 校验令牌后替换邮箱；新邮箱在确认前被其他用户注册时修改失败。
+// 替换成功后通知原邮箱，便于账号被盗用时及时发现
+func (s *userService) ConfirmEmail(req protocol.ConfirmEmailRequest) (bool, string) {
+	user := s.userRepo.FindByUsername(req.Username)
+	if user == nil || user.PendingEmail == "" || req.Token == "" {
+		return false, "没有待确认的邮箱修改"
+	}
+	if !time.Now().Before(user.EmailTokenExpires) {
+		return false, "确认令牌已过期，请重新申请"
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(req.Token)), []byte(user.EmailTokenHash)) != 1 {
+		return false, "确认令牌错误"
+	}
+
+	ok, err := s.userRepo.ChangeEmail(req.Username, user.PendingEmail)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) {
+			return false, err.Error()
+		}
+		log.Printf("修改用户 %s 的邮箱失败: %v", req.Username, err)
+		return false, "修改邮箱失败，请稍后重试"
 	}
+	if !ok {
+		return false, "用户不存在"
+	}
+	log.Printf("用户 %s 的邮箱已修改", req.Username)
+
+	if user.Email != "" {
+		body := fmt.Sprintf("你好 %s：\n\n你的账号邮箱已修改为 %s，之后的通知将发送到新邮箱。如果这不是你本人的操作，请立即联系我们。\n",
+			req.Username, user.PendingEmail)
+		if err := s.mailer.Send(user.Email, "账号邮箱已修改", body); err != nil {
+			log.Printf("通知用户 %s 原邮箱失败: %v", req.Username, err)
+		}
+	}
+	return true, "邮箱已修改"
+}
+
+// ChangePassword 修改密码：旧密码正确且新密码符合密码策略时更新摘要，再注销除 req.SessionID 之外的所有会话
+// 并断开对应连接，其他设备需要用新密码重新登录；SessionID 为空或不属于该用户时注销全部会话
+func (s *userService) ChangePassword(req protocol.ChangePasswordRequest) (bool, string, string) {
+	user := s.userRepo.FindByUsername(req.Username)
+	if user == nil {
+		return false, "用户不存在", ""
+	}
+	if user.Password != hashPassword(req.OldPassword) {
+		return false, "旧密码错误", ""
+	}
+	if req.NewPassword == req.OldPassword {
+		return false, "新密码不能与旧密码相同", ""
+	}
+	if msg, code := s.checkPassword(req.Username, req.NewPassword); code != "" {
+		return false, msg, code
+	}
+
+	hash := hashPassword(req.NewPassword)
+	if !s.userRepo.Modify(req.Username, func(user *models.User) bool {
+		user.Password = hash
+		return true
+	}) {
+		return false, "用户不存在", ""
+	}
+
+	keep := ""
+	if session := s.sessionRepo.Get(req.SessionID); session != nil && session.UserID == user.UserID {
+		keep = session.ID
+	}
+	revoked := 0
+	for _, session := range s.sessionRepo.ListByUser(user.UserID) {
+		if session.ID != keep && s.sessionRepo.Remove(session.ID) {
+			revoked++
+		}
+	}
+	if s.sessions != nil {
+		s.sessions.DisconnectOthers(req.Username, keep, "密码已修改，请重新登录")
+	}
+	// 其他设备上记住的登录同样失效，发起修改的设备需要重新登录时才签发新令牌
+	s.refreshRepo.RemoveUser(user.UserID)
+	if keep == "" {
+		s.userRepo.Modify(req.Username, func(user *models.User) bool {
+			if !user.Online {
+				return false
+			}
+			user.Online = false
+			user.RoomID = ""
+			return true
+		})
+	}
+	log.Printf("用户 %s 修改了密码，注销了 %d 个其他会话", req.Username, revoked)
+	return true, "密码已修改", ""
+}
+
+// ChangeUsername 修改用户名：密码正确、新用户名合法且未被任何用户（包括其旧用户名）占用、距上次修改超过冷却期，
+// 且用户不在进行中的对局里时生效。历史结果先按旧用户名补上稳定用户ID，之后按ID归属；房间随之改用新用户名。
+// 登录历史和刷新令牌按用户ID关联，无需改动；登录会话全部注销，用户需要用新用户名重新登录
+func (s *userService) ChangeUsername(req protocol.ChangeUsernameRequest) (bool, string) {
+	user := s.userRepo.FindByUsername(req.Username)
+	if user == nil {
+		return false, "用户不存在"
+	}
+	if user.Password != hashPassword(req.Password) {
+		return false, "密码错误"
+	}
+	if err := validate.Username(req.NewUsername); err != nil {
+		return false, err.Error()
+	}
+	if err := s.words.CheckName(req.NewUsername); err != nil {
+		return false, err.Error()
+	}
+	if req.NewUsername == user.Username {
+		return false, "新用户名不能与当前用户名相同"
+	}
+	now := time.Now()
+	if last := user.LastRenamed(); !last.IsZero() && now.Sub(last) < usernameChangeCooldown {
+		next := last.Add(usernameChangeCooldown)
+		return false, fmt.Sprintf("修改用户名过于频繁，请在 %s 之后再试", next.Format("2006-01-02 15:04"))
+	}
+	for _, room := range s.roomRepo.GetAll() {
+		if room.Status == "playing" && slices.Contains(room.Players, user.Username) {
+			return false, "对局进行中，不能修改用户名"
+		}
+	}
+
+	oldName := user.Username
+	tagged := s.resultRepo.TagPlayer(oldName, user.UserID)
+	renamed, err := s.userRepo.Rename(oldName, req.NewUsername, now)
+	if err != nil {
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			return false, err.Error()
+		}
+		log.Printf("修改用户名 %s 失败: %v", oldName, err)
+		return false, "修改失败，请稍后重试"
+	}
+	if !renamed {
+		return false, "用户不存在"
+	}
+
+	for _, room := range s.roomRepo.GetAll() {
+		_, err := updateRoom(s.roomRepo, room.ID, func(room *models.Room) bool {
+			return renamePlayer(room, oldName, req.NewUsername)
+		})
+		if err != nil {
+			log.Printf("房间 %s 中的用户 %s 改名失败: %v", room.ID, oldName, err)
+		}
+	}
+	s.sessionRepo.RemoveUser(user.UserID)
+	if s.sessions != nil {
+		s.sessions.DisconnectUser(oldName, "用户名已修改，请使用新用户名重新登录")
+	}
+	log.Printf("用户 %s 改名为 %s，%d 条历史结果记录了用户ID", oldName, req.NewUsername, tagged)
+	return true, "用户名已修改，请使用新用户名重新登录"
+}
+
+// renamePlayer 把房间中的玩家和封禁名单中的 username 改为 newName，返回房间是否有改动
+func renamePlayer(room *models.Room, username, newName string) bool {
+	banned := slices.Index(room.Banned, username)
+	if banned >= 0 {
+		room.Banned[banned] = newName
+	}
+	index := slices.Index(room.Players, username)
+	if index < 0 {
+		return banned >= 0
+	}
+	room.Players[index] = newName
+	if room.HostID == username {
+		room.HostID = newName
+	}
+	if hero, ok := room.Heroes[username]; ok {
+		delete(room.Heroes, username)
+		room.Heroes[newName] = hero
+	}
+	return true
+}
+
+// RejoinRoom 优先返回用户记录的房间（对局中断线等待重连时保留），其次是玩家列表中仍有该用户的未开局房间
+func (s *userService) RejoinRoom(username string) *models.Room {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return nil
+	}
+	if user.RoomID != "" {
+		if room := s.roomRepo.GetByID(user.RoomID); room != nil && slices.Contains(room.Players, username) {
+			return room
+		}
+	}
+	for _, room := range s.roomRepo.GetAll() {
+		if room.Status != "playing" && slices.Contains(room.Players, username) {
+			return &room
+		}
+	}
+	return nil
+}
+
+// LookupUser 按用户名查找用户，当前用户名中找不到时再查曾用名
+func (s *userService) LookupUser(username string) *models.User {
+	if user := s.userRepo.FindByUsername(username); user != nil {
+		return user
+	}
+	return s.userRepo.FindByPreviousName(username)
+}
+
+// RecentOpponents 从最近的对局结果中汇总对手，对手按用户ID合并，改名后仍算同一人；
+// 机器人和已注销的用户找不到账号，不会出现在列表中
+func (s *userService) RecentOpponents(username string) []RecentOpponent {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return nil
+	}
+	results := s.resultRepo.FindByUser(user.UserID, username)
+	if len(results) > recentOpponentsResults {
+		results = results[:recentOpponentsResults]
+	}
+
+	opponents := make([]RecentOpponent, 0)
+	index := make(map[string]int) // 对手用户ID到 opponents 下标
+	for _, r := range results {
+		self := r.NameOf(user.UserID)
+		if self == "" {
+			self = username
+		}
+		for _, opponent := range s.opponentsIn(r, self) {
+			if opponent.UserID == user.UserID {
+				continue
+			}
+			i, ok := index[opponent.UserID]
+			if !ok {
+				if len(opponents) >= recentOpponentsLimit {
+					continue
+				}
+				i = len(opponents)
+				index[opponent.UserID] = i
+				opponents = append(opponents, RecentOpponent{User: *opponent, LastPlayed: r.PlayTime})
+			}
+			opponents[i].Matches++
+			if r.Winner == self {
+				opponents[i].Wins++
+			} else if r.Loser == self {
+				opponents[i].Losses++
+			}
+		}
+	}
+	return opponents
+}
+
+// opponentsIn 返回一局中 self 之外的玩家账号，优先按结果中记录的用户ID查找，早期结果按对局时的用户名查找
+func (s *userService) opponentsIn(r models.GameResult, self string) []*models.User {
+	players := make([]models.PlayerResult, 0, len(r.Players))
+	if len(r.Players) > 0 {
+		players = append(players, r.Players...)
+	} else {
+		players = append(players, models.PlayerResult{Username: r.Winner}, models.PlayerResult{Username: r.Loser})
+	}
+	users := make([]*models.User, 0, len(players))
+	for _, p := range players {
+		if p.Username == self || p.Username == "" {
+			continue
+		}
+		var user *models.User
+		if p.UserID != "" {
+			user = s.userRepo.FindByID(p.UserID)
+		} else {
+			user = s.userRepo.FindByUsername(p.Username)
+		}
+		if user != nil {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// hashToken 计算确认令牌的 SHA-256 摘要，存储中只保存摘要
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeleteAccount 处理用户自行注销账号，需要密码确认
+func (s *userService) DeleteAccount(req protocol.DeleteAccountRequest) (bool, string) {
+	user := s.userRepo.FindByUsername(req.Username)
+	if user == nil {
+		return false, "用户不存在"
+	}
+	if user.Password != hashPassword(req.Password) {
+		return false, "密码错误"
+	}
+	s.DeleteUser(req.Username)
+	return true, "账号已注销"
+}
+
+// DeleteUser 删除用户并级联清理：断开连接、移出房间、匿名化历史结果、删除头像
+func (s *userService) DeleteUser(username string) bool {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return false
+	}
+
+	// 先断开在线连接，避免删除过程中继续产生数据
+	if s.sessions != nil {
+		s.sessions.DisconnectUser(username, "账号已注销")
+	}
+
+	// 从所有房间中移除
+	for _, room := range s.roomRepo.GetAll() {
+		updated, err := updateRoom(s.roomRepo, room.ID, func(room *models.Room) bool {
+			return removePlayer(room, username)
+		})
+		if err != nil {
+			log.Printf("从房间 %s 中移除用户 %s 失败: %v", room.ID, username, err)
+			continue
+		}
+		if updated != nil && len(updated.Players) == 0 {
+			s.roomRepo.Remove(room.ID)
+		}
+	}
+
+	// 匿名化历史结果，包括用户改名前留下的结果
+	alias := anonymousName(username)
+	names := usernames(user)
+	changed := 0
+	for _, name := range names {
+		changed += s.resultRepo.RenamePlayer(name, alias)
+	}
+	if changed > 0 {
+		// 改名只是在日志末尾追加新行，原来带有真实用户名的行要压缩日志后才会消失
+		if _, err := s.resultRepo.Compact(); err != nil {
+			log.Printf("匿名化用户 %s 后压缩游戏结果日志失败: %v", username, err)
+		}
+	}
+	if s.eraser != nil {
+		s.eraser.EraseUser(user.UserID, names, alias)
+	}
+
+	s.sessionRepo.RemoveUser(user.UserID)
+	s.loginRepo.RemoveUser(user.UserID)
+	s.refreshRepo.RemoveUser(user.UserID)
+	if err := s.avatarRepo.Delete(user.UserID); err != nil {
+		log.Printf("删除用户 %s 的头像失败: %v", username, err)
+	}
+	s.userRepo.Remove(username)
+	log.Printf("用户 %s 已删除，匿名化 %d 条游戏结果", username, changed)
+	return true
+}
+
+// removePlayer 将玩家从房间中移除，房主离开时由下一位玩家接任，返回玩家是否在房间中
+func removePlayer(room *models.Room, username string) bool {
+	for i, player := range room.Players {
+		if player != username {
+			continue
+		}
+		room.Players = append(room.Players[:i], room.Players[i+1:]...)
+		if room.HostID == username && len(room.Players) > 0 {
+			room.HostID = room.Players[0]
+		}
+		if len(room.Players) < 2 && room.Status == "ready" {
+			room.Status = "waiting"
+		}
+		return true
+	}
+	return false
+}
+
+// anonymousName 生成注销用户在历史结果中的匿名名称
+func anonymousName(username string) string {
+	sum := sha256.Sum256([]byte(username))
+	return "deleted_" + hex.EncodeToString(sum[:4])
+}
+
+// hashPassword 计算密码的 MD5 摘要
+func hashPassword(password string) string {
+	passwordHash := md5.Sum([]byte(password))
+	return hex.EncodeToString(passwordHash[:])
 }
+
+// ExportData 汇总用户资料、战绩、参与过的对局、登录会话和登录记录，以及连接层各模块中的用户数据，用户不存在时返回 nil
+func (s *userService) ExportData(username string) *UserData {
+	user := s.userRepo.FindByUsername(username)
+	if user == nil {
+		return nil
+	}
+	results := s.resultRepo.FindByUser(user.UserID, username)
+	data := &UserData{
+		User:     *user,
+		Stats:    computeStats(user.UserID, username, results),
+		Results:  results,
+		Sessions: s.sessionRepo.ListByUser(user.UserID),
+		Logins:   s.loginRepo.ListByUser(user.UserID),
+	}
+	if s.exporter != nil {
+		s.exporter.ExportUser(user.UserID, usernames(user), data)
+	}
+	return data
+}
+
+// usernames 返回用户当前及曾用的全部用户名
+func usernames(user *models.User) []string {
+	names := []string{user.Username}
+	for _, prev := range user.PreviousNames {
+		names = append(names, prev.Username)
+	}
+	return names
+}
+
+// computeStats 根据游戏结果计算玩家战绩，userID 对应的玩家改过名时按其在每局中使用的用户名统计
+func computeStats(userID, current string, results []models.GameResult) models.PlayerStats {
+	var stats models.PlayerStats
+	for _, r := range results {
+		username := r.NameOf(userID)
+		if username == "" {
+			username = current
+		}
+		stats.Matches++
+		if r.Winner == username {
+			stats.Wins++
+		} else if r.Loser == username {
+			stats.Losses++
+		}
+		for _, p := range r.Players {
+			if p.Username != username {
+				continue
+			}
+			stats.Kills += p.Kills
+			stats.Deaths += p.Deaths
+			stats.ShotsFired += p.ShotsFired
+			stats.ShotsHit += p.ShotsHit
+		}
+	}
+	if stats.ShotsFired > 0 {
+		stats.Accuracy = float64(stats.ShotsHit) / float64(stats.ShotsFired)
+	}
+	return stats
+}
\ No newline at end of file
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"game/repository"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 聊天消息包含屏蔽词时的处理方式
+const (
+	ChatFilterMask   = "mask"   // 把屏蔽词替换为 *
+	ChatFilterReject = "reject" // 拒绝发送整条消息
+	ChatFilterOff    = "off"    // 不过滤聊天，用户名和房间名仍然检查
+)
+
+// 名称或聊天消息包含屏蔽词
+var (
+	ErrNameProfane = errors.New("名称包含不当用语，请修改后重试")
+	ErrChatProfane = errors.New("消息包含不当用语，未发送")
+)
+
+// WordFilter 屏蔽词过滤器，用于注册和改名时的用户名、创建房间时的房间名以及聊天消息。
+// 名称包含屏蔽词时总是拒绝，聊天消息按 chatMode 打码或拒绝。屏蔽词列表可在运行时修改，
+// 由 Hub 和各个服务共享同一个实例；nil 过滤器不过滤任何内容
+type WordFilter struct {
+	repo     repository.WordRepository
+	chatMode string
+
+	mu    sync.RWMutex
+	words []string // 小写的屏蔽词
+}
+
+// NewWordFilter 创建 WordFilter 实例，chatMode 为空或未知时按 ChatFilterMask 处理
+func NewWordFilter(repo repository.WordRepository, chatMode string) *WordFilter {
+	if chatMode != ChatFilterReject && chatMode != ChatFilterOff {
+		chatMode = ChatFilterMask
+	}
+	f := &WordFilter{repo: repo, chatMode: chatMode}
+	f.reload()
+	return f
+}
+
+// reload 从存储重新读取屏蔽词
+func (f *WordFilter) reload() {
+	words := f.repo.All()
+	f.mu.Lock()
+	f.words = words
+	f.mu.Unlock()
+}
+
+// CheckName 检查用户名或房间名，忽略大小写以及名称中用来分隔的下划线、连字符、点和空白
+func (f *WordFilter) CheckName(name string) error {
+	if f == nil {
+		return nil
+	}
+	lower := strings.ToLower(name)
+	stripped := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == '.' || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, lower)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, word := range f.words {
+		if strings.Contains(lower, word) || strings.Contains(stripped, word) {
+			return ErrNameProfane
+		}
+	}
+	return nil
+}
+
+// FilterChat 过滤聊天消息：打码模式下返回替换后的消息，拒绝模式下包含屏蔽词时返回 ErrChatProfane
+func (f *WordFilter) FilterChat(text string) (string, error) {
+	if f == nil || f.chatMode == ChatFilterOff {
+		return text, nil
+	}
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	masked := false
+	f.mu.RLock()
+	for _, word := range f.words {
+		w := []rune(word)
+		for i := 0; i+len(w) <= len(lower); i++ {
+			if string(lower[i:i+len(w)]) != word {
+				continue
+			}
+			if f.chatMode == ChatFilterReject {
+				f.mu.RUnlock()
+				return "", ErrChatProfane
+			}
+			for j := i; j < i+len(w); j++ {
+				runes[j] = '*'
+			}
+			masked = true
+		}
+	}
+	f.mu.RUnlock()
+	if !masked {
+		return text, nil
+	}
+	return string(runes), nil
+}
+
+// ChatMode 返回聊天消息的处理方式
+func (f *WordFilter) ChatMode() string {
+	return f.chatMode
+}
+
+// Words 返回当前的屏蔽词，按字母序排列
+func (f *WordFilter) Words() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]string(nil), f.words...)
+}
+
+// Add 添加屏蔽词并立即生效，返回实际添加的数量
+func (f *WordFilter) Add(words []string) int {
+	n := f.repo.Add(words)
+	if n > 0 {
+		f.reload()
+	}
+	return n
+}
+
+// Remove 删除屏蔽词并立即生效，返回是否存在
+func (f *WordFilter) Remove(word string) bool {
+	if !f.repo.Remove(word) {
+		return false
+	}
+	f.reload()
+	return true
+}
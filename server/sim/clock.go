@@ -0,0 +1,112 @@
+// Package sim 提供可替换的时钟和随机数源。
+// 服务器默认使用真实时间和随机种子，测试中注入 FakeClock 和固定种子，使对局和超时逻辑可以确定性地重放
+package sim
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时间来源
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker 周期触发器，语义与 time.Ticker 一致：接收方来不及处理时丢弃多余的触发
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// RealClock 使用系统时间
+type RealClock struct{}
+
+// Now 返回当前系统时间
+func (RealClock) Now() time.Time { return time.Now() }
+
+// NewTicker 创建基于系统时间的 Ticker
+func (RealClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
+
+// FakeClock 手动推进的时钟，只有调用 Advance 时时间才会前进、Ticker 才会触发
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// NewFakeClock 创建从 start 开始的手动时钟
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now 返回当前模拟时间
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTicker 创建在模拟时间上触发的 Ticker
+func (f *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("sim: NewTicker 的间隔必须为正数")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{clock: f, period: d, next: f.now.Add(d), c: make(chan time.Time, 1)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// Advance 将时间推进 d，并按时间顺序触发期间到期的 Ticker
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	target := f.now.Add(d)
+	for {
+		// 找出最早到期的 Ticker，保证跨多个周期推进时触发顺序与真实时间一致
+		var due *fakeTicker
+		for _, t := range f.tickers {
+			if !t.next.After(target) && (due == nil || t.next.Before(due.next)) {
+				due = t
+			}
+		}
+		if due == nil {
+			break
+		}
+		f.now = due.next
+		due.next = due.next.Add(due.period)
+		select {
+		case due.c <- f.now:
+		default:
+		}
+	}
+	f.now = target
+}
+
+type fakeTicker struct {
+	clock  *FakeClock
+	period time.Duration
+	next   time.Time
+	c      chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, other := range f.tickers {
+		if other == t {
+			f.tickers = append(f.tickers[:i], f.tickers[i+1:]...)
+			return
+		}
+	}
+}
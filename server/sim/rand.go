@@ -0,0 +1,51 @@
+package sim
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RNG 对局逻辑使用的随机数接口，*rand.Rand 满足该接口
+type RNG interface {
+	Float64() float64
+	Int63() int64
+	Intn(n int) int
+}
+
+// Seeder 为每个对局派生独立的随机数源。
+// 固定主种子时，按相同顺序开始的对局得到相同的随机序列
+type Seeder struct {
+	mu     sync.Mutex
+	master *rand.Rand
+}
+
+// NewSeeder 创建派生器，seed 为 0 时使用当前时间作为主种子
+func NewSeeder(seed int64) *Seeder {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Seeder{master: rand.New(rand.NewSource(seed))}
+}
+
+// New 派生一个新的随机数源，返回值只能在单个协程中使用
+func (s *Seeder) New() RNG {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return rand.New(rand.NewSource(s.master.Int63()))
+}
+
+// Roll 返回共享随机序列 stream 的第 n 个值，范围 [0, 1)。
+// 服务器和客户端用同一个种子得到相同的序列：先用 FNV-1a 64 位哈希 stream，与种子异或，
+// 加上 n 乘以 0x9E3779B97F4A7C15，再经 SplitMix64 混合，取高 53 位除以 2^53
+func Roll(seed int64, stream string, n uint64) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(stream))
+	x := uint64(seed) ^ h.Sum64()
+	x += n * 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	x ^= x >> 31
+	return float64(x>>11) / (1 << 53)
+}
@@ -0,0 +1,140 @@
+package validate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 密码策略违规代码，随错误消息一起返回给客户端，便于客户端给出针对性提示
+const (
+	CodePasswordTooShort         = "password_too_short"
+	CodePasswordTooLong          = "password_too_long"
+	CodePasswordTooSimple        = "password_too_simple"
+	CodePasswordCommon           = "password_common"
+	CodePasswordContainsUsername = "password_contains_username"
+)
+
+// passwordMaxLength 密码长度上限，防止超长输入拖慢哈希计算
+const passwordMaxLength = 128
+
+// commonPasswords 内置的常见弱密码，比较时不区分大小写
+var commonPasswords = []string{
+	"123456", "1234567", "12345678", "123456789", "1234567890", "111111", "000000",
+	"123123", "654321", "666666", "888888", "112233", "121212", "abc123", "a123456",
+	"password", "password1", "passw0rd", "qwerty", "qwerty123", "qwertyuiop", "1q2w3e4r",
+	"1qaz2wsx", "asdfgh", "zxcvbn", "iloveyou", "admin", "admin123", "welcome",
+	"letmein", "monkey", "dragon", "football", "baseball", "sunshine", "woaini",
+	"woaini1314", "5201314", "aa123456",
+}
+
+// PolicyError 违反密码策略或房间名规则的错误，Code 为对应的违规代码
+type PolicyError struct {
+	Code    string
+	Message string
+}
+
+func (e *PolicyError) Error() string {
+	return e.Message
+}
+
+// PasswordPolicy 密码策略
+type PasswordPolicy struct {
+	MinLength    int  // 最少字符数
+	MinClasses   int  // 至少包含的字符类别数：小写字母、大写字母、数字、符号
+	RejectCommon bool // 拒绝常见弱密码
+
+	blacklist map[string]bool
+}
+
+// NewPasswordPolicy 创建密码策略，extra 为内置列表之外追加的弱密码
+func NewPasswordPolicy(minLength, minClasses int, rejectCommon bool, extra []string) *PasswordPolicy {
+	p := &PasswordPolicy{
+		MinLength:    minLength,
+		MinClasses:   minClasses,
+		RejectCommon: rejectCommon,
+		blacklist:    make(map[string]bool, len(commonPasswords)+len(extra)),
+	}
+	for _, list := range [][]string{commonPasswords, extra} {
+		for _, pw := range list {
+			if pw = strings.TrimSpace(pw); pw != "" {
+				p.blacklist[strings.ToLower(pw)] = true
+			}
+		}
+	}
+	return p
+}
+
+// LoadList 读取弱密码、屏蔽词等列表文件，每行一个，忽略空行和 # 开头的注释
+func LoadList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+	return list, scanner.Err()
+}
+
+// Check 检查密码是否符合策略，符合时返回 nil
+func (p *PasswordPolicy) Check(username, password string) *PolicyError {
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		return &PolicyError{Code: CodePasswordTooShort, Message: fmt.Sprintf("密码长度至少%d位", p.MinLength)}
+	}
+	if n > passwordMaxLength {
+		return &PolicyError{Code: CodePasswordTooLong, Message: fmt.Sprintf("密码长度不能超过%d位", passwordMaxLength)}
+	}
+
+	if classes := passwordClasses(password); classes < p.MinClasses {
+		return &PolicyError{
+			Code:    CodePasswordTooSimple,
+			Message: fmt.Sprintf("密码至少需要包含小写字母、大写字母、数字、符号中的%d种", p.MinClasses),
+		}
+	}
+
+	lower := strings.ToLower(password)
+	if username != "" && strings.Contains(lower, strings.ToLower(username)) {
+		return &PolicyError{Code: CodePasswordContainsUsername, Message: "密码不能包含用户名"}
+	}
+	if p.RejectCommon && p.blacklist[lower] {
+		return &PolicyError{Code: CodePasswordCommon, Message: "密码过于常见，请换一个"}
+	}
+	return nil
+}
+
+// passwordClasses 统计密码包含的字符类别数
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, has := range []bool{lower, upper, digit, symbol} {
+		if has {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,48 @@
+package validate
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 房间名违规代码
+const (
+	CodeRoomNameEmpty   = "room_name_empty"
+	CodeRoomNameTooLong = "room_name_too_long"
+	CodeRoomNameInvalid = "room_name_invalid"
+)
+
+// RoomNameMaxLength 房间名最多字符数，按字符计算
+const RoomNameMaxLength = 24
+
+// RoomName 校验房间名并返回去掉首尾空白后的名称：1-24 个字符，只能包含文字、数字、空格和标点，
+// 不能有连续的空格。违规时返回的 PolicyError 带有上面定义的违规代码
+func RoomName(name string) (string, *PolicyError) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", &PolicyError{Code: CodeRoomNameEmpty, Message: "房间名不能为空"}
+	}
+	if !utf8.ValidString(name) {
+		return "", &PolicyError{Code: CodeRoomNameInvalid, Message: "房间名包含无效字符"}
+	}
+	if utf8.RuneCountInString(name) > RoomNameMaxLength {
+		return "", &PolicyError{Code: CodeRoomNameTooLong, Message: "房间名不能超过24个字符"}
+	}
+
+	prevSpace := false
+	for _, r := range name {
+		switch {
+		case r == ' ':
+			if prevSpace {
+				return "", &PolicyError{Code: CodeRoomNameInvalid, Message: "房间名不能包含连续的空格"}
+			}
+			prevSpace = true
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsPunct(r):
+			prevSpace = false
+		default:
+			return "", &PolicyError{Code: CodeRoomNameInvalid, Message: "房间名只能包含文字、数字、空格和标点"}
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,122 @@
+// Package validate 校验用户输入的用户名和邮箱，并提供比较唯一性时使用的规范化形式
+package validate
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"game/models"
+)
+
+// 用户名长度限制，按字符（而非字节）计算，中文用户名与英文用户名长度规则一致
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 20
+)
+
+// 邮箱长度限制，见 RFC 5321 4.5.3.1
+const (
+	emailMaxLength = 254
+	localMaxLength = 64
+)
+
+// usernamePunct 用户名中允许出现的标点，不能出现在开头或结尾，也不能连续出现
+const usernamePunct = "_-."
+
+// reservedUsernames 保留用户名，比较时不区分大小写
+var reservedUsernames = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"root":          true,
+	"system":        true,
+	"server":        true,
+	"moderator":     true,
+	"support":       true,
+	"official":      true,
+	"guest":         true,
+	"anonymous":     true,
+	"null":          true,
+	"undefined":     true,
+	"管理员":           true,
+	"系统":            true,
+}
+
+// Username 校验用户名：3-20 个字符，只能包含字母（含中文等各语言文字）、数字和 _-.，
+// 标点不能位于首尾或连续出现，不能是保留名或以机器人前缀开头
+func Username(name string) error {
+	if !utf8.ValidString(name) {
+		return errors.New("用户名包含无效字符")
+	}
+	n := utf8.RuneCountInString(name)
+	if n < UsernameMinLength || n > UsernameMaxLength {
+		return errors.New("用户名长度必须在3-20个字符之间")
+	}
+
+	prevPunct := true // 视开头为标点，从而拒绝以标点开头
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			prevPunct = false
+		case strings.ContainsRune(usernamePunct, r):
+			if prevPunct {
+				return errors.New("用户名不能以 _-. 开头，且不能连续使用")
+			}
+			prevPunct = true
+		case unicode.IsSpace(r):
+			return errors.New("用户名不能包含空格")
+		default:
+			return errors.New("用户名只能包含文字、数字和 _-.")
+		}
+	}
+	if prevPunct {
+		return errors.New("用户名不能以 _-. 结尾")
+	}
+
+	if models.IsBot(FoldUsername(name)) {
+		return errors.New("用户名不能以 " + models.BotPrefix + " 开头")
+	}
+	if reservedUsernames[FoldUsername(name)] {
+		return errors.New("该用户名为系统保留，请换一个")
+	}
+	return nil
+}
+
+// Email 按 RFC 5322 解析邮箱，只接受不带显示名的纯地址，返回域名转为小写后的地址
+func Email(addr string) (string, error) {
+	if addr == "" {
+		return "", errors.New("邮箱不能为空")
+	}
+	if len(addr) > emailMaxLength {
+		return "", errors.New("邮箱过长")
+	}
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil || parsed.Name != "" || parsed.Address != addr {
+		return "", errors.New("邮箱格式不正确")
+	}
+
+	at := strings.LastIndexByte(addr, '@')
+	local, domain := addr[:at], addr[at+1:]
+	if len(local) > localMaxLength {
+		return "", errors.New("邮箱格式不正确")
+	}
+	// net/mail 接受 user@localhost 这类单标签域名，注册邮箱要求完整域名
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") ||
+		strings.HasPrefix(domain, "[") {
+		return "", errors.New("邮箱域名不正确")
+	}
+	return local + "@" + strings.ToLower(domain), nil
+}
+
+// FoldUsername 返回用户名的规范化形式，用于不区分大小写的唯一性比较
+func FoldUsername(name string) string {
+	return strings.ToLower(name)
+}
+
+// FoldEmail 返回邮箱的规范化形式，用于唯一性比较。
+// 虽然 RFC 允许本地部分区分大小写，但主流邮箱服务都不区分，注册时统一按不区分处理
+func FoldEmail(addr string) string {
+	return strings.ToLower(addr)
+}